@@ -0,0 +1,5 @@
+// Package phonebookv1 holds the generated protobuf and gRPC code for the
+// phonebook API defined in phonebook.proto.
+package phonebookv1
+
+//go:generate protoc -I . --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative phonebook.proto
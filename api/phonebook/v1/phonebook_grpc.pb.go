@@ -0,0 +1,568 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: phonebook.proto
+
+package phonebookv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	PhonebookService_AddContact_FullMethodName      = "/phonebook.v1.PhonebookService/AddContact"
+	PhonebookService_GetContact_FullMethodName      = "/phonebook.v1.PhonebookService/GetContact"
+	PhonebookService_UpdateContact_FullMethodName   = "/phonebook.v1.PhonebookService/UpdateContact"
+	PhonebookService_PatchContact_FullMethodName    = "/phonebook.v1.PhonebookService/PatchContact"
+	PhonebookService_DeleteContact_FullMethodName   = "/phonebook.v1.PhonebookService/DeleteContact"
+	PhonebookService_ListContacts_FullMethodName    = "/phonebook.v1.PhonebookService/ListContacts"
+	PhonebookService_ImportContacts_FullMethodName  = "/phonebook.v1.PhonebookService/ImportContacts"
+	PhonebookService_WatchContacts_FullMethodName   = "/phonebook.v1.PhonebookService/WatchContacts"
+	PhonebookService_RegisterWebhook_FullMethodName = "/phonebook.v1.PhonebookService/RegisterWebhook"
+	PhonebookService_ListWebhooks_FullMethodName    = "/phonebook.v1.PhonebookService/ListWebhooks"
+	PhonebookService_DeleteWebhook_FullMethodName   = "/phonebook.v1.PhonebookService/DeleteWebhook"
+)
+
+// PhonebookServiceClient is the client API for PhonebookService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PhonebookServiceClient interface {
+	AddContact(ctx context.Context, in *AddContactRequest, opts ...grpc.CallOption) (*AddContactResponse, error)
+	GetContact(ctx context.Context, in *GetContactRequest, opts ...grpc.CallOption) (*GetContactResponse, error)
+	UpdateContact(ctx context.Context, in *UpdateContactRequest, opts ...grpc.CallOption) (*UpdateContactResponse, error)
+	PatchContact(ctx context.Context, in *PatchContactRequest, opts ...grpc.CallOption) (*PatchContactResponse, error)
+	DeleteContact(ctx context.Context, in *DeleteContactRequest, opts ...grpc.CallOption) (*DeleteContactResponse, error)
+	ListContacts(ctx context.Context, in *ListContactsRequest, opts ...grpc.CallOption) (PhonebookService_ListContactsClient, error)
+	ImportContacts(ctx context.Context, opts ...grpc.CallOption) (PhonebookService_ImportContactsClient, error)
+	WatchContacts(ctx context.Context, in *WatchContactsRequest, opts ...grpc.CallOption) (PhonebookService_WatchContactsClient, error)
+	RegisterWebhook(ctx context.Context, in *RegisterWebhookRequest, opts ...grpc.CallOption) (*RegisterWebhookResponse, error)
+	ListWebhooks(ctx context.Context, in *ListWebhooksRequest, opts ...grpc.CallOption) (*ListWebhooksResponse, error)
+	DeleteWebhook(ctx context.Context, in *DeleteWebhookRequest, opts ...grpc.CallOption) (*DeleteWebhookResponse, error)
+}
+
+type phonebookServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPhonebookServiceClient(cc grpc.ClientConnInterface) PhonebookServiceClient {
+	return &phonebookServiceClient{cc}
+}
+
+func (c *phonebookServiceClient) AddContact(ctx context.Context, in *AddContactRequest, opts ...grpc.CallOption) (*AddContactResponse, error) {
+	out := new(AddContactResponse)
+	err := c.cc.Invoke(ctx, PhonebookService_AddContact_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *phonebookServiceClient) GetContact(ctx context.Context, in *GetContactRequest, opts ...grpc.CallOption) (*GetContactResponse, error) {
+	out := new(GetContactResponse)
+	err := c.cc.Invoke(ctx, PhonebookService_GetContact_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *phonebookServiceClient) UpdateContact(ctx context.Context, in *UpdateContactRequest, opts ...grpc.CallOption) (*UpdateContactResponse, error) {
+	out := new(UpdateContactResponse)
+	err := c.cc.Invoke(ctx, PhonebookService_UpdateContact_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *phonebookServiceClient) PatchContact(ctx context.Context, in *PatchContactRequest, opts ...grpc.CallOption) (*PatchContactResponse, error) {
+	out := new(PatchContactResponse)
+	err := c.cc.Invoke(ctx, PhonebookService_PatchContact_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *phonebookServiceClient) DeleteContact(ctx context.Context, in *DeleteContactRequest, opts ...grpc.CallOption) (*DeleteContactResponse, error) {
+	out := new(DeleteContactResponse)
+	err := c.cc.Invoke(ctx, PhonebookService_DeleteContact_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *phonebookServiceClient) ListContacts(ctx context.Context, in *ListContactsRequest, opts ...grpc.CallOption) (PhonebookService_ListContactsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &PhonebookService_ServiceDesc.Streams[0], PhonebookService_ListContacts_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &phonebookServiceListContactsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type PhonebookService_ListContactsClient interface {
+	Recv() (*ContactEntry, error)
+	grpc.ClientStream
+}
+
+type phonebookServiceListContactsClient struct {
+	grpc.ClientStream
+}
+
+func (x *phonebookServiceListContactsClient) Recv() (*ContactEntry, error) {
+	m := new(ContactEntry)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *phonebookServiceClient) ImportContacts(ctx context.Context, opts ...grpc.CallOption) (PhonebookService_ImportContactsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &PhonebookService_ServiceDesc.Streams[1], PhonebookService_ImportContacts_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &phonebookServiceImportContactsClient{stream}
+	return x, nil
+}
+
+type PhonebookService_ImportContactsClient interface {
+	Send(*ImportContactsRequest) error
+	CloseAndRecv() (*ImportContactsResponse, error)
+	grpc.ClientStream
+}
+
+type phonebookServiceImportContactsClient struct {
+	grpc.ClientStream
+}
+
+func (x *phonebookServiceImportContactsClient) Send(m *ImportContactsRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *phonebookServiceImportContactsClient) CloseAndRecv() (*ImportContactsResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ImportContactsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *phonebookServiceClient) WatchContacts(ctx context.Context, in *WatchContactsRequest, opts ...grpc.CallOption) (PhonebookService_WatchContactsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &PhonebookService_ServiceDesc.Streams[2], PhonebookService_WatchContacts_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &phonebookServiceWatchContactsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type PhonebookService_WatchContactsClient interface {
+	Recv() (*ContactEvent, error)
+	grpc.ClientStream
+}
+
+type phonebookServiceWatchContactsClient struct {
+	grpc.ClientStream
+}
+
+func (x *phonebookServiceWatchContactsClient) Recv() (*ContactEvent, error) {
+	m := new(ContactEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *phonebookServiceClient) RegisterWebhook(ctx context.Context, in *RegisterWebhookRequest, opts ...grpc.CallOption) (*RegisterWebhookResponse, error) {
+	out := new(RegisterWebhookResponse)
+	err := c.cc.Invoke(ctx, PhonebookService_RegisterWebhook_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *phonebookServiceClient) ListWebhooks(ctx context.Context, in *ListWebhooksRequest, opts ...grpc.CallOption) (*ListWebhooksResponse, error) {
+	out := new(ListWebhooksResponse)
+	err := c.cc.Invoke(ctx, PhonebookService_ListWebhooks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *phonebookServiceClient) DeleteWebhook(ctx context.Context, in *DeleteWebhookRequest, opts ...grpc.CallOption) (*DeleteWebhookResponse, error) {
+	out := new(DeleteWebhookResponse)
+	err := c.cc.Invoke(ctx, PhonebookService_DeleteWebhook_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PhonebookServiceServer is the server API for PhonebookService service.
+// All implementations must embed UnimplementedPhonebookServiceServer
+// for forward compatibility
+type PhonebookServiceServer interface {
+	AddContact(context.Context, *AddContactRequest) (*AddContactResponse, error)
+	GetContact(context.Context, *GetContactRequest) (*GetContactResponse, error)
+	UpdateContact(context.Context, *UpdateContactRequest) (*UpdateContactResponse, error)
+	PatchContact(context.Context, *PatchContactRequest) (*PatchContactResponse, error)
+	DeleteContact(context.Context, *DeleteContactRequest) (*DeleteContactResponse, error)
+	ListContacts(*ListContactsRequest, PhonebookService_ListContactsServer) error
+	ImportContacts(PhonebookService_ImportContactsServer) error
+	WatchContacts(*WatchContactsRequest, PhonebookService_WatchContactsServer) error
+	RegisterWebhook(context.Context, *RegisterWebhookRequest) (*RegisterWebhookResponse, error)
+	ListWebhooks(context.Context, *ListWebhooksRequest) (*ListWebhooksResponse, error)
+	DeleteWebhook(context.Context, *DeleteWebhookRequest) (*DeleteWebhookResponse, error)
+	mustEmbedUnimplementedPhonebookServiceServer()
+}
+
+// UnimplementedPhonebookServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedPhonebookServiceServer struct {
+}
+
+func (UnimplementedPhonebookServiceServer) AddContact(context.Context, *AddContactRequest) (*AddContactResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddContact not implemented")
+}
+func (UnimplementedPhonebookServiceServer) GetContact(context.Context, *GetContactRequest) (*GetContactResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetContact not implemented")
+}
+func (UnimplementedPhonebookServiceServer) UpdateContact(context.Context, *UpdateContactRequest) (*UpdateContactResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateContact not implemented")
+}
+func (UnimplementedPhonebookServiceServer) PatchContact(context.Context, *PatchContactRequest) (*PatchContactResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PatchContact not implemented")
+}
+func (UnimplementedPhonebookServiceServer) DeleteContact(context.Context, *DeleteContactRequest) (*DeleteContactResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteContact not implemented")
+}
+func (UnimplementedPhonebookServiceServer) ListContacts(*ListContactsRequest, PhonebookService_ListContactsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListContacts not implemented")
+}
+func (UnimplementedPhonebookServiceServer) ImportContacts(PhonebookService_ImportContactsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ImportContacts not implemented")
+}
+func (UnimplementedPhonebookServiceServer) WatchContacts(*WatchContactsRequest, PhonebookService_WatchContactsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchContacts not implemented")
+}
+func (UnimplementedPhonebookServiceServer) RegisterWebhook(context.Context, *RegisterWebhookRequest) (*RegisterWebhookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterWebhook not implemented")
+}
+func (UnimplementedPhonebookServiceServer) ListWebhooks(context.Context, *ListWebhooksRequest) (*ListWebhooksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWebhooks not implemented")
+}
+func (UnimplementedPhonebookServiceServer) DeleteWebhook(context.Context, *DeleteWebhookRequest) (*DeleteWebhookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteWebhook not implemented")
+}
+func (UnimplementedPhonebookServiceServer) mustEmbedUnimplementedPhonebookServiceServer() {}
+
+// UnsafePhonebookServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PhonebookServiceServer will
+// result in compilation errors.
+type UnsafePhonebookServiceServer interface {
+	mustEmbedUnimplementedPhonebookServiceServer()
+}
+
+func RegisterPhonebookServiceServer(s grpc.ServiceRegistrar, srv PhonebookServiceServer) {
+	s.RegisterService(&PhonebookService_ServiceDesc, srv)
+}
+
+func _PhonebookService_AddContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddContactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PhonebookServiceServer).AddContact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PhonebookService_AddContact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PhonebookServiceServer).AddContact(ctx, req.(*AddContactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PhonebookService_GetContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetContactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PhonebookServiceServer).GetContact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PhonebookService_GetContact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PhonebookServiceServer).GetContact(ctx, req.(*GetContactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PhonebookService_UpdateContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateContactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PhonebookServiceServer).UpdateContact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PhonebookService_UpdateContact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PhonebookServiceServer).UpdateContact(ctx, req.(*UpdateContactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PhonebookService_PatchContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PatchContactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PhonebookServiceServer).PatchContact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PhonebookService_PatchContact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PhonebookServiceServer).PatchContact(ctx, req.(*PatchContactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PhonebookService_DeleteContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteContactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PhonebookServiceServer).DeleteContact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PhonebookService_DeleteContact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PhonebookServiceServer).DeleteContact(ctx, req.(*DeleteContactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PhonebookService_ListContacts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListContactsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PhonebookServiceServer).ListContacts(m, &phonebookServiceListContactsServer{stream})
+}
+
+type PhonebookService_ListContactsServer interface {
+	Send(*ContactEntry) error
+	grpc.ServerStream
+}
+
+type phonebookServiceListContactsServer struct {
+	grpc.ServerStream
+}
+
+func (x *phonebookServiceListContactsServer) Send(m *ContactEntry) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _PhonebookService_ImportContacts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PhonebookServiceServer).ImportContacts(&phonebookServiceImportContactsServer{stream})
+}
+
+type PhonebookService_ImportContactsServer interface {
+	SendAndClose(*ImportContactsResponse) error
+	Recv() (*ImportContactsRequest, error)
+	grpc.ServerStream
+}
+
+type phonebookServiceImportContactsServer struct {
+	grpc.ServerStream
+}
+
+func (x *phonebookServiceImportContactsServer) SendAndClose(m *ImportContactsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *phonebookServiceImportContactsServer) Recv() (*ImportContactsRequest, error) {
+	m := new(ImportContactsRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _PhonebookService_WatchContacts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchContactsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PhonebookServiceServer).WatchContacts(m, &phonebookServiceWatchContactsServer{stream})
+}
+
+type PhonebookService_WatchContactsServer interface {
+	Send(*ContactEvent) error
+	grpc.ServerStream
+}
+
+type phonebookServiceWatchContactsServer struct {
+	grpc.ServerStream
+}
+
+func (x *phonebookServiceWatchContactsServer) Send(m *ContactEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _PhonebookService_RegisterWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterWebhookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PhonebookServiceServer).RegisterWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PhonebookService_RegisterWebhook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PhonebookServiceServer).RegisterWebhook(ctx, req.(*RegisterWebhookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PhonebookService_ListWebhooks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWebhooksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PhonebookServiceServer).ListWebhooks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PhonebookService_ListWebhooks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PhonebookServiceServer).ListWebhooks(ctx, req.(*ListWebhooksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PhonebookService_DeleteWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteWebhookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PhonebookServiceServer).DeleteWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PhonebookService_DeleteWebhook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PhonebookServiceServer).DeleteWebhook(ctx, req.(*DeleteWebhookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PhonebookService_ServiceDesc is the grpc.ServiceDesc for PhonebookService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PhonebookService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "phonebook.v1.PhonebookService",
+	HandlerType: (*PhonebookServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddContact",
+			Handler:    _PhonebookService_AddContact_Handler,
+		},
+		{
+			MethodName: "GetContact",
+			Handler:    _PhonebookService_GetContact_Handler,
+		},
+		{
+			MethodName: "UpdateContact",
+			Handler:    _PhonebookService_UpdateContact_Handler,
+		},
+		{
+			MethodName: "PatchContact",
+			Handler:    _PhonebookService_PatchContact_Handler,
+		},
+		{
+			MethodName: "DeleteContact",
+			Handler:    _PhonebookService_DeleteContact_Handler,
+		},
+		{
+			MethodName: "RegisterWebhook",
+			Handler:    _PhonebookService_RegisterWebhook_Handler,
+		},
+		{
+			MethodName: "ListWebhooks",
+			Handler:    _PhonebookService_ListWebhooks_Handler,
+		},
+		{
+			MethodName: "DeleteWebhook",
+			Handler:    _PhonebookService_DeleteWebhook_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListContacts",
+			Handler:       _PhonebookService_ListContacts_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ImportContacts",
+			Handler:       _PhonebookService_ImportContacts_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchContacts",
+			Handler:       _PhonebookService_WatchContacts_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "phonebook.proto",
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Businge931/practice-interfaces/internal/adoptors/carddavapi"
+	"github.com/Businge931/practice-interfaces/internal/adoptors/database"
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func main() {
+	addr := flag.String("addr", ":8008", "address to listen on")
+	dataDir := flag.String("data", "", "directory for the filesystem database (in memory if empty)")
+	authDir := flag.String("auth", "", "directory of the users and API keys; enables authorization when set")
+	flag.Parse()
+
+	var db ports.Database = database.NewInMemoryDatabase()
+	if *dataDir != "" {
+		db = database.NewFileSystemDatabase(*dataDir)
+	}
+
+	var options []application.Option
+	var handlerOptions []carddavapi.Option
+	if *authDir != "" {
+		auth := application.NewAuthService(database.NewFileSystemDatabase(*authDir))
+		options = append(options, application.WithAuthorization())
+		handlerOptions = append(handlerOptions, carddavapi.WithAuth(auth))
+	}
+
+	handler := carddavapi.NewHandler(application.NewPhonebookService(db, options...), handlerOptions...)
+	log.Printf("Serving CardDAV on %s", *addr)
+	if err := http.ListenAndServe(*addr, handler); err != nil {
+		log.Fatalf("CardDAV server stopped: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+
+	"github.com/Businge931/practice-interfaces/internal/adoptors/database"
+	"github.com/Businge931/practice-interfaces/internal/adoptors/grpcapi"
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func main() {
+	addr := flag.String("addr", ":50051", "address to listen on")
+	dataDir := flag.String("data", "", "directory for the filesystem database (in memory if empty)")
+	authDir := flag.String("auth", "", "directory of the users and API keys; enables authorization when set")
+	tenantsDir := flag.String("tenants", "", "directory of the tenant registry; gives each tenant its own phonebook when set")
+	keyFile := flag.String("keyfile", "", "master key file; encrypts phone numbers and addresses at rest when set")
+	dualWriteBackend := flag.String("dual-write-backend", "", "backend that also receives every write while data is migrated to it")
+	dualWriteTarget := flag.String("dual-write-target", "", "directory, DSN or URI of the dual-write backend")
+	consistency := flag.String("dual-write-consistency", string(database.MirrorBestEffort), "best-effort queues failed dual writes for repair, strict reverts and fails them")
+	shadowReads := flag.Bool("shadow-reads", false, "compare every read with the dual-write backend and log divergence")
+	reconcileInterval := flag.Duration("reconcile-interval", 0, "how often to fix drift in the dual-write backend; 0 disables it")
+	webhooksDir := flag.String("webhooks", "", "directory of the webhook registry and delivery queue; delivers contact events to webhooks when set")
+	webhookInterval := flag.Duration("webhook-interval", 10*time.Second, "how often due webhook deliveries are attempted")
+	flag.Parse()
+
+	var db ports.Database = database.NewInMemoryDatabase()
+	if *dataDir != "" {
+		db = database.NewFileSystemDatabase(*dataDir)
+	}
+	if *dualWriteBackend != "" {
+		secondary, closeSecondary, err := database.Open(*dualWriteBackend, *dualWriteTarget)
+		if err != nil {
+			log.Fatalf("Failed to open the %s database: %v", *dualWriteBackend, err)
+		}
+		defer closeSecondary()
+		options := []database.MirrorOption{database.WithConsistency(database.MirrorConsistency(*consistency))}
+		if *shadowReads {
+			options = append(options, database.WithShadowReads())
+		}
+		mirror := database.NewMirroredDatabase(db, []ports.Database{secondary}, options...)
+		mirror.Start(context.Background(), time.Minute, *reconcileInterval)
+		db = mirror
+	}
+	if *keyFile != "" {
+		masterKey, err := database.LoadKeyFile(*keyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		db, err = database.NewEncryptedDatabase(db, masterKey, application.SensitiveFields, database.WithBlindIndex("phone", application.NormalizePhone))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", *addr, err)
+	}
+
+	var options []application.Option
+	var serverOptions []grpc.ServerOption
+	if *authDir != "" {
+		auth := application.NewAuthService(database.NewFileSystemDatabase(*authDir))
+		options = append(options, application.WithAuthorization())
+		serverOptions = append(serverOptions, grpcapi.WithAuth(auth)...)
+	}
+	if *tenantsDir != "" {
+		tenants := application.NewTenantManager(database.NewFileSystemDatabase(*tenantsDir), database.Tenants(db))
+		defer tenants.Close()
+		options = append(options, application.WithTenants(tenants))
+		serverOptions = append(serverOptions, grpcapi.WithTenants()...)
+	}
+
+	phonebook := application.NewPhonebookService(db, options...)
+	var webhooks *application.WebhookDispatcher
+	if *webhooksDir != "" {
+		if *tenantsDir != "" {
+			log.Fatal("Webhooks cannot be used with tenants")
+		}
+		webhooks, err = application.NewWebhookDispatcher(phonebook, database.NewFileSystemDatabase(*webhooksDir))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if success, message := webhooks.Start(context.Background(), *webhookInterval); !success {
+			log.Fatalf("Failed to start webhook deliveries: %s", message)
+		}
+	}
+
+	server := grpcapi.NewGRPCServer(phonebook, webhooks, serverOptions...)
+	log.Printf("Serving gRPC on %s", listener.Addr())
+	if err := server.Serve(listener); err != nil {
+		log.Fatalf("gRPC server stopped: %v", err)
+	}
+}
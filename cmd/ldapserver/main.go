@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Businge931/practice-interfaces/internal/adoptors/database"
+	"github.com/Businge931/practice-interfaces/internal/adoptors/ldapapi"
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func main() {
+	addr := flag.String("addr", ":10389", "address to listen on")
+	dataDir := flag.String("data", "", "directory for the filesystem database (in memory if empty)")
+	authDir := flag.String("auth", "", "directory of the users and API keys; binds are checked against it and authorization is enabled when set")
+	baseDN := flag.String("base-dn", ldapapi.DefaultBaseDN, "entry the contacts are listed under")
+	bindDN := flag.String("bind-dn", "", "DN allowed to bind with -bind-password")
+	bindPassword := flag.String("bind-password", "", "password for -bind-dn")
+	anonymous := flag.Bool("anonymous", true, "allow searches without binding")
+	sizeLimit := flag.Int("size-limit", ldapapi.DefaultMaxSizeLimit, "maximum entries returned per search")
+	flag.Parse()
+
+	var db ports.Database = database.NewInMemoryDatabase()
+	if *dataDir != "" {
+		db = database.NewFileSystemDatabase(*dataDir)
+	}
+
+	config := ldapapi.Config{
+		BaseDN:         *baseDN,
+		AllowAnonymous: *anonymous,
+		MaxSizeLimit:   *sizeLimit,
+	}
+	if *bindDN != "" {
+		config.Users = map[string]string{*bindDN: *bindPassword}
+	}
+
+	var options []application.Option
+	if *authDir != "" {
+		config.Auth = application.NewAuthService(database.NewFileSystemDatabase(*authDir))
+		options = append(options, application.WithAuthorization())
+	}
+
+	server, err := ldapapi.NewServer(application.NewPhonebookService(db, options...), config)
+	if err != nil {
+		log.Fatalf("Failed to create LDAP server: %v", err)
+	}
+	log.Printf("Serving LDAP on %s", *addr)
+	if err := server.Run(*addr); err != nil {
+		log.Fatalf("LDAP server stopped: %v", err)
+	}
+}
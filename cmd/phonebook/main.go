@@ -0,0 +1,353 @@
+// Command phonebook backs up, restores, migrates and verifies the phonebook
+// of any backend, rebalances sharded backends, prunes unused photos and
+// attachments and manages the phonebook's custom fields.
+//
+// Records are copied as stored, so the backup of an encrypted backend stays
+// encrypted and restores under the same master key.
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Businge931/practice-interfaces/internal/adoptors/database"
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+const usage = `usage: phonebook [-backend name] [-target target] [-tenant id] <command> [arguments]
+
+commands:
+  backup [-o file]
+  restore [-mode replace|merge] [-dry-run] <file>
+  migrate -to-backend name -to-target target [-chunk n] [-checkpoint dir] [-id name] [-restart]
+  compare -to-backend name -to-target target
+  rebalance
+  prune-blobs
+  verify
+  fields
+  define-field [-type text|number|boolean|date] [-required] [-enum a,b] [-pattern regexp] <name>
+  remove-field <name>
+
+Sharded backends take their shards as the target, e.g.
+  -backend sharded -target "a=postgres:postgres://...;b=filesystem:data-b"
+and need a rebalance after a shard is added.
+
+A file of "-" is standard output or input.
+`
+
+func main() {
+	backend := flag.String("backend", database.BackendFileSystem, "database backend: memory, filesystem, postgres, mongodb or sharded")
+	target := flag.String("target", "data", "directory, DSN or URI of the database")
+	tenant := flag.String("tenant", "", "tenant whose phonebook to use")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, closeDB, err := openDatabase(*backend, *target, *tenant)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeDB()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, db, *tenant, flag.Args()); err != nil {
+		if errors.Is(err, errUsage) {
+			flag.Usage()
+			os.Exit(2)
+		}
+		closeDB()
+		log.Fatal(err)
+	}
+}
+
+// openDatabase opens a backend, or the phonebook of one of its tenants.
+func openDatabase(backend, target, tenant string) (ports.Database, func() error, error) {
+	db, closeDB, err := database.Open(backend, target)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open the %s database: %v", backend, err)
+	}
+	if tenant == "" {
+		return db, closeDB, nil
+	}
+	success, message, tenantDB := database.Tenants(db).Tenant(tenant)
+	if !success {
+		closeDB()
+		return nil, nil, fmt.Errorf("failed to open tenant %s: %s", tenant, message)
+	}
+	return tenantDB, closeDB, nil
+}
+
+var errUsage = errors.New("invalid usage")
+
+func run(ctx context.Context, db ports.Database, tenant string, args []string) error {
+	phonebook := application.NewPhonebookService(db)
+	command, args := args[0], args[1:]
+	switch command {
+	case "backup":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		output := flags.String("o", "-", "file to write the backup to")
+		if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+			return errUsage
+		}
+		return backup(phonebook, *output)
+	case "restore":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		mode := flags.String("mode", string(domain.RestoreReplace), "replace to match the backup exactly, merge to keep other records")
+		dryRun := flags.Bool("dry-run", false, "report the changes without making them")
+		if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+			return errUsage
+		}
+		return restore(phonebook, flags.Arg(0), domain.RestoreMode(*mode), *dryRun)
+	case "migrate", "compare":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		toBackend := flags.String("to-backend", "", "database backend to copy to")
+		toTarget := flags.String("to-target", "", "directory, DSN or URI of the database to copy to")
+		chunk := flags.Int("chunk", application.DefaultMigrationChunkSize, "records copied per batch")
+		checkpoints := flags.String("checkpoint", "migrations", "directory keeping the progress of migrations")
+		id := flags.String("id", "", "name of the migration, to resume it; derived from the target by default")
+		restart := flags.Bool("restart", false, "copy everything again instead of resuming")
+		if err := flags.Parse(args); err != nil || flags.NArg() != 0 || *toBackend == "" {
+			return errUsage
+		}
+
+		target, closeTarget, err := openDatabase(*toBackend, *toTarget, tenant)
+		if err != nil {
+			return err
+		}
+		defer closeTarget()
+		if *id == "" {
+			sum := sha256.Sum256([]byte(*toTarget + "\x00" + tenant))
+			*id = *toBackend + "-" + hex.EncodeToString(sum[:6])
+		}
+		if command == "migrate" {
+			options := []application.MigrationOption{
+				application.WithChunkSize(*chunk),
+				application.WithCheckpoint(database.NewFileSystemDatabase(*checkpoints), *id),
+				application.WithProgress(logProgress),
+			}
+			if *restart {
+				options = append(options, application.WithRestart())
+			}
+			if err := check(phonebook.Migrate(ctx, target, options...)); err != nil {
+				return err
+			}
+		}
+		return compare(phonebook, target)
+	case "rebalance":
+		if len(args) != 0 {
+			return errUsage
+		}
+		if tenant != "" {
+			return errors.New("rebalance moves the records of every tenant, run it without -tenant")
+		}
+		sharded, ok := db.(*database.ShardedDatabase)
+		if !ok {
+			return errors.New("only the sharded backend can be rebalanced")
+		}
+		return rebalance(ctx, sharded)
+	case "prune-blobs":
+		if len(args) != 0 {
+			return errUsage
+		}
+		success, message, pruned := phonebook.PruneBlobs()
+		if err := check(success, message); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Deleted %d unused blobs\n", pruned)
+		return nil
+	case "verify":
+		if len(args) != 0 {
+			return errUsage
+		}
+		return verify(phonebook)
+	case "fields":
+		if len(args) != 0 {
+			return errUsage
+		}
+		success, message, definitions := phonebook.FieldDefinitions()
+		if err := check(success, message); err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTYPE\tREQUIRED\tENUM\tPATTERN")
+		for _, definition := range definitions {
+			fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\n", definition.Name, definition.Type, definition.Required, strings.Join(definition.Enum, ","), definition.Pattern)
+		}
+		return w.Flush()
+	case "define-field":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		fieldType := flags.String("type", string(domain.FieldText), "type of the values: text, number, boolean or date")
+		required := flags.Bool("required", false, "whether every contact must have a value")
+		enum := flags.String("enum", "", "comma-separated list of the allowed values")
+		pattern := flags.String("pattern", "", "regular expression values must match")
+		if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+			return errUsage
+		}
+		definition := domain.FieldDefinition{
+			Name:     flags.Arg(0),
+			Type:     domain.FieldType(*fieldType),
+			Required: *required,
+			Pattern:  *pattern,
+		}
+		if *enum != "" {
+			definition.Enum = strings.Split(*enum, ",")
+		}
+		return check(phonebook.DefineField(definition))
+	case "remove-field":
+		if len(args) != 1 {
+			return errUsage
+		}
+		return check(phonebook.RemoveField(args[0]))
+	}
+	return errUsage
+}
+
+// verify fails when any record of the phonebook cannot be read cleanly.
+func verify(phonebook *application.PhonebookService) error {
+	success, message, report := phonebook.VerifyRecords()
+	if !success {
+		return errors.New(message)
+	}
+	for _, problem := range report.Problems {
+		for _, err := range problem.Errors {
+			fmt.Printf("%s: %v\n", problem.ID, err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Checked %d records: %d with problems, %d in an older version\n", report.Records, len(report.Problems), report.Outdated)
+	if len(report.Problems) > 0 {
+		return fmt.Errorf("found %d records with problems", len(report.Problems))
+	}
+	return nil
+}
+
+func rebalance(ctx context.Context, db *database.ShardedDatabase) error {
+	success, message, report := db.Rebalance(ctx)
+	for _, shard := range db.Shards() {
+		fmt.Fprintf(os.Stderr, "Moved %d records and %d blobs to shard %s\n", report.Moved[shard], report.Blobs[shard], shard)
+	}
+	return check(success, message)
+}
+
+func logProgress(progress domain.MigrationProgress) {
+	percent := 100
+	if progress.Total > 0 {
+		percent = progress.Copied * 100 / progress.Total
+	}
+	fmt.Fprintf(os.Stderr, "Copied %d of %d records (%d%%)\n", progress.Copied, progress.Total, percent)
+}
+
+// compare fails unless target holds exactly the phonebook's data.
+func compare(phonebook *application.PhonebookService, target ports.Database) error {
+	success, message, report := phonebook.VerifyMigration(target)
+	if !success {
+		return errors.New(message)
+	}
+	fmt.Fprintf(os.Stderr, "Source: %d records, sha256 %s\nTarget: %d records, sha256 %s\n",
+		report.SourceRecords, report.SourceChecksum, report.TargetRecords, report.TargetChecksum)
+	if report.InSync() {
+		fmt.Fprintln(os.Stderr, "In sync")
+		return nil
+	}
+	for _, list := range []struct {
+		label     string
+		locations []string
+	}{
+		{"missing", report.Missing},
+		{"extra", report.Extra},
+		{"different", report.Mismatched},
+		{"different group", report.MismatchedGroups},
+		{"missing blob", report.MissingBlobs},
+	} {
+		for _, location := range list.locations {
+			fmt.Printf("%s\t%s\n", list.label, location)
+		}
+	}
+	return fmt.Errorf("target differs: %d missing, %d extra, %d different, %d groups different, %d blobs missing",
+		len(report.Missing), len(report.Extra), len(report.Mismatched), len(report.MismatchedGroups), len(report.MissingBlobs))
+}
+
+func check(success bool, message string, _ ...interface{}) error {
+	if !success {
+		return errors.New(message)
+	}
+	return nil
+}
+
+// backup writes to a temporary file first, so a failed backup never replaces
+// a good one.
+func backup(phonebook *application.PhonebookService, path string) error {
+	if path == "-" {
+		success, message, manifest := phonebook.Backup(os.Stdout)
+		if !success {
+			return errors.New(message)
+		}
+		logManifest(manifest)
+		return nil
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	success, message, manifest := phonebook.Backup(f)
+	if err := f.Close(); err != nil && success {
+		success, message = false, err.Error()
+	}
+	if !success {
+		os.Remove(tmp)
+		return errors.New(message)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	logManifest(manifest)
+	return nil
+}
+
+func restore(phonebook *application.PhonebookService, path string, mode domain.RestoreMode, dryRun bool) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	success, message, report := phonebook.Restore(r, mode, dryRun)
+	if !success {
+		return errors.New(message)
+	}
+	verb := "Restored"
+	if report.DryRun {
+		verb = "Would restore"
+	}
+	fmt.Fprintf(os.Stderr, "%s backup of %s: %d created, %d updated, %d deleted, %d groups, %d blobs stored, %d blobs deleted\n",
+		verb, report.Manifest.CreatedAt.Format("2006-01-02 15:04:05 MST"), report.Created, report.Updated, report.Deleted, report.Groups, report.Blobs, report.DeletedBlobs)
+	return nil
+}
+
+func logManifest(manifest domain.BackupManifest) {
+	fmt.Fprintf(os.Stderr, "Backed up %d records, %d blobs and %d groups (sha256 %s)\n", manifest.Records, manifest.Blobs, manifest.Groups, manifest.Checksum)
+}
@@ -0,0 +1,406 @@
+// Command phonebookadmin manages the users, API keys, tenants, webhooks and
+// encryption keys of the phonebook.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Businge931/practice-interfaces/internal/adoptors/database"
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+const usage = `usage: phonebookadmin [-auth dir] [-tenants dir] [-webhooks dir] [-backend name] [-target target] [-keyfile file] <command> [arguments]
+
+commands:
+  user add <name> [-role viewer|editor|admin] [-tenant id]
+  user list
+  user delete <name>
+  user role <name> <role|none>
+  user grant <name> <group> <role|none>
+  user disable <name>
+  user enable <name>
+  key create <user> [-name label]
+  key list [user]
+  key revoke <id>
+  token issue <user> [-ttl 24h]
+  tenant create <id> [-name name] [-max-contacts n]
+  tenant list
+  tenant quota <id> <max-contacts>
+  tenant delete <id>
+  webhook add <url> [-events created,updated,deleted] [-group name] [-secret secret]
+  webhook list
+  webhook delete <id>
+`
+
+func main() {
+	a := &admin{}
+	authDir := flag.String("auth", "auth", "directory of the users and API keys")
+	flag.StringVar(&a.tenantsDir, "tenants", "tenants", "directory of the tenant registry")
+	flag.StringVar(&a.webhooksDir, "webhooks", "webhooks", "directory of the webhook registry")
+	flag.StringVar(&a.backend, "backend", database.BackendFileSystem, "backend holding the phonebooks")
+	flag.StringVar(&a.target, "target", "data", "directory, DSN or URI of the backend")
+	flag.StringVar(&a.keyFile, "keyfile", "", "master key file of an encrypted backend")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	a.auth = application.NewAuthService(database.NewFileSystemDatabase(*authDir))
+	if err := a.run(flag.Args()); err != nil {
+		if errors.Is(err, errUsage) {
+			flag.Usage()
+			os.Exit(2)
+		}
+		log.Fatal(err)
+	}
+}
+
+var errUsage = errors.New("invalid usage")
+
+// admin runs the commands. The backend is only opened by the commands that
+// need it.
+type admin struct {
+	auth        *application.AuthService
+	tenantsDir  string
+	webhooksDir string
+	backend     string
+	target      string
+	keyFile     string
+}
+
+func (a *admin) run(args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	command, args := args[0]+" "+args[1], args[2:]
+	switch {
+	case command == "encryption keygen":
+		if len(args) != 1 {
+			return errUsage
+		}
+		if err := database.GenerateKeyFile(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote a new master key to %s. Keep a copy somewhere safe: data encrypted under it is lost without it.\n", args[0])
+		return nil
+	case strings.HasPrefix(command, "encryption "):
+		db, closeDB, err := a.openEncrypted()
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+		return runEncryption(db, command, args)
+	case strings.HasPrefix(command, "tenant "):
+		db, closeDB, err := a.openData()
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+		tenants := application.NewTenantManager(database.NewFileSystemDatabase(a.tenantsDir), database.Tenants(db))
+		defer tenants.Close()
+		return runTenant(tenants, command, args)
+	case strings.HasPrefix(command, "webhook "):
+		db, closeDB, err := a.openData()
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+		webhooks, err := application.NewWebhookDispatcher(application.NewPhonebookService(db), database.NewFileSystemDatabase(a.webhooksDir))
+		if err != nil {
+			return err
+		}
+		return runWebhook(webhooks, command, args)
+	}
+	auth := a.auth
+
+	switch command {
+	case "user add":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		role := flags.String("role", "", "role on every contact")
+		tenant := flags.String("tenant", "", "tenant the user is confined to")
+		name, err := parseWithName(flags, args)
+		if err != nil {
+			return err
+		}
+		return check(auth.CreateUser(domain.User{Name: name, Role: domain.Role(*role), Tenant: *tenant}))
+	case "user list":
+		success, message, users := auth.ListUsers()
+		if !success {
+			return errors.New(message)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tROLE\tGROUPS\tTENANT\tDISABLED")
+		for _, user := range users {
+			groups := make([]string, 0, len(user.Groups))
+			for group, role := range user.Groups {
+				groups = append(groups, group+"="+string(role))
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%v\n", user.Name, user.Role, strings.Join(groups, ","), user.Tenant, user.Disabled)
+		}
+		return w.Flush()
+	case "user delete":
+		if len(args) != 1 {
+			return errUsage
+		}
+		return check(auth.DeleteUser(args[0]))
+	case "user role":
+		if len(args) != 2 {
+			return errUsage
+		}
+		return check(auth.SetRole(args[0], parseRole(args[1])))
+	case "user grant":
+		if len(args) != 3 {
+			return errUsage
+		}
+		return check(auth.GrantGroup(args[0], args[1], parseRole(args[2])))
+	case "user disable", "user enable":
+		if len(args) != 1 {
+			return errUsage
+		}
+		return check(auth.SetDisabled(args[0], command == "user disable"))
+	case "key create":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		label := flags.String("name", "", "label to tell keys apart")
+		user, err := parseWithName(flags, args)
+		if err != nil {
+			return err
+		}
+		success, message, key, record := auth.CreateAPIKey(user, *label)
+		if !success {
+			return errors.New(message)
+		}
+		fmt.Printf("Created key %s for %s. Store it now, it is not shown again:\n%s\n", record.ID, user, key)
+		return nil
+	case "key list":
+		user := ""
+		if len(args) > 0 {
+			user = args[0]
+		}
+		success, message, keys := auth.ListAPIKeys(user)
+		if !success {
+			return errors.New(message)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tUSER\tNAME\tCREATED\tLAST USED")
+		for _, key := range keys {
+			lastUsed := "never"
+			if !key.LastUsedAt.IsZero() {
+				lastUsed = key.LastUsedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", key.ID, key.User, key.Name, key.CreatedAt.Format(time.RFC3339), lastUsed)
+		}
+		return w.Flush()
+	case "key revoke":
+		if len(args) != 1 {
+			return errUsage
+		}
+		return check(auth.RevokeAPIKey(args[0]))
+	case "token issue":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		ttl := flags.Duration("ttl", 24*time.Hour, "how long the token is valid")
+		user, err := parseWithName(flags, args)
+		if err != nil {
+			return err
+		}
+		success, message, token := auth.IssueToken(user, *ttl)
+		if !success {
+			return errors.New(message)
+		}
+		fmt.Println(token)
+		return nil
+	}
+	return errUsage
+}
+
+func runTenant(tenants *application.TenantManager, command string, args []string) error {
+	switch command {
+	case "tenant create":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		name := flags.String("name", "", "display name")
+		maxContacts := flags.Int("max-contacts", 0, "most contacts the tenant may hold, 0 for no limit")
+		id, err := parseWithName(flags, args)
+		if err != nil {
+			return err
+		}
+		return check(tenants.CreateTenant(domain.Tenant{ID: id, Name: *name, MaxContacts: *maxContacts}))
+	case "tenant list":
+		success, message, list := tenants.ListTenants()
+		if !success {
+			return errors.New(message)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tMAX CONTACTS\tCREATED")
+		for _, tenant := range list {
+			quota := "unlimited"
+			if tenant.MaxContacts > 0 {
+				quota = strconv.Itoa(tenant.MaxContacts)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", tenant.ID, tenant.Name, quota, tenant.CreatedAt.Format(time.RFC3339))
+		}
+		return w.Flush()
+	case "tenant quota":
+		if len(args) != 2 {
+			return errUsage
+		}
+		maxContacts, err := strconv.Atoi(args[1])
+		if err != nil {
+			return errUsage
+		}
+		return check(tenants.SetQuota(args[0], maxContacts))
+	case "tenant delete":
+		if len(args) != 1 {
+			return errUsage
+		}
+		return check(tenants.DeleteTenant(args[0]))
+	}
+	return errUsage
+}
+
+func runWebhook(webhooks *application.WebhookDispatcher, command string, args []string) error {
+	switch command {
+	case "webhook add":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		events := flags.String("events", "", "comma-separated event types to deliver, all when empty")
+		group := flags.String("group", "", "only deliver events of this group's members")
+		secret := flags.String("secret", "", "signing secret, generated when empty")
+		url, err := parseWithName(flags, args)
+		if err != nil {
+			return err
+		}
+		hook := domain.Webhook{URL: url, Secret: *secret, Group: *group}
+		if *events != "" {
+			for _, event := range strings.Split(*events, ",") {
+				hook.Events = append(hook.Events, domain.ContactEventType(event))
+			}
+		}
+		success, message, hook := webhooks.RegisterWebhook(hook)
+		if !success {
+			return errors.New(message)
+		}
+		fmt.Printf("Registered webhook %s. Deliveries are signed with this secret, it is not shown again:\n%s\n", hook.ID, hook.Secret)
+		return nil
+	case "webhook list":
+		success, message, hooks := webhooks.ListWebhooks()
+		if !success {
+			return errors.New(message)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tURL\tEVENTS\tGROUP\tCREATED")
+		for _, hook := range hooks {
+			events := "all"
+			if len(hook.Events) > 0 {
+				names := make([]string, len(hook.Events))
+				for i, event := range hook.Events {
+					names[i] = string(event)
+				}
+				events = strings.Join(names, ",")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", hook.ID, hook.URL, events, hook.Group, hook.CreatedAt.Format(time.RFC3339))
+		}
+		return w.Flush()
+	case "webhook delete":
+		if len(args) != 1 {
+			return errUsage
+		}
+		return check(webhooks.DeleteWebhook(args[0]))
+	}
+	return errUsage
+}
+
+func runEncryption(db *database.EncryptedDatabase, command string, args []string) error {
+	switch command {
+	case "encryption rotate":
+		success, message, id := db.RotateKey()
+		if !success {
+			return errors.New(message)
+		}
+		fmt.Printf("New writes use data key %s. Run encryption reencrypt to move existing contacts to it.\n", id)
+		return nil
+	case "encryption reencrypt":
+		success, message, rewritten := db.Reencrypt()
+		if !success {
+			return errors.New(message)
+		}
+		fmt.Printf("Re-encrypted %d records\n", rewritten)
+		return nil
+	case "encryption rewrap":
+		if len(args) != 1 {
+			return errUsage
+		}
+		masterKey, err := database.LoadKeyFile(args[0])
+		if err != nil {
+			return err
+		}
+		if err := check(db.RotateMasterKey(masterKey)); err != nil {
+			return err
+		}
+		fmt.Printf("Data keys are now wrapped by %s. Retire the old keyfile.\n", args[0])
+		return nil
+	}
+	return errUsage
+}
+
+func (a *admin) openData() (ports.Database, func() error, error) {
+	if a.keyFile != "" {
+		return a.openEncrypted()
+	}
+	return database.Open(a.backend, a.target)
+}
+
+func (a *admin) openEncrypted() (*database.EncryptedDatabase, func() error, error) {
+	if a.keyFile == "" {
+		return nil, nil, errors.New("encryption commands need -keyfile")
+	}
+	masterKey, err := database.LoadKeyFile(a.keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, closeDB, err := database.Open(a.backend, a.target)
+	if err != nil {
+		return nil, nil, err
+	}
+	encrypted, err := database.NewEncryptedDatabase(db, masterKey, application.SensitiveFields, database.WithBlindIndex("phone", application.NormalizePhone))
+	if err != nil {
+		closeDB()
+		return nil, nil, err
+	}
+	return encrypted, closeDB, nil
+}
+
+// parseWithName parses flags given before or after a single name argument.
+func parseWithName(flags *flag.FlagSet, args []string) (string, error) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		args = append(args[1:], args[0])
+	}
+	if err := flags.Parse(args); err != nil {
+		return "", errUsage
+	}
+	if flags.NArg() != 1 {
+		return "", errUsage
+	}
+	return flags.Arg(0), nil
+}
+
+// parseRole reads a role argument, where "none" removes the role.
+func parseRole(arg string) domain.Role {
+	if arg == "none" {
+		return ""
+	}
+	return domain.Role(arg)
+}
+
+func check(success bool, message string, _ ...interface{}) error {
+	if !success {
+		return errors.New(message)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Businge931/practice-interfaces/internal/adoptors/database"
+	"github.com/Businge931/practice-interfaces/internal/adoptors/tui"
+	"github.com/Businge931/practice-interfaces/internal/application"
+)
+
+func main() {
+	backend := flag.String("backend", database.BackendFileSystem, "database backend: memory, filesystem, postgres or mongodb")
+	target := flag.String("target", "data", "directory, DSN or URI of the database")
+	authDir := flag.String("auth", "", "directory of the users and API keys; enables authorization when set")
+	credential := flag.String("key", "", "API key or token to act with when -auth is set; read from $PHONEBOOK_API_KEY if empty")
+	refresh := flag.Duration("refresh", tui.DefaultRefreshInterval, "reload interval for databases that cannot be watched")
+	flag.Parse()
+
+	db, closeDB, err := database.Open(*backend, *target)
+	if err != nil {
+		log.Fatalf("Failed to open the %s database: %v", *backend, err)
+	}
+	defer closeDB()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	phonebook := application.NewPhonebookService(db)
+	if *authDir != "" {
+		if *credential == "" {
+			*credential = os.Getenv("PHONEBOOK_API_KEY")
+		}
+		auth := application.NewAuthService(database.NewFileSystemDatabase(*authDir))
+		success, message, actor := auth.Authenticate(*credential)
+		if !success {
+			log.Fatalf("Failed to authenticate: %s", message)
+		}
+		phonebook = application.NewPhonebookService(db, application.WithAuthorization()).As(application.ContextWithActor(ctx, actor))
+	}
+
+	app := tui.NewApp(phonebook, *refresh)
+	if err := app.Run(ctx); err != nil {
+		log.Errorf("Terminal UI stopped: %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Businge931/practice-interfaces/internal/adoptors/database"
+	"github.com/Businge931/practice-interfaces/internal/adoptors/webui"
+	"github.com/Businge931/practice-interfaces/internal/application"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	backend := flag.String("backend", database.BackendFileSystem, "database backend: memory, filesystem, postgres or mongodb")
+	target := flag.String("target", "data", "directory, DSN or URI of the database")
+	authDir := flag.String("auth", "", "directory of the users and API keys; enables authorization when set")
+	flag.Parse()
+
+	db, closeDB, err := database.Open(*backend, *target)
+	if err != nil {
+		log.Fatalf("Failed to open the %s database: %v", *backend, err)
+	}
+	defer closeDB()
+
+	var options []application.Option
+	var handlerOptions []webui.Option
+	if *authDir != "" {
+		auth := application.NewAuthService(database.NewFileSystemDatabase(*authDir))
+		options = append(options, application.WithAuthorization())
+		handlerOptions = append(handlerOptions, webui.WithAuth(auth))
+	}
+
+	handler := webui.NewHandler(application.NewPhonebookService(db, options...), handlerOptions...)
+	log.Printf("Serving the web UI on %s", *addr)
+	if err := http.ListenAndServe(*addr, handler); err != nil {
+		log.Errorf("Web server stopped: %v", err)
+	}
+}
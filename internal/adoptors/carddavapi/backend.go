@@ -0,0 +1,349 @@
+package carddavapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/emersion/go-vcard"
+	"github.com/emersion/go-webdav"
+	"github.com/emersion/go-webdav/carddav"
+
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+// Every client sees the same principal. Address books live under the home
+// set: one holding every contact and one per group.
+const (
+	principalPath = "/me/"
+	homeSetPath   = "/me/books/"
+	allContacts   = "~all"
+	cardExt       = ".vcf"
+)
+
+// backend implements carddav.Backend on top of the phonebook service.
+type backend struct {
+	phonebook *application.PhonebookService
+	// authenticated binds every call to the caller on its context.
+	authenticated bool
+}
+
+var _ carddav.Backend = (*backend)(nil)
+
+// service returns the phonebook acting for the caller of the request.
+func (b *backend) service(ctx context.Context) *application.PhonebookService {
+	if !b.authenticated {
+		return b.phonebook
+	}
+	return b.phonebook.As(ctx)
+}
+
+func (b *backend) CurrentUserPrincipal(ctx context.Context) (string, error) {
+	return principalPath, nil
+}
+
+func (b *backend) AddressBookHomeSetPath(ctx context.Context) (string, error) {
+	return homeSetPath, nil
+}
+
+func (b *backend) ListAddressBooks(ctx context.Context) ([]carddav.AddressBook, error) {
+	books := []carddav.AddressBook{addressBook("")}
+
+	success, message, groups := b.service(ctx).ListGroups()
+	if !success && message != domain.ErrGroupsNotSupported.Error() {
+		return nil, httpError(message)
+	}
+	for _, group := range groups {
+		books = append(books, addressBook(group))
+	}
+	return books, nil
+}
+
+func (b *backend) GetAddressBook(ctx context.Context, bookPath string) (*carddav.AddressBook, error) {
+	group, _, err := parsePath(bookPath)
+	if err != nil {
+		return nil, err
+	}
+	if group != "" {
+		if success, message, _ := b.service(ctx).GetGroup(group); !success {
+			return nil, httpError(message)
+		}
+	}
+	book := addressBook(group)
+	return &book, nil
+}
+
+func (b *backend) CreateAddressBook(ctx context.Context, book *carddav.AddressBook) error {
+	group, _, err := parsePath(book.Path)
+	if err != nil {
+		return err
+	}
+	if group == "" {
+		return webdav.NewHTTPError(http.StatusMethodNotAllowed, errors.New("address book already exists"))
+	}
+	if success, message := b.service(ctx).CreateGroup(group); !success {
+		return httpError(message)
+	}
+	return nil
+}
+
+func (b *backend) DeleteAddressBook(ctx context.Context, bookPath string) error {
+	group, _, err := parsePath(bookPath)
+	if err != nil {
+		return err
+	}
+	if group == "" {
+		return webdav.NewHTTPError(http.StatusForbidden, errors.New("the address book of all contacts cannot be deleted"))
+	}
+	if success, message := b.service(ctx).DeleteGroup(group); !success {
+		return httpError(message)
+	}
+	return nil
+}
+
+func (b *backend) GetAddressObject(ctx context.Context, objectPath string, req *carddav.AddressDataRequest) (*carddav.AddressObject, error) {
+	group, id, err := parsePath(objectPath)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, webdav.NewHTTPError(http.StatusNotFound, domain.ErrContactNotFound)
+	}
+
+	success, message, contact := b.service(ctx).GetContact(id)
+	if !success {
+		return nil, httpError(message)
+	}
+	if group != "" && !b.isMember(ctx, group, id) {
+		return nil, webdav.NewHTTPError(http.StatusNotFound, domain.ErrContactNotFound)
+	}
+	return addressObject(group, id, contact), nil
+}
+
+func (b *backend) ListAddressObjects(ctx context.Context, bookPath string, req *carddav.AddressDataRequest) ([]carddav.AddressObject, error) {
+	group, _, err := parsePath(bookPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var success bool
+	var message string
+	var phonebook *domain.Phonebook
+	if group == "" {
+		success, message, phonebook = b.service(ctx).ListContacts("")
+	} else {
+		success, message, phonebook = b.service(ctx).ListGroupContacts(group)
+	}
+	if !success {
+		return nil, httpError(message)
+	}
+
+	ids := make([]string, 0, len(phonebook.Contacts))
+	for id := range phonebook.Contacts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	objects := make([]carddav.AddressObject, 0, len(ids))
+	for _, id := range ids {
+		objects = append(objects, *addressObject(group, id, phonebook.Contacts[id]))
+	}
+	return objects, nil
+}
+
+func (b *backend) QueryAddressObjects(ctx context.Context, bookPath string, query *carddav.AddressBookQuery) ([]carddav.AddressObject, error) {
+	objects, err := b.ListAddressObjects(ctx, bookPath, &query.DataRequest)
+	if err != nil {
+		return nil, err
+	}
+	return carddav.Filter(query, objects)
+}
+
+// PutAddressObject creates or updates the contact named by the path. Cards
+// put into a group's address book are added to the group.
+func (b *backend) PutAddressObject(ctx context.Context, objectPath string, card vcard.Card, opts *carddav.PutAddressObjectOptions) (*carddav.AddressObject, error) {
+	group, id, err := parsePath(objectPath)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, webdav.NewHTTPError(http.StatusForbidden, domain.ErrMissingContactID)
+	}
+
+	exists, _, current := b.service(ctx).GetContact(id)
+	if err := checkPreconditions(exists, current, opts); err != nil {
+		return nil, err
+	}
+
+	contact := application.ContactFromCard(card)
+	var success bool
+	var message string
+	if exists {
+		success, message = b.service(ctx).UpdateContact(id, contact)
+	} else {
+		success, message = b.service(ctx).AddContact(id, contact)
+	}
+	if !success {
+		return nil, httpError(message)
+	}
+
+	if group != "" {
+		if success, message := b.service(ctx).AddToGroup(group, id); !success {
+			return nil, httpError(message)
+		}
+	}
+	return addressObject(group, id, contact), nil
+}
+
+// DeleteAddressObject moves the contact to the trash, or only removes it from
+// the group when deleted from a group's address book.
+func (b *backend) DeleteAddressObject(ctx context.Context, objectPath string) error {
+	group, id, err := parsePath(objectPath)
+	if err != nil {
+		return err
+	}
+
+	var success bool
+	var message string
+	if group == "" {
+		success, message = b.service(ctx).DeleteContact(id)
+	} else {
+		success, message = b.service(ctx).RemoveFromGroup(group, id)
+	}
+	if !success {
+		return httpError(message)
+	}
+	return nil
+}
+
+func (b *backend) isMember(ctx context.Context, group, id string) bool {
+	success, _, groups := b.service(ctx).GroupsOfContact(id)
+	if !success {
+		return false
+	}
+	for _, name := range groups {
+		if name == group {
+			return true
+		}
+	}
+	return false
+}
+
+func checkPreconditions(exists bool, current domain.Contact, opts *carddav.PutAddressObjectOptions) error {
+	if opts == nil {
+		return nil
+	}
+	failed := webdav.NewHTTPError(http.StatusPreconditionFailed, errors.New("precondition failed"))
+
+	if opts.IfNoneMatch.IsWildcard() && exists {
+		return failed
+	}
+	if opts.IfMatch.IsSet() {
+		if !exists {
+			return failed
+		}
+		if !opts.IfMatch.IsWildcard() {
+			want, err := opts.IfMatch.ETag()
+			if err != nil || want != etag(current) {
+				return failed
+			}
+		}
+	}
+	return nil
+}
+
+func addressBook(group string) carddav.AddressBook {
+	book := carddav.AddressBook{
+		Path: bookPath(group),
+		Name: group,
+	}
+	if group == "" {
+		book.Name = "All contacts"
+	}
+	return book
+}
+
+func addressObject(group, id string, contact domain.Contact) *carddav.AddressObject {
+	return &carddav.AddressObject{
+		Path: objectPath(group, id),
+		ETag: etag(contact),
+		Card: application.CardFromContact(id, contact),
+	}
+}
+
+func bookPath(group string) string {
+	if group == "" {
+		return homeSetPath + allContacts + "/"
+	}
+	return homeSetPath + escapeName(group) + "/"
+}
+
+func objectPath(group, id string) string {
+	return bookPath(group) + escapeName(id) + cardExt
+}
+
+// parsePath returns the group and contact id named by a path under the home
+// set. The group is empty for the address book of all contacts and the id is
+// empty for address book paths.
+func parsePath(p string) (string, string, error) {
+	rest, ok := strings.CutPrefix(path.Clean(p)+"/", homeSetPath)
+	if !ok || rest == "" {
+		return "", "", webdav.NewHTTPError(http.StatusNotFound, errors.New("not an address book path"))
+	}
+
+	parts := strings.Split(strings.TrimSuffix(rest, "/"), "/")
+	if len(parts) > 2 {
+		return "", "", webdav.NewHTTPError(http.StatusNotFound, errors.New("not an address book path"))
+	}
+
+	group := ""
+	if parts[0] != allContacts {
+		if group, ok = unescapeName(parts[0]); !ok {
+			return "", "", webdav.NewHTTPError(http.StatusNotFound, errors.New("invalid address book name"))
+		}
+	}
+	if len(parts) == 1 {
+		return group, "", nil
+	}
+
+	id, ok := unescapeName(strings.TrimSuffix(parts[1], cardExt))
+	if !ok {
+		return "", "", webdav.NewHTTPError(http.StatusNotFound, errors.New("invalid address object name"))
+	}
+	return group, id, nil
+}
+
+// Contact ids and group names may contain slashes, which cannot appear in a
+// path segment. They are escaped as "~s", and "~" itself as "~~".
+var nameEscaper = strings.NewReplacer("~", "~~", "/", "~s")
+
+func escapeName(name string) string {
+	return nameEscaper.Replace(name)
+}
+
+func unescapeName(escaped string) (string, bool) {
+	var name strings.Builder
+	for i := 0; i < len(escaped); i++ {
+		if escaped[i] != '~' {
+			name.WriteByte(escaped[i])
+			continue
+		}
+		if i+1 == len(escaped) {
+			return "", false
+		}
+		i++
+		switch escaped[i] {
+		case '~':
+			name.WriteByte('~')
+		case 's':
+			name.WriteByte('/')
+		default:
+			return "", false
+		}
+	}
+	return name.String(), true
+}
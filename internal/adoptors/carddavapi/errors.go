@@ -0,0 +1,39 @@
+package carddavapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/emersion/go-webdav"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+// statusCodes maps the messages returned by the service and the database
+// adapters to HTTP status codes. Anything else is reported as 500.
+var statusCodes = map[string]int{
+	domain.ErrContactNotFound.Error():      http.StatusNotFound,
+	"Location does not exist":              http.StatusNotFound,
+	"File does not exist":                  http.StatusNotFound,
+	"Group does not exist":                 http.StatusNotFound,
+	domain.ErrContactExists.Error():        http.StatusConflict,
+	domain.ErrContactInTrash.Error():       http.StatusConflict,
+	"Location already exists":              http.StatusConflict,
+	"File already exists":                  http.StatusConflict,
+	"Group already exists":                 http.StatusMethodNotAllowed,
+	domain.ErrInvalidContactName.Error():   http.StatusBadRequest,
+	domain.ErrInvalidContactNumber.Error(): http.StatusBadRequest,
+	domain.ErrReservedContactID.Error():    http.StatusBadRequest,
+	domain.ErrInvalidGroupName.Error():     http.StatusBadRequest,
+	domain.ErrGroupsNotSupported.Error():   http.StatusNotImplemented,
+	domain.ErrUnauthenticated.Error():      http.StatusUnauthorized,
+	domain.ErrPermissionDenied.Error():     http.StatusForbidden,
+}
+
+func httpError(message string) error {
+	code, ok := statusCodes[message]
+	if !ok {
+		code = http.StatusInternalServerError
+	}
+	return webdav.NewHTTPError(code, errors.New(message))
+}
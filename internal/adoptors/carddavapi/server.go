@@ -0,0 +1,114 @@
+// Package carddavapi serves the phonebook over CardDAV (RFC 6352), so phones
+// and mail clients can sync contacts natively. Groups are exposed as address
+// books next to one holding every contact.
+package carddavapi
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+
+	"github.com/emersion/go-webdav"
+	"github.com/emersion/go-webdav/carddav"
+
+	"github.com/Businge931/practice-interfaces/internal/adoptors/httpauth"
+	"github.com/Businge931/practice-interfaces/internal/application"
+)
+
+type Handler struct {
+	backend *backend
+	carddav *carddav.Handler
+	sync    *syncTokens
+	auth    *application.AuthService
+	handler http.Handler
+}
+
+type Option func(*Handler)
+
+// WithAuth makes every request authenticate against auth and act for the
+// caller, so a phonebook with authorization enabled checks what they may do.
+// Clients send an API key or a token as their password.
+func WithAuth(auth *application.AuthService) Option {
+	return func(h *Handler) {
+		h.auth = auth
+	}
+}
+
+func NewHandler(phonebook *application.PhonebookService, opts ...Option) *Handler {
+	b := &backend{phonebook: phonebook}
+	h := &Handler{
+		backend: b,
+		carddav: &carddav.Handler{Backend: b},
+		sync:    newSyncTokens(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.handler = http.HandlerFunc(h.serve)
+	if h.auth != nil {
+		b.authenticated = true
+		h.handler = httpauth.Handler(h.auth, "phonebook", h.handler)
+	}
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.ServeHTTP(w, r)
+}
+
+// serve handles sync-collection reports and conditional deletes itself and
+// passes every other request to the CardDAV handler.
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "REPORT":
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if isSyncCollection(body) {
+			h.serveSyncCollection(w, r, body)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	case http.MethodDelete:
+		if !h.deletePreconditionMet(r) {
+			http.Error(w, "precondition failed", http.StatusPreconditionFailed)
+			return
+		}
+	}
+	h.carddav.ServeHTTP(w, r)
+}
+
+// deletePreconditionMet honours If-Match on address objects. Missing objects
+// are left for the CardDAV handler to report.
+func (h *Handler) deletePreconditionMet(r *http.Request) bool {
+	ifMatch := webdav.ConditionalMatch(r.Header.Get("If-Match"))
+	if !ifMatch.IsSet() || ifMatch.IsWildcard() {
+		return true
+	}
+	if _, id, err := parsePath(r.URL.Path); err != nil || id == "" {
+		return true
+	}
+
+	object, err := h.backend.GetAddressObject(r.Context(), r.URL.Path, &carddav.AddressDataRequest{})
+	if err != nil {
+		return true
+	}
+	want, err := ifMatch.ETag()
+	return err == nil && want == object.ETag
+}
+
+func isSyncCollection(body []byte) bool {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Space == "DAV:" && start.Name.Local == "sync-collection"
+		}
+	}
+}
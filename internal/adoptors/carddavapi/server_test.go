@@ -0,0 +1,315 @@
+package carddavapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-vcard"
+	"github.com/emersion/go-webdav"
+	"github.com/emersion/go-webdav/carddav"
+
+	"github.com/Businge931/practice-interfaces/internal/adoptors/database"
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+var testContact = domain.Contact{
+	Name:    "John Doe",
+	Phone:   "123-456-7890",
+	Email:   "john@example.com",
+	Address: "123 Main St",
+}
+
+func setupCardDAVTest(t *testing.T) (*application.PhonebookService, *carddav.Client, *httptest.Server) {
+	phonebook := application.NewPhonebookService(database.NewInMemoryDatabase())
+	server := httptest.NewServer(NewHandler(phonebook))
+	t.Cleanup(server.Close)
+
+	client, err := carddav.NewClient(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return phonebook, client, server
+}
+
+func TestHandler_Discovery(t *testing.T) {
+	phonebook, client, _ := setupCardDAVTest(t)
+	phonebook.CreateGroup("family/close")
+	ctx := context.Background()
+
+	homeSet, err := client.FindAddressBookHomeSet(ctx, principalPath)
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if homeSet != homeSetPath {
+		t.Errorf("Expected home set %q but got %q", homeSetPath, homeSet)
+	}
+
+	books, err := client.FindAddressBooks(ctx, homeSet)
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	paths := map[string]bool{}
+	for _, book := range books {
+		paths[book.Path] = true
+	}
+	for _, expected := range []string{"/me/books/~all/", "/me/books/family~sclose/"} {
+		if !paths[expected] {
+			t.Errorf("Expected address book %q in %v", expected, paths)
+		}
+	}
+}
+
+func TestHandler_PutGetQuery(t *testing.T) {
+	phonebook, client, _ := setupCardDAVTest(t)
+	phonebook.AddContact("contacts/jane", domain.Contact{Name: "Jane Roe", Phone: "555-000-1111", Email: "jane@example.org"})
+	ctx := context.Background()
+
+	path := objectPath("", "contacts/john")
+	object, err := client.PutAddressObject(ctx, path, application.CardFromContact("contacts/john", testContact))
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if object.ETag != etag(testContact) {
+		t.Errorf("Expected etag %q but got %q", etag(testContact), object.ETag)
+	}
+	if _, _, contact := phonebook.GetContact("contacts/john"); !reflect.DeepEqual(contact, testContact) {
+		t.Errorf("Expected contact %+v but got %+v", testContact, contact)
+	}
+
+	object, err = client.GetAddressObject(ctx, path)
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if contact := application.ContactFromCard(object.Card); !reflect.DeepEqual(contact, testContact) {
+		t.Errorf("Expected contact %+v but got %+v", testContact, contact)
+	}
+
+	objects, err := client.QueryAddressBook(ctx, bookPath(""), &carddav.AddressBookQuery{
+		DataRequest: carddav.AddressDataRequest{AllProp: true},
+		PropFilters: []carddav.PropFilter{{
+			Name:        vcard.FieldEmail,
+			TextMatches: []carddav.TextMatch{{Text: "example.com"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Path != path {
+		t.Errorf("Expected only %q to match but got %+v", path, objects)
+	}
+}
+
+func TestHandler_CustomFields(t *testing.T) {
+	phonebook, client, _ := setupCardDAVTest(t)
+	phonebook.DefineField(domain.FieldDefinition{Name: "employee_id", Type: domain.FieldNumber})
+	ctx := context.Background()
+
+	contact := testContact
+	contact.Custom = map[string]string{"employee_id": "42"}
+	path := objectPath("", "contacts/john")
+	if _, err := client.PutAddressObject(ctx, path, application.CardFromContact("contacts/john", contact)); err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+
+	object, err := client.GetAddressObject(ctx, path)
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if value := object.Card.Value("X-PHONEBOOK-EMPLOYEE-ID"); value != "42" {
+		t.Errorf("Expected X-PHONEBOOK-EMPLOYEE-ID 42 but got %q", value)
+	}
+
+	// Clients that drop unknown properties keep the stored value
+	if _, err := client.PutAddressObject(ctx, path, application.CardFromContact("contacts/john", testContact)); err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if _, _, stored := phonebook.GetContact("contacts/john"); !reflect.DeepEqual(stored, contact) {
+		t.Errorf("Expected contact %+v but got %+v", contact, stored)
+	}
+}
+
+func TestHandler_GroupAddressBook(t *testing.T) {
+	phonebook, client, _ := setupCardDAVTest(t)
+	phonebook.CreateGroup("family")
+	ctx := context.Background()
+
+	path := objectPath("family", "contacts/john")
+	if _, err := client.PutAddressObject(ctx, path, application.CardFromContact("contacts/john", testContact)); err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if _, _, groups := phonebook.GroupsOfContact("contacts/john"); len(groups) != 1 || groups[0] != "family" {
+		t.Errorf("Expected contact to join the group but got %v", groups)
+	}
+
+	// Deleting from a group's address book only leaves the group
+	if err := client.RemoveAll(ctx, path); err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if _, _, groups := phonebook.GroupsOfContact("contacts/john"); len(groups) != 0 {
+		t.Errorf("Expected no groups but got %v", groups)
+	}
+	if success, _, _ := phonebook.GetContact("contacts/john"); !success {
+		t.Error("Expected contact to be kept")
+	}
+	if _, err := client.GetAddressObject(ctx, path); err == nil {
+		t.Error("Expected contact to be gone from the group's address book")
+	}
+}
+
+func TestHandler_Preconditions(t *testing.T) {
+	phonebook, _, server := setupCardDAVTest(t)
+	phonebook.AddContact("contacts/john", testContact)
+	url := server.URL + objectPath("", "contacts/john")
+
+	var card strings.Builder
+	vcard.NewEncoder(&card).Encode(application.CardFromContact("contacts/john", testContact))
+
+	tests := []struct {
+		name     string
+		method   string
+		header   string
+		value    string
+		expected int
+	}{
+		{"put stale etag", http.MethodPut, "If-Match", `"stale"`, http.StatusPreconditionFailed},
+		{"put if none match", http.MethodPut, "If-None-Match", "*", http.StatusPreconditionFailed},
+		{"put current etag", http.MethodPut, "If-Match", `"` + etag(testContact) + `"`, http.StatusCreated},
+		{"delete stale etag", http.MethodDelete, "If-Match", `"stale"`, http.StatusPreconditionFailed},
+		{"delete current etag", http.MethodDelete, "If-Match", `"` + etag(testContact) + `"`, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, url, strings.NewReader(card.String()))
+			req.Header.Set("Content-Type", vcard.MIMEType)
+			req.Header.Set(tt.header, tt.value)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.expected {
+				t.Errorf("Expected status %d but got %d", tt.expected, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestHandler_SyncCollection(t *testing.T) {
+	phonebook, client, _ := setupCardDAVTest(t)
+	phonebook.AddContact("contacts/john", testContact)
+	phonebook.AddContact("contacts/jane", domain.Contact{Name: "Jane Roe", Phone: "555-000-1111"})
+	ctx := context.Background()
+	book := bookPath("")
+
+	initial, err := client.SyncCollection(ctx, book, &carddav.SyncQuery{})
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if len(initial.Updated) != 2 || len(initial.Deleted) != 0 || initial.SyncToken == "" {
+		t.Fatalf("Expected 2 updated cards and a token but got %+v", initial)
+	}
+
+	phonebook.UpdateContact("contacts/john", domain.Contact{Name: "John Doe", Phone: "999"})
+	phonebook.DeleteContact("contacts/jane")
+	phonebook.AddContact("contacts/bob", domain.Contact{Name: "Bob Poe", Phone: "777"})
+
+	changes, err := client.SyncCollection(ctx, book, &carddav.SyncQuery{SyncToken: initial.SyncToken})
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	updated := map[string]bool{}
+	for _, object := range changes.Updated {
+		updated[object.Path] = true
+	}
+	if len(updated) != 2 || !updated[objectPath("", "contacts/john")] || !updated[objectPath("", "contacts/bob")] {
+		t.Errorf("Expected john and bob to be updated but got %v", updated)
+	}
+	if len(changes.Deleted) != 1 || changes.Deleted[0] != objectPath("", "contacts/jane") {
+		t.Errorf("Expected jane to be deleted but got %v", changes.Deleted)
+	}
+
+	// Nothing changed since the last token
+	unchanged, err := client.SyncCollection(ctx, book, &carddav.SyncQuery{SyncToken: changes.SyncToken})
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if len(unchanged.Updated) != 0 || len(unchanged.Deleted) != 0 {
+		t.Errorf("Expected no changes but got %+v", unchanged)
+	}
+
+	if _, err := client.SyncCollection(ctx, book, &carddav.SyncQuery{SyncToken: "urn:x-phonebook:sync:unknown"}); err == nil {
+		t.Error("Expected an unknown token to be rejected")
+	}
+}
+
+func TestEscapeName(t *testing.T) {
+	tests := []struct {
+		name    string
+		escaped string
+	}{
+		{"contacts/john", "contacts~sjohn"},
+		{"a~b", "a~~b"},
+		{"~s/", "~~s~s"},
+		{"plain", "plain"},
+	}
+	for _, tt := range tests {
+		if escaped := escapeName(tt.name); escaped != tt.escaped {
+			t.Errorf("escapeName(%q): expected %q but got %q", tt.name, tt.escaped, escaped)
+		}
+		if name, ok := unescapeName(tt.escaped); !ok || name != tt.name {
+			t.Errorf("unescapeName(%q): expected %q but got %q", tt.escaped, tt.name, name)
+		}
+	}
+	for _, invalid := range []string{"~all", "trailing~"} {
+		if _, ok := unescapeName(invalid); ok {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestHandler_Auth(t *testing.T) {
+	auth := application.NewAuthService(database.NewInMemoryDatabase())
+	auth.CreateUser(domain.User{Name: "alice", Role: domain.RoleEditor})
+	auth.CreateUser(domain.User{Name: "bob", Role: domain.RoleViewer})
+	_, _, aliceKey, _ := auth.CreateAPIKey("alice", "")
+	_, _, bobKey, _ := auth.CreateAPIKey("bob", "")
+
+	phonebook := application.NewPhonebookService(database.NewInMemoryDatabase(), application.WithAuthorization())
+	admin := application.ContextWithActor(context.Background(), domain.Actor{Name: "root", Role: domain.RoleAdmin})
+	phonebook.As(admin).AddContact("contacts/john", testContact)
+	server := httptest.NewServer(NewHandler(phonebook, WithAuth(auth)))
+	t.Cleanup(server.Close)
+
+	client := func(user, key string) *carddav.Client {
+		c, err := carddav.NewClient(webdav.HTTPClientWithBasicAuth(http.DefaultClient, user, key), server.URL)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		return c
+	}
+	ctx := context.Background()
+	path := objectPath("", "contacts/john")
+	card := application.CardFromContact("contacts/john", testContact)
+
+	if _, err := client("", "").GetAddressObject(ctx, path); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected anonymous requests to be refused but got %v", err)
+	}
+
+	viewer := client("bob", bobKey)
+	if _, err := viewer.GetAddressObject(ctx, path); err != nil {
+		t.Fatalf("Expected a viewer to read contacts but got %v", err)
+	}
+	if _, err := viewer.PutAddressObject(ctx, path, card); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected a viewer to be refused writes but got %v", err)
+	}
+
+	if _, err := client("alice", aliceKey).PutAddressObject(ctx, path, card); err != nil {
+		t.Errorf("Expected an editor to write contacts but got %v", err)
+	}
+}
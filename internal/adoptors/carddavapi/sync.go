@@ -0,0 +1,171 @@
+package carddavapi
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/emersion/go-vcard"
+	"github.com/emersion/go-webdav/carddav"
+)
+
+// maxSyncTokens bounds how many address book snapshots are remembered.
+// Clients holding an older token are told to resync from scratch.
+const maxSyncTokens = 256
+
+const syncTokenPrefix = "urn:x-phonebook:sync:"
+
+// syncTokens remembers the etag of every card an address book held when each
+// token was issued, so that sync-collection (RFC 6578) can report what changed
+// since, whichever database is behind the phonebook.
+type syncTokens struct {
+	mu        sync.Mutex
+	next      int
+	snapshots map[string]syncSnapshot
+	order     []string
+}
+
+type syncSnapshot struct {
+	book  string
+	etags map[string]string
+}
+
+func newSyncTokens() *syncTokens {
+	return &syncTokens{snapshots: make(map[string]syncSnapshot)}
+}
+
+func (t *syncTokens) issue(book string, etags map[string]string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.next++
+	token := syncTokenPrefix + strconv.Itoa(t.next)
+	t.snapshots[token] = syncSnapshot{book: book, etags: etags}
+	t.order = append(t.order, token)
+	if len(t.order) > maxSyncTokens {
+		delete(t.snapshots, t.order[0])
+		t.order = t.order[1:]
+	}
+	return token
+}
+
+func (t *syncTokens) lookup(book, token string) (map[string]string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	snapshot, ok := t.snapshots[token]
+	if !ok || snapshot.book != book {
+		return nil, false
+	}
+	return snapshot.etags, true
+}
+
+type syncCollectionRequest struct {
+	XMLName   xml.Name `xml:"DAV: sync-collection"`
+	SyncToken string   `xml:"DAV: sync-token"`
+	Prop      struct {
+		AddressData *struct{} `xml:"urn:ietf:params:xml:ns:carddav address-data"`
+	} `xml:"DAV: prop"`
+}
+
+// The response is written with literal prefixes, which encoding/xml cannot
+// produce from namespaced names.
+type syncMultistatus struct {
+	XMLName   xml.Name       `xml:"D:multistatus"`
+	DAV       string         `xml:"xmlns:D,attr"`
+	CardDAV   string         `xml:"xmlns:C,attr"`
+	Responses []syncResponse `xml:"D:response"`
+	SyncToken string         `xml:"D:sync-token"`
+}
+
+type syncResponse struct {
+	Href     string        `xml:"D:href"`
+	Propstat *syncPropstat `xml:"D:propstat,omitempty"`
+	Status   string        `xml:"D:status,omitempty"`
+}
+
+type syncPropstat struct {
+	ETag        string `xml:"D:prop>D:getetag"`
+	AddressData string `xml:"D:prop>C:address-data,omitempty"`
+	Status      string `xml:"D:status"`
+}
+
+type syncError struct {
+	XMLName        xml.Name `xml:"D:error"`
+	DAV            string   `xml:"xmlns:D,attr"`
+	ValidSyncToken struct{} `xml:"D:valid-sync-token"`
+}
+
+func (h *Handler) serveSyncCollection(w http.ResponseWriter, r *http.Request, body []byte) {
+	var req syncCollectionRequest
+	if err := xml.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	group, id, err := parsePath(r.URL.Path)
+	if err != nil || id != "" {
+		http.Error(w, "sync-collection is only supported on address books", http.StatusForbidden)
+		return
+	}
+	book := bookPath(group)
+
+	objects, err := h.backend.ListAddressObjects(r.Context(), book, &carddav.AddressDataRequest{AllProp: true})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	previous := map[string]string{}
+	if req.SyncToken != "" {
+		var ok bool
+		if previous, ok = h.sync.lookup(book, req.SyncToken); !ok {
+			writeXML(w, http.StatusForbidden, syncError{DAV: "DAV:"})
+			return
+		}
+	}
+
+	current := make(map[string]string, len(objects))
+	response := syncMultistatus{DAV: "DAV:", CardDAV: "urn:ietf:params:xml:ns:carddav"}
+	for _, object := range objects {
+		current[object.Path] = object.ETag
+		if previous[object.Path] == object.ETag {
+			continue
+		}
+
+		propstat := &syncPropstat{ETag: strconv.Quote(object.ETag), Status: "HTTP/1.1 200 OK"}
+		if req.Prop.AddressData != nil {
+			var card strings.Builder
+			if err := vcard.NewEncoder(&card).Encode(object.Card); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			propstat.AddressData = card.String()
+		}
+		response.Responses = append(response.Responses, syncResponse{Href: object.Path, Propstat: propstat})
+	}
+	deleted := make([]string, 0)
+	for href := range previous {
+		if _, ok := current[href]; !ok {
+			deleted = append(deleted, href)
+		}
+	}
+	sort.Strings(deleted)
+	for _, href := range deleted {
+		response.Responses = append(response.Responses, syncResponse{Href: href, Status: "HTTP/1.1 404 Not Found"})
+	}
+
+	response.SyncToken = h.sync.issue(book, current)
+	writeXML(w, http.StatusMultiStatus, response)
+}
+
+func writeXML(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprint(w, xml.Header)
+	xml.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,25 @@
+package carddavapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+// etag identifies a version of a contact.
+func etag(contact domain.Contact) string {
+	parts := []string{contact.Name, contact.Phone, contact.Email, contact.Address}
+	names := make([]string, 0, len(contact.Custom))
+	for name := range contact.Custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, name, contact.Custom[name])
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,33 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// checkBlob returns why content cannot be stored under hash, or an empty
+// string if it can.
+func checkBlob(hash string, content []byte) string {
+	if !isBlobHash(hash) {
+		return "Invalid blob hash"
+	}
+	sum := sha256.Sum256(content)
+	if hex.EncodeToString(sum[:]) != hash {
+		return "Blob does not match its hash"
+	}
+	return ""
+}
+
+// isBlobHash reports whether hash is a lowercase hex SHA-256 hash, which
+// also keeps it safe to use as a file name.
+func isBlobHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range hash {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
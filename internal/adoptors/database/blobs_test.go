@@ -0,0 +1,82 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func blobHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// testBlobStore checks the blob operations of store, which starts empty.
+func testBlobStore(t *testing.T, store ports.BlobStore) {
+	photo := []byte("\x89PNG photo")
+	contract := []byte("%PDF contract")
+
+	tests := []struct {
+		name            string
+		hash            string
+		content         []byte
+		expectedMessage string
+	}{
+		{"store", blobHash(photo), photo, ""},
+		{"store again", blobHash(photo), photo, ""},
+		{"store another", blobHash(contract), contract, ""},
+		{"wrong hash", blobHash(photo), contract, "Blob does not match its hash"},
+		{"invalid hash", "../../etc/passwd", photo, "Invalid blob hash"},
+		{"uppercase hash", "AB" + blobHash(photo)[2:], photo, "Invalid blob hash"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			success, message := store.PutBlob(tt.hash, tt.content)
+			assert.Equal(t, tt.expectedMessage == "", success)
+			assert.Equal(t, tt.expectedMessage, message)
+		})
+	}
+
+	success, message, content := store.GetBlob(blobHash(photo))
+	require.True(t, success, message)
+	assert.Equal(t, photo, content)
+
+	success, message, hashes := store.ListBlobs()
+	require.True(t, success, message)
+	expected := []string{blobHash(photo), blobHash(contract)}
+	if expected[0] > expected[1] {
+		expected[0], expected[1] = expected[1], expected[0]
+	}
+	assert.Equal(t, expected, hashes, "identical content is stored once")
+
+	require.True(t, mustSucceed(store.DeleteBlob(blobHash(photo))))
+	success, message, _ = store.GetBlob(blobHash(photo))
+	assert.False(t, success)
+	assert.Equal(t, "Blob does not exist", message)
+	success, message = store.DeleteBlob(blobHash(photo))
+	assert.False(t, success)
+	assert.Equal(t, "Blob does not exist", message)
+}
+
+func TestInMemoryDatabase_Blobs(t *testing.T) {
+	testBlobStore(t, NewInMemoryDatabase())
+}
+
+func TestFileSystemDatabase_Blobs(t *testing.T) {
+	db := NewFileSystemDatabase(t.TempDir())
+	testBlobStore(t, db)
+
+	// Blobs are not records
+	require.True(t, mustSucceed(db.PutBlob(blobHash([]byte("photo")), []byte("photo"))))
+	require.True(t, mustSucceed(db.Create("contacts/john", map[string]interface{}{"name": "John"})))
+	_, _, locations := db.List("")
+	assert.Equal(t, []string{"contacts/john"}, locations)
+	success, message := db.Create(blobsDir+"/x", map[string]interface{}{})
+	assert.False(t, success)
+	assert.Equal(t, "Invalid location", message)
+}
@@ -0,0 +1,726 @@
+package database
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+const (
+	// encryptedValuePrefix starts every encrypted field, followed by the id of
+	// the data key and the base64 ciphertext of the JSON value.
+	encryptedValuePrefix = "enc:v1:"
+	// blindIndexFieldPrefix names the stored hash of an indexed field.
+	blindIndexFieldPrefix = "_bidx_"
+	// blindIndexPrefix holds one empty record per indexed value and location,
+	// at <prefix><field>/<hash>/<location>.
+	blindIndexPrefix = encryptionPrefix + "index/"
+)
+
+// EncryptedDatabase encrypts chosen fields of every record with AES-GCM
+// before they reach the wrapped database. Data keys are generated and kept in
+// the database, wrapped by a master key held outside it. Fields given a
+// blind index also store an HMAC of their value, so records can be found by
+// exact value without decrypting them. Blobs are sealed whole.
+//
+// Like NamespacedDatabase it supports every optional interface, reporting
+// those the wrapped database lacks as not supported.
+type EncryptedDatabase struct {
+	db      ports.Database
+	keys    *keyring
+	fields  []string
+	indexes map[string]func(string) string
+	// inTx is set on the copies running inside a transaction of db.
+	inTx bool
+}
+
+type EncryptionOption func(*EncryptedDatabase)
+
+// WithBlindIndex keeps a blind index over field. Values are passed through
+// normalize, when given, both when they are stored and when they are looked up.
+func WithBlindIndex(field string, normalize func(string) string) EncryptionOption {
+	return func(e *EncryptedDatabase) {
+		if normalize == nil {
+			normalize = func(value string) string { return value }
+		}
+		e.indexes[field] = normalize
+	}
+}
+
+func NewEncryptedDatabase(db ports.Database, masterKey []byte, fields []string, opts ...EncryptionOption) (*EncryptedDatabase, error) {
+	if len(masterKey) != MasterKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes", MasterKeySize)
+	}
+	e := &EncryptedDatabase{
+		db:      db,
+		keys:    newKeyring(db, masterKey),
+		fields:  fields,
+		indexes: make(map[string]func(string) string),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e, nil
+}
+
+func (e *EncryptedDatabase) Create(location string, data map[string]interface{}) (bool, string) {
+	if isEncryptionLocation(location) {
+		return false, "Invalid location"
+	}
+	sealed, err := e.seal(data)
+	if err != nil {
+		return false, err.Error()
+	}
+	return e.indexed(func(e *EncryptedDatabase) (bool, string) {
+		if success, message := e.db.Create(location, sealed); !success {
+			return false, message
+		}
+		return e.reindex(location, nil, sealed)
+	})
+}
+
+func (e *EncryptedDatabase) Read(location string) (bool, string, map[string]interface{}) {
+	if isEncryptionLocation(location) {
+		return false, "Invalid location", nil
+	}
+	success, message, data := e.db.Read(location)
+	if !success {
+		return false, message, nil
+	}
+	opened, err := e.open(data)
+	if err != nil {
+		return false, err.Error(), nil
+	}
+	return true, "", opened
+}
+
+func (e *EncryptedDatabase) Update(location string, data map[string]interface{}) (bool, string) {
+	if isEncryptionLocation(location) {
+		return false, "Invalid location"
+	}
+	sealed, err := e.seal(data)
+	if err != nil {
+		return false, err.Error()
+	}
+	return e.indexed(func(e *EncryptedDatabase) (bool, string) {
+		_, _, old := e.db.Read(location)
+		if success, message := e.db.Update(location, sealed); !success {
+			return false, message
+		}
+		return e.reindex(location, old, sealed)
+	})
+}
+
+func (e *EncryptedDatabase) Delete(location string) (bool, string) {
+	if isEncryptionLocation(location) {
+		return false, "Invalid location"
+	}
+	return e.indexed(func(e *EncryptedDatabase) (bool, string) {
+		_, _, old := e.db.Read(location)
+		if success, message := e.db.Delete(location); !success {
+			return false, message
+		}
+		return e.reindex(location, old, nil)
+	})
+}
+
+func (e *EncryptedDatabase) Upsert(location string, data map[string]interface{}) (bool, string, bool) {
+	return e.writeConditional(location, data, upsertRecord, true)
+}
+
+func (e *EncryptedDatabase) CreateIfAbsent(location string, data map[string]interface{}) (bool, string, bool) {
+	return e.writeConditional(location, data, createRecordIfAbsent, false)
+}
+
+func (e *EncryptedDatabase) UpdateIfExists(location string, data map[string]interface{}) (bool, string, bool) {
+	return e.writeConditional(location, data, updateRecordIfExists, false)
+}
+
+// ReadFields reads and decrypts only the given fields.
+func (e *EncryptedDatabase) ReadFields(location string, fields []string) (bool, string, map[string]interface{}) {
+	if isEncryptionLocation(location) {
+		return false, "Invalid location", nil
+	}
+	success, message, data := readFields(e.db, location, fields)
+	if !success {
+		return false, message, nil
+	}
+	opened, err := e.open(data)
+	if err != nil {
+		return false, err.Error(), nil
+	}
+	return true, "", opened
+}
+
+func (e *EncryptedDatabase) ListFields(prefix string, fields []string) (bool, string, map[string]map[string]interface{}) {
+	success, message, records := listFields(e.db, prefix, fields)
+	if !success {
+		return false, message, nil
+	}
+	opened := make(map[string]map[string]interface{}, len(records))
+	for location, data := range records {
+		if isEncryptionLocation(location) {
+			continue
+		}
+		record, err := e.open(data)
+		if err != nil {
+			return false, err.Error(), nil
+		}
+		opened[location] = record
+	}
+	return true, "", opened
+}
+
+func (e *EncryptedDatabase) List(prefix string) (bool, string, []string) {
+	success, message, locations := e.db.List(prefix)
+	if !success {
+		return false, message, nil
+	}
+	visible := make([]string, 0, len(locations))
+	for _, location := range locations {
+		if !isEncryptionLocation(location) {
+			visible = append(visible, location)
+		}
+	}
+	return true, "", visible
+}
+
+func (e *EncryptedDatabase) IndexedField(field string) bool {
+	_, ok := e.indexes[field]
+	return ok
+}
+
+// LookupField returns the locations whose field holds value, using the blind
+// index of the field.
+func (e *EncryptedDatabase) LookupField(field, value string) (bool, string, []string) {
+	normalize, ok := e.indexes[field]
+	if !ok {
+		return false, fmt.Sprintf("Field %s is not indexed", field), nil
+	}
+	hash, err := e.blindIndex(normalize(value))
+	if err != nil {
+		return false, err.Error(), nil
+	}
+	if hash == "" {
+		return true, "", []string{}
+	}
+
+	prefix := blindIndexPrefix + field + "/" + hash + "/"
+	success, message, entries := e.db.List(prefix)
+	if !success {
+		return false, message, nil
+	}
+	locations := make([]string, len(entries))
+	for i, entry := range entries {
+		locations[i] = strings.TrimPrefix(entry, prefix)
+	}
+	return true, "", locations
+}
+
+// RotateKey starts encrypting new writes with a fresh data key and returns
+// its id. Records written with older keys stay readable; Reencrypt moves them
+// to the new key.
+func (e *EncryptedDatabase) RotateKey() (bool, string, string) {
+	id, err := e.keys.rotate()
+	if err != nil {
+		return false, fmt.Sprintf("Error rotating key: %v", err), ""
+	}
+	return true, "", id
+}
+
+// Reencrypt rewrites every record not yet sealed with the active data key,
+// including records stored before encryption was enabled, then deletes the
+// data keys no record or blob uses any more. Blobs are not rewritten. It
+// returns how many records changed.
+func (e *EncryptedDatabase) Reencrypt() (bool, string, int) {
+	active, _, err := e.keys.activeKey()
+	if err != nil {
+		return false, err.Error(), 0
+	}
+
+	success, message, locations := e.List("")
+	if !success {
+		return false, message, 0
+	}
+
+	rewritten := 0
+	inUse := make(map[string]bool)
+	if _, ok := e.db.(ports.BlobStore); ok {
+		if inUse, err = e.blobKeyIDs(); err != nil {
+			return false, err.Error(), 0
+		}
+	}
+	for _, location := range locations {
+		success, message, raw := e.db.Read(location)
+		if !success {
+			return false, message, rewritten
+		}
+		if e.isCurrent(raw, active) {
+			continue
+		}
+
+		data, err := e.open(raw)
+		if err != nil {
+			// Leave the record and its key alone rather than lose it
+			for _, id := range e.keyIDs(raw) {
+				inUse[id] = true
+			}
+			continue
+		}
+		if success, message := e.Update(location, data); !success {
+			return false, message, rewritten
+		}
+		rewritten++
+	}
+
+	if _, err := e.keys.retire(inUse); err != nil {
+		return false, fmt.Sprintf("Error retiring keys: %v", err), rewritten
+	}
+	return true, "", rewritten
+}
+
+// RotateMasterKey wraps every data key with a new master key. Records are
+// not rewritten. The old master key is useless once this succeeds.
+func (e *EncryptedDatabase) RotateMasterKey(masterKey []byte) (bool, string) {
+	if err := e.keys.rewrap(masterKey); err != nil {
+		return false, fmt.Sprintf("Error rewrapping keys: %v", err)
+	}
+	return true, ""
+}
+
+func (e *EncryptedDatabase) CreateGroup(name string) (bool, string) {
+	groups, ok := e.db.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+	return groups.CreateGroup(name)
+}
+
+func (e *EncryptedDatabase) RenameGroup(name, newName string) (bool, string) {
+	groups, ok := e.db.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+	return groups.RenameGroup(name, newName)
+}
+
+func (e *EncryptedDatabase) DeleteGroup(name string) (bool, string) {
+	groups, ok := e.db.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+	return groups.DeleteGroup(name)
+}
+
+func (e *EncryptedDatabase) ListGroups() (bool, string, []string) {
+	groups, ok := e.db.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error(), nil
+	}
+	return groups.ListGroups()
+}
+
+func (e *EncryptedDatabase) AddMember(group, location string) (bool, string) {
+	groups, ok := e.db.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+	return groups.AddMember(group, location)
+}
+
+func (e *EncryptedDatabase) RemoveMember(group, location string) (bool, string) {
+	groups, ok := e.db.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+	return groups.RemoveMember(group, location)
+}
+
+func (e *EncryptedDatabase) Members(group string) (bool, string, []string) {
+	groups, ok := e.db.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error(), nil
+	}
+	return groups.Members(group)
+}
+
+func (e *EncryptedDatabase) GroupsOf(location string) (bool, string, []string) {
+	groups, ok := e.db.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error(), nil
+	}
+	return groups.GroupsOf(location)
+}
+
+// BatchCreate seals the items and writes them in one batch when the wrapped
+// database supports it.
+func (e *EncryptedDatabase) BatchRead(locations []string) (bool, string, map[string]map[string]interface{}) {
+	visible := make([]string, 0, len(locations))
+	for _, location := range locations {
+		if !isEncryptionLocation(location) {
+			visible = append(visible, location)
+		}
+	}
+	success, message, records := readRecords(e.db, visible)
+	if !success {
+		return false, message, nil
+	}
+	opened := make(map[string]map[string]interface{}, len(records))
+	for location, data := range records {
+		record, err := e.open(data)
+		if err != nil {
+			return false, err.Error(), nil
+		}
+		opened[location] = record
+	}
+	return true, "", opened
+}
+
+func (e *EncryptedDatabase) BatchCreate(items []ports.BatchItem) []ports.BatchResult {
+	batch, ok := e.db.(ports.BatchDatabase)
+	if !ok {
+		return e.each(items, e.Create)
+	}
+	sealed, failed := e.sealItems(items)
+	if failed != nil {
+		return failed
+	}
+	results := batch.BatchCreate(sealed)
+	for i, result := range results {
+		if result.Success {
+			results[i].Success, results[i].Message = e.reindex(result.Location, nil, sealed[i].Data)
+		}
+	}
+	return results
+}
+
+func (e *EncryptedDatabase) BatchUpsert(items []ports.BatchItem) []ports.BatchResult {
+	batch, ok := e.db.(ports.BatchDatabase)
+	if !ok || len(e.indexes) > 0 {
+		// The previous values are needed to update the blind indexes
+		return e.each(items, func(location string, data map[string]interface{}) (bool, string) {
+			success, message, _ := e.Upsert(location, data)
+			return success, message
+		})
+	}
+	sealed, failed := e.sealItems(items)
+	if failed != nil {
+		return failed
+	}
+	return batch.BatchUpsert(sealed)
+}
+
+func (e *EncryptedDatabase) BatchDelete(locations []string) []ports.BatchResult {
+	batch, ok := e.db.(ports.BatchDatabase)
+	if !ok || len(e.indexes) > 0 {
+		results := make([]ports.BatchResult, len(locations))
+		for i, location := range locations {
+			success, message := e.Delete(location)
+			results[i] = ports.BatchResult{Location: location, Success: success, Message: message}
+		}
+		return results
+	}
+	return batch.BatchDelete(locations)
+}
+
+// WithTx runs fn in a transaction of the wrapped database, or directly when
+// it has none. The keyring is loaded first, so the transaction only writes
+// records and index entries.
+func (e *EncryptedDatabase) WithTx(fn func(tx ports.Database) error) error {
+	if err := e.keys.load(); err != nil {
+		return err
+	}
+	transactor, ok := e.db.(ports.Transactor)
+	if !ok {
+		return fn(e)
+	}
+	return transactor.WithTx(func(tx ports.Database) error {
+		scoped := *e
+		scoped.db = tx
+		scoped.inTx = true
+		return fn(&scoped)
+	})
+}
+
+// Watch decrypts the changes of the wrapped database and hides its own records.
+func (e *EncryptedDatabase) Watch(ctx context.Context, prefix, fromCursor string) (bool, string, <-chan ports.ChangeEvent) {
+	watcher, ok := e.db.(ports.Watcher)
+	if !ok {
+		return false, domain.ErrWatchNotSupported.Error(), nil
+	}
+	success, message, changes := watcher.Watch(ctx, prefix, fromCursor)
+	if !success {
+		return false, message, nil
+	}
+
+	out := make(chan ports.ChangeEvent)
+	go func() {
+		defer close(out)
+		for change := range changes {
+			if isEncryptionLocation(change.Location) {
+				continue
+			}
+			if change.Data != nil {
+				change.Data, _ = e.open(change.Data)
+			}
+			select {
+			case out <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return true, "", out
+}
+
+// seal returns a copy of data with the configured fields encrypted and the
+// blind indexes added.
+func (e *EncryptedDatabase) seal(data map[string]interface{}) (map[string]interface{}, error) {
+	sealed := make(map[string]interface{}, len(data)+len(e.indexes))
+	for key, value := range data {
+		if !strings.HasPrefix(key, blindIndexFieldPrefix) {
+			sealed[key] = value
+		}
+	}
+
+	for field, normalize := range e.indexes {
+		value, _ := data[field].(string)
+		hash, err := e.blindIndex(normalize(value))
+		if err != nil {
+			return nil, err
+		}
+		if hash != "" {
+			sealed[blindIndexFieldPrefix+field] = hash
+		}
+	}
+
+	id, key, err := e.keys.activeKey()
+	if err != nil {
+		return nil, fmt.Errorf("Error loading data key: %v", err)
+	}
+	for _, field := range e.fields {
+		value, ok := data[field]
+		if !ok || value == nil {
+			continue
+		}
+		plaintext, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("Error encrypting %s: %v", field, err)
+		}
+		sealed[field] = encryptedValuePrefix + id + ":" + base64.StdEncoding.EncodeToString(seal(key, plaintext, field))
+	}
+	return sealed, nil
+}
+
+// open returns a copy of stored data with the fields decrypted and the blind
+// indexes removed. Fields that are not encrypted are returned as stored.
+func (e *EncryptedDatabase) open(data map[string]interface{}) (map[string]interface{}, error) {
+	opened := make(map[string]interface{}, len(data))
+	for field, value := range data {
+		if strings.HasPrefix(field, blindIndexFieldPrefix) {
+			continue
+		}
+		text, ok := value.(string)
+		if !ok || !strings.HasPrefix(text, encryptedValuePrefix) {
+			opened[field] = value
+			continue
+		}
+
+		id, encoded, _ := strings.Cut(strings.TrimPrefix(text, encryptedValuePrefix), ":")
+		key, err := e.keys.key(id)
+		if err != nil {
+			return nil, fmt.Errorf("Error decrypting %s: %v", field, err)
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("Error decrypting %s: malformed value", field)
+		}
+		plaintext, err := unseal(key, ciphertext, field)
+		if err != nil {
+			return nil, fmt.Errorf("Error decrypting %s: %v", field, err)
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(plaintext, &decoded); err != nil {
+			return nil, fmt.Errorf("Error decrypting %s: %v", field, err)
+		}
+		opened[field] = decoded
+	}
+	return opened, nil
+}
+
+// isCurrent reports whether stored data is fully sealed with the active key.
+func (e *EncryptedDatabase) isCurrent(data map[string]interface{}, active string) bool {
+	for _, field := range e.fields {
+		value, ok := data[field]
+		if !ok || value == nil {
+			continue
+		}
+		text, _ := value.(string)
+		if !strings.HasPrefix(text, encryptedValuePrefix+active+":") {
+			return false
+		}
+	}
+	for field, normalize := range e.indexes {
+		if _, ok := data[blindIndexFieldPrefix+field]; !ok && normalize(stringValue(data, field)) != "" {
+			// Plaintext values are indexed once they are sealed
+			return false
+		}
+	}
+	return true
+}
+
+// keyIDs returns the ids of the data keys stored data was sealed with.
+func (e *EncryptedDatabase) keyIDs(data map[string]interface{}) []string {
+	ids := make([]string, 0)
+	for _, field := range e.fields {
+		text, _ := data[field].(string)
+		if strings.HasPrefix(text, encryptedValuePrefix) {
+			id, _, _ := strings.Cut(strings.TrimPrefix(text, encryptedValuePrefix), ":")
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// blindIndex returns the HMAC of a normalized value, or "" for empty values,
+// which are not indexed.
+func (e *EncryptedDatabase) blindIndex(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	key, err := e.keys.blindIndexKey()
+	if err != nil {
+		return "", fmt.Errorf("Error loading index key: %v", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// writeConditional seals data for one of the conditional writes and updates
+// the blind indexes if it wrote anything. always means the write happens
+// whatever the third result of write says.
+func (e *EncryptedDatabase) writeConditional(location string, data map[string]interface{}, write func(ports.Database, string, map[string]interface{}) (bool, string, bool), always bool) (bool, string, bool) {
+	if isEncryptionLocation(location) {
+		return false, "Invalid location", false
+	}
+	sealed, err := e.seal(data)
+	if err != nil {
+		return false, err.Error(), false
+	}
+	result := false
+	success, message := e.indexed(func(e *EncryptedDatabase) (bool, string) {
+		_, _, old := e.db.Read(location)
+		success, message, written := write(e.db, location, sealed)
+		if !success {
+			return false, message
+		}
+		result = written
+		if always || written {
+			return e.reindex(location, old, sealed)
+		}
+		return true, ""
+	})
+	if !success {
+		return false, message, false
+	}
+	return true, "", result
+}
+
+// indexed runs a write that also moves blind index entries inside a
+// transaction of the wrapped database, when it has one, so the record and its
+// entries change together and the old values read for reindexing stay current.
+func (e *EncryptedDatabase) indexed(write func(e *EncryptedDatabase) (bool, string)) (bool, string) {
+	transactor, ok := e.db.(ports.Transactor)
+	if !ok || e.inTx || len(e.indexes) == 0 {
+		return write(e)
+	}
+
+	success, message := false, ""
+	err := transactor.WithTx(func(tx ports.Database) error {
+		scoped := *e
+		scoped.db = tx
+		scoped.inTx = true
+		if success, message = write(&scoped); !success {
+			return errors.New(message)
+		}
+		return nil
+	})
+	if err != nil {
+		if success || message == "" {
+			return false, err.Error()
+		}
+		return false, message
+	}
+	return true, ""
+}
+
+// reindex moves the index entries of location from its old stored data to the
+// current one. Either may be nil.
+func (e *EncryptedDatabase) reindex(location string, old, current map[string]interface{}) (bool, string) {
+	fields := make([]string, 0, len(e.indexes))
+	for field := range e.indexes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		oldHash := stringValue(old, blindIndexFieldPrefix+field)
+		newHash := stringValue(current, blindIndexFieldPrefix+field)
+		if oldHash == newHash {
+			continue
+		}
+		if oldHash != "" {
+			success, message := e.db.Delete(blindIndexPrefix + field + "/" + oldHash + "/" + location)
+			if !success && !missing(message) {
+				return false, fmt.Sprintf("Error unindexing %s: %s", field, message)
+			}
+		}
+		if newHash != "" {
+			success, message := e.db.Create(blindIndexPrefix+field+"/"+newHash+"/"+location, map[string]interface{}{})
+			if !success && !alreadyExists(message) {
+				return false, fmt.Sprintf("Error indexing %s: %s", field, message)
+			}
+		}
+	}
+	return true, ""
+}
+
+func (e *EncryptedDatabase) sealItems(items []ports.BatchItem) ([]ports.BatchItem, []ports.BatchResult) {
+	sealed := make([]ports.BatchItem, len(items))
+	for i, item := range items {
+		data, err := e.seal(item.Data)
+		if err != nil {
+			results := make([]ports.BatchResult, len(items))
+			for j, item := range items {
+				results[j] = ports.BatchResult{Location: item.Location, Message: err.Error()}
+			}
+			return nil, results
+		}
+		sealed[i] = ports.BatchItem{Location: item.Location, Data: data}
+	}
+	return sealed, nil
+}
+
+func (e *EncryptedDatabase) each(items []ports.BatchItem, write func(string, map[string]interface{}) (bool, string)) []ports.BatchResult {
+	results := make([]ports.BatchResult, len(items))
+	for i, item := range items {
+		success, message := write(item.Location, item.Data)
+		results[i] = ports.BatchResult{Location: item.Location, Success: success, Message: message}
+	}
+	return results
+}
+
+func isEncryptionLocation(location string) bool {
+	return strings.HasPrefix(location, encryptionPrefix)
+}
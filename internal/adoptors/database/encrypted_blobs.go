@@ -0,0 +1,143 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// blobKeyPrefix holds one record per blob at <prefix><hash>, naming the data
+// key its content was sealed with and the hash the ciphertext is stored under
+// in the wrapped blob store.
+const blobKeyPrefix = encryptionPrefix + "blobs/"
+
+// PutBlob seals content with the active data key and stores the ciphertext
+// under its own hash. Content already stored is not sealed again.
+func (e *EncryptedDatabase) PutBlob(hash string, content []byte) (bool, string) {
+	store, ok := e.db.(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error()
+	}
+	if message := checkBlob(hash, content); message != "" {
+		return false, message
+	}
+	if success, _, _ := e.db.Read(blobKeyPrefix + hash); success {
+		return true, ""
+	}
+
+	id, key, err := e.keys.activeKey()
+	if err != nil {
+		return false, fmt.Sprintf("Error loading data key: %v", err)
+	}
+	sealed := seal(key, content, hash)
+	sum := sha256.Sum256(sealed)
+	sealedHash := hex.EncodeToString(sum[:])
+	if success, message := store.PutBlob(sealedHash, sealed); !success {
+		return false, message
+	}
+
+	success, message := e.db.Create(blobKeyPrefix+hash, map[string]interface{}{"key": id, "blob": sealedHash})
+	if !success {
+		// Another writer stored the same content first
+		store.DeleteBlob(sealedHash)
+		if alreadyExists(message) {
+			return true, ""
+		}
+		return false, message
+	}
+	return true, ""
+}
+
+func (e *EncryptedDatabase) GetBlob(hash string) (bool, string, []byte) {
+	store, ok := e.db.(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error(), nil
+	}
+	success, message, id, sealedHash := e.blobKey(hash)
+	if !success {
+		return false, message, nil
+	}
+
+	success, message, sealed := store.GetBlob(sealedHash)
+	if !success {
+		return false, message, nil
+	}
+	key, err := e.keys.key(id)
+	if err != nil {
+		return false, fmt.Sprintf("Error decrypting blob: %v", err), nil
+	}
+	content, err := unseal(key, sealed, hash)
+	if err != nil {
+		return false, fmt.Sprintf("Error decrypting blob: %v", err), nil
+	}
+	return true, "", content
+}
+
+// DeleteBlob forgets the blob before deleting its ciphertext, so a failure
+// leaves unreachable ciphertext rather than a blob that cannot be read.
+func (e *EncryptedDatabase) DeleteBlob(hash string) (bool, string) {
+	store, ok := e.db.(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error()
+	}
+	success, message, _, sealedHash := e.blobKey(hash)
+	if !success {
+		return false, message
+	}
+
+	if success, message := e.db.Delete(blobKeyPrefix + hash); !success {
+		return false, message
+	}
+	if success, message := store.DeleteBlob(sealedHash); !success && !missing(message) {
+		return false, message
+	}
+	return true, ""
+}
+
+func (e *EncryptedDatabase) ListBlobs() (bool, string, []string) {
+	if _, ok := e.db.(ports.BlobStore); !ok {
+		return false, domain.ErrBlobsNotSupported.Error(), nil
+	}
+	success, message, locations := e.db.List(blobKeyPrefix)
+	if !success {
+		return false, message, nil
+	}
+	hashes := make([]string, len(locations))
+	for i, location := range locations {
+		hashes[i] = strings.TrimPrefix(location, blobKeyPrefix)
+	}
+	return true, "", hashes
+}
+
+// blobKey returns the data key id and ciphertext hash of a blob.
+func (e *EncryptedDatabase) blobKey(hash string) (bool, string, string, string) {
+	if !isBlobHash(hash) {
+		return false, "Blob does not exist", "", ""
+	}
+	success, message, data := e.db.Read(blobKeyPrefix + hash)
+	if !success {
+		if missing(message) {
+			return false, "Blob does not exist", "", ""
+		}
+		return false, message, "", ""
+	}
+	return true, "", stringValue(data, "key"), stringValue(data, "blob")
+}
+
+// blobKeyIDs returns the ids of the data keys the blobs were sealed with.
+func (e *EncryptedDatabase) blobKeyIDs() (map[string]bool, error) {
+	inUse := make(map[string]bool)
+	success, message, records := listFields(e.db, blobKeyPrefix, []string{"key"})
+	if !success {
+		return nil, errors.New(message)
+	}
+	for _, data := range records {
+		inUse[stringValue(data, "key")] = true
+	}
+	return inUse, nil
+}
@@ -0,0 +1,251 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+var testMasterKey = []byte("0123456789abcdef0123456789abcdef")
+
+func digitsOnly(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+func setupEncryptedTest(t *testing.T, db ports.Database, masterKey []byte) *EncryptedDatabase {
+	encrypted, err := NewEncryptedDatabase(db, masterKey, []string{"phone", "address"}, WithBlindIndex("phone", digitsOnly))
+	require.NoError(t, err)
+	return encrypted
+}
+
+func TestEncryptedDatabase_CRUD(t *testing.T) {
+	for name, inner := range map[string]ports.Database{
+		"InMemory":   NewInMemoryDatabase(),
+		"FileSystem": NewFileSystemDatabase(t.TempDir()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			db := setupEncryptedTest(t, inner, testMasterKey)
+			contact := map[string]interface{}{"name": "John Doe", "phone": "555-123-4567", "address": "1 Main St"}
+			require.True(t, mustSucceed(db.Create("contacts/john", contact)))
+
+			// Sensitive fields never reach the wrapped database in plaintext
+			_, _, raw := inner.Read("contacts/john")
+			assert.Equal(t, "John Doe", raw["name"])
+			assert.NotContains(t, raw["phone"], "555")
+			assert.NotContains(t, raw["address"], "Main")
+
+			_, _, data := db.Read("contacts/john")
+			assert.Equal(t, contact, data)
+			_, _, locations := db.List("")
+			assert.Equal(t, []string{"contacts/john"}, locations)
+
+			success, message := db.Create("_encryption/active", map[string]interface{}{})
+			assert.False(t, success)
+			assert.Equal(t, "Invalid location", message)
+
+			// Wrong master keys cannot read the data
+			other := setupEncryptedTest(t, inner, []byte("fedcba9876543210fedcba9876543210"))
+			success, _, _ = other.Read("contacts/john")
+			assert.False(t, success)
+		})
+	}
+}
+
+func TestEncryptedDatabase_LookupField(t *testing.T) {
+	db := setupEncryptedTest(t, NewInMemoryDatabase(), testMasterKey)
+	require.True(t, mustSucceed(db.Create("contacts/john", map[string]interface{}{"name": "John", "phone": "555-123-4567"})))
+	require.True(t, mustSucceed(db.Create("contacts/jane", map[string]interface{}{"name": "Jane", "phone": "(555) 123 4567"})))
+	require.True(t, mustSucceed(db.Create("contacts/bob", map[string]interface{}{"name": "Bob", "phone": "555-000-0000"})))
+
+	tests := []struct {
+		name     string
+		setup    func()
+		value    string
+		expected []string
+	}{
+		{"normalized match", nil, "5551234567", []string{"contacts/jane", "contacts/john"}},
+		{"no match", nil, "555-999-9999", []string{}},
+		{"after update", func() {
+			db.Update("contacts/john", map[string]interface{}{"name": "John", "phone": "555-999-9999"})
+		}, "555-123-4567", []string{"contacts/jane"}},
+		{"new value", nil, "555-999-9999", []string{"contacts/john"}},
+		{"after delete", func() {
+			db.Delete("contacts/jane")
+		}, "555-123-4567", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup()
+			}
+			success, message, locations := db.LookupField("phone", tt.value)
+			require.True(t, success, message)
+			assert.Equal(t, tt.expected, locations)
+		})
+	}
+
+	success, _, _ := db.LookupField("address", "1 Main St")
+	assert.False(t, success)
+}
+
+func TestEncryptedDatabase_Rotation(t *testing.T) {
+	inner := NewInMemoryDatabase()
+
+	// A record written before encryption was enabled
+	require.True(t, mustSucceed(inner.Create("contacts/legacy", map[string]interface{}{"name": "Old", "phone": "555-000-1111"})))
+
+	db := setupEncryptedTest(t, inner, testMasterKey)
+	require.True(t, mustSucceed(db.Create("contacts/john", map[string]interface{}{"name": "John", "phone": "555-123-4567"})))
+	_, _, firstKeys := inner.List(dataKeyPrefix)
+	require.Len(t, firstKeys, 1)
+
+	success, message, id := db.RotateKey()
+	require.True(t, success, message)
+	success, message, rewritten := db.Reencrypt()
+	require.True(t, success, message)
+	assert.Equal(t, 2, rewritten)
+
+	// Every record is sealed with the new key and the old one is gone
+	for _, location := range []string{"contacts/john", "contacts/legacy"} {
+		_, _, raw := inner.Read(location)
+		assert.True(t, strings.HasPrefix(raw["phone"].(string), encryptedValuePrefix+id+":"), location)
+	}
+	_, _, keys := inner.List(dataKeyPrefix)
+	assert.Equal(t, []string{dataKeyPrefix + id}, keys)
+	_, _, locations := db.LookupField("phone", "5550001111")
+	assert.Equal(t, []string{"contacts/legacy"}, locations)
+
+	success, _, rewritten = db.Reencrypt()
+	assert.True(t, success)
+	assert.Zero(t, rewritten)
+
+	// A new master key takes over from the old one
+	newMaster := []byte("fedcba9876543210fedcba9876543210")
+	require.True(t, mustSucceed(db.RotateMasterKey(newMaster)))
+	_, _, data := setupEncryptedTest(t, inner, newMaster).Read("contacts/john")
+	assert.Equal(t, "555-123-4567", data["phone"])
+	success, _, _ = setupEncryptedTest(t, inner, testMasterKey).Read("contacts/john")
+	assert.False(t, success)
+}
+
+func TestEncryptedDatabase_BatchAndTx(t *testing.T) {
+	inner := NewInMemoryDatabase()
+	db := setupEncryptedTest(t, inner, testMasterKey)
+
+	results := db.BatchCreate([]ports.BatchItem{
+		{Location: "contacts/john", Data: map[string]interface{}{"name": "John", "phone": "555-123-4567"}},
+		{Location: "contacts/jane", Data: map[string]interface{}{"name": "Jane", "phone": "555-000-1111"}},
+	})
+	for _, result := range results {
+		assert.True(t, result.Success, result.Message)
+	}
+	success, message, records := db.BatchRead([]string{"contacts/john", "contacts/jane", activeKeyLocation})
+	require.True(t, success, message)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"contacts/john": {"name": "John", "phone": "555-123-4567"},
+		"contacts/jane": {"name": "Jane", "phone": "555-000-1111"},
+	}, records)
+
+	err := db.WithTx(func(tx ports.Database) error {
+		success, message := tx.Update("contacts/john", map[string]interface{}{"name": "John", "phone": "555-999-9999"})
+		assert.True(t, success, message)
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, _, raw := inner.Read("contacts/john")
+	assert.NotContains(t, raw["phone"], "555")
+	_, _, locations := db.LookupField("phone", "555-999-9999")
+	assert.Equal(t, []string{"contacts/john"}, locations)
+	_, _, locations = db.LookupField("phone", "555-000-1111")
+	assert.Equal(t, []string{"contacts/jane"}, locations)
+}
+
+func TestEncryptedDatabase_Blobs(t *testing.T) {
+	testBlobStore(t, setupEncryptedTest(t, NewInMemoryDatabase(), testMasterKey))
+
+	inner := NewInMemoryDatabase()
+	db := setupEncryptedTest(t, inner, testMasterKey)
+	photo := []byte("\x89PNG photo")
+	require.True(t, mustSucceed(db.PutBlob(blobHash(photo), photo)))
+
+	// The wrapped store only holds ciphertext, under its own hash
+	_, _, hashes := inner.ListBlobs()
+	require.Len(t, hashes, 1)
+	assert.NotEqual(t, blobHash(photo), hashes[0])
+	_, _, stored := inner.GetBlob(hashes[0])
+	assert.NotContains(t, string(stored), "PNG")
+	_, _, locations := db.List("")
+	assert.Empty(t, locations)
+
+	// Blobs stay readable after their key is rotated out
+	success, message, _ := db.RotateKey()
+	require.True(t, success, message)
+	success, message, _ = db.Reencrypt()
+	require.True(t, success, message)
+	success, message, content := db.GetBlob(blobHash(photo))
+	require.True(t, success, message)
+	assert.Equal(t, photo, content)
+}
+
+// unindexFailingDatabase fails to delete blind index entries, inside its
+// transactions too.
+type unindexFailingDatabase struct {
+	*InMemoryDatabase
+}
+
+func (db unindexFailingDatabase) Delete(location string) (bool, string) {
+	if strings.HasPrefix(location, blindIndexPrefix) {
+		return false, "Disk full"
+	}
+	return db.InMemoryDatabase.Delete(location)
+}
+
+func (db unindexFailingDatabase) WithTx(fn func(tx ports.Database) error) error {
+	return db.InMemoryDatabase.WithTx(func(tx ports.Database) error {
+		return fn(unindexFailingDatabase{tx.(*InMemoryDatabase)})
+	})
+}
+
+func TestEncryptedDatabase_UnindexFailure(t *testing.T) {
+	inner := unindexFailingDatabase{NewInMemoryDatabase()}
+	db := setupEncryptedTest(t, inner, testMasterKey)
+	require.True(t, mustSucceed(db.Create("contacts/john", map[string]interface{}{"name": "John", "phone": "555-123-4567"})))
+
+	// The error is reported and the record keeps its old value and index entry
+	success, message := db.Update("contacts/john", map[string]interface{}{"name": "John", "phone": "555-999-9999"})
+	assert.False(t, success)
+	assert.Equal(t, "Error unindexing phone: Disk full", message)
+	_, _, data := db.Read("contacts/john")
+	assert.Equal(t, "555-123-4567", data["phone"])
+	_, _, locations := db.LookupField("phone", "555-999-9999")
+	assert.Empty(t, locations)
+
+	success, message = db.Delete("contacts/john")
+	assert.False(t, success)
+	assert.Equal(t, "Error unindexing phone: Disk full", message)
+	_, _, locations = db.LookupField("phone", "555-123-4567")
+	assert.Equal(t, []string{"contacts/john"}, locations)
+	success, _, _ = db.Read("contacts/john")
+	assert.True(t, success)
+}
+
+func TestKeyFile(t *testing.T) {
+	path := t.TempDir() + "/master.key"
+	require.NoError(t, GenerateKeyFile(path))
+	assert.Error(t, GenerateKeyFile(path))
+
+	key, err := LoadKeyFile(path)
+	require.NoError(t, err)
+	assert.Len(t, key, MasterKeySize)
+}
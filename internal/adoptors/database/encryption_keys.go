@@ -0,0 +1,345 @@
+package database
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// MasterKeySize is the length of the AES-256 master key kept in a keyfile.
+const MasterKeySize = 32
+
+// Records the encrypted database keeps for itself, hidden from its callers.
+const (
+	encryptionPrefix  = "_encryption/"
+	dataKeyPrefix     = encryptionPrefix + "keys/"
+	activeKeyLocation = encryptionPrefix + "active"
+	indexKeyLocation  = encryptionPrefix + "index-key"
+)
+
+// GenerateKeyFile writes a new random master key to path, hex encoded and
+// readable only by its owner. It never overwrites an existing file.
+func GenerateKeyFile(path string) error {
+	key := make([]byte, MasterKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate key: %v", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create keyfile: %v", err)
+	}
+	if _, err := f.WriteString(hex.EncodeToString(key) + "\n"); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write keyfile: %v", err)
+	}
+	return f.Close()
+}
+
+// LoadKeyFile reads a master key written by GenerateKeyFile.
+func LoadKeyFile(path string) ([]byte, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyfile: %v", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(contents)))
+	if err != nil || len(key) != MasterKeySize {
+		return nil, fmt.Errorf("keyfile %s does not hold a %d-byte hex key", path, MasterKeySize)
+	}
+	return key, nil
+}
+
+// keyring keeps the data keys of an encrypted database in the database
+// itself, each wrapped by the master key. One data key is active and used
+// for new writes; the others stay readable until re-encryption retires them.
+// The blind index key is wrapped the same way and never rotates, so lookups
+// keep working across rotations.
+type keyring struct {
+	store  ports.Database
+	master []byte
+
+	mu       sync.Mutex
+	loaded   bool
+	keys     map[string][]byte
+	active   string
+	indexKey []byte
+}
+
+func newKeyring(store ports.Database, master []byte) *keyring {
+	return &keyring{store: store, master: master, keys: make(map[string][]byte)}
+}
+
+// load reads every data key, creating the first one and the index key on
+// first use. Keys are loaded up front so that transactions never have to
+// read them.
+func (k *keyring) load() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.loaded {
+		return nil
+	}
+
+	if _, _, data := k.store.Read(activeKeyLocation); data == nil {
+		if err := k.initialize(); err != nil {
+			return err
+		}
+	}
+	if err := k.reload(); err != nil {
+		return err
+	}
+	k.loaded = true
+	return nil
+}
+
+func (k *keyring) initialize() error {
+	indexKey := make([]byte, MasterKeySize)
+	if _, err := rand.Read(indexKey); err != nil {
+		return err
+	}
+	success, message := k.store.Create(indexKeyLocation, map[string]interface{}{"wrapped": k.wrap(indexKey, indexKeyLocation)})
+	if !success && !alreadyExists(message) {
+		return errors.New(message)
+	}
+
+	id, err := k.createDataKey()
+	if err != nil {
+		return err
+	}
+	success, message = k.store.Create(activeKeyLocation, map[string]interface{}{"id": id})
+	if !success && !alreadyExists(message) {
+		return errors.New(message)
+	}
+	return nil
+}
+
+func (k *keyring) reload() error {
+	success, message, data := k.store.Read(activeKeyLocation)
+	if !success {
+		return errors.New(message)
+	}
+	active := stringValue(data, "id")
+
+	success, message, data = k.store.Read(indexKeyLocation)
+	if !success {
+		return errors.New(message)
+	}
+	indexKey, err := k.unwrap(stringValue(data, "wrapped"), indexKeyLocation)
+	if err != nil {
+		return err
+	}
+
+	success, message, locations := k.store.List(dataKeyPrefix)
+	if !success {
+		return errors.New(message)
+	}
+	keys := make(map[string][]byte, len(locations))
+	for _, location := range locations {
+		success, message, data := k.store.Read(location)
+		if !success {
+			return errors.New(message)
+		}
+		key, err := k.unwrap(stringValue(data, "wrapped"), location)
+		if err != nil {
+			return err
+		}
+		keys[strings.TrimPrefix(location, dataKeyPrefix)] = key
+	}
+	if keys[active] == nil {
+		return fmt.Errorf("active data key %q is missing", active)
+	}
+
+	k.keys, k.active, k.indexKey = keys, active, indexKey
+	return nil
+}
+
+func (k *keyring) createDataKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	id := time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(key[:4])
+	location := dataKeyPrefix + id
+	data := map[string]interface{}{
+		"wrapped":    k.wrap(key, location),
+		"created_at": time.Now().UTC().Format(time.RFC3339),
+	}
+	if success, message := k.store.Create(location, data); !success {
+		return "", errors.New(message)
+	}
+	return id, nil
+}
+
+// activeKey returns the data key for new writes.
+func (k *keyring) activeKey() (string, []byte, error) {
+	if err := k.load(); err != nil {
+		return "", nil, err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.active, k.keys[k.active], nil
+}
+
+// key returns a data key by id, reloading the keyring once for keys added
+// since it was loaded.
+func (k *keyring) key(id string) ([]byte, error) {
+	if err := k.load(); err != nil {
+		return nil, err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if key, ok := k.keys[id]; ok {
+		return key, nil
+	}
+	if err := k.reload(); err != nil {
+		return nil, err
+	}
+	if key, ok := k.keys[id]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown data key %q", id)
+}
+
+func (k *keyring) blindIndexKey() ([]byte, error) {
+	if err := k.load(); err != nil {
+		return nil, err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.indexKey, nil
+}
+
+// rotate makes a new data key the active one.
+func (k *keyring) rotate() (string, error) {
+	if err := k.load(); err != nil {
+		return "", err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	id, err := k.createDataKey()
+	if err != nil {
+		return "", err
+	}
+	if success, message := k.store.Update(activeKeyLocation, map[string]interface{}{"id": id}); !success {
+		return "", errors.New(message)
+	}
+	return id, k.reload()
+}
+
+// retire deletes the data keys other than the active one and those in use.
+func (k *keyring) retire(inUse map[string]bool) (int, error) {
+	if err := k.load(); err != nil {
+		return 0, err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	retired := 0
+	for id := range k.keys {
+		if id == k.active || inUse[id] {
+			continue
+		}
+		if success, message := k.store.Delete(dataKeyPrefix + id); !success {
+			return retired, errors.New(message)
+		}
+		delete(k.keys, id)
+		retired++
+	}
+	return retired, nil
+}
+
+// rewrap wraps every key with a new master key.
+func (k *keyring) rewrap(master []byte) error {
+	if len(master) != MasterKeySize {
+		return fmt.Errorf("master key must be %d bytes", MasterKeySize)
+	}
+	if err := k.load(); err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	rewrapped := &keyring{master: master}
+	for id, key := range k.keys {
+		location := dataKeyPrefix + id
+		success, message, data := k.store.Read(location)
+		if !success {
+			return errors.New(message)
+		}
+		data["wrapped"] = rewrapped.wrap(key, location)
+		if success, message := k.store.Update(location, data); !success {
+			return errors.New(message)
+		}
+	}
+	data := map[string]interface{}{"wrapped": rewrapped.wrap(k.indexKey, indexKeyLocation)}
+	if success, message := k.store.Update(indexKeyLocation, data); !success {
+		return errors.New(message)
+	}
+	k.master = master
+	return nil
+}
+
+// wrap encrypts a key with the master key, bound to the location it is
+// stored at.
+func (k *keyring) wrap(key []byte, location string) string {
+	return base64.StdEncoding.EncodeToString(seal(k.master, key, location))
+}
+
+func (k *keyring) unwrap(wrapped, location string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("malformed key at %s", location)
+	}
+	key, err := unseal(k.master, sealed, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key at %s: wrong master key?", location)
+	}
+	return key, nil
+}
+
+// seal encrypts plaintext with AES-GCM, prefixing the random nonce.
+// Additional data binds the ciphertext to where it belongs.
+func seal(key, plaintext []byte, additional string) []byte {
+	gcm := newGCM(key)
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	return gcm.Seal(nonce, nonce, plaintext, []byte(additional))
+}
+
+func unseal(key, sealed []byte, additional string) ([]byte, error) {
+	gcm := newGCM(key)
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, []byte(additional))
+}
+
+// newGCM panics on key sizes other than 16, 24 or 32 bytes, which the
+// keyring never produces.
+func newGCM(key []byte) cipher.AEAD {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return gcm
+}
+
+func stringValue(data map[string]interface{}, key string) string {
+	value, _ := data[key].(string)
+	return value
+}
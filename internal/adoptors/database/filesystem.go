@@ -6,17 +6,41 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 type FileSystemDatabase struct {
 	BaseDir string
+	// PollInterval is how often Watch scans for changes. Zero means one second.
+	PollInterval time.Duration
+	mu           sync.Mutex
+	txMu         sync.Mutex
+	// writeMu is shared by plain writes and held alone by a committing
+	// transaction, so no write lands between its conflict check and its
+	// journal being applied.
+	writeMu sync.RWMutex
 }
 
 func NewFileSystemDatabase(baseDir string) *FileSystemDatabase {
-	return &FileSystemDatabase{BaseDir: baseDir}
+	fs := &FileSystemDatabase{BaseDir: baseDir}
+
+	// Finish a transaction interrupted by a crash. If that fails the journal
+	// is kept and replayed before the next transaction.
+	_ = fs.replayJournal()
+
+	return fs
 }
 
 func (fs *FileSystemDatabase) Create(location string, data map[string]interface{}) (bool, string) {
+	fs.writeMu.RLock()
+	defer fs.writeMu.RUnlock()
+
+	if !isStoredLocation(location) {
+		return false, "Invalid location"
+	}
 	filePath := filepath.Join(fs.BaseDir, location)
 
 	// Check if the file already exists.
@@ -44,6 +68,9 @@ func (fs *FileSystemDatabase) Create(location string, data map[string]interface{
 }
 
 func (fs *FileSystemDatabase) Read(location string) (bool, string, map[string]interface{}) {
+	if !isStoredLocation(location) {
+		return false, "Invalid location", nil
+	}
 	filePath := filepath.Join(fs.BaseDir, location)
 
 	// Check if the file exists.
@@ -67,6 +94,12 @@ func (fs *FileSystemDatabase) Read(location string) (bool, string, map[string]in
 }
 
 func (fs *FileSystemDatabase) Update(location string, data map[string]interface{}) (bool, string) {
+	fs.writeMu.RLock()
+	defer fs.writeMu.RUnlock()
+
+	if !isStoredLocation(location) {
+		return false, "Invalid location"
+	}
 	filePath := filepath.Join(fs.BaseDir, location)
 
 	// Check if the file exists.
@@ -89,6 +122,12 @@ func (fs *FileSystemDatabase) Update(location string, data map[string]interface{
 }
 
 func (fs *FileSystemDatabase) Delete(location string) (bool, string) {
+	fs.writeMu.RLock()
+	defer fs.writeMu.RUnlock()
+
+	if !isStoredLocation(location) {
+		return false, "Invalid location"
+	}
 	filePath := filepath.Join(fs.BaseDir, location)
 
 	// Check if the file exists.
@@ -101,5 +140,63 @@ func (fs *FileSystemDatabase) Delete(location string) (bool, string) {
 		return false, fmt.Sprintf("Failed to delete file: %v", err)
 	}
 
-	return true, ""
+	// Drop the record from any groups.
+	return fs.removeMemberEverywhere(location)
+}
+
+func (fs *FileSystemDatabase) List(prefix string) (bool, string, []string) {
+	locations := make([]string, 0)
+
+	// Nothing has been stored yet.
+	if _, err := os.Stat(fs.BaseDir); errors.Is(err, os.ErrNotExist) {
+		return true, "", locations
+	}
+
+	// Walk the base directory and collect the matching files.
+	err := filepath.WalkDir(fs.BaseDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(fs.BaseDir, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// Tenants are listed through their own database.
+			if rel == tenantsDir || rel == blobsDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		location := filepath.ToSlash(rel)
+		if isInternalFile(location) {
+			return nil
+		}
+		if strings.HasPrefix(location, prefix) {
+			locations = append(locations, location)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Sprintf("Failed to list files: %v", err), nil
+	}
+
+	sort.Strings(locations)
+	return true, "", locations
+}
+
+// isStoredLocation reports whether location names a record inside the base
+// directory, outside the adapter's own files, the tenant subdirectories and
+// the blobs.
+func isStoredLocation(location string) bool {
+	if !filepath.IsLocal(location) {
+		return false
+	}
+	first, _, _ := strings.Cut(filepath.ToSlash(filepath.Clean(location)), "/")
+	return first != tenantsDir && first != blobsDir && !isInternalFile(first)
+}
+
+// isInternalFile reports whether location is one of the adapter's own files.
+func isInternalFile(location string) bool {
+	return location == groupsFile || location == journalFile || location == journalFile+".tmp"
 }
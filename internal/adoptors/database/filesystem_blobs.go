@@ -0,0 +1,93 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// blobsDir holds the blobs, in subdirectories named after the first two
+// characters of their hash.
+const blobsDir = ".blobs"
+
+func (fs *FileSystemDatabase) PutBlob(hash string, content []byte) (bool, string) {
+	if message := checkBlob(hash, content); message != "" {
+		return false, message
+	}
+	blobPath := fs.blobPath(hash)
+	if _, err := os.Stat(blobPath); err == nil {
+		return true, ""
+	}
+	if err := os.MkdirAll(filepath.Dir(blobPath), os.ModePerm); err != nil {
+		return false, fmt.Sprintf("Failed to create directories: %v", err)
+	}
+
+	// Write to a temporary file and rename it, so a blob is never seen half
+	// written. Concurrent writers of the same blob write the same bytes.
+	file, err := os.CreateTemp(filepath.Dir(blobPath), hash+".*.tmp")
+	if err != nil {
+		return false, fmt.Sprintf("Failed to write blob: %v", err)
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), blobPath)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return false, fmt.Sprintf("Failed to write blob: %v", err)
+	}
+	return true, ""
+}
+
+func (fs *FileSystemDatabase) GetBlob(hash string) (bool, string, []byte) {
+	if !isBlobHash(hash) {
+		return false, "Invalid blob hash", nil
+	}
+	content, err := os.ReadFile(fs.blobPath(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, "Blob does not exist", nil
+	}
+	if err != nil {
+		return false, fmt.Sprintf("Failed to read blob: %v", err), nil
+	}
+	return true, "", content
+}
+
+func (fs *FileSystemDatabase) DeleteBlob(hash string) (bool, string) {
+	if !isBlobHash(hash) {
+		return false, "Invalid blob hash"
+	}
+	err := os.Remove(fs.blobPath(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, "Blob does not exist"
+	}
+	if err != nil {
+		return false, fmt.Sprintf("Failed to delete blob: %v", err)
+	}
+	return true, ""
+}
+
+func (fs *FileSystemDatabase) ListBlobs() (bool, string, []string) {
+	paths, err := filepath.Glob(filepath.Join(fs.BaseDir, blobsDir, "*", "*"))
+	if err != nil {
+		return false, fmt.Sprintf("Failed to list blobs: %v", err), nil
+	}
+	hashes := make([]string, 0, len(paths))
+	for _, path := range paths {
+		// Skip temporary files of unfinished writes
+		if hash := filepath.Base(path); isBlobHash(hash) {
+			hashes = append(hashes, hash)
+		}
+	}
+	sort.Strings(hashes)
+	return true, "", hashes
+}
+
+func (fs *FileSystemDatabase) blobPath(hash string) string {
+	return filepath.Join(fs.BaseDir, blobsDir, hash[:2], hash)
+}
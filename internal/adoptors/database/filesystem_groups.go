@@ -0,0 +1,242 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// groupsFile holds every group and its members, relative to BaseDir.
+const groupsFile = ".groups.json"
+
+func (fs *FileSystemDatabase) CreateGroup(name string) (bool, string) {
+	fs.writeMu.RLock()
+	defer fs.writeMu.RUnlock()
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	groups, msg := fs.loadGroups()
+	if msg != "" {
+		return false, msg
+	}
+	if _, exists := groups[name]; exists {
+		return false, "Group already exists"
+	}
+
+	groups[name] = []string{}
+	return fs.saveGroups(groups)
+}
+
+func (fs *FileSystemDatabase) RenameGroup(name, newName string) (bool, string) {
+	fs.writeMu.RLock()
+	defer fs.writeMu.RUnlock()
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	groups, msg := fs.loadGroups()
+	if msg != "" {
+		return false, msg
+	}
+	members, exists := groups[name]
+	if !exists {
+		return false, "Group does not exist"
+	}
+	if _, exists := groups[newName]; exists {
+		return false, "Group already exists"
+	}
+
+	delete(groups, name)
+	groups[newName] = members
+	return fs.saveGroups(groups)
+}
+
+func (fs *FileSystemDatabase) DeleteGroup(name string) (bool, string) {
+	fs.writeMu.RLock()
+	defer fs.writeMu.RUnlock()
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	groups, msg := fs.loadGroups()
+	if msg != "" {
+		return false, msg
+	}
+	if _, exists := groups[name]; !exists {
+		return false, "Group does not exist"
+	}
+
+	delete(groups, name)
+	return fs.saveGroups(groups)
+}
+
+func (fs *FileSystemDatabase) ListGroups() (bool, string, []string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	groups, msg := fs.loadGroups()
+	if msg != "" {
+		return false, msg, nil
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return true, "", names
+}
+
+func (fs *FileSystemDatabase) AddMember(group, location string) (bool, string) {
+	fs.writeMu.RLock()
+	defer fs.writeMu.RUnlock()
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	groups, msg := fs.loadGroups()
+	if msg != "" {
+		return false, msg
+	}
+	members, exists := groups[group]
+	if !exists {
+		return false, "Group does not exist"
+	}
+	if _, err := os.Stat(filepath.Join(fs.BaseDir, location)); errors.Is(err, os.ErrNotExist) {
+		return false, "File does not exist"
+	}
+
+	for _, member := range members {
+		if member == location {
+			return true, ""
+		}
+	}
+	members = append(members, location)
+	sort.Strings(members)
+	groups[group] = members
+	return fs.saveGroups(groups)
+}
+
+func (fs *FileSystemDatabase) RemoveMember(group, location string) (bool, string) {
+	fs.writeMu.RLock()
+	defer fs.writeMu.RUnlock()
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	groups, msg := fs.loadGroups()
+	if msg != "" {
+		return false, msg
+	}
+	if _, exists := groups[group]; !exists {
+		return false, "Group does not exist"
+	}
+
+	groups[group] = removeString(groups[group], location)
+	return fs.saveGroups(groups)
+}
+
+func (fs *FileSystemDatabase) Members(group string) (bool, string, []string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	groups, msg := fs.loadGroups()
+	if msg != "" {
+		return false, msg, nil
+	}
+	members, exists := groups[group]
+	if !exists {
+		return false, "Group does not exist", nil
+	}
+
+	return true, "", members
+}
+
+func (fs *FileSystemDatabase) GroupsOf(location string) (bool, string, []string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if _, err := os.Stat(filepath.Join(fs.BaseDir, location)); errors.Is(err, os.ErrNotExist) {
+		return false, "File does not exist", nil
+	}
+	groups, msg := fs.loadGroups()
+	if msg != "" {
+		return false, msg, nil
+	}
+
+	names := make([]string, 0)
+	for name, members := range groups {
+		for _, member := range members {
+			if member == location {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return true, "", names
+}
+
+// loadGroups reads the groups file. A missing file means no groups.
+func (fs *FileSystemDatabase) loadGroups() (map[string][]string, string) {
+	groups := make(map[string][]string)
+
+	fileData, err := os.ReadFile(filepath.Join(fs.BaseDir, groupsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return groups, ""
+	}
+	if err != nil {
+		return nil, fmt.Sprintf("Failed to read groups: %v", err)
+	}
+
+	if err := json.Unmarshal(fileData, &groups); err != nil {
+		return nil, fmt.Sprintf("Failed to parse groups: %v", err)
+	}
+	return groups, ""
+}
+
+func (fs *FileSystemDatabase) saveGroups(groups map[string][]string) (bool, string) {
+	if err := os.MkdirAll(fs.BaseDir, os.ModePerm); err != nil {
+		return false, fmt.Sprintf("Failed to create directories: %v", err)
+	}
+
+	jsonData, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		return false, fmt.Sprintf("Failed to serialize groups: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(fs.BaseDir, groupsFile), jsonData, 0644); err != nil {
+		return false, fmt.Sprintf("Failed to write groups: %v", err)
+	}
+	return true, ""
+}
+
+// removeMemberEverywhere drops location from every group after the record is deleted.
+func (fs *FileSystemDatabase) removeMemberEverywhere(location string) (bool, string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	groups, msg := fs.loadGroups()
+	if msg != "" {
+		return false, msg
+	}
+	if len(groups) == 0 {
+		return true, ""
+	}
+
+	for name, members := range groups {
+		groups[name] = removeString(members, location)
+	}
+	return fs.saveGroups(groups)
+}
+
+func removeString(values []string, value string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+	return result
+}
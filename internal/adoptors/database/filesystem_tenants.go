@@ -0,0 +1,33 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// tenantsDir holds one subdirectory per tenant, each a database of its own.
+const tenantsDir = ".tenants"
+
+// Tenant returns the database of a tenant, kept in its own subdirectory.
+func (fs *FileSystemDatabase) Tenant(id string) (bool, string, ports.Database) {
+	if !filepath.IsLocal(id) || filepath.Base(id) != id {
+		return false, "Invalid tenant", nil
+	}
+	tenant := NewFileSystemDatabase(filepath.Join(fs.BaseDir, tenantsDir, id))
+	tenant.PollInterval = fs.PollInterval
+	return true, "", tenant
+}
+
+// DropTenant removes the subdirectory of a tenant with everything in it.
+func (fs *FileSystemDatabase) DropTenant(id string) (bool, string) {
+	if !filepath.IsLocal(id) || filepath.Base(id) != id {
+		return false, "Invalid tenant"
+	}
+	if err := os.RemoveAll(filepath.Join(fs.BaseDir, tenantsDir, id)); err != nil {
+		return false, fmt.Sprintf("Failed to delete tenant: %v", err)
+	}
+	return true, ""
+}
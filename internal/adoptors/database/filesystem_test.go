@@ -3,6 +3,7 @@ package database
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -296,3 +297,26 @@ func TestFileSystemDatabase_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestFileSystemDatabase_List(t *testing.T) {
+	baseDir := t.TempDir()
+	db := NewFileSystemDatabase(baseDir)
+	db.Create("test/john.json", map[string]interface{}{"name": "John Doe"})
+	db.Create("test/jane.json", map[string]interface{}{"name": "Jane Doe"})
+	db.Create("other/bob.json", map[string]interface{}{"name": "Bob"})
+
+	success, msg, locations := db.List("test/")
+	if !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+	want := []string{"test/jane.json", "test/john.json"}
+	if !reflect.DeepEqual(locations, want) {
+		t.Errorf("Expected locations %v but got %v", want, locations)
+	}
+
+	// A base directory that does not exist yet lists nothing
+	empty := NewFileSystemDatabase(filepath.Join(baseDir, "missing"))
+	if success, _, locations := empty.List(""); !success || len(locations) != 0 {
+		t.Errorf("Expected empty listing but got %v", locations)
+	}
+}
@@ -0,0 +1,535 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// journalFile holds the writes of a committing transaction, relative to BaseDir.
+// It is written before any record is touched and removed once all writes are
+// applied, so a crash mid-commit is rolled forward by replaying it.
+const journalFile = ".journal.json"
+
+type journalEntry struct {
+	Location string                 `json:"location"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+	Deleted  bool                   `json:"deleted,omitempty"`
+	// Groups replaces the groups file when Location is groupsFile.
+	Groups map[string][]string `json:"groups,omitempty"`
+}
+
+// errTxConflict is returned when a file a transaction looked at was changed
+// by someone else before it committed.
+var errTxConflict = errors.New("Transaction conflicts with a concurrent write")
+
+// fileSystemTx buffers writes in memory until the transaction commits. It
+// remembers the state of every file it looked at so the commit can tell
+// whether another writer changed them in the meantime.
+type fileSystemTx struct {
+	fs       *FileSystemDatabase
+	pending  map[string]*journalEntry
+	order    []string
+	observed map[string]observedFile
+	// groups is loaded on first use; groupsChanged tells whether the
+	// transaction wrote it.
+	groups        map[string][]string
+	groupsChanged bool
+}
+
+// observedFile is the state of a file when a transaction first looked at it.
+type observedFile struct {
+	exists bool
+	state  fileState
+}
+
+// WithTx runs fn against a buffered view of the database and commits its
+// writes through the journal. Transactions run one at a time, and a commit
+// fails with a conflict when a plain write changed a file the transaction
+// read or wrote.
+func (fs *FileSystemDatabase) WithTx(fn func(tx ports.Database) error) error {
+	fs.txMu.Lock()
+	defer fs.txMu.Unlock()
+
+	// Finish any earlier commit before starting on top of it.
+	fs.writeMu.Lock()
+	err := fs.replayJournal()
+	fs.writeMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tx := &fileSystemTx{fs: fs, pending: make(map[string]*journalEntry), observed: make(map[string]observedFile)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if len(tx.order) == 0 && !tx.groupsChanged {
+		return nil
+	}
+
+	entries := make([]journalEntry, 0, len(tx.order)+1)
+	for _, location := range tx.order {
+		entries = append(entries, *tx.pending[location])
+	}
+	// The groups go last so they win over the member removals of deletes
+	if tx.groupsChanged {
+		entries = append(entries, journalEntry{Location: groupsFile, Groups: tx.groups})
+	}
+
+	fs.writeMu.Lock()
+	defer fs.writeMu.Unlock()
+	conflict, err := tx.conflicts()
+	if err != nil {
+		return err
+	}
+	if conflict {
+		return errTxConflict
+	}
+	if err := fs.writeJournal(entries); err != nil {
+		return err
+	}
+	return fs.replayJournal()
+}
+
+func (fs *FileSystemDatabase) writeJournal(entries []journalEntry) error {
+	if err := os.MkdirAll(fs.BaseDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directories: %v", err)
+	}
+
+	jsonData, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to serialize journal: %v", err)
+	}
+
+	// Write to a temporary file and rename so the journal is never half written
+	tmpPath := filepath.Join(fs.BaseDir, journalFile+".tmp")
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write journal: %v", err)
+	}
+	if _, err := file.Write(jsonData); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write journal: %v", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync journal: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write journal: %v", err)
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(fs.BaseDir, journalFile)); err != nil {
+		return fmt.Errorf("failed to commit journal: %v", err)
+	}
+	return nil
+}
+
+// replayJournal applies a committed journal, if there is one, and removes it.
+// Every entry is idempotent, so replaying a partly applied journal is safe.
+func (fs *FileSystemDatabase) replayJournal() error {
+	journalPath := filepath.Join(fs.BaseDir, journalFile)
+	fileData, err := os.ReadFile(journalPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read journal: %v", err)
+	}
+
+	var entries []journalEntry
+	if err := json.Unmarshal(fileData, &entries); err != nil {
+		return fmt.Errorf("failed to parse journal: %v", err)
+	}
+
+	for _, entry := range entries {
+		if entry.Location == groupsFile {
+			groups := entry.Groups
+			if groups == nil {
+				groups = make(map[string][]string)
+			}
+			fs.mu.Lock()
+			success, msg := fs.saveGroups(groups)
+			fs.mu.Unlock()
+			if !success {
+				return errors.New(msg)
+			}
+			continue
+		}
+
+		filePath := filepath.Join(fs.BaseDir, entry.Location)
+		if entry.Deleted {
+			if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to delete file: %v", err)
+			}
+			if success, msg := fs.removeMemberEverywhere(entry.Location); !success {
+				return errors.New(msg)
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+			return fmt.Errorf("failed to create directories: %v", err)
+		}
+		jsonData, err := json.MarshalIndent(entry.Data, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to serialize data: %v", err)
+		}
+		if err := os.WriteFile(filePath, jsonData, 0644); err != nil {
+			return fmt.Errorf("failed to write file: %v", err)
+		}
+	}
+
+	if err := os.Remove(journalPath); err != nil {
+		return fmt.Errorf("failed to remove journal: %v", err)
+	}
+	return nil
+}
+
+func (tx *fileSystemTx) Create(location string, data map[string]interface{}) (bool, string) {
+	exists, msg := tx.exists(location)
+	if msg != "" {
+		return false, msg
+	}
+	if exists {
+		return false, "File already exists"
+	}
+
+	// Check the data can be stored before accepting it.
+	if _, err := json.Marshal(data); err != nil {
+		return false, fmt.Sprintf("Failed to serialize data: %v", err)
+	}
+
+	tx.record(&journalEntry{Location: location, Data: data})
+	return true, ""
+}
+
+func (tx *fileSystemTx) Read(location string) (bool, string, map[string]interface{}) {
+	entry, buffered := tx.pending[location]
+	if !buffered {
+		if !isStoredLocation(location) {
+			return false, "Invalid location", nil
+		}
+		if msg := tx.observe(location); msg != "" {
+			return false, msg, nil
+		}
+		return tx.fs.Read(location)
+	}
+	if entry.Deleted {
+		return false, "File does not exist", nil
+	}
+
+	dataCopy := make(map[string]interface{})
+	for k, v := range entry.Data {
+		dataCopy[k] = v
+	}
+	return true, "", dataCopy
+}
+
+func (tx *fileSystemTx) Update(location string, data map[string]interface{}) (bool, string) {
+	exists, msg := tx.exists(location)
+	if msg != "" {
+		return false, msg
+	}
+	if !exists {
+		return false, "File does not exist"
+	}
+
+	if _, err := json.Marshal(data); err != nil {
+		return false, fmt.Sprintf("Failed to serialize data: %v", err)
+	}
+
+	tx.record(&journalEntry{Location: location, Data: data})
+	return true, ""
+}
+
+func (tx *fileSystemTx) Delete(location string) (bool, string) {
+	exists, msg := tx.exists(location)
+	if msg != "" {
+		return false, msg
+	}
+	if !exists {
+		return false, "File does not exist"
+	}
+
+	tx.record(&journalEntry{Location: location, Deleted: true})
+
+	// Drop the record from the groups the transaction already loaded; the
+	// replay drops it from the stored ones.
+	if tx.groups != nil {
+		for name, members := range tx.groups {
+			tx.groups[name] = removeString(members, location)
+		}
+		tx.groupsChanged = true
+	}
+	return true, ""
+}
+
+func (tx *fileSystemTx) List(prefix string) (bool, string, []string) {
+	success, msg, stored := tx.fs.List(prefix)
+	if !success {
+		return false, msg, nil
+	}
+
+	// Overlay the buffered writes on the stored locations.
+	present := make(map[string]bool, len(stored))
+	for _, location := range stored {
+		present[location] = true
+	}
+	for location, entry := range tx.pending {
+		if strings.HasPrefix(location, prefix) {
+			present[location] = !entry.Deleted
+		}
+	}
+
+	locations := make([]string, 0, len(present))
+	for location, ok := range present {
+		if ok {
+			locations = append(locations, location)
+		}
+	}
+
+	sort.Strings(locations)
+	return true, "", locations
+}
+
+func (tx *fileSystemTx) exists(location string) (bool, string) {
+	if !isStoredLocation(location) {
+		return false, "Invalid location"
+	}
+	if entry, buffered := tx.pending[location]; buffered {
+		return !entry.Deleted, ""
+	}
+
+	if msg := tx.observe(location); msg != "" {
+		return false, msg
+	}
+	return tx.observed[location].exists, ""
+}
+
+// observe remembers the state of a file the first time the transaction
+// looks at it.
+func (tx *fileSystemTx) observe(location string) string {
+	if _, seen := tx.observed[location]; seen {
+		return ""
+	}
+	file, err := statFile(filepath.Join(tx.fs.BaseDir, location))
+	if err != nil {
+		return fmt.Sprintf("Failed to check file: %v", err)
+	}
+	tx.observed[location] = file
+	return ""
+}
+
+// conflicts reports whether any observed file has changed since. It runs
+// with writeMu held so nothing changes between the check and the commit.
+func (tx *fileSystemTx) conflicts() (bool, error) {
+	for location, before := range tx.observed {
+		after, err := statFile(filepath.Join(tx.fs.BaseDir, location))
+		if err != nil {
+			return false, fmt.Errorf("failed to check file: %v", err)
+		}
+		if after != before {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func statFile(filePath string) (observedFile, error) {
+	info, err := os.Stat(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return observedFile{}, nil
+	}
+	if err != nil {
+		return observedFile{}, err
+	}
+	return observedFile{exists: true, state: fileState{modTime: info.ModTime(), size: info.Size()}}, nil
+}
+
+func (tx *fileSystemTx) record(entry *journalEntry) {
+	if _, buffered := tx.pending[entry.Location]; !buffered {
+		tx.order = append(tx.order, entry.Location)
+	}
+	tx.pending[entry.Location] = entry
+}
+
+// loadGroups returns the groups as the transaction sees them, reading the
+// groups file on first use.
+func (tx *fileSystemTx) loadGroups() (map[string][]string, string) {
+	if tx.groups != nil {
+		return tx.groups, ""
+	}
+	if msg := tx.observe(groupsFile); msg != "" {
+		return nil, msg
+	}
+
+	tx.fs.mu.Lock()
+	groups, msg := tx.fs.loadGroups()
+	tx.fs.mu.Unlock()
+	if msg != "" {
+		return nil, msg
+	}
+
+	// Leave out the records deleted earlier in the transaction
+	for location, entry := range tx.pending {
+		if entry.Deleted {
+			for name, members := range groups {
+				groups[name] = removeString(members, location)
+			}
+		}
+	}
+	tx.groups = groups
+	return groups, ""
+}
+
+func (tx *fileSystemTx) CreateGroup(name string) (bool, string) {
+	groups, msg := tx.loadGroups()
+	if msg != "" {
+		return false, msg
+	}
+	if _, exists := groups[name]; exists {
+		return false, "Group already exists"
+	}
+
+	groups[name] = []string{}
+	tx.groupsChanged = true
+	return true, ""
+}
+
+func (tx *fileSystemTx) RenameGroup(name, newName string) (bool, string) {
+	groups, msg := tx.loadGroups()
+	if msg != "" {
+		return false, msg
+	}
+	members, exists := groups[name]
+	if !exists {
+		return false, "Group does not exist"
+	}
+	if _, exists := groups[newName]; exists {
+		return false, "Group already exists"
+	}
+
+	delete(groups, name)
+	groups[newName] = members
+	tx.groupsChanged = true
+	return true, ""
+}
+
+func (tx *fileSystemTx) DeleteGroup(name string) (bool, string) {
+	groups, msg := tx.loadGroups()
+	if msg != "" {
+		return false, msg
+	}
+	if _, exists := groups[name]; !exists {
+		return false, "Group does not exist"
+	}
+
+	delete(groups, name)
+	tx.groupsChanged = true
+	return true, ""
+}
+
+func (tx *fileSystemTx) ListGroups() (bool, string, []string) {
+	groups, msg := tx.loadGroups()
+	if msg != "" {
+		return false, msg, nil
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return true, "", names
+}
+
+func (tx *fileSystemTx) AddMember(group, location string) (bool, string) {
+	groups, msg := tx.loadGroups()
+	if msg != "" {
+		return false, msg
+	}
+	members, exists := groups[group]
+	if !exists {
+		return false, "Group does not exist"
+	}
+	exists, msg = tx.exists(location)
+	if msg != "" {
+		return false, msg
+	}
+	if !exists {
+		return false, "File does not exist"
+	}
+
+	for _, member := range members {
+		if member == location {
+			return true, ""
+		}
+	}
+	members = append(members, location)
+	sort.Strings(members)
+	groups[group] = members
+	tx.groupsChanged = true
+	return true, ""
+}
+
+func (tx *fileSystemTx) RemoveMember(group, location string) (bool, string) {
+	groups, msg := tx.loadGroups()
+	if msg != "" {
+		return false, msg
+	}
+	if _, exists := groups[group]; !exists {
+		return false, "Group does not exist"
+	}
+
+	groups[group] = removeString(groups[group], location)
+	tx.groupsChanged = true
+	return true, ""
+}
+
+func (tx *fileSystemTx) Members(group string) (bool, string, []string) {
+	groups, msg := tx.loadGroups()
+	if msg != "" {
+		return false, msg, nil
+	}
+	members, exists := groups[group]
+	if !exists {
+		return false, "Group does not exist", nil
+	}
+
+	return true, "", append([]string(nil), members...)
+}
+
+func (tx *fileSystemTx) GroupsOf(location string) (bool, string, []string) {
+	exists, msg := tx.exists(location)
+	if msg != "" {
+		return false, msg, nil
+	}
+	if !exists {
+		return false, "File does not exist", nil
+	}
+	groups, msg := tx.loadGroups()
+	if msg != "" {
+		return false, msg, nil
+	}
+
+	names := make([]string, 0)
+	for name, members := range groups {
+		for _, member := range members {
+			if member == location {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return true, "", names
+}
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func TestFileSystemDatabase_WithTx(t *testing.T) {
+	baseDir := t.TempDir()
+	db := NewFileSystemDatabase(baseDir)
+	db.Create("test/john.json", map[string]interface{}{"name": "John Doe"})
+
+	// Writes are visible inside the transaction but not outside until commit
+	errRollback := errors.New("rollback")
+	err := db.WithTx(func(tx ports.Database) error {
+		tx.Create("test/jane.json", map[string]interface{}{"name": "Jane Doe"})
+		tx.Delete("test/john.json")
+
+		if success, _, _ := tx.Read("test/jane.json"); !success {
+			t.Error("Expected buffered create to be readable in the transaction")
+		}
+		if _, _, locations := tx.List("test/"); !reflect.DeepEqual(locations, []string{"test/jane.json"}) {
+			t.Errorf("Unexpected locations in transaction %v", locations)
+		}
+		if success, _, _ := db.Read("test/jane.json"); success {
+			t.Error("Expected buffered create to be invisible outside the transaction")
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("Expected rollback error but got %v", err)
+	}
+	if _, _, locations := db.List(""); !reflect.DeepEqual(locations, []string{"test/john.json"}) {
+		t.Errorf("Expected rollback to leave the store untouched but got %v", locations)
+	}
+
+	err = db.WithTx(func(tx ports.Database) error {
+		tx.Create("test/jane.json", map[string]interface{}{"name": "Jane Doe"})
+		tx.Update("test/jane.json", map[string]interface{}{"name": "Jane Smith"})
+		tx.Delete("test/john.json")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected commit but got %v", err)
+	}
+	if _, _, data := db.Read("test/jane.json"); data["name"] != "Jane Smith" {
+		t.Errorf("Expected committed update but got %v", data)
+	}
+	if success, _, _ := db.Read("test/john.json"); success {
+		t.Error("Expected committed delete")
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, journalFile)); !os.IsNotExist(err) {
+		t.Error("Expected journal to be removed after commit")
+	}
+}
+
+func TestFileSystemDatabase_ReplayJournal(t *testing.T) {
+	baseDir := t.TempDir()
+	db := NewFileSystemDatabase(baseDir)
+	db.Create("test/john.json", map[string]interface{}{"name": "John Doe"})
+
+	// Simulate a crash after the journal was written but before it was applied
+	journal, _ := json.Marshal([]journalEntry{
+		{Location: "test/jane.json", Data: map[string]interface{}{"name": "Jane Doe"}},
+		{Location: "test/john.json", Deleted: true},
+	})
+	if err := os.WriteFile(filepath.Join(baseDir, journalFile), journal, 0644); err != nil {
+		t.Fatalf("Failed to write journal: %v", err)
+	}
+
+	db = NewFileSystemDatabase(baseDir)
+	if _, _, locations := db.List(""); !reflect.DeepEqual(locations, []string{"test/jane.json"}) {
+		t.Errorf("Expected journal to be replayed but got %v", locations)
+	}
+}
+
+func TestFileSystemDatabase_WithTxConflict(t *testing.T) {
+	db := NewFileSystemDatabase(t.TempDir())
+	db.Create("test/john.json", map[string]interface{}{"name": "John Doe"})
+
+	// A plain write to a record the transaction read fails the commit
+	err := db.WithTx(func(tx ports.Database) error {
+		tx.Read("test/john.json")
+		tx.Create("test/jane.json", map[string]interface{}{"name": "Jane Doe"})
+		db.Update("test/john.json", map[string]interface{}{"name": "John Smith"})
+		return nil
+	})
+	if !errors.Is(err, errTxConflict) {
+		t.Fatalf("Expected conflict but got %v", err)
+	}
+	if success, _, _ := db.Read("test/jane.json"); success {
+		t.Error("Expected conflicting transaction to be discarded")
+	}
+	if _, _, data := db.Read("test/john.json"); data["name"] != "John Smith" {
+		t.Errorf("Expected plain write to be kept but got %v", data)
+	}
+
+	// So does creating a record the transaction found missing
+	err = db.WithTx(func(tx ports.Database) error {
+		tx.Create("test/jane.json", map[string]interface{}{"name": "Jane Doe"})
+		db.Create("test/jane.json", map[string]interface{}{"name": "Jane Smith"})
+		return nil
+	})
+	if !errors.Is(err, errTxConflict) {
+		t.Fatalf("Expected conflict but got %v", err)
+	}
+	if _, _, data := db.Read("test/jane.json"); data["name"] != "Jane Smith" {
+		t.Errorf("Expected plain create to be kept but got %v", data)
+	}
+}
+
+func TestFileSystemDatabase_WithTxGroups(t *testing.T) {
+	db := NewFileSystemDatabase(t.TempDir())
+	db.Create("test/john.json", map[string]interface{}{"name": "John Doe"})
+	db.CreateGroup("friends")
+	db.AddMember("friends", "test/john.json")
+
+	// Memberships are buffered with the records and dropped on rollback
+	errRollback := errors.New("rollback")
+	err := db.WithTx(func(tx ports.Database) error {
+		groups := tx.(ports.GroupStore)
+		tx.Create("test/jane.json", map[string]interface{}{"name": "Jane Doe"})
+		if success, msg := groups.AddMember("friends", "test/jane.json"); !success {
+			t.Errorf("Expected buffered record to be added but got %s", msg)
+		}
+		if _, _, members := groups.Members("friends"); !reflect.DeepEqual(members, []string{"test/jane.json", "test/john.json"}) {
+			t.Errorf("Unexpected members in transaction %v", members)
+		}
+		if _, _, members := db.Members("friends"); !reflect.DeepEqual(members, []string{"test/john.json"}) {
+			t.Errorf("Expected buffered membership to be invisible outside the transaction but got %v", members)
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("Expected rollback error but got %v", err)
+	}
+	if _, _, members := db.Members("friends"); !reflect.DeepEqual(members, []string{"test/john.json"}) {
+		t.Errorf("Expected rollback to leave the groups untouched but got %v", members)
+	}
+
+	// A move keeps the memberships of the record it deletes
+	err = db.WithTx(func(tx ports.Database) error {
+		groups := tx.(ports.GroupStore)
+		tx.Create("test/johnny.json", map[string]interface{}{"name": "John Doe"})
+		groups.AddMember("friends", "test/johnny.json")
+		tx.Delete("test/john.json")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected commit but got %v", err)
+	}
+	if _, _, members := db.Members("friends"); !reflect.DeepEqual(members, []string{"test/johnny.json"}) {
+		t.Errorf("Expected the membership to move but got %v", members)
+	}
+
+	// A plain group write fails a transaction that read the groups
+	err = db.WithTx(func(tx ports.Database) error {
+		tx.(ports.GroupStore).CreateGroup("family")
+		db.CreateGroup("work")
+		return nil
+	})
+	if !errors.Is(err, errTxConflict) {
+		t.Fatalf("Expected conflict but got %v", err)
+	}
+	if _, _, names := db.ListGroups(); !reflect.DeepEqual(names, []string{"friends", "work"}) {
+		t.Errorf("Expected only the plain group write but got %v", names)
+	}
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Upsert creates the file exclusively and truncates the existing one if that
+// fails, so of two concurrent writers only one reports a creation.
+func (fs *FileSystemDatabase) Upsert(location string, data map[string]interface{}) (bool, string, bool) {
+	for {
+		success, message, created := fs.CreateIfAbsent(location, data)
+		if !success || created {
+			return success, message, created
+		}
+		success, message, replaced := fs.UpdateIfExists(location, data)
+		if !success || replaced {
+			return success, message, false
+		}
+		// Deleted in between, try creating it again
+	}
+}
+
+func (fs *FileSystemDatabase) CreateIfAbsent(location string, data map[string]interface{}) (bool, string, bool) {
+	fs.writeMu.RLock()
+	defer fs.writeMu.RUnlock()
+
+	if !isStoredLocation(location) {
+		return false, "Invalid location", false
+	}
+	filePath := filepath.Join(fs.BaseDir, location)
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return false, fmt.Sprintf("Failed to create directories: %v", err), false
+	}
+
+	err := writeRecordFile(filePath, os.O_CREATE|os.O_EXCL, data)
+	if errors.Is(err, os.ErrExist) {
+		return true, "", false
+	}
+	if err != nil {
+		return false, err.Error(), false
+	}
+	return true, "", true
+}
+
+func (fs *FileSystemDatabase) UpdateIfExists(location string, data map[string]interface{}) (bool, string, bool) {
+	fs.writeMu.RLock()
+	defer fs.writeMu.RUnlock()
+
+	if !isStoredLocation(location) {
+		return false, "Invalid location", false
+	}
+
+	// Opening without O_CREATE fails if the file is gone
+	err := writeRecordFile(filepath.Join(fs.BaseDir, location), os.O_TRUNC, data)
+	if errors.Is(err, os.ErrNotExist) {
+		return true, "", false
+	}
+	if err != nil {
+		return false, err.Error(), false
+	}
+	return true, "", true
+}
+
+// writeRecordFile opens filePath for writing with the extra flags and writes
+// data as JSON. It returns the open error unwrapped so callers can tell an
+// existing or missing file apart.
+func writeRecordFile(filePath string, flag int, data map[string]interface{}) error {
+	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Failed to serialize data: %v", err)
+	}
+
+	file, err := os.OpenFile(filePath, os.O_WRONLY|flag, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(jsonData); err != nil {
+		file.Close()
+		return fmt.Errorf("Failed to write file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("Failed to write file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// defaultPollInterval is used when FileSystemDatabase.PollInterval is zero.
+const defaultPollInterval = time.Second
+
+// watchClockSlack is how far a file's modification time may lag the clock
+// when it is written, as file times come from a coarser clock.
+const watchClockSlack = 50 * time.Millisecond
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// watchPosition orders the changes of a watch. Writes are ordered by the
+// modification time of their file, then by location. The deletes found by a
+// poll are placed at the time the poll started, after its writes and ahead of
+// the writes of later polls.
+type watchPosition struct {
+	at       time.Time
+	location string
+	deleted  bool
+}
+
+// cursor encodes the position as "<nanos>:<location>" for writes and
+// "<nanos>!<location>" for deletes.
+func (p watchPosition) cursor() string {
+	separator := ":"
+	if p.deleted {
+		separator = "!"
+	}
+	return strconv.FormatInt(p.at.UnixNano(), 10) + separator + p.location
+}
+
+// before reports whether a file written at modTime comes before p, so a watch
+// resuming from p has already seen it.
+func (p watchPosition) before(location string, modTime time.Time) bool {
+	if !modTime.Equal(p.at) {
+		return modTime.Before(p.at)
+	}
+	return !p.deleted && location <= p.location
+}
+
+// parseWatchCursor reads a cursor. Cursors holding only a time, from before
+// locations were added, resume like the cursor of a delete.
+func parseWatchCursor(cursor string) (watchPosition, bool) {
+	i := strings.IndexAny(cursor, ":!")
+	if i < 0 {
+		nanos, err := strconv.ParseInt(cursor, 10, 64)
+		return watchPosition{at: time.Unix(0, nanos), deleted: true}, err == nil
+	}
+	nanos, err := strconv.ParseInt(cursor[:i], 10, 64)
+	if err != nil {
+		return watchPosition{}, false
+	}
+	return watchPosition{at: time.Unix(0, nanos), location: cursor[i+1:], deleted: cursor[i] == '!'}, true
+}
+
+// Watch polls the base directory for changes. Each poll reports the files
+// written before it started, so a file written during a poll is left for the
+// next and cursors only move forward. A resumed watch reports every file
+// written after the cursor as an update; deletions that happened while nobody
+// was watching are not reported.
+func (fs *FileSystemDatabase) Watch(ctx context.Context, prefix, fromCursor string) (bool, string, <-chan ports.ChangeEvent) {
+	var since watchPosition
+	if fromCursor != "" {
+		var ok bool
+		if since, ok = parseWatchCursor(fromCursor); !ok {
+			return false, "Invalid cursor", nil
+		}
+	}
+
+	scannedAt := time.Now().Add(-watchClockSlack)
+	state, msg := fs.scan(prefix)
+	if msg != "" {
+		return false, msg, nil
+	}
+
+	interval := fs.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	out := make(chan ports.ChangeEvent)
+	go func() {
+		defer close(out)
+
+		// Catch up on files written after the cursor, leaving those written
+		// during the scan to the first poll
+		if fromCursor != "" {
+			changes := make([]fileChange, 0)
+			for location, file := range state {
+				if since.before(location, file.modTime) {
+					continue
+				}
+				if !file.modTime.Before(scannedAt) {
+					delete(state, location)
+					continue
+				}
+				changes = append(changes, fileChange{op: ports.ChangeUpdate, location: location, modTime: file.modTime})
+			}
+			if !fs.emitChanges(ctx, out, changes, scannedAt) {
+				return
+			}
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			scannedAt = time.Now().Add(-watchClockSlack)
+			current, msg := fs.scan(prefix)
+			if msg != "" {
+				continue
+			}
+
+			changes := make([]fileChange, 0)
+			for location, after := range current {
+				before, existed := state[location]
+				if existed && before == after {
+					continue
+				}
+				if !after.modTime.Before(scannedAt) {
+					// Written during the scan; keep the old state so the next
+					// poll reports it
+					if existed {
+						current[location] = before
+					} else {
+						delete(current, location)
+					}
+					continue
+				}
+				op := ports.ChangeUpdate
+				if !existed {
+					op = ports.ChangeCreate
+				}
+				changes = append(changes, fileChange{op: op, location: location, modTime: after.modTime})
+			}
+			for location := range state {
+				if _, exists := current[location]; !exists {
+					changes = append(changes, fileChange{op: ports.ChangeDelete, location: location})
+				}
+			}
+			if !fs.emitChanges(ctx, out, changes, scannedAt) {
+				return
+			}
+
+			state = current
+		}
+	}()
+
+	return true, "", out
+}
+
+// fileChange is a change found by a scan.
+type fileChange struct {
+	op       ports.ChangeOp
+	location string
+	modTime  time.Time
+}
+
+// emitChanges sends changes in cursor order, placing deletes at scannedAt.
+// It returns false when the watch has been cancelled.
+func (fs *FileSystemDatabase) emitChanges(ctx context.Context, out chan<- ports.ChangeEvent, changes []fileChange, scannedAt time.Time) bool {
+	positions := make([]watchPosition, len(changes))
+	for i, change := range changes {
+		if change.op == ports.ChangeDelete {
+			positions[i] = watchPosition{at: scannedAt, location: change.location, deleted: true}
+		} else {
+			positions[i] = watchPosition{at: change.modTime, location: change.location}
+		}
+	}
+	order := make([]int, len(changes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := positions[order[i]], positions[order[j]]
+		if !a.at.Equal(b.at) {
+			return a.at.Before(b.at)
+		}
+		if a.deleted != b.deleted {
+			return a.deleted
+		}
+		return a.location < b.location
+	})
+
+	for _, i := range order {
+		if !fs.emit(ctx, out, changes[i].op, changes[i].location, positions[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// emit sends one event, reading the record for creates and updates. It
+// returns false when the watch has been cancelled.
+func (fs *FileSystemDatabase) emit(ctx context.Context, out chan<- ports.ChangeEvent, op ports.ChangeOp, location string, position watchPosition) bool {
+	event := ports.ChangeEvent{
+		Cursor:   position.cursor(),
+		Op:       op,
+		Location: location,
+		Time:     position.at,
+	}
+	if op != ports.ChangeDelete {
+		success, _, data := fs.Read(location)
+		if !success {
+			// Removed since the scan; the next poll reports the delete
+			return true
+		}
+		event.Data = data
+	}
+
+	select {
+	case out <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// scan returns the state of every record starting with prefix.
+func (fs *FileSystemDatabase) scan(prefix string) (map[string]fileState, string) {
+	success, msg, locations := fs.List(prefix)
+	if !success {
+		return nil, msg
+	}
+
+	state := make(map[string]fileState, len(locations))
+	for _, location := range locations {
+		info, err := os.Stat(filepath.Join(fs.BaseDir, location))
+		if err != nil {
+			continue
+		}
+		state[location] = fileState{modTime: info.ModTime(), size: info.Size()}
+	}
+	return state, ""
+}
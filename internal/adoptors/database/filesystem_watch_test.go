@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func TestFileSystemDatabase_Watch(t *testing.T) {
+	db := NewFileSystemDatabase(t.TempDir())
+	db.PollInterval = 10 * time.Millisecond
+	db.Create("test/before.json", map[string]interface{}{"name": "Before"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	success, msg, events := db.Watch(ctx, "test/", "")
+	if !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+
+	db.Create("test/john.json", map[string]interface{}{"name": "John Doe"})
+	created := receiveChange(t, events)
+	if created.Op != ports.ChangeCreate || created.Location != "test/john.json" || created.Data["name"] != "John Doe" {
+		t.Errorf("Unexpected create event %+v", created)
+	}
+
+	db.Update("test/john.json", map[string]interface{}{"name": "John Doe Updated"})
+	updated := receiveChange(t, events)
+	if updated.Op != ports.ChangeUpdate || updated.Data["name"] != "John Doe Updated" {
+		t.Errorf("Unexpected update event %+v", updated)
+	}
+
+	db.Delete("test/john.json")
+	if deleted := receiveChange(t, events); deleted.Op != ports.ChangeDelete {
+		t.Errorf("Unexpected delete event %+v", deleted)
+	}
+
+	// Resuming reports files written after the cursor
+	db.Create("test/jane.json", map[string]interface{}{"name": "Jane Doe"})
+	success, msg, resumed := db.Watch(ctx, "test/", created.Cursor)
+	if !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+	if event := receiveChange(t, resumed); event.Location != "test/jane.json" {
+		t.Errorf("Expected catch-up event for jane but got %+v", event)
+	}
+}
+
+func TestFileSystemDatabase_WatchResumesWithinBatch(t *testing.T) {
+	db := NewFileSystemDatabase(t.TempDir())
+	db.PollInterval = 10 * time.Millisecond
+	written := time.Now().Add(-time.Minute)
+	for _, file := range []struct {
+		location string
+		modTime  time.Time
+	}{
+		{"test/a.json", written},
+		{"test/b.json", written},
+		{"test/c.json", written.Add(-time.Second)},
+	} {
+		db.Create(file.location, map[string]interface{}{"name": file.location})
+		if err := os.Chtimes(filepath.Join(db.BaseDir, file.location), file.modTime, file.modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Every file is written after the start of time, in one catch-up batch
+	success, msg, events := db.Watch(ctx, "test/", "0:")
+	if !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+	var batch []ports.ChangeEvent
+	for i := 0; i < 3; i++ {
+		batch = append(batch, receiveChange(t, events))
+	}
+	want := []string{"test/c.json", "test/a.json", "test/b.json"}
+	for i, event := range batch {
+		if event.Location != want[i] {
+			t.Errorf("Expected event %d for %s but got %+v", i, want[i], event)
+		}
+	}
+	if batch[1].Cursor == batch[2].Cursor {
+		t.Errorf("Expected every event to have its own cursor but got %q twice", batch[1].Cursor)
+	}
+
+	// Resuming from any event reports exactly the rest of the batch
+	for i, event := range batch {
+		resumeCtx, cancelResume := context.WithCancel(ctx)
+		success, msg, resumed := db.Watch(resumeCtx, "test/", event.Cursor)
+		if !success {
+			t.Fatalf("Expected success but got error: %s", msg)
+		}
+		for _, location := range want[i+1:] {
+			if got := receiveChange(t, resumed); got.Location != location {
+				t.Errorf("Resuming after %s: expected %s but got %+v", event.Location, location, got)
+			}
+		}
+		select {
+		case got := <-resumed:
+			t.Errorf("Resuming after %s: expected no more events but got %+v", event.Location, got)
+		case <-time.After(50 * time.Millisecond):
+		}
+		cancelResume()
+	}
+
+	if success, _, _ := db.Watch(ctx, "test/", "yesterday"); success {
+		t.Error("Expected an invalid cursor to be rejected")
+	}
+}
@@ -1,17 +1,37 @@
 package database
 
 import (
+	"sort"
+	"strings"
 	"sync"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
 )
 
 type InMemoryDatabase struct {
-	store map[string]map[string]interface{}
-	mu    sync.RWMutex
+	store  map[string]map[string]interface{}
+	groups map[string]map[string]bool
+	blobs  map[string][]byte
+	mu     sync.RWMutex
+
+	// Recent changes for watchers, the sequence number of the last one and a
+	// channel closed whenever a change is recorded.
+	changes   []ports.ChangeEvent
+	changeSeq uint64
+	changed   chan struct{}
+
+	// inTx is set on the view a transaction writes through, which logs how
+	// to undo each write in undo.
+	inTx bool
+	undo []func()
 }
 
 func NewInMemoryDatabase() *InMemoryDatabase {
 	return &InMemoryDatabase{
-		store: make(map[string]map[string]interface{}),
+		store:   make(map[string]map[string]interface{}),
+		groups:  make(map[string]map[string]bool),
+		blobs:   make(map[string][]byte),
+		changed: make(chan struct{}),
 	}
 }
 
@@ -25,7 +45,9 @@ func (db *InMemoryDatabase) Create(location string, data map[string]interface{})
 	}
 
 	// Store the data.
+	db.saveRecord(location)
 	db.store[location] = data
+	db.recordChange(ports.ChangeCreate, location, data)
 	return true, ""
 }
 
@@ -58,7 +80,9 @@ func (db *InMemoryDatabase) Update(location string, data map[string]interface{})
 	}
 
 	// Update the data.
+	db.saveRecord(location)
 	db.store[location] = data
+	db.recordChange(ports.ChangeUpdate, location, data)
 	return true, ""
 }
 
@@ -71,7 +95,29 @@ func (db *InMemoryDatabase) Delete(location string) (bool, string) {
 		return false, "Location does not exist"
 	}
 
-	// Delete the data.
+	// Delete the data and its group memberships.
+	db.saveRecord(location)
 	delete(db.store, location)
+	for _, members := range db.groups {
+		db.saveMember(members, location)
+		delete(members, location)
+	}
+	db.recordChange(ports.ChangeDelete, location, nil)
 	return true, ""
 }
+
+func (db *InMemoryDatabase) List(prefix string) (bool, string, []string) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	// Collect the matching locations.
+	locations := make([]string, 0)
+	for location := range db.store {
+		if strings.HasPrefix(location, prefix) {
+			locations = append(locations, location)
+		}
+	}
+
+	sort.Strings(locations)
+	return true, "", locations
+}
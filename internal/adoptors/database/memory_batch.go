@@ -0,0 +1,88 @@
+package database
+
+import (
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func (db *InMemoryDatabase) BatchRead(locations []string) (bool, string, map[string]map[string]interface{}) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	records := make(map[string]map[string]interface{}, len(locations))
+	for _, location := range locations {
+		data, exists := db.store[location]
+		if !exists {
+			continue
+		}
+		dataCopy := make(map[string]interface{}, len(data))
+		for k, v := range data {
+			dataCopy[k] = v
+		}
+		records[location] = dataCopy
+	}
+
+	return true, "", records
+}
+
+func (db *InMemoryDatabase) BatchCreate(items []ports.BatchItem) []ports.BatchResult {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	results := make([]ports.BatchResult, len(items))
+	for i, item := range items {
+		results[i].Location = item.Location
+		if _, exists := db.store[item.Location]; exists {
+			results[i].Message = "Location already exists"
+			continue
+		}
+		db.saveRecord(item.Location)
+		db.store[item.Location] = item.Data
+		db.recordChange(ports.ChangeCreate, item.Location, item.Data)
+		results[i].Success = true
+	}
+
+	return results
+}
+
+func (db *InMemoryDatabase) BatchUpsert(items []ports.BatchItem) []ports.BatchResult {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	results := make([]ports.BatchResult, len(items))
+	for i, item := range items {
+		op := ports.ChangeUpdate
+		if _, exists := db.store[item.Location]; !exists {
+			op = ports.ChangeCreate
+		}
+		db.saveRecord(item.Location)
+		db.store[item.Location] = item.Data
+		db.recordChange(op, item.Location, item.Data)
+		results[i] = ports.BatchResult{Location: item.Location, Success: true}
+	}
+
+	return results
+}
+
+func (db *InMemoryDatabase) BatchDelete(locations []string) []ports.BatchResult {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	results := make([]ports.BatchResult, len(locations))
+	for i, location := range locations {
+		results[i].Location = location
+		if _, exists := db.store[location]; !exists {
+			results[i].Message = "Location does not exist"
+			continue
+		}
+		db.saveRecord(location)
+		delete(db.store, location)
+		for _, members := range db.groups {
+			db.saveMember(members, location)
+			delete(members, location)
+		}
+		db.recordChange(ports.ChangeDelete, location, nil)
+		results[i].Success = true
+	}
+
+	return results
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func TestInMemoryDatabase_Batch(t *testing.T) {
+	db := NewInMemoryDatabase()
+	db.Create("contacts/existing.json", map[string]interface{}{"name": "Existing"})
+
+	results := db.BatchCreate([]ports.BatchItem{
+		{Location: "contacts/john.json", Data: map[string]interface{}{"name": "John Doe"}},
+		{Location: "contacts/existing.json", Data: map[string]interface{}{"name": "Clobbered"}},
+		{Location: "contacts/jane.json", Data: map[string]interface{}{"name": "Jane Doe"}},
+	})
+	if !results[0].Success || results[1].Success || !results[2].Success {
+		t.Errorf("Unexpected create results %+v", results)
+	}
+	if results[1].Message != "Location already exists" {
+		t.Errorf("Expected error message %q but got %q", "Location already exists", results[1].Message)
+	}
+	if _, _, data := db.Read("contacts/existing.json"); data["name"] != "Existing" {
+		t.Errorf("Expected existing record to be kept but got %v", data)
+	}
+
+	results = db.BatchUpsert([]ports.BatchItem{
+		{Location: "contacts/existing.json", Data: map[string]interface{}{"name": "Replaced"}},
+		{Location: "contacts/new.json", Data: map[string]interface{}{"name": "New"}},
+	})
+	for _, result := range results {
+		if !result.Success {
+			t.Errorf("Expected upsert of %s to succeed but got %q", result.Location, result.Message)
+		}
+	}
+	if _, _, data := db.Read("contacts/existing.json"); data["name"] != "Replaced" {
+		t.Errorf("Expected existing record to be replaced but got %v", data)
+	}
+
+	success, msg, records := db.BatchRead([]string{"contacts/existing.json", "contacts/nobody.json", "contacts/jane.json"})
+	if !success {
+		t.Fatalf("Expected batch read to succeed but got %q", msg)
+	}
+	if len(records) != 2 || records["contacts/existing.json"]["name"] != "Replaced" || records["contacts/jane.json"]["name"] != "Jane Doe" {
+		t.Errorf("Unexpected read records %v", records)
+	}
+
+	results = db.BatchDelete([]string{"contacts/john.json", "contacts/nobody.json", "contacts/john.json"})
+	if !results[0].Success || results[1].Success || results[2].Success {
+		t.Errorf("Unexpected delete results %+v", results)
+	}
+	if _, _, locations := db.List(""); len(locations) != 3 {
+		t.Errorf("Expected 3 remaining records but got %v", locations)
+	}
+}
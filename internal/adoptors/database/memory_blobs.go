@@ -0,0 +1,55 @@
+package database
+
+import (
+	"sort"
+)
+
+func (db *InMemoryDatabase) PutBlob(hash string, content []byte) (bool, string) {
+	if message := checkBlob(hash, content); message != "" {
+		return false, message
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, exists := db.blobs[hash]; !exists {
+		db.saveBlob(hash)
+		db.blobs[hash] = append([]byte(nil), content...)
+	}
+	return true, ""
+}
+
+func (db *InMemoryDatabase) GetBlob(hash string) (bool, string, []byte) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	content, exists := db.blobs[hash]
+	if !exists {
+		return false, "Blob does not exist", nil
+	}
+	return true, "", append([]byte(nil), content...)
+}
+
+func (db *InMemoryDatabase) DeleteBlob(hash string) (bool, string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, exists := db.blobs[hash]; !exists {
+		return false, "Blob does not exist"
+	}
+	db.saveBlob(hash)
+	delete(db.blobs, hash)
+	return true, ""
+}
+
+func (db *InMemoryDatabase) ListBlobs() (bool, string, []string) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	hashes := make([]string, 0, len(db.blobs))
+	for hash := range db.blobs {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+	return true, "", hashes
+}
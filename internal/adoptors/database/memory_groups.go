@@ -0,0 +1,132 @@
+package database
+
+import (
+	"sort"
+)
+
+func (db *InMemoryDatabase) CreateGroup(name string) (bool, string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	// Check if the group already exists.
+	if _, exists := db.groups[name]; exists {
+		return false, "Group already exists"
+	}
+
+	db.saveGroup(name)
+	db.groups[name] = make(map[string]bool)
+	return true, ""
+}
+
+func (db *InMemoryDatabase) RenameGroup(name, newName string) (bool, string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	members, exists := db.groups[name]
+	if !exists {
+		return false, "Group does not exist"
+	}
+	if _, exists := db.groups[newName]; exists {
+		return false, "Group already exists"
+	}
+
+	db.saveGroup(name)
+	db.saveGroup(newName)
+	delete(db.groups, name)
+	db.groups[newName] = members
+	return true, ""
+}
+
+func (db *InMemoryDatabase) DeleteGroup(name string) (bool, string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, exists := db.groups[name]; !exists {
+		return false, "Group does not exist"
+	}
+
+	db.saveGroup(name)
+	delete(db.groups, name)
+	return true, ""
+}
+
+func (db *InMemoryDatabase) ListGroups() (bool, string, []string) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	names := make([]string, 0, len(db.groups))
+	for name := range db.groups {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return true, "", names
+}
+
+func (db *InMemoryDatabase) AddMember(group, location string) (bool, string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	members, exists := db.groups[group]
+	if !exists {
+		return false, "Group does not exist"
+	}
+	if _, exists := db.store[location]; !exists {
+		return false, "Location does not exist"
+	}
+
+	db.saveMember(members, location)
+	members[location] = true
+	return true, ""
+}
+
+func (db *InMemoryDatabase) RemoveMember(group, location string) (bool, string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	members, exists := db.groups[group]
+	if !exists {
+		return false, "Group does not exist"
+	}
+
+	db.saveMember(members, location)
+	delete(members, location)
+	return true, ""
+}
+
+func (db *InMemoryDatabase) Members(group string) (bool, string, []string) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	members, exists := db.groups[group]
+	if !exists {
+		return false, "Group does not exist", nil
+	}
+
+	locations := make([]string, 0, len(members))
+	for location := range members {
+		locations = append(locations, location)
+	}
+
+	sort.Strings(locations)
+	return true, "", locations
+}
+
+func (db *InMemoryDatabase) GroupsOf(location string) (bool, string, []string) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if _, exists := db.store[location]; !exists {
+		return false, "Location does not exist", nil
+	}
+
+	names := make([]string, 0)
+	for name, members := range db.groups {
+		if members[location] {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return true, "", names
+}
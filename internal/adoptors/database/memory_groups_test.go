@@ -0,0 +1,60 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInMemoryDatabase_Groups(t *testing.T) {
+	db := NewInMemoryDatabase()
+	db.Create("contacts/john.json", map[string]interface{}{"name": "John Doe"})
+	db.Create("contacts/jane.json", map[string]interface{}{"name": "Jane Doe"})
+
+	if success, msg := db.CreateGroup("family"); !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+	if success, msg := db.CreateGroup("family"); success || msg != "Group already exists" {
+		t.Errorf("Expected duplicate group error but got %q", msg)
+	}
+	if success, msg := db.AddMember("missing", "contacts/john.json"); success || msg != "Group does not exist" {
+		t.Errorf("Expected missing group error but got %q", msg)
+	}
+	if success, msg := db.AddMember("family", "contacts/nobody.json"); success || msg != "Location does not exist" {
+		t.Errorf("Expected missing location error but got %q", msg)
+	}
+
+	db.AddMember("family", "contacts/john.json")
+	db.AddMember("family", "contacts/jane.json")
+	db.CreateGroup("work")
+	db.AddMember("work", "contacts/john.json")
+
+	if _, _, members := db.Members("family"); !reflect.DeepEqual(members, []string{"contacts/jane.json", "contacts/john.json"}) {
+		t.Errorf("Unexpected members %v", members)
+	}
+	if _, _, groups := db.GroupsOf("contacts/john.json"); !reflect.DeepEqual(groups, []string{"family", "work"}) {
+		t.Errorf("Unexpected groups %v", groups)
+	}
+
+	// Renaming keeps members
+	if success, msg := db.RenameGroup("family", "relatives"); !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+	if _, _, members := db.Members("relatives"); len(members) != 2 {
+		t.Errorf("Expected members to survive rename but got %v", members)
+	}
+
+	// Deleting a contact removes its memberships
+	db.Delete("contacts/john.json")
+	if _, _, members := db.Members("work"); len(members) != 0 {
+		t.Errorf("Expected deleted contact to leave groups but got %v", members)
+	}
+
+	db.RemoveMember("relatives", "contacts/jane.json")
+	db.DeleteGroup("work")
+	if _, _, names := db.ListGroups(); !reflect.DeepEqual(names, []string{"relatives"}) {
+		t.Errorf("Unexpected groups %v", names)
+	}
+	if _, _, members := db.Members("relatives"); len(members) != 0 {
+		t.Errorf("Expected no members but got %v", members)
+	}
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func (db *InMemoryDatabase) Patch(location string, fields map[string]interface{}) (bool, string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	current, exists := db.store[location]
+	if !exists {
+		return false, "Location does not exist"
+	}
+
+	// Records are replaced rather than modified, which transactions rely on
+	data := make(map[string]interface{}, len(current)+len(fields))
+	for k, v := range current {
+		data[k] = v
+	}
+	for k, v := range fields {
+		if v == nil {
+			delete(data, k)
+		} else {
+			data[k] = v
+		}
+	}
+	db.saveRecord(location)
+	db.store[location] = data
+	db.recordChange(ports.ChangeUpdate, location, data)
+	return true, ""
+}
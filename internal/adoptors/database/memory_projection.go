@@ -0,0 +1,29 @@
+package database
+
+import (
+	"strings"
+)
+
+func (db *InMemoryDatabase) ReadFields(location string, fields []string) (bool, string, map[string]interface{}) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	data, exists := db.store[location]
+	if !exists {
+		return false, "Location does not exist", nil
+	}
+	return true, "", project(data, fields)
+}
+
+func (db *InMemoryDatabase) ListFields(prefix string, fields []string) (bool, string, map[string]map[string]interface{}) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	records := make(map[string]map[string]interface{})
+	for location, data := range db.store {
+		if strings.HasPrefix(location, prefix) {
+			records[location] = project(data, fields)
+		}
+	}
+	return true, "", records
+}
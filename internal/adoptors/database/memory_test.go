@@ -343,3 +343,23 @@ func TestInMemoryDatabase_Concurrency(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestInMemoryDatabase_List(t *testing.T) {
+	db := NewInMemoryDatabase()
+	db.Create("contacts/john.json", map[string]interface{}{"name": "John Doe"})
+	db.Create("contacts/jane.json", map[string]interface{}{"name": "Jane Doe"})
+	db.Create("other/bob.json", map[string]interface{}{"name": "Bob"})
+
+	success, msg, locations := db.List("contacts/")
+	if !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+	want := []string{"contacts/jane.json", "contacts/john.json"}
+	if fmt.Sprint(locations) != fmt.Sprint(want) {
+		t.Errorf("Expected locations %v but got %v", want, locations)
+	}
+
+	if _, _, locations := db.List("missing/"); len(locations) != 0 {
+		t.Errorf("Expected no locations but got %v", locations)
+	}
+}
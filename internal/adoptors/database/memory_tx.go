@@ -0,0 +1,115 @@
+package database
+
+import (
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// WithTx runs fn against the live data, logging how to undo each write. The
+// log is replayed backwards if fn fails and discarded otherwise, so a
+// transaction costs as much as its writes. Other callers wait until the
+// transaction finishes.
+func (db *InMemoryDatabase) WithTx(fn func(tx ports.Database) error) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tx := &InMemoryDatabase{
+		store:   db.store,
+		groups:  db.groups,
+		blobs:   db.blobs,
+		changed: make(chan struct{}),
+		inTx:    true,
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.rollback()
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	committed = true
+
+	// A nested transaction is undone with the one it runs in
+	if db.inTx {
+		db.undo = append(db.undo, tx.undo...)
+	}
+
+	// Publish the transaction's changes to the watchers
+	for _, change := range tx.changes {
+		db.recordChange(change.Op, change.Location, change.Data)
+	}
+	return nil
+}
+
+// rollback undoes the writes of a transaction, newest first.
+func (db *InMemoryDatabase) rollback() {
+	for i := len(db.undo) - 1; i >= 0; i-- {
+		db.undo[i]()
+	}
+	db.undo = nil
+}
+
+// saveRecord logs how to restore the record at location before a write. It
+// does nothing outside transactions. The caller must hold the write lock.
+func (db *InMemoryDatabase) saveRecord(location string) {
+	if !db.inTx {
+		return
+	}
+	data, exists := db.store[location]
+	db.undo = append(db.undo, func() {
+		if exists {
+			db.store[location] = data
+		} else {
+			delete(db.store, location)
+		}
+	})
+}
+
+// saveGroup logs how to restore the group called name before it is created,
+// renamed or deleted.
+func (db *InMemoryDatabase) saveGroup(name string) {
+	if !db.inTx {
+		return
+	}
+	members, exists := db.groups[name]
+	db.undo = append(db.undo, func() {
+		if exists {
+			db.groups[name] = members
+		} else {
+			delete(db.groups, name)
+		}
+	})
+}
+
+// saveMember logs how to restore whether location is a member of members.
+func (db *InMemoryDatabase) saveMember(members map[string]bool, location string) {
+	if !db.inTx {
+		return
+	}
+	member := members[location]
+	db.undo = append(db.undo, func() {
+		if member {
+			members[location] = true
+		} else {
+			delete(members, location)
+		}
+	})
+}
+
+// saveBlob logs how to restore the blob stored under hash before it is put or
+// deleted.
+func (db *InMemoryDatabase) saveBlob(hash string) {
+	if !db.inTx {
+		return
+	}
+	content, exists := db.blobs[hash]
+	db.undo = append(db.undo, func() {
+		if exists {
+			db.blobs[hash] = content
+		} else {
+			delete(db.blobs, hash)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func TestInMemoryDatabase_WithTx(t *testing.T) {
+	db := NewInMemoryDatabase()
+	db.Create("contacts/john.json", map[string]interface{}{"name": "John Doe"})
+	db.CreateGroup("family")
+	db.AddMember("family", "contacts/john.json")
+
+	// A failing transaction leaves no trace
+	errRollback := errors.New("rollback")
+	err := db.WithTx(func(tx ports.Database) error {
+		tx.Create("contacts/jane.json", map[string]interface{}{"name": "Jane Doe"})
+		tx.Delete("contacts/john.json")
+		tx.(ports.GroupStore).DeleteGroup("family")
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("Expected rollback error but got %v", err)
+	}
+	if success, _, _ := db.Read("contacts/jane.json"); success {
+		t.Error("Expected rolled back create to be discarded")
+	}
+	if success, _, _ := db.Read("contacts/john.json"); !success {
+		t.Error("Expected rolled back delete to be discarded")
+	}
+	if _, _, members := db.Members("family"); len(members) != 1 {
+		t.Errorf("Expected group to be untouched but got %v", members)
+	}
+
+	// A successful transaction applies every write
+	err = db.WithTx(func(tx ports.Database) error {
+		if success, msg := tx.Create("contacts/jane.json", map[string]interface{}{"name": "Jane Doe"}); !success {
+			return errors.New(msg)
+		}
+		if success, msg := tx.Delete("contacts/john.json"); !success {
+			return errors.New(msg)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected commit but got %v", err)
+	}
+	if _, _, locations := db.List(""); len(locations) != 1 || locations[0] != "contacts/jane.json" {
+		t.Errorf("Unexpected locations after commit %v", locations)
+	}
+}
+
+func TestInMemoryDatabase_WithTxRollsBackEveryWrite(t *testing.T) {
+	db := NewInMemoryDatabase()
+	db.Create("contacts/john.json", map[string]interface{}{"name": "John Doe"})
+	db.Create("contacts/jane.json", map[string]interface{}{"name": "Jane Doe"})
+	db.CreateGroup("family")
+	db.CreateGroup("work")
+	db.AddMember("family", "contacts/john.json")
+	db.AddMember("work", "contacts/jane.json")
+
+	errRollback := errors.New("rollback")
+	err := db.WithTx(func(tx ports.Database) error {
+		groups := tx.(ports.GroupStore)
+		tx.Update("contacts/john.json", map[string]interface{}{"name": "Johnny"})
+		tx.(ports.PatchDatabase).Patch("contacts/jane.json", map[string]interface{}{"name": "Janet"})
+		tx.(ports.BatchDatabase).BatchUpsert([]ports.BatchItem{{Location: "contacts/bob.json", Data: map[string]interface{}{"name": "Bob"}}})
+		groups.AddMember("work", "contacts/john.json")
+		groups.RemoveMember("work", "contacts/jane.json")
+		groups.RenameGroup("family", "relatives")
+		groups.CreateGroup("friends")
+		tx.Delete("contacts/john.json")
+		// Nested transactions roll back with the outer one
+		if err := tx.(ports.Transactor).WithTx(func(nested ports.Database) error {
+			nested.Delete("contacts/jane.json")
+			return nil
+		}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("Expected rollback error but got %v", err)
+	}
+
+	if _, _, locations := db.List(""); len(locations) != 2 {
+		t.Errorf("Expected the original records but got %v", locations)
+	}
+	for location, name := range map[string]string{"contacts/john.json": "John Doe", "contacts/jane.json": "Jane Doe"} {
+		if _, _, data := db.Read(location); data["name"] != name {
+			t.Errorf("Expected %s to be %q but got %v", location, name, data)
+		}
+	}
+	if _, _, groups := db.ListGroups(); len(groups) != 2 || groups[0] != "family" || groups[1] != "work" {
+		t.Errorf("Expected the original groups but got %v", groups)
+	}
+	if _, _, members := db.Members("family"); len(members) != 1 || members[0] != "contacts/john.json" {
+		t.Errorf("Expected the original family members but got %v", members)
+	}
+	if _, _, members := db.Members("work"); len(members) != 1 || members[0] != "contacts/jane.json" {
+		t.Errorf("Expected the original work members but got %v", members)
+	}
+}
+
+func TestInMemoryDatabase_WithTxRollsBackBlobs(t *testing.T) {
+	db := NewInMemoryDatabase()
+	photo, contract := []byte("photo"), []byte("contract")
+	db.PutBlob(blobHash(photo), photo)
+
+	errRollback := errors.New("rollback")
+	err := db.WithTx(func(tx ports.Database) error {
+		blobs := tx.(ports.BlobStore)
+		blobs.PutBlob(blobHash(contract), contract)
+		blobs.DeleteBlob(blobHash(photo))
+		// Nested transactions roll back with the outer one
+		if err := tx.(ports.Transactor).WithTx(func(nested ports.Database) error {
+			nested.(ports.BlobStore).DeleteBlob(blobHash(contract))
+			return nil
+		}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("Expected rollback error but got %v", err)
+	}
+
+	if _, _, hashes := db.ListBlobs(); len(hashes) != 1 || hashes[0] != blobHash(photo) {
+		t.Errorf("Expected only the original blob but got %v", hashes)
+	}
+	if _, _, content := db.GetBlob(blobHash(photo)); string(content) != "photo" {
+		t.Errorf("Expected the deleted blob to be restored but got %q", content)
+	}
+
+	// A successful transaction keeps its blobs
+	err = db.WithTx(func(tx ports.Database) error {
+		if success, msg := tx.(ports.BlobStore).PutBlob(blobHash(contract), contract); !success {
+			return errors.New(msg)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected commit but got %v", err)
+	}
+	if success, _, _ := db.GetBlob(blobHash(contract)); !success {
+		t.Error("Expected the committed blob to be kept")
+	}
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func (db *InMemoryDatabase) Upsert(location string, data map[string]interface{}) (bool, string, bool) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	_, exists := db.store[location]
+	op := ports.ChangeUpdate
+	if !exists {
+		op = ports.ChangeCreate
+	}
+	db.saveRecord(location)
+	db.store[location] = data
+	db.recordChange(op, location, data)
+	return true, "", !exists
+}
+
+func (db *InMemoryDatabase) CreateIfAbsent(location string, data map[string]interface{}) (bool, string, bool) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, exists := db.store[location]; exists {
+		return true, "", false
+	}
+	db.saveRecord(location)
+	db.store[location] = data
+	db.recordChange(ports.ChangeCreate, location, data)
+	return true, "", true
+}
+
+func (db *InMemoryDatabase) UpdateIfExists(location string, data map[string]interface{}) (bool, string, bool) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, exists := db.store[location]; !exists {
+		return true, "", false
+	}
+	db.saveRecord(location)
+	db.store[location] = data
+	db.recordChange(ports.ChangeUpdate, location, data)
+	return true, "", true
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// maxMemoryChanges is how many recent changes are kept for resuming watches.
+const maxMemoryChanges = 1000
+
+// recordChange appends a change to the log and wakes the watchers. The caller
+// must hold the write lock.
+func (db *InMemoryDatabase) recordChange(op ports.ChangeOp, location string, data map[string]interface{}) {
+	var dataCopy map[string]interface{}
+	if data != nil {
+		dataCopy = make(map[string]interface{}, len(data))
+		for k, v := range data {
+			dataCopy[k] = v
+		}
+	}
+
+	db.changeSeq++
+	db.changes = append(db.changes, ports.ChangeEvent{
+		Cursor:   strconv.FormatUint(db.changeSeq, 10),
+		Op:       op,
+		Location: location,
+		Data:     dataCopy,
+		Time:     time.Now(),
+	})
+	if len(db.changes) > maxMemoryChanges {
+		db.changes = append([]ports.ChangeEvent(nil), db.changes[len(db.changes)-maxMemoryChanges:]...)
+	}
+
+	close(db.changed)
+	db.changed = make(chan struct{})
+}
+
+// changesSince returns the logged changes from sequence number next onwards
+// and a channel that is closed on the next change. lapped is true when some
+// of those changes have already been dropped from the log. The caller must
+// hold the read lock.
+func (db *InMemoryDatabase) changesSince(next uint64) (events []ports.ChangeEvent, wait <-chan struct{}, lapped bool) {
+	oldest := db.changeSeq - uint64(len(db.changes)) + 1
+	if next < oldest {
+		return nil, nil, true
+	}
+	if next > db.changeSeq {
+		return nil, db.changed, false
+	}
+	return append([]ports.ChangeEvent(nil), db.changes[next-oldest:]...), db.changed, false
+}
+
+func (db *InMemoryDatabase) Watch(ctx context.Context, prefix, fromCursor string) (bool, string, <-chan ports.ChangeEvent) {
+	db.mu.RLock()
+	next := db.changeSeq + 1
+	if fromCursor != "" {
+		seq, err := strconv.ParseUint(fromCursor, 10, 64)
+		if err != nil {
+			db.mu.RUnlock()
+			return false, "Invalid cursor", nil
+		}
+		next = seq + 1
+		if _, _, lapped := db.changesSince(next); lapped {
+			db.mu.RUnlock()
+			return false, ports.CursorExpired, nil
+		}
+	}
+	db.mu.RUnlock()
+
+	out := make(chan ports.ChangeEvent)
+	go func() {
+		defer close(out)
+
+		for {
+			db.mu.RLock()
+			events, wait, lapped := db.changesSince(next)
+			db.mu.RUnlock()
+
+			// The watcher fell too far behind; the consumer must resume
+			if lapped {
+				return
+			}
+
+			for _, event := range events {
+				next++
+				if !strings.HasPrefix(event.Location, prefix) {
+					continue
+				}
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if len(events) == 0 {
+				select {
+				case <-wait:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return true, "", out
+}
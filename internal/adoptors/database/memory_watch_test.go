@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func receiveChange(t *testing.T, events <-chan ports.ChangeEvent) ports.ChangeEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("Expected an event but the watch was closed")
+		}
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for an event")
+	}
+	return ports.ChangeEvent{}
+}
+
+func TestInMemoryDatabase_Watch(t *testing.T) {
+	db := NewInMemoryDatabase()
+	db.Create("contacts/before.json", map[string]interface{}{"name": "Before"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	success, msg, events := db.Watch(ctx, "contacts/", "")
+	if !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+
+	db.Create("other/skipped.json", map[string]interface{}{"name": "Skipped"})
+	db.Create("contacts/john.json", map[string]interface{}{"name": "John Doe"})
+	db.Update("contacts/john.json", map[string]interface{}{"name": "John Updated"})
+	db.Delete("contacts/john.json")
+
+	created := receiveChange(t, events)
+	if created.Op != ports.ChangeCreate || created.Location != "contacts/john.json" || created.Data["name"] != "John Doe" {
+		t.Errorf("Unexpected create event %+v", created)
+	}
+	updated := receiveChange(t, events)
+	if updated.Op != ports.ChangeUpdate || updated.Data["name"] != "John Updated" {
+		t.Errorf("Unexpected update event %+v", updated)
+	}
+	deleted := receiveChange(t, events)
+	if deleted.Op != ports.ChangeDelete || deleted.Data != nil {
+		t.Errorf("Unexpected delete event %+v", deleted)
+	}
+
+	// Resuming from a cursor replays what came after it
+	success, msg, resumed := db.Watch(ctx, "contacts/", created.Cursor)
+	if !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+	if event := receiveChange(t, resumed); event.Cursor != updated.Cursor {
+		t.Errorf("Expected to resume at %s but got %+v", updated.Cursor, event)
+	}
+
+	// Cancelling closes the stream
+	cancel()
+	for range events {
+	}
+}
+
+func TestInMemoryDatabase_WatchInvalidCursor(t *testing.T) {
+	db := NewInMemoryDatabase()
+	if success, msg, _ := db.Watch(context.Background(), "", "abc"); success || msg != "Invalid cursor" {
+		t.Errorf("Expected invalid cursor error but got %q", msg)
+	}
+
+	for i := 0; i < maxMemoryChanges+10; i++ {
+		db.Create("contacts/"+time.Now().String(), map[string]interface{}{"i": i})
+	}
+	if success, msg, _ := db.Watch(context.Background(), "", "1"); success || msg != "Cursor expired" {
+		t.Errorf("Expected expired cursor error but got %q", msg)
+	}
+}
+
+func TestInMemoryDatabase_WatchTx(t *testing.T) {
+	db := NewInMemoryDatabase()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, _, events := db.Watch(ctx, "", "")
+
+	db.WithTx(func(tx ports.Database) error {
+		tx.Create("contacts/john.json", map[string]interface{}{"name": "John Doe"})
+		return nil
+	})
+
+	if event := receiveChange(t, events); event.Location != "contacts/john.json" {
+		t.Errorf("Expected committed change to be published but got %+v", event)
+	}
+}
@@ -0,0 +1,959 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// MirrorConsistency decides what a write does when a secondary fails it.
+type MirrorConsistency string
+
+const (
+	// MirrorBestEffort keeps the write and queues the secondary for repair.
+	MirrorBestEffort MirrorConsistency = "best-effort"
+	// MirrorStrict reverts the write everywhere and fails it.
+	MirrorStrict MirrorConsistency = "strict"
+)
+
+// MirroredDatabase applies every write to a primary database and then to one
+// or more secondaries, for backend migrations and hot standbys. Reads and
+// watches use the primary. Writes to secondaries create or replace, so a
+// secondary catches up on records it has not been given yet.
+//
+// Writes are serialized so that every store sees them in the same order.
+// Inside a transaction the secondaries only see the writes once it commits;
+// failures then are always queued for repair, whatever the consistency.
+type MirroredDatabase struct {
+	primary     ports.Database
+	secondaries []ports.Database
+	consistency MirrorConsistency
+	shadowReads bool
+
+	// pending queues the secondaries' writes inside a transaction until it
+	// commits; it is nil outside of one.
+	pending *[]func()
+	state   *mirrorState
+}
+
+// mirrorState is shared by a database and its transactions.
+type mirrorState struct {
+	// mu serializes writes
+	mu sync.Mutex
+
+	repairsMu sync.Mutex
+	repairs   map[mirrorRepair]bool
+}
+
+// mirrorRepair names a record, group or blob a secondary has drifted on.
+type mirrorRepair struct {
+	secondary int
+	kind      mirrorKind
+	name      string
+}
+
+// mirrorKind tells what a repair names.
+type mirrorKind int
+
+const (
+	mirrorRecord mirrorKind = iota
+	mirrorGroup
+	mirrorBlob
+)
+
+type MirrorOption func(*MirroredDatabase)
+
+// WithConsistency sets how writes treat failing secondaries. The default is
+// MirrorBestEffort.
+func WithConsistency(consistency MirrorConsistency) MirrorOption {
+	return func(m *MirroredDatabase) {
+		m.consistency = consistency
+	}
+}
+
+// WithShadowReads also reads every record from the secondaries, logging
+// where they diverge from the primary and queueing them for repair.
+func WithShadowReads() MirrorOption {
+	return func(m *MirroredDatabase) {
+		m.shadowReads = true
+	}
+}
+
+func NewMirroredDatabase(primary ports.Database, secondaries []ports.Database, opts ...MirrorOption) *MirroredDatabase {
+	m := &MirroredDatabase{
+		primary:     primary,
+		secondaries: secondaries,
+		consistency: MirrorBestEffort,
+		state:       &mirrorState{repairs: make(map[mirrorRepair]bool)},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *MirroredDatabase) Create(location string, data map[string]interface{}) (bool, string) {
+	return m.writeRecord(location, func(db ports.Database) (bool, string) {
+		return db.Create(location, data)
+	}, func(db ports.Database) (bool, string) {
+		return putRecord(db, location, data)
+	})
+}
+
+func (m *MirroredDatabase) Read(location string) (bool, string, map[string]interface{}) {
+	success, message, data := m.primary.Read(location)
+	if m.shadowReads && m.pending == nil {
+		for i, secondary := range m.secondaries {
+			found, _, shadow := secondary.Read(location)
+			if found != success || (success && !sameRecord(data, shadow)) {
+				log.WithFields(log.Fields{"secondary": i, "location": location}).Warn("Mirrored record diverges from the primary")
+				m.queueRepair(mirrorRepair{secondary: i, name: location})
+			}
+		}
+	}
+	return success, message, data
+}
+
+func (m *MirroredDatabase) Update(location string, data map[string]interface{}) (bool, string) {
+	return m.writeRecord(location, func(db ports.Database) (bool, string) {
+		return db.Update(location, data)
+	}, func(db ports.Database) (bool, string) {
+		return putRecord(db, location, data)
+	})
+}
+
+func (m *MirroredDatabase) Delete(location string) (bool, string) {
+	return m.writeRecord(location, func(db ports.Database) (bool, string) {
+		return db.Delete(location)
+	}, func(db ports.Database) (bool, string) {
+		return deleteRecord(db, location)
+	})
+}
+
+func (m *MirroredDatabase) Upsert(location string, data map[string]interface{}) (bool, string, bool) {
+	return m.writeConditional(location, data, upsertRecord, true)
+}
+
+func (m *MirroredDatabase) CreateIfAbsent(location string, data map[string]interface{}) (bool, string, bool) {
+	return m.writeConditional(location, data, createRecordIfAbsent, false)
+}
+
+func (m *MirroredDatabase) UpdateIfExists(location string, data map[string]interface{}) (bool, string, bool) {
+	return m.writeConditional(location, data, updateRecordIfExists, false)
+}
+
+func (m *MirroredDatabase) List(prefix string) (bool, string, []string) {
+	return m.primary.List(prefix)
+}
+
+func (m *MirroredDatabase) ReadFields(location string, fields []string) (bool, string, map[string]interface{}) {
+	return readFields(m.primary, location, fields)
+}
+
+func (m *MirroredDatabase) ListFields(prefix string, fields []string) (bool, string, map[string]map[string]interface{}) {
+	return listFields(m.primary, prefix, fields)
+}
+
+func (m *MirroredDatabase) CreateGroup(name string) (bool, string) {
+	return m.writeGroup([]string{name}, func(groups ports.GroupStore) (bool, string) {
+		return groups.CreateGroup(name)
+	}, func(groups ports.GroupStore) (bool, string) {
+		return groups.DeleteGroup(name)
+	})
+}
+
+func (m *MirroredDatabase) RenameGroup(name, newName string) (bool, string) {
+	return m.writeGroup([]string{name, newName}, func(groups ports.GroupStore) (bool, string) {
+		return groups.RenameGroup(name, newName)
+	}, func(groups ports.GroupStore) (bool, string) {
+		return groups.RenameGroup(newName, name)
+	})
+}
+
+func (m *MirroredDatabase) DeleteGroup(name string) (bool, string) {
+	var members []string
+	if groups, ok := m.primary.(ports.GroupStore); ok {
+		_, _, members = groups.Members(name)
+	}
+	return m.writeGroup([]string{name}, func(groups ports.GroupStore) (bool, string) {
+		return groups.DeleteGroup(name)
+	}, func(groups ports.GroupStore) (bool, string) {
+		if success, message := groups.CreateGroup(name); !success {
+			return false, message
+		}
+		for _, member := range members {
+			if success, message := groups.AddMember(name, member); !success {
+				return false, message
+			}
+		}
+		return true, ""
+	})
+}
+
+func (m *MirroredDatabase) ListGroups() (bool, string, []string) {
+	groups, ok := m.primary.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error(), nil
+	}
+	return groups.ListGroups()
+}
+
+func (m *MirroredDatabase) AddMember(group, location string) (bool, string) {
+	return m.writeGroup([]string{group}, func(groups ports.GroupStore) (bool, string) {
+		return groups.AddMember(group, location)
+	}, func(groups ports.GroupStore) (bool, string) {
+		return groups.RemoveMember(group, location)
+	})
+}
+
+func (m *MirroredDatabase) RemoveMember(group, location string) (bool, string) {
+	return m.writeGroup([]string{group}, func(groups ports.GroupStore) (bool, string) {
+		return groups.RemoveMember(group, location)
+	}, func(groups ports.GroupStore) (bool, string) {
+		return groups.AddMember(group, location)
+	})
+}
+
+func (m *MirroredDatabase) Members(group string) (bool, string, []string) {
+	groups, ok := m.primary.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error(), nil
+	}
+	return groups.Members(group)
+}
+
+func (m *MirroredDatabase) GroupsOf(location string) (bool, string, []string) {
+	groups, ok := m.primary.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error(), nil
+	}
+	return groups.GroupsOf(location)
+}
+
+// BatchRead reads from the primary.
+func (m *MirroredDatabase) BatchRead(locations []string) (bool, string, map[string]map[string]interface{}) {
+	return readRecords(m.primary, locations)
+}
+
+// BatchCreate and the other batches write to the primary in one batch when
+// it supports them. Strict consistency writes item by item instead, so each
+// failure can be reverted.
+func (m *MirroredDatabase) BatchCreate(items []ports.BatchItem) []ports.BatchResult {
+	return m.batchWrite(items, func(batch ports.BatchDatabase) []ports.BatchResult {
+		return batch.BatchCreate(items)
+	}, m.Create)
+}
+
+func (m *MirroredDatabase) BatchUpsert(items []ports.BatchItem) []ports.BatchResult {
+	return m.batchWrite(items, func(batch ports.BatchDatabase) []ports.BatchResult {
+		return batch.BatchUpsert(items)
+	}, func(location string, data map[string]interface{}) (bool, string) {
+		success, message, _ := m.Upsert(location, data)
+		return success, message
+	})
+}
+
+func (m *MirroredDatabase) BatchDelete(locations []string) []ports.BatchResult {
+	items := make([]ports.BatchItem, len(locations))
+	for i, location := range locations {
+		items[i] = ports.BatchItem{Location: location}
+	}
+	return m.batchWrite(items, func(batch ports.BatchDatabase) []ports.BatchResult {
+		return batch.BatchDelete(locations)
+	}, func(location string, _ map[string]interface{}) (bool, string) {
+		return m.Delete(location)
+	})
+}
+
+// PutBlob stores the blob on the primary and then the secondaries.
+func (m *MirroredDatabase) PutBlob(hash string, content []byte) (bool, string) {
+	return m.writeBlob(hash, func(store ports.BlobStore) (bool, string) {
+		return store.PutBlob(hash, content)
+	})
+}
+
+func (m *MirroredDatabase) GetBlob(hash string) (bool, string, []byte) {
+	store, ok := m.primary.(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error(), nil
+	}
+	return store.GetBlob(hash)
+}
+
+func (m *MirroredDatabase) DeleteBlob(hash string) (bool, string) {
+	return m.writeBlob(hash, func(store ports.BlobStore) (bool, string) {
+		return store.DeleteBlob(hash)
+	})
+}
+
+func (m *MirroredDatabase) ListBlobs() (bool, string, []string) {
+	store, ok := m.primary.(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error(), nil
+	}
+	return store.ListBlobs()
+}
+
+// WithTx runs fn in a transaction of the primary.
+func (m *MirroredDatabase) WithTx(fn func(tx ports.Database) error) error {
+	transactor, ok := m.primary.(ports.Transactor)
+	if !ok {
+		return fn(m)
+	}
+
+	var pending []func()
+	err := transactor.WithTx(func(tx ports.Database) error {
+		pending = nil
+		return fn(&MirroredDatabase{
+			primary:     tx,
+			secondaries: m.secondaries,
+			consistency: m.consistency,
+			pending:     &pending,
+			state:       m.state,
+		})
+	})
+	if err == nil && len(pending) > 0 {
+		m.state.mu.Lock()
+		defer m.state.mu.Unlock()
+		for _, write := range pending {
+			write()
+		}
+	}
+	return err
+}
+
+func (m *MirroredDatabase) Watch(ctx context.Context, prefix, fromCursor string) (bool, string, <-chan ports.ChangeEvent) {
+	watcher, ok := m.primary.(ports.Watcher)
+	if !ok {
+		return false, domain.ErrWatchNotSupported.Error(), nil
+	}
+	return watcher.Watch(ctx, prefix, fromCursor)
+}
+
+// PendingRepairs returns how many records and groups are queued for repair.
+func (m *MirroredDatabase) PendingRepairs() int {
+	m.state.repairsMu.Lock()
+	defer m.state.repairsMu.Unlock()
+	return len(m.state.repairs)
+}
+
+// Repair copies the primary's state of every queued record, group and blob to
+// the secondary that drifted on it. Those that still fail stay queued.
+func (m *MirroredDatabase) Repair() (bool, string, int) {
+	m.state.repairsMu.Lock()
+	repairs := make([]mirrorRepair, 0, len(m.state.repairs))
+	for repair := range m.state.repairs {
+		repairs = append(repairs, repair)
+	}
+	m.state.repairs = make(map[mirrorRepair]bool)
+	m.state.repairsMu.Unlock()
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+	repaired, failed := 0, ""
+	for _, repair := range repairs {
+		var success bool
+		var message string
+		switch repair.kind {
+		case mirrorGroup:
+			success, message = syncGroup(m.primary, m.secondaries[repair.secondary], repair.name)
+		case mirrorBlob:
+			success, message = syncBlob(m.primary, m.secondaries[repair.secondary], repair.name)
+		default:
+			success, message = syncRecord(m.primary, m.secondaries[repair.secondary], repair.name)
+		}
+		if !success {
+			m.queueRepair(repair)
+			failed = message
+			continue
+		}
+		repaired++
+	}
+	if failed != "" {
+		return false, failed, repaired
+	}
+	return true, "", repaired
+}
+
+// MirrorReport counts what reconciliation fixed on one secondary.
+type MirrorReport struct {
+	Secondary int
+	Copied    int
+	Deleted   int
+	Groups    int
+	Blobs     int
+}
+
+// Reconcile compares every record, group and blob of each secondary with the
+// primary and fixes the differences, clearing the repair queue.
+func (m *MirroredDatabase) Reconcile() (bool, string, []MirrorReport) {
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	success, message, locations := m.primary.List("")
+	if !success {
+		return false, message, nil
+	}
+	reports := make([]MirrorReport, len(m.secondaries))
+	for i, secondary := range m.secondaries {
+		report := MirrorReport{Secondary: i}
+		success, message, existing := secondary.List("")
+		if !success {
+			return false, message, nil
+		}
+		stale := make(map[string]bool, len(existing))
+		for _, location := range existing {
+			stale[location] = true
+		}
+
+		for _, location := range locations {
+			delete(stale, location)
+			_, _, data := m.primary.Read(location)
+			if found, _, shadow := secondary.Read(location); found && sameRecord(data, shadow) {
+				continue
+			}
+			if success, message := putRecord(secondary, location, data); !success {
+				return false, fmt.Sprintf("Error reconciling %s: %s", location, message), nil
+			}
+			report.Copied++
+		}
+		for _, location := range sortedSet(stale) {
+			if success, message := deleteRecord(secondary, location); !success {
+				return false, fmt.Sprintf("Error reconciling %s: %s", location, message), nil
+			}
+			report.Deleted++
+		}
+
+		names, err := groupNames(m.primary, secondary)
+		if err != nil {
+			return false, err.Error(), nil
+		}
+		for _, name := range names {
+			changed, message := groupDiffers(m.primary, secondary, name)
+			if message != "" {
+				return false, message, nil
+			}
+			if !changed {
+				continue
+			}
+			if success, message := syncGroup(m.primary, secondary, name); !success {
+				return false, message, nil
+			}
+			report.Groups++
+		}
+
+		blobs, err := blobDifferences(m.primary, secondary)
+		if err != nil {
+			return false, err.Error(), nil
+		}
+		for _, hash := range blobs {
+			if success, message := syncBlob(m.primary, secondary, hash); !success {
+				return false, fmt.Sprintf("Error reconciling blob %s: %s", hash, message), nil
+			}
+			report.Blobs++
+		}
+		reports[i] = report
+	}
+
+	m.state.repairsMu.Lock()
+	m.state.repairs = make(map[mirrorRepair]bool)
+	m.state.repairsMu.Unlock()
+	return true, "", reports
+}
+
+// Start repairs the queue every repairInterval and reconciles everything
+// every reconcileInterval, until ctx is cancelled. A zero interval disables
+// that job.
+func (m *MirroredDatabase) Start(ctx context.Context, repairInterval, reconcileInterval time.Duration) {
+	tick := func(interval time.Duration) (<-chan time.Time, func()) {
+		if interval <= 0 {
+			return nil, func() {}
+		}
+		ticker := time.NewTicker(interval)
+		return ticker.C, ticker.Stop
+	}
+	repairs, stopRepairs := tick(repairInterval)
+	reconciles, stopReconciles := tick(reconcileInterval)
+
+	go func() {
+		defer stopRepairs()
+		defer stopReconciles()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-repairs:
+				if m.PendingRepairs() == 0 {
+					continue
+				}
+				if success, message, _ := m.Repair(); !success {
+					log.Printf("Error repairing mirrors: %v", message)
+				}
+			case <-reconciles:
+				success, message, reports := m.Reconcile()
+				if !success {
+					log.Printf("Error reconciling mirrors: %v", message)
+					continue
+				}
+				for _, report := range reports {
+					if report.Copied+report.Deleted+report.Groups > 0 {
+						log.Printf("Reconciled secondary %d: %d copied, %d deleted, %d groups", report.Secondary, report.Copied, report.Deleted, report.Groups)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// writeRecord writes to the primary and then the secondaries. Strict
+// consistency first takes a snapshot of the record, to put back when a
+// secondary fails.
+func (m *MirroredDatabase) writeRecord(location string, write, mirror func(ports.Database) (bool, string)) (bool, string) {
+	if m.pending != nil {
+		success, message := write(m.primary)
+		if success {
+			*m.pending = append(*m.pending, func() { m.mirrorBestEffort(location, mirrorRecord, mirror) })
+		}
+		return success, message
+	}
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+	if m.consistency != MirrorStrict {
+		success, message := write(m.primary)
+		if success {
+			m.mirrorBestEffort(location, mirrorRecord, mirror)
+		}
+		return success, message
+	}
+
+	existed, _, previous := m.primary.Read(location)
+	revert := func(db ports.Database) (bool, string) {
+		if existed {
+			return putRecord(db, location, previous)
+		}
+		return deleteRecord(db, location)
+	}
+	success, message := write(m.primary)
+	if !success {
+		return false, message
+	}
+	return m.mirrorStrict([]string{location}, mirrorRecord, mirror, revert)
+}
+
+// writeConditional applies one of the conditional writes to the primary and
+// mirrors it only if it wrote anything. always means the write happens
+// whatever the third result of write says.
+func (m *MirroredDatabase) writeConditional(location string, data map[string]interface{}, write func(ports.Database, string, map[string]interface{}) (bool, string, bool), always bool) (bool, string, bool) {
+	var result bool
+	success, message := m.writeRecord(location, func(db ports.Database) (bool, string) {
+		var success bool
+		var message string
+		success, message, result = write(db, location, data)
+		return success, message
+	}, func(db ports.Database) (bool, string) {
+		if !always && !result {
+			return true, ""
+		}
+		return putRecord(db, location, data)
+	})
+	return success, message, result
+}
+
+// writeBlob writes a blob to the primary and then copies its state to the
+// secondaries. Strict consistency first takes the blob's content, to put
+// back when a secondary fails.
+func (m *MirroredDatabase) writeBlob(hash string, write func(ports.BlobStore) (bool, string)) (bool, string) {
+	store, ok := m.primary.(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error()
+	}
+
+	if m.pending != nil {
+		success, message := write(store)
+		if success {
+			*m.pending = append(*m.pending, func() { m.mirrorBestEffort(hash, mirrorBlob, nil) })
+		}
+		return success, message
+	}
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+	if m.consistency != MirrorStrict {
+		success, message := write(store)
+		if success {
+			m.mirrorBestEffort(hash, mirrorBlob, nil)
+		}
+		return success, message
+	}
+
+	existed, _, previous := store.GetBlob(hash)
+	revert := func(db ports.Database) (bool, string) {
+		target, ok := db.(ports.BlobStore)
+		if !ok {
+			return false, domain.ErrBlobsNotSupported.Error()
+		}
+		if existed {
+			return target.PutBlob(hash, previous)
+		}
+		if success, message := target.DeleteBlob(hash); !success && !missing(message) {
+			return false, message
+		}
+		return true, ""
+	}
+	mirror := func(db ports.Database) (bool, string) {
+		return syncBlob(m.primary, db, hash)
+	}
+	success, message := write(store)
+	if !success {
+		return false, message
+	}
+	return m.mirrorStrict([]string{hash}, mirrorBlob, mirror, revert)
+}
+
+func (m *MirroredDatabase) writeGroup(names []string, write, undo func(ports.GroupStore) (bool, string)) (bool, string) {
+	groups, ok := m.primary.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+	mirror := func(db ports.Database) (bool, string) {
+		groups, ok := db.(ports.GroupStore)
+		if !ok {
+			return false, domain.ErrGroupsNotSupported.Error()
+		}
+		return write(groups)
+	}
+	revert := func(db ports.Database) (bool, string) {
+		groups, ok := db.(ports.GroupStore)
+		if !ok {
+			return false, domain.ErrGroupsNotSupported.Error()
+		}
+		return undo(groups)
+	}
+
+	if m.pending != nil {
+		success, message := write(groups)
+		if success {
+			*m.pending = append(*m.pending, func() {
+				for _, name := range names {
+					m.mirrorBestEffort(name, mirrorGroup, nil)
+				}
+			})
+		}
+		return success, message
+	}
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+	success, message := write(groups)
+	if !success {
+		return false, message
+	}
+	if m.consistency != MirrorStrict {
+		for i, secondary := range m.secondaries {
+			if success, message := mirror(secondary); !success {
+				m.secondaryFailed(i, names, mirrorGroup, message)
+			}
+		}
+		return true, ""
+	}
+	return m.mirrorStrict(names, mirrorGroup, mirror, revert)
+}
+
+// mirrorBestEffort applies a write to each secondary, queueing those that
+// fail. A nil mirror syncs the whole record, group or blob from the primary.
+func (m *MirroredDatabase) mirrorBestEffort(name string, kind mirrorKind, mirror func(ports.Database) (bool, string)) {
+	for i, secondary := range m.secondaries {
+		var success bool
+		var message string
+		switch {
+		case mirror != nil:
+			success, message = mirror(secondary)
+		case kind == mirrorGroup:
+			success, message = syncGroup(m.primary, secondary, name)
+		case kind == mirrorBlob:
+			success, message = syncBlob(m.primary, secondary, name)
+		default:
+			success, message = syncRecord(m.primary, secondary, name)
+		}
+		if !success {
+			m.secondaryFailed(i, []string{name}, kind, message)
+		}
+	}
+}
+
+// mirrorStrict applies a write to each secondary and, when one fails,
+// reverts it on the primary and on the secondaries already written. What
+// cannot be reverted is queued for repair.
+func (m *MirroredDatabase) mirrorStrict(names []string, kind mirrorKind, mirror, revert func(ports.Database) (bool, string)) (bool, string) {
+	for i, secondary := range m.secondaries {
+		success, message := mirror(secondary)
+		if success {
+			continue
+		}
+
+		if reverted, revertMessage := revert(m.primary); !reverted {
+			log.WithField("name", names[0]).Errorf("Failed to revert a mirrored write on the primary: %s", revertMessage)
+			for j := range m.secondaries {
+				m.secondaryFailed(j, names, kind, revertMessage)
+			}
+		} else {
+			for j := 0; j < i; j++ {
+				if reverted, revertMessage := revert(m.secondaries[j]); !reverted {
+					m.secondaryFailed(j, names, kind, revertMessage)
+				}
+			}
+		}
+		return false, fmt.Sprintf("Error mirroring %s to secondary %d: %s", names[0], i, message)
+	}
+	return true, ""
+}
+
+func (m *MirroredDatabase) batchWrite(items []ports.BatchItem, write func(ports.BatchDatabase) []ports.BatchResult, single func(string, map[string]interface{}) (bool, string)) []ports.BatchResult {
+	batch, ok := m.primary.(ports.BatchDatabase)
+	if !ok || m.consistency == MirrorStrict {
+		results := make([]ports.BatchResult, len(items))
+		for i, item := range items {
+			success, message := single(item.Location, item.Data)
+			results[i] = ports.BatchResult{Location: item.Location, Success: success, Message: message}
+		}
+		return results
+	}
+
+	if m.pending == nil {
+		m.state.mu.Lock()
+		defer m.state.mu.Unlock()
+	}
+	results := write(batch)
+	for _, result := range results {
+		if !result.Success {
+			continue
+		}
+		location := result.Location
+		if m.pending != nil {
+			*m.pending = append(*m.pending, func() { m.mirrorBestEffort(location, mirrorRecord, nil) })
+		} else {
+			m.mirrorBestEffort(location, mirrorRecord, nil)
+		}
+	}
+	return results
+}
+
+func (m *MirroredDatabase) secondaryFailed(secondary int, names []string, kind mirrorKind, message string) {
+	for _, name := range names {
+		log.WithFields(log.Fields{"secondary": secondary, "name": name}).Warnf("Mirrored write failed, queued for repair: %s", message)
+		m.queueRepair(mirrorRepair{secondary: secondary, kind: kind, name: name})
+	}
+}
+
+func (m *MirroredDatabase) queueRepair(repair mirrorRepair) {
+	m.state.repairsMu.Lock()
+	defer m.state.repairsMu.Unlock()
+	m.state.repairs[repair] = true
+}
+
+// putRecord creates or replaces a record.
+func putRecord(db ports.Database, location string, data map[string]interface{}) (bool, string) {
+	success, message, _ := upsertRecord(db, location, data)
+	return success, message
+}
+
+// deleteRecord deletes a record that may not exist.
+func deleteRecord(db ports.Database, location string) (bool, string) {
+	success, message := db.Delete(location)
+	if !success && missing(message) {
+		return true, ""
+	}
+	return success, message
+}
+
+// syncRecord copies the primary's state of one record to a secondary.
+func syncRecord(primary, secondary ports.Database, location string) (bool, string) {
+	if success, message, data := primary.Read(location); success {
+		return putRecord(secondary, location, data)
+	} else if !missing(message) {
+		return false, message
+	}
+	return deleteRecord(secondary, location)
+}
+
+// syncBlob copies the primary's state of one blob to a secondary.
+func syncBlob(primary, secondary ports.Database, hash string) (bool, string) {
+	from, ok := primary.(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error()
+	}
+	to, ok := secondary.(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error()
+	}
+
+	success, message, content := from.GetBlob(hash)
+	if success {
+		return to.PutBlob(hash, content)
+	}
+	if !missing(message) {
+		return false, message
+	}
+	if success, message := to.DeleteBlob(hash); !success && !missing(message) {
+		return false, message
+	}
+	return true, ""
+}
+
+// syncGroup makes a secondary's group match the primary's, creating or
+// deleting it as needed.
+func syncGroup(primary, secondary ports.Database, name string) (bool, string) {
+	from, ok := primary.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+	to, ok := secondary.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+
+	success, message, members := from.Members(name)
+	if !success {
+		if !missing(message) {
+			return false, message
+		}
+		if success, message := to.DeleteGroup(name); !success && !missing(message) {
+			return false, message
+		}
+		return true, ""
+	}
+
+	current := make(map[string]bool)
+	if success, message, existing := to.Members(name); success {
+		for _, member := range existing {
+			current[member] = true
+		}
+	} else if !missing(message) {
+		return false, message
+	} else if success, message := to.CreateGroup(name); !success {
+		return false, message
+	}
+	for _, member := range members {
+		if current[member] {
+			delete(current, member)
+			continue
+		}
+		if success, message := to.AddMember(name, member); !success {
+			return false, message
+		}
+	}
+	for member := range current {
+		if success, message := to.RemoveMember(name, member); !success {
+			return false, message
+		}
+	}
+	return true, ""
+}
+
+// groupNames returns the groups of either database, sorted.
+func groupNames(primary, secondary ports.Database) ([]string, error) {
+	names := make(map[string]bool)
+	for _, db := range []ports.Database{primary, secondary} {
+		groups, ok := db.(ports.GroupStore)
+		if !ok {
+			continue
+		}
+		success, message, list := groups.ListGroups()
+		if !success {
+			return nil, errors.New(message)
+		}
+		for _, name := range list {
+			names[name] = true
+		}
+	}
+	return sortedSet(names), nil
+}
+
+// blobDifferences returns the blobs only one of the databases holds, sorted.
+// Blobs are named by their content, so those both hold are the same.
+func blobDifferences(primary, secondary ports.Database) ([]string, error) {
+	held := make(map[string]int)
+	for _, db := range []ports.Database{primary, secondary} {
+		store, ok := db.(ports.BlobStore)
+		if !ok {
+			return nil, nil
+		}
+		success, message, hashes := store.ListBlobs()
+		if !success {
+			return nil, errors.New(message)
+		}
+		for _, hash := range hashes {
+			held[hash]++
+		}
+	}
+
+	differing := make(map[string]bool)
+	for hash, count := range held {
+		if count == 1 {
+			differing[hash] = true
+		}
+	}
+	return sortedSet(differing), nil
+}
+
+// groupDiffers reports whether a group's members differ between databases.
+func groupDiffers(primary, secondary ports.Database, name string) (bool, string) {
+	read := func(db ports.Database) ([]string, bool, string) {
+		groups, ok := db.(ports.GroupStore)
+		if !ok {
+			return nil, false, ""
+		}
+		success, message, members := groups.Members(name)
+		if !success {
+			if missing(message) {
+				return nil, false, ""
+			}
+			return nil, false, message
+		}
+		return members, true, ""
+	}
+
+	want, wantExists, message := read(primary)
+	if message != "" {
+		return false, message
+	}
+	have, haveExists, message := read(secondary)
+	if message != "" {
+		return false, message
+	}
+	return wantExists != haveExists || strings.Join(want, "\x00") != strings.Join(have, "\x00"), ""
+}
+
+// sameRecord compares records by their JSON encoding, which is the same for
+// equal data whatever number types a backend decodes to.
+func sameRecord(a, b map[string]interface{}) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
+
+func sortedSet(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// missing reports whether message is an adapter's missing-record or
+// missing-group message.
+func missing(message string) bool {
+	return strings.HasSuffix(message, "does not exist")
+}
@@ -0,0 +1,275 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// flakyDatabase fails every write while down.
+type flakyDatabase struct {
+	*InMemoryDatabase
+	down bool
+}
+
+func (f *flakyDatabase) Create(location string, data map[string]interface{}) (bool, string) {
+	if f.down {
+		return false, "connection refused"
+	}
+	return f.InMemoryDatabase.Create(location, data)
+}
+
+func (f *flakyDatabase) Update(location string, data map[string]interface{}) (bool, string) {
+	if f.down {
+		return false, "connection refused"
+	}
+	return f.InMemoryDatabase.Update(location, data)
+}
+
+func (f *flakyDatabase) Delete(location string) (bool, string) {
+	if f.down {
+		return false, "connection refused"
+	}
+	return f.InMemoryDatabase.Delete(location)
+}
+
+func (f *flakyDatabase) Upsert(location string, data map[string]interface{}) (bool, string, bool) {
+	if f.down {
+		return false, "connection refused", false
+	}
+	return f.InMemoryDatabase.Upsert(location, data)
+}
+
+func (f *flakyDatabase) AddMember(group, location string) (bool, string) {
+	if f.down {
+		return false, "connection refused"
+	}
+	return f.InMemoryDatabase.AddMember(group, location)
+}
+
+func (f *flakyDatabase) PutBlob(hash string, content []byte) (bool, string) {
+	if f.down {
+		return false, "connection refused"
+	}
+	return f.InMemoryDatabase.PutBlob(hash, content)
+}
+
+func TestMirroredDatabase_MirrorsWrites(t *testing.T) {
+	primary := NewInMemoryDatabase()
+	secondaries := []ports.Database{NewInMemoryDatabase(), NewInMemoryDatabase()}
+	require.True(t, mustSucceed(primary.Create("contacts/old", map[string]interface{}{"name": "Old"})))
+	db := NewMirroredDatabase(primary, secondaries)
+
+	tests := []struct {
+		name     string
+		write    func() (bool, string)
+		location string
+		expected map[string]interface{}
+	}{
+		{"create", func() (bool, string) {
+			return db.Create("contacts/john", map[string]interface{}{"name": "John"})
+		}, "contacts/john", map[string]interface{}{"name": "John"}},
+		{"update", func() (bool, string) {
+			return db.Update("contacts/john", map[string]interface{}{"name": "Johnny"})
+		}, "contacts/john", map[string]interface{}{"name": "Johnny"}},
+		{"update of a record not mirrored yet", func() (bool, string) {
+			return db.Update("contacts/old", map[string]interface{}{"name": "Older"})
+		}, "contacts/old", map[string]interface{}{"name": "Older"}},
+		{"delete", func() (bool, string) {
+			return db.Delete("contacts/john")
+		}, "contacts/john", nil},
+		{"batch upsert", func() (bool, string) {
+			results := db.BatchUpsert([]ports.BatchItem{{Location: "contacts/jane", Data: map[string]interface{}{"name": "Jane"}}})
+			return results[0].Success, results[0].Message
+		}, "contacts/jane", map[string]interface{}{"name": "Jane"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			success, message := tt.write()
+			require.True(t, success, message)
+			for _, secondary := range secondaries {
+				_, _, data := secondary.Read(tt.location)
+				assert.Equal(t, tt.expected, data)
+			}
+		})
+	}
+}
+
+func TestMirroredDatabase_Consistency(t *testing.T) {
+	tests := []struct {
+		name            string
+		consistency     MirrorConsistency
+		expectedSuccess bool
+		expectedPrimary map[string]interface{}
+		expectedHealthy map[string]interface{}
+		expectedRepairs int
+	}{
+		{"best effort", MirrorBestEffort, true, map[string]interface{}{"name": "Johnny"}, map[string]interface{}{"name": "Johnny"}, 1},
+		{"strict", MirrorStrict, false, map[string]interface{}{"name": "John"}, map[string]interface{}{"name": "John"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, healthy := NewInMemoryDatabase(), NewInMemoryDatabase()
+			flaky := &flakyDatabase{InMemoryDatabase: NewInMemoryDatabase()}
+			db := NewMirroredDatabase(primary, []ports.Database{healthy, flaky}, WithConsistency(tt.consistency))
+			require.True(t, mustSucceed(db.Create("contacts/john", map[string]interface{}{"name": "John"})))
+
+			flaky.down = true
+			success, _ := db.Update("contacts/john", map[string]interface{}{"name": "Johnny"})
+			assert.Equal(t, tt.expectedSuccess, success)
+			_, _, data := primary.Read("contacts/john")
+			assert.Equal(t, tt.expectedPrimary, data)
+			_, _, data = healthy.Read("contacts/john")
+			assert.Equal(t, tt.expectedHealthy, data)
+			assert.Equal(t, tt.expectedRepairs, db.PendingRepairs())
+
+			// Repairs wait until the secondary is back
+			success, _, _ = db.Repair()
+			assert.Equal(t, tt.expectedRepairs == 0, success)
+			flaky.down = false
+			success, message, _ := db.Repair()
+			require.True(t, success, message)
+			assert.Zero(t, db.PendingRepairs())
+			_, _, data = flaky.Read("contacts/john")
+			assert.Equal(t, tt.expectedPrimary, data)
+		})
+	}
+}
+
+func TestMirroredDatabase_StrictRevertsCreate(t *testing.T) {
+	primary := NewInMemoryDatabase()
+	flaky := &flakyDatabase{InMemoryDatabase: NewInMemoryDatabase(), down: true}
+	db := NewMirroredDatabase(primary, []ports.Database{flaky}, WithConsistency(MirrorStrict))
+
+	success, message := db.Create("contacts/john", map[string]interface{}{"name": "John"})
+	assert.False(t, success)
+	assert.Contains(t, message, "connection refused")
+	success, _, _ = primary.Read("contacts/john")
+	assert.False(t, success)
+
+	require.True(t, mustSucceed(primary.CreateGroup("family")))
+	require.True(t, mustSucceed(primary.Create("contacts/jane", map[string]interface{}{"name": "Jane"})))
+	success, _ = db.AddMember("family", "contacts/jane")
+	assert.False(t, success)
+	_, _, members := primary.Members("family")
+	assert.Empty(t, members)
+}
+
+func TestMirroredDatabase_ShadowReads(t *testing.T) {
+	primary, secondary := NewInMemoryDatabase(), NewInMemoryDatabase()
+	db := NewMirroredDatabase(primary, []ports.Database{secondary}, WithShadowReads())
+	require.True(t, mustSucceed(db.Create("contacts/john", map[string]interface{}{"name": "John"})))
+
+	db.Read("contacts/john")
+	assert.Zero(t, db.PendingRepairs())
+
+	secondary.Update("contacts/john", map[string]interface{}{"name": "Drifted"})
+	_, _, data := db.Read("contacts/john")
+	assert.Equal(t, "John", data["name"], "reads come from the primary")
+	assert.Equal(t, 1, db.PendingRepairs())
+
+	success, message, repaired := db.Repair()
+	require.True(t, success, message)
+	assert.Equal(t, 1, repaired)
+	_, _, data = secondary.Read("contacts/john")
+	assert.Equal(t, "John", data["name"])
+}
+
+func TestMirroredDatabase_Reconcile(t *testing.T) {
+	primary, secondary := NewInMemoryDatabase(), NewInMemoryDatabase()
+	require.True(t, mustSucceed(primary.Create("contacts/john", map[string]interface{}{"name": "John"})))
+	require.True(t, mustSucceed(primary.Create("contacts/jane", map[string]interface{}{"name": "Jane"})))
+	require.True(t, mustSucceed(primary.CreateGroup("family")))
+	require.True(t, mustSucceed(primary.AddMember("family", "contacts/jane")))
+	require.True(t, mustSucceed(secondary.Create("contacts/jane", map[string]interface{}{"name": "Old"})))
+	require.True(t, mustSucceed(secondary.Create("contacts/gone", map[string]interface{}{"name": "Gone"})))
+	require.True(t, mustSucceed(secondary.CreateGroup("work")))
+
+	db := NewMirroredDatabase(primary, []ports.Database{secondary})
+	success, message, reports := db.Reconcile()
+	require.True(t, success, message)
+	assert.Equal(t, []MirrorReport{{Secondary: 0, Copied: 2, Deleted: 1, Groups: 2}}, reports)
+
+	_, _, locations := secondary.List("")
+	assert.Equal(t, []string{"contacts/jane", "contacts/john"}, locations)
+	_, _, data := secondary.Read("contacts/jane")
+	assert.Equal(t, "Jane", data["name"])
+	_, _, names := secondary.ListGroups()
+	assert.Equal(t, []string{"family"}, names)
+	_, _, members := secondary.Members("family")
+	assert.Equal(t, []string{"contacts/jane"}, members)
+
+	_, _, reports = db.Reconcile()
+	assert.Equal(t, []MirrorReport{{Secondary: 0}}, reports)
+}
+
+func TestMirroredDatabase_Blobs(t *testing.T) {
+	secondary := NewInMemoryDatabase()
+	testBlobStore(t, NewMirroredDatabase(NewInMemoryDatabase(), []ports.Database{secondary}))
+	_, _, hashes := secondary.ListBlobs()
+	assert.Equal(t, []string{blobHash([]byte("%PDF contract"))}, hashes)
+
+	photo := []byte("\x89PNG photo")
+
+	// A secondary that misses a blob is repaired from the primary
+	primary := NewInMemoryDatabase()
+	flaky := &flakyDatabase{InMemoryDatabase: NewInMemoryDatabase(), down: true}
+	db := NewMirroredDatabase(primary, []ports.Database{flaky})
+	require.True(t, mustSucceed(db.PutBlob(blobHash(photo), photo)))
+	assert.Equal(t, 1, db.PendingRepairs())
+	flaky.down = false
+	success, message, _ := db.Repair()
+	require.True(t, success, message)
+	_, _, content := flaky.GetBlob(blobHash(photo))
+	assert.Equal(t, photo, content)
+
+	// Strict consistency takes the blob back off the primary
+	primary = NewInMemoryDatabase()
+	flaky.down = true
+	db = NewMirroredDatabase(primary, []ports.Database{flaky}, WithConsistency(MirrorStrict))
+	contract := []byte("%PDF contract")
+	success, message = db.PutBlob(blobHash(contract), contract)
+	assert.False(t, success)
+	assert.Contains(t, message, "connection refused")
+	_, _, hashes = primary.ListBlobs()
+	assert.Empty(t, hashes)
+
+	// Reconciling copies missing blobs and deletes stale ones
+	require.True(t, mustSucceed(primary.PutBlob(blobHash(contract), contract)))
+	flaky.down = false
+	success, message, reports := NewMirroredDatabase(primary, []ports.Database{flaky}).Reconcile()
+	require.True(t, success, message)
+	assert.Equal(t, []MirrorReport{{Secondary: 0, Blobs: 2}}, reports)
+	_, _, hashes = flaky.ListBlobs()
+	assert.Equal(t, []string{blobHash(contract)}, hashes)
+}
+
+func TestMirroredDatabase_WithTx(t *testing.T) {
+	primary, secondary := NewInMemoryDatabase(), NewInMemoryDatabase()
+	db := NewMirroredDatabase(primary, []ports.Database{secondary})
+
+	err := db.WithTx(func(tx ports.Database) error {
+		tx.Create("contacts/john", map[string]interface{}{"name": "John"})
+		return errors.New("rolled back")
+	})
+	require.Error(t, err)
+	success, _, _ := secondary.Read("contacts/john")
+	assert.False(t, success, "a rolled back write is not mirrored")
+
+	err = db.WithTx(func(tx ports.Database) error {
+		_, message := tx.Create("contacts/jane", map[string]interface{}{"name": "Jane"})
+		if message != "" {
+			return errors.New(message)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	_, _, data := secondary.Read("contacts/jane")
+	assert.Equal(t, "Jane", data["name"])
+}
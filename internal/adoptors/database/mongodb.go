@@ -3,20 +3,33 @@ package database
 import (
 	"context"
 	"fmt"
+	"regexp"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
 )
 
 type MongoDatabase struct {
 	client     *mongo.Client
 	collection *mongo.Collection
+	groups     *mongo.Collection
+	// sessionCtx carries the session inside WithTx.
+	sessionCtx context.Context
+	// tenant databases share the client of the database they came from.
+	tenant bool
 }
 
 type MongoDocument struct {
 	Location string                 `bson:"_id"`
 	Data     map[string]interface{} `bson:"data"`
+	Groups   []string               `bson:"groups,omitempty"`
+}
+
+type MongoGroup struct {
+	Name string `bson:"_id"`
 }
 
 func NewMongoDatabase(uri, database, collection string) (*MongoDatabase, error) {
@@ -32,10 +45,17 @@ func NewMongoDatabase(uri, database, collection string) (*MongoDatabase, error)
 		return nil, fmt.Errorf("failed to ping MongoDB: %v", err)
 	}
 
+	return openMongoCollection(client, database, collection)
+}
+
+// openMongoCollection prepares the collections of a database on a connected
+// client.
+func openMongoCollection(client *mongo.Client, database, collection string) (*MongoDatabase, error) {
+	ctx := context.Background()
 	coll := client.Database(database).Collection(collection)
 
 	// Create unique index on location
-	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
 		Keys:    bson.D{{Key: "_id", Value: 1}},
 		Options: options.Index().SetUnique(true),
 	})
@@ -43,14 +63,23 @@ func NewMongoDatabase(uri, database, collection string) (*MongoDatabase, error)
 		return nil, fmt.Errorf("failed to create index: %v", err)
 	}
 
+	// Index group membership for group listings
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "groups", Value: 1}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create index: %v", err)
+	}
+
 	return &MongoDatabase{
 		client:     client,
 		collection: coll,
+		groups:     client.Database(database).Collection(collection + "_groups"),
 	}, nil
 }
 
 func (m *MongoDatabase) Create(location string, data map[string]interface{}) (bool, string) {
-	ctx := context.Background()
+	ctx := m.context()
 
 	doc := MongoDocument{
 		Location: location,
@@ -69,7 +98,7 @@ func (m *MongoDatabase) Create(location string, data map[string]interface{}) (bo
 }
 
 func (m *MongoDatabase) Read(location string) (bool, string, map[string]interface{}) {
-	ctx := context.Background()
+	ctx := m.context()
 
 	var doc MongoDocument
 	err := m.collection.FindOne(ctx, bson.M{"_id": location}).Decode(&doc)
@@ -84,7 +113,7 @@ func (m *MongoDatabase) Read(location string) (bool, string, map[string]interfac
 }
 
 func (m *MongoDatabase) Update(location string, data map[string]interface{}) (bool, string) {
-	ctx := context.Background()
+	ctx := m.context()
 
 	result, err := m.collection.UpdateOne(
 		ctx,
@@ -102,7 +131,7 @@ func (m *MongoDatabase) Update(location string, data map[string]interface{}) (bo
 }
 
 func (m *MongoDatabase) Delete(location string) (bool, string) {
-	ctx := context.Background()
+	ctx := m.context()
 
 	result, err := m.collection.DeleteOne(ctx, bson.M{"_id": location})
 	if err != nil {
@@ -115,6 +144,64 @@ func (m *MongoDatabase) Delete(location string) (bool, string) {
 	return true, ""
 }
 
+func (m *MongoDatabase) List(prefix string) (bool, string, []string) {
+	ctx := m.context()
+
+	filter := bson.M{"_id": bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}}
+	opts := options.Find().
+		SetProjection(bson.M{"_id": 1}).
+		SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := m.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return false, fmt.Sprintf("Error listing documents: %v", err), nil
+	}
+	defer cursor.Close(ctx)
+
+	locations := make([]string, 0)
+	for cursor.Next(ctx) {
+		var doc MongoDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return false, fmt.Sprintf("Error decoding document: %v", err), nil
+		}
+		locations = append(locations, doc.Location)
+	}
+	if err := cursor.Err(); err != nil {
+		return false, fmt.Sprintf("Error listing documents: %v", err), nil
+	}
+
+	return true, "", locations
+}
+
+// WithTx runs fn inside a multi-document transaction. MongoDB only supports
+// transactions on replica sets and sharded clusters.
+func (m *MongoDatabase) WithTx(fn func(tx ports.Database) error) error {
+	ctx := m.context()
+
+	session, err := m.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx context.Context) (interface{}, error) {
+		tx := *m
+		tx.sessionCtx = sessionCtx
+		return nil, fn(&tx)
+	})
+	return err
+}
+
+func (m *MongoDatabase) context() context.Context {
+	if m.sessionCtx != nil {
+		return m.sessionCtx
+	}
+	return context.Background()
+}
+
 func (m *MongoDatabase) Close() error {
+	if m.tenant {
+		return nil
+	}
 	return m.client.Disconnect(context.Background())
 }
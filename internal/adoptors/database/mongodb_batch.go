@@ -0,0 +1,129 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// duplicateKeyCode is the server error code for a unique index violation.
+const duplicateKeyCode = 11000
+
+func (m *MongoDatabase) BatchRead(locations []string) (bool, string, map[string]map[string]interface{}) {
+	ctx := m.context()
+	cursor, err := m.collection.Find(ctx, bson.M{"_id": bson.M{"$in": locations}})
+	if err != nil {
+		return false, fmt.Sprintf("Error finding documents: %v", err), nil
+	}
+	var docs []MongoDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return false, fmt.Sprintf("Error finding documents: %v", err), nil
+	}
+
+	records := make(map[string]map[string]interface{}, len(docs))
+	for _, doc := range docs {
+		records[doc.Location] = doc.Data
+	}
+	return true, "", records
+}
+
+func (m *MongoDatabase) BatchCreate(items []ports.BatchItem) []ports.BatchResult {
+	models := make([]mongo.WriteModel, len(items))
+	for i, item := range items {
+		models[i] = mongo.NewInsertOneModel().SetDocument(MongoDocument{
+			Location: item.Location,
+			Data:     item.Data,
+		})
+	}
+
+	return m.bulkWrite(items, models)
+}
+
+func (m *MongoDatabase) BatchUpsert(items []ports.BatchItem) []ports.BatchResult {
+	models := make([]mongo.WriteModel, len(items))
+	for i, item := range items {
+		// $set keeps other fields on the document, such as group membership
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": item.Location}).
+			SetUpdate(bson.M{"$set": bson.M{"data": item.Data}}).
+			SetUpsert(true)
+	}
+
+	return m.bulkWrite(items, models)
+}
+
+func (m *MongoDatabase) BatchDelete(locations []string) []ports.BatchResult {
+	ctx := m.context()
+	results := make([]ports.BatchResult, len(locations))
+	for i, location := range locations {
+		results[i].Location = location
+	}
+
+	// Find which documents exist so each item gets its own result
+	cursor, err := m.collection.Find(
+		ctx,
+		bson.M{"_id": bson.M{"$in": locations}},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		failResults(results, fmt.Sprintf("Error finding documents: %v", err))
+		return results
+	}
+	var docs []MongoDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		failResults(results, fmt.Sprintf("Error finding documents: %v", err))
+		return results
+	}
+
+	existing := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		existing = append(existing, doc.Location)
+	}
+
+	if _, err := m.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": existing}}); err != nil {
+		failResults(results, fmt.Sprintf("Error deleting documents: %v", err))
+		return results
+	}
+
+	markResults(results, existing, "Location does not exist")
+	return results
+}
+
+// bulkWrite runs an unordered bulk write and maps write errors back to items.
+func (m *MongoDatabase) bulkWrite(items []ports.BatchItem, models []mongo.WriteModel) []ports.BatchResult {
+	ctx := m.context()
+	results := make([]ports.BatchResult, len(items))
+	for i, item := range items {
+		results[i] = ports.BatchResult{Location: item.Location, Success: true}
+	}
+	if len(models) == 0 {
+		return results
+	}
+
+	_, err := m.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err == nil {
+		return results
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		failResults(results, fmt.Sprintf("Error writing documents: %v", err))
+		return results
+	}
+
+	for _, writeErr := range bulkErr.WriteErrors {
+		results[writeErr.Index].Success = false
+		if writeErr.Code == duplicateKeyCode {
+			results[writeErr.Index].Message = "Location already exists"
+		} else {
+			results[writeErr.Index].Message = fmt.Sprintf("Error writing document: %v", writeErr.Message)
+		}
+	}
+
+	return results
+}
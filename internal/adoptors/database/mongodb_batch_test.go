@@ -0,0 +1,51 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func TestMongoDatabase_Batch(t *testing.T) {
+	db := setupMongoTest(t)
+	defer cleanupMongoTest(t, db)
+
+	success, err := db.Create("contacts/existing", map[string]interface{}{"name": "Existing"})
+	assert.True(t, success, err)
+
+	results := db.BatchCreate([]ports.BatchItem{
+		{Location: "contacts/john", Data: map[string]interface{}{"name": "John Doe"}},
+		{Location: "contacts/existing", Data: map[string]interface{}{"name": "Clobbered"}},
+		{Location: "contacts/jane", Data: map[string]interface{}{"name": "Jane Doe"}},
+	})
+	assert.True(t, results[0].Success)
+	assert.False(t, results[1].Success)
+	assert.Contains(t, results[1].Message, "Location already exists")
+	assert.True(t, results[2].Success)
+
+	results = db.BatchUpsert([]ports.BatchItem{
+		{Location: "contacts/existing", Data: map[string]interface{}{"name": "Replaced"}},
+		{Location: "contacts/new", Data: map[string]interface{}{"name": "New"}},
+	})
+	assert.True(t, results[0].Success)
+	assert.True(t, results[1].Success)
+	_, _, data := db.Read("contacts/existing")
+	assert.Equal(t, map[string]interface{}{"name": "Replaced"}, data)
+
+	success, message, records := db.BatchRead([]string{"contacts/existing", "contacts/nobody", "contacts/new"})
+	assert.True(t, success, message)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"contacts/existing": {"name": "Replaced"},
+		"contacts/new":      {"name": "New"},
+	}, records)
+
+	results = db.BatchDelete([]string{"contacts/john", "contacts/nobody"})
+	assert.True(t, results[0].Success)
+	assert.False(t, results[1].Success)
+	assert.Contains(t, results[1].Message, "Location does not exist")
+
+	_, _, locations := db.List("")
+	assert.Equal(t, []string{"contacts/existing", "contacts/jane", "contacts/new"}, locations)
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func (m *MongoDatabase) PutBlob(hash string, content []byte) (bool, string) {
+	if message := checkBlob(hash, content); message != "" {
+		return false, message
+	}
+	bucket := m.blobs()
+	count, err := bucket.GetFilesCollection().CountDocuments(m.context(), bson.M{"_id": hash})
+	if err != nil {
+		return false, fmt.Sprintf("Error storing blob: %v", err)
+	}
+	if count > 0 {
+		return true, ""
+	}
+
+	// A concurrent writer of the same blob makes the upload fail on the
+	// unique file id or chunk index
+	err = bucket.UploadFromStreamWithID(m.context(), hash, hash, bytes.NewReader(content))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return false, fmt.Sprintf("Error storing blob: %v", err)
+	}
+	return true, ""
+}
+
+func (m *MongoDatabase) GetBlob(hash string) (bool, string, []byte) {
+	var content bytes.Buffer
+	_, err := m.blobs().DownloadToStream(m.context(), hash, &content)
+	if errors.Is(err, mongo.ErrFileNotFound) {
+		return false, "Blob does not exist", nil
+	}
+	if err != nil {
+		return false, fmt.Sprintf("Error reading blob: %v", err), nil
+	}
+	return true, "", content.Bytes()
+}
+
+func (m *MongoDatabase) DeleteBlob(hash string) (bool, string) {
+	err := m.blobs().Delete(m.context(), hash)
+	if errors.Is(err, mongo.ErrFileNotFound) {
+		return false, "Blob does not exist"
+	}
+	if err != nil {
+		return false, fmt.Sprintf("Error deleting blob: %v", err)
+	}
+	return true, ""
+}
+
+func (m *MongoDatabase) ListBlobs() (bool, string, []string) {
+	ctx := m.context()
+	cursor, err := m.blobs().GetFilesCollection().Find(ctx, bson.M{},
+		options.Find().SetProjection(bson.M{"_id": 1}).SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return false, fmt.Sprintf("Error listing blobs: %v", err), nil
+	}
+	defer cursor.Close(ctx)
+
+	hashes := make([]string, 0)
+	for cursor.Next(ctx) {
+		var file struct {
+			Hash string `bson:"_id"`
+		}
+		if err := cursor.Decode(&file); err != nil {
+			return false, fmt.Sprintf("Error decoding blob: %v", err), nil
+		}
+		hashes = append(hashes, file.Hash)
+	}
+	if err := cursor.Err(); err != nil {
+		return false, fmt.Sprintf("Error listing blobs: %v", err), nil
+	}
+	return true, "", hashes
+}
+
+// blobs is the GridFS bucket holding the blobs, named after the contacts
+// collection. The hash is the file id.
+func (m *MongoDatabase) blobs() *mongo.GridFSBucket {
+	return m.collection.Database().GridFSBucket(options.GridFSBucket().SetName(m.collection.Name() + "_blobs"))
+}
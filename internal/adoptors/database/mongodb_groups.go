@@ -0,0 +1,196 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func (m *MongoDatabase) CreateGroup(name string) (bool, string) {
+	ctx := m.context()
+
+	_, err := m.groups.InsertOne(ctx, MongoGroup{Name: name})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, "Group already exists"
+	}
+	if err != nil {
+		return false, fmt.Sprintf("Error creating group: %v", err)
+	}
+
+	return true, ""
+}
+
+func (m *MongoDatabase) RenameGroup(name, newName string) (bool, string) {
+	ctx := m.context()
+
+	// Group names are document ids, so renaming is insert + delete
+	exists, err := m.groupExists(ctx, name)
+	if err != nil {
+		return false, fmt.Sprintf("Error checking group: %v", err)
+	}
+	if !exists {
+		return false, "Group does not exist"
+	}
+	if success, msg := m.CreateGroup(newName); !success {
+		return false, msg
+	}
+
+	// Rename the group in every member's array
+	_, err = m.collection.UpdateMany(
+		ctx,
+		bson.M{"groups": name},
+		bson.M{"$set": bson.M{"groups.$": newName}},
+	)
+	if err != nil {
+		return false, fmt.Sprintf("Error renaming group: %v", err)
+	}
+
+	if _, err := m.groups.DeleteOne(ctx, bson.M{"_id": name}); err != nil {
+		return false, fmt.Sprintf("Error renaming group: %v", err)
+	}
+
+	return true, ""
+}
+
+func (m *MongoDatabase) DeleteGroup(name string) (bool, string) {
+	ctx := m.context()
+
+	result, err := m.groups.DeleteOne(ctx, bson.M{"_id": name})
+	if err != nil {
+		return false, fmt.Sprintf("Error deleting group: %v", err)
+	}
+	if result.DeletedCount == 0 {
+		return false, "Group does not exist"
+	}
+
+	// Remove the group from every member's array
+	_, err = m.collection.UpdateMany(
+		ctx,
+		bson.M{"groups": name},
+		bson.M{"$pull": bson.M{"groups": name}},
+	)
+	if err != nil {
+		return false, fmt.Sprintf("Error deleting group: %v", err)
+	}
+
+	return true, ""
+}
+
+func (m *MongoDatabase) ListGroups() (bool, string, []string) {
+	ctx := m.context()
+
+	cursor, err := m.groups.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return false, fmt.Sprintf("Error listing groups: %v", err), nil
+	}
+	defer cursor.Close(ctx)
+
+	var groups []MongoGroup
+	if err := cursor.All(ctx, &groups); err != nil {
+		return false, fmt.Sprintf("Error listing groups: %v", err), nil
+	}
+
+	names := make([]string, 0, len(groups))
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+
+	return true, "", names
+}
+
+func (m *MongoDatabase) AddMember(group, location string) (bool, string) {
+	return m.updateMembership(group, location, "$addToSet")
+}
+
+func (m *MongoDatabase) RemoveMember(group, location string) (bool, string) {
+	return m.updateMembership(group, location, "$pull")
+}
+
+func (m *MongoDatabase) Members(group string) (bool, string, []string) {
+	ctx := m.context()
+
+	exists, err := m.groupExists(ctx, group)
+	if err != nil {
+		return false, fmt.Sprintf("Error checking group: %v", err), nil
+	}
+	if !exists {
+		return false, "Group does not exist", nil
+	}
+
+	opts := options.Find().
+		SetProjection(bson.M{"_id": 1}).
+		SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := m.collection.Find(ctx, bson.M{"groups": group}, opts)
+	if err != nil {
+		return false, fmt.Sprintf("Error listing members: %v", err), nil
+	}
+	defer cursor.Close(ctx)
+
+	var docs []MongoDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return false, fmt.Sprintf("Error listing members: %v", err), nil
+	}
+
+	locations := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		locations = append(locations, doc.Location)
+	}
+
+	return true, "", locations
+}
+
+func (m *MongoDatabase) GroupsOf(location string) (bool, string, []string) {
+	ctx := m.context()
+
+	var doc MongoDocument
+	err := m.collection.FindOne(
+		ctx,
+		bson.M{"_id": location},
+		options.FindOne().SetProjection(bson.M{"groups": 1}),
+	).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return false, "Location does not exist", nil
+	}
+	if err != nil {
+		return false, fmt.Sprintf("Error reading document: %v", err), nil
+	}
+
+	names := append([]string{}, doc.Groups...)
+	sort.Strings(names)
+	return true, "", names
+}
+
+func (m *MongoDatabase) updateMembership(group, location, operator string) (bool, string) {
+	ctx := m.context()
+
+	exists, err := m.groupExists(ctx, group)
+	if err != nil {
+		return false, fmt.Sprintf("Error checking group: %v", err)
+	}
+	if !exists {
+		return false, "Group does not exist"
+	}
+
+	result, err := m.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": location},
+		bson.M{operator: bson.M{"groups": group}},
+	)
+	if err != nil {
+		return false, fmt.Sprintf("Error updating document: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return false, "Location does not exist"
+	}
+
+	return true, ""
+}
+
+func (m *MongoDatabase) groupExists(ctx context.Context, name string) (bool, error) {
+	count, err := m.groups.CountDocuments(ctx, bson.M{"_id": name})
+	return count > 0, err
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Patch sets and unsets data.<field> on the document, leaving its other
+// fields alone.
+func (m *MongoDatabase) Patch(location string, fields map[string]interface{}) (bool, string) {
+	set, unset := bson.M{}, bson.M{}
+	for field, value := range fields {
+		// Dots and dollars would address other parts of the document
+		if field == "" || strings.ContainsAny(field, ".$") {
+			return false, fmt.Sprintf("Invalid field %q", field)
+		}
+		if value == nil {
+			unset["data."+field] = ""
+		} else {
+			set["data."+field] = value
+		}
+	}
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if len(update) == 0 {
+		success, message, _ := m.Read(location)
+		return success, message
+	}
+
+	result, err := m.collection.UpdateOne(m.context(), bson.M{"_id": location}, update)
+	if err != nil {
+		return false, fmt.Sprintf("Error updating document: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return false, "Location does not exist"
+	}
+	return true, ""
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func (m *MongoDatabase) ReadFields(location string, fields []string) (bool, string, map[string]interface{}) {
+	projection, message := dataProjection(fields)
+	if projection == nil {
+		return false, message, nil
+	}
+
+	var doc MongoDocument
+	err := m.collection.FindOne(m.context(), bson.M{"_id": location}, options.FindOne().SetProjection(projection)).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return false, "Location does not exist", nil
+	}
+	if err != nil {
+		return false, fmt.Sprintf("Error reading document: %v", err), nil
+	}
+	return true, "", projectedData(doc)
+}
+
+func (m *MongoDatabase) ListFields(prefix string, fields []string) (bool, string, map[string]map[string]interface{}) {
+	projection, message := dataProjection(fields)
+	if projection == nil {
+		return false, message, nil
+	}
+
+	ctx := m.context()
+	filter := bson.M{"_id": bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}}
+	cursor, err := m.collection.Find(ctx, filter, options.Find().SetProjection(projection))
+	if err != nil {
+		return false, fmt.Sprintf("Error listing documents: %v", err), nil
+	}
+	defer cursor.Close(ctx)
+
+	records := make(map[string]map[string]interface{})
+	for cursor.Next(ctx) {
+		var doc MongoDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return false, fmt.Sprintf("Error decoding document: %v", err), nil
+		}
+		records[doc.Location] = projectedData(doc)
+	}
+	if err := cursor.Err(); err != nil {
+		return false, fmt.Sprintf("Error listing documents: %v", err), nil
+	}
+	return true, "", records
+}
+
+// dataProjection builds a projection document selecting data.<field>.
+func dataProjection(fields []string) (bson.M, string) {
+	projection := bson.M{"_id": 1}
+	for _, field := range fields {
+		// Dots and dollars would address other parts of the document
+		if field == "" || strings.ContainsAny(field, ".$") {
+			return nil, fmt.Sprintf("Invalid field %q", field)
+		}
+		projection["data."+field] = 1
+	}
+	return projection, ""
+}
+
+// projectedData returns the data of a projected document, which has none
+// when none of the fields were set.
+func projectedData(doc MongoDocument) map[string]interface{} {
+	if doc.Data == nil {
+		return map[string]interface{}{}
+	}
+	return doc.Data
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// Tenant returns the database of a tenant, kept in collections of its own
+// named after the tenant.
+func (m *MongoDatabase) Tenant(id string) (bool, string, ports.Database) {
+	tenant, err := openMongoCollection(m.client, m.collection.Database().Name(), m.tenantCollection(id))
+	if err != nil {
+		return false, err.Error(), nil
+	}
+	tenant.tenant = true
+	return true, "", tenant
+}
+
+// DropTenant drops the collections of a tenant.
+func (m *MongoDatabase) DropTenant(id string) (bool, string) {
+	ctx := context.Background()
+	db := m.collection.Database()
+	name := m.tenantCollection(id)
+
+	if err := db.Collection(name).Drop(ctx); err != nil {
+		return false, fmt.Sprintf("Error dropping tenant: %v", err)
+	}
+	if err := db.Collection(name + "_groups").Drop(ctx); err != nil {
+		return false, fmt.Sprintf("Error dropping tenant: %v", err)
+	}
+	if err := db.GridFSBucket(options.GridFSBucket().SetName(name + "_blobs")).Drop(ctx); err != nil {
+		return false, fmt.Sprintf("Error dropping tenant: %v", err)
+	}
+	return true, ""
+}
+
+func (m *MongoDatabase) tenantCollection(id string) string {
+	return m.collection.Name() + "_" + id
+}
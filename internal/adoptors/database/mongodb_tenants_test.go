@@ -0,0 +1,12 @@
+package database
+
+import "testing"
+
+func TestMongoDatabase_Tenants(t *testing.T) {
+	db := setupMongoTest(t)
+	defer cleanupMongoTest(t, db)
+	defer db.DropTenant("acme")
+	defer db.DropTenant("globex")
+
+	testTenantIsolation(t, db, Tenants(db))
+}
@@ -48,6 +48,14 @@ func cleanupMongoTest(t *testing.T, db *MongoDatabase) {
 	if err != nil {
 		t.Errorf("Failed to cleanup test collection: %v", err)
 	}
+	err = db.groups.Drop(ctx)
+	if err != nil {
+		t.Errorf("Failed to cleanup test collection: %v", err)
+	}
+	err = db.blobs().Drop(ctx)
+	if err != nil {
+		t.Errorf("Failed to cleanup test collection: %v", err)
+	}
 	err = db.Close()
 	if err != nil {
 		t.Errorf("Failed to close database connection: %v", err)
@@ -358,3 +366,18 @@ func TestMongoDatabase_Concurrency(t *testing.T) {
 	assert.True(t, success, "Failed to read final value")
 	assert.Equal(t, int64(numGoroutines), data["counter"].(int64), "Counter value mismatch")
 }
+
+func TestMongoDatabase_List(t *testing.T) {
+	db := setupMongoTest(t)
+	defer cleanupMongoTest(t, db)
+
+	for _, location := range []string{"contacts/b", "contacts/a", "other/c"} {
+		success, err := db.Create(location, map[string]interface{}{"name": location})
+		assert.True(t, success, "Failed to create contact: %v", err)
+	}
+
+	success, err, locations := db.List("contacts/")
+	assert.True(t, success)
+	assert.Empty(t, err)
+	assert.Equal(t, []string{"contacts/a", "contacts/b"}, locations)
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func (m *MongoDatabase) Upsert(location string, data map[string]interface{}) (bool, string, bool) {
+	// $set keeps other fields on the document, such as group membership
+	result, err := m.collection.UpdateOne(
+		m.context(),
+		bson.M{"_id": location},
+		bson.M{"$set": bson.M{"data": data}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	if err != nil {
+		return false, fmt.Sprintf("Error writing document: %v", err), false
+	}
+	return true, "", result.UpsertedCount == 1
+}
+
+func (m *MongoDatabase) CreateIfAbsent(location string, data map[string]interface{}) (bool, string, bool) {
+	_, err := m.collection.InsertOne(m.context(), MongoDocument{Location: location, Data: data})
+	if mongo.IsDuplicateKeyError(err) {
+		return true, "", false
+	}
+	if err != nil {
+		return false, fmt.Sprintf("Error creating document: %v", err), false
+	}
+	return true, "", true
+}
+
+func (m *MongoDatabase) UpdateIfExists(location string, data map[string]interface{}) (bool, string, bool) {
+	result, err := m.collection.UpdateOne(
+		m.context(),
+		bson.M{"_id": location},
+		bson.M{"$set": bson.M{"data": data}},
+	)
+	if err != nil {
+		return false, fmt.Sprintf("Error updating document: %v", err), false
+	}
+	return true, "", result.MatchedCount == 1
+}
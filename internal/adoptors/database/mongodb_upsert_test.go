@@ -0,0 +1,10 @@
+package database
+
+import "testing"
+
+func TestMongoDatabase_Upsert(t *testing.T) {
+	db := setupMongoTest(t)
+	defer cleanupMongoTest(t, db)
+
+	testUpsertDatabase(t, db)
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+type mongoChange struct {
+	OperationType string `bson:"operationType"`
+	DocumentKey   struct {
+		Location string `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument *MongoDocument `bson:"fullDocument"`
+	ClusterTime  bson.Timestamp `bson:"clusterTime"`
+}
+
+// Watch streams the collection's change stream. Cursors are base64 resume
+// tokens. Change streams need a replica set or sharded cluster.
+func (m *MongoDatabase) Watch(ctx context.Context, prefix, fromCursor string) (bool, string, <-chan ports.ChangeEvent) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"documentKey._id": bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)},
+			"operationType":   bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}},
+		}}},
+	}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if fromCursor != "" {
+		token, err := base64.StdEncoding.DecodeString(fromCursor)
+		if err != nil {
+			return false, "Invalid cursor", nil
+		}
+		opts.SetResumeAfter(bson.Raw(token))
+	}
+
+	stream, err := m.collection.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return false, fmt.Sprintf("Error watching collection: %v", err), nil
+	}
+
+	out := make(chan ports.ChangeEvent)
+	go func() {
+		defer close(out)
+		defer stream.Close(context.Background())
+
+		for stream.Next(ctx) {
+			var change mongoChange
+			if err := stream.Decode(&change); err != nil {
+				continue
+			}
+
+			event := ports.ChangeEvent{
+				Cursor:   base64.StdEncoding.EncodeToString(stream.ResumeToken()),
+				Location: change.DocumentKey.Location,
+				Time:     time.Unix(int64(change.ClusterTime.T), 0),
+			}
+			switch change.OperationType {
+			case "insert":
+				event.Op = ports.ChangeCreate
+			case "delete":
+				event.Op = ports.ChangeDelete
+			default:
+				event.Op = ports.ChangeUpdate
+			}
+			if event.Op != ports.ChangeDelete {
+				// The document may be gone by the time the update is looked up
+				if change.FullDocument == nil {
+					continue
+				}
+				event.Data = change.FullDocument.Data
+			}
+
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return true, "", out
+}
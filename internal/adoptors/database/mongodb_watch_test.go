@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func TestMongoDatabase_Watch(t *testing.T) {
+	db := setupMongoTest(t)
+	defer cleanupMongoTest(t, db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	success, err, events := db.Watch(ctx, "contacts/", "")
+	assert.True(t, success, err)
+
+	db.Create("other/skipped", map[string]interface{}{"name": "Skipped"})
+	db.Create("contacts/john", map[string]interface{}{"name": "John Doe"})
+	db.Update("contacts/john", map[string]interface{}{"name": "John Updated"})
+	db.Delete("contacts/john")
+
+	created := receiveChange(t, events)
+	assert.Equal(t, ports.ChangeCreate, created.Op)
+	assert.Equal(t, "contacts/john", created.Location)
+	assert.Equal(t, "John Doe", created.Data["name"])
+
+	updated := receiveChange(t, events)
+	assert.Equal(t, ports.ChangeUpdate, updated.Op)
+	assert.Equal(t, "John Updated", updated.Data["name"])
+
+	deleted := receiveChange(t, events)
+	assert.Equal(t, ports.ChangeDelete, deleted.Op)
+
+	// Resuming from a cursor replays what came after it
+	success, err, resumed := db.Watch(ctx, "contacts/", created.Cursor)
+	assert.True(t, success, err)
+	assert.Equal(t, updated.Cursor, receiveChange(t, resumed).Cursor)
+}
@@ -0,0 +1,489 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// tenantNamespace is the location prefix under which NamespacedTenants keeps
+// the records of each tenant.
+const tenantNamespace = "tenants/"
+
+// namespaceBlobsPrefix holds an empty record at <prefix><namespace><hash> for
+// every blob a namespace stored. Blobs are shared by content in the wrapped
+// store; the records keep each namespace to its own.
+const namespaceBlobsPrefix = "_blobs/"
+
+// blobLocks holds a lock per wrapped store, taken by every namespace storing
+// or deleting one of its blobs. Without it a namespace could delete shared
+// content between another one storing it and recording its hold on it.
+// Namespaces in other processes are not covered.
+var blobLocks sync.Map
+
+// NamespacedDatabase confines a database to the locations, groups and blobs
+// under a prefix, which it hides from the caller. It supports every optional
+// interface; those the wrapped database lacks report that they are not
+// supported, and batches and transactions fall back to single writes.
+type NamespacedDatabase struct {
+	db     ports.Database
+	prefix string
+}
+
+func NewNamespacedDatabase(db ports.Database, prefix string) *NamespacedDatabase {
+	return &NamespacedDatabase{db: db, prefix: prefix}
+}
+
+func (n *NamespacedDatabase) Create(location string, data map[string]interface{}) (bool, string) {
+	return n.db.Create(n.prefix+location, data)
+}
+
+func (n *NamespacedDatabase) Read(location string) (bool, string, map[string]interface{}) {
+	return n.db.Read(n.prefix + location)
+}
+
+func (n *NamespacedDatabase) Update(location string, data map[string]interface{}) (bool, string) {
+	return n.db.Update(n.prefix+location, data)
+}
+
+func (n *NamespacedDatabase) Delete(location string) (bool, string) {
+	return n.db.Delete(n.prefix + location)
+}
+
+func (n *NamespacedDatabase) List(prefix string) (bool, string, []string) {
+	success, message, locations := n.db.List(n.prefix + prefix)
+	if !success {
+		return false, message, nil
+	}
+	return true, "", n.strip(locations)
+}
+
+func (n *NamespacedDatabase) ReadFields(location string, fields []string) (bool, string, map[string]interface{}) {
+	return readFields(n.db, n.prefix+location, fields)
+}
+
+func (n *NamespacedDatabase) ListFields(prefix string, fields []string) (bool, string, map[string]map[string]interface{}) {
+	success, message, records := listFields(n.db, n.prefix+prefix, fields)
+	if !success {
+		return false, message, nil
+	}
+	stripped := make(map[string]map[string]interface{}, len(records))
+	for location, data := range records {
+		stripped[strings.TrimPrefix(location, n.prefix)] = data
+	}
+	return true, "", stripped
+}
+
+func (n *NamespacedDatabase) CreateGroup(name string) (bool, string) {
+	groups, ok := n.db.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+	return groups.CreateGroup(n.prefix + name)
+}
+
+func (n *NamespacedDatabase) RenameGroup(name, newName string) (bool, string) {
+	groups, ok := n.db.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+	return groups.RenameGroup(n.prefix+name, n.prefix+newName)
+}
+
+func (n *NamespacedDatabase) DeleteGroup(name string) (bool, string) {
+	groups, ok := n.db.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+	return groups.DeleteGroup(n.prefix + name)
+}
+
+func (n *NamespacedDatabase) ListGroups() (bool, string, []string) {
+	groups, ok := n.db.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error(), nil
+	}
+	success, message, names := groups.ListGroups()
+	if !success {
+		return false, message, nil
+	}
+	return true, "", n.strip(names)
+}
+
+func (n *NamespacedDatabase) AddMember(group, location string) (bool, string) {
+	groups, ok := n.db.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+	return groups.AddMember(n.prefix+group, n.prefix+location)
+}
+
+func (n *NamespacedDatabase) RemoveMember(group, location string) (bool, string) {
+	groups, ok := n.db.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+	return groups.RemoveMember(n.prefix+group, n.prefix+location)
+}
+
+func (n *NamespacedDatabase) Members(group string) (bool, string, []string) {
+	groups, ok := n.db.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error(), nil
+	}
+	success, message, locations := groups.Members(n.prefix + group)
+	if !success {
+		return false, message, nil
+	}
+	return true, "", n.strip(locations)
+}
+
+func (n *NamespacedDatabase) GroupsOf(location string) (bool, string, []string) {
+	groups, ok := n.db.(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error(), nil
+	}
+	success, message, names := groups.GroupsOf(n.prefix + location)
+	if !success {
+		return false, message, nil
+	}
+	return true, "", n.strip(names)
+}
+
+func (n *NamespacedDatabase) BatchRead(locations []string) (bool, string, map[string]map[string]interface{}) {
+	prefixed := make([]string, len(locations))
+	for i, location := range locations {
+		prefixed[i] = n.prefix + location
+	}
+	success, message, records := readRecords(n.db, prefixed)
+	if !success {
+		return false, message, nil
+	}
+	stripped := make(map[string]map[string]interface{}, len(records))
+	for location, data := range records {
+		stripped[strings.TrimPrefix(location, n.prefix)] = data
+	}
+	return true, "", stripped
+}
+
+func (n *NamespacedDatabase) BatchCreate(items []ports.BatchItem) []ports.BatchResult {
+	batch, ok := n.db.(ports.BatchDatabase)
+	if !ok {
+		return n.each(items, n.Create)
+	}
+	return n.unprefixResults(batch.BatchCreate(n.prefixItems(items)))
+}
+
+func (n *NamespacedDatabase) BatchUpsert(items []ports.BatchItem) []ports.BatchResult {
+	batch, ok := n.db.(ports.BatchDatabase)
+	if !ok {
+		return n.each(items, func(location string, data map[string]interface{}) (bool, string) {
+			success, message, _ := n.Upsert(location, data)
+			return success, message
+		})
+	}
+	return n.unprefixResults(batch.BatchUpsert(n.prefixItems(items)))
+}
+
+func (n *NamespacedDatabase) BatchDelete(locations []string) []ports.BatchResult {
+	batch, ok := n.db.(ports.BatchDatabase)
+	if !ok {
+		results := make([]ports.BatchResult, len(locations))
+		for i, location := range locations {
+			success, message := n.Delete(location)
+			results[i] = ports.BatchResult{Location: location, Success: success, Message: message}
+		}
+		return results
+	}
+
+	prefixed := make([]string, len(locations))
+	for i, location := range locations {
+		prefixed[i] = n.prefix + location
+	}
+	return n.unprefixResults(batch.BatchDelete(prefixed))
+}
+
+func (n *NamespacedDatabase) Upsert(location string, data map[string]interface{}) (bool, string, bool) {
+	return upsertRecord(n.db, n.prefix+location, data)
+}
+
+func (n *NamespacedDatabase) CreateIfAbsent(location string, data map[string]interface{}) (bool, string, bool) {
+	return createRecordIfAbsent(n.db, n.prefix+location, data)
+}
+
+func (n *NamespacedDatabase) UpdateIfExists(location string, data map[string]interface{}) (bool, string, bool) {
+	return updateRecordIfExists(n.db, n.prefix+location, data)
+}
+
+// Patch changes fields in place when the wrapped database can, and reads and
+// rewrites the record otherwise.
+func (n *NamespacedDatabase) Patch(location string, fields map[string]interface{}) (bool, string) {
+	if patcher, ok := n.db.(ports.PatchDatabase); ok {
+		return patcher.Patch(n.prefix+location, fields)
+	}
+	success, message, data := n.Read(location)
+	if !success {
+		return false, message
+	}
+	for field, value := range fields {
+		if value == nil {
+			delete(data, field)
+		} else {
+			data[field] = value
+		}
+	}
+	return n.Update(location, data)
+}
+
+// WithTx runs fn in a transaction of the wrapped database, or directly when
+// it has none.
+func (n *NamespacedDatabase) WithTx(fn func(tx ports.Database) error) error {
+	transactor, ok := n.db.(ports.Transactor)
+	if !ok {
+		return fn(n)
+	}
+	return transactor.WithTx(func(tx ports.Database) error {
+		return fn(NewNamespacedDatabase(tx, n.prefix))
+	})
+}
+
+func (n *NamespacedDatabase) Watch(ctx context.Context, prefix, fromCursor string) (bool, string, <-chan ports.ChangeEvent) {
+	watcher, ok := n.db.(ports.Watcher)
+	if !ok {
+		return false, domain.ErrWatchNotSupported.Error(), nil
+	}
+	success, message, changes := watcher.Watch(ctx, n.prefix+prefix, fromCursor)
+	if !success {
+		return false, message, nil
+	}
+
+	out := make(chan ports.ChangeEvent)
+	go func() {
+		defer close(out)
+		for change := range changes {
+			change.Location = strings.TrimPrefix(change.Location, n.prefix)
+			select {
+			case out <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return true, "", out
+}
+
+// PutBlob stores the blob in the wrapped store and records that the
+// namespace holds it.
+func (n *NamespacedDatabase) PutBlob(hash string, content []byte) (bool, string) {
+	store, ok := n.db.(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error()
+	}
+	if n.prefix == "" {
+		return store.PutBlob(hash, content)
+	}
+	if message := checkBlob(hash, content); message != "" {
+		return false, message
+	}
+
+	lock := n.blobLock()
+	lock.Lock()
+	defer lock.Unlock()
+	if success, message := store.PutBlob(hash, content); !success {
+		return false, message
+	}
+	if success, message := n.db.Create(n.blobLocation(hash), map[string]interface{}{}); !success && !alreadyExists(message) {
+		return false, message
+	}
+	return true, ""
+}
+
+func (n *NamespacedDatabase) GetBlob(hash string) (bool, string, []byte) {
+	store, ok := n.db.(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error(), nil
+	}
+	if n.prefix != "" && !n.holdsBlob(hash) {
+		return false, "Blob does not exist", nil
+	}
+	return store.GetBlob(hash)
+}
+
+// DeleteBlob forgets the namespace's blob, deleting it from the wrapped store
+// once no namespace holds it.
+func (n *NamespacedDatabase) DeleteBlob(hash string) (bool, string) {
+	store, ok := n.db.(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error()
+	}
+	if n.prefix == "" {
+		return store.DeleteBlob(hash)
+	}
+	lock := n.blobLock()
+	lock.Lock()
+	defer lock.Unlock()
+	if !n.holdsBlob(hash) {
+		return false, "Blob does not exist"
+	}
+
+	if success, message := n.db.Delete(n.blobLocation(hash)); !success {
+		return false, message
+	}
+	success, message, locations := n.db.List(namespaceBlobsPrefix)
+	if !success {
+		return false, message
+	}
+	for _, location := range locations {
+		if strings.HasSuffix(location, "/"+hash) {
+			return true, ""
+		}
+	}
+	if success, message := store.DeleteBlob(hash); !success && !missing(message) {
+		return false, message
+	}
+	return true, ""
+}
+
+func (n *NamespacedDatabase) ListBlobs() (bool, string, []string) {
+	store, ok := n.db.(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error(), nil
+	}
+	if n.prefix == "" {
+		return store.ListBlobs()
+	}
+
+	// Leave out the blobs of namespaces nested in this one
+	success, message, locations := n.db.List(namespaceBlobsPrefix + n.prefix)
+	if !success {
+		return false, message, nil
+	}
+	hashes := make([]string, 0, len(locations))
+	for _, location := range locations {
+		hash := strings.TrimPrefix(location, namespaceBlobsPrefix+n.prefix)
+		if isBlobHash(hash) {
+			hashes = append(hashes, hash)
+		}
+	}
+	return true, "", hashes
+}
+
+func (n *NamespacedDatabase) blobLock() *sync.Mutex {
+	lock, _ := blobLocks.LoadOrStore(n.db, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+func (n *NamespacedDatabase) blobLocation(hash string) string {
+	return namespaceBlobsPrefix + n.prefix + hash
+}
+
+// holdsBlob reports whether the namespace stored the blob.
+func (n *NamespacedDatabase) holdsBlob(hash string) bool {
+	if !isBlobHash(hash) {
+		return false
+	}
+	success, _, _ := n.db.Read(n.blobLocation(hash))
+	return success
+}
+
+// strip removes the prefix from names, dropping any outside it.
+func (n *NamespacedDatabase) strip(names []string) []string {
+	stripped := make([]string, 0, len(names))
+	for _, name := range names {
+		if strings.HasPrefix(name, n.prefix) {
+			stripped = append(stripped, strings.TrimPrefix(name, n.prefix))
+		}
+	}
+	return stripped
+}
+
+func (n *NamespacedDatabase) prefixItems(items []ports.BatchItem) []ports.BatchItem {
+	prefixed := make([]ports.BatchItem, len(items))
+	for i, item := range items {
+		prefixed[i] = ports.BatchItem{Location: n.prefix + item.Location, Data: item.Data}
+	}
+	return prefixed
+}
+
+func (n *NamespacedDatabase) unprefixResults(results []ports.BatchResult) []ports.BatchResult {
+	for i := range results {
+		results[i].Location = strings.TrimPrefix(results[i].Location, n.prefix)
+	}
+	return results
+}
+
+func (n *NamespacedDatabase) each(items []ports.BatchItem, write func(string, map[string]interface{}) (bool, string)) []ports.BatchResult {
+	results := make([]ports.BatchResult, len(items))
+	for i, item := range items {
+		success, message := write(item.Location, item.Data)
+		results[i] = ports.BatchResult{Location: item.Location, Success: success, Message: message}
+	}
+	return results
+}
+
+// NamespacedTenants keeps tenants apart in a database without native
+// isolation by giving each one a NamespacedDatabase under tenants/<id>/.
+type NamespacedTenants struct {
+	db ports.Database
+}
+
+func NewNamespacedTenants(db ports.Database) *NamespacedTenants {
+	return &NamespacedTenants{db: db}
+}
+
+func (t *NamespacedTenants) Tenant(id string) (bool, string, ports.Database) {
+	return true, "", NewNamespacedDatabase(t.db, tenantNamespace+id+"/")
+}
+
+func (t *NamespacedTenants) DropTenant(id string) (bool, string) {
+	tenant := NewNamespacedDatabase(t.db, tenantNamespace+id+"/")
+
+	// Groups first, so memberships go before the contacts they name
+	if _, ok := t.db.(ports.GroupStore); ok {
+		success, message, names := tenant.ListGroups()
+		if !success {
+			return false, message
+		}
+		for _, name := range names {
+			if success, message := tenant.DeleteGroup(name); !success {
+				return false, message
+			}
+		}
+	}
+
+	success, message, locations := tenant.List("")
+	if !success {
+		return false, message
+	}
+	for _, result := range tenant.BatchDelete(locations) {
+		if !result.Success {
+			return false, result.Message
+		}
+	}
+
+	if _, ok := t.db.(ports.BlobStore); !ok {
+		return true, ""
+	}
+	success, message, hashes := tenant.ListBlobs()
+	if !success {
+		return false, message
+	}
+	for _, hash := range hashes {
+		if success, message := tenant.DeleteBlob(hash); !success {
+			return false, message
+		}
+	}
+	return true, ""
+}
+
+// Tenants returns the tenant isolation of db: its own when it has one, and
+// namespacing otherwise.
+func Tenants(db ports.Database) ports.TenantDatabase {
+	if tenants, ok := db.(ports.TenantDatabase); ok {
+		return tenants
+	}
+	return NewNamespacedTenants(db)
+}
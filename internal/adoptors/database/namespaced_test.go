@@ -0,0 +1,179 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// testTenantIsolation checks that tenants of base see only their own records
+// and groups, and that dropping one leaves the others alone.
+func testTenantIsolation(t *testing.T, base ports.Database, tenants ports.TenantDatabase) {
+	success, message, acme := tenants.Tenant("acme")
+	require.True(t, success, message)
+	success, message, globex := tenants.Tenant("globex")
+	require.True(t, success, message)
+
+	assert.True(t, mustSucceed(base.Create("contacts/john", map[string]interface{}{"name": "Base"})))
+	assert.True(t, mustSucceed(acme.Create("contacts/john", map[string]interface{}{"name": "Acme"})))
+	assert.True(t, mustSucceed(acme.Create("contacts/jane", map[string]interface{}{"name": "Acme"})))
+
+	// The same location is a different record in every tenant
+	_, _, data := acme.Read("contacts/john")
+	assert.Equal(t, "Acme", data["name"])
+	_, _, data = base.Read("contacts/john")
+	assert.Equal(t, "Base", data["name"])
+	success, _, _ = globex.Read("contacts/jane")
+	assert.False(t, success)
+
+	_, _, locations := acme.List("contacts/")
+	assert.Equal(t, []string{"contacts/jane", "contacts/john"}, locations)
+	_, _, locations = globex.List("")
+	assert.Empty(t, locations)
+	_, _, locations = base.List("contacts/")
+	assert.Equal(t, []string{"contacts/john"}, locations)
+
+	if groups, ok := acme.(ports.GroupStore); ok {
+		assert.True(t, mustSucceed(groups.CreateGroup("family")))
+		assert.True(t, mustSucceed(groups.AddMember("family", "contacts/jane")))
+		_, _, members := groups.Members("family")
+		assert.Equal(t, []string{"contacts/jane"}, members)
+
+		_, _, names := globex.(ports.GroupStore).ListGroups()
+		assert.Empty(t, names)
+		assert.True(t, mustSucceed(globex.(ports.GroupStore).CreateGroup("family")))
+	}
+
+	success, message = tenants.DropTenant("acme")
+	require.True(t, success, message)
+	_, _, locations = acme.List("")
+	assert.Empty(t, locations)
+	_, _, data = base.Read("contacts/john")
+	assert.Equal(t, "Base", data["name"])
+	if groups, ok := globex.(ports.GroupStore); ok {
+		_, _, names := groups.ListGroups()
+		assert.Equal(t, []string{"family"}, names)
+	}
+}
+
+func TestNamespacedTenants_InMemory(t *testing.T) {
+	db := NewInMemoryDatabase()
+	testTenantIsolation(t, db, Tenants(db))
+}
+
+func TestFileSystemDatabase_Tenants(t *testing.T) {
+	db := NewFileSystemDatabase(t.TempDir())
+	testTenantIsolation(t, db, Tenants(db))
+
+	// Tenant directories cannot be reached from the base database
+	success, message := db.Create(".tenants/globex/contacts/x", map[string]interface{}{})
+	assert.False(t, success)
+	assert.Equal(t, "Invalid location", message)
+	success, message = db.Create("../escape", map[string]interface{}{})
+	assert.False(t, success)
+	assert.Equal(t, "Invalid location", message)
+}
+
+func TestNamespacedDatabase_Batch(t *testing.T) {
+	db := NewNamespacedDatabase(NewInMemoryDatabase(), "tenants/acme/")
+
+	results := db.BatchCreate([]ports.BatchItem{
+		{Location: "contacts/john", Data: map[string]interface{}{"name": "John"}},
+		{Location: "contacts/john", Data: map[string]interface{}{"name": "Again"}},
+	})
+	require.Len(t, results, 2)
+	assert.Equal(t, "contacts/john", results[0].Location)
+	assert.True(t, results[0].Success)
+	assert.False(t, results[1].Success)
+
+	success, message, records := db.BatchRead([]string{"contacts/john", "contacts/nobody"})
+	require.True(t, success, message)
+	assert.Equal(t, map[string]map[string]interface{}{"contacts/john": {"name": "John"}}, records)
+
+	err := db.WithTx(func(tx ports.Database) error {
+		success, message := tx.Update("contacts/john", map[string]interface{}{"name": "Johnny"})
+		assert.True(t, success, message)
+		return nil
+	})
+	require.NoError(t, err)
+	_, _, data := db.Read("contacts/john")
+	assert.Equal(t, "Johnny", data["name"])
+}
+
+// listHookDatabase runs a hook after blob holds are first listed.
+type listHookDatabase struct {
+	*InMemoryDatabase
+	hook func()
+}
+
+func (d *listHookDatabase) List(prefix string) (bool, string, []string) {
+	success, message, locations := d.InMemoryDatabase.List(prefix)
+	if prefix == namespaceBlobsPrefix && d.hook != nil {
+		hook := d.hook
+		d.hook = nil
+		hook()
+	}
+	return success, message, locations
+}
+
+func TestNamespacedDatabase_BlobDeleteRace(t *testing.T) {
+	inner := &listHookDatabase{InMemoryDatabase: NewInMemoryDatabase()}
+	acme := NewNamespacedDatabase(inner, "tenants/acme/")
+	globex := NewNamespacedDatabase(inner, "tenants/globex/")
+	photo := []byte("\x89PNG photo")
+	require.True(t, mustSucceed(acme.PutBlob(blobHash(photo), photo)))
+
+	// Globex stores the same photo while acme checks for other holds on it
+	done := make(chan bool)
+	inner.hook = func() {
+		go func() {
+			done <- mustSucceed(globex.PutBlob(blobHash(photo), photo))
+		}()
+		time.Sleep(50 * time.Millisecond)
+	}
+	require.True(t, mustSucceed(acme.DeleteBlob(blobHash(photo))))
+	require.True(t, <-done)
+
+	success, message, content := globex.GetBlob(blobHash(photo))
+	require.True(t, success, message)
+	assert.Equal(t, photo, content)
+}
+
+func TestNamespacedDatabase_Blobs(t *testing.T) {
+	testBlobStore(t, NewNamespacedDatabase(NewInMemoryDatabase(), "tenants/acme/"))
+	testBlobStore(t, NewNamespacedDatabase(NewInMemoryDatabase(), ""))
+
+	// Namespaces sharing a blob only see and delete their own hold on it
+	inner := NewInMemoryDatabase()
+	acme := NewNamespacedDatabase(inner, "tenants/acme/")
+	globex := NewNamespacedDatabase(inner, "tenants/globex/")
+	photo := []byte("\x89PNG photo")
+	require.True(t, mustSucceed(acme.PutBlob(blobHash(photo), photo)))
+	require.True(t, mustSucceed(globex.PutBlob(blobHash(photo), photo)))
+	contract := []byte("%PDF contract")
+	require.True(t, mustSucceed(globex.PutBlob(blobHash(contract), contract)))
+
+	_, _, hashes := acme.ListBlobs()
+	assert.Equal(t, []string{blobHash(photo)}, hashes)
+	success, message, _ := acme.GetBlob(blobHash(contract))
+	assert.False(t, success)
+	assert.Equal(t, "Blob does not exist", message)
+
+	require.True(t, mustSucceed(acme.DeleteBlob(blobHash(photo))))
+	_, _, content := globex.GetBlob(blobHash(photo))
+	assert.Equal(t, photo, content)
+	require.True(t, mustSucceed(globex.DeleteBlob(blobHash(photo))))
+	success, _, _ = inner.GetBlob(blobHash(photo))
+	assert.False(t, success, "the last namespace to let go deletes the blob")
+
+	// Dropping a tenant lets go of its blobs
+	require.True(t, mustSucceed(NewNamespacedTenants(inner).DropTenant("globex")))
+	_, _, hashes = inner.ListBlobs()
+	assert.Empty(t, hashes)
+	_, _, locations := inner.List("")
+	assert.Empty(t, locations)
+}
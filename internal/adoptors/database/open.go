@@ -0,0 +1,103 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// Backends accepted by Open.
+const (
+	BackendMemory     = "memory"
+	BackendFileSystem = "filesystem"
+	BackendPostgres   = "postgres"
+	BackendMongoDB    = "mongodb"
+	BackendSharded    = "sharded"
+)
+
+// Open connects to a backend by name. The target is the directory for the
+// filesystem backend, the DSN for Postgres and the connection URI for MongoDB,
+// whose path names the database (default "phonebook"). The sharded backend
+// takes its shards as "name=backend:target" separated by semicolons. The
+// returned function closes the connection.
+func Open(backend, target string) (ports.Database, func() error, error) {
+	noop := func() error { return nil }
+
+	switch backend {
+	case BackendMemory:
+		return NewInMemoryDatabase(), noop, nil
+	case BackendFileSystem:
+		if target == "" {
+			return nil, nil, fmt.Errorf("the filesystem backend needs a directory")
+		}
+		return NewFileSystemDatabase(target), noop, nil
+	case BackendPostgres:
+		db, err := NewPostgresDatabase(target)
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, db.Close, nil
+	case BackendMongoDB:
+		name := "phonebook"
+		if u, err := url.Parse(target); err == nil && strings.Trim(u.Path, "/") != "" {
+			name = strings.Trim(u.Path, "/")
+		}
+		db, err := NewMongoDatabase(target, name, "contacts")
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, db.Close, nil
+	case BackendSharded:
+		return openSharded(target)
+	}
+	return nil, nil, fmt.Errorf("unknown backend %q", backend)
+}
+
+func openSharded(target string) (ports.Database, func() error, error) {
+	shards := make(map[string]ports.Database)
+	var closers []func() error
+	closeAll := func() error {
+		var errs []error
+		for _, closer := range closers {
+			errs = append(errs, closer())
+		}
+		return errors.Join(errs...)
+	}
+
+	for _, spec := range strings.Split(target, ";") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		name, rest, ok := strings.Cut(spec, "=")
+		backend, shardTarget, _ := strings.Cut(rest, ":")
+		if !ok || name == "" || backend == "" {
+			closeAll()
+			return nil, nil, fmt.Errorf("invalid shard %q, expected name=backend:target", spec)
+		}
+		if _, exists := shards[name]; exists {
+			closeAll()
+			return nil, nil, fmt.Errorf("shard %q is listed twice", name)
+		}
+		if backend == BackendSharded {
+			closeAll()
+			return nil, nil, fmt.Errorf("shard %q cannot itself be sharded", name)
+		}
+		db, closer, err := Open(backend, shardTarget)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("shard %s: %w", name, err)
+		}
+		shards[name] = db
+		closers = append(closers, closer)
+	}
+
+	db, err := NewShardedDatabase(shards)
+	if err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+	return db, closeAll, nil
+}
@@ -0,0 +1,40 @@
+package database
+
+import "testing"
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+		target  string
+		wantErr bool
+	}{
+		{"memory", BackendMemory, "", false},
+		{"filesystem", BackendFileSystem, t.TempDir(), false},
+		{"filesystem without directory", BackendFileSystem, "", true},
+		{"unknown", "sqlite", "", true},
+		{"sharded", BackendSharded, "a=memory:;b=filesystem:" + t.TempDir(), false},
+		{"sharded without shards", BackendSharded, "", true},
+		{"sharded with invalid shard", BackendSharded, "a=memory:;b", true},
+		{"sharded with duplicate shard", BackendSharded, "a=memory:;a=memory:", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, closeDB, err := Open(tt.backend, tt.target)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+			defer closeDB()
+			if success, msg := db.Create("test/open", map[string]interface{}{"name": "Open"}); !success {
+				t.Errorf("Expected a working database but got error: %s", msg)
+			}
+		})
+	}
+}
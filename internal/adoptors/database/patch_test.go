@@ -0,0 +1,54 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+type patchTestDatabase interface {
+	ports.Database
+	ports.PatchDatabase
+}
+
+// testPatchDatabase checks the in-place patches of db, which starts empty.
+func testPatchDatabase(t *testing.T, db patchTestDatabase) {
+	require.True(t, mustSucceed(db.Create("contacts/john", map[string]interface{}{"name": "John", "phone": "+256700000001", "email": "john@example.com"})))
+
+	tests := []struct {
+		name            string
+		location        string
+		fields          map[string]interface{}
+		expectedSuccess bool
+		expectedData    map[string]interface{}
+	}{
+		{"set a field", "contacts/john", map[string]interface{}{"email": "johnny@example.com"}, true,
+			map[string]interface{}{"name": "John", "phone": "+256700000001", "email": "johnny@example.com"}},
+		{"add and remove fields", "contacts/john", map[string]interface{}{"address": "Kampala", "email": nil}, true,
+			map[string]interface{}{"name": "John", "phone": "+256700000001", "address": "Kampala"}},
+		{"nothing to change", "contacts/john", map[string]interface{}{}, true,
+			map[string]interface{}{"name": "John", "phone": "+256700000001", "address": "Kampala"}},
+		{"missing record", "contacts/nobody", map[string]interface{}{"name": "Nobody"}, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			success, message := db.Patch(tt.location, tt.fields)
+			assert.Equal(t, tt.expectedSuccess, success, message)
+			_, _, data := db.Read(tt.location)
+			assert.Equal(t, tt.expectedData, data)
+		})
+	}
+}
+
+func TestInMemoryDatabase_Patch(t *testing.T) {
+	testPatchDatabase(t, NewInMemoryDatabase())
+}
+
+func TestNamespacedDatabase_Patch(t *testing.T) {
+	testPatchDatabase(t, NewNamespacedDatabase(NewInMemoryDatabase(), "tenants/a/"))
+	testPatchDatabase(t, NewNamespacedDatabase(NewFileSystemDatabase(t.TempDir()), "tenants/a/"))
+}
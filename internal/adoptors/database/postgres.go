@@ -5,60 +5,255 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/dialect/pgdialect"
 	"github.com/uptrace/bun/driver/pgdriver"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
 )
 
 type Contact struct {
 	bun.BaseModel `bun:"table:contacts"`
 
+	TenantID string          `bun:"tenant_id,pk,default:phonebook_tenant()"`
 	ID       string          `bun:"id,pk"`
 	Data     json.RawMessage `bun:"data,type:jsonb"`
-	Location string          `bun:"location,unique"`
+	Location string          `bun:"location,notnull"`
+}
+
+type Group struct {
+	bun.BaseModel `bun:"table:groups"`
+
+	TenantID string `bun:"tenant_id,pk,default:phonebook_tenant()"`
+	Name     string `bun:"name,pk"`
+}
+
+type ContactGroup struct {
+	bun.BaseModel `bun:"table:contact_groups"`
+
+	TenantID  string `bun:"tenant_id,pk,default:phonebook_tenant()"`
+	GroupName string `bun:"group_name,pk"`
+	Location  string `bun:"location,pk"`
+}
+
+// Blob is binary content stored under its hash.
+type Blob struct {
+	bun.BaseModel `bun:"table:blobs"`
+
+	TenantID string `bun:"tenant_id,pk,default:phonebook_tenant()"`
+	Hash     string `bun:"hash,pk"`
+	Content  []byte `bun:"content,type:bytea,notnull"`
+}
+
+// ContactChange is a row of the change log filled by a trigger on contacts.
+// XactID is the id of the writing transaction, which orders changes by
+// commit where ID, taken at insert time, does not.
+type ContactChange struct {
+	bun.BaseModel `bun:"table:contact_changes"`
+
+	ID        int64           `bun:"id,pk,autoincrement"`
+	XactID    string          `bun:"xact_id,type:xid8,notnull,default:pg_current_xact_id()"`
+	TenantID  string          `bun:"tenant_id,notnull,default:phonebook_tenant()"`
+	Location  string          `bun:"location,notnull"`
+	Op        string          `bun:"op,notnull"`
+	Data      json.RawMessage `bun:"data,type:jsonb"`
+	ChangedAt time.Time       `bun:"changed_at,notnull,default:current_timestamp"`
 }
 
 type PostgresDatabase struct {
-	db *bun.DB
+	db  *bun.DB
+	dsn string
+	// conn runs the queries: the database itself, or a transaction inside WithTx.
+	conn bun.IDB
 }
 
+// NewPostgresDatabase migrates the schema and connects as the default tenant,
+// whose rows have an empty tenant id. Creating the tenant role needs the
+// CREATEROLE privilege.
 func NewPostgresDatabase(dsn string) (*PostgresDatabase, error) {
-	sqldb := sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn)))
-	db := bun.NewDB(sqldb, pgdialect.New())
+	owner := openPostgres(dsn, nil)
+	defer owner.Close()
 
 	// Run migrations
-	if err := runMigrations(db); err != nil {
+	if err := runMigrations(owner); err != nil {
 		return nil, fmt.Errorf("failed to run migrations: %v", err)
 	}
 
-	return &PostgresDatabase{db: db}, nil
+	// Row-level security does not apply to the table owner or superusers, so
+	// queries run under the tenant role.
+	db := openPostgres(dsn, map[string]interface{}{"role": postgresTenantRole})
+	return &PostgresDatabase{db: db, conn: db, dsn: dsn}, nil
+}
+
+func openPostgres(dsn string, params map[string]interface{}) *bun.DB {
+	options := []pgdriver.Option{pgdriver.WithDSN(dsn)}
+	if params != nil {
+		options = append(options, pgdriver.WithConnParams(params))
+	}
+	return bun.NewDB(sql.OpenDB(pgdriver.NewConnector(options...)), pgdialect.New())
 }
 
 func runMigrations(db *bun.DB) error {
 	ctx := context.Background()
 	
-	// Drop the existing table if it exists
-	_, err := db.NewDropTable().
+	// Rows belong to the tenant of the session that writes them
+	if _, err := db.ExecContext(ctx, tenantFunction); err != nil {
+		return err
+	}
+
+	// Create missing tables only, so reopening a database keeps its data
+	_, err := db.NewCreateTable().
 		Model((*Contact)(nil)).
-		IfExists().
+		IfNotExists().
 		Exec(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to drop table: %v", err)
+		return err
 	}
-	
-	// Create the table with correct schema
+
 	_, err = db.NewCreateTable().
-		Model((*Contact)(nil)).
+		Model((*Group)(nil)).
+		IfNotExists().
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Memberships follow group renames and disappear with their group or contact
+	_, err = db.NewCreateTable().
+		Model((*ContactGroup)(nil)).
+		IfNotExists().
+		ForeignKey(`("tenant_id", "group_name") REFERENCES "groups" ("tenant_id", "name") ON DELETE CASCADE ON UPDATE CASCADE`).
+		ForeignKey(`("tenant_id", "location") REFERENCES "contacts" ("tenant_id", "id") ON DELETE CASCADE`).
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.NewCreateTable().
+		Model((*Blob)(nil)).
+		IfNotExists().
 		Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Log every contact write and notify watchers
+	_, err = db.NewCreateTable().
+		Model((*ContactChange)(nil)).
+		IfNotExists().
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err = db.ExecContext(ctx, changeLogColumns); err != nil {
+		return err
+	}
+	if _, err = db.ExecContext(ctx, changeLogTrigger); err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, tenantIsolation)
 	return err
 }
 
+// tenantFunction returns the tenant of the session, set through the
+// app.tenant_id connection parameter.
+const tenantFunction = `
+CREATE OR REPLACE FUNCTION phonebook_tenant() RETURNS text AS $$
+	SELECT COALESCE(current_setting('app.tenant_id', true), '')
+$$ LANGUAGE sql STABLE;
+`
+
+// postgresTenantRole is the role every query runs under, so that the
+// row-level security policies apply to it.
+const postgresTenantRole = "phonebook_tenant"
+
+// tenantIsolation limits every table to the rows of the session's tenant and
+// lets the connecting user switch to the tenant role.
+const tenantIsolation = `
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'phonebook_tenant') THEN
+		CREATE ROLE phonebook_tenant NOLOGIN;
+	END IF;
+END
+$$;
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON contacts, groups, contact_groups, contact_changes, blobs TO phonebook_tenant;
+GRANT USAGE ON SEQUENCE contact_changes_id_seq TO phonebook_tenant;
+GRANT phonebook_tenant TO CURRENT_USER;
+
+ALTER TABLE contacts ENABLE ROW LEVEL SECURITY;
+ALTER TABLE contacts FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON contacts;
+CREATE POLICY tenant_isolation ON contacts USING (tenant_id = phonebook_tenant());
+
+ALTER TABLE groups ENABLE ROW LEVEL SECURITY;
+ALTER TABLE groups FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON groups;
+CREATE POLICY tenant_isolation ON groups USING (tenant_id = phonebook_tenant());
+
+ALTER TABLE contact_groups ENABLE ROW LEVEL SECURITY;
+ALTER TABLE contact_groups FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON contact_groups;
+CREATE POLICY tenant_isolation ON contact_groups USING (tenant_id = phonebook_tenant());
+
+ALTER TABLE contact_changes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE contact_changes FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON contact_changes;
+CREATE POLICY tenant_isolation ON contact_changes USING (tenant_id = phonebook_tenant());
+
+ALTER TABLE blobs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE blobs FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON blobs;
+CREATE POLICY tenant_isolation ON blobs USING (tenant_id = phonebook_tenant());
+`
+
+// changeLogColumns adds the transaction id to change logs created before it
+// existed and indexes the columns watches and pruning read.
+const changeLogColumns = `
+ALTER TABLE contact_changes ADD COLUMN IF NOT EXISTS xact_id xid8 NOT NULL DEFAULT pg_current_xact_id();
+CREATE INDEX IF NOT EXISTS contact_changes_xact_id ON contact_changes (xact_id, id);
+CREATE INDEX IF NOT EXISTS contact_changes_changed_at ON contact_changes (changed_at);
+`
+
+// changeLogTrigger records each write to contacts in contact_changes and
+// sends the change id on the contact_changes channel. Notifications are only
+// delivered when the writing transaction commits. Every 1000th change deletes
+// the tenant's changes older than seven days, so the log stays bounded;
+// watches resuming from a pruned change fail with "Cursor expired".
+const changeLogTrigger = `
+CREATE OR REPLACE FUNCTION log_contact_change() RETURNS trigger AS $$
+DECLARE
+	change_id BIGINT;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		INSERT INTO contact_changes (tenant_id, location, op) VALUES (OLD.tenant_id, OLD.location, 'delete') RETURNING id INTO change_id;
+	ELSIF TG_OP = 'INSERT' THEN
+		INSERT INTO contact_changes (tenant_id, location, op, data) VALUES (NEW.tenant_id, NEW.location, 'create', NEW.data) RETURNING id INTO change_id;
+	ELSE
+		INSERT INTO contact_changes (tenant_id, location, op, data) VALUES (NEW.tenant_id, NEW.location, 'update', NEW.data) RETURNING id INTO change_id;
+	END IF;
+	PERFORM pg_notify('contact_changes', change_id::text);
+	IF change_id % 1000 = 0 THEN
+		DELETE FROM contact_changes WHERE changed_at < now() - interval '7 days';
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS contacts_change_log ON contacts;
+CREATE TRIGGER contacts_change_log
+AFTER INSERT OR UPDATE OR DELETE ON contacts
+FOR EACH ROW EXECUTE FUNCTION log_contact_change();
+`
+
 func (pg *PostgresDatabase) Create(location string, data map[string]interface{}) (bool, string) {
 	ctx := context.Background()
 
 	// Check if location already exists
-	exists, err := pg.db.NewSelect().
+	exists, err := pg.conn.NewSelect().
 		Model((*Contact)(nil)).
 		Where("location = ?", location).
 		Exists(ctx)
@@ -81,7 +276,7 @@ func (pg *PostgresDatabase) Create(location string, data map[string]interface{})
 		Data:     jsonData,
 	}
 
-	_, err = pg.db.NewInsert().
+	_, err = pg.conn.NewInsert().
 		Model(contact).
 		Exec(ctx)
 	if err != nil {
@@ -95,7 +290,7 @@ func (pg *PostgresDatabase) Read(location string) (bool, string, map[string]inte
 	ctx := context.Background()
 	contact := new(Contact)
 
-	err := pg.db.NewSelect().
+	err := pg.conn.NewSelect().
 		Model(contact).
 		Where("location = ?", location).
 		Scan(ctx)
@@ -116,7 +311,7 @@ func (pg *PostgresDatabase) Update(location string, data map[string]interface{})
 	ctx := context.Background()
 
 	// Check if location exists
-	exists, err := pg.db.NewSelect().
+	exists, err := pg.conn.NewSelect().
 		Model((*Contact)(nil)).
 		Where("location = ?", location).
 		Exists(ctx)
@@ -141,7 +336,7 @@ func (pg *PostgresDatabase) Update(location string, data map[string]interface{})
 	}
 
 	// Update using the model
-	_, err = pg.db.NewUpdate().
+	_, err = pg.conn.NewUpdate().
 		Model(contact).
 		Column("data").
 		Where("location = ?", location).
@@ -156,7 +351,7 @@ func (pg *PostgresDatabase) Update(location string, data map[string]interface{})
 func (pg *PostgresDatabase) Delete(location string) (bool, string) {
 	ctx := context.Background()
 
-	result, err := pg.db.NewDelete().
+	result, err := pg.conn.NewDelete().
 		Model((*Contact)(nil)).
 		Where("location = ?", location).
 		Exec(ctx)
@@ -175,6 +370,30 @@ func (pg *PostgresDatabase) Delete(location string) (bool, string) {
 	return true, ""
 }
 
+func (pg *PostgresDatabase) List(prefix string) (bool, string, []string) {
+	ctx := context.Background()
+
+	locations := make([]string, 0)
+	err := pg.conn.NewSelect().
+		Model((*Contact)(nil)).
+		Column("location").
+		Where("starts_with(location, ?)", prefix).
+		Order("location ASC").
+		Scan(ctx, &locations)
+	if err != nil {
+		return false, fmt.Sprintf("Error listing records: %v", err), nil
+	}
+
+	return true, "", locations
+}
+
+// WithTx runs fn inside a database transaction. Nested calls use savepoints.
+func (pg *PostgresDatabase) WithTx(fn func(tx ports.Database) error) error {
+	return pg.conn.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+		return fn(&PostgresDatabase{db: pg.db, dsn: pg.dsn, conn: tx})
+	})
+}
+
 func (pg *PostgresDatabase) Close() error {
 	return pg.db.Close()
 }
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/uptrace/bun"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// postgresBatchSize caps the rows sent in a single multi-row statement.
+const postgresBatchSize = 1000
+
+func (pg *PostgresDatabase) BatchRead(locations []string) (bool, string, map[string]map[string]interface{}) {
+	ctx := context.Background()
+	records := make(map[string]map[string]interface{}, len(locations))
+
+	for start := 0; start < len(locations); start += postgresBatchSize {
+		end := min(start+postgresBatchSize, len(locations))
+
+		var contacts []Contact
+		err := pg.conn.NewSelect().
+			Model(&contacts).
+			Where("location IN (?)", bun.In(locations[start:end])).
+			Scan(ctx)
+		if err != nil {
+			return false, fmt.Sprintf("Error reading records: %v", err), nil
+		}
+		for _, contact := range contacts {
+			var data map[string]interface{}
+			if err := json.Unmarshal(contact.Data, &data); err != nil {
+				return false, fmt.Sprintf("Error unmarshaling data: %v", err), nil
+			}
+			records[contact.Location] = data
+		}
+	}
+
+	return true, "", records
+}
+
+func (pg *PostgresDatabase) BatchCreate(items []ports.BatchItem) []ports.BatchResult {
+	return pg.batchInsert(items, "CONFLICT DO NOTHING", false)
+}
+
+func (pg *PostgresDatabase) BatchUpsert(items []ports.BatchItem) []ports.BatchResult {
+	return pg.batchInsert(items, "CONFLICT (tenant_id, id) DO UPDATE SET data = EXCLUDED.data", true)
+}
+
+func (pg *PostgresDatabase) BatchDelete(locations []string) []ports.BatchResult {
+	ctx := context.Background()
+	results := make([]ports.BatchResult, len(locations))
+	for i, location := range locations {
+		results[i].Location = location
+	}
+
+	for start := 0; start < len(locations); start += postgresBatchSize {
+		end := min(start+postgresBatchSize, len(locations))
+		chunk := locations[start:end]
+
+		deleted := make([]string, 0, len(chunk))
+		_, err := pg.conn.NewDelete().
+			Model((*Contact)(nil)).
+			Where("location IN (?)", bun.In(chunk)).
+			Returning("location").
+			Exec(ctx, &deleted)
+		if err != nil {
+			failResults(results[start:end], fmt.Sprintf("Error deleting records: %v", err))
+			continue
+		}
+
+		markResults(results[start:end], deleted, "Location does not exist")
+	}
+
+	return results
+}
+
+// batchInsert writes items with multi-row INSERT statements using the given
+// ON clause. Upserts let the last write to a location win; creates keep the first.
+func (pg *PostgresDatabase) batchInsert(items []ports.BatchItem, on string, upsert bool) []ports.BatchResult {
+	ctx := context.Background()
+	results := make([]ports.BatchResult, len(items))
+	for i, item := range items {
+		results[i].Location = item.Location
+	}
+
+	for start := 0; start < len(items); start += postgresBatchSize {
+		end := min(start+postgresBatchSize, len(items))
+
+		// A statement may not touch the same row twice, so only one write per
+		// location within the chunk is sent.
+		winner := make(map[string]int)
+		for i := start; i < end; i++ {
+			if _, seen := winner[items[i].Location]; !seen || upsert {
+				winner[items[i].Location] = i
+			}
+		}
+
+		contacts := make([]*Contact, 0, len(winner))
+		for i := start; i < end; i++ {
+			if winner[items[i].Location] != i {
+				if upsert {
+					results[i].Success = true
+				} else {
+					results[i].Message = "Location already exists"
+				}
+				continue
+			}
+			jsonData, err := json.Marshal(items[i].Data)
+			if err != nil {
+				results[i].Message = fmt.Sprintf("Error marshaling data: %v", err)
+				continue
+			}
+			contacts = append(contacts, &Contact{ID: items[i].Location, Location: items[i].Location, Data: jsonData})
+		}
+		if len(contacts) == 0 {
+			continue
+		}
+
+		written := make([]string, 0, len(contacts))
+		_, err := pg.conn.NewInsert().
+			Model(&contacts).
+			On(on).
+			Returning("location").
+			Exec(ctx, &written)
+		if err != nil {
+			failResults(results[start:end], fmt.Sprintf("Error writing records: %v", err))
+			continue
+		}
+
+		writtenSet := make(map[string]bool, len(written))
+		for _, location := range written {
+			writtenSet[location] = true
+		}
+		for i := start; i < end; i++ {
+			if winner[items[i].Location] != i || results[i].Message != "" {
+				continue
+			}
+			if writtenSet[items[i].Location] {
+				results[i].Success = true
+			} else {
+				results[i].Message = "Location already exists"
+			}
+		}
+	}
+
+	return results
+}
+
+func markResults(results []ports.BatchResult, done []string, missingMsg string) {
+	doneSet := make(map[string]bool, len(done))
+	for _, location := range done {
+		doneSet[location] = true
+	}
+	for i := range results {
+		if doneSet[results[i].Location] {
+			results[i].Success = true
+			delete(doneSet, results[i].Location)
+		} else {
+			results[i].Message = missingMsg
+		}
+	}
+}
+
+func failResults(results []ports.BatchResult, msg string) {
+	for i := range results {
+		results[i].Success = false
+		results[i].Message = msg
+	}
+}
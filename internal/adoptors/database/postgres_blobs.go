@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+func (pg *PostgresDatabase) PutBlob(hash string, content []byte) (bool, string) {
+	if message := checkBlob(hash, content); message != "" {
+		return false, message
+	}
+	_, err := pg.conn.NewInsert().
+		Model(&Blob{Hash: hash, Content: content}).
+		On("CONFLICT DO NOTHING").
+		Exec(context.Background())
+	if err != nil {
+		return false, fmt.Sprintf("Error storing blob: %v", err)
+	}
+	return true, ""
+}
+
+func (pg *PostgresDatabase) GetBlob(hash string) (bool, string, []byte) {
+	var blob Blob
+	err := pg.conn.NewSelect().
+		Model(&blob).
+		Where("hash = ?", hash).
+		Scan(context.Background())
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, "Blob does not exist", nil
+	}
+	if err != nil {
+		return false, fmt.Sprintf("Error reading blob: %v", err), nil
+	}
+	return true, "", blob.Content
+}
+
+func (pg *PostgresDatabase) DeleteBlob(hash string) (bool, string) {
+	result, err := pg.conn.NewDelete().
+		Model((*Blob)(nil)).
+		Where("hash = ?", hash).
+		Exec(context.Background())
+	if err != nil {
+		return false, fmt.Sprintf("Error deleting blob: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return false, "Blob does not exist"
+	}
+	return true, ""
+}
+
+func (pg *PostgresDatabase) ListBlobs() (bool, string, []string) {
+	hashes := make([]string, 0)
+	err := pg.conn.NewSelect().
+		Model((*Blob)(nil)).
+		Column("hash").
+		Order("hash").
+		Scan(context.Background(), &hashes)
+	if err != nil {
+		return false, fmt.Sprintf("Error listing blobs: %v", err), nil
+	}
+	return true, "", hashes
+}
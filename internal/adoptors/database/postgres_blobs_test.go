@@ -0,0 +1,10 @@
+package database
+
+import "testing"
+
+func TestPostgresDatabase_Blobs(t *testing.T) {
+	db := setupPostgresTest(t)
+	defer cleanupPostgresTest(t, db)
+
+	testBlobStore(t, db)
+}
@@ -0,0 +1,208 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+func (pg *PostgresDatabase) CreateGroup(name string) (bool, string) {
+	ctx := context.Background()
+
+	result, err := pg.conn.NewInsert().
+		Model(&Group{Name: name}).
+		On("CONFLICT (tenant_id, name) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Sprintf("Error creating group: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Sprintf("Error checking rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return false, "Group already exists"
+	}
+
+	return true, ""
+}
+
+func (pg *PostgresDatabase) RenameGroup(name, newName string) (bool, string) {
+	ctx := context.Background()
+
+	exists, err := pg.groupExists(ctx, newName)
+	if err != nil {
+		return false, fmt.Sprintf("Error checking group: %v", err)
+	}
+	if exists {
+		return false, "Group already exists"
+	}
+
+	// Memberships follow through ON UPDATE CASCADE
+	result, err := pg.conn.NewUpdate().
+		Model((*Group)(nil)).
+		Set("name = ?", newName).
+		Where("name = ?", name).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Sprintf("Error renaming group: %v", err)
+	}
+
+	return groupRowsAffected(result.RowsAffected())
+}
+
+func (pg *PostgresDatabase) DeleteGroup(name string) (bool, string) {
+	ctx := context.Background()
+
+	result, err := pg.conn.NewDelete().
+		Model((*Group)(nil)).
+		Where("name = ?", name).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Sprintf("Error deleting group: %v", err)
+	}
+
+	return groupRowsAffected(result.RowsAffected())
+}
+
+func (pg *PostgresDatabase) ListGroups() (bool, string, []string) {
+	ctx := context.Background()
+
+	names := make([]string, 0)
+	err := pg.conn.NewSelect().
+		Model((*Group)(nil)).
+		Column("name").
+		Order("name ASC").
+		Scan(ctx, &names)
+	if err != nil {
+		return false, fmt.Sprintf("Error listing groups: %v", err), nil
+	}
+
+	return true, "", names
+}
+
+func (pg *PostgresDatabase) AddMember(group, location string) (bool, string) {
+	ctx := context.Background()
+
+	exists, err := pg.groupExists(ctx, group)
+	if err != nil {
+		return false, fmt.Sprintf("Error checking group: %v", err)
+	}
+	if !exists {
+		return false, "Group does not exist"
+	}
+
+	exists, err = pg.conn.NewSelect().
+		Model((*Contact)(nil)).
+		Where("location = ?", location).
+		Exists(ctx)
+	if err != nil {
+		return false, fmt.Sprintf("Error checking location: %v", err)
+	}
+	if !exists {
+		return false, "Location does not exist"
+	}
+
+	_, err = pg.conn.NewInsert().
+		Model(&ContactGroup{GroupName: group, Location: location}).
+		On("CONFLICT DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Sprintf("Error adding member: %v", err)
+	}
+
+	return true, ""
+}
+
+func (pg *PostgresDatabase) RemoveMember(group, location string) (bool, string) {
+	ctx := context.Background()
+
+	exists, err := pg.groupExists(ctx, group)
+	if err != nil {
+		return false, fmt.Sprintf("Error checking group: %v", err)
+	}
+	if !exists {
+		return false, "Group does not exist"
+	}
+
+	_, err = pg.conn.NewDelete().
+		Model((*ContactGroup)(nil)).
+		Where("group_name = ?", group).
+		Where("location = ?", location).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Sprintf("Error removing member: %v", err)
+	}
+
+	return true, ""
+}
+
+func (pg *PostgresDatabase) Members(group string) (bool, string, []string) {
+	ctx := context.Background()
+
+	exists, err := pg.groupExists(ctx, group)
+	if err != nil {
+		return false, fmt.Sprintf("Error checking group: %v", err), nil
+	}
+	if !exists {
+		return false, "Group does not exist", nil
+	}
+
+	locations := make([]string, 0)
+	err = pg.conn.NewSelect().
+		Model((*ContactGroup)(nil)).
+		Column("location").
+		Where("group_name = ?", group).
+		Order("location ASC").
+		Scan(ctx, &locations)
+	if err != nil {
+		return false, fmt.Sprintf("Error listing members: %v", err), nil
+	}
+
+	return true, "", locations
+}
+
+func (pg *PostgresDatabase) GroupsOf(location string) (bool, string, []string) {
+	ctx := context.Background()
+
+	exists, err := pg.conn.NewSelect().
+		Model((*Contact)(nil)).
+		Where("location = ?", location).
+		Exists(ctx)
+	if err != nil {
+		return false, fmt.Sprintf("Error checking location: %v", err), nil
+	}
+	if !exists {
+		return false, "Location does not exist", nil
+	}
+
+	names := make([]string, 0)
+	err = pg.conn.NewSelect().
+		Model((*ContactGroup)(nil)).
+		Column("group_name").
+		Where("location = ?", location).
+		Order("group_name ASC").
+		Scan(ctx, &names)
+	if err != nil {
+		return false, fmt.Sprintf("Error listing groups: %v", err), nil
+	}
+
+	return true, "", names
+}
+
+func (pg *PostgresDatabase) groupExists(ctx context.Context, name string) (bool, error) {
+	return pg.conn.NewSelect().
+		Model((*Group)(nil)).
+		Where("name = ?", name).
+		Exists(ctx)
+}
+
+func groupRowsAffected(rowsAffected int64, err error) (bool, string) {
+	if err != nil {
+		return false, fmt.Sprintf("Error checking rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return false, "Group does not exist"
+	}
+	return true, ""
+}
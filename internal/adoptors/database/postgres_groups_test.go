@@ -0,0 +1,56 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresDatabase_Groups(t *testing.T) {
+	db := setupPostgresTest(t)
+	defer cleanupPostgresTest(t, db)
+
+	for _, location := range []string{"contacts/john", "contacts/jane"} {
+		success, err := db.Create(location, map[string]interface{}{"name": location})
+		assert.True(t, success, "Failed to create contact: %v", err)
+	}
+
+	success, err := db.CreateGroup("family")
+	assert.True(t, success, err)
+	success, err = db.CreateGroup("family")
+	assert.False(t, success)
+	assert.Contains(t, err, "Group already exists")
+
+	success, err = db.AddMember("family", "contacts/nobody")
+	assert.False(t, success)
+	assert.Contains(t, err, "Location does not exist")
+
+	assert.True(t, mustSucceed(db.AddMember("family", "contacts/john")))
+	assert.True(t, mustSucceed(db.AddMember("family", "contacts/jane")))
+	assert.True(t, mustSucceed(db.CreateGroup("work")))
+	assert.True(t, mustSucceed(db.AddMember("work", "contacts/john")))
+
+	_, _, members := db.Members("family")
+	assert.Equal(t, []string{"contacts/jane", "contacts/john"}, members)
+	_, _, groups := db.GroupsOf("contacts/john")
+	assert.Equal(t, []string{"family", "work"}, groups)
+
+	// Renaming keeps members
+	assert.True(t, mustSucceed(db.RenameGroup("family", "relatives")))
+	_, _, members = db.Members("relatives")
+	assert.Equal(t, []string{"contacts/jane", "contacts/john"}, members)
+
+	// Deleting a contact removes its memberships
+	assert.True(t, mustSucceed(db.Delete("contacts/john")))
+	_, _, members = db.Members("work")
+	assert.Empty(t, members)
+
+	assert.True(t, mustSucceed(db.RemoveMember("relatives", "contacts/jane")))
+	assert.True(t, mustSucceed(db.DeleteGroup("work")))
+	_, _, names := db.ListGroups()
+	assert.Equal(t, []string{"relatives"}, names)
+}
+
+func mustSucceed(success bool, _ string) bool {
+	return success
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// Patch merges the fields into the stored document with jsonb || and removes
+// the nil ones with jsonb -, in one UPDATE.
+func (pg *PostgresDatabase) Patch(location string, fields map[string]interface{}) (bool, string) {
+	set := make(map[string]interface{}, len(fields))
+	remove := make([]string, 0)
+	for field, value := range fields {
+		if value == nil {
+			remove = append(remove, field)
+		} else {
+			set[field] = value
+		}
+	}
+	jsonData, err := json.Marshal(set)
+	if err != nil {
+		return false, fmt.Sprintf("Error marshaling data: %v", err)
+	}
+
+	result, err := pg.conn.NewUpdate().
+		Model((*Contact)(nil)).
+		Set("data = (data || ?::jsonb) - ?::text[]", string(jsonData), pgdialect.Array(remove)).
+		Where("location = ?", location).
+		Exec(context.Background())
+	if err != nil {
+		return false, fmt.Sprintf("Error updating record: %v", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return false, "Location does not exist"
+	}
+	return true, ""
+}
@@ -0,0 +1,10 @@
+package database
+
+import "testing"
+
+func TestPostgresDatabase_Patch(t *testing.T) {
+	db := setupPostgresTest(t)
+	defer cleanupPostgresTest(t, db)
+
+	testPatchDatabase(t, db)
+}
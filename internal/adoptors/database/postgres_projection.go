@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// projectedDataColumn selects the requested keys of the data column on the server,
+// leaving out the ones a record lacks.
+const projectedDataColumn = "COALESCE((SELECT jsonb_object_agg(key, value) FROM jsonb_each(data) WHERE key = ANY(?)), '{}'::jsonb) AS data"
+
+type projectedRow struct {
+	Location string          `bun:"location"`
+	Data     json.RawMessage `bun:"data"`
+}
+
+func (pg *PostgresDatabase) ReadFields(location string, fields []string) (bool, string, map[string]interface{}) {
+	var row projectedRow
+	err := pg.conn.NewSelect().
+		Model((*Contact)(nil)).
+		Column("location").
+		ColumnExpr(projectedDataColumn, pgdialect.Array(fields)).
+		Where("location = ?", location).
+		Scan(context.Background(), &row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, "Location does not exist", nil
+	}
+	if err != nil {
+		return false, fmt.Sprintf("Error reading record: %v", err), nil
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(row.Data, &data); err != nil {
+		return false, fmt.Sprintf("Error unmarshaling data: %v", err), nil
+	}
+	return true, "", data
+}
+
+func (pg *PostgresDatabase) ListFields(prefix string, fields []string) (bool, string, map[string]map[string]interface{}) {
+	var rows []projectedRow
+	err := pg.conn.NewSelect().
+		Model((*Contact)(nil)).
+		Column("location").
+		ColumnExpr(projectedDataColumn, pgdialect.Array(fields)).
+		Where("starts_with(location, ?)", prefix).
+		Scan(context.Background(), &rows)
+	if err != nil {
+		return false, fmt.Sprintf("Error listing records: %v", err), nil
+	}
+
+	records := make(map[string]map[string]interface{}, len(rows))
+	for _, row := range rows {
+		var data map[string]interface{}
+		if err := json.Unmarshal(row.Data, &data); err != nil {
+			return false, fmt.Sprintf("Error unmarshaling data: %v", err), nil
+		}
+		records[row.Location] = data
+	}
+	return true, "", records
+}
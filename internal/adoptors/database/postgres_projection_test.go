@@ -0,0 +1,10 @@
+package database
+
+import "testing"
+
+func TestPostgresDatabase_Projection(t *testing.T) {
+	db := setupPostgresTest(t)
+	defer cleanupPostgresTest(t, db)
+
+	testProjectionDatabase(t, db)
+}
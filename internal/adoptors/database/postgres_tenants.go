@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// Tenant returns the database of a tenant: a connection pool whose sessions
+// only see the tenant's rows.
+func (pg *PostgresDatabase) Tenant(id string) (bool, string, ports.Database) {
+	if id == "" {
+		return false, "Invalid tenant", nil
+	}
+	db := openPostgres(pg.dsn, map[string]interface{}{
+		"app.tenant_id": id,
+		"role":          postgresTenantRole,
+	})
+	return true, "", &PostgresDatabase{db: db, conn: db, dsn: pg.dsn}
+}
+
+// DropTenant deletes every row of a tenant.
+func (pg *PostgresDatabase) DropTenant(id string) (bool, string) {
+	success, message, tenant := pg.Tenant(id)
+	if !success {
+		return false, message
+	}
+	defer tenant.(*PostgresDatabase).Close()
+
+	err := tenant.(*PostgresDatabase).WithTx(func(tx ports.Database) error {
+		conn := tx.(*PostgresDatabase).conn
+		for _, model := range []interface{}{(*ContactGroup)(nil), (*Group)(nil), (*Contact)(nil), (*ContactChange)(nil), (*Blob)(nil)} {
+			if _, err := conn.NewDelete().Model(model).Where("TRUE").Exec(context.Background()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Sprintf("Error dropping tenant: %v", err)
+	}
+	return true, ""
+}
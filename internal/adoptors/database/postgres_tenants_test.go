@@ -0,0 +1,10 @@
+package database
+
+import "testing"
+
+func TestPostgresDatabase_Tenants(t *testing.T) {
+	db := setupPostgresTest(t)
+	defer cleanupPostgresTest(t, db)
+
+	testTenantIsolation(t, db, Tenants(db))
+}
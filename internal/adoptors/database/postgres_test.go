@@ -57,11 +57,17 @@ func setupPostgresTest(t *testing.T) *PostgresDatabase {
 
 func cleanupPostgresTest(t *testing.T, db *PostgresDatabase) {
 	ctx := context.Background()
-	_, err := db.db.NewDropTable().Model((*Contact)(nil)).IfExists().Exec(ctx)
-	if err != nil {
-		t.Errorf("Failed to cleanup test database: %v", err)
+
+	// Queries run under the tenant role, which cannot drop tables
+	owner := openPostgres(db.dsn, nil)
+	defer owner.Close()
+	for _, model := range []interface{}{(*ContactGroup)(nil), (*Group)(nil), (*Contact)(nil), (*ContactChange)(nil), (*Blob)(nil)} {
+		_, err := owner.NewDropTable().Model(model).IfExists().Exec(ctx)
+		if err != nil {
+			t.Errorf("Failed to cleanup test database: %v", err)
+		}
 	}
-	err = db.Close()
+	err := db.Close()
 	if err != nil {
 		t.Errorf("Failed to close database connection: %v", err)
 	}
@@ -372,3 +378,18 @@ func TestPostgresDatabase_Concurrency(t *testing.T) {
 	assert.True(t, success, "Failed to read final value")
 	assert.Equal(t, float64(numGoroutines), data["counter"].(float64), "Counter value mismatch")
 }
+
+func TestPostgresDatabase_List(t *testing.T) {
+	db := setupPostgresTest(t)
+	defer cleanupPostgresTest(t, db)
+
+	for _, location := range []string{"contacts/b", "contacts/a", "other/c"} {
+		success, err := db.Create(location, map[string]interface{}{"name": location})
+		assert.True(t, success, "Failed to create contact: %v", err)
+	}
+
+	success, err, locations := db.List("contacts/")
+	assert.True(t, success)
+	assert.Empty(t, err)
+	assert.Equal(t, []string{"contacts/a", "contacts/b"}, locations)
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func TestPostgresDatabase_WithTx(t *testing.T) {
+	db := setupPostgresTest(t)
+	defer cleanupPostgresTest(t, db)
+
+	success, err := db.Create("contacts/john", map[string]interface{}{"name": "John Doe"})
+	assert.True(t, success, err)
+
+	// A failing transaction is rolled back
+	errRollback := errors.New("rollback")
+	txErr := db.WithTx(func(tx ports.Database) error {
+		tx.Create("contacts/jane", map[string]interface{}{"name": "Jane Doe"})
+		tx.Delete("contacts/john")
+		return errRollback
+	})
+	assert.ErrorIs(t, txErr, errRollback)
+	_, _, locations := db.List("")
+	assert.Equal(t, []string{"contacts/john"}, locations)
+
+	// A successful transaction commits every write
+	txErr = db.WithTx(func(tx ports.Database) error {
+		if success, msg := tx.Create("contacts/jane", map[string]interface{}{"name": "Jane Doe"}); !success {
+			return errors.New(msg)
+		}
+		if success, msg := tx.Delete("contacts/john"); !success {
+			return errors.New(msg)
+		}
+		return nil
+	})
+	assert.NoError(t, txErr)
+	_, _, locations = db.List("")
+	assert.Equal(t, []string{"contacts/jane"}, locations)
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Upsert writes with a single INSERT ... ON CONFLICT. A freshly inserted row
+// has no deleting transaction, which tells a creation from a replacement.
+func (pg *PostgresDatabase) Upsert(location string, data map[string]interface{}) (bool, string, bool) {
+	contact, message := newContactRow(location, data)
+	if contact == nil {
+		return false, message, false
+	}
+
+	inserted := make([]bool, 0, 1)
+	_, err := pg.conn.NewInsert().
+		Model(contact).
+		On("CONFLICT (tenant_id, id) DO UPDATE SET data = EXCLUDED.data").
+		Returning("(xmax = 0) AS inserted").
+		Exec(context.Background(), &inserted)
+	if err != nil {
+		return false, fmt.Sprintf("Error writing record: %v", err), false
+	}
+	return true, "", len(inserted) == 1 && inserted[0]
+}
+
+func (pg *PostgresDatabase) CreateIfAbsent(location string, data map[string]interface{}) (bool, string, bool) {
+	contact, message := newContactRow(location, data)
+	if contact == nil {
+		return false, message, false
+	}
+
+	created := make([]string, 0, 1)
+	_, err := pg.conn.NewInsert().
+		Model(contact).
+		On("CONFLICT DO NOTHING").
+		Returning("location").
+		Exec(context.Background(), &created)
+	if err != nil {
+		return false, fmt.Sprintf("Error creating record: %v", err), false
+	}
+	return true, "", len(created) == 1
+}
+
+func (pg *PostgresDatabase) UpdateIfExists(location string, data map[string]interface{}) (bool, string, bool) {
+	contact, message := newContactRow(location, data)
+	if contact == nil {
+		return false, message, false
+	}
+
+	updated := make([]string, 0, 1)
+	_, err := pg.conn.NewUpdate().
+		Model(contact).
+		Column("data").
+		Where("location = ?", location).
+		Returning("location").
+		Exec(context.Background(), &updated)
+	if err != nil {
+		return false, fmt.Sprintf("Error updating record: %v", err), false
+	}
+	return true, "", len(updated) == 1
+}
+
+func newContactRow(location string, data map[string]interface{}) (*Contact, string) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Sprintf("Error marshaling data: %v", err)
+	}
+	return &Contact{ID: location, Location: location, Data: jsonData}, ""
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun/driver/pgdriver"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+const (
+	// changeChannel is the LISTEN/NOTIFY channel used by the change log trigger.
+	changeChannel = "contact_changes"
+	// changePageSize is how many change log rows are read per query.
+	changePageSize = 500
+	// changePollInterval re-reads the change log in case a notification was missed.
+	changePollInterval = 5 * time.Second
+)
+
+// Watch streams rows of the change log in commit order. Change log ids are
+// taken when a row is inserted, so a transaction can commit after a higher id
+// was already delivered; changes are instead ordered by writing transaction
+// and only delivered once every transaction before them has finished. A long
+// running writer therefore delays later changes until it ends. Cursors are
+// "<transaction id>:<change log id>".
+func (pg *PostgresDatabase) Watch(ctx context.Context, prefix, fromCursor string) (bool, string, <-chan ports.ChangeEvent) {
+	var lastXact string
+	var last int64
+	if fromCursor != "" {
+		xact, id, ok := parseChangeCursor(fromCursor)
+		if !ok {
+			return false, "Invalid cursor", nil
+		}
+		// The change a cursor points at is gone once the log was pruned past it
+		exists, err := pg.db.NewSelect().
+			Model((*ContactChange)(nil)).
+			Where("id = ?", id).
+			Exists(ctx)
+		if err != nil {
+			return false, fmt.Sprintf("Error reading change log: %v", err), nil
+		}
+		if !exists {
+			return false, ports.CursorExpired, nil
+		}
+		lastXact, last = xact, id
+	} else {
+		// Transactions still running may commit changes after the watch
+		// starts, so they are watched too
+		err := pg.db.NewSelect().
+			ColumnExpr("pg_snapshot_xmin(pg_current_snapshot())::text").
+			Scan(ctx, &lastXact)
+		if err != nil {
+			return false, fmt.Sprintf("Error reading change log: %v", err), nil
+		}
+	}
+
+	listener := pgdriver.NewListener(pg.db)
+	if err := listener.Listen(ctx, changeChannel); err != nil {
+		listener.Close()
+		return false, fmt.Sprintf("Error listening for changes: %v", err), nil
+	}
+	notifications := listener.Channel()
+
+	out := make(chan ports.ChangeEvent)
+	go func() {
+		defer close(out)
+		defer listener.Close()
+
+		ticker := time.NewTicker(changePollInterval)
+		defer ticker.Stop()
+
+		for {
+			// Drain the log before waiting again
+			for {
+				changes := make([]ContactChange, 0, changePageSize)
+				err := pg.db.NewSelect().
+					Model(&changes).
+					Where("(xact_id, id) > (?::xid8, ?)", lastXact, last).
+					Where("xact_id < pg_snapshot_xmin(pg_current_snapshot())").
+					Where("starts_with(location, ?)", prefix).
+					OrderExpr("xact_id ASC, id ASC").
+					Limit(changePageSize).
+					Scan(ctx)
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					break
+				}
+
+				for _, change := range changes {
+					lastXact, last = change.XactID, change.ID
+					event := ports.ChangeEvent{
+						Cursor:   change.XactID + ":" + strconv.FormatInt(change.ID, 10),
+						Op:       ports.ChangeOp(change.Op),
+						Location: change.Location,
+						Time:     change.ChangedAt,
+					}
+					if len(change.Data) > 0 {
+						if err := json.Unmarshal(change.Data, &event.Data); err != nil {
+							continue
+						}
+					}
+					select {
+					case out <- event:
+					case <-ctx.Done():
+						return
+					}
+				}
+				if len(changes) < changePageSize {
+					break
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-notifications:
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return true, "", out
+}
+
+// parseChangeCursor splits a cursor into its transaction and change log ids.
+func parseChangeCursor(cursor string) (string, int64, bool) {
+	xact, rest, found := strings.Cut(cursor, ":")
+	if !found {
+		return "", 0, false
+	}
+	if _, err := strconv.ParseUint(xact, 10, 64); err != nil {
+		return "", 0, false
+	}
+	id, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return xact, id, true
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func TestPostgresDatabase_Watch(t *testing.T) {
+	db := setupPostgresTest(t)
+	defer cleanupPostgresTest(t, db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	success, err, events := db.Watch(ctx, "contacts/", "")
+	assert.True(t, success, err)
+
+	db.Create("other/skipped", map[string]interface{}{"name": "Skipped"})
+	db.Create("contacts/john", map[string]interface{}{"name": "John Doe"})
+	db.Update("contacts/john", map[string]interface{}{"name": "John Updated"})
+	db.Delete("contacts/john")
+
+	created := receiveChange(t, events)
+	assert.Equal(t, ports.ChangeCreate, created.Op)
+	assert.Equal(t, "contacts/john", created.Location)
+	assert.Equal(t, "John Doe", created.Data["name"])
+
+	updated := receiveChange(t, events)
+	assert.Equal(t, ports.ChangeUpdate, updated.Op)
+	assert.Equal(t, "John Updated", updated.Data["name"])
+
+	deleted := receiveChange(t, events)
+	assert.Equal(t, ports.ChangeDelete, deleted.Op)
+
+	// Resuming from a cursor replays what came after it
+	success, err, resumed := db.Watch(ctx, "contacts/", created.Cursor)
+	assert.True(t, success, err)
+	assert.Equal(t, updated.Cursor, receiveChange(t, resumed).Cursor)
+}
+
+func TestPostgresDatabase_WatchLateCommit(t *testing.T) {
+	db := setupPostgresTest(t)
+	defer cleanupPostgresTest(t, db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	success, err, events := db.Watch(ctx, "contacts/", "")
+	assert.True(t, success, err)
+
+	// The transaction takes its change log id first but commits last
+	written, commit, done := make(chan struct{}), make(chan struct{}), make(chan error)
+	go func() {
+		done <- db.WithTx(func(tx ports.Database) error {
+			tx.Create("contacts/late", map[string]interface{}{"name": "Late"})
+			close(written)
+			<-commit
+			return nil
+		})
+	}()
+	<-written
+	db.Create("contacts/early", map[string]interface{}{"name": "Early"})
+	close(commit)
+	assert.NoError(t, <-done)
+
+	received := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		received[receiveChange(t, events).Location] = true
+	}
+	assert.Equal(t, map[string]bool{"contacts/late": true, "contacts/early": true}, received)
+}
+
+func TestPostgresDatabase_WatchExpiredCursor(t *testing.T) {
+	db := setupPostgresTest(t)
+	defer cleanupPostgresTest(t, db)
+
+	success, message, _ := db.Watch(context.Background(), "", "1:1")
+	assert.False(t, success)
+	assert.Equal(t, "Cursor expired", message)
+
+	success, message, _ = db.Watch(context.Background(), "", "1")
+	assert.False(t, success)
+	assert.Equal(t, "Invalid cursor", message)
+}
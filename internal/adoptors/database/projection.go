@@ -0,0 +1,71 @@
+package database
+
+import (
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// readFields reads some fields of a record, through db's projection when it
+// has one and by reading the whole record otherwise.
+func readFields(db ports.Database, location string, fields []string) (bool, string, map[string]interface{}) {
+	if projector, ok := db.(ports.ProjectionDatabase); ok {
+		return projector.ReadFields(location, fields)
+	}
+	success, message, data := db.Read(location)
+	if !success {
+		return false, message, nil
+	}
+	return true, "", project(data, fields)
+}
+
+// listFields reads some fields of every record under prefix.
+func listFields(db ports.Database, prefix string, fields []string) (bool, string, map[string]map[string]interface{}) {
+	if projector, ok := db.(ports.ProjectionDatabase); ok {
+		return projector.ListFields(prefix, fields)
+	}
+	success, message, locations := db.List(prefix)
+	if !success {
+		return false, message, nil
+	}
+	records := make(map[string]map[string]interface{}, len(locations))
+	for _, location := range locations {
+		success, message, data := readFields(db, location, fields)
+		if !success {
+			if missing(message) {
+				continue
+			}
+			return false, message, nil
+		}
+		records[location] = data
+	}
+	return true, "", records
+}
+
+// readRecords reads many records, in one batch when db supports it.
+func readRecords(db ports.Database, locations []string) (bool, string, map[string]map[string]interface{}) {
+	if batch, ok := db.(ports.BatchDatabase); ok {
+		return batch.BatchRead(locations)
+	}
+	records := make(map[string]map[string]interface{}, len(locations))
+	for _, location := range locations {
+		success, message, data := db.Read(location)
+		if !success {
+			if missing(message) {
+				continue
+			}
+			return false, message, nil
+		}
+		records[location] = data
+	}
+	return true, "", records
+}
+
+// project returns the given fields of data.
+func project(data map[string]interface{}, fields []string) map[string]interface{} {
+	projected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if value, ok := data[field]; ok {
+			projected[field] = value
+		}
+	}
+	return projected
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+type projectionTestDatabase interface {
+	ports.Database
+	ports.ProjectionDatabase
+}
+
+// testProjectionDatabase checks the projected reads of db, which starts empty.
+func testProjectionDatabase(t *testing.T, db projectionTestDatabase) {
+	require.True(t, mustSucceed(db.Create("contacts/john", map[string]interface{}{"name": "John", "phone": "555-0001", "address": "1 Main St"})))
+	require.True(t, mustSucceed(db.Create("contacts/jane", map[string]interface{}{"name": "Jane", "phone": "555-0002"})))
+	require.True(t, mustSucceed(db.Create("other/jim", map[string]interface{}{"name": "Jim"})))
+
+	readTests := []struct {
+		name            string
+		location        string
+		fields          []string
+		expectedSuccess bool
+		expectedData    map[string]interface{}
+	}{
+		{"some fields", "contacts/john", []string{"name", "phone"}, true, map[string]interface{}{"name": "John", "phone": "555-0001"}},
+		{"absent field is left out", "contacts/jane", []string{"name", "address"}, true, map[string]interface{}{"name": "Jane"}},
+		{"no fields", "contacts/john", []string{}, true, map[string]interface{}{}},
+		{"missing record", "contacts/nobody", []string{"name"}, false, nil},
+	}
+	for _, tt := range readTests {
+		t.Run(tt.name, func(t *testing.T) {
+			success, message, data := db.ReadFields(tt.location, tt.fields)
+			assert.Equal(t, tt.expectedSuccess, success, message)
+			assert.Equal(t, tt.expectedData, data)
+		})
+	}
+
+	t.Run("list", func(t *testing.T) {
+		success, message, records := db.ListFields("contacts/", []string{"address"})
+		require.True(t, success, message)
+		assert.Equal(t, map[string]map[string]interface{}{
+			"contacts/john": {"address": "1 Main St"},
+			"contacts/jane": {},
+		}, records)
+	})
+}
+
+func TestInMemoryDatabase_Projection(t *testing.T) {
+	testProjectionDatabase(t, NewInMemoryDatabase())
+}
+
+func TestNamespacedDatabase_Projection(t *testing.T) {
+	testProjectionDatabase(t, NewNamespacedDatabase(NewInMemoryDatabase(), "tenants/a/"))
+}
+
+func TestNamespacedDatabase_ProjectionWithoutNativeSupport(t *testing.T) {
+	testProjectionDatabase(t, NewNamespacedDatabase(plainDatabase{NewInMemoryDatabase()}, ""))
+}
+
+func TestEncryptedDatabase_Projection(t *testing.T) {
+	inner := NewInMemoryDatabase()
+	testProjectionDatabase(t, setupEncryptedTest(t, inner, testMasterKey))
+
+	// Sealed fields stay sealed in the wrapped database
+	_, _, data := inner.ReadFields("contacts/john", []string{"phone"})
+	assert.NotEqual(t, "555-0001", data["phone"])
+}
+
+func TestMirroredDatabase_Projection(t *testing.T) {
+	testProjectionDatabase(t, NewMirroredDatabase(NewInMemoryDatabase(), []ports.Database{NewInMemoryDatabase()}))
+}
+
+func TestShardedDatabase_Projection(t *testing.T) {
+	db, err := NewShardedDatabase(newShards("a", "b"))
+	require.NoError(t, err)
+	testProjectionDatabase(t, db)
+}
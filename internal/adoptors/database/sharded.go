@@ -0,0 +1,823 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// DefaultVirtualNodes is how many points each shard gets on the hash ring.
+const DefaultVirtualNodes = 128
+
+// Every shard records the shards it was last balanced across, so any process
+// opening a changed set of shards knows a rebalance is pending.
+const (
+	shardingPrefix     = "_sharding/"
+	ringMarkerLocation = shardingPrefix + "ring"
+)
+
+// ShardedDatabase spreads records over several databases by consistent
+// hashing of their locations, so adding a shard moves only the records that
+// now belong to it. Listing and lookups fan out to every shard and merge the
+// results. Groups exist on every shard; a membership lives with its contact.
+// Blobs are placed by their hash, the way records are by their location.
+//
+// Until a rebalance has placed every record, a record missing from its shard
+// is looked for on all of them, so the database stays fully usable while
+// records move. Transactions and watches are not supported across shards.
+type ShardedDatabase struct {
+	virtualNodes int
+
+	mu          sync.RWMutex
+	names       []string
+	shards      map[string]ports.Database
+	ring        []ringPoint
+	rebalancing bool
+
+	// locks serialize writes and moves of the same location
+	locks [64]sync.Mutex
+}
+
+type ringPoint struct {
+	hash  uint64
+	shard string
+}
+
+type ShardOption func(*ShardedDatabase)
+
+// WithVirtualNodes sets how many points each shard gets on the hash ring.
+// More points spread records more evenly.
+func WithVirtualNodes(n int) ShardOption {
+	return func(s *ShardedDatabase) {
+		if n > 0 {
+			s.virtualNodes = n
+		}
+	}
+}
+
+// NewShardedDatabase routes over the named shards. Names, not order, decide
+// placement, so they must stay the same between runs.
+func NewShardedDatabase(shards map[string]ports.Database, opts ...ShardOption) (*ShardedDatabase, error) {
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("a sharded database needs at least one shard")
+	}
+	s := &ShardedDatabase{virtualNodes: DefaultVirtualNodes, shards: make(map[string]ports.Database)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	for name, db := range shards {
+		s.shards[name] = db
+		s.names = append(s.names, name)
+	}
+	sort.Strings(s.names)
+	s.buildRing()
+
+	// A shard balanced across a different set of shards may hold records
+	// that now belong elsewhere
+	ring := strings.Join(s.names, ",")
+	for _, name := range s.names {
+		if _, _, data := s.shards[name].Read(ringMarkerLocation); stringValue(data, "shards") != ring {
+			s.rebalancing = true
+			break
+		}
+	}
+	return s, nil
+}
+
+// AddShard adds a shard and starts moving records to it: until Rebalance
+// completes, records are found wherever they are.
+func (s *ShardedDatabase) AddShard(name string, db ports.Database) (bool, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.shards[name]; exists {
+		return false, fmt.Sprintf("Shard %s already exists", name)
+	}
+
+	// Every shard knows every group
+	if _, ok := db.(ports.GroupStore); ok {
+		success, message, names := s.listGroups()
+		if !success {
+			return false, message
+		}
+		for _, group := range names {
+			if success, message := db.(ports.GroupStore).CreateGroup(group); !success && !alreadyExists(message) {
+				return false, message
+			}
+		}
+	}
+
+	s.shards[name] = db
+	s.names = append(s.names, name)
+	sort.Strings(s.names)
+	s.buildRing()
+	s.rebalancing = true
+	return true, ""
+}
+
+// Shards returns the names of the shards.
+func (s *ShardedDatabase) Shards() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string{}, s.names...)
+}
+
+// RebalanceReport counts the records and blobs a rebalance moved to each shard.
+type RebalanceReport struct {
+	Moved map[string]int
+	Blobs map[string]int
+}
+
+// Rebalance moves every record that is not on the shard it hashes to. Reads
+// and writes keep working while it runs; it stops between records when ctx
+// is cancelled and can be run again.
+func (s *ShardedDatabase) Rebalance(ctx context.Context) (bool, string, RebalanceReport) {
+	report := RebalanceReport{Moved: make(map[string]int), Blobs: make(map[string]int)}
+	s.mu.Lock()
+	s.rebalancing = true
+	names := append([]string{}, s.names...)
+	s.mu.Unlock()
+
+	for _, name := range names {
+		shard := s.shard(name)
+		success, message, locations := shard.List("")
+		if !success {
+			return false, message, report
+		}
+		for _, location := range locations {
+			if strings.HasPrefix(location, shardingPrefix) {
+				continue
+			}
+			if ctx.Err() != nil {
+				return false, ctx.Err().Error(), report
+			}
+			owner := s.owner(location)
+			if owner == name {
+				continue
+			}
+			unlock := s.lock(location)
+			success, message := s.move(location, shard, s.shard(owner))
+			unlock()
+			if !success {
+				return false, fmt.Sprintf("Error moving %s to shard %s: %s", location, owner, message), report
+			}
+			report.Moved[owner]++
+		}
+
+		store, ok := shard.(ports.BlobStore)
+		if !ok {
+			continue
+		}
+		success, message, hashes := store.ListBlobs()
+		if !success {
+			return false, message, report
+		}
+		for _, hash := range hashes {
+			if ctx.Err() != nil {
+				return false, ctx.Err().Error(), report
+			}
+			owner := s.owner(hash)
+			if owner == name {
+				continue
+			}
+			unlock := s.lock(hash)
+			success, message := moveBlob(hash, store, s.shard(owner))
+			unlock()
+			if !success {
+				return false, fmt.Sprintf("Error moving blob %s to shard %s: %s", hash, owner, message), report
+			}
+			report.Blobs[owner]++
+		}
+	}
+
+	ring := map[string]interface{}{"shards": strings.Join(names, ",")}
+	for _, name := range names {
+		if success, message := putRecord(s.shard(name), ringMarkerLocation, ring); !success {
+			return false, message, report
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Shards added meanwhile still need their records
+	s.rebalancing = len(s.names) != len(names)
+	return true, "", report
+}
+
+func (s *ShardedDatabase) Create(location string, data map[string]interface{}) (bool, string) {
+	unlock := s.lock(location)
+	defer unlock()
+
+	if _, found := s.find(location); found != nil && found != s.shard(s.owner(location)) {
+		return false, "Location already exists"
+	}
+	return s.shard(s.owner(location)).Create(location, data)
+}
+
+func (s *ShardedDatabase) Read(location string) (bool, string, map[string]interface{}) {
+	owner := s.shard(s.owner(location))
+	success, message, data := owner.Read(location)
+	if success || !missing(message) || !s.isRebalancing() {
+		return success, message, data
+	}
+	if _, found := s.find(location); found != nil {
+		return found.Read(location)
+	}
+	return success, message, data
+}
+
+func (s *ShardedDatabase) Update(location string, data map[string]interface{}) (bool, string) {
+	unlock := s.lock(location)
+	defer unlock()
+
+	owner := s.shard(s.owner(location))
+	success, message := owner.Update(location, data)
+	if success || !missing(message) || !s.isRebalancing() {
+		return success, message
+	}
+	_, found := s.find(location)
+	if found == nil {
+		return success, message
+	}
+	// Move the record while updating it
+	if success, message := s.move(location, found, owner); !success {
+		return false, message
+	}
+	return owner.Update(location, data)
+}
+
+func (s *ShardedDatabase) Delete(location string) (bool, string) {
+	unlock := s.lock(location)
+	defer unlock()
+
+	success, message := s.shard(s.owner(location)).Delete(location)
+	if success || !missing(message) || !s.isRebalancing() {
+		return success, message
+	}
+	if _, found := s.find(location); found != nil {
+		return found.Delete(location)
+	}
+	return success, message
+}
+
+func (s *ShardedDatabase) Upsert(location string, data map[string]interface{}) (bool, string, bool) {
+	return s.writeConditional(location, data, upsertRecord)
+}
+
+func (s *ShardedDatabase) CreateIfAbsent(location string, data map[string]interface{}) (bool, string, bool) {
+	return s.writeConditional(location, data, createRecordIfAbsent)
+}
+
+func (s *ShardedDatabase) UpdateIfExists(location string, data map[string]interface{}) (bool, string, bool) {
+	return s.writeConditional(location, data, updateRecordIfExists)
+}
+
+func (s *ShardedDatabase) List(prefix string) (bool, string, []string) {
+	success, message, lists := s.fanOut(func(db ports.Database) (bool, string, []string) {
+		return db.List(prefix)
+	})
+	if !success {
+		return false, message, nil
+	}
+	return true, "", mergeSorted(lists, func(location string) bool {
+		return !strings.HasPrefix(location, shardingPrefix)
+	})
+}
+
+func (s *ShardedDatabase) ReadFields(location string, fields []string) (bool, string, map[string]interface{}) {
+	return readFields(s.holder(location), location, fields)
+}
+
+// ListFields reads the fields from every shard concurrently.
+func (s *ShardedDatabase) ListFields(prefix string, fields []string) (bool, string, map[string]map[string]interface{}) {
+	var mu sync.Mutex
+	records := make(map[string]map[string]interface{})
+	success, message, _ := s.fanOut(func(db ports.Database) (bool, string, []string) {
+		success, message, shardRecords := listFields(db, prefix, fields)
+		if !success {
+			return false, message, nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for location, data := range shardRecords {
+			if !strings.HasPrefix(location, shardingPrefix) {
+				records[location] = data
+			}
+		}
+		return true, "", nil
+	})
+	if !success {
+		return false, message, nil
+	}
+	return true, "", records
+}
+
+// CreateGroup and the other group writes apply to every shard, except
+// memberships, which go to the shard of their contact.
+func (s *ShardedDatabase) CreateGroup(name string) (bool, string) {
+	return s.everyGroupStore(func(groups ports.GroupStore) (bool, string) {
+		return groups.CreateGroup(name)
+	})
+}
+
+func (s *ShardedDatabase) RenameGroup(name, newName string) (bool, string) {
+	return s.everyGroupStore(func(groups ports.GroupStore) (bool, string) {
+		return groups.RenameGroup(name, newName)
+	})
+}
+
+func (s *ShardedDatabase) DeleteGroup(name string) (bool, string) {
+	return s.everyGroupStore(func(groups ports.GroupStore) (bool, string) {
+		return groups.DeleteGroup(name)
+	})
+}
+
+func (s *ShardedDatabase) ListGroups() (bool, string, []string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.listGroups()
+}
+
+func (s *ShardedDatabase) AddMember(group, location string) (bool, string) {
+	groups, ok := s.holder(location).(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+	return groups.AddMember(group, location)
+}
+
+func (s *ShardedDatabase) RemoveMember(group, location string) (bool, string) {
+	groups, ok := s.holder(location).(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+	return groups.RemoveMember(group, location)
+}
+
+func (s *ShardedDatabase) Members(group string) (bool, string, []string) {
+	success, message, lists := s.fanOut(func(db ports.Database) (bool, string, []string) {
+		groups, ok := db.(ports.GroupStore)
+		if !ok {
+			return false, domain.ErrGroupsNotSupported.Error(), nil
+		}
+		return groups.Members(group)
+	})
+	if !success {
+		return false, message, nil
+	}
+	return true, "", mergeSorted(lists, nil)
+}
+
+func (s *ShardedDatabase) GroupsOf(location string) (bool, string, []string) {
+	groups, ok := s.holder(location).(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error(), nil
+	}
+	return groups.GroupsOf(location)
+}
+
+// BatchCreate and the other batches send each shard its part of the batch.
+// While rebalancing they write item by item, so records are found wherever
+// they are.
+// BatchRead reads from each shard in one batch. While rebalancing, records
+// may be on either side of a move, so they are read one at a time.
+func (s *ShardedDatabase) BatchRead(locations []string) (bool, string, map[string]map[string]interface{}) {
+	records := make(map[string]map[string]interface{}, len(locations))
+	if s.isRebalancing() {
+		for _, location := range locations {
+			success, message, data := s.Read(location)
+			if !success {
+				if missing(message) {
+					continue
+				}
+				return false, message, nil
+			}
+			records[location] = data
+		}
+		return true, "", records
+	}
+
+	byShard := make(map[string][]string)
+	for _, location := range locations {
+		owner := s.owner(location)
+		byShard[owner] = append(byShard[owner], location)
+	}
+	for name, part := range byShard {
+		success, message, shardRecords := readRecords(s.shard(name), part)
+		if !success {
+			return false, message, nil
+		}
+		for location, data := range shardRecords {
+			records[location] = data
+		}
+	}
+	return true, "", records
+}
+
+func (s *ShardedDatabase) BatchCreate(items []ports.BatchItem) []ports.BatchResult {
+	return s.batch(items, func(db ports.BatchDatabase, items []ports.BatchItem) []ports.BatchResult {
+		return db.BatchCreate(items)
+	}, s.Create)
+}
+
+func (s *ShardedDatabase) BatchUpsert(items []ports.BatchItem) []ports.BatchResult {
+	return s.batch(items, func(db ports.BatchDatabase, items []ports.BatchItem) []ports.BatchResult {
+		return db.BatchUpsert(items)
+	}, func(location string, data map[string]interface{}) (bool, string) {
+		success, message, _ := s.Upsert(location, data)
+		return success, message
+	})
+}
+
+func (s *ShardedDatabase) BatchDelete(locations []string) []ports.BatchResult {
+	items := make([]ports.BatchItem, len(locations))
+	for i, location := range locations {
+		items[i] = ports.BatchItem{Location: location}
+	}
+	return s.batch(items, func(db ports.BatchDatabase, items []ports.BatchItem) []ports.BatchResult {
+		locations := make([]string, len(items))
+		for i, item := range items {
+			locations[i] = item.Location
+		}
+		return db.BatchDelete(locations)
+	}, func(location string, _ map[string]interface{}) (bool, string) {
+		return s.Delete(location)
+	})
+}
+
+// IndexedField reports whether every shard indexes field.
+func (s *ShardedDatabase) IndexedField(field string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, name := range s.names {
+		index, ok := s.shards[name].(ports.FieldIndex)
+		if !ok || !index.IndexedField(field) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *ShardedDatabase) LookupField(field, value string) (bool, string, []string) {
+	success, message, lists := s.fanOut(func(db ports.Database) (bool, string, []string) {
+		index, ok := db.(ports.FieldIndex)
+		if !ok {
+			return false, fmt.Sprintf("Field %s is not indexed", field), nil
+		}
+		return index.LookupField(field, value)
+	})
+	if !success {
+		return false, message, nil
+	}
+	return true, "", mergeSorted(lists, nil)
+}
+
+func (s *ShardedDatabase) PutBlob(hash string, content []byte) (bool, string) {
+	unlock := s.lock(hash)
+	defer unlock()
+
+	store, ok := s.shard(s.owner(hash)).(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error()
+	}
+	return store.PutBlob(hash, content)
+}
+
+func (s *ShardedDatabase) GetBlob(hash string) (bool, string, []byte) {
+	store, ok := s.shard(s.owner(hash)).(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error(), nil
+	}
+	success, message, content := store.GetBlob(hash)
+	if success || !missing(message) || !s.isRebalancing() {
+		return success, message, content
+	}
+	for _, shard := range s.shardList() {
+		if other, ok := shard.(ports.BlobStore); ok {
+			if found, _, content := other.GetBlob(hash); found {
+				return true, "", content
+			}
+		}
+	}
+	return success, message, content
+}
+
+// DeleteBlob deletes the blob from its shard and, while records move, from
+// any other shard still holding it.
+func (s *ShardedDatabase) DeleteBlob(hash string) (bool, string) {
+	unlock := s.lock(hash)
+	defer unlock()
+
+	store, ok := s.shard(s.owner(hash)).(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error()
+	}
+	success, message := store.DeleteBlob(hash)
+	if (!success && !missing(message)) || !s.isRebalancing() {
+		return success, message
+	}
+	for _, shard := range s.shardList() {
+		other, ok := shard.(ports.BlobStore)
+		if !ok || other == store {
+			continue
+		}
+		deleted, otherMessage := other.DeleteBlob(hash)
+		if !deleted && !missing(otherMessage) {
+			return false, otherMessage
+		}
+		success = success || deleted
+	}
+	if !success {
+		return false, message
+	}
+	return true, ""
+}
+
+func (s *ShardedDatabase) ListBlobs() (bool, string, []string) {
+	success, message, lists := s.fanOut(func(db ports.Database) (bool, string, []string) {
+		store, ok := db.(ports.BlobStore)
+		if !ok {
+			return false, domain.ErrBlobsNotSupported.Error(), nil
+		}
+		return store.ListBlobs()
+	})
+	if !success {
+		return false, message, nil
+	}
+	return true, "", mergeSorted(lists, nil)
+}
+
+// writeConditional applies one of the conditional writes to the owning
+// shard, first moving the record there if a rebalance has not yet.
+func (s *ShardedDatabase) writeConditional(location string, data map[string]interface{}, write func(ports.Database, string, map[string]interface{}) (bool, string, bool)) (bool, string, bool) {
+	unlock := s.lock(location)
+	defer unlock()
+
+	owner := s.shard(s.owner(location))
+	if s.isRebalancing() {
+		if _, found := s.find(location); found != nil && found != owner {
+			if success, message := s.move(location, found, owner); !success {
+				return false, message, false
+			}
+		}
+	}
+	return write(owner, location, data)
+}
+
+// move copies a record and its memberships to another shard, then deletes
+// it from the shard it was on.
+func (s *ShardedDatabase) move(location string, from, to ports.Database) (bool, string) {
+	success, message, data := from.Read(location)
+	if !success {
+		if missing(message) {
+			return true, ""
+		}
+		return false, message
+	}
+	if success, message := putRecord(to, location, data); !success {
+		return false, message
+	}
+
+	if groups, ok := from.(ports.GroupStore); ok {
+		success, message, names := groups.GroupsOf(location)
+		if !success {
+			return false, message
+		}
+		target, ok := to.(ports.GroupStore)
+		if len(names) > 0 && !ok {
+			return false, domain.ErrGroupsNotSupported.Error()
+		}
+		for _, name := range names {
+			if success, message := target.AddMember(name, location); !success {
+				return false, message
+			}
+		}
+	}
+	return deleteRecord(from, location)
+}
+
+// moveBlob copies a blob to the shard it belongs on and deletes it from the
+// one holding it.
+func moveBlob(hash string, from ports.BlobStore, to ports.Database) (bool, string) {
+	target, ok := to.(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error()
+	}
+	success, message, content := from.GetBlob(hash)
+	if !success {
+		if missing(message) {
+			return true, ""
+		}
+		return false, message
+	}
+	if success, message := target.PutBlob(hash, content); !success {
+		return false, message
+	}
+	if success, message := from.DeleteBlob(hash); !success && !missing(message) {
+		return false, message
+	}
+	return true, ""
+}
+
+// find returns the shard holding a record, or nil.
+func (s *ShardedDatabase) find(location string) (string, ports.Database) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	owner := s.ownerLocked(location)
+	if success, _, _ := s.shards[owner].Read(location); success {
+		return owner, s.shards[owner]
+	}
+	if !s.rebalancing {
+		return "", nil
+	}
+	for _, name := range s.names {
+		if name == owner {
+			continue
+		}
+		if success, _, _ := s.shards[name].Read(location); success {
+			return name, s.shards[name]
+		}
+	}
+	return "", nil
+}
+
+// holder returns the shard holding a record, falling back to its owner.
+func (s *ShardedDatabase) holder(location string) ports.Database {
+	if _, found := s.find(location); found != nil {
+		return found
+	}
+	return s.shard(s.owner(location))
+}
+
+func (s *ShardedDatabase) owner(location string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ownerLocked(location)
+}
+
+func (s *ShardedDatabase) ownerLocked(location string) string {
+	hash := hashKey(location)
+	i := sort.Search(len(s.ring), func(i int) bool { return s.ring[i].hash >= hash })
+	if i == len(s.ring) {
+		i = 0
+	}
+	return s.ring[i].shard
+}
+
+func (s *ShardedDatabase) shard(name string) ports.Database {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.shards[name]
+}
+
+func (s *ShardedDatabase) isRebalancing() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rebalancing
+}
+
+func (s *ShardedDatabase) buildRing() {
+	s.ring = s.ring[:0]
+	for _, name := range s.names {
+		for i := 0; i < s.virtualNodes; i++ {
+			s.ring = append(s.ring, ringPoint{hash: hashKey(name + "#" + strconv.Itoa(i)), shard: name})
+		}
+	}
+	sort.Slice(s.ring, func(i, j int) bool { return s.ring[i].hash < s.ring[j].hash })
+}
+
+func (s *ShardedDatabase) lock(location string) func() {
+	l := &s.locks[hashKey(location)%uint64(len(s.locks))]
+	l.Lock()
+	return l.Unlock
+}
+
+// shardList returns the shards in name order.
+func (s *ShardedDatabase) shardList() []ports.Database {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	shards := make([]ports.Database, len(s.names))
+	for i, name := range s.names {
+		shards[i] = s.shards[name]
+	}
+	return shards
+}
+
+// fanOut runs fn on every shard concurrently.
+func (s *ShardedDatabase) fanOut(fn func(ports.Database) (bool, string, []string)) (bool, string, [][]string) {
+	shards := s.shardList()
+
+	type result struct {
+		success bool
+		message string
+		list    []string
+	}
+	results := make([]result, len(shards))
+	var wg sync.WaitGroup
+	for i, shard := range shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			success, message, list := fn(shard)
+			results[i] = result{success, message, list}
+		}()
+	}
+	wg.Wait()
+
+	lists := make([][]string, len(results))
+	for i, result := range results {
+		if !result.success {
+			return false, result.message, nil
+		}
+		lists[i] = result.list
+	}
+	return true, "", lists
+}
+
+func (s *ShardedDatabase) everyGroupStore(fn func(ports.GroupStore) (bool, string)) (bool, string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, name := range s.names {
+		groups, ok := s.shards[name].(ports.GroupStore)
+		if !ok {
+			return false, domain.ErrGroupsNotSupported.Error()
+		}
+		if success, message := fn(groups); !success {
+			return false, message
+		}
+	}
+	return true, ""
+}
+
+func (s *ShardedDatabase) listGroups() (bool, string, []string) {
+	groups, ok := s.shards[s.names[0]].(ports.GroupStore)
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error(), nil
+	}
+	return groups.ListGroups()
+}
+
+func (s *ShardedDatabase) batch(items []ports.BatchItem, write func(ports.BatchDatabase, []ports.BatchItem) []ports.BatchResult, single func(string, map[string]interface{}) (bool, string)) []ports.BatchResult {
+	results := make([]ports.BatchResult, len(items))
+	byShard := make(map[string][]int)
+	if !s.isRebalancing() {
+		for i, item := range items {
+			owner := s.owner(item.Location)
+			byShard[owner] = append(byShard[owner], i)
+		}
+	}
+
+	for name, indexes := range byShard {
+		batch, ok := s.shard(name).(ports.BatchDatabase)
+		if !ok {
+			continue
+		}
+		part := make([]ports.BatchItem, len(indexes))
+		for j, i := range indexes {
+			part[j] = items[i]
+		}
+		for j, result := range write(batch, part) {
+			results[indexes[j]] = result
+		}
+		delete(byShard, name)
+	}
+
+	for i, item := range items {
+		if results[i].Location != "" {
+			continue
+		}
+		success, message := single(item.Location, item.Data)
+		results[i] = ports.BatchResult{Location: item.Location, Success: success, Message: message}
+	}
+	return results
+}
+
+// mergeSorted merges sorted lists, dropping duplicates and, when keep is
+// set, the entries it rejects.
+func mergeSorted(lists [][]string, keep func(string) bool) []string {
+	seen := make(map[string]bool)
+	merged := make([]string, 0)
+	for _, list := range lists {
+		for _, entry := range list {
+			if seen[entry] || (keep != nil && !keep(entry)) {
+				continue
+			}
+			seen[entry] = true
+			merged = append(merged, entry)
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+func hashKey(key string) uint64 {
+	sum := sha256.Sum256([]byte(key))
+	return binary.BigEndian.Uint64(sum[:8])
+}
@@ -0,0 +1,215 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func newShards(names ...string) map[string]ports.Database {
+	shards := make(map[string]ports.Database)
+	for _, name := range names {
+		shards[name] = NewInMemoryDatabase()
+	}
+	return shards
+}
+
+func TestShardedDatabase_Placement(t *testing.T) {
+	shards := newShards("a", "b", "c")
+	db, err := NewShardedDatabase(shards)
+	require.NoError(t, err)
+
+	var expected []string
+	for i := 0; i < 60; i++ {
+		location := fmt.Sprintf("contacts/%02d", i)
+		require.True(t, mustSucceed(db.Create(location, map[string]interface{}{"name": location})))
+		expected = append(expected, location)
+	}
+
+	for name, shard := range shards {
+		_, _, locations := shard.List("")
+		assert.NotEmpty(t, locations, "shard %s holds no records", name)
+		for _, location := range locations {
+			assert.Equal(t, name, db.owner(location))
+		}
+	}
+
+	_, _, locations := db.List("contacts/")
+	assert.Equal(t, expected, locations)
+	_, _, data := db.Read("contacts/42")
+	assert.Equal(t, "contacts/42", data["name"])
+
+	success, message := db.Create("contacts/42", map[string]interface{}{"name": "Again"})
+	assert.False(t, success)
+	assert.Equal(t, "Location already exists", message)
+
+	require.True(t, mustSucceed(db.Delete("contacts/42")))
+	success, _, _ = db.Read("contacts/42")
+	assert.False(t, success)
+}
+
+func TestShardedDatabase_Groups(t *testing.T) {
+	db, err := NewShardedDatabase(newShards("a", "b"))
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		require.True(t, mustSucceed(db.Create(fmt.Sprintf("contacts/%d", i), map[string]interface{}{})))
+	}
+
+	require.True(t, mustSucceed(db.CreateGroup("family")))
+	for i := 0; i < 10; i += 3 {
+		require.True(t, mustSucceed(db.AddMember("family", fmt.Sprintf("contacts/%d", i))))
+	}
+	require.True(t, mustSucceed(db.RenameGroup("family", "relatives")))
+
+	_, _, names := db.ListGroups()
+	assert.Equal(t, []string{"relatives"}, names)
+	_, _, members := db.Members("relatives")
+	assert.Equal(t, []string{"contacts/0", "contacts/3", "contacts/6", "contacts/9"}, members)
+	_, _, groups := db.GroupsOf("contacts/3")
+	assert.Equal(t, []string{"relatives"}, groups)
+}
+
+func TestShardedDatabase_Batch(t *testing.T) {
+	db, err := NewShardedDatabase(newShards("a", "b", "c"))
+	require.NoError(t, err)
+
+	var items []ports.BatchItem
+	for i := 0; i < 20; i++ {
+		items = append(items, ports.BatchItem{Location: fmt.Sprintf("contacts/%02d", i), Data: map[string]interface{}{"n": i}})
+	}
+	for i, result := range db.BatchCreate(items) {
+		assert.Equal(t, items[i].Location, result.Location)
+		assert.True(t, result.Success, result.Message)
+	}
+
+	success, message, records := db.BatchRead([]string{"contacts/03", "contacts/17", "contacts/missing"})
+	require.True(t, success, message)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"contacts/03": {"n": 3},
+		"contacts/17": {"n": 17},
+	}, records)
+
+	results := db.BatchDelete([]string{"contacts/03", "contacts/missing"})
+	assert.True(t, results[0].Success)
+	assert.False(t, results[1].Success)
+	_, _, locations := db.List("")
+	assert.Len(t, locations, 19)
+}
+
+func TestShardedDatabase_Rebalance(t *testing.T) {
+	shards := newShards("a", "b")
+	db, err := NewShardedDatabase(shards)
+	require.NoError(t, err)
+	success, message, _ := db.Rebalance(context.Background())
+	require.True(t, success, message)
+
+	require.True(t, mustSucceed(db.CreateGroup("family")))
+	for i := 0; i < 40; i++ {
+		location := fmt.Sprintf("contacts/%02d", i)
+		require.True(t, mustSucceed(db.Create(location, map[string]interface{}{"n": i})))
+		require.True(t, mustSucceed(db.AddMember("family", location)))
+	}
+
+	added := NewInMemoryDatabase()
+	require.True(t, mustSucceed(db.AddShard("c", added)))
+	success, _ = db.AddShard("c", added)
+	assert.False(t, success)
+
+	// Records stay reachable before they move
+	var moving []string
+	for i := 0; i < 40; i++ {
+		location := fmt.Sprintf("contacts/%02d", i)
+		if db.owner(location) == "c" {
+			moving = append(moving, location)
+		}
+	}
+	require.NotEmpty(t, moving)
+	_, _, data := db.Read(moving[0])
+	assert.NotNil(t, data)
+	success, _ = db.Create(moving[0], map[string]interface{}{})
+	assert.False(t, success)
+	require.True(t, mustSucceed(db.Update(moving[0], map[string]interface{}{"n": "updated"})))
+	_, _, data = added.Read(moving[0])
+	assert.Equal(t, "updated", data["n"], "an update moves the record")
+
+	success, message, report := db.Rebalance(context.Background())
+	require.True(t, success, message)
+	assert.Equal(t, map[string]int{"c": len(moving) - 1}, report.Moved)
+
+	for name, shard := range map[string]ports.Database{"a": shards["a"], "b": shards["b"], "c": added} {
+		_, _, locations := shard.List("contacts/")
+		for _, location := range locations {
+			assert.Equal(t, name, db.owner(location))
+		}
+	}
+	_, _, locations := db.List("")
+	assert.Len(t, locations, 40)
+	_, _, members := db.Members("family")
+	assert.Len(t, members, 40)
+	_, _, groups := added.GroupsOf(moving[1])
+	assert.Equal(t, []string{"family"}, groups)
+
+	// A router opened over the balanced shards needs no rebalance
+	reopened, err := NewShardedDatabase(map[string]ports.Database{"a": shards["a"], "b": shards["b"], "c": added})
+	require.NoError(t, err)
+	assert.False(t, reopened.isRebalancing())
+	reopened, err = NewShardedDatabase(map[string]ports.Database{"a": shards["a"], "b": shards["b"]})
+	require.NoError(t, err)
+	assert.True(t, reopened.isRebalancing())
+}
+
+func TestNewShardedDatabase_NoShards(t *testing.T) {
+	_, err := NewShardedDatabase(nil)
+	assert.Error(t, err)
+}
+
+func TestShardedDatabase_Blobs(t *testing.T) {
+	db, err := NewShardedDatabase(newShards("a", "b", "c"))
+	require.NoError(t, err)
+	testBlobStore(t, db)
+
+	shards := newShards("a", "b")
+	db, err = NewShardedDatabase(shards)
+	require.NoError(t, err)
+	success, message, _ := db.Rebalance(context.Background())
+	require.True(t, success, message)
+
+	var hashes []string
+	for i := 0; i < 20; i++ {
+		content := []byte(fmt.Sprintf("photo %d", i))
+		require.True(t, mustSucceed(db.PutBlob(blobHash(content), content)))
+		hashes = append(hashes, blobHash(content))
+	}
+	for name, shard := range shards {
+		_, _, stored := shard.(ports.BlobStore).ListBlobs()
+		for _, hash := range stored {
+			assert.Equal(t, name, db.owner(hash))
+		}
+	}
+
+	// Blobs stay reachable until a rebalance moves them
+	added := NewInMemoryDatabase()
+	require.True(t, mustSucceed(db.AddShard("c", added)))
+	moving := 0
+	for _, hash := range hashes {
+		success, message, _ := db.GetBlob(hash)
+		assert.True(t, success, message)
+		if db.owner(hash) == "c" {
+			moving++
+		}
+	}
+	require.NotZero(t, moving)
+
+	success, message, report := db.Rebalance(context.Background())
+	require.True(t, success, message)
+	assert.Equal(t, map[string]int{"c": moving}, report.Blobs)
+	_, _, stored := added.ListBlobs()
+	assert.Len(t, stored, moving)
+	_, _, listed := db.ListBlobs()
+	assert.Len(t, listed, len(hashes))
+}
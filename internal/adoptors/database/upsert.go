@@ -0,0 +1,55 @@
+package database
+
+import (
+	"strings"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// upsertRecord creates or replaces a record, atomically when db supports it.
+// The third result reports whether the record was created.
+func upsertRecord(db ports.Database, location string, data map[string]interface{}) (bool, string, bool) {
+	if upserter, ok := db.(ports.UpsertDatabase); ok {
+		return upserter.Upsert(location, data)
+	}
+	for {
+		success, message := db.Update(location, data)
+		if success || !missing(message) {
+			return success, message, false
+		}
+		success, message = db.Create(location, data)
+		if success || !alreadyExists(message) {
+			return success, message, success
+		}
+		// Created in between, replace it instead
+	}
+}
+
+// createRecordIfAbsent creates a record unless it exists.
+func createRecordIfAbsent(db ports.Database, location string, data map[string]interface{}) (bool, string, bool) {
+	if upserter, ok := db.(ports.UpsertDatabase); ok {
+		return upserter.CreateIfAbsent(location, data)
+	}
+	success, message := db.Create(location, data)
+	if !success && alreadyExists(message) {
+		return true, "", false
+	}
+	return success, message, success
+}
+
+// updateRecordIfExists replaces a record if it exists.
+func updateRecordIfExists(db ports.Database, location string, data map[string]interface{}) (bool, string, bool) {
+	if upserter, ok := db.(ports.UpsertDatabase); ok {
+		return upserter.UpdateIfExists(location, data)
+	}
+	success, message := db.Update(location, data)
+	if !success && missing(message) {
+		return true, "", false
+	}
+	return success, message, success
+}
+
+// alreadyExists reports whether a failed create found the record already there.
+func alreadyExists(message string) bool {
+	return strings.HasSuffix(message, "already exists")
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+type upsertTestDatabase interface {
+	ports.Database
+	ports.UpsertDatabase
+}
+
+// testUpsertDatabase checks the conditional writes of db, which starts empty.
+func testUpsertDatabase(t *testing.T, db upsertTestDatabase) {
+	tests := []struct {
+		name            string
+		write           func(string, map[string]interface{}) (bool, string, bool)
+		location        string
+		data            map[string]interface{}
+		expectedWritten bool
+		expectedData    map[string]interface{}
+	}{
+		{"upsert creates", db.Upsert, "contacts/john", map[string]interface{}{"name": "John"}, true, map[string]interface{}{"name": "John"}},
+		{"upsert replaces", db.Upsert, "contacts/john", map[string]interface{}{"name": "Johnny"}, false, map[string]interface{}{"name": "Johnny"}},
+		{"create if absent creates", db.CreateIfAbsent, "contacts/jane", map[string]interface{}{"name": "Jane"}, true, map[string]interface{}{"name": "Jane"}},
+		{"create if absent keeps existing", db.CreateIfAbsent, "contacts/jane", map[string]interface{}{"name": "Clobbered"}, false, map[string]interface{}{"name": "Jane"}},
+		{"update if exists replaces", db.UpdateIfExists, "contacts/jane", map[string]interface{}{"name": "Janet"}, true, map[string]interface{}{"name": "Janet"}},
+		{"update if exists skips missing", db.UpdateIfExists, "contacts/nobody", map[string]interface{}{"name": "Nobody"}, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			success, message, written := tt.write(tt.location, tt.data)
+			require.True(t, success, message)
+			assert.Equal(t, tt.expectedWritten, written)
+			_, _, data := db.Read(tt.location)
+			assert.Equal(t, tt.expectedData, data)
+		})
+	}
+}
+
+func TestInMemoryDatabase_Upsert(t *testing.T) {
+	testUpsertDatabase(t, NewInMemoryDatabase())
+}
+
+func TestFileSystemDatabase_Upsert(t *testing.T) {
+	db := NewFileSystemDatabase(t.TempDir())
+	testUpsertDatabase(t, db)
+
+	success, message, _ := db.Upsert("../outside", map[string]interface{}{})
+	assert.False(t, success)
+	assert.Equal(t, "Invalid location", message)
+}
+
+func TestNamespacedDatabase_Upsert(t *testing.T) {
+	inner := NewInMemoryDatabase()
+	testUpsertDatabase(t, NewNamespacedDatabase(inner, "tenants/a/"))
+	_, _, locations := inner.List("")
+	assert.Equal(t, []string{"tenants/a/contacts/jane", "tenants/a/contacts/john"}, locations)
+}
+
+// plainDatabase hides the optional interfaces of the database it wraps.
+type plainDatabase struct {
+	ports.Database
+}
+
+func TestNamespacedDatabase_UpsertWithoutNativeSupport(t *testing.T) {
+	testUpsertDatabase(t, NewNamespacedDatabase(plainDatabase{NewInMemoryDatabase()}, ""))
+}
+
+func TestMirroredDatabase_Upsert(t *testing.T) {
+	secondary := NewInMemoryDatabase()
+	db := NewMirroredDatabase(NewInMemoryDatabase(), []ports.Database{secondary}, WithConsistency(MirrorStrict))
+	testUpsertDatabase(t, db)
+
+	_, _, data := secondary.Read("contacts/jane")
+	assert.Equal(t, map[string]interface{}{"name": "Janet"}, data)
+	success, _, _ := secondary.Read("contacts/nobody")
+	assert.False(t, success)
+}
+
+func TestShardedDatabase_Upsert(t *testing.T) {
+	db, err := NewShardedDatabase(newShards("a", "b"))
+	require.NoError(t, err)
+	testUpsertDatabase(t, db)
+}
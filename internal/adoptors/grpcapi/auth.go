@@ -0,0 +1,74 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Businge931/practice-interfaces/internal/application"
+)
+
+// Metadata keys carrying credentials. The authorization key takes a JWT or
+// an API key as "Bearer <credential>"; the API key header takes a bare key.
+const (
+	authorizationKey = "authorization"
+	apiKeyKey        = "x-api-key"
+)
+
+// WithAuth returns server options that authenticate every call and put the
+// caller on its context. Calls without credentials go through anonymously
+// and are refused by a service with authorization enabled.
+func WithAuth(auth *application.AuthService) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			ctx, err := authenticate(ctx, auth)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := authenticate(stream.Context(), auth)
+			if err != nil {
+				return err
+			}
+			return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+		}),
+	}
+}
+
+func authenticate(ctx context.Context, auth *application.AuthService) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	credential := ""
+	if values := md.Get(authorizationKey); len(values) > 0 {
+		scheme, value, ok := strings.Cut(values[0], " ")
+		if !ok || !strings.EqualFold(scheme, "bearer") {
+			return nil, status.Error(codes.Unauthenticated, "authorization must use the Bearer scheme")
+		}
+		credential = strings.TrimSpace(value)
+	} else if values := md.Get(apiKeyKey); len(values) > 0 {
+		credential = values[0]
+	}
+	if credential == "" {
+		return ctx, nil
+	}
+
+	success, message, actor := auth.Authenticate(credential)
+	if !success {
+		return nil, status.Error(codes.Unauthenticated, message)
+	}
+	return application.ContextWithActor(ctx, actor), nil
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,83 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	phonebookv1 "github.com/Businge931/practice-interfaces/api/phonebook/v1"
+	"github.com/Businge931/practice-interfaces/internal/adoptors/database"
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+func TestServer_Auth(t *testing.T) {
+	auth := application.NewAuthService(database.NewInMemoryDatabase())
+	auth.CreateUser(domain.User{Name: "alice", Role: domain.RoleEditor})
+	auth.CreateUser(domain.User{Name: "bob", Role: domain.RoleViewer})
+	_, _, aliceKey, _ := auth.CreateAPIKey("alice", "")
+	_, _, bobToken := auth.IssueToken("bob", time.Hour)
+
+	phonebook := application.NewPhonebookService(database.NewInMemoryDatabase(), application.WithAuthorization())
+	client := serveGRPCTest(t, NewGRPCServer(phonebook, nil, WithAuth(auth)...))
+
+	tests := []struct {
+		name     string
+		md       metadata.MD
+		id       string
+		expected codes.Code
+	}{
+		{"anonymous", nil, "contacts/anon", codes.Unauthenticated},
+		{"bad credential", metadata.Pairs(authorizationKey, "Bearer nope"), "contacts/bad", codes.Unauthenticated},
+		{"bad scheme", metadata.Pairs(authorizationKey, "Basic abc"), "contacts/basic", codes.Unauthenticated},
+		{"viewer", metadata.Pairs(authorizationKey, "Bearer "+bobToken), "contacts/bob", codes.PermissionDenied},
+		{"editor key header", metadata.Pairs(apiKeyKey, aliceKey), "contacts/john", codes.OK},
+		{"editor bearer key", metadata.Pairs(authorizationKey, "bearer "+aliceKey), "contacts/jane", codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewOutgoingContext(context.Background(), tt.md)
+			_, err := client.AddContact(ctx, &phonebookv1.AddContactRequest{Id: tt.id, Contact: testContact})
+			if status.Code(err) != tt.expected {
+				t.Errorf("Expected %v but got %v", tt.expected, err)
+			}
+		})
+	}
+
+	// Streams carry the caller too
+	viewer := metadata.NewOutgoingContext(context.Background(), metadata.Pairs(authorizationKey, "Bearer "+bobToken))
+	stream, err := client.ListContacts(viewer, &phonebookv1.ListContactsRequest{})
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+	count := 0
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Expected the viewer to list contacts but got %v", err)
+		}
+		count++
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 contacts but got %d", count)
+	}
+
+	// Deletions are recorded against the authenticated caller
+	editor := metadata.NewOutgoingContext(context.Background(), metadata.Pairs(apiKeyKey, aliceKey))
+	if _, err := client.DeleteContact(editor, &phonebookv1.DeleteContactRequest{Id: "contacts/john", Actor: "mallory"}); err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	admin := application.ContextWithActor(context.Background(), domain.Actor{Name: "root", Role: domain.RoleAdmin})
+	if _, _, trashed := phonebook.As(admin).ListTrash(""); len(trashed) != 1 || trashed[0].DeletedBy != "alice" {
+		t.Errorf("Expected the deletion to be recorded against alice but got %+v", trashed)
+	}
+}
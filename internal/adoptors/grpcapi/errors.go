@@ -0,0 +1,56 @@
+package grpcapi
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+// statusCodes maps the messages returned by the service and the database
+// adapters to gRPC codes. Anything else is reported as Internal.
+var statusCodes = map[string]codes.Code{
+	domain.ErrContactNotFound.Error():      codes.NotFound,
+	"Location does not exist":              codes.NotFound,
+	"File does not exist":                  codes.NotFound,
+	"Group does not exist":                 codes.NotFound,
+	domain.ErrContactExists.Error():        codes.AlreadyExists,
+	domain.ErrContactInTrash.Error():       codes.AlreadyExists,
+	domain.ErrContactMerged.Error():        codes.FailedPrecondition,
+	"Location already exists":              codes.AlreadyExists,
+	"File already exists":                  codes.AlreadyExists,
+	domain.ErrInvalidContactName.Error():   codes.InvalidArgument,
+	domain.ErrInvalidContactNumber.Error(): codes.InvalidArgument,
+	domain.ErrMissingContactID.Error():     codes.InvalidArgument,
+	domain.ErrReservedContactID.Error():    codes.InvalidArgument,
+	domain.ErrInvalidPatch.Error():         codes.InvalidArgument,
+	domain.ErrInvalidPatchFormat.Error():   codes.InvalidArgument,
+	domain.ErrPatchTestFailed.Error():      codes.FailedPrecondition,
+	domain.ErrUnknownContactField.Error():  codes.InvalidArgument,
+	domain.ErrUnknownCustomField.Error():   codes.InvalidArgument,
+	domain.ErrMissingCustomField.Error():   codes.InvalidArgument,
+	domain.ErrInvalidCustomValue.Error():   codes.InvalidArgument,
+	"Invalid cursor":                       codes.InvalidArgument,
+	"Cursor expired":                       codes.OutOfRange,
+	domain.ErrWatchNotSupported.Error():    codes.Unimplemented,
+	domain.ErrGroupsNotSupported.Error():   codes.Unimplemented,
+	domain.ErrUnauthenticated.Error():      codes.Unauthenticated,
+	domain.ErrInvalidCredentials.Error():   codes.Unauthenticated,
+	domain.ErrPermissionDenied.Error():     codes.PermissionDenied,
+	domain.ErrTenantRequired.Error():       codes.InvalidArgument,
+	domain.ErrTenantNotFound.Error():       codes.NotFound,
+	domain.ErrTenantQuotaExceeded.Error():  codes.ResourceExhausted,
+	domain.ErrInvalidWebhookURL.Error():    codes.InvalidArgument,
+	domain.ErrInvalidWebhookEvent.Error():  codes.InvalidArgument,
+	domain.ErrWebhookNotFound.Error():      codes.NotFound,
+	domain.ErrWebhooksNotEnabled.Error():   codes.Unimplemented,
+}
+
+// statusError turns a failed service call into a gRPC status error.
+func statusError(message string) error {
+	code, ok := statusCodes[message]
+	if !ok {
+		code = codes.Internal
+	}
+	return status.Error(code, message)
+}
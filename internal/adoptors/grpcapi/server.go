@@ -0,0 +1,270 @@
+// Package grpcapi serves the phonebook over gRPC using the API defined in
+// api/phonebook/v1.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	phonebookv1 "github.com/Businge931/practice-interfaces/api/phonebook/v1"
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+type Server struct {
+	phonebookv1.UnimplementedPhonebookServiceServer
+	phonebook *application.PhonebookService
+	webhooks  *application.WebhookDispatcher
+}
+
+// NewServer returns the phonebook service. The webhook calls fail with
+// Unimplemented when webhooks is nil.
+func NewServer(phonebook *application.PhonebookService, webhooks *application.WebhookDispatcher) *Server {
+	return &Server{phonebook: phonebook, webhooks: webhooks}
+}
+
+// NewGRPCServer returns a gRPC server with the phonebook service and
+// reflection registered.
+func NewGRPCServer(phonebook *application.PhonebookService, webhooks *application.WebhookDispatcher, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	phonebookv1.RegisterPhonebookServiceServer(server, NewServer(phonebook, webhooks))
+	reflection.Register(server)
+	return server
+}
+
+func (s *Server) AddContact(ctx context.Context, req *phonebookv1.AddContactRequest) (*phonebookv1.AddContactResponse, error) {
+	if req.GetId() == "" {
+		return nil, statusError(domain.ErrMissingContactID.Error())
+	}
+	if success, message := s.phonebook.As(ctx).AddContact(req.GetId(), contactFromProto(req.GetContact())); !success {
+		return nil, statusError(message)
+	}
+	return &phonebookv1.AddContactResponse{}, nil
+}
+
+func (s *Server) GetContact(ctx context.Context, req *phonebookv1.GetContactRequest) (*phonebookv1.GetContactResponse, error) {
+	success, message, contact := s.phonebook.As(ctx).GetContact(req.GetId(), req.GetFields()...)
+	if !success {
+		return nil, statusError(message)
+	}
+	return &phonebookv1.GetContactResponse{Contact: contactToProto(contact)}, nil
+}
+
+func (s *Server) UpdateContact(ctx context.Context, req *phonebookv1.UpdateContactRequest) (*phonebookv1.UpdateContactResponse, error) {
+	if success, message := s.phonebook.As(ctx).UpdateContact(req.GetId(), contactFromProto(req.GetContact())); !success {
+		return nil, statusError(message)
+	}
+	return &phonebookv1.UpdateContactResponse{}, nil
+}
+
+func (s *Server) PatchContact(ctx context.Context, req *phonebookv1.PatchContactRequest) (*phonebookv1.PatchContactResponse, error) {
+	patch := domain.ContactPatch{Format: domain.MergePatch, Document: req.GetPatch()}
+	if req.GetFormat() == phonebookv1.PatchContactRequest_FORMAT_JSON_PATCH {
+		patch.Format = domain.JSONPatch
+	}
+	success, message, contact := s.phonebook.As(ctx).PatchContact(req.GetId(), patch)
+	if !success {
+		return nil, statusError(message)
+	}
+	return &phonebookv1.PatchContactResponse{Contact: contactToProto(contact)}, nil
+}
+
+func (s *Server) DeleteContact(ctx context.Context, req *phonebookv1.DeleteContactRequest) (*phonebookv1.DeleteContactResponse, error) {
+	// Authenticated callers are recorded as themselves
+	actor := req.GetActor()
+	if caller, ok := application.ActorFromContext(ctx); ok {
+		actor = caller.Name
+	}
+	if success, message := s.phonebook.As(ctx).DeleteContactAs(req.GetId(), actor); !success {
+		return nil, statusError(message)
+	}
+	return &phonebookv1.DeleteContactResponse{}, nil
+}
+
+func (s *Server) ListContacts(req *phonebookv1.ListContactsRequest, stream phonebookv1.PhonebookService_ListContactsServer) error {
+	success, message, phonebook := s.phonebook.As(stream.Context()).ListContacts(req.GetPrefix(), req.GetFields()...)
+	if !success {
+		return statusError(message)
+	}
+
+	ids := make([]string, 0, len(phonebook.Contacts))
+	for id := range phonebook.Contacts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		entry := &phonebookv1.ContactEntry{Id: id, Contact: contactToProto(phonebook.Contacts[id])}
+		if err := stream.Send(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) ImportContacts(stream phonebookv1.PhonebookService_ImportContactsServer) error {
+	var importer *application.Importer
+	for index := 1; ; index++ {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if importer == nil {
+			importer = s.phonebook.As(stream.Context()).NewImporter(req.GetOverwrite())
+		}
+		entry := req.GetEntry()
+		importer.Add(index, entry.GetId(), contactFromProto(entry.GetContact()))
+	}
+
+	response := &phonebookv1.ImportContactsResponse{}
+	if importer == nil {
+		return stream.SendAndClose(response)
+	}
+	for _, result := range importer.Close() {
+		response.Results = append(response.Results, &phonebookv1.ImportResult{
+			Index:   int32(result.Line),
+			Id:      result.ID,
+			Success: result.Success,
+			Message: result.Message,
+		})
+	}
+	return stream.SendAndClose(response)
+}
+
+func (s *Server) WatchContacts(req *phonebookv1.WatchContactsRequest, stream phonebookv1.PhonebookService_WatchContactsServer) error {
+	success, message, events := s.phonebook.As(stream.Context()).WatchContacts(stream.Context(), req.GetPrefix(), req.GetCursor())
+	if !success {
+		return statusError(message)
+	}
+
+	for event := range events {
+		if err := stream.Send(eventToProto(event)); err != nil {
+			return err
+		}
+	}
+	if err := stream.Context().Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Unavailable, "watch closed")
+}
+
+func (s *Server) RegisterWebhook(ctx context.Context, req *phonebookv1.RegisterWebhookRequest) (*phonebookv1.RegisterWebhookResponse, error) {
+	if s.webhooks == nil {
+		return nil, statusError(domain.ErrWebhooksNotEnabled.Error())
+	}
+	success, message, hook := s.webhooks.As(ctx).RegisterWebhook(webhookFromProto(req.GetWebhook()))
+	if !success {
+		return nil, statusError(message)
+	}
+	return &phonebookv1.RegisterWebhookResponse{Webhook: webhookToProto(hook)}, nil
+}
+
+func (s *Server) ListWebhooks(ctx context.Context, req *phonebookv1.ListWebhooksRequest) (*phonebookv1.ListWebhooksResponse, error) {
+	if s.webhooks == nil {
+		return nil, statusError(domain.ErrWebhooksNotEnabled.Error())
+	}
+	success, message, hooks := s.webhooks.As(ctx).ListWebhooks()
+	if !success {
+		return nil, statusError(message)
+	}
+	resp := &phonebookv1.ListWebhooksResponse{}
+	for _, hook := range hooks {
+		resp.Webhooks = append(resp.Webhooks, webhookToProto(hook))
+	}
+	return resp, nil
+}
+
+func (s *Server) DeleteWebhook(ctx context.Context, req *phonebookv1.DeleteWebhookRequest) (*phonebookv1.DeleteWebhookResponse, error) {
+	if s.webhooks == nil {
+		return nil, statusError(domain.ErrWebhooksNotEnabled.Error())
+	}
+	if success, message := s.webhooks.As(ctx).DeleteWebhook(req.GetId()); !success {
+		return nil, statusError(message)
+	}
+	return &phonebookv1.DeleteWebhookResponse{}, nil
+}
+
+func contactFromProto(contact *phonebookv1.Contact) domain.Contact {
+	return domain.Contact{
+		Name:    contact.GetName(),
+		Phone:   contact.GetPhone(),
+		Email:   contact.GetEmail(),
+		Address: contact.GetAddress(),
+		Custom:  contact.GetCustom(),
+	}
+}
+
+func contactToProto(contact domain.Contact) *phonebookv1.Contact {
+	return &phonebookv1.Contact{
+		Name:    contact.Name,
+		Phone:   contact.Phone,
+		Email:   contact.Email,
+		Address: contact.Address,
+		Custom:  contact.Custom,
+	}
+}
+
+var eventTypes = map[domain.ContactEventType]phonebookv1.ContactEvent_Type{
+	domain.ContactCreated: phonebookv1.ContactEvent_TYPE_CREATED,
+	domain.ContactUpdated: phonebookv1.ContactEvent_TYPE_UPDATED,
+	domain.ContactDeleted: phonebookv1.ContactEvent_TYPE_DELETED,
+}
+
+var eventTypesFromProto = map[phonebookv1.ContactEvent_Type]domain.ContactEventType{
+	phonebookv1.ContactEvent_TYPE_CREATED: domain.ContactCreated,
+	phonebookv1.ContactEvent_TYPE_UPDATED: domain.ContactUpdated,
+	phonebookv1.ContactEvent_TYPE_DELETED: domain.ContactDeleted,
+}
+
+func eventToProto(event domain.ContactEvent) *phonebookv1.ContactEvent {
+	msg := &phonebookv1.ContactEvent{
+		Cursor: event.Cursor,
+		Type:   eventTypes[event.Type],
+		Id:     event.ID,
+		Time:   timestamppb.New(event.Time),
+	}
+	if event.Type != domain.ContactDeleted {
+		msg.Contact = contactToProto(event.Contact)
+	}
+	return msg
+}
+
+// webhookFromProto leaves unknown event types empty, so they are rejected as
+// invalid.
+func webhookFromProto(hook *phonebookv1.Webhook) domain.Webhook {
+	events := make([]domain.ContactEventType, 0, len(hook.GetEvents()))
+	for _, eventType := range hook.GetEvents() {
+		events = append(events, eventTypesFromProto[eventType])
+	}
+	return domain.Webhook{
+		URL:    hook.GetUrl(),
+		Secret: hook.GetSecret(),
+		Events: events,
+		Group:  hook.GetGroup(),
+	}
+}
+
+func webhookToProto(hook domain.Webhook) *phonebookv1.Webhook {
+	msg := &phonebookv1.Webhook{
+		Id:        hook.ID,
+		Url:       hook.URL,
+		Secret:    hook.Secret,
+		Group:     hook.Group,
+		CreatedAt: timestamppb.New(hook.CreatedAt),
+	}
+	for _, event := range hook.Events {
+		msg.Events = append(msg.Events, eventTypes[event])
+	}
+	return msg
+}
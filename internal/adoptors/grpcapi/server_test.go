@@ -0,0 +1,338 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	phonebookv1 "github.com/Businge931/practice-interfaces/api/phonebook/v1"
+	"github.com/Businge931/practice-interfaces/internal/adoptors/database"
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+var testContact = &phonebookv1.Contact{
+	Name:    "John Doe",
+	Phone:   "123-456-7890",
+	Email:   "john@example.com",
+	Address: "123 Main St",
+}
+
+func setupGRPCTest(t *testing.T) phonebookv1.PhonebookServiceClient {
+	return serveGRPCTest(t, NewGRPCServer(application.NewPhonebookService(database.NewInMemoryDatabase()), nil))
+}
+
+func serveGRPCTest(t *testing.T, server *grpc.Server) phonebookv1.PhonebookServiceClient {
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return phonebookv1.NewPhonebookServiceClient(conn)
+}
+
+func TestServer_CRUD(t *testing.T) {
+	client := setupGRPCTest(t)
+	ctx := context.Background()
+
+	if _, err := client.AddContact(ctx, &phonebookv1.AddContactRequest{Id: "contacts/john", Contact: testContact}); err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	resp, err := client.GetContact(ctx, &phonebookv1.GetContactRequest{Id: "contacts/john"})
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if resp.GetContact().GetName() != "John Doe" {
+		t.Errorf("Expected John Doe but got %v", resp.GetContact())
+	}
+	resp, err = client.GetContact(ctx, &phonebookv1.GetContactRequest{Id: "contacts/john", Fields: []string{"phone"}})
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if resp.GetContact().GetName() != "" || resp.GetContact().GetPhone() != "123-456-7890" {
+		t.Errorf("Expected only the phone but got %v", resp.GetContact())
+	}
+
+	updated := &phonebookv1.Contact{Name: "John Updated", Phone: "999"}
+	if _, err := client.UpdateContact(ctx, &phonebookv1.UpdateContactRequest{Id: "contacts/john", Contact: updated}); err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	patched, err := client.PatchContact(ctx, &phonebookv1.PatchContactRequest{Id: "contacts/john", Patch: []byte(`{"email":"john@example.com"}`)})
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if patched.GetContact().GetName() != "John Updated" || patched.GetContact().GetEmail() != "john@example.com" {
+		t.Errorf("Expected the patched contact but got %v", patched.GetContact())
+	}
+	if _, err := client.DeleteContact(ctx, &phonebookv1.DeleteContactRequest{Id: "contacts/john", Actor: "alice"}); err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+}
+
+func TestServer_ErrorCodes(t *testing.T) {
+	client := setupGRPCTest(t)
+	ctx := context.Background()
+	client.AddContact(ctx, &phonebookv1.AddContactRequest{Id: "contacts/john", Contact: testContact})
+
+	tests := []struct {
+		name     string
+		call     func() error
+		expected codes.Code
+	}{
+		{"not found", func() error {
+			_, err := client.GetContact(ctx, &phonebookv1.GetContactRequest{Id: "contacts/missing"})
+			return err
+		}, codes.NotFound},
+		{"already exists", func() error {
+			_, err := client.AddContact(ctx, &phonebookv1.AddContactRequest{Id: "contacts/john", Contact: testContact})
+			return err
+		}, codes.AlreadyExists},
+		{"invalid contact", func() error {
+			_, err := client.AddContact(ctx, &phonebookv1.AddContactRequest{Id: "contacts/jane", Contact: &phonebookv1.Contact{Phone: "123"}})
+			return err
+		}, codes.InvalidArgument},
+		{"missing id", func() error {
+			_, err := client.AddContact(ctx, &phonebookv1.AddContactRequest{Contact: testContact})
+			return err
+		}, codes.InvalidArgument},
+		{"unknown field", func() error {
+			_, err := client.GetContact(ctx, &phonebookv1.GetContactRequest{Id: "contacts/john", Fields: []string{"nickname"}})
+			return err
+		}, codes.InvalidArgument},
+		{"unknown custom field", func() error {
+			_, err := client.AddContact(ctx, &phonebookv1.AddContactRequest{Id: "contacts/jane", Contact: &phonebookv1.Contact{
+				Name: "Jane", Phone: "123", Custom: map[string]string{"nickname": "Jo"},
+			}})
+			return err
+		}, codes.InvalidArgument},
+		{"invalid patch", func() error {
+			_, err := client.PatchContact(ctx, &phonebookv1.PatchContactRequest{Id: "contacts/john", Patch: []byte(`{"nickname":"Jo"}`)})
+			return err
+		}, codes.InvalidArgument},
+		{"failed patch test", func() error {
+			_, err := client.PatchContact(ctx, &phonebookv1.PatchContactRequest{
+				Id:     "contacts/john",
+				Format: phonebookv1.PatchContactRequest_FORMAT_JSON_PATCH,
+				Patch:  []byte(`[{"op":"test","path":"/name","value":"Jane"}]`),
+			})
+			return err
+		}, codes.FailedPrecondition},
+		{"bad cursor", func() error {
+			stream, err := client.WatchContacts(ctx, &phonebookv1.WatchContactsRequest{Cursor: "abc"})
+			if err != nil {
+				return err
+			}
+			_, err = stream.Recv()
+			return err
+		}, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := status.Code(tt.call()); code != tt.expected {
+				t.Errorf("Expected code %v but got %v", tt.expected, code)
+			}
+		})
+	}
+}
+
+func TestServer_CustomFields(t *testing.T) {
+	phonebook := application.NewPhonebookService(database.NewInMemoryDatabase())
+	if success, message := phonebook.DefineField(domain.FieldDefinition{Name: "employee_id", Type: domain.FieldNumber}); !success {
+		t.Fatalf("Expected success but got error: %s", message)
+	}
+	client := serveGRPCTest(t, NewGRPCServer(phonebook, nil))
+	ctx := context.Background()
+
+	contact := &phonebookv1.Contact{Name: "John Doe", Phone: "123", Custom: map[string]string{"employee_id": "42"}}
+	if _, err := client.AddContact(ctx, &phonebookv1.AddContactRequest{Id: "contacts/john", Contact: contact}); err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	resp, err := client.GetContact(ctx, &phonebookv1.GetContactRequest{Id: "contacts/john"})
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if resp.GetContact().GetCustom()["employee_id"] != "42" {
+		t.Errorf("Expected employee_id 42 but got %v", resp.GetContact())
+	}
+
+	contact.Custom["employee_id"] = "forty-two"
+	_, err = client.UpdateContact(ctx, &phonebookv1.UpdateContactRequest{Id: "contacts/john", Contact: contact})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("Expected code %v but got %v", codes.InvalidArgument, code)
+	}
+}
+
+func TestServer_ListContacts(t *testing.T) {
+	client := setupGRPCTest(t)
+	ctx := context.Background()
+	for _, id := range []string{"contacts/b", "contacts/a", "other/c"} {
+		client.AddContact(ctx, &phonebookv1.AddContactRequest{Id: id, Contact: testContact})
+	}
+
+	stream, err := client.ListContacts(ctx, &phonebookv1.ListContactsRequest{Prefix: "contacts/"})
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	ids := []string{}
+	for {
+		entry, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+		ids = append(ids, entry.GetId())
+	}
+	if len(ids) != 2 || ids[0] != "contacts/a" || ids[1] != "contacts/b" {
+		t.Errorf("Expected [contacts/a contacts/b] but got %v", ids)
+	}
+}
+
+func TestServer_ImportContacts(t *testing.T) {
+	client := setupGRPCTest(t)
+	ctx := context.Background()
+	client.AddContact(ctx, &phonebookv1.AddContactRequest{Id: "contacts/existing", Contact: testContact})
+
+	stream, err := client.ImportContacts(ctx)
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	entries := []*phonebookv1.ContactEntry{
+		{Id: "contacts/john", Contact: testContact},
+		{Id: "contacts/invalid", Contact: &phonebookv1.Contact{Name: "No Phone"}},
+		{Id: "contacts/existing", Contact: testContact},
+	}
+	for _, entry := range entries {
+		if err := stream.Send(&phonebookv1.ImportContactsRequest{Entry: entry}); err != nil {
+			t.Fatalf("Failed to send entry: %v", err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+
+	expected := []bool{true, false, false}
+	if len(resp.GetResults()) != len(expected) {
+		t.Fatalf("Expected %d results but got %d", len(expected), len(resp.GetResults()))
+	}
+	for i, result := range resp.GetResults() {
+		if result.GetSuccess() != expected[i] || result.GetIndex() != int32(i+1) {
+			t.Errorf("Result %d: expected success=%v but got %+v", i, expected[i], result)
+		}
+	}
+}
+
+func TestServer_WatchContacts(t *testing.T) {
+	client := setupGRPCTest(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchContacts(ctx, &phonebookv1.WatchContactsRequest{Prefix: "contacts/"})
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	// Wait for the watch to be established before writing
+	time.Sleep(50 * time.Millisecond)
+	client.AddContact(ctx, &phonebookv1.AddContactRequest{Id: "contacts/john", Contact: testContact})
+	client.DeleteContact(ctx, &phonebookv1.DeleteContactRequest{Id: "contacts/john"})
+
+	expected := []phonebookv1.ContactEvent_Type{
+		phonebookv1.ContactEvent_TYPE_CREATED,
+		phonebookv1.ContactEvent_TYPE_DELETED,
+	}
+	for _, eventType := range expected {
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Expected an event but got error: %v", err)
+		}
+		if event.GetType() != eventType || event.GetId() != "contacts/john" {
+			t.Errorf("Expected %v event but got %v", eventType, event)
+		}
+	}
+}
+
+func TestNewGRPCServer_Reflection(t *testing.T) {
+	server := NewGRPCServer(application.NewPhonebookService(database.NewInMemoryDatabase()), nil)
+	services := server.GetServiceInfo()
+	if _, ok := services["phonebook.v1.PhonebookService"]; !ok {
+		t.Error("Expected the phonebook service to be registered")
+	}
+	if _, ok := services["grpc.reflection.v1.ServerReflection"]; !ok {
+		t.Error("Expected reflection to be registered")
+	}
+}
+
+func TestServer_Webhooks(t *testing.T) {
+	phonebook := application.NewPhonebookService(database.NewInMemoryDatabase())
+	webhooks, err := application.NewWebhookDispatcher(phonebook, database.NewInMemoryDatabase())
+	if err != nil {
+		t.Fatalf("Failed to create the dispatcher: %v", err)
+	}
+	client := serveGRPCTest(t, NewGRPCServer(phonebook, webhooks))
+	ctx := context.Background()
+
+	registered, err := client.RegisterWebhook(ctx, &phonebookv1.RegisterWebhookRequest{Webhook: &phonebookv1.Webhook{
+		Url:    "https://example.com/hook",
+		Events: []phonebookv1.ContactEvent_Type{phonebookv1.ContactEvent_TYPE_DELETED},
+	}})
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	hook := registered.GetWebhook()
+	if hook.GetId() == "" || hook.GetSecret() == "" {
+		t.Errorf("Expected a generated id and secret but got %v", hook)
+	}
+
+	list, err := client.ListWebhooks(ctx, &phonebookv1.ListWebhooksRequest{})
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if len(list.GetWebhooks()) != 1 {
+		t.Fatalf("Expected 1 webhook but got %v", list.GetWebhooks())
+	}
+	listed := list.GetWebhooks()[0]
+	if listed.GetId() != hook.GetId() || listed.GetSecret() != "" || len(listed.GetEvents()) != 1 || listed.GetEvents()[0] != phonebookv1.ContactEvent_TYPE_DELETED {
+		t.Errorf("Expected %v without its secret but got %v", hook, listed)
+	}
+
+	_, err = client.RegisterWebhook(ctx, &phonebookv1.RegisterWebhookRequest{Webhook: &phonebookv1.Webhook{
+		Url:    "https://example.com/hook",
+		Events: []phonebookv1.ContactEvent_Type{phonebookv1.ContactEvent_TYPE_UNSPECIFIED},
+	}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument but got %v", err)
+	}
+
+	if _, err := client.DeleteWebhook(ctx, &phonebookv1.DeleteWebhookRequest{Id: hook.GetId()}); err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if _, err := client.DeleteWebhook(ctx, &phonebookv1.DeleteWebhookRequest{Id: hook.GetId()}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound but got %v", err)
+	}
+
+	// Servers without a dispatcher do not offer webhooks
+	_, err = setupGRPCTest(t).ListWebhooks(ctx, &phonebookv1.ListWebhooksRequest{})
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("Expected Unimplemented but got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package grpcapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/Businge931/practice-interfaces/internal/application"
+)
+
+// tenantKey is the metadata key selecting the tenant a call runs against.
+const tenantKey = "x-phonebook-tenant"
+
+// WithTenants returns server options that put the tenant selected by each
+// call on its context, for a service with tenants enabled.
+func WithTenants() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			return handler(selectTenant(ctx), req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, &contextStream{ServerStream: stream, ctx: selectTenant(stream.Context())})
+		}),
+	}
+}
+
+func selectTenant(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(tenantKey); len(values) > 0 {
+		return application.ContextWithTenant(ctx, values[0])
+	}
+	return ctx
+}
@@ -0,0 +1,57 @@
+package grpcapi
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	phonebookv1 "github.com/Businge931/practice-interfaces/api/phonebook/v1"
+	"github.com/Businge931/practice-interfaces/internal/adoptors/database"
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+func TestServer_Tenants(t *testing.T) {
+	tenants := application.NewTenantManager(database.NewInMemoryDatabase(), database.Tenants(database.NewInMemoryDatabase()))
+	tenants.CreateTenant(domain.Tenant{ID: "acme"})
+	tenants.CreateTenant(domain.Tenant{ID: "globex", MaxContacts: 1})
+
+	phonebook := application.NewPhonebookService(database.NewInMemoryDatabase(), application.WithTenants(tenants))
+	client := serveGRPCTest(t, NewGRPCServer(phonebook, nil, WithTenants()...))
+
+	tests := []struct {
+		name     string
+		md       metadata.MD
+		id       string
+		expected codes.Code
+	}{
+		{"no tenant", nil, "contacts/john", codes.InvalidArgument},
+		{"unknown tenant", metadata.Pairs(tenantKey, "initech"), "contacts/john", codes.NotFound},
+		{"acme", metadata.Pairs(tenantKey, "acme"), "contacts/john", codes.OK},
+		{"globex", metadata.Pairs(tenantKey, "globex"), "contacts/john", codes.OK},
+		{"globex over quota", metadata.Pairs(tenantKey, "globex"), "contacts/jane", codes.ResourceExhausted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewOutgoingContext(context.Background(), tt.md)
+			_, err := client.AddContact(ctx, &phonebookv1.AddContactRequest{Id: tt.id, Contact: testContact})
+			if status.Code(err) != tt.expected {
+				t.Errorf("Expected %v but got %v", tt.expected, err)
+			}
+		})
+	}
+
+	// Streams run against the selected tenant too
+	acme := metadata.NewOutgoingContext(context.Background(), metadata.Pairs(tenantKey, "acme"))
+	stream, err := client.ListContacts(acme, &phonebookv1.ListContactsRequest{})
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Expected a contact but got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+// Package httpauth authenticates HTTP requests against the phonebook's users
+// and API keys, for the front ends served over HTTP.
+package httpauth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+// APIKeyHeader carries a bare API key.
+const APIKeyHeader = "X-API-Key"
+
+// Handler authenticates every request before passing it to next with the
+// caller on its context. The credential is an API key or a JWT, sent as
+// "Authorization: Bearer <credential>", in the API key header, or as the
+// password of a basic authorization, whose user name must then be the
+// credential's user. Requests without valid credentials are answered with a
+// basic challenge, so browsers and CardDAV clients prompt for them.
+func Handler(auth *application.AuthService, realm string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, credential := credentials(r)
+		if credential == "" {
+			challenge(w, realm, domain.ErrUnauthenticated.Error())
+			return
+		}
+
+		success, message, actor := auth.Authenticate(credential)
+		if !success {
+			challenge(w, realm, message)
+			return
+		}
+		if user != "" && user != actor.Name {
+			challenge(w, realm, domain.ErrInvalidCredentials.Error())
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(application.ContextWithActor(r.Context(), actor)))
+	})
+}
+
+// credentials returns the user name and credential sent with r.
+func credentials(r *http.Request) (string, string) {
+	if user, password, ok := r.BasicAuth(); ok {
+		return user, password
+	}
+	if scheme, value, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "bearer") {
+		return "", strings.TrimSpace(value)
+	}
+	return "", r.Header.Get(APIKeyHeader)
+}
+
+func challenge(w http.ResponseWriter, realm, message string) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+	http.Error(w, message, http.StatusUnauthorized)
+}
@@ -0,0 +1,65 @@
+package httpauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Businge931/practice-interfaces/internal/adoptors/database"
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+func TestHandler(t *testing.T) {
+	auth := application.NewAuthService(database.NewInMemoryDatabase())
+	auth.CreateUser(domain.User{Name: "alice", Role: domain.RoleEditor})
+	auth.CreateUser(domain.User{Name: "bob", Role: domain.RoleViewer})
+	_, _, aliceKey, _ := auth.CreateAPIKey("alice", "")
+	_, _, bobToken := auth.IssueToken("bob", time.Hour)
+
+	handler := Handler(auth, "phonebook", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor, _ := application.ActorFromContext(r.Context())
+		w.Write([]byte(actor.Name))
+	}))
+
+	tests := []struct {
+		name     string
+		header   http.Header
+		basic    []string
+		expected int
+		actor    string
+	}{
+		{"anonymous", nil, nil, http.StatusUnauthorized, ""},
+		{"bad credential", http.Header{"Authorization": {"Bearer nope"}}, nil, http.StatusUnauthorized, ""},
+		{"bearer token", http.Header{"Authorization": {"Bearer " + bobToken}}, nil, http.StatusOK, "bob"},
+		{"api key header", http.Header{APIKeyHeader: {aliceKey}}, nil, http.StatusOK, "alice"},
+		{"basic", nil, []string{"alice", aliceKey}, http.StatusOK, "alice"},
+		{"basic without user", nil, []string{"", aliceKey}, http.StatusOK, "alice"},
+		{"basic other user", nil, []string{"bob", aliceKey}, http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for key, values := range tt.header {
+				req.Header.Set(key, values[0])
+			}
+			if tt.basic != nil {
+				req.SetBasicAuth(tt.basic[0], tt.basic[1])
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Fatalf("Expected status %d but got %d: %s", tt.expected, rec.Code, rec.Body.String())
+			}
+			if tt.expected == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("Expected a basic challenge")
+			}
+			if tt.expected == http.StatusOK && rec.Body.String() != tt.actor {
+				t.Errorf("Expected actor %q but got %q", tt.actor, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package ldapapi
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+// attribute is an LDAP attribute with its canonical name.
+type attribute struct {
+	name   string
+	values []string
+}
+
+type entry struct {
+	dn         string
+	attributes []attribute
+}
+
+// get returns the values of an attribute, matching the name case-insensitively.
+func (e entry) get(name string) []string {
+	for _, attr := range e.attributes {
+		if strings.EqualFold(attr.name, name) {
+			return attr.values
+		}
+	}
+	return nil
+}
+
+// selected returns the attributes a search asked for. An empty list or "*"
+// returns everything and "1.1" returns nothing.
+func (e entry) selected(requested []string) map[string][]string {
+	all := len(requested) == 0
+	for _, name := range requested {
+		if name == "*" {
+			all = true
+		}
+	}
+
+	result := make(map[string][]string)
+	for _, attr := range e.attributes {
+		if all {
+			result[attr.name] = attr.values
+			continue
+		}
+		for _, name := range requested {
+			if strings.EqualFold(attr.name, name) {
+				result[attr.name] = attr.values
+			}
+		}
+	}
+	return result
+}
+
+// customAttributePrefix names the attributes holding custom fields:
+// employee_id is published as x-phonebook-employee-id.
+const customAttributePrefix = "x-phonebook-"
+
+// contactEntry maps a contact onto an inetOrgPerson entry named by its id.
+// Custom fields are added as extension attributes.
+func contactEntry(baseDN, id string, contact domain.Contact) entry {
+	given, family := splitName(contact.Name)
+	attributes := []attribute{
+		{"objectClass", []string{"top", "person", "organizationalPerson", "inetOrgPerson"}},
+		{"uid", []string{id}},
+		{"cn", []string{contact.Name}},
+		{"sn", []string{family}},
+	}
+	if given != "" {
+		attributes = append(attributes, attribute{"givenName", []string{given}})
+	}
+	attributes = append(attributes, attribute{"telephoneNumber", []string{contact.Phone}})
+	if contact.Email != "" {
+		attributes = append(attributes, attribute{"mail", []string{contact.Email}})
+	}
+	if contact.Address != "" {
+		attributes = append(attributes, attribute{"postalAddress", []string{contact.Address}})
+	}
+	names := make([]string, 0, len(contact.Custom))
+	for name := range contact.Custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		attributes = append(attributes, attribute{customAttributePrefix + strings.ReplaceAll(name, "_", "-"), []string{contact.Custom[name]}})
+	}
+
+	return entry{dn: contactDN(baseDN, id), attributes: attributes}
+}
+
+// baseEntry is the organizational unit holding the contacts.
+func baseEntry(baseDN *ldap.DN) entry {
+	attributes := []attribute{{"objectClass", []string{"top", "organizationalUnit"}}}
+	if len(baseDN.RDNs) > 0 && len(baseDN.RDNs[0].Attributes) > 0 {
+		first := baseDN.RDNs[0].Attributes[0]
+		attributes = append(attributes, attribute{first.Type, []string{first.Value}})
+	}
+	return entry{dn: baseDN.String(), attributes: attributes}
+}
+
+// rootDSE describes the server to clients that look before they search.
+func rootDSE(baseDN *ldap.DN) entry {
+	return entry{attributes: []attribute{
+		{"objectClass", []string{"top"}},
+		{"namingContexts", []string{baseDN.String()}},
+		{"supportedLDAPVersion", []string{"3"}},
+	}}
+}
+
+func contactDN(baseDN, id string) string {
+	return "uid=" + ldap.EscapeDN(id) + "," + baseDN
+}
+
+// splitName splits "Given Family" for givenName and sn. A single word is used
+// as the surname, which inetOrgPerson requires.
+func splitName(name string) (string, string) {
+	parts := strings.Fields(name)
+	if len(parts) < 2 {
+		return "", name
+	}
+	return strings.Join(parts[:len(parts)-1], " "), parts[len(parts)-1]
+}
@@ -0,0 +1,148 @@
+package ldapapi
+
+import (
+	"strings"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// searchAttributes maps attributes to the contact field SearchContacts looks
+// at, so that filters on them can narrow the candidates before matching.
+var searchAttributes = map[string]bool{
+	"cn":              true,
+	"sn":              true,
+	"givenname":       true,
+	"mail":            true,
+	"telephonenumber": true,
+	"postaladdress":   true,
+}
+
+// matches evaluates a compiled filter against an entry. Matching ignores case,
+// and telephone numbers also ignore spaces and hyphens.
+func matches(filter *ber.Packet, e entry) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matches(child, e) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matches(child, e) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return len(filter.Children) == 1 && !matches(filter.Children[0], e)
+	case ldap.FilterPresent:
+		return len(e.get(filterString(filter))) > 0
+	case ldap.FilterEqualityMatch, ldap.FilterApproxMatch:
+		name, want := filterAssertion(filter)
+		for _, value := range e.get(name) {
+			if normalize(name, value) == normalize(name, want) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterSubstrings:
+		name := filterString(filter.Children[0])
+		for _, value := range e.get(name) {
+			if matchSubstrings(normalize(name, value), filter.Children[1].Children, name) {
+				return true
+			}
+		}
+		return false
+	}
+	// Ordering and extensible matches are not supported
+	return false
+}
+
+func matchSubstrings(value string, parts []*ber.Packet, name string) bool {
+	for _, part := range parts {
+		piece := normalize(name, filterString(part))
+		switch part.Tag {
+		case ldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(value, piece) {
+				return false
+			}
+			value = value[len(piece):]
+		case ldap.FilterSubstringsAny:
+			i := strings.Index(value, piece)
+			if i < 0 {
+				return false
+			}
+			value = value[i+len(piece):]
+		case ldap.FilterSubstringsFinal:
+			if !strings.HasSuffix(value, piece) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// searchTerm picks a value every matching entry must contain in a field known
+// to SearchContacts, or "" when the filter cannot be narrowed that way.
+func searchTerm(filter *ber.Packet) string {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		best := ""
+		for _, child := range filter.Children {
+			if term := searchTerm(child); len(term) > len(best) {
+				best = term
+			}
+		}
+		return best
+	case ldap.FilterEqualityMatch:
+		name, value := filterAssertion(filter)
+		return termFor(name, value)
+	case ldap.FilterSubstrings:
+		name := filterString(filter.Children[0])
+		best := ""
+		for _, part := range filter.Children[1].Children {
+			if term := termFor(name, filterString(part)); len(term) > len(best) {
+				best = term
+			}
+		}
+		return best
+	}
+	return ""
+}
+
+func termFor(name, value string) string {
+	name = strings.ToLower(name)
+	if !searchAttributes[name] {
+		return ""
+	}
+	if name == "telephonenumber" {
+		// SearchContacts matches bare digits against the phone's digits
+		digits := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, value)
+		return digits
+	}
+	return strings.TrimSpace(value)
+}
+
+func normalize(name, value string) string {
+	value = strings.ToLower(value)
+	if strings.EqualFold(name, "telephoneNumber") {
+		value = strings.NewReplacer(" ", "", "-", "").Replace(value)
+	}
+	return value
+}
+
+func filterAssertion(filter *ber.Packet) (string, string) {
+	return filterString(filter.Children[0]), filterString(filter.Children[1])
+}
+
+func filterString(packet *ber.Packet) string {
+	return ber.DecodeString(packet.Data.Bytes())
+}
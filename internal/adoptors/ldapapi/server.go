@@ -0,0 +1,353 @@
+// Package ldapapi serves the phonebook as a read-only LDAPv3 directory of
+// inetOrgPerson entries, for desk phones and mail clients.
+package ldapapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/go-hclog"
+	"github.com/jimlambrt/gldap"
+
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+const (
+	DefaultBaseDN       = "ou=contacts,dc=phonebook"
+	DefaultMaxSizeLimit = 500
+)
+
+type Config struct {
+	// BaseDN is the entry the contacts are listed under.
+	BaseDN string
+	// Users maps bind DNs to their passwords for simple binds.
+	Users map[string]string
+	// Auth, when set, checks simple binds against the phonebook's users
+	// instead of Users: the first RDN of the bind DN (uid or cn) names the
+	// user and the password is one of their API keys or tokens. Searches
+	// then act for that user, and anonymous searches are refused.
+	Auth *application.AuthService
+	// AllowAnonymous lets clients search without binding.
+	AllowAnonymous bool
+	// MaxSizeLimit caps the entries returned by one search, whatever the
+	// client asks for.
+	MaxSizeLimit int
+}
+
+type Server struct {
+	phonebook *application.PhonebookService
+	config    Config
+	baseDN    *ldap.DN
+	server    *gldap.Server
+
+	mu     sync.Mutex
+	bound  map[int]bool
+	actors map[int]domain.Actor
+}
+
+func NewServer(phonebook *application.PhonebookService, config Config) (*Server, error) {
+	if config.BaseDN == "" {
+		config.BaseDN = DefaultBaseDN
+	}
+	if config.MaxSizeLimit <= 0 {
+		config.MaxSizeLimit = DefaultMaxSizeLimit
+	}
+	baseDN, err := ldap.ParseDN(config.BaseDN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base DN %q: %w", config.BaseDN, err)
+	}
+
+	s := &Server{
+		phonebook: phonebook,
+		config:    config,
+		baseDN:    baseDN,
+		bound:     make(map[int]bool),
+		actors:    make(map[int]domain.Actor),
+	}
+
+	s.server, err = gldap.NewServer(
+		gldap.WithOnClose(s.forget),
+		gldap.WithLogger(hclog.New(&hclog.LoggerOptions{Name: "ldap", Level: hclog.Off})),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	mux, err := gldap.NewMux()
+	if err != nil {
+		return nil, err
+	}
+	mux.Bind(s.handleBind)
+	mux.Search(s.handleSearch)
+	mux.Unbind(func(w *gldap.ResponseWriter, r *gldap.Request) {})
+	mux.Add(readOnly(gldap.ApplicationAddResponse))
+	mux.Modify(readOnly(gldap.ApplicationModifyResponse))
+	mux.Delete(readOnly(gldap.ApplicationDelResponse))
+	if err := s.server.Router(mux); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Run serves LDAP on addr until Stop is called.
+func (s *Server) Run(addr string) error {
+	return s.server.Run(addr)
+}
+
+func (s *Server) Ready() bool {
+	return s.server.Ready()
+}
+
+func (s *Server) Stop() error {
+	return s.server.Stop()
+}
+
+func (s *Server) handleBind(w *gldap.ResponseWriter, r *gldap.Request) {
+	resp := r.NewBindResponse(gldap.WithResponseCode(gldap.ResultInvalidCredentials))
+	defer w.Write(resp)
+
+	msg, err := r.GetSimpleBindMessage()
+	if err != nil {
+		resp.SetResultCode(gldap.ResultAuthMethodNotSupported)
+		return
+	}
+
+	if msg.UserName == "" && msg.Password == "" {
+		if !s.config.AllowAnonymous || s.config.Auth != nil {
+			resp.SetResultCode(gldap.ResultInappropriateAuthentication)
+			return
+		}
+		s.setBound(r.ConnectionID(), false)
+		resp.SetResultCode(gldap.ResultSuccess)
+		return
+	}
+
+	if s.config.Auth != nil {
+		if actor, ok := s.authenticate(msg.UserName, string(msg.Password)); ok {
+			s.setActor(r.ConnectionID(), actor)
+			resp.SetResultCode(gldap.ResultSuccess)
+			return
+		}
+		s.forget(r.ConnectionID())
+		return
+	}
+
+	if s.checkPassword(msg.UserName, string(msg.Password)) {
+		s.setBound(r.ConnectionID(), true)
+		resp.SetResultCode(gldap.ResultSuccess)
+		return
+	}
+	s.setBound(r.ConnectionID(), false)
+}
+
+func (s *Server) handleSearch(w *gldap.ResponseWriter, r *gldap.Request) {
+	resp := r.NewSearchDoneResponse(gldap.WithResponseCode(gldap.ResultSuccess))
+	defer w.Write(resp)
+
+	msg, err := r.GetSearchMessage()
+	if err != nil {
+		resp.SetResultCode(gldap.ResultProtocolError)
+		return
+	}
+	phonebook, ok := s.service(r.ConnectionID())
+	if !ok {
+		resp.SetResultCode(gldap.ResultInsufficientAccessRights)
+		return
+	}
+	filter, err := ldap.CompileFilter(msg.Filter)
+	if err != nil {
+		resp.SetResultCode(gldap.ResultProtocolError)
+		resp.SetDiagnosticMessage(err.Error())
+		return
+	}
+
+	entries, code, message := s.candidates(phonebook, msg, filter)
+	if code != gldap.ResultSuccess {
+		resp.SetResultCode(code)
+		resp.SetDiagnosticMessage(message)
+		return
+	}
+
+	limit := s.config.MaxSizeLimit
+	if msg.SizeLimit > 0 && int(msg.SizeLimit) < limit {
+		limit = int(msg.SizeLimit)
+	}
+
+	sent := 0
+	for _, e := range entries {
+		if !matches(filter, e) {
+			continue
+		}
+		if sent == limit {
+			resp.SetResultCode(gldap.ResultSizeLimitExceeded)
+			return
+		}
+		w.Write(r.NewSearchResponseEntry(e.dn, gldap.WithAttributes(e.selected(msg.Attributes))))
+		sent++
+	}
+}
+
+// candidates returns the entries in scope of a search, narrowed by the
+// phonebook search when the filter allows it.
+func (s *Server) candidates(phonebook *application.PhonebookService, msg *gldap.SearchMessage, filter *ber.Packet) ([]entry, int, string) {
+	if msg.BaseDN == "" && msg.Scope == gldap.BaseObject {
+		return []entry{rootDSE(s.baseDN)}, gldap.ResultSuccess, ""
+	}
+
+	base, err := ldap.ParseDN(msg.BaseDN)
+	if err != nil {
+		return nil, gldap.ResultInvalidDNSyntax, err.Error()
+	}
+
+	switch {
+	case base.EqualFold(s.baseDN):
+		result := []entry{}
+		if msg.Scope != gldap.SingleLevel {
+			result = append(result, baseEntry(s.baseDN))
+		}
+		if msg.Scope == gldap.BaseObject {
+			return result, gldap.ResultSuccess, ""
+		}
+		contacts, code, message := s.contacts(phonebook, searchTerm(filter))
+		return append(result, contacts...), code, message
+
+	case base.AncestorOfFold(s.baseDN) && msg.Scope == gldap.WholeSubtree:
+		contacts, code, message := s.contacts(phonebook, searchTerm(filter))
+		return append([]entry{baseEntry(s.baseDN)}, contacts...), code, message
+
+	case s.baseDN.AncestorOfFold(base) && len(base.RDNs) == len(s.baseDN.RDNs)+1:
+		if msg.Scope == gldap.SingleLevel {
+			return nil, gldap.ResultSuccess, ""
+		}
+		id, ok := contactID(base)
+		if !ok {
+			return nil, gldap.ResultNoSuchObject, ""
+		}
+		success, _, contact := phonebook.GetContact(id)
+		if !success {
+			return nil, gldap.ResultNoSuchObject, ""
+		}
+		return []entry{contactEntry(s.baseDN.String(), id, contact)}, gldap.ResultSuccess, ""
+	}
+
+	return nil, gldap.ResultNoSuchObject, ""
+}
+
+// contacts returns the entries of every live contact matching term, ordered by id.
+func (s *Server) contacts(service *application.PhonebookService, term string) ([]entry, int, string) {
+	success, message, phonebook := service.SearchContacts(term, "")
+	if !success {
+		if message == domain.ErrPermissionDenied.Error() {
+			return nil, gldap.ResultInsufficientAccessRights, message
+		}
+		return nil, gldap.ResultOperationsError, message
+	}
+
+	ids := make([]string, 0, len(phonebook.Contacts))
+	for id := range phonebook.Contacts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	entries := make([]entry, 0, len(ids))
+	for _, id := range ids {
+		entries = append(entries, contactEntry(s.baseDN.String(), id, phonebook.Contacts[id]))
+	}
+	return entries, gldap.ResultSuccess, ""
+}
+
+func (s *Server) checkPassword(bindDN, password string) bool {
+	dn, err := ldap.ParseDN(bindDN)
+	if err != nil {
+		return false
+	}
+	for user, want := range s.config.Users {
+		userDN, err := ldap.ParseDN(user)
+		if err != nil || !userDN.EqualFold(dn) {
+			continue
+		}
+		return subtle.ConstantTimeCompare([]byte(password), []byte(want)) == 1
+	}
+	return false
+}
+
+// authenticate checks a bind against the phonebook's users.
+func (s *Server) authenticate(bindDN, credential string) (domain.Actor, bool) {
+	dn, err := ldap.ParseDN(bindDN)
+	if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) != 1 {
+		return domain.Actor{}, false
+	}
+	attribute := dn.RDNs[0].Attributes[0]
+	if !strings.EqualFold(attribute.Type, "uid") && !strings.EqualFold(attribute.Type, "cn") {
+		return domain.Actor{}, false
+	}
+
+	success, _, actor := s.config.Auth.Authenticate(credential)
+	if !success || actor.Name != attribute.Value {
+		return domain.Actor{}, false
+	}
+	return actor, true
+}
+
+// service returns the phonebook acting for the connection, or false when the
+// connection may not search.
+func (s *Server) service(connID int) (*application.PhonebookService, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.config.Auth != nil {
+		actor, ok := s.actors[connID]
+		if !ok {
+			return nil, false
+		}
+		return s.phonebook.As(application.ContextWithActor(context.Background(), actor)), true
+	}
+	if !s.config.AllowAnonymous && !s.bound[connID] {
+		return nil, false
+	}
+	return s.phonebook, true
+}
+
+func (s *Server) setActor(connID int, actor domain.Actor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.actors[connID] = actor
+}
+
+func (s *Server) setBound(connID int, bound bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bound[connID] = bound
+}
+
+func (s *Server) forget(connID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.bound, connID)
+	delete(s.actors, connID)
+}
+
+func contactID(dn *ldap.DN) (string, bool) {
+	rdn := dn.RDNs[0]
+	if len(rdn.Attributes) != 1 || !strings.EqualFold(rdn.Attributes[0].Type, "uid") {
+		return "", false
+	}
+	return rdn.Attributes[0].Value, true
+}
+
+// readOnly answers write operations with unwillingToPerform.
+func readOnly(applicationCode int) gldap.HandlerFunc {
+	return func(w *gldap.ResponseWriter, r *gldap.Request) {
+		w.Write(r.NewResponse(
+			gldap.WithApplicationCode(applicationCode),
+			gldap.WithResponseCode(gldap.ResultUnwillingToPerform),
+			gldap.WithDiagnosticMessage("the directory is read-only"),
+		))
+	}
+}
@@ -0,0 +1,240 @@
+package ldapapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/Businge931/practice-interfaces/internal/adoptors/database"
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+const testAdminDN = "cn=admin,dc=phonebook"
+
+// setupLDAPTest serves three contacts. With config.Auth set, the phonebook
+// checks every search against the bound user.
+func setupLDAPTest(t *testing.T, config Config) *ldap.Conn {
+	var options []application.Option
+	if config.Auth != nil {
+		options = append(options, application.WithAuthorization())
+	}
+	service := application.NewPhonebookService(database.NewInMemoryDatabase(), options...)
+	phonebook := service.As(application.ContextWithActor(context.Background(), domain.Actor{Name: "root", Role: domain.RoleAdmin}))
+	phonebook.DefineField(domain.FieldDefinition{Name: "employee_id", Type: domain.FieldText})
+	phonebook.AddContact("contacts/john", domain.Contact{Name: "John Doe", Phone: "123-456-7890", Email: "john@example.com", Address: "123 Main St",
+		Custom: map[string]string{"employee_id": "E42"}})
+	phonebook.AddContact("contacts/jane", domain.Contact{Name: "Jane Roe", Phone: "555-000-1111", Email: "jane@example.org"})
+	phonebook.AddContact("contacts/bob", domain.Contact{Name: "Bob", Phone: "777 888 9999"})
+
+	server, err := NewServer(service, config)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	go server.Run(addr)
+	t.Cleanup(func() { server.Stop() })
+	for deadline := time.Now().Add(2 * time.Second); !server.Ready(); {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the server")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	conn, err := ldap.DialURL(fmt.Sprintf("ldap://%s", addr))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func search(conn *ldap.Conn, base string, scope int, sizeLimit int, filter string) (*ldap.SearchResult, error) {
+	return conn.Search(ldap.NewSearchRequest(base, scope, ldap.NeverDerefAliases, sizeLimit, 0, false, filter, nil, nil))
+}
+
+func uids(result *ldap.SearchResult) []string {
+	ids := []string{}
+	for _, e := range result.Entries {
+		if uid := e.GetAttributeValue("uid"); uid != "" {
+			ids = append(ids, uid)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestServer_Search(t *testing.T) {
+	conn := setupLDAPTest(t, Config{AllowAnonymous: true})
+
+	tests := []struct {
+		name     string
+		filter   string
+		expected []string
+	}{
+		{"presence", "(mail=*)", []string{"contacts/jane", "contacts/john"}},
+		{"equality ignores case", "(cn=john doe)", []string{"contacts/john"}},
+		{"substring initial", "(cn=Ja*)", []string{"contacts/jane"}},
+		{"substring any and final", "(mail=*@example.*m)", []string{"contacts/john"}},
+		{"telephone ignores separators", "(telephoneNumber=7778889999)", []string{"contacts/bob"}},
+		{"and", "(&(objectClass=inetOrgPerson)(sn=Roe))", []string{"contacts/jane"}},
+		{"or", "(|(givenName=John)(sn=Bob))", []string{"contacts/bob", "contacts/john"}},
+		{"not", "(&(objectClass=person)(!(mail=*)))", []string{"contacts/bob"}},
+		{"custom field", "(x-phonebook-employee-id=e42)", []string{"contacts/john"}},
+		{"no match", "(cn=nobody)", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := search(conn, DefaultBaseDN, ldap.ScopeWholeSubtree, 0, tt.filter)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+			if ids := uids(result); fmt.Sprint(ids) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected %v but got %v", tt.expected, ids)
+			}
+		})
+	}
+}
+
+func TestServer_SearchEntry(t *testing.T) {
+	conn := setupLDAPTest(t, Config{AllowAnonymous: true})
+
+	result, err := search(conn, "uid=contacts/john,"+DefaultBaseDN, ldap.ScopeBaseObject, 0, "(objectClass=*)")
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if len(result.Entries) != 1 {
+		t.Fatalf("Expected 1 entry but got %d", len(result.Entries))
+	}
+	e := result.Entries[0]
+	expected := map[string]string{
+		"cn":                      "John Doe",
+		"sn":                      "Doe",
+		"givenName":               "John",
+		"telephoneNumber":         "123-456-7890",
+		"mail":                    "john@example.com",
+		"postalAddress":           "123 Main St",
+		"x-phonebook-employee-id": "E42",
+	}
+	for name, value := range expected {
+		if got := e.GetAttributeValue(name); got != value {
+			t.Errorf("Expected %s=%q but got %q", name, value, got)
+		}
+	}
+
+	if _, err := search(conn, "uid=contacts/missing,"+DefaultBaseDN, ldap.ScopeBaseObject, 0, "(objectClass=*)"); !ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		t.Errorf("Expected noSuchObject but got %v", err)
+	}
+	if _, err := search(conn, "dc=elsewhere", ldap.ScopeWholeSubtree, 0, "(objectClass=*)"); !ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		t.Errorf("Expected noSuchObject but got %v", err)
+	}
+}
+
+func TestServer_SizeLimit(t *testing.T) {
+	conn := setupLDAPTest(t, Config{AllowAnonymous: true, MaxSizeLimit: 2})
+
+	tests := []struct {
+		name      string
+		sizeLimit int
+		expected  int
+	}{
+		{"client limit", 1, 1},
+		{"server limit", 0, 2},
+		{"client limit above server limit", 10, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := search(conn, DefaultBaseDN, ldap.ScopeSingleLevel, tt.sizeLimit, "(objectClass=inetOrgPerson)")
+			if !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+				t.Errorf("Expected sizeLimitExceeded but got %v", err)
+			}
+			if result == nil || len(result.Entries) != tt.expected {
+				t.Errorf("Expected %d entries but got %+v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestServer_Bind(t *testing.T) {
+	conn := setupLDAPTest(t, Config{Users: map[string]string{testAdminDN: "secret"}})
+
+	// Searching needs a bind when anonymous access is off
+	if _, err := search(conn, DefaultBaseDN, ldap.ScopeWholeSubtree, 0, "(cn=*)"); !ldap.IsErrorWithCode(err, ldap.LDAPResultInsufficientAccessRights) {
+		t.Errorf("Expected insufficientAccessRights but got %v", err)
+	}
+	if err := conn.UnauthenticatedBind(""); err == nil {
+		t.Error("Expected anonymous bind to fail")
+	}
+	if err := conn.Bind(testAdminDN, "wrong"); !ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		t.Errorf("Expected invalidCredentials but got %v", err)
+	}
+	if err := conn.Bind("CN=Admin,DC=phonebook", "secret"); err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+
+	result, err := search(conn, DefaultBaseDN, ldap.ScopeSingleLevel, 0, "(cn=*)")
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if len(result.Entries) != 3 {
+		t.Errorf("Expected 3 entries but got %d", len(result.Entries))
+	}
+}
+
+func TestServer_Auth(t *testing.T) {
+	auth := application.NewAuthService(database.NewInMemoryDatabase())
+	auth.CreateUser(domain.User{Name: "alice", Role: domain.RoleViewer})
+	auth.CreateUser(domain.User{Name: "bob"})
+	_, _, aliceKey, _ := auth.CreateAPIKey("alice", "")
+	_, _, bobKey, _ := auth.CreateAPIKey("bob", "")
+	conn := setupLDAPTest(t, Config{Auth: auth, AllowAnonymous: true})
+
+	// Anonymous access is off whatever the config says
+	if _, err := search(conn, DefaultBaseDN, ldap.ScopeSingleLevel, 0, "(cn=*)"); !ldap.IsErrorWithCode(err, ldap.LDAPResultInsufficientAccessRights) {
+		t.Errorf("Expected insufficientAccessRights but got %v", err)
+	}
+	if err := conn.Bind("uid=bob,dc=phonebook", aliceKey); !ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		t.Errorf("Expected another user's key to be refused but got %v", err)
+	}
+
+	// Searches only return what the bound user may read
+	if err := conn.Bind("uid=bob,dc=phonebook", bobKey); err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if result, err := search(conn, DefaultBaseDN, ldap.ScopeSingleLevel, 0, "(cn=*)"); err == nil && len(result.Entries) != 0 {
+		t.Errorf("Expected no entries for a user without roles but got %d", len(result.Entries))
+	}
+
+	if err := conn.Bind("cn=alice,dc=phonebook", aliceKey); err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	result, err := search(conn, DefaultBaseDN, ldap.ScopeSingleLevel, 0, "(cn=*)")
+	if err != nil {
+		t.Fatalf("Expected success but got error: %v", err)
+	}
+	if len(result.Entries) != 3 {
+		t.Errorf("Expected 3 entries but got %d", len(result.Entries))
+	}
+}
+
+func TestServer_ReadOnly(t *testing.T) {
+	conn := setupLDAPTest(t, Config{AllowAnonymous: true})
+
+	err := conn.Del(ldap.NewDelRequest("uid=contacts/john,"+DefaultBaseDN, nil))
+	if !ldap.IsErrorWithCode(err, ldap.LDAPResultUnwillingToPerform) {
+		t.Errorf("Expected unwillingToPerform but got %v", err)
+	}
+}
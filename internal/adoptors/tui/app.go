@@ -0,0 +1,307 @@
+// Package tui is an interactive terminal interface for browsing and editing
+// the phonebook.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+// DefaultRefreshInterval is how often the list is reloaded when the database
+// cannot be watched for changes.
+const DefaultRefreshInterval = 5 * time.Second
+
+const (
+	mainPage   = "main"
+	formPage   = "form"
+	deletePage = "delete"
+	allGroups  = "All contacts"
+	helpText   = "[yellow]/[-] search  [yellow]g[-] group  [yellow]a[-] add  [yellow]e[-] edit  [yellow]d[-] delete  [yellow]r[-] refresh  [yellow]q[-] quit"
+)
+
+type App struct {
+	phonebook       *application.PhonebookService
+	refreshInterval time.Duration
+
+	app    *tview.Application
+	pages  *tview.Pages
+	search *tview.InputField
+	groups *tview.DropDown
+	table  *tview.Table
+	detail *tview.TextView
+	status *tview.TextView
+
+	query    string
+	group    string
+	ids      []string
+	contacts map[string]domain.Contact
+}
+
+func NewApp(phonebook *application.PhonebookService, refreshInterval time.Duration) *App {
+	if refreshInterval <= 0 {
+		refreshInterval = DefaultRefreshInterval
+	}
+	a := &App{
+		phonebook:       phonebook,
+		refreshInterval: refreshInterval,
+		app:             tview.NewApplication(),
+		pages:           tview.NewPages(),
+		contacts:        make(map[string]domain.Contact),
+	}
+
+	a.search = tview.NewInputField().
+		SetLabel("Search: ").
+		SetChangedFunc(func(text string) {
+			a.query = text
+			a.reload()
+		}).
+		SetDoneFunc(func(tcell.Key) {
+			a.app.SetFocus(a.table)
+		})
+
+	a.groups = tview.NewDropDown().SetLabel("Group: ")
+	a.groups.SetDoneFunc(func(tcell.Key) {
+		a.app.SetFocus(a.table)
+	})
+
+	a.table = tview.NewTable().
+		SetSelectable(true, false).
+		SetFixed(1, 0).
+		SetSelectionChangedFunc(func(row, column int) {
+			a.showDetail()
+		})
+	a.table.SetBorder(true).SetTitle(" Contacts ")
+	a.table.SetInputCapture(a.handleKey)
+
+	a.detail = tview.NewTextView().SetDynamicColors(true).SetWrap(true)
+	a.detail.SetBorder(true).SetTitle(" Details ")
+
+	a.status = tview.NewTextView().SetDynamicColors(true).SetText(helpText)
+
+	filters := tview.NewFlex().
+		AddItem(a.search, 0, 2, false).
+		AddItem(a.groups, 0, 1, false)
+	body := tview.NewFlex().
+		AddItem(a.table, 0, 3, true).
+		AddItem(a.detail, 0, 2, false)
+	main := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(filters, 1, 0, false).
+		AddItem(body, 0, 1, true).
+		AddItem(a.status, 1, 0, false)
+
+	a.pages.AddPage(mainPage, main, true, true)
+	a.app.SetRoot(a.pages, true).SetFocus(a.table)
+
+	a.reloadGroups()
+	a.reload()
+	return a
+}
+
+// Run shows the interface until the user quits or ctx is cancelled. The list
+// follows changes made elsewhere, by watching the database when it supports
+// it and by polling otherwise.
+func (a *App) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go a.follow(ctx)
+	go func() {
+		<-ctx.Done()
+		a.app.Stop()
+	}()
+	return a.app.Run()
+}
+
+func (a *App) follow(ctx context.Context) {
+	refresh := func() {
+		a.app.QueueUpdateDraw(func() {
+			a.reloadGroups()
+			a.reload()
+		})
+	}
+
+	if success, _, events := a.phonebook.WatchContacts(ctx, "", ""); success {
+		for range events {
+			refresh()
+		}
+		return
+	}
+
+	ticker := time.NewTicker(a.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
+
+func (a *App) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	switch {
+	case event.Key() == tcell.KeyEnter:
+		a.showEditForm(a.selected())
+	case event.Rune() == '/':
+		a.app.SetFocus(a.search)
+	case event.Rune() == 'g':
+		a.app.SetFocus(a.groups)
+	case event.Rune() == 'a':
+		a.showAddForm()
+	case event.Rune() == 'e':
+		a.showEditForm(a.selected())
+	case event.Rune() == 'd':
+		a.confirmDelete(a.selected())
+	case event.Rune() == 'r':
+		a.reloadGroups()
+		a.reload()
+	case event.Rune() == 'q':
+		a.app.Stop()
+	default:
+		return event
+	}
+	return nil
+}
+
+// reload lists the contacts matching the search and group filter, keeping
+// the selected contact selected.
+func (a *App) reload() {
+	selected := a.selected()
+
+	success, message, phonebook := a.phonebook.SearchContacts(a.query, a.group)
+	if !success {
+		a.setError(message)
+		return
+	}
+
+	a.contacts = phonebook.Contacts
+	a.ids = make([]string, 0, len(phonebook.Contacts))
+	for id := range phonebook.Contacts {
+		a.ids = append(a.ids, id)
+	}
+	sort.Strings(a.ids)
+
+	a.table.Clear()
+	for column, title := range []string{"ID", "Name", "Phone", "Email"} {
+		a.table.SetCell(0, column, tview.NewTableCell(title).SetSelectable(false).SetTextColor(tcell.ColorYellow))
+	}
+	row := 1
+	for i, id := range a.ids {
+		contact := a.contacts[id]
+		a.table.SetCell(i+1, 0, tview.NewTableCell(id))
+		a.table.SetCell(i+1, 1, tview.NewTableCell(contact.Name).SetExpansion(1))
+		a.table.SetCell(i+1, 2, tview.NewTableCell(contact.Phone))
+		a.table.SetCell(i+1, 3, tview.NewTableCell(contact.Email))
+		if id == selected {
+			row = i + 1
+		}
+	}
+	a.table.Select(row, 0)
+	a.showDetail()
+}
+
+// reloadGroups fills the group filter. Databases without groups only offer
+// every contact.
+func (a *App) reloadGroups() {
+	options := []string{allGroups}
+	if success, _, groups := a.phonebook.ListGroups(); success {
+		options = append(options, groups...)
+	}
+
+	current := 0
+	for i, option := range options {
+		if option == a.group {
+			current = i
+		}
+	}
+	if current == 0 {
+		a.group = ""
+	}
+
+	a.groups.SetOptions(options, func(option string, index int) {
+		group := option
+		if index == 0 {
+			group = ""
+		}
+		if group != a.group {
+			a.group = group
+			a.reload()
+		}
+	})
+	a.groups.SetCurrentOption(current)
+}
+
+func (a *App) selected() string {
+	row, _ := a.table.GetSelection()
+	if row < 1 || row > len(a.ids) {
+		return ""
+	}
+	return a.ids[row-1]
+}
+
+func (a *App) showDetail() {
+	id := a.selected()
+	if id == "" {
+		a.detail.SetText("[gray]No contact selected")
+		return
+	}
+
+	contact := a.contacts[id]
+	var text strings.Builder
+	fmt.Fprintf(&text, "[yellow]ID[-]       %s\n", tview.Escape(id))
+	fmt.Fprintf(&text, "[yellow]Name[-]     %s\n", tview.Escape(contact.Name))
+	fmt.Fprintf(&text, "[yellow]Phone[-]    %s\n", tview.Escape(contact.Phone))
+	fmt.Fprintf(&text, "[yellow]Email[-]    %s\n", tview.Escape(contact.Email))
+	fmt.Fprintf(&text, "[yellow]Address[-]  %s\n", tview.Escape(contact.Address))
+	if success, _, groups := a.phonebook.GroupsOfContact(id); success && len(groups) > 0 {
+		fmt.Fprintf(&text, "[yellow]Groups[-]   %s\n", tview.Escape(strings.Join(groups, ", ")))
+	}
+	a.detail.SetText(text.String())
+}
+
+func (a *App) confirmDelete(id string) {
+	if id == "" {
+		return
+	}
+
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("Move %s (%s) to the trash?", a.contacts[id].Name, id)).
+		AddButtons([]string{"Delete", "Cancel"}).
+		SetDoneFunc(func(index int, label string) {
+			a.closePage(deletePage)
+			if label != "Delete" {
+				return
+			}
+			if success, message := a.phonebook.DeleteContact(id); !success {
+				a.setError(message)
+				return
+			}
+			a.setInfo(fmt.Sprintf("Deleted %s", id))
+			a.reload()
+		})
+	a.pages.AddPage(deletePage, modal, true, true)
+	a.app.SetFocus(modal)
+}
+
+func (a *App) closePage(name string) {
+	a.pages.RemovePage(name)
+	a.app.SetFocus(a.table)
+}
+
+func (a *App) setError(message string) {
+	a.status.SetText("[red]" + tview.Escape(message) + "[-]  " + helpText)
+}
+
+func (a *App) setInfo(message string) {
+	a.status.SetText("[green]" + tview.Escape(message) + "[-]  " + helpText)
+}
@@ -0,0 +1,188 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/Businge931/practice-interfaces/internal/adoptors/database"
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+func setupTUITest(t *testing.T) (*App, *application.PhonebookService) {
+	phonebook := application.NewPhonebookService(database.NewInMemoryDatabase())
+	phonebook.AddContact("contacts/john", domain.Contact{Name: "John Doe", Phone: "123-456-7890", Email: "john@example.com"})
+	phonebook.AddContact("contacts/jane", domain.Contact{Name: "Jane Roe", Phone: "555-000-1111"})
+	phonebook.CreateGroup("family")
+	phonebook.AddToGroup("family", "contacts/jane")
+	return NewApp(phonebook, 0), phonebook
+}
+
+func pressKey(a *App, key tcell.Key, r rune) {
+	event := tcell.NewEventKey(key, r, tcell.ModNone)
+	a.app.GetFocus().InputHandler()(event, func(p tview.Primitive) { a.app.SetFocus(p) })
+}
+
+func formInput(t *testing.T, a *App, label string) *tview.InputField {
+	_, page := a.pages.GetFrontPage()
+	form := page.(*tview.Flex).GetItem(0).(*tview.Form)
+	field, ok := form.GetFormItemByLabel(label).(*tview.InputField)
+	if !ok {
+		t.Fatalf("Expected a %q field in the form", label)
+	}
+	return field
+}
+
+func pressButton(a *App, label string) {
+	_, page := a.pages.GetFrontPage()
+	form := page.(*tview.Flex).GetItem(0).(*tview.Form)
+	button := form.GetButton(form.GetButtonIndex(label))
+	button.InputHandler()(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone), nil)
+}
+
+func errorText(a *App) string {
+	_, page := a.pages.GetFrontPage()
+	return page.(*tview.Flex).GetItem(1).(*tview.TextView).GetText(true)
+}
+
+func TestApp_Filters(t *testing.T) {
+	a, _ := setupTUITest(t)
+
+	tests := []struct {
+		name     string
+		query    string
+		group    int
+		expected []string
+	}{
+		{"all", "", 0, []string{"contacts/jane", "contacts/john"}},
+		{"search", "john", 0, []string{"contacts/john"}},
+		{"group", "", 1, []string{"contacts/jane"}},
+		{"search in group", "john", 1, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a.groups.SetCurrentOption(tt.group)
+			a.search.SetText(tt.query)
+			if len(a.ids) != len(tt.expected) {
+				t.Fatalf("Expected %v but got %v", tt.expected, a.ids)
+			}
+			for i, id := range tt.expected {
+				if a.ids[i] != id {
+					t.Errorf("Expected %v but got %v", tt.expected, a.ids)
+				}
+			}
+		})
+	}
+}
+
+func TestApp_KeepsSelection(t *testing.T) {
+	a, phonebook := setupTUITest(t)
+
+	a.table.Select(2, 0)
+	if a.selected() != "contacts/john" {
+		t.Fatalf("Expected contacts/john to be selected but got %q", a.selected())
+	}
+
+	phonebook.AddContact("contacts/adam", domain.Contact{Name: "Adam", Phone: "111"})
+	a.reload()
+	if a.selected() != "contacts/john" {
+		t.Errorf("Expected the selection to follow contacts/john but got %q", a.selected())
+	}
+	if detail := a.detail.GetText(true); !strings.Contains(detail, "John Doe") {
+		t.Errorf("Expected details of John Doe but got %q", detail)
+	}
+}
+
+func TestApp_AddContact(t *testing.T) {
+	a, phonebook := setupTUITest(t)
+
+	pressKey(a, tcell.KeyRune, 'a')
+	if errorText(a) != domain.ErrInvalidContactName.Error() {
+		t.Errorf("Expected %q for an empty form but got %q", domain.ErrInvalidContactName.Error(), errorText(a))
+	}
+
+	formInput(t, a, "ID").SetText("contacts/bob")
+	formInput(t, a, "Name").SetText("Bob")
+	if errorText(a) != domain.ErrInvalidContactNumber.Error() {
+		t.Errorf("Expected %q without a phone but got %q", domain.ErrInvalidContactNumber.Error(), errorText(a))
+	}
+
+	// Invalid contacts are not saved
+	pressButton(a, "Save")
+	if success, _, _ := phonebook.GetContact("contacts/bob"); success {
+		t.Fatal("Expected the invalid contact not to be saved")
+	}
+
+	formInput(t, a, "Phone").SetText("777-888-9999")
+	if errorText(a) != "" {
+		t.Errorf("Expected no error but got %q", errorText(a))
+	}
+	pressButton(a, "Save")
+
+	success, msg, contact := phonebook.GetContact("contacts/bob")
+	if !success || contact.Phone != "777-888-9999" {
+		t.Fatalf("Expected the contact to be saved but got %+v (%s)", contact, msg)
+	}
+	if name, _ := a.pages.GetFrontPage(); name != mainPage {
+		t.Errorf("Expected the form to close but %q is shown", name)
+	}
+	if len(a.ids) != 3 {
+		t.Errorf("Expected 3 contacts listed but got %v", a.ids)
+	}
+}
+
+func TestApp_EditContact(t *testing.T) {
+	a, phonebook := setupTUITest(t)
+
+	a.table.Select(2, 0)
+	pressKey(a, tcell.KeyEnter, 0)
+	if formInput(t, a, "Name").GetText() != "John Doe" {
+		t.Fatalf("Expected the form to show John Doe but got %q", formInput(t, a, "Name").GetText())
+	}
+
+	formInput(t, a, "Email").SetText("johnny@example.com")
+	pressButton(a, "Save")
+
+	if _, _, contact := phonebook.GetContact("contacts/john"); contact.Email != "johnny@example.com" {
+		t.Errorf("Expected the email to be updated but got %+v", contact)
+	}
+	if a.contacts["contacts/john"].Email != "johnny@example.com" {
+		t.Errorf("Expected the list to be reloaded but got %+v", a.contacts["contacts/john"])
+	}
+}
+
+func TestApp_DeleteContact(t *testing.T) {
+	tests := []struct {
+		name    string
+		button  tcell.Key
+		deleted bool
+	}{
+		{"confirm", tcell.KeyEnter, true},
+		{"cancel", tcell.KeyEscape, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, phonebook := setupTUITest(t)
+
+			a.table.Select(2, 0)
+			pressKey(a, tcell.KeyRune, 'd')
+			if name, _ := a.pages.GetFrontPage(); name != deletePage {
+				t.Fatalf("Expected a confirmation but %q is shown", name)
+			}
+			pressKey(a, tt.button, 0)
+
+			success, _, _ := phonebook.GetContact("contacts/john")
+			if success == tt.deleted {
+				t.Errorf("Expected deleted=%v but the contact exists=%v", tt.deleted, success)
+			}
+			if name, _ := a.pages.GetFrontPage(); name != mainPage {
+				t.Errorf("Expected the confirmation to close but %q is shown", name)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/rivo/tview"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+// contactForm edits a contact, validating it on every keystroke.
+type contactForm struct {
+	form   *tview.Form
+	errors *tview.TextView
+	layout *tview.Flex
+}
+
+func (a *App) showAddForm() {
+	a.showForm("Add contact", "", domain.Contact{}, func(id string, contact domain.Contact) (bool, string) {
+		return a.phonebook.AddContact(id, contact)
+	})
+}
+
+func (a *App) showEditForm(id string) {
+	if id == "" {
+		return
+	}
+	a.showForm("Edit "+id, id, a.contacts[id], func(_ string, contact domain.Contact) (bool, string) {
+		return a.phonebook.UpdateContact(id, contact)
+	})
+}
+
+// showForm opens the contact form. The id can only be chosen when adding.
+func (a *App) showForm(title, id string, contact domain.Contact, save func(id string, contact domain.Contact) (bool, string)) {
+	f := &contactForm{
+		form:   tview.NewForm(),
+		errors: tview.NewTextView().SetDynamicColors(true),
+	}
+
+	adding := id == ""
+	values := func() (string, domain.Contact) {
+		value := func(label string) string {
+			return f.form.GetFormItemByLabel(label).(*tview.InputField).GetText()
+		}
+		formID := id
+		if adding {
+			formID = value("ID")
+		}
+		return formID, domain.Contact{
+			Name:    value("Name"),
+			Phone:   value("Phone"),
+			Email:   value("Email"),
+			Address: value("Address"),
+		}
+	}
+	validate := func() bool {
+		formID, contact := values()
+		if formID == "" {
+			f.errors.SetText("[red]" + domain.ErrMissingContactID.Error())
+			return false
+		}
+		if err := a.phonebook.ValidateContact(contact); err != nil {
+			f.errors.SetText("[red]" + tview.Escape(err.Error()))
+			return false
+		}
+		f.errors.SetText("")
+		return true
+	}
+	changed := func(string) { validate() }
+
+	if adding {
+		f.form.AddInputField("ID", "contacts/", 40, nil, changed)
+	}
+	f.form.
+		AddInputField("Name", contact.Name, 40, nil, changed).
+		AddInputField("Phone", contact.Phone, 20, nil, changed).
+		AddInputField("Email", contact.Email, 40, nil, changed).
+		AddInputField("Address", contact.Address, 60, nil, changed).
+		AddButton("Save", func() {
+			if !validate() {
+				return
+			}
+			formID, contact := values()
+			if success, message := save(formID, contact); !success {
+				f.errors.SetText("[red]" + tview.Escape(message))
+				return
+			}
+			a.closePage(formPage)
+			a.setInfo(fmt.Sprintf("Saved %s", formID))
+			a.reload()
+		}).
+		AddButton("Cancel", func() {
+			a.closePage(formPage)
+		}).
+		SetCancelFunc(func() {
+			a.closePage(formPage)
+		})
+	f.form.SetBorder(true).SetTitle(" " + title + " ")
+
+	f.layout = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(f.form, 0, 1, true).
+		AddItem(f.errors, 1, 0, false)
+
+	validate()
+	a.pages.AddPage(formPage, f.layout, true, true)
+	a.app.SetFocus(f.form)
+}
@@ -0,0 +1,46 @@
+package webui
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+)
+
+const (
+	csrfCookie = "phonebook_csrf"
+	csrfField  = "csrf_token"
+)
+
+// csrfToken returns the token of the client, issuing a new one in a cookie
+// when it has none. Forms echo it back in a hidden field.
+func csrfToken(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(csrfCookie); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token
+}
+
+// checkCSRF rejects state-changing requests whose form token does not match
+// the cookie. The form must already be parsed.
+func checkCSRF(r *http.Request) bool {
+	cookie, err := r.Cookie(csrfCookie)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	token := r.PostFormValue(csrfField)
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(token)) == 1
+}
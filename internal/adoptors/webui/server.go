@@ -0,0 +1,517 @@
+// Package webui is a browser interface to the phonebook. Templates and
+// styles are embedded in the binary, so it needs no external assets.
+package webui
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Businge931/practice-interfaces/internal/adoptors/httpauth"
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+const (
+	// PageSize is how many contacts the list shows per page.
+	PageSize = 25
+	// MaxUploadSize bounds the size of an import upload.
+	MaxUploadSize = 10 << 20
+)
+
+//go:embed templates/*.html static/*
+var assets embed.FS
+
+type Handler struct {
+	phonebook *application.PhonebookService
+	auth      *application.AuthService
+	mux       *http.ServeMux
+	pages     map[string]*template.Template
+	handler   http.Handler
+}
+
+type Option func(*Handler)
+
+// WithAuth makes every request authenticate against auth and act for the
+// caller, so a phonebook with authorization enabled checks what they may do.
+func WithAuth(auth *application.AuthService) Option {
+	return func(h *Handler) {
+		h.auth = auth
+	}
+}
+
+func NewHandler(phonebook *application.PhonebookService, opts ...Option) *Handler {
+	h := &Handler{
+		phonebook: phonebook,
+		mux:       http.NewServeMux(),
+		pages:     make(map[string]*template.Template),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.handler = http.HandlerFunc(h.serve)
+	if h.auth != nil {
+		h.handler = httpauth.Handler(h.auth, "phonebook", h.handler)
+	}
+
+	layout := template.Must(template.New("layout.html").ParseFS(assets, "templates/layout.html"))
+	for _, page := range []string{"list.html", "form.html", "import.html"} {
+		h.pages[page] = template.Must(template.Must(layout.Clone()).ParseFS(assets, "templates/"+page))
+	}
+
+	static, _ := fs.Sub(assets, "static")
+	h.mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.FS(static))))
+	h.mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/contacts", http.StatusFound)
+	})
+	h.mux.HandleFunc("GET /contacts", h.list)
+	h.mux.HandleFunc("GET /contacts/new", h.newForm)
+	h.mux.HandleFunc("POST /contacts/new", h.create)
+	h.mux.HandleFunc("GET /contacts/edit", h.editForm)
+	h.mux.HandleFunc("POST /contacts/edit", h.update)
+	h.mux.HandleFunc("POST /contacts/delete", h.delete)
+	h.mux.HandleFunc("GET /contacts/photo", h.photo)
+	h.mux.HandleFunc("POST /contacts/photo", h.uploadPhoto)
+	h.mux.HandleFunc("POST /contacts/photo/delete", h.deletePhoto)
+	h.mux.HandleFunc("GET /contacts/attachment", h.attachment)
+	h.mux.HandleFunc("POST /contacts/attachments", h.uploadAttachment)
+	h.mux.HandleFunc("POST /contacts/attachments/delete", h.deleteAttachment)
+	h.mux.HandleFunc("GET /import", h.importForm)
+	h.mux.HandleFunc("POST /import", h.importUpload)
+	h.mux.HandleFunc("GET /export", h.export)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.ServeHTTP(w, r)
+}
+
+// serve rejects state-changing requests without a valid CSRF token before
+// routing them.
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize)
+		if !checkCSRF(r) {
+			http.Error(w, "invalid CSRF token", http.StatusForbidden)
+			return
+		}
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// service returns the phonebook acting for the caller of r.
+func (h *Handler) service(r *http.Request) *application.PhonebookService {
+	if h.auth == nil {
+		return h.phonebook
+	}
+	return h.phonebook.As(r.Context())
+}
+
+type listPage struct {
+	Query    string
+	Notice   string
+	Error    string
+	Total    int
+	Page     int
+	Pages    int
+	Contacts []listedContact
+	PrevURL  string
+	NextURL  string
+}
+
+type listedContact struct {
+	ID string
+	domain.Contact
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	data := listPage{Query: query, Notice: r.URL.Query().Get("notice"), Page: page}
+	success, message, phonebook := h.service(r).SearchContacts(query, "")
+	if !success {
+		data.Error = message
+		h.render(w, r, http.StatusInternalServerError, "list.html", data)
+		return
+	}
+
+	ids := make([]string, 0, len(phonebook.Contacts))
+	for id := range phonebook.Contacts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	data.Total = len(ids)
+	data.Pages = (len(ids) + PageSize - 1) / PageSize
+	if data.Pages == 0 {
+		data.Pages = 1
+	}
+	if page > data.Pages {
+		page = data.Pages
+		data.Page = page
+	}
+	start := (page - 1) * PageSize
+	end := min(start+PageSize, len(ids))
+	for _, id := range ids[start:end] {
+		data.Contacts = append(data.Contacts, listedContact{ID: id, Contact: phonebook.Contacts[id]})
+	}
+	if page > 1 {
+		data.PrevURL = listURL(query, page-1)
+	}
+	if page < data.Pages {
+		data.NextURL = listURL(query, page+1)
+	}
+
+	h.render(w, r, http.StatusOK, "list.html", data)
+}
+
+func listURL(query string, page int) string {
+	values := url.Values{}
+	if query != "" {
+		values.Set("q", query)
+	}
+	values.Set("page", strconv.Itoa(page))
+	return "/contacts?" + values.Encode()
+}
+
+type formPage struct {
+	Editing bool
+	ID      string
+	Contact domain.Contact
+	Error   string
+	// Blobs is set when the database can store photos and attachments.
+	Blobs       bool
+	Photo       *domain.Photo
+	Attachments []domain.Attachment
+	// Fields are the phonebook's custom fields with the contact's values.
+	Fields []customInput
+}
+
+type customInput struct {
+	domain.FieldDefinition
+	Value string
+}
+
+// customFormPrefix names the form inputs of custom fields.
+const customFormPrefix = "custom."
+
+// renderForm renders the contact form with an input for every custom field.
+func (h *Handler) renderForm(w http.ResponseWriter, r *http.Request, status int, data formPage) {
+	if success, _, definitions := h.service(r).FieldDefinitions(); success {
+		for _, definition := range definitions {
+			data.Fields = append(data.Fields, customInput{definition, data.Contact.Custom[definition.Name]})
+		}
+	}
+	h.render(w, r, status, "form.html", data)
+}
+
+func (h *Handler) newForm(w http.ResponseWriter, r *http.Request) {
+	h.renderForm(w, r, http.StatusOK, formPage{ID: "contacts/"})
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	data := formPage{ID: strings.TrimSpace(r.PostFormValue("id")), Contact: contactFromForm(r)}
+	if data.ID == "" {
+		data.Error = domain.ErrMissingContactID.Error()
+		h.renderForm(w, r, http.StatusUnprocessableEntity, data)
+		return
+	}
+	if err := h.service(r).ValidateContact(data.Contact); err != nil {
+		data.Error = err.Error()
+		h.renderForm(w, r, http.StatusUnprocessableEntity, data)
+		return
+	}
+	if success, message := h.service(r).AddContact(data.ID, data.Contact); !success {
+		data.Error = message
+		h.renderForm(w, r, http.StatusConflict, data)
+		return
+	}
+	redirectNotice(w, r, "Added "+data.ID)
+}
+
+func (h *Handler) editForm(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	success, message, contact := h.service(r).GetContact(id)
+	if !success {
+		http.Error(w, message, http.StatusNotFound)
+		return
+	}
+	data := formPage{Editing: true, ID: id, Contact: contact}
+	if success, _, attachments := h.service(r).Attachments(id); success {
+		data.Blobs = true
+		data.Attachments = attachments
+	}
+	if success, _, photo := h.service(r).Photo(id); success {
+		data.Photo = &photo
+	}
+	h.renderForm(w, r, http.StatusOK, data)
+}
+
+func (h *Handler) update(w http.ResponseWriter, r *http.Request) {
+	data := formPage{Editing: true, ID: r.URL.Query().Get("id"), Contact: contactFromForm(r)}
+	if err := h.service(r).ValidateContact(data.Contact); err != nil {
+		data.Error = err.Error()
+		h.renderForm(w, r, http.StatusUnprocessableEntity, data)
+		return
+	}
+	if success, message := h.service(r).UpdateContact(data.ID, data.Contact); !success {
+		data.Error = message
+		h.renderForm(w, r, http.StatusNotFound, data)
+		return
+	}
+	redirectNotice(w, r, "Saved "+data.ID)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if success, message := h.service(r).DeleteContact(id); !success {
+		http.Error(w, message, http.StatusNotFound)
+		return
+	}
+	redirectNotice(w, r, "Moved "+id+" to the trash")
+}
+
+func (h *Handler) photo(w http.ResponseWriter, r *http.Request) {
+	thumbnail := r.URL.Query().Get("size") == "thumbnail"
+	success, message, blob, content := h.service(r).PhotoContent(r.URL.Query().Get("id"), thumbnail)
+	if !success {
+		http.Error(w, message, blobStatus(message))
+		return
+	}
+	serveBlob(w, r, blob, content)
+}
+
+func (h *Handler) uploadPhoto(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	_, content, ok := formFile(w, r)
+	if !ok {
+		return
+	}
+	if success, message, _ := h.service(r).SetPhoto(id, content); !success {
+		http.Error(w, message, blobStatus(message))
+		return
+	}
+	redirectEdit(w, r, id)
+}
+
+func (h *Handler) deletePhoto(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if success, message := h.service(r).RemovePhoto(id); !success {
+		http.Error(w, message, blobStatus(message))
+		return
+	}
+	redirectEdit(w, r, id)
+}
+
+// attachment downloads an attachment. It is never shown inline, so uploaded
+// HTML cannot run in the UI's origin.
+func (h *Handler) attachment(w http.ResponseWriter, r *http.Request) {
+	success, message, attachment, content := h.service(r).AttachmentContent(r.URL.Query().Get("id"), r.URL.Query().Get("name"))
+	if !success {
+		http.Error(w, message, blobStatus(message))
+		return
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}))
+	serveBlob(w, r, attachment.Blob, content)
+}
+
+func (h *Handler) uploadAttachment(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	name, content, ok := formFile(w, r)
+	if !ok {
+		return
+	}
+	if success, message, _ := h.service(r).AddAttachment(id, name, content); !success {
+		http.Error(w, message, blobStatus(message))
+		return
+	}
+	redirectEdit(w, r, id)
+}
+
+func (h *Handler) deleteAttachment(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if success, message := h.service(r).RemoveAttachment(id, r.URL.Query().Get("name")); !success {
+		http.Error(w, message, blobStatus(message))
+		return
+	}
+	redirectEdit(w, r, id)
+}
+
+// formFile reads the uploaded file, replying with an error if there is none.
+func formFile(w http.ResponseWriter, r *http.Request) (string, []byte, bool) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "choose a file to upload", http.StatusBadRequest)
+		return "", nil, false
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "failed to read the upload", http.StatusBadRequest)
+		return "", nil, false
+	}
+	return path.Base(header.Filename), content, true
+}
+
+// serveBlob writes content with its sniffed type. Blobs never change, so the
+// hash is a strong ETag.
+func serveBlob(w http.ResponseWriter, r *http.Request, blob domain.Blob, content []byte) {
+	w.Header().Set("Content-Type", blob.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", strconv.Quote(blob.Hash))
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+}
+
+func blobStatus(message string) int {
+	switch message {
+	case domain.ErrBlobTooLarge.Error():
+		return http.StatusRequestEntityTooLarge
+	case domain.ErrUnsupportedPhoto.Error(), domain.ErrInvalidAttachmentName.Error():
+		return http.StatusBadRequest
+	case domain.ErrBlobsNotSupported.Error():
+		return http.StatusNotImplemented
+	}
+	return http.StatusNotFound
+}
+
+type importPage struct {
+	Error    string
+	Imported int
+	Failed   []domain.ImportResult
+	Done     bool
+}
+
+func (h *Handler) importForm(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, http.StatusOK, "import.html", importPage{})
+}
+
+// importUpload imports an uploaded CSV or vCard file, telling the formats
+// apart by the file extension.
+func (h *Handler) importUpload(w http.ResponseWriter, r *http.Request) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.render(w, r, http.StatusBadRequest, "import.html", importPage{Error: "Choose a file to import"})
+		return
+	}
+	defer file.Close()
+
+	overwrite := r.PostFormValue("overwrite") == "on"
+	var (
+		success bool
+		message string
+		results []domain.ImportResult
+	)
+	switch strings.ToLower(path.Ext(header.Filename)) {
+	case ".vcf", ".vcard":
+		success, message, results = h.service(r).ImportVCard(file, overwrite)
+	case ".csv":
+		success, message, results = h.service(r).ImportCSV(file, overwrite)
+	default:
+		h.render(w, r, http.StatusBadRequest, "import.html", importPage{Error: "Only .csv and .vcf files can be imported"})
+		return
+	}
+	if !success {
+		h.render(w, r, http.StatusBadRequest, "import.html", importPage{Error: message})
+		return
+	}
+
+	data := importPage{Done: true}
+	for _, result := range results {
+		if result.Success {
+			data.Imported++
+		} else {
+			data.Failed = append(data.Failed, result)
+		}
+	}
+	h.render(w, r, http.StatusOK, "import.html", data)
+}
+
+func (h *Handler) export(w http.ResponseWriter, r *http.Request) {
+	var (
+		buf         bytes.Buffer
+		success     bool
+		message     string
+		contentType string
+		filename    string
+	)
+	switch r.URL.Query().Get("format") {
+	case "vcf":
+		success, message = h.service(r).ExportVCard("", &buf)
+		contentType, filename = "text/vcard; charset=utf-8", "contacts.vcf"
+	case "", "csv":
+		success, message = h.service(r).ExportContacts("", &buf)
+		contentType, filename = "text/csv; charset=utf-8", "contacts.csv"
+	default:
+		http.Error(w, "unknown export format", http.StatusBadRequest)
+		return
+	}
+	if !success {
+		http.Error(w, message, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	buf.WriteTo(w)
+}
+
+// render executes a page inside the layout, handing it the CSRF token.
+func (h *Handler) render(w http.ResponseWriter, r *http.Request, status int, page string, data interface{}) {
+	var buf bytes.Buffer
+	err := h.pages[page].Execute(&buf, map[string]interface{}{
+		"CSRF": csrfToken(w, r),
+		"Page": data,
+	})
+	if err != nil {
+		log.Errorf("Failed to render %s: %v", page, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+func contactFromForm(r *http.Request) domain.Contact {
+	contact := domain.Contact{
+		Name:    strings.TrimSpace(r.PostFormValue("name")),
+		Phone:   strings.TrimSpace(r.PostFormValue("phone")),
+		Email:   strings.TrimSpace(r.PostFormValue("email")),
+		Address: strings.TrimSpace(r.PostFormValue("address")),
+	}
+	for key, values := range r.PostForm {
+		if name, ok := strings.CutPrefix(key, customFormPrefix); ok && len(values) > 0 {
+			if contact.Custom == nil {
+				contact.Custom = make(map[string]string)
+			}
+			contact.Custom[name] = strings.TrimSpace(values[0])
+		}
+	}
+	return contact
+}
+
+func redirectEdit(w http.ResponseWriter, r *http.Request, id string) {
+	http.Redirect(w, r, "/contacts/edit?id="+url.QueryEscape(id), http.StatusSeeOther)
+}
+
+func redirectNotice(w http.ResponseWriter, r *http.Request, notice string) {
+	http.Redirect(w, r, "/contacts?notice="+url.QueryEscape(notice), http.StatusSeeOther)
+}
@@ -0,0 +1,440 @@
+package webui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Businge931/practice-interfaces/internal/adoptors/database"
+	"github.com/Businge931/practice-interfaces/internal/application"
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+var testContact = domain.Contact{Name: "John Doe", Phone: "123-456-7890", Email: "john@example.com", Address: "123 Main St"}
+
+// testClient drives the handler like a browser, keeping the CSRF cookie.
+// Requests carry key as the basic authorization password when it is set.
+type testClient struct {
+	handler http.Handler
+	token   string
+	key     string
+}
+
+func setupWebTest(t *testing.T) (*testClient, *application.PhonebookService) {
+	phonebook := application.NewPhonebookService(database.NewInMemoryDatabase())
+	phonebook.AddContact("contacts/john", testContact)
+	c := &testClient{handler: NewHandler(phonebook)}
+
+	rec := c.get("/contacts")
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == csrfCookie {
+			c.token = cookie.Value
+		}
+	}
+	if c.token == "" {
+		t.Fatal("Expected a CSRF cookie")
+	}
+	return c, phonebook
+}
+
+func (c *testClient) get(target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if c.token != "" {
+		req.AddCookie(&http.Cookie{Name: csrfCookie, Value: c.token})
+	}
+	if c.key != "" {
+		req.SetBasicAuth("", c.key)
+	}
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func (c *testClient) post(target string, form url.Values) *httptest.ResponseRecorder {
+	if form.Get(csrfField) == "" {
+		form.Set(csrfField, c.token)
+	}
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: csrfCookie, Value: c.token})
+	if c.key != "" {
+		req.SetBasicAuth("", c.key)
+	}
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func (c *testClient) upload(filename, content string, overwrite bool) *httptest.ResponseRecorder {
+	fields := map[string]string{}
+	if overwrite {
+		fields["overwrite"] = "on"
+	}
+	return c.uploadTo("/import", filename, content, fields)
+}
+
+// uploadTo posts a multipart form with the file and fields to target.
+func (c *testClient) uploadTo(target, filename, content string, fields map[string]string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField(csrfField, c.token)
+	for name, value := range fields {
+		writer.WriteField(name, value)
+	}
+	part, _ := writer.CreateFormFile("file", filename)
+	io.WriteString(part, content)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, target, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.AddCookie(&http.Cookie{Name: csrfCookie, Value: c.token})
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler_List(t *testing.T) {
+	c, phonebook := setupWebTest(t)
+	for i := 0; i < PageSize+5; i++ {
+		phonebook.AddContact(fmt.Sprintf("contacts/extra%02d", i), domain.Contact{Name: fmt.Sprintf("Extra %02d", i), Phone: "555"})
+	}
+
+	tests := []struct {
+		name     string
+		target   string
+		contains []string
+		excludes []string
+	}{
+		{"first page", "/contacts", []string{"Extra 00", "Page 1 of 2", "page=2"}, []string{"John Doe", "Previous"}},
+		{"second page", "/contacts?page=2", []string{"John Doe", "Page 2 of 2", "Previous"}, []string{"Extra 00", "Next"}},
+		{"past the end", "/contacts?page=9", []string{"Page 2 of 2"}, nil},
+		{"search", "/contacts?q=john", []string{"John Doe", "1 contact<", "Page 1 of 1"}, []string{"Extra"}},
+		{"no match", "/contacts?q=nobody", []string{"No contacts found"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := c.get(tt.target)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected 200 but got %d", rec.Code)
+			}
+			body := rec.Body.String()
+			for _, want := range tt.contains {
+				if !strings.Contains(body, want) {
+					t.Errorf("Expected the page to contain %q", want)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(body, unwanted) {
+					t.Errorf("Expected the page not to contain %q", unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_CreateAndEdit(t *testing.T) {
+	c, phonebook := setupWebTest(t)
+
+	tests := []struct {
+		name     string
+		target   string
+		form     url.Values
+		expected int
+		contains string
+	}{
+		{"missing id", "/contacts/new", url.Values{"name": {"Bob"}, "phone": {"1"}}, http.StatusUnprocessableEntity, domain.ErrMissingContactID.Error()},
+		{"missing name", "/contacts/new", url.Values{"id": {"contacts/bob"}, "phone": {"1"}}, http.StatusUnprocessableEntity, domain.ErrInvalidContactName.Error()},
+		{"duplicate", "/contacts/new", url.Values{"id": {"contacts/john"}, "name": {"John"}, "phone": {"1"}}, http.StatusConflict, "John"},
+		{"create", "/contacts/new", url.Values{"id": {"contacts/bob"}, "name": {"Bob <b>"}, "phone": {"777"}}, http.StatusSeeOther, ""},
+		{"invalid edit", "/contacts/edit?id=contacts/john", url.Values{"name": {"John"}}, http.StatusUnprocessableEntity, domain.ErrInvalidContactNumber.Error()},
+		{"edit", "/contacts/edit?id=contacts/john", url.Values{"name": {"Johnny"}, "phone": {"999"}}, http.StatusSeeOther, ""},
+		{"edit missing", "/contacts/edit?id=contacts/nobody", url.Values{"name": {"Nobody"}, "phone": {"999"}}, http.StatusNotFound, "Nobody"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := c.post(tt.target, tt.form)
+			if rec.Code != tt.expected {
+				t.Fatalf("Expected %d but got %d: %s", tt.expected, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.contains) {
+				t.Errorf("Expected the response to contain %q", tt.contains)
+			}
+		})
+	}
+
+	if _, _, bob := phonebook.GetContact("contacts/bob"); bob.Phone != "777" {
+		t.Errorf("Expected bob to be created but got %+v", bob)
+	}
+	if _, _, john := phonebook.GetContact("contacts/john"); john.Name != "Johnny" || john.Email != "" {
+		t.Errorf("Expected john to be replaced but got %+v", john)
+	}
+	if body := c.get("/contacts?q=bob").Body.String(); !strings.Contains(body, "Bob &lt;b&gt;") {
+		t.Error("Expected the name to be escaped")
+	}
+	if rec := c.get("/contacts/edit?id=contacts/john"); !strings.Contains(rec.Body.String(), `value="Johnny"`) {
+		t.Error("Expected the edit form to be filled in")
+	}
+}
+
+func TestHandler_CustomFields(t *testing.T) {
+	c, phonebook := setupWebTest(t)
+	phonebook.DefineField(domain.FieldDefinition{Name: "timezone", Type: domain.FieldText, Enum: []string{"UTC", "EAT"}})
+	phonebook.DefineField(domain.FieldDefinition{Name: "started", Type: domain.FieldDate})
+
+	if body := c.get("/contacts/new").Body.String(); !strings.Contains(body, `name="custom.timezone"`) || !strings.Contains(body, `type="date" name="custom.started"`) {
+		t.Errorf("Expected inputs for the custom fields but got %s", body)
+	}
+
+	rec := c.post("/contacts/edit?id=contacts/john", url.Values{"name": {"John"}, "phone": {"1"}, "custom.timezone": {"PST"}})
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), domain.ErrInvalidCustomValue.Error()) {
+		t.Fatalf("Expected the value to be rejected but got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = c.post("/contacts/edit?id=contacts/john", url.Values{"name": {"John"}, "phone": {"1"}, "custom.timezone": {"EAT"}, "custom.started": {"2024-02-01"}})
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("Expected 303 but got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, _, john := phonebook.GetContact("contacts/john"); john.Custom["timezone"] != "EAT" || john.Custom["started"] != "2024-02-01" {
+		t.Errorf("Expected the custom fields to be saved but got %+v", john)
+	}
+	body := c.get("/contacts/edit?id=contacts/john").Body.String()
+	if !strings.Contains(body, "<option selected>EAT</option>") || !strings.Contains(body, `value="2024-02-01"`) {
+		t.Errorf("Expected the custom fields to be filled in but got %s", body)
+	}
+}
+
+func TestHandler_Delete(t *testing.T) {
+	c, phonebook := setupWebTest(t)
+
+	if rec := c.post("/contacts/delete?id=contacts/john", url.Values{}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("Expected 303 but got %d", rec.Code)
+	}
+	if success, _, _ := phonebook.GetContact("contacts/john"); success {
+		t.Error("Expected the contact to be deleted")
+	}
+	if rec := c.post("/contacts/delete?id=contacts/john", url.Values{}); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 but got %d", rec.Code)
+	}
+}
+
+func TestHandler_CSRF(t *testing.T) {
+	c, phonebook := setupWebTest(t)
+
+	tests := []struct {
+		name   string
+		cookie string
+		token  string
+	}{
+		{"no cookie", "", c.token},
+		{"no token", c.token, ""},
+		{"mismatch", c.token, "forged"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{csrfField: {tt.token}}
+			req := httptest.NewRequest(http.MethodPost, "/contacts/delete?id=contacts/john", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: csrfCookie, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			c.handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("Expected 403 but got %d", rec.Code)
+			}
+		})
+	}
+
+	if success, _, _ := phonebook.GetContact("contacts/john"); !success {
+		t.Error("Expected the contact to survive forged requests")
+	}
+	if body := c.get("/contacts/new").Body.String(); !strings.Contains(body, `name="csrf_token" value="`+c.token+`"`) {
+		t.Error("Expected forms to carry the CSRF token")
+	}
+}
+
+func TestHandler_Auth(t *testing.T) {
+	auth := application.NewAuthService(database.NewInMemoryDatabase())
+	auth.CreateUser(domain.User{Name: "alice", Role: domain.RoleEditor})
+	auth.CreateUser(domain.User{Name: "bob", Role: domain.RoleViewer})
+	_, _, aliceKey, _ := auth.CreateAPIKey("alice", "")
+	_, _, bobKey, _ := auth.CreateAPIKey("bob", "")
+
+	phonebook := application.NewPhonebookService(database.NewInMemoryDatabase(), application.WithAuthorization())
+	admin := application.ContextWithActor(context.Background(), domain.Actor{Name: "root", Role: domain.RoleAdmin})
+	phonebook.As(admin).AddContact("contacts/john", testContact)
+	handler := NewHandler(phonebook, WithAuth(auth))
+
+	anonymous := &testClient{handler: handler}
+	if rec := anonymous.get("/contacts"); rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("Expected a challenge for anonymous requests but got %d", rec.Code)
+	}
+
+	// Each client picks up its CSRF cookie on its first page
+	login := func(key string) *testClient {
+		c := &testClient{handler: handler, key: key}
+		rec := c.get("/contacts")
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "John Doe") {
+			t.Fatalf("Expected the contact list but got %d", rec.Code)
+		}
+		for _, cookie := range rec.Result().Cookies() {
+			if cookie.Name == csrfCookie {
+				c.token = cookie.Value
+			}
+		}
+		return c
+	}
+
+	viewer := login(bobKey)
+	viewer.post("/contacts/delete?id=contacts/john", url.Values{})
+	if success, _, _ := phonebook.As(admin).GetContact("contacts/john"); !success {
+		t.Fatal("Expected a viewer to be refused the deletion")
+	}
+
+	editor := login(aliceKey)
+	if rec := editor.post("/contacts/delete?id=contacts/john", url.Values{}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("Expected a redirect but got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, _, trashed := phonebook.As(admin).ListTrash(""); len(trashed) != 1 || trashed[0].DeletedBy != "alice" {
+		t.Errorf("Expected the deletion to be recorded against alice but got %+v", trashed)
+	}
+}
+
+func TestHandler_ImportExport(t *testing.T) {
+	c, phonebook := setupWebTest(t)
+
+	csv := "id,name,phone\ncontacts/jane,Jane,555\ncontacts/bad,,555\n"
+	vcf := "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:contacts/bob\r\nFN:Bob\r\nTEL:777\r\nEND:VCARD\r\n"
+
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		expected int
+		contains []string
+	}{
+		{"csv", "contacts.csv", csv, http.StatusOK, []string{"Imported 1 contact, 1 failed", domain.ErrInvalidContactName.Error()}},
+		{"vcard", "contacts.VCF", vcf, http.StatusOK, []string{"Imported 1 contact."}},
+		{"bad header", "contacts.csv", "name\nJohn\n", http.StatusBadRequest, []string{domain.ErrInvalidImportHeader.Error()}},
+		{"unknown format", "contacts.txt", csv, http.StatusBadRequest, []string{"Only .csv and .vcf"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := c.upload(tt.filename, tt.content, false)
+			if rec.Code != tt.expected {
+				t.Fatalf("Expected %d but got %d: %s", tt.expected, rec.Code, rec.Body.String())
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(rec.Body.String(), want) {
+					t.Errorf("Expected the page to contain %q", want)
+				}
+			}
+		})
+	}
+
+	for _, id := range []string{"contacts/jane", "contacts/bob"} {
+		if success, _, _ := phonebook.GetContact(id); !success {
+			t.Errorf("Expected %s to be imported", id)
+		}
+	}
+
+	rec := c.get("/export?format=csv")
+	if rec.Header().Get("Content-Disposition") != `attachment; filename="contacts.csv"` {
+		t.Errorf("Expected a CSV download but got %q", rec.Header().Get("Content-Disposition"))
+	}
+	if !strings.Contains(rec.Body.String(), "contacts/jane,Jane,555") {
+		t.Errorf("Expected jane in the export but got %q", rec.Body.String())
+	}
+
+	rec = c.get("/export?format=vcf")
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/vcard") || strings.Count(rec.Body.String(), "BEGIN:VCARD") != 3 {
+		t.Errorf("Expected 3 vCards but got %q", rec.Body.String())
+	}
+	if rec := c.get("/export?format=xml"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 but got %d", rec.Code)
+	}
+}
+
+func TestHandler_PhotoAndAttachments(t *testing.T) {
+	c, _ := setupWebTest(t)
+
+	var photo bytes.Buffer
+	png.Encode(&photo, image.NewRGBA(image.Rect(0, 0, 256, 256)))
+	if rec := c.uploadTo("/contacts/photo?id=contacts/john", "me.png", photo.String(), nil); rec.Code != http.StatusSeeOther {
+		t.Fatalf("Expected a redirect but got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := c.uploadTo("/contacts/photo?id=contacts/john", "notes.txt", "not an image", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a non-image photo but got %d", rec.Code)
+	}
+
+	rec := c.get("/contacts/photo?id=contacts/john&size=thumbnail")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("Expected the thumbnail but got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	config, err := png.DecodeConfig(rec.Body)
+	if err != nil || config.Width != application.ThumbnailSize {
+		t.Errorf("Expected a %dpx thumbnail but got %v, %v", application.ThumbnailSize, config, err)
+	}
+
+	// Browsers revalidate with the ETag
+	req := httptest.NewRequest(http.MethodGet, "/contacts/photo?id=contacts/john", nil)
+	req.Header.Set("If-None-Match", c.get("/contacts/photo?id=contacts/john").Header().Get("ETag"))
+	cached := httptest.NewRecorder()
+	c.handler.ServeHTTP(cached, req)
+	if cached.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 but got %d", cached.Code)
+	}
+
+	html := "<script>alert(1)</script>"
+	if rec := c.uploadTo("/contacts/attachments?id=contacts/john", "page.html", html, nil); rec.Code != http.StatusSeeOther {
+		t.Fatalf("Expected a redirect but got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = c.get("/contacts/attachment?id=contacts/john&name=page.html")
+	if rec.Body.String() != html || rec.Header().Get("Content-Disposition") != "attachment; filename=page.html" {
+		t.Errorf("Expected the attachment as a download but got %v", rec.Header())
+	}
+
+	body := c.get("/contacts/edit?id=contacts/john").Body.String()
+	for _, want := range []string{"size=thumbnail", "page.html"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected the edit form to contain %q", want)
+		}
+	}
+
+	if rec := c.post("/contacts/attachments/delete?id=contacts/john&name=page.html", url.Values{}); rec.Code != http.StatusSeeOther {
+		t.Errorf("Expected a redirect but got %d", rec.Code)
+	}
+	if rec := c.get("/contacts/attachment?id=contacts/john&name=page.html"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 but got %d", rec.Code)
+	}
+	if rec := c.post("/contacts/photo/delete?id=contacts/john", url.Values{}); rec.Code != http.StatusSeeOther {
+		t.Errorf("Expected a redirect but got %d", rec.Code)
+	}
+	if rec := c.get("/contacts/photo?id=contacts/john"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 but got %d", rec.Code)
+	}
+}
+
+func TestHandler_Static(t *testing.T) {
+	c, _ := setupWebTest(t)
+
+	if rec := c.get("/static/style.css"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "body") {
+		t.Errorf("Expected the embedded stylesheet but got %d", rec.Code)
+	}
+	if rec := c.get("/"); rec.Code != http.StatusFound || rec.Header().Get("Location") != "/contacts" {
+		t.Errorf("Expected a redirect to the list but got %d", rec.Code)
+	}
+}
@@ -0,0 +1,375 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// APIKeyPrefix starts every API key, which reads "pbk_<id>_<secret>".
+const APIKeyPrefix = "pbk_"
+
+// tokenIssuer is the issuer of the JWTs signed by the auth service.
+const tokenIssuer = "phonebook"
+
+// Users, API keys and the token signing key are kept in the auth store as JSON.
+const (
+	userPrefix         = "auth/users/"
+	apiKeyPrefix       = "auth/keys/"
+	signingKeyLocation = "auth/signing-key"
+	signingKeyField    = "key"
+)
+
+// AuthService manages users and their credentials, and turns API keys and
+// JWTs into actors for PhonebookService.
+type AuthService struct {
+	store ports.Database
+	now   func() time.Time
+
+	mu         sync.Mutex
+	signingKey []byte
+}
+
+type AuthOption func(*AuthService)
+
+// WithSigningKey sets the HMAC key for JWTs instead of the one generated and
+// kept in the store.
+func WithSigningKey(key []byte) AuthOption {
+	return func(a *AuthService) {
+		a.signingKey = key
+	}
+}
+
+// WithAuthClock replaces the clock used to issue and check tokens.
+func WithAuthClock(now func() time.Time) AuthOption {
+	return func(a *AuthService) {
+		a.now = now
+	}
+}
+
+func NewAuthService(store ports.Database, opts ...AuthOption) *AuthService {
+	a := &AuthService{
+		store: store,
+		now:   time.Now,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// CreateUser stores a new user. The global role may be empty for users who are
+// only granted individual groups.
+func (a *AuthService) CreateUser(user domain.User) (bool, string, domain.User) {
+	if user.Name == "" || strings.Contains(user.Name, "/") {
+		return false, domain.ErrInvalidUserName.Error(), domain.User{}
+	}
+	if err := validateRoles(user); err != nil {
+		return false, err.Error(), domain.User{}
+	}
+
+	user.CreatedAt = a.now().UTC()
+	if success, message := a.store.Create(userPrefix+user.Name, encodeRecord(user)); !success {
+		if strings.HasSuffix(message, "already exists") {
+			message = domain.ErrUserExists.Error()
+		}
+		return false, message, domain.User{}
+	}
+	return true, "", user
+}
+
+func (a *AuthService) GetUser(name string) (bool, string, domain.User) {
+	var user domain.User
+	if success, message := readRecord(a.store, userPrefix+name, &user); !success {
+		return false, notFound(message, domain.ErrUserNotFound), domain.User{}
+	}
+	return true, "", user
+}
+
+func (a *AuthService) ListUsers() (bool, string, []domain.User) {
+	success, message, ids := a.store.List(userPrefix)
+	if !success {
+		return false, message, nil
+	}
+	sort.Strings(ids)
+
+	users := make([]domain.User, 0, len(ids))
+	for _, id := range ids {
+		var user domain.User
+		if success, message := readRecord(a.store, id, &user); !success {
+			return false, message, nil
+		}
+		users = append(users, user)
+	}
+	return true, "", users
+}
+
+// SetRole changes the global role of a user. An empty role removes it.
+func (a *AuthService) SetRole(name string, role domain.Role) (bool, string) {
+	return a.updateUser(name, func(user *domain.User) {
+		user.Role = role
+	})
+}
+
+// GrantGroup gives a user a role on the members of a group. An empty role
+// revokes it.
+func (a *AuthService) GrantGroup(name, group string, role domain.Role) (bool, string) {
+	if group == "" {
+		return false, domain.ErrInvalidGroupName.Error()
+	}
+	return a.updateUser(name, func(user *domain.User) {
+		if role == "" {
+			delete(user.Groups, group)
+			return
+		}
+		if user.Groups == nil {
+			user.Groups = make(map[string]domain.Role)
+		}
+		user.Groups[group] = role
+	})
+}
+
+// SetDisabled blocks or unblocks every credential of a user.
+func (a *AuthService) SetDisabled(name string, disabled bool) (bool, string) {
+	return a.updateUser(name, func(user *domain.User) {
+		user.Disabled = disabled
+	})
+}
+
+// DeleteUser removes a user together with their API keys.
+func (a *AuthService) DeleteUser(name string) (bool, string) {
+	if success, message, _ := a.GetUser(name); !success {
+		return false, message
+	}
+
+	success, message, keys := a.ListAPIKeys(name)
+	if !success {
+		return false, message
+	}
+	for _, key := range keys {
+		if success, message := a.store.Delete(apiKeyPrefix + key.ID); !success {
+			return false, message
+		}
+	}
+	return a.store.Delete(userPrefix + name)
+}
+
+func (a *AuthService) updateUser(name string, change func(*domain.User)) (bool, string) {
+	success, message, user := a.GetUser(name)
+	if !success {
+		return false, message
+	}
+	change(&user)
+	if err := validateRoles(user); err != nil {
+		return false, err.Error()
+	}
+	return a.store.Update(userPrefix+name, encodeRecord(user))
+}
+
+func validateRoles(user domain.User) error {
+	if user.Role != "" && !user.Role.Valid() {
+		return domain.ErrInvalidRole
+	}
+	for _, role := range user.Groups {
+		if !role.Valid() {
+			return domain.ErrInvalidRole
+		}
+	}
+	return nil
+}
+
+// CreateAPIKey issues a new key for a user. The returned key is the only copy
+// of the secret.
+func (a *AuthService) CreateAPIKey(user, name string) (bool, string, string, domain.APIKey) {
+	if success, message, _ := a.GetUser(user); !success {
+		return false, message, "", domain.APIKey{}
+	}
+
+	secret := randomID() + randomID()
+	key := domain.APIKey{
+		ID:        randomID(),
+		User:      user,
+		Name:      name,
+		Hash:      hashSecret(secret),
+		CreatedAt: a.now().UTC(),
+	}
+	if success, message := a.store.Create(apiKeyPrefix+key.ID, encodeRecord(key)); !success {
+		return false, message, "", domain.APIKey{}
+	}
+	return true, "", APIKeyPrefix + key.ID + "_" + secret, key
+}
+
+// ListAPIKeys returns the keys of a user, or every key when user is empty.
+func (a *AuthService) ListAPIKeys(user string) (bool, string, []domain.APIKey) {
+	success, message, ids := a.store.List(apiKeyPrefix)
+	if !success {
+		return false, message, nil
+	}
+	sort.Strings(ids)
+
+	keys := make([]domain.APIKey, 0)
+	for _, id := range ids {
+		var key domain.APIKey
+		if success, message := readRecord(a.store, id, &key); !success {
+			return false, message, nil
+		}
+		if user == "" || key.User == user {
+			keys = append(keys, key)
+		}
+	}
+	return true, "", keys
+}
+
+func (a *AuthService) RevokeAPIKey(id string) (bool, string) {
+	var key domain.APIKey
+	if success, message := readRecord(a.store, apiKeyPrefix+id, &key); !success {
+		return false, notFound(message, domain.ErrAPIKeyNotFound)
+	}
+	return a.store.Delete(apiKeyPrefix + id)
+}
+
+// IssueToken signs a JWT for a user that expires after ttl.
+func (a *AuthService) IssueToken(user string, ttl time.Duration) (bool, string, string) {
+	if success, message, _ := a.GetUser(user); !success {
+		return false, message, ""
+	}
+	success, message, key := a.loadSigningKey()
+	if !success {
+		return false, message, ""
+	}
+
+	now := a.now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    tokenIssuer,
+		Subject:   user,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	})
+	signed, err := token.SignedString(key)
+	if err != nil {
+		return false, err.Error(), ""
+	}
+	return true, "", signed
+}
+
+// Authenticate checks an API key or a JWT and returns the actor it belongs
+// to. Roles are read from the user on every call, so changes apply at once.
+func (a *AuthService) Authenticate(credential string) (bool, string, domain.Actor) {
+	var name string
+	var err error
+	if strings.HasPrefix(credential, APIKeyPrefix) {
+		name, err = a.checkAPIKey(credential)
+	} else {
+		name, err = a.checkToken(credential)
+	}
+	if err != nil {
+		return false, err.Error(), domain.Actor{}
+	}
+
+	success, _, user := a.GetUser(name)
+	if !success || user.Disabled {
+		return false, domain.ErrInvalidCredentials.Error(), domain.Actor{}
+	}
+	return true, "", domain.Actor{Name: user.Name, Role: user.Role, Groups: user.Groups, Tenant: user.Tenant}
+}
+
+func (a *AuthService) checkAPIKey(credential string) (string, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(credential, APIKeyPrefix), "_")
+	if !ok || id == "" || strings.Contains(id, "/") {
+		return "", domain.ErrInvalidCredentials
+	}
+
+	var key domain.APIKey
+	if success, _ := readRecord(a.store, apiKeyPrefix+id, &key); !success {
+		return "", domain.ErrInvalidCredentials
+	}
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashSecret(secret))) != 1 {
+		return "", domain.ErrInvalidCredentials
+	}
+
+	// Usage is informational, so a failed write does not fail the request
+	key.LastUsedAt = a.now().UTC()
+	a.store.Update(apiKeyPrefix+id, encodeRecord(key))
+	return key.User, nil
+}
+
+func (a *AuthService) checkToken(credential string) (string, error) {
+	success, message, key := a.loadSigningKey()
+	if !success {
+		return "", errors.New(message)
+	}
+
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(credential, &claims, func(*jwt.Token) (interface{}, error) {
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(a.now),
+	)
+	if err != nil || claims.Subject == "" {
+		return "", domain.ErrInvalidCredentials
+	}
+	return claims.Subject, nil
+}
+
+// loadSigningKey reads the JWT key from the store, generating it on first use
+// so every process sharing the store accepts the same tokens.
+func (a *AuthService) loadSigningKey() (bool, string, []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.signingKey != nil {
+		return true, "", a.signingKey
+	}
+
+	success, _, data := a.store.Read(signingKeyLocation)
+	if !success {
+		generated := make([]byte, 32)
+		rand.Read(generated)
+		data = map[string]interface{}{signingKeyField: hex.EncodeToString(generated)}
+		if success, message := a.store.Create(signingKeyLocation, data); !success {
+			// Another process may have created it first
+			if success, _, data = a.store.Read(signingKeyLocation); !success {
+				return false, message, nil
+			}
+		}
+	}
+
+	key, err := hex.DecodeString(stringField(data, signingKeyField))
+	if err != nil || len(key) == 0 {
+		return false, "invalid signing key in the auth store", nil
+	}
+	a.signingKey = key
+	return true, "", key
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+type actorKey struct{}
+
+// ContextWithActor returns a context carrying the authenticated caller.
+func ContextWithActor(ctx context.Context, actor domain.Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the caller carried by ctx, if any.
+func ActorFromContext(ctx context.Context) (domain.Actor, bool) {
+	actor, ok := ctx.Value(actorKey{}).(domain.Actor)
+	return actor, ok
+}
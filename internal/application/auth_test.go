@@ -0,0 +1,139 @@
+package application
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+func TestAuthService_Users(t *testing.T) {
+	a := NewAuthService(newMapDatabase())
+
+	tests := []struct {
+		name        string
+		user        domain.User
+		expectedErr string
+	}{
+		{"admin", domain.User{Name: "alice", Role: domain.RoleAdmin}, ""},
+		{"group only", domain.User{Name: "bob", Groups: map[string]domain.Role{"family": domain.RoleEditor}}, ""},
+		{"duplicate", domain.User{Name: "alice"}, domain.ErrUserExists.Error()},
+		{"no name", domain.User{Role: domain.RoleViewer}, domain.ErrInvalidUserName.Error()},
+		{"slash", domain.User{Name: "a/b"}, domain.ErrInvalidUserName.Error()},
+		{"bad role", domain.User{Name: "carol", Role: "owner"}, domain.ErrInvalidRole.Error()},
+		{"bad group role", domain.User{Name: "carol", Groups: map[string]domain.Role{"family": "owner"}}, domain.ErrInvalidRole.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			success, msg, _ := a.CreateUser(tt.user)
+			if tt.expectedErr == "" && !success {
+				t.Errorf("Expected success but got error: %s", msg)
+			}
+			if tt.expectedErr != "" && (success || msg != tt.expectedErr) {
+				t.Errorf("Expected error %q but got success=%v msg=%q", tt.expectedErr, success, msg)
+			}
+		})
+	}
+
+	if success, msg := a.GrantGroup("bob", "work", domain.RoleViewer); !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+	if success, msg := a.SetRole("bob", "owner"); success || msg != domain.ErrInvalidRole.Error() {
+		t.Errorf("Expected %q but got success=%v msg=%q", domain.ErrInvalidRole.Error(), success, msg)
+	}
+	_, _, bob := a.GetUser("bob")
+	if bob.Role != "" || bob.Groups["work"] != domain.RoleViewer || bob.Groups["family"] != domain.RoleEditor {
+		t.Errorf("Unexpected user %+v", bob)
+	}
+
+	a.CreateAPIKey("bob", "laptop")
+	if success, msg := a.DeleteUser("bob"); !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+	if success, msg, _ := a.GetUser("bob"); success || msg != domain.ErrUserNotFound.Error() {
+		t.Errorf("Expected %q but got success=%v msg=%q", domain.ErrUserNotFound.Error(), success, msg)
+	}
+	if _, _, keys := a.ListAPIKeys(""); len(keys) != 0 {
+		t.Errorf("Expected the user's keys to be deleted but got %+v", keys)
+	}
+	if _, _, users := a.ListUsers(); len(users) != 1 || users[0].Name != "alice" {
+		t.Errorf("Expected only alice but got %+v", users)
+	}
+}
+
+func TestAuthService_Authenticate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newMapDatabase()
+	a := NewAuthService(store, WithAuthClock(func() time.Time { return now }))
+	a.CreateUser(domain.User{Name: "alice", Role: domain.RoleEditor, Groups: map[string]domain.Role{"family": domain.RoleAdmin}})
+	a.CreateUser(domain.User{Name: "bob", Role: domain.RoleViewer})
+
+	_, _, aliceKey, key := a.CreateAPIKey("alice", "laptop")
+	if !strings.HasPrefix(aliceKey, APIKeyPrefix) || strings.Contains(key.Hash, strings.Split(aliceKey, "_")[2]) {
+		t.Fatalf("Unexpected key %q with record %+v", aliceKey, key)
+	}
+	_, _, bobToken := a.IssueToken("bob", time.Hour)
+
+	// Another service sharing the store accepts the same tokens
+	other := NewAuthService(store, WithAuthClock(func() time.Time { return now }))
+
+	tests := []struct {
+		name       string
+		auth       *AuthService
+		credential string
+		expected   string
+	}{
+		{"api key", a, aliceKey, "alice"},
+		{"token", a, bobToken, "bob"},
+		{"token on another instance", other, bobToken, "bob"},
+		{"wrong secret", a, aliceKey[:len(aliceKey)-1] + "x", ""},
+		{"unknown key", a, APIKeyPrefix + "nope_secret", ""},
+		{"malformed key", a, APIKeyPrefix + "nope", ""},
+		{"tampered token", a, bobToken[:len(bobToken)-2] + "xx", ""},
+		{"garbage", a, "hello", ""},
+		{"foreign token", NewAuthService(newMapDatabase(), WithSigningKey([]byte("other"))), bobToken, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			success, msg, actor := tt.auth.Authenticate(tt.credential)
+			if tt.expected == "" {
+				if success || msg != domain.ErrInvalidCredentials.Error() {
+					t.Errorf("Expected %q but got success=%v msg=%q", domain.ErrInvalidCredentials.Error(), success, msg)
+				}
+				return
+			}
+			if !success || actor.Name != tt.expected {
+				t.Errorf("Expected %s but got %+v (%s)", tt.expected, actor, msg)
+			}
+		})
+	}
+
+	_, _, actor := a.Authenticate(aliceKey)
+	if actor.Role != domain.RoleEditor || actor.Groups["family"] != domain.RoleAdmin {
+		t.Errorf("Expected the roles of alice but got %+v", actor)
+	}
+	if _, _, keys := a.ListAPIKeys("alice"); len(keys) != 1 || !keys[0].LastUsedAt.Equal(now) {
+		t.Errorf("Expected the key use to be recorded but got %+v", keys)
+	}
+
+	// Disabled users, revoked keys and expired tokens are rejected
+	a.SetDisabled("bob", true)
+	if success, _, _ := a.Authenticate(bobToken); success {
+		t.Error("Expected the disabled user to be rejected")
+	}
+	a.RevokeAPIKey(key.ID)
+	if success, _, _ := a.Authenticate(aliceKey); success {
+		t.Error("Expected the revoked key to be rejected")
+	}
+	if success, msg := a.RevokeAPIKey(key.ID); success || msg != domain.ErrAPIKeyNotFound.Error() {
+		t.Errorf("Expected %q but got success=%v msg=%q", domain.ErrAPIKeyNotFound.Error(), success, msg)
+	}
+	a.SetDisabled("bob", false)
+	now = now.Add(2 * time.Hour)
+	if success, _, _ := a.Authenticate(bobToken); success {
+		t.Error("Expected the expired token to be rejected")
+	}
+}
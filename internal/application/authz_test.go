@@ -0,0 +1,150 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+func setupAuthzTest() *PhonebookService {
+	s := NewPhonebookService(newMapGroupDatabase(), WithAuthorization())
+	admin := s.As(ContextWithActor(context.Background(), domain.Actor{Name: "root", Role: domain.RoleAdmin}))
+	admin.AddContact("contacts/john", trashTestContact)
+	admin.AddContact("contacts/jane", domain.Contact{Name: "Jane Doe", Phone: "555-000-1111"})
+	admin.CreateGroup("family")
+	admin.AddToGroup("family", "contacts/jane")
+	return s
+}
+
+func TestPhonebookService_Authorization(t *testing.T) {
+	viewer := domain.Actor{Name: "vera", Role: domain.RoleViewer}
+	editor := domain.Actor{Name: "ed", Role: domain.RoleEditor}
+	family := domain.Actor{Name: "fay", Groups: map[string]domain.Role{"family": domain.RoleEditor}}
+
+	tests := []struct {
+		name        string
+		actor       *domain.Actor
+		call        func(s *PhonebookService) (bool, string)
+		expectedErr error
+	}{
+		{"anonymous read", nil, func(s *PhonebookService) (bool, string) {
+			success, message, _ := s.GetContact("contacts/john")
+			return success, message
+		}, domain.ErrUnauthenticated},
+		{"viewer read", &viewer, func(s *PhonebookService) (bool, string) {
+			success, message, _ := s.GetContact("contacts/john")
+			return success, message
+		}, nil},
+		{"viewer update", &viewer, func(s *PhonebookService) (bool, string) {
+			return s.UpdateContact("contacts/john", trashTestContact)
+		}, domain.ErrPermissionDenied},
+		{"viewer import", &viewer, func(s *PhonebookService) (bool, string) {
+			imp := s.NewImporter(false)
+			imp.Add(1, "contacts/bob", trashTestContact)
+			results := imp.Close()
+			return results[0].Success, results[0].Message
+		}, domain.ErrPermissionDenied},
+		{"editor add", &editor, func(s *PhonebookService) (bool, string) {
+			return s.AddContact("contacts/bob", trashTestContact)
+		}, nil},
+		{"editor create group", &editor, func(s *PhonebookService) (bool, string) {
+			return s.CreateGroup("work")
+		}, domain.ErrPermissionDenied},
+		{"editor purge", &editor, func(s *PhonebookService) (bool, string) {
+			success, message, _ := s.PurgeTrash()
+			return success, message
+		}, domain.ErrPermissionDenied},
+		{"group member read", &family, func(s *PhonebookService) (bool, string) {
+			success, message, _ := s.GetContact("contacts/jane")
+			return success, message
+		}, nil},
+		{"group member update", &family, func(s *PhonebookService) (bool, string) {
+			return s.UpdateContact("contacts/jane", domain.Contact{Name: "Jane", Phone: "1"})
+		}, nil},
+		{"outside group read", &family, func(s *PhonebookService) (bool, string) {
+			success, message, _ := s.GetContact("contacts/john")
+			return success, message
+		}, domain.ErrPermissionDenied},
+		{"missing contact read", &family, func(s *PhonebookService) (bool, string) {
+			success, message, _ := s.GetContact("contacts/nobody")
+			return success, message
+		}, domain.ErrPermissionDenied},
+		{"missing contact update", &family, func(s *PhonebookService) (bool, string) {
+			return s.UpdateContact("contacts/nobody", domain.Contact{Name: "Nobody", Phone: "1"})
+		}, domain.ErrPermissionDenied},
+		{"group member move", &family, func(s *PhonebookService) (bool, string) {
+			return s.MoveContact("contacts/jane", "contacts/janet")
+		}, domain.ErrPermissionDenied},
+		{"group member add", &family, func(s *PhonebookService) (bool, string) {
+			return s.AddContact("contacts/bob", trashTestContact)
+		}, domain.ErrPermissionDenied},
+		{"add outsider to group", &family, func(s *PhonebookService) (bool, string) {
+			return s.AddToGroup("family", "contacts/john")
+		}, domain.ErrPermissionDenied},
+		{"group member delete", &family, func(s *PhonebookService) (bool, string) {
+			return s.DeleteContact("contacts/jane")
+		}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.actor != nil {
+				ctx = ContextWithActor(ctx, *tt.actor)
+			}
+			success, msg := tt.call(setupAuthzTest().As(ctx))
+			if tt.expectedErr == nil && !success {
+				t.Errorf("Expected success but got error: %s", msg)
+			}
+			if tt.expectedErr != nil && (success || msg != tt.expectedErr.Error()) {
+				t.Errorf("Expected %q but got success=%v msg=%q", tt.expectedErr.Error(), success, msg)
+			}
+		})
+	}
+}
+
+func TestPhonebookService_AuthorizationFiltersLists(t *testing.T) {
+	s := setupAuthzTest()
+	s.As(ContextWithActor(context.Background(), domain.Actor{Name: "root", Role: domain.RoleAdmin})).CreateGroup("work")
+
+	tests := []struct {
+		name     string
+		actor    domain.Actor
+		contacts int
+		groups   []string
+	}{
+		{"viewer", domain.Actor{Name: "vera", Role: domain.RoleViewer}, 2, []string{"family", "work"}},
+		{"group member", domain.Actor{Name: "fay", Groups: map[string]domain.Role{"family": domain.RoleViewer}}, 1, []string{"family"}},
+		{"no grants", domain.Actor{Name: "nobody"}, 0, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bound := s.As(ContextWithActor(context.Background(), tt.actor))
+			if _, _, phonebook := bound.ListContacts(""); len(phonebook.Contacts) != tt.contacts {
+				t.Errorf("Expected %d contacts but got %+v", tt.contacts, phonebook.Contacts)
+			}
+			_, _, groups := bound.ListGroups()
+			if len(groups) != len(tt.groups) {
+				t.Fatalf("Expected groups %v but got %v", tt.groups, groups)
+			}
+			for i, group := range tt.groups {
+				if groups[i] != group {
+					t.Errorf("Expected groups %v but got %v", tt.groups, groups)
+				}
+			}
+		})
+	}
+}
+
+func TestPhonebookService_DeleteRecordsActor(t *testing.T) {
+	s := setupAuthzTest()
+	editor := s.As(ContextWithActor(context.Background(), domain.Actor{Name: "ed", Role: domain.RoleEditor}))
+	editor.DeleteContact("contacts/john")
+
+	_, _, trashed := editor.ListTrash("")
+	if len(trashed) != 1 || trashed[0].DeletedBy != "ed" {
+		t.Errorf("Expected the deletion to be recorded against ed but got %+v", trashed)
+	}
+}
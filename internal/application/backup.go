@@ -0,0 +1,441 @@
+package application
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// Backups are gzipped JSON lines: a header, one line per record, per blob
+// and per group, and a trailer holding the manifest. The checksum in the
+// trailer covers every line before it, so a truncated or edited archive is
+// rejected before anything is written. Version 1 archives have no blobs.
+const (
+	BackupFormat  = "phonebook-backup"
+	BackupVersion = 2
+)
+
+// maxBackupLine bounds a single line of an archive. Blob lines hold their
+// content base64-encoded.
+const maxBackupLine = 64 << 20
+
+type backupLine struct {
+	Type      string                 `json:"type"`
+	Format    string                 `json:"format,omitempty"`
+	Version   int                    `json:"version,omitempty"`
+	CreatedAt *time.Time             `json:"created_at,omitempty"`
+	Location  string                 `json:"location,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Name      string                 `json:"name,omitempty"`
+	Members   []string               `json:"members,omitempty"`
+	Hash      string                 `json:"hash,omitempty"`
+	Content   []byte                 `json:"content,omitempty"`
+	Records   int                    `json:"records,omitempty"`
+	Groups    int                    `json:"groups,omitempty"`
+	Blobs     int                    `json:"blobs,omitempty"`
+	Checksum  string                 `json:"sha256,omitempty"`
+}
+
+// backupGroup is a group and its members as held in an archive.
+type backupGroup struct {
+	name    string
+	members []string
+}
+
+// Backup writes every record of the database to w, including the trash,
+// merge history and the metadata the service keeps for itself, followed by
+// the blobs and groups when the database has them.
+func (s *PhonebookService) Backup(w io.Writer) (bool, string, domain.BackupManifest) {
+	if err := s.authorize(domain.RoleAdmin); err != nil {
+		return false, err.Error(), domain.BackupManifest{}
+	}
+
+	success, message, locations := s.db.List("")
+	if !success {
+		return false, message, domain.BackupManifest{}
+	}
+	sort.Strings(locations)
+
+	zw := gzip.NewWriter(w)
+	archive := &backupWriter{w: zw, sum: sha256.New()}
+	createdAt := time.Now().UTC()
+	archive.write(backupLine{Type: "header", Format: BackupFormat, Version: BackupVersion, CreatedAt: &createdAt})
+
+	for _, location := range locations {
+		success, message, data := s.db.Read(location)
+		if !success {
+			return false, fmt.Sprintf("Error reading %s: %s", location, message), domain.BackupManifest{}
+		}
+		archive.write(backupLine{Type: "record", Location: location, Data: data})
+	}
+
+	var hashes []string
+	if store, ok := s.db.(ports.BlobStore); ok {
+		if success, message, hashes = store.ListBlobs(); !success {
+			return false, message, domain.BackupManifest{}
+		}
+		sort.Strings(hashes)
+		for _, hash := range hashes {
+			success, message, content := store.GetBlob(hash)
+			if !success {
+				return false, fmt.Sprintf("Error reading blob %s: %s", hash, message), domain.BackupManifest{}
+			}
+			archive.write(backupLine{Type: "blob", Hash: hash, Content: content})
+		}
+	}
+
+	var groups []backupGroup
+	if store, ok := s.groupStore(); ok {
+		var err error
+		if groups, err = listGroups(store); err != nil {
+			return false, err.Error(), domain.BackupManifest{}
+		}
+	}
+	for _, group := range groups {
+		archive.write(backupLine{Type: "group", Name: group.name, Members: group.members})
+	}
+
+	manifest := domain.BackupManifest{
+		Format:    BackupFormat,
+		Version:   BackupVersion,
+		CreatedAt: createdAt,
+		Records:   len(locations),
+		Groups:    len(groups),
+		Blobs:     len(hashes),
+		Checksum:  hex.EncodeToString(archive.sum.Sum(nil)),
+	}
+	archive.write(backupLine{Type: "trailer", Records: manifest.Records, Groups: manifest.Groups, Blobs: manifest.Blobs, Checksum: manifest.Checksum})
+	if archive.err == nil {
+		archive.err = zw.Close()
+	}
+	if archive.err != nil {
+		return false, fmt.Sprintf("Error writing backup: %v", archive.err), domain.BackupManifest{}
+	}
+	return true, "", manifest
+}
+
+type backupWriter struct {
+	w   io.Writer
+	sum hash.Hash
+	err error
+}
+
+func (b *backupWriter) write(line backupLine) {
+	if b.err != nil {
+		return
+	}
+	encoded, err := json.Marshal(line)
+	if err != nil {
+		b.err = err
+		return
+	}
+	encoded = append(encoded, '\n')
+	b.sum.Write(encoded)
+	_, b.err = b.w.Write(encoded)
+}
+
+// Restore loads an archive written by Backup. The whole archive is read and
+// verified first. Replace mode then makes the database match it exactly;
+// merge mode only writes what it holds. Blobs are stored before the records
+// referring to them. A dry run reports the changes without making them.
+func (s *PhonebookService) Restore(r io.Reader, mode domain.RestoreMode, dryRun bool) (bool, string, domain.RestoreReport) {
+	if err := s.authorize(domain.RoleAdmin); err != nil {
+		return false, err.Error(), domain.RestoreReport{}
+	}
+	if mode != domain.RestoreReplace && mode != domain.RestoreMerge {
+		return false, domain.ErrInvalidRestoreMode.Error(), domain.RestoreReport{}
+	}
+
+	manifest, records, blobs, groups, err := readBackup(r)
+	if err != nil {
+		return false, err.Error(), domain.RestoreReport{}
+	}
+	report := domain.RestoreReport{Manifest: manifest, DryRun: dryRun, Groups: len(groups)}
+
+	groupStore, hasGroups := s.groupStore()
+	if len(groups) > 0 && !hasGroups {
+		return false, domain.ErrGroupsNotSupported.Error(), domain.RestoreReport{}
+	}
+	blobStore, hasBlobs := s.db.(ports.BlobStore)
+	if len(blobs) > 0 && !hasBlobs {
+		return false, domain.ErrBlobsNotSupported.Error(), domain.RestoreReport{}
+	}
+	var missingBlobs []backupLine
+	var staleBlobs []string
+	if hasBlobs {
+		if missingBlobs, staleBlobs, err = diffBlobs(blobStore, blobs); err != nil {
+			return false, err.Error(), domain.RestoreReport{}
+		}
+		if mode != domain.RestoreReplace {
+			staleBlobs = nil
+		}
+		report.Blobs, report.DeletedBlobs = len(missingBlobs), len(staleBlobs)
+	}
+
+	success, message, locations := s.db.List("")
+	if !success {
+		return false, message, domain.RestoreReport{}
+	}
+	existing := make(map[string]bool, len(locations))
+	for _, location := range locations {
+		existing[location] = true
+	}
+	restored := make(map[string]bool, len(records))
+	for _, record := range records {
+		restored[record.Location] = true
+		if existing[record.Location] {
+			report.Updated++
+		} else {
+			report.Created++
+		}
+	}
+	var stale []string
+	if mode == domain.RestoreReplace {
+		for _, location := range locations {
+			if !restored[location] {
+				stale = append(stale, location)
+			}
+		}
+		report.Deleted = len(stale)
+	}
+	if dryRun {
+		return true, "", report
+	}
+
+	if hasGroups {
+		if err := restoreGroups(groupStore, groups, mode, true); err != nil {
+			return false, err.Error(), domain.RestoreReport{}
+		}
+	}
+	for _, blob := range missingBlobs {
+		if success, message := blobStore.PutBlob(blob.Hash, blob.Content); !success {
+			return false, fmt.Sprintf("Error restoring blob %s: %s", blob.Hash, message), domain.RestoreReport{}
+		}
+	}
+	for _, result := range s.batchDelete(stale) {
+		if !result.Success {
+			return false, fmt.Sprintf("Error deleting %s: %s", result.Location, result.Message), domain.RestoreReport{}
+		}
+	}
+	for _, result := range s.batchUpsert(records) {
+		if !result.Success {
+			return false, fmt.Sprintf("Error restoring %s: %s", result.Location, result.Message), domain.RestoreReport{}
+		}
+	}
+	if hasGroups {
+		if err := restoreGroups(groupStore, groups, mode, false); err != nil {
+			return false, err.Error(), domain.RestoreReport{}
+		}
+	}
+	for _, hash := range staleBlobs {
+		if success, message := blobStore.DeleteBlob(hash); !success {
+			return false, fmt.Sprintf("Error deleting blob %s: %s", hash, message), domain.RestoreReport{}
+		}
+	}
+	return true, "", report
+}
+
+// diffBlobs returns the blobs of the archive that store lacks, and the
+// hashes store holds that the archive does not.
+func diffBlobs(store ports.BlobStore, blobs []backupLine) ([]backupLine, []string, error) {
+	success, message, hashes := store.ListBlobs()
+	if !success {
+		return nil, nil, errors.New(message)
+	}
+	stored := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		stored[hash] = true
+	}
+	archived := make(map[string]bool, len(blobs))
+	var missing []backupLine
+	for _, blob := range blobs {
+		archived[blob.Hash] = true
+		if !stored[blob.Hash] {
+			missing = append(missing, blob)
+		}
+	}
+	var stale []string
+	for _, hash := range hashes {
+		if !archived[hash] {
+			stale = append(stale, hash)
+		}
+	}
+	return missing, stale, nil
+}
+
+// restoreGroups runs in two passes around the records. The first removes
+// groups and members the backup does not have, so stale records can be
+// deleted; the second creates the groups and adds their members once the
+// records exist. Merge mode skips the first pass.
+func restoreGroups(store ports.GroupStore, groups []backupGroup, mode domain.RestoreMode, prune bool) error {
+	success, message, names := store.ListGroups()
+	if !success {
+		return errors.New(message)
+	}
+	existing := make(map[string]bool, len(names))
+	for _, name := range names {
+		existing[name] = true
+	}
+	wanted := make(map[string]backupGroup, len(groups))
+	for _, group := range groups {
+		wanted[group.name] = group
+	}
+
+	if prune {
+		if mode != domain.RestoreReplace {
+			return nil
+		}
+		for _, name := range names {
+			group, ok := wanted[name]
+			if !ok {
+				if err := resultError(store.DeleteGroup(name)); err != nil {
+					return err
+				}
+				continue
+			}
+			keep := make(map[string]bool, len(group.members))
+			for _, member := range group.members {
+				keep[member] = true
+			}
+			success, message, members := store.Members(name)
+			if !success {
+				return errors.New(message)
+			}
+			for _, member := range members {
+				if keep[member] {
+					continue
+				}
+				if err := resultError(store.RemoveMember(name, member)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for _, group := range groups {
+		if !existing[group.name] {
+			if err := resultError(store.CreateGroup(group.name)); err != nil {
+				return err
+			}
+		}
+		success, message, members := store.Members(group.name)
+		if !success {
+			return errors.New(message)
+		}
+		present := make(map[string]bool, len(members))
+		for _, member := range members {
+			present[member] = true
+		}
+		for _, member := range group.members {
+			if present[member] {
+				continue
+			}
+			if err := resultError(store.AddMember(group.name, member)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listGroups reads every group and its members, sorted by name.
+func listGroups(store ports.GroupStore) ([]backupGroup, error) {
+	success, message, names := store.ListGroups()
+	if !success {
+		return nil, errors.New(message)
+	}
+	sort.Strings(names)
+
+	groups := make([]backupGroup, 0, len(names))
+	for _, name := range names {
+		success, message, members := store.Members(name)
+		if !success {
+			return nil, errors.New(message)
+		}
+		groups = append(groups, backupGroup{name: name, members: members})
+	}
+	return groups, nil
+}
+
+// readBackup reads and verifies a whole archive.
+func readBackup(r io.Reader) (domain.BackupManifest, []ports.BatchItem, []backupLine, []backupGroup, error) {
+	var manifest domain.BackupManifest
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return manifest, nil, nil, nil, domain.ErrInvalidBackup
+	}
+	defer zr.Close()
+
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(make([]byte, 64*1024), maxBackupLine)
+	sum := sha256.New()
+	var records []ports.BatchItem
+	var blobs []backupLine
+	var groups []backupGroup
+	header, trailer := false, false
+
+	for scanner.Scan() {
+		if trailer {
+			return manifest, nil, nil, nil, domain.ErrBackupChecksum
+		}
+		var line backupLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			if !header {
+				return manifest, nil, nil, nil, domain.ErrInvalidBackup
+			}
+			return manifest, nil, nil, nil, domain.ErrBackupChecksum
+		}
+
+		switch {
+		case !header:
+			if line.Type != "header" || line.Format != BackupFormat || line.CreatedAt == nil {
+				return manifest, nil, nil, nil, domain.ErrInvalidBackup
+			}
+			if line.Version < 1 || line.Version > BackupVersion {
+				return manifest, nil, nil, nil, domain.ErrUnsupportedBackupVersion
+			}
+			header = true
+			manifest.Format, manifest.Version, manifest.CreatedAt = line.Format, line.Version, *line.CreatedAt
+		case line.Type == "record":
+			records = append(records, ports.BatchItem{Location: line.Location, Data: line.Data})
+		case line.Type == "blob":
+			blobs = append(blobs, line)
+		case line.Type == "group":
+			groups = append(groups, backupGroup{name: line.Name, members: line.Members})
+		case line.Type == "trailer":
+			trailer = true
+			manifest.Records, manifest.Groups, manifest.Blobs, manifest.Checksum = line.Records, line.Groups, line.Blobs, line.Checksum
+			continue
+		default:
+			return manifest, nil, nil, nil, domain.ErrInvalidBackup
+		}
+		sum.Write(scanner.Bytes())
+		sum.Write([]byte{'\n'})
+	}
+	if err := scanner.Err(); err != nil {
+		if !header {
+			return manifest, nil, nil, nil, domain.ErrInvalidBackup
+		}
+		return manifest, nil, nil, nil, domain.ErrBackupChecksum
+	}
+	if !header {
+		return manifest, nil, nil, nil, domain.ErrInvalidBackup
+	}
+	if !trailer || manifest.Checksum != hex.EncodeToString(sum.Sum(nil)) ||
+		manifest.Records != len(records) || manifest.Groups != len(groups) || manifest.Blobs != len(blobs) {
+		return manifest, nil, nil, nil, domain.ErrBackupChecksum
+	}
+	return manifest, records, blobs, groups, nil
+}
@@ -0,0 +1,179 @@
+package application
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+func setupBackupTest(t *testing.T) (*PhonebookService, []byte) {
+	db := newMapGroupDatabase()
+	s := NewPhonebookService(db)
+	require.True(t, mustSucceed(s.AddContact("alice", domain.Contact{Name: "Alice", Phone: "+256700000001"})))
+	require.True(t, mustSucceed(s.AddContact("bob", domain.Contact{Name: "Bob", Phone: "+256700000002"})))
+	require.True(t, mustSucceed(s.DeleteContact("bob")))
+	require.True(t, mustSucceed(s.CreateGroup("family")))
+	require.True(t, mustSucceed(s.AddToGroup("family", "alice")))
+
+	var archive bytes.Buffer
+	success, message, manifest := s.Backup(&archive)
+	require.True(t, success, message)
+	assert.Equal(t, BackupVersion, manifest.Version)
+	assert.Equal(t, 1, manifest.Groups)
+	assert.NotEmpty(t, manifest.Checksum)
+	return s, archive.Bytes()
+}
+
+func TestPhonebookService_BackupRestore(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     domain.RestoreMode
+		dryRun   bool
+		expected domain.RestoreReport
+		contacts []string
+		groups   []string
+	}{
+		{"replace", domain.RestoreReplace, false, domain.RestoreReport{Created: 1, Updated: 1, Deleted: 1, Groups: 1}, []string{"alice"}, []string{"family"}},
+		{"merge", domain.RestoreMerge, false, domain.RestoreReport{Created: 1, Updated: 1, Groups: 1}, []string{"alice", "carol"}, []string{"family", "work"}},
+		{"dry run", domain.RestoreReplace, true, domain.RestoreReport{Created: 1, Updated: 1, Deleted: 1, Groups: 1, DryRun: true}, []string{"alice", "carol"}, []string{"work"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, archive := setupBackupTest(t)
+
+			target := NewPhonebookService(newMapGroupDatabase())
+			require.True(t, mustSucceed(target.AddContact("alice", domain.Contact{Name: "Alice", Phone: "+256799999999"})))
+			require.True(t, mustSucceed(target.AddContact("carol", domain.Contact{Name: "Carol", Phone: "+256700000003"})))
+			require.True(t, mustSucceed(target.CreateGroup("work")))
+
+			success, message, report := target.Restore(bytes.NewReader(archive), tt.mode, tt.dryRun)
+			require.True(t, success, message)
+			tt.expected.Manifest = report.Manifest
+			assert.Equal(t, tt.expected, report)
+
+			_, _, phonebook := target.ListContacts("")
+			assert.ElementsMatch(t, tt.contacts, sortedKeys(phonebook.Contacts))
+			_, _, groups := target.ListGroups()
+			assert.ElementsMatch(t, tt.groups, groups)
+
+			if !tt.dryRun {
+				_, _, alice := target.GetContact("alice")
+				assert.Equal(t, "+256700000001", alice.Phone)
+				_, _, family := target.GetGroup("family")
+				assert.Equal(t, []string{"alice"}, family.Members)
+			}
+		})
+	}
+}
+
+func TestPhonebookService_RestoreRestoresTrash(t *testing.T) {
+	_, archive := setupBackupTest(t)
+	target := NewPhonebookService(newMapGroupDatabase())
+	success, message, _ := target.Restore(bytes.NewReader(archive), domain.RestoreReplace, false)
+	require.True(t, success, message)
+
+	_, _, trash := target.ListTrash("")
+	require.Len(t, trash, 1)
+	assert.True(t, mustSucceed(target.RestoreContact(trash[0].ID)))
+}
+
+func TestPhonebookService_RestoreRejectsInvalidArchives(t *testing.T) {
+	_, archive := setupBackupTest(t)
+	lines := gunzip(t, archive)
+
+	tests := []struct {
+		name        string
+		archive     []byte
+		mode        domain.RestoreMode
+		expectedErr error
+	}{
+		{"not gzip", []byte("name,phone\n"), domain.RestoreReplace, domain.ErrInvalidBackup},
+		{"not a backup", gzipped(t, []byte(`{"type":"record"}`+"\n")), domain.RestoreReplace, domain.ErrInvalidBackup},
+		{"future version", gzipped(t, bytes.Replace(lines, []byte(`"version":2`), []byte(`"version":3`), 1)), domain.RestoreReplace, domain.ErrUnsupportedBackupVersion},
+		{"tampered", gzipped(t, bytes.Replace(lines, []byte("+256700000001"), []byte("+256700000009"), 1)), domain.RestoreReplace, domain.ErrBackupChecksum},
+		{"truncated", gzipped(t, lines[:bytes.LastIndexByte(lines[:len(lines)-1], '\n')+1]), domain.RestoreReplace, domain.ErrBackupChecksum},
+		{"corrupt", archive[:len(archive)/2], domain.RestoreReplace, domain.ErrBackupChecksum},
+		{"invalid mode", archive, "overwrite", domain.ErrInvalidRestoreMode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMapGroupDatabase()
+			target := NewPhonebookService(db)
+			require.True(t, mustSucceed(target.AddContact("carol", domain.Contact{Name: "Carol", Phone: "+256700000003"})))
+
+			success, message, _ := target.Restore(bytes.NewReader(tt.archive), tt.mode, false)
+			assert.False(t, success)
+			assert.Equal(t, tt.expectedErr.Error(), message)
+
+			_, _, locations := db.List("")
+			assert.Len(t, locations, 1, "nothing is written from a rejected archive")
+		})
+	}
+}
+
+func TestPhonebookService_BackupRequiresAdmin(t *testing.T) {
+	s := NewPhonebookService(newMapDatabase(), WithAuthorization())
+	editor := s.As(ContextWithActor(context.Background(), domain.Actor{Name: "ed", Role: domain.RoleEditor}))
+
+	success, message, _ := editor.Backup(io.Discard)
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrPermissionDenied.Error(), message)
+	success, message, _ = editor.Restore(bytes.NewReader(nil), domain.RestoreMerge, true)
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrPermissionDenied.Error(), message)
+}
+
+func gunzip(t *testing.T, archive []byte) []byte {
+	zr, err := gzip.NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	lines, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	return lines
+}
+
+func gzipped(t *testing.T, lines []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(lines)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestPhonebookService_BackupRestoreBlobs(t *testing.T) {
+	s := NewPhonebookService(newBlobDatabase())
+	require.True(t, mustSucceed(s.AddContact("alice", domain.Contact{Name: "Alice", Phone: "+256700000001"})))
+	success, message, _ := s.AddAttachment("alice", "notes.txt", []byte("call after 5"))
+	require.True(t, success, message)
+
+	var archive bytes.Buffer
+	success, message, manifest := s.Backup(&archive)
+	require.True(t, success, message)
+	assert.Equal(t, 1, manifest.Blobs)
+
+	// Replace mode drops blobs the backup does not have
+	db := newBlobDatabase()
+	db.PutBlob("stale", []byte("old"))
+	target := NewPhonebookService(db)
+	success, message, report := target.Restore(bytes.NewReader(archive.Bytes()), domain.RestoreReplace, false)
+	require.True(t, success, message)
+	assert.Equal(t, 1, report.Blobs)
+	assert.Equal(t, 1, report.DeletedBlobs)
+	success, message, _, content := target.AttachmentContent("alice", "notes.txt")
+	require.True(t, success, message)
+	assert.Equal(t, "call after 5", string(content))
+	assert.NotContains(t, db.blobs, "stale")
+
+	success, message, _ = NewPhonebookService(newMapDatabase()).Restore(bytes.NewReader(archive.Bytes()), domain.RestoreReplace, false)
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrBlobsNotSupported.Error(), message)
+}
@@ -0,0 +1,84 @@
+package application
+
+import (
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// batchRead reads records, in one batch when supported. Locations without a
+// record are left out.
+func (s *PhonebookService) batchRead(locations []string) (bool, string, map[string]map[string]interface{}) {
+	if batch, ok := s.db.(ports.BatchDatabase); ok {
+		return batch.BatchRead(locations)
+	}
+
+	records := make(map[string]map[string]interface{}, len(locations))
+	for _, location := range locations {
+		success, message, data := s.db.Read(location)
+		if !success {
+			if isMissing(message) {
+				continue
+			}
+			return false, message, nil
+		}
+		records[location] = data
+	}
+	return true, "", records
+}
+
+// batchCreate writes items in one batch when the database supports it and
+// falls back to one Create per item otherwise.
+func (s *PhonebookService) batchCreate(items []ports.BatchItem) []ports.BatchResult {
+	if batch, ok := s.db.(ports.BatchDatabase); ok {
+		return batch.BatchCreate(items)
+	}
+
+	results := make([]ports.BatchResult, len(items))
+	for i, item := range items {
+		success, message := s.db.Create(item.Location, item.Data)
+		results[i] = ports.BatchResult{Location: item.Location, Success: success, Message: message}
+	}
+	return results
+}
+
+// batchUpsert creates or replaces items, in one batch when supported.
+func (s *PhonebookService) batchUpsert(items []ports.BatchItem) []ports.BatchResult {
+	if batch, ok := s.db.(ports.BatchDatabase); ok {
+		return batch.BatchUpsert(items)
+	}
+
+	results := make([]ports.BatchResult, len(items))
+	for i, item := range items {
+		success, message, _ := upsert(s.db, item.Location, item.Data)
+		results[i] = ports.BatchResult{Location: item.Location, Success: success, Message: message}
+	}
+	return results
+}
+
+// upsert creates or replaces a record in one step when the database supports
+// it, and reads first otherwise. The third result reports whether the record
+// was created.
+func upsert(db ports.Database, location string, data map[string]interface{}) (bool, string, bool) {
+	if upserter, ok := db.(ports.UpsertDatabase); ok {
+		return upserter.Upsert(location, data)
+	}
+	if exists, _, _ := db.Read(location); exists {
+		success, message := db.Update(location, data)
+		return success, message, false
+	}
+	success, message := db.Create(location, data)
+	return success, message, success
+}
+
+// batchDelete permanently removes locations, in one batch when supported.
+func (s *PhonebookService) batchDelete(locations []string) []ports.BatchResult {
+	if batch, ok := s.db.(ports.BatchDatabase); ok {
+		return batch.BatchDelete(locations)
+	}
+
+	results := make([]ports.BatchResult, len(locations))
+	for i, location := range locations {
+		success, message := s.db.Delete(location)
+		results[i] = ports.BatchResult{Location: location, Success: success, Message: message}
+	}
+	return results
+}
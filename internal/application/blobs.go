@@ -0,0 +1,377 @@
+package application
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+const (
+	// DefaultMaxBlobSize bounds the size of a photo or attachment.
+	DefaultMaxBlobSize = 10 << 20
+	// ThumbnailSize is the longest side of photo thumbnails, in pixels.
+	ThumbnailSize = 128
+	// maxPhotoPixels bounds the decoded size of a photo, so a small file
+	// cannot expand into an enormous image.
+	maxPhotoPixels = 50_000_000
+)
+
+// Blob references stored alongside the contact fields. They are JSON
+// strings, which every backend keeps as they are, unlike nested documents.
+const (
+	photoField       = "_photo"
+	attachmentsField = "_attachments"
+)
+
+// WithMaxBlobSize sets the largest photo or attachment that can be stored.
+func WithMaxBlobSize(size int64) Option {
+	return func(s *PhonebookService) {
+		s.maxBlobSize = size
+	}
+}
+
+// SetPhoto stores an image as the contact's photo, with a thumbnail of it,
+// replacing the previous photo.
+func (s *PhonebookService) SetPhoto(id string, content []byte) (bool, string, domain.Photo) {
+	success, message, id, data, store := s.readForBlobs(id, domain.RoleEditor)
+	if !success {
+		return false, message, domain.Photo{}
+	}
+	if int64(len(content)) > s.maxBlobSize {
+		return false, domain.ErrBlobTooLarge.Error(), domain.Photo{}
+	}
+
+	photo := domain.Photo{Blob: describeBlob(content)}
+	switch photo.ContentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return false, domain.ErrUnsupportedPhoto.Error(), domain.Photo{}
+	}
+	thumbnail, err := makeThumbnail(content, photo.ContentType)
+	if err != nil {
+		return false, err.Error(), domain.Photo{}
+	}
+	photo.Thumbnail = describeBlob(thumbnail)
+
+	if success, message := store.PutBlob(photo.Hash, content); !success {
+		return false, message, domain.Photo{}
+	}
+	if success, message := store.PutBlob(photo.Thumbnail.Hash, thumbnail); !success {
+		return false, message, domain.Photo{}
+	}
+	data[photoField] = blobRef(photo)
+	if success, message := s.db.Update(id, data); !success {
+		return false, message, domain.Photo{}
+	}
+	return true, "", photo
+}
+
+// Photo describes the contact's photo.
+func (s *PhonebookService) Photo(id string) (bool, string, domain.Photo) {
+	success, message, _, data, _ := s.readForBlobs(id, domain.RoleViewer)
+	if !success {
+		return false, message, domain.Photo{}
+	}
+	photo, ok := photoFromData(data)
+	if !ok {
+		return false, domain.ErrPhotoNotFound.Error(), domain.Photo{}
+	}
+	return true, "", photo
+}
+
+// PhotoContent returns the contact's photo, or its thumbnail.
+func (s *PhonebookService) PhotoContent(id string, thumbnail bool) (bool, string, domain.Blob, []byte) {
+	success, message, _, data, store := s.readForBlobs(id, domain.RoleViewer)
+	if !success {
+		return false, message, domain.Blob{}, nil
+	}
+	photo, ok := photoFromData(data)
+	if !ok {
+		return false, domain.ErrPhotoNotFound.Error(), domain.Blob{}, nil
+	}
+	blob := photo.Blob
+	if thumbnail {
+		blob = photo.Thumbnail
+	}
+	success, message, content := store.GetBlob(blob.Hash)
+	if !success {
+		return false, message, domain.Blob{}, nil
+	}
+	return true, "", blob, content
+}
+
+// RemovePhoto removes the contact's photo. The blobs stay until PruneBlobs
+// finds them unused.
+func (s *PhonebookService) RemovePhoto(id string) (bool, string) {
+	success, message, id, data, _ := s.readForBlobs(id, domain.RoleEditor)
+	if !success {
+		return false, message
+	}
+	if _, ok := photoFromData(data); !ok {
+		return false, domain.ErrPhotoNotFound.Error()
+	}
+	delete(data, photoField)
+	return s.db.Update(id, data)
+}
+
+// AddAttachment attaches a document to the contact, replacing the attachment
+// with the same name.
+func (s *PhonebookService) AddAttachment(id, name string, content []byte) (bool, string, domain.Attachment) {
+	if name == "" || strings.Contains(name, "/") {
+		return false, domain.ErrInvalidAttachmentName.Error(), domain.Attachment{}
+	}
+	success, message, id, data, store := s.readForBlobs(id, domain.RoleEditor)
+	if !success {
+		return false, message, domain.Attachment{}
+	}
+	if int64(len(content)) > s.maxBlobSize {
+		return false, domain.ErrBlobTooLarge.Error(), domain.Attachment{}
+	}
+
+	attachment := domain.Attachment{Name: name, Blob: describeBlob(content)}
+	if success, message := store.PutBlob(attachment.Hash, content); !success {
+		return false, message, domain.Attachment{}
+	}
+	attachments := []domain.Attachment{attachment}
+	for _, other := range attachmentsFromData(data) {
+		if other.Name != name {
+			attachments = append(attachments, other)
+		}
+	}
+	setAttachments(data, attachments)
+	if success, message := s.db.Update(id, data); !success {
+		return false, message, domain.Attachment{}
+	}
+	return true, "", attachment
+}
+
+// Attachments describes the documents attached to the contact, by name.
+func (s *PhonebookService) Attachments(id string) (bool, string, []domain.Attachment) {
+	success, message, _, data, _ := s.readForBlobs(id, domain.RoleViewer)
+	if !success {
+		return false, message, nil
+	}
+	return true, "", attachmentsFromData(data)
+}
+
+// AttachmentContent returns a document attached to the contact.
+func (s *PhonebookService) AttachmentContent(id, name string) (bool, string, domain.Attachment, []byte) {
+	success, message, _, data, store := s.readForBlobs(id, domain.RoleViewer)
+	if !success {
+		return false, message, domain.Attachment{}, nil
+	}
+	for _, attachment := range attachmentsFromData(data) {
+		if attachment.Name == name {
+			success, message, content := store.GetBlob(attachment.Hash)
+			if !success {
+				return false, message, domain.Attachment{}, nil
+			}
+			return true, "", attachment, content
+		}
+	}
+	return false, domain.ErrAttachmentNotFound.Error(), domain.Attachment{}, nil
+}
+
+// RemoveAttachment removes a document from the contact. The blob stays
+// until PruneBlobs finds it unused.
+func (s *PhonebookService) RemoveAttachment(id, name string) (bool, string) {
+	success, message, id, data, _ := s.readForBlobs(id, domain.RoleEditor)
+	if !success {
+		return false, message
+	}
+	attachments := attachmentsFromData(data)
+	for i, attachment := range attachments {
+		if attachment.Name == name {
+			setAttachments(data, append(attachments[:i], attachments[i+1:]...))
+			return s.db.Update(id, data)
+		}
+	}
+	return false, domain.ErrAttachmentNotFound.Error()
+}
+
+// PruneBlobs deletes the blobs no contact refers to, including contacts in
+// the trash, and returns how many were deleted. Blobs are shared between
+// contacts, so they are not deleted when a reference goes away. A blob
+// stored while pruning runs may be deleted before its contact refers to it,
+// so prune when nothing is being uploaded.
+func (s *PhonebookService) PruneBlobs() (bool, string, int) {
+	if err := s.authorize(domain.RoleAdmin); err != nil {
+		return false, err.Error(), 0
+	}
+	store, ok := s.db.(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error(), 0
+	}
+
+	// List the stored blobs first, so blobs referenced from here on are kept
+	success, message, hashes := store.ListBlobs()
+	if !success {
+		return false, message, 0
+	}
+	success, message, ids := s.db.List("")
+	if !success {
+		return false, message, 0
+	}
+	referenced := make(map[string]bool)
+	for _, id := range ids {
+		success, message, data := s.db.Read(id)
+		if !success {
+			if isMissing(message) {
+				continue
+			}
+			return false, message, 0
+		}
+		if photo, ok := photoFromData(data); ok {
+			referenced[photo.Hash] = true
+			referenced[photo.Thumbnail.Hash] = true
+		}
+		for _, attachment := range attachmentsFromData(data) {
+			referenced[attachment.Hash] = true
+		}
+	}
+
+	pruned := 0
+	for _, hash := range hashes {
+		if referenced[hash] {
+			continue
+		}
+		if success, message := store.DeleteBlob(hash); !success {
+			return false, message, pruned
+		}
+		pruned++
+	}
+	return true, "", pruned
+}
+
+// readForBlobs reads a live contact the actor has role on, along with the
+// blob store.
+func (s *PhonebookService) readForBlobs(id string, role domain.Role) (bool, string, string, map[string]interface{}, ports.BlobStore) {
+	store, ok := s.db.(ports.BlobStore)
+	if !ok {
+		return false, domain.ErrBlobsNotSupported.Error(), id, nil, nil
+	}
+	success, message, id, data := s.readAuthorized(id, role)
+	if !success {
+		return false, message, id, nil, nil
+	}
+	return true, "", id, data, store
+}
+
+// describeBlob hashes content and sniffs its MIME type.
+func describeBlob(content []byte) domain.Blob {
+	sum := sha256.Sum256(content)
+	return domain.Blob{
+		Hash:        hex.EncodeToString(sum[:]),
+		ContentType: http.DetectContentType(content),
+		Size:        int64(len(content)),
+	}
+}
+
+func blobRef(value interface{}) string {
+	ref, _ := json.Marshal(value)
+	return string(ref)
+}
+
+func photoFromData(data map[string]interface{}) (domain.Photo, bool) {
+	var photo domain.Photo
+	ref := stringField(data, photoField)
+	if ref == "" || json.Unmarshal([]byte(ref), &photo) != nil {
+		return domain.Photo{}, false
+	}
+	return photo, true
+}
+
+// attachmentsFromData returns the attachments of a record sorted by name.
+func attachmentsFromData(data map[string]interface{}) []domain.Attachment {
+	attachments := make([]domain.Attachment, 0)
+	for _, ref := range stringSlice(data[attachmentsField]) {
+		var attachment domain.Attachment
+		if json.Unmarshal([]byte(ref), &attachment) == nil {
+			attachments = append(attachments, attachment)
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].Name < attachments[j].Name })
+	return attachments
+}
+
+func setAttachments(data map[string]interface{}, attachments []domain.Attachment) {
+	if len(attachments) == 0 {
+		delete(data, attachmentsField)
+		return
+	}
+	refs := make([]interface{}, len(attachments))
+	for i, attachment := range attachments {
+		refs[i] = blobRef(attachment)
+	}
+	data[attachmentsField] = refs
+}
+
+// makeThumbnail scales a photo down to fit ThumbnailSize, keeping JPEG photos
+// JPEG and encoding others as PNG to keep their transparency. Photos that
+// already fit are their own thumbnail.
+func makeThumbnail(content []byte, contentType string) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil || config.Width*config.Height > maxPhotoPixels {
+		return nil, domain.ErrUnsupportedPhoto
+	}
+	if config.Width <= ThumbnailSize && config.Height <= ThumbnailSize {
+		return content, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, domain.ErrUnsupportedPhoto
+	}
+
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, scaleDown(img, ThumbnailSize), &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buf, scaleDown(img, ThumbnailSize))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// scaleDown shrinks img to fit in a size by size square, averaging the pixels
+// that fall on each pixel of the result.
+func scaleDown(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	scaledWidth, scaledHeight := size, size
+	if width > height {
+		scaledHeight = max(1, height*size/width)
+	} else {
+		scaledWidth = max(1, width*size/height)
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, scaledWidth, scaledHeight))
+	for sy := 0; sy < scaledHeight; sy++ {
+		y0, y1 := bounds.Min.Y+sy*height/scaledHeight, bounds.Min.Y+(sy+1)*height/scaledHeight
+		for sx := 0; sx < scaledWidth; sx++ {
+			x0, x1 := bounds.Min.X+sx*width/scaledWidth, bounds.Min.X+(sx+1)*width/scaledWidth
+			var r, g, b, a, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					pr, pg, pb, pa := img.At(x, y).RGBA()
+					r, g, b, a, n = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa), n+1
+				}
+			}
+			scaled.SetRGBA(sx, sy, color.RGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(b / n >> 8), A: uint8(a / n >> 8)})
+		}
+	}
+	return scaled
+}
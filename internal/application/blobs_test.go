@@ -0,0 +1,245 @@
+package application
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+// blobDatabase adds a blob store to a MockDatabase.
+type blobDatabase struct {
+	*MockDatabase
+	blobs map[string][]byte
+}
+
+func newBlobDatabase() *blobDatabase {
+	return &blobDatabase{MockDatabase: newMapDatabase(), blobs: make(map[string][]byte)}
+}
+
+func (b *blobDatabase) PutBlob(hash string, content []byte) (bool, string) {
+	b.blobs[hash] = content
+	return true, ""
+}
+
+func (b *blobDatabase) GetBlob(hash string) (bool, string, []byte) {
+	content, ok := b.blobs[hash]
+	if !ok {
+		return false, "Blob does not exist", nil
+	}
+	return true, "", content
+}
+
+func (b *blobDatabase) DeleteBlob(hash string) (bool, string) {
+	delete(b.blobs, hash)
+	return true, ""
+}
+
+func (b *blobDatabase) ListBlobs() (bool, string, []string) {
+	hashes := make([]string, 0, len(b.blobs))
+	for hash := range b.blobs {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+	return true, "", hashes
+}
+
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	return img
+}
+
+func encodeImage(t *testing.T, format string, img image.Image) []byte {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestPhonebookService_SetPhoto(t *testing.T) {
+	largePNG := encodeImage(t, "png", testImage(300, 150))
+	smallJPEG := encodeImage(t, "jpeg", testImage(64, 64))
+
+	tests := []struct {
+		name              string
+		id                string
+		content           []byte
+		expectedMessage   string
+		expectedType      string
+		expectedThumbType string
+		expectedThumbSize image.Point
+	}{
+		{"large png is scaled down", "john", largePNG, "", "image/png", "image/png", image.Pt(128, 64)},
+		{"tall jpeg keeps its format", "john", encodeImage(t, "jpeg", testImage(100, 400)), "", "image/jpeg", "image/jpeg", image.Pt(32, 128)},
+		{"gif thumbnail is png", "john", encodeImage(t, "gif", testImage(256, 256)), "", "image/gif", "image/png", image.Pt(128, 128)},
+		{"small photo is its own thumbnail", "john", smallJPEG, "", "image/jpeg", "image/jpeg", image.Pt(64, 64)},
+		{"not an image", "john", []byte("%PDF-1.4 contract"), domain.ErrUnsupportedPhoto.Error(), "", "", image.Point{}},
+		{"corrupt image", "john", largePNG[:100], domain.ErrUnsupportedPhoto.Error(), "", "", image.Point{}},
+		{"too large", "john", make([]byte, 65<<10), domain.ErrBlobTooLarge.Error(), "", "", image.Point{}},
+		{"missing contact", "nobody", smallJPEG, "Location does not exist", "", "", image.Point{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newBlobDatabase()
+			s := NewPhonebookService(db, WithMaxBlobSize(64<<10))
+			require.True(t, mustSucceed(s.AddContact("john", domain.Contact{Name: "John", Phone: "+256700000001"})))
+
+			success, message, photo := s.SetPhoto(tt.id, tt.content)
+			assert.Equal(t, tt.expectedMessage == "", success)
+			assert.Equal(t, tt.expectedMessage, message)
+			if !success {
+				assert.Empty(t, db.blobs)
+				return
+			}
+			assert.Equal(t, tt.expectedType, photo.ContentType)
+			assert.Equal(t, int64(len(tt.content)), photo.Size)
+			assert.Equal(t, tt.expectedThumbType, photo.Thumbnail.ContentType)
+
+			success, message, blob, content := s.PhotoContent("john", false)
+			require.True(t, success, message)
+			assert.Equal(t, photo.Blob, blob)
+			assert.Equal(t, tt.content, content)
+
+			success, message, blob, content = s.PhotoContent("john", true)
+			require.True(t, success, message)
+			assert.Equal(t, photo.Thumbnail, blob)
+			config, _, err := image.DecodeConfig(bytes.NewReader(content))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedThumbSize, image.Pt(config.Width, config.Height))
+		})
+	}
+}
+
+func TestPhonebookService_PhotoLifecycle(t *testing.T) {
+	db := newBlobDatabase()
+	s := NewPhonebookService(db)
+	require.True(t, mustSucceed(s.AddContact("john", domain.Contact{Name: "John", Phone: "+256700000001"})))
+	require.True(t, mustSucceed(s.AddContact("jane", domain.Contact{Name: "Jane", Phone: "+256700000002"})))
+
+	success, message, _ := s.Photo("john")
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrPhotoNotFound.Error(), message)
+
+	content := encodeImage(t, "png", testImage(200, 200))
+	_, _, photo := s.SetPhoto("john", content)
+	_, _, shared := s.SetPhoto("jane", content)
+	assert.Equal(t, photo, shared)
+	assert.Len(t, db.blobs, 2, "identical photos are stored once")
+
+	// Editing the contact keeps its photo
+	require.True(t, mustSucceed(s.UpdateContact("john", domain.Contact{Name: "Johnny", Phone: "+256700000001"})))
+	success, message, stored := s.Photo("john")
+	require.True(t, success, message)
+	assert.Equal(t, photo, stored)
+
+	require.True(t, mustSucceed(s.RemovePhoto("john")))
+	success, message, _ = s.Photo("john")
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrPhotoNotFound.Error(), message)
+	assert.Len(t, db.blobs, 2, "jane still uses the photo")
+
+	require.True(t, mustSucceed(s.RemovePhoto("jane")))
+	success, message, pruned := s.PruneBlobs()
+	require.True(t, success, message)
+	assert.Equal(t, 2, pruned)
+	assert.Empty(t, db.blobs)
+}
+
+func TestPhonebookService_Attachments(t *testing.T) {
+	db := newBlobDatabase()
+	s := NewPhonebookService(db)
+	require.True(t, mustSucceed(s.AddContact("john", domain.Contact{Name: "John", Phone: "+256700000001"})))
+	require.True(t, mustSucceed(s.AddContact("jane", domain.Contact{Name: "Jane", Phone: "+256700000002"})))
+
+	contract := []byte("%PDF-1.4 contract")
+	card := []byte("BEGIN:VCARD\r\nFN:John\r\nEND:VCARD\r\n")
+	tests := []struct {
+		name            string
+		id              string
+		attachment      string
+		content         []byte
+		expectedMessage string
+		expectedType    string
+	}{
+		{"pdf", "john", "contract.pdf", contract, "", "application/pdf"},
+		{"text", "john", "card.vcf", card, "", "text/plain; charset=utf-8"},
+		{"same content on another contact", "jane", "contract.pdf", contract, "", "application/pdf"},
+		{"empty name", "john", "", card, domain.ErrInvalidAttachmentName.Error(), ""},
+		{"name with a slash", "john", "a/b", card, domain.ErrInvalidAttachmentName.Error(), ""},
+		{"missing contact", "nobody", "card.vcf", card, "Location does not exist", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			success, message, attachment := s.AddAttachment(tt.id, tt.attachment, tt.content)
+			assert.Equal(t, tt.expectedMessage == "", success)
+			assert.Equal(t, tt.expectedMessage, message)
+			assert.Equal(t, tt.expectedType, attachment.ContentType)
+		})
+	}
+	assert.Len(t, db.blobs, 2, "identical attachments are stored once")
+
+	success, message, attachments := s.Attachments("john")
+	require.True(t, success, message)
+	require.Len(t, attachments, 2)
+	assert.Equal(t, "card.vcf", attachments[0].Name)
+	assert.Equal(t, "contract.pdf", attachments[1].Name)
+
+	// Attaching under an existing name replaces the attachment
+	_, _, replaced := s.AddAttachment("john", "card.vcf", contract)
+	success, message, attachment, content := s.AttachmentContent("john", "card.vcf")
+	require.True(t, success, message)
+	assert.Equal(t, replaced, attachment)
+	assert.Equal(t, contract, content)
+
+	require.True(t, mustSucceed(s.RemoveAttachment("john", "card.vcf")))
+	success, message = s.RemoveAttachment("john", "card.vcf")
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrAttachmentNotFound.Error(), message)
+	success, message, _, _ = s.AttachmentContent("john", "card.vcf")
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrAttachmentNotFound.Error(), message)
+
+	// Trashed contacts keep their attachments, only unused blobs are pruned
+	require.True(t, mustSucceed(s.DeleteContact("jane")))
+	success, message, pruned := s.PruneBlobs()
+	require.True(t, success, message)
+	assert.Equal(t, 1, pruned)
+	_, _, attachments = s.Attachments("john")
+	assert.Len(t, attachments, 1)
+	assert.Len(t, db.blobs, 1)
+}
+
+func TestPhonebookService_BlobsNotSupported(t *testing.T) {
+	s := NewPhonebookService(newMapDatabase())
+	require.True(t, mustSucceed(s.AddContact("john", domain.Contact{Name: "John", Phone: "+256700000001"})))
+
+	success, message, _ := s.AddAttachment("john", "card.vcf", []byte("card"))
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrBlobsNotSupported.Error(), message)
+	success, message, _ = s.PruneBlobs()
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrBlobsNotSupported.Error(), message)
+}
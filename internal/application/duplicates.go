@@ -0,0 +1,437 @@
+package application
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+// DefaultDuplicateThreshold is the minimum score reported by FindDuplicates.
+const DefaultDuplicateThreshold = 0.6
+
+// Merge bookkeeping stored alongside the contact fields.
+const (
+	redirectField     = "_redirect_to"
+	mergedAtField     = "_merged_at"
+	mergeHistoryField = "_merge_history"
+	otherPhonesField  = "_other_phones"
+	otherEmailsField  = "_other_emails"
+)
+
+// Strength of each signal in a duplicate score. Signals are combined as
+// independent evidence, so no single one reaches the default threshold alone.
+const (
+	phoneWeight = 0.5
+	emailWeight = 0.5
+	nameWeight  = 0.5
+)
+
+// FindDuplicates scores every pair of live contacts under prefix that share a
+// phone number, an email address or a phonetic name key, and returns the pairs
+// scoring at least threshold, best first.
+func (s *PhonebookService) FindDuplicates(prefix string, threshold float64) (bool, string, []domain.DuplicateCandidate) {
+	success, message, phonebook := s.ListContacts(prefix)
+	if !success {
+		return false, message, nil
+	}
+
+	// Group contacts into blocks so only plausible pairs are compared
+	blocks := make(map[string][]string)
+	for id, contact := range phonebook.Contacts {
+		if phone := NormalizePhone(contact.Phone); phone != "" {
+			blocks["phone:"+phone] = append(blocks["phone:"+phone], id)
+		}
+		if email := normalizeEmail(contact.Email); email != "" {
+			blocks["email:"+email] = append(blocks["email:"+email], id)
+		}
+		for _, token := range nameTokens(contact.Name) {
+			key := "name:" + soundex(token)
+			blocks[key] = append(blocks[key], id)
+		}
+	}
+
+	seen := make(map[[2]string]bool)
+	candidates := make([]domain.DuplicateCandidate, 0)
+	for _, ids := range blocks {
+		sort.Strings(ids)
+		for i := 0; i < len(ids); i++ {
+			for j := i + 1; j < len(ids); j++ {
+				pair := [2]string{ids[i], ids[j]}
+				if pair[0] == pair[1] || seen[pair] {
+					continue
+				}
+				seen[pair] = true
+
+				score, reasons := scoreDuplicate(phonebook.Contacts[pair[0]], phonebook.Contacts[pair[1]])
+				if score >= threshold {
+					candidates = append(candidates, domain.DuplicateCandidate{IDs: pair, Score: score, Reasons: reasons})
+				}
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].IDs[0]+candidates[i].IDs[1] < candidates[j].IDs[0]+candidates[j].IDs[1]
+	})
+	return true, "", candidates
+}
+
+// MergeContacts merges the given contacts into the first one. Multi-value
+// fields are combined, the merge is appended to the survivor's history and the
+// other ids are left as redirects to the survivor.
+func (s *PhonebookService) MergeContacts(ids []string, strategy domain.MergeStrategy) (bool, string, domain.Contact) {
+	if err := s.authorize(domain.RoleEditor); err != nil {
+		return false, err.Error(), domain.Contact{}
+	}
+	if len(ids) < 2 {
+		return false, domain.ErrMergeTooFewContacts.Error(), domain.Contact{}
+	}
+	if strategy != domain.MergePreferFirst && strategy != domain.MergePreferComplete {
+		return false, domain.ErrUnknownMergeStrategy.Error(), domain.Contact{}
+	}
+
+	// All reads and writes of the merge happen in one transaction
+	var contact domain.Contact
+	var survivorID string
+	var mergedIDs []string
+	groupsMoved := false
+	success, message := s.withTx(func(tx *PhonebookService) error {
+		var err error
+		contact, survivorID, mergedIDs, err = tx.mergeContacts(ids, strategy)
+		_, groupsMoved = tx.groupStore()
+		return err
+	})
+	if !success {
+		return false, message, domain.Contact{}
+	}
+
+	// Transactions without group support leave memberships to be moved after the commit
+	if !groupsMoved {
+		for _, id := range mergedIDs {
+			if success, message := s.moveMemberships(id, survivorID); !success {
+				return false, message, domain.Contact{}
+			}
+		}
+	}
+
+	return true, "", contact
+}
+
+// mergeContacts performs a merge and returns the merged contact, the survivor's
+// id and the ids that now redirect to it.
+func (s *PhonebookService) mergeContacts(ids []string, strategy domain.MergeStrategy) (domain.Contact, string, []string, error) {
+	// Read every contact, resolving ids that are already redirects
+	resolved := make([]string, 0, len(ids))
+	records := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		success, message, liveID, data := s.readLive(id)
+		if !success {
+			return domain.Contact{}, "", nil, errors.New(message)
+		}
+		resolved = append(resolved, liveID)
+		records = append(records, data)
+	}
+
+	survivorID := resolved[0]
+	merged := records[0]
+	contact := contactFromData(merged)
+	phones := newValueSet(NormalizePhone, append([]string{contact.Phone}, stringSlice(merged[otherPhonesField])...)...)
+	emails := newValueSet(normalizeEmail, append([]string{contact.Email}, stringSlice(merged[otherEmailsField])...)...)
+	mergedIDs := make([]interface{}, 0, len(ids)-1)
+	seen := map[string]bool{survivorID: true}
+
+	for i := 1; i < len(records); i++ {
+		if seen[resolved[i]] {
+			continue
+		}
+		seen[resolved[i]] = true
+		other := contactFromData(records[i])
+		contact = mergeContact(contact, other, strategy)
+		phones.add(other.Phone)
+		phones.add(stringSlice(records[i][otherPhonesField])...)
+		emails.add(other.Email)
+		emails.add(stringSlice(records[i][otherEmailsField])...)
+		mergedIDs = append(mergedIDs, resolved[i])
+	}
+
+	// Every id already resolves to the survivor, so there is nothing to merge
+	if len(mergedIDs) == 0 {
+		return contact, survivorID, nil, nil
+	}
+
+	// Build the survivor's record
+	now := s.now().UTC().Format(time.RFC3339Nano)
+	data := contactToData(contact)
+	copyMetadata(data, merged)
+	data[otherPhonesField] = phones.without(contact.Phone)
+	data[otherEmailsField] = emails.without(contact.Email)
+	data[mergeHistoryField] = append(interfaceSlice(merged[mergeHistoryField]), map[string]interface{}{
+		"merged_at":  now,
+		"merged_ids": mergedIDs,
+		"strategy":   string(strategy),
+	})
+
+	if err := resultError(s.db.Update(survivorID, data)); err != nil {
+		return domain.Contact{}, "", nil, err
+	}
+
+	// Leave redirects behind so the old ids keep resolving
+	redirected := make([]string, 0, len(mergedIDs))
+	for _, id := range mergedIDs {
+		redirected = append(redirected, id.(string))
+		if err := resultError(s.moveMemberships(id.(string), survivorID)); err != nil {
+			return domain.Contact{}, "", nil, err
+		}
+		redirect := map[string]interface{}{
+			redirectField: survivorID,
+			mergedAtField: now,
+		}
+		if err := resultError(s.db.Update(id.(string), redirect)); err != nil {
+			return domain.Contact{}, "", nil, err
+		}
+	}
+
+	return contact, survivorID, redirected, nil
+}
+
+func isRedirectData(data map[string]interface{}) bool {
+	_, ok := data[redirectField]
+	return ok
+}
+
+func mergeContact(base, other domain.Contact, strategy domain.MergeStrategy) domain.Contact {
+	pick := func(a, b string) string {
+		if a == "" {
+			return b
+		}
+		if strategy == domain.MergePreferComplete && len(b) > len(a) {
+			return b
+		}
+		return a
+	}
+	// Custom fields are picked one by one, like the built-in ones
+	var custom map[string]string
+	for _, fields := range []map[string]string{base.Custom, other.Custom} {
+		for name := range fields {
+			if value := pick(base.Custom[name], other.Custom[name]); value != "" {
+				if custom == nil {
+					custom = make(map[string]string)
+				}
+				custom[name] = value
+			}
+		}
+	}
+	return domain.Contact{
+		Name:    pick(base.Name, other.Name),
+		Phone:   pick(base.Phone, other.Phone),
+		Email:   pick(base.Email, other.Email),
+		Address: pick(base.Address, other.Address),
+		Custom:  custom,
+	}
+}
+
+func scoreDuplicate(a, b domain.Contact) (float64, []string) {
+	// Probability that every signal is wrong; the score is its complement
+	miss := 1.0
+	reasons := make([]string, 0)
+
+	if phone := NormalizePhone(a.Phone); phone != "" && phone == NormalizePhone(b.Phone) {
+		miss *= 1 - phoneWeight
+		reasons = append(reasons, "same phone")
+	}
+	if email := normalizeEmail(a.Email); email != "" && email == normalizeEmail(b.Email) {
+		miss *= 1 - emailWeight
+		reasons = append(reasons, "same email")
+	}
+	if similarity := nameSimilarity(a.Name, b.Name); similarity > 0 {
+		miss *= 1 - nameWeight*similarity
+		if similarity >= 0.8 {
+			reasons = append(reasons, "similar name")
+		}
+	}
+
+	return 1 - miss, reasons
+}
+
+// nameSimilarity averages the edit-distance ratio of the normalized names with
+// the share of name tokens that sound alike.
+func nameSimilarity(a, b string) float64 {
+	tokensA, tokensB := nameTokens(a), nameTokens(b)
+	if len(tokensA) == 0 || len(tokensB) == 0 {
+		return 0
+	}
+
+	joinedA, joinedB := strings.Join(tokensA, " "), strings.Join(tokensB, " ")
+	longest := len([]rune(joinedA))
+	if l := len([]rune(joinedB)); l > longest {
+		longest = l
+	}
+	editRatio := 1 - float64(levenshtein(joinedA, joinedB))/float64(longest)
+
+	codesB := make(map[string]int)
+	for _, token := range tokensB {
+		codesB[soundex(token)]++
+	}
+	matches := 0
+	for _, token := range tokensA {
+		if code := soundex(token); codesB[code] > 0 {
+			codesB[code]--
+			matches++
+		}
+	}
+	total := len(tokensA)
+	if len(tokensB) > total {
+		total = len(tokensB)
+	}
+	phoneticRatio := float64(matches) / float64(total)
+
+	return (editRatio + phoneticRatio) / 2
+}
+
+func nameTokens(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+}
+
+// NormalizePhone keeps only the digits of a phone number, so that differently
+// formatted numbers compare equal.
+func NormalizePhone(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// soundex returns the American Soundex code of a single word.
+func soundex(word string) string {
+	codes := map[rune]rune{
+		'b': '1', 'f': '1', 'p': '1', 'v': '1',
+		'c': '2', 'g': '2', 'j': '2', 'k': '2', 'q': '2', 's': '2', 'x': '2', 'z': '2',
+		'd': '3', 't': '3',
+		'l': '4',
+		'm': '5', 'n': '5',
+		'r': '6',
+	}
+
+	result := make([]rune, 0, 4)
+	var last rune
+	for i, r := range []rune(strings.ToLower(word)) {
+		code := codes[r]
+		if i == 0 {
+			result = append(result, unicode.ToUpper(r))
+			last = code
+			continue
+		}
+		if r == 'h' || r == 'w' {
+			continue
+		}
+		if code != 0 && code != last {
+			result = append(result, code)
+			if len(result) == 4 {
+				break
+			}
+		}
+		last = code
+	}
+	for len(result) < 4 {
+		result = append(result, '0')
+	}
+	return string(result)
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// valueSet keeps distinct values, comparing them in normalized form.
+type valueSet struct {
+	normalize func(string) string
+	seen      map[string]bool
+	values    []string
+}
+
+func newValueSet(normalize func(string) string, values ...string) *valueSet {
+	set := &valueSet{normalize: normalize, seen: make(map[string]bool)}
+	set.add(values...)
+	return set
+}
+
+func (v *valueSet) add(values ...string) {
+	for _, value := range values {
+		key := v.normalize(value)
+		if key == "" || v.seen[key] {
+			continue
+		}
+		v.seen[key] = true
+		v.values = append(v.values, value)
+	}
+}
+
+// without returns the values other than primary, in a form every adapter can store.
+func (v *valueSet) without(primary string) []interface{} {
+	values := make([]interface{}, 0, len(v.values))
+	for _, value := range v.values {
+		if v.normalize(value) != v.normalize(primary) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func stringSlice(value interface{}) []string {
+	items := interfaceSlice(value)
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// interfaceSlice converts a decoded array to []interface{}. Adapters return
+// their own slice types (bson.A from Mongo), so the conversion uses reflection.
+func interfaceSlice(value interface{}) []interface{} {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice {
+		return nil
+	}
+	items := make([]interface{}, v.Len())
+	for i := range items {
+		items[i] = v.Index(i).Interface()
+	}
+	return items
+}
@@ -0,0 +1,204 @@
+package application
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+func TestSoundex(t *testing.T) {
+	tests := map[string]string{
+		"Robert":   "R163",
+		"Rupert":   "R163",
+		"Ashcraft": "A261",
+		"Tymczak":  "T522",
+		"John":     "J500",
+		"Jon":      "J500",
+		"Doe":      "D000",
+	}
+
+	for word, want := range tests {
+		if got := soundex(word); got != want {
+			t.Errorf("soundex(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestPhonebookService_FindDuplicates(t *testing.T) {
+	s := NewPhonebookService(newMapDatabase())
+	s.AddContact("contacts/john", domain.Contact{Name: "John Doe", Phone: "123-456-7890", Email: "john@example.com"})
+	s.AddContact("contacts/jon", domain.Contact{Name: "Jon Doe", Phone: "(123) 456 7890"})
+	s.AddContact("contacts/jane", domain.Contact{Name: "Jane Smith", Phone: "555-000-1111", Email: "jane@example.com"})
+	s.AddContact("contacts/jane2", domain.Contact{Name: "Jane Smyth", Phone: "555-999-2222", Email: "JANE@example.com"})
+
+	success, msg, candidates := s.FindDuplicates("contacts/", DefaultDuplicateThreshold)
+	if !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+	if len(candidates) != 2 {
+		t.Fatalf("Expected 2 candidates but got %+v", candidates)
+	}
+	found := make(map[[2]string]bool)
+	for i, c := range candidates {
+		found[c.IDs] = true
+		if c.Score < DefaultDuplicateThreshold || c.Score > 1 {
+			t.Errorf("Unexpected score %v for %v", c.Score, c.IDs)
+		}
+		if i > 0 && c.Score > candidates[i-1].Score {
+			t.Errorf("Expected candidates sorted by score but got %+v", candidates)
+		}
+	}
+	if !found[[2]string{"contacts/john", "contacts/jon"}] {
+		t.Errorf("Expected john/jon to match but got %+v", candidates)
+	}
+	if !found[[2]string{"contacts/jane", "contacts/jane2"}] {
+		t.Errorf("Expected the two Janes to match but got %+v", candidates)
+	}
+}
+
+func TestPhonebookService_MergeContacts(t *testing.T) {
+	tests := []struct {
+		name     string
+		ids      []string
+		strategy domain.MergeStrategy
+		want     domain.Contact
+		wantErr  string
+	}{
+		{
+			name:     "prefer first",
+			ids:      []string{"contacts/jon", "contacts/john"},
+			strategy: domain.MergePreferFirst,
+			want:     domain.Contact{Name: "Jon Doe", Phone: "123-456-7890", Email: "jon@example.com", Address: "123 Main St"},
+		},
+		{
+			name:     "prefer complete",
+			ids:      []string{"contacts/jon", "contacts/john"},
+			strategy: domain.MergePreferComplete,
+			want:     domain.Contact{Name: "John Doe", Phone: "123-456-7890", Email: "john.doe@example.com", Address: "123 Main St"},
+		},
+		{
+			name:     "too few contacts",
+			ids:      []string{"contacts/jon"},
+			strategy: domain.MergePreferFirst,
+			wantErr:  domain.ErrMergeTooFewContacts.Error(),
+		},
+		{
+			name:     "unknown strategy",
+			ids:      []string{"contacts/jon", "contacts/john"},
+			strategy: "newest",
+			wantErr:  domain.ErrUnknownMergeStrategy.Error(),
+		},
+		{
+			name:     "missing contact",
+			ids:      []string{"contacts/jon", "contacts/nobody"},
+			strategy: domain.MergePreferFirst,
+			wantErr:  "Location does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMapDatabase()
+			s := NewPhonebookService(db)
+			s.AddContact("contacts/jon", domain.Contact{Name: "Jon Doe", Phone: "123-456-7890", Email: "jon@example.com"})
+			s.AddContact("contacts/john", domain.Contact{Name: "John Doe", Phone: "123 456 7890", Email: "john.doe@example.com", Address: "123 Main St"})
+
+			success, msg, merged := s.MergeContacts(tt.ids, tt.strategy)
+			if tt.wantErr != "" {
+				if success || msg != tt.wantErr {
+					t.Errorf("Expected error %q but got success=%v msg=%q", tt.wantErr, success, msg)
+				}
+				return
+			}
+			if !success {
+				t.Fatalf("Expected success but got error: %s", msg)
+			}
+			if !reflect.DeepEqual(merged, tt.want) {
+				t.Errorf("Expected merged contact %+v but got %+v", tt.want, merged)
+			}
+
+			// The merged id redirects to the survivor
+			_, _, viaRedirect := s.GetContact("contacts/john")
+			if !reflect.DeepEqual(viaRedirect, tt.want) {
+				t.Errorf("Expected redirect to resolve to %+v but got %+v", tt.want, viaRedirect)
+			}
+			_, _, phonebook := s.ListContacts("contacts/")
+			if len(phonebook.Contacts) != 1 {
+				t.Errorf("Expected only the survivor to be listed but got %v", phonebook.Contacts)
+			}
+
+			// Alternate values and the merge are recorded on the survivor
+			_, _, data := db.Read("contacts/jon")
+			if emails := stringSlice(data[otherEmailsField]); len(emails) != 1 {
+				t.Errorf("Expected one alternate email but got %v", emails)
+			}
+			if phones := stringSlice(data[otherPhonesField]); len(phones) != 0 {
+				t.Errorf("Expected same phone to be deduplicated but got %v", phones)
+			}
+			if history := interfaceSlice(data[mergeHistoryField]); len(history) != 1 {
+				t.Errorf("Expected one history entry but got %v", history)
+			}
+		})
+	}
+}
+
+func TestPhonebookService_MergeContactsCustomFields(t *testing.T) {
+	tests := []struct {
+		strategy domain.MergeStrategy
+		want     map[string]string
+	}{
+		{domain.MergePreferFirst, map[string]string{"team": "ops", "floor": "3", "badge": "B-1234"}},
+		{domain.MergePreferComplete, map[string]string{"team": "platform", "floor": "3", "badge": "B-1234"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			s := NewPhonebookService(newMapDatabase())
+			for _, name := range []string{"team", "floor", "badge"} {
+				if success, msg := s.DefineField(domain.FieldDefinition{Name: name, Type: domain.FieldText}); !success {
+					t.Fatalf("Failed to define %s: %s", name, msg)
+				}
+			}
+			s.AddContact("contacts/jon", domain.Contact{Name: "Jon Doe", Phone: "123-456-7890", Custom: map[string]string{"team": "ops", "floor": "3"}})
+			s.AddContact("contacts/john", domain.Contact{Name: "John Doe", Phone: "123 456 7890", Custom: map[string]string{"team": "platform", "badge": "B-1234"}})
+
+			success, msg, merged := s.MergeContacts([]string{"contacts/jon", "contacts/john"}, tt.strategy)
+			if !success {
+				t.Fatalf("Expected success but got error: %s", msg)
+			}
+			if !reflect.DeepEqual(merged.Custom, tt.want) {
+				t.Errorf("Expected custom fields %v but got %v", tt.want, merged.Custom)
+			}
+			_, _, stored := s.GetContact("contacts/jon")
+			if !reflect.DeepEqual(stored.Custom, tt.want) {
+				t.Errorf("Expected stored custom fields %v but got %v", tt.want, stored.Custom)
+			}
+		})
+	}
+}
+
+func TestPhonebookService_MergeContactsAlreadyMerged(t *testing.T) {
+	db := newMapDatabase()
+	s := NewPhonebookService(db)
+	s.AddContact("contacts/jon", domain.Contact{Name: "Jon Doe", Phone: "123-456-7890"})
+	s.AddContact("contacts/john", domain.Contact{Name: "John Doe", Phone: "123 456 7890"})
+	if success, msg, _ := s.MergeContacts([]string{"contacts/jon", "contacts/john"}, domain.MergePreferFirst); !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+
+	for _, ids := range [][]string{{"contacts/jon", "contacts/john"}, {"contacts/jon", "contacts/jon"}} {
+		success, msg, merged := s.MergeContacts(ids, domain.MergePreferFirst)
+		if !success {
+			t.Fatalf("Expected success but got error: %s", msg)
+		}
+		if merged.Name != "Jon Doe" {
+			t.Errorf("Expected the survivor but got %+v", merged)
+		}
+	}
+
+	_, _, data := db.Read("contacts/jon")
+	if history := interfaceSlice(data[mergeHistoryField]); len(history) != 1 {
+		t.Errorf("Expected one history entry but got %v", history)
+	}
+}
@@ -0,0 +1,69 @@
+package application
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+// csvHeader is the column layout used for CSV export and import. The
+// phonebook's custom fields follow as columns named after them.
+var csvHeader = []string{"id", "name", "phone", "email", "address"}
+
+// ExportContacts writes every live contact whose id starts with prefix as CSV.
+func (s *PhonebookService) ExportContacts(prefix string, w io.Writer) (bool, string) {
+	success, message, phonebook := s.ListContacts(prefix)
+	if !success {
+		return false, message
+	}
+	return s.writeCSV(w, phonebook)
+}
+
+// ExportGroup writes the live contacts of a single group as CSV.
+func (s *PhonebookService) ExportGroup(group string, w io.Writer) (bool, string) {
+	success, message, phonebook := s.ListGroupContacts(group)
+	if !success {
+		return false, message
+	}
+	return s.writeCSV(w, phonebook)
+}
+
+func (s *PhonebookService) writeCSV(w io.Writer, phonebook *domain.Phonebook) (bool, string) {
+	schema, err := s.fieldSchema()
+	if err != nil {
+		return false, err.Error()
+	}
+	header := append([]string{}, csvHeader...)
+	for _, definition := range schema {
+		header = append(header, definition.Name)
+	}
+
+	ids := make([]string, 0, len(phonebook.Contacts))
+	for id := range phonebook.Contacts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return false, err.Error()
+	}
+	for _, id := range ids {
+		contact := phonebook.Contacts[id]
+		record := []string{id, contact.Name, contact.Phone, contact.Email, contact.Address}
+		for _, definition := range schema {
+			record = append(record, contact.Custom[definition.Name])
+		}
+		if err := writer.Write(record); err != nil {
+			return false, err.Error()
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return false, err.Error()
+	}
+	return true, ""
+}
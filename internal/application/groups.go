@@ -0,0 +1,244 @@
+package application
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func (s *PhonebookService) groupStore() (ports.GroupStore, bool) {
+	groups, ok := s.db.(ports.GroupStore)
+	return groups, ok
+}
+
+func (s *PhonebookService) CreateGroup(name string) (bool, string) {
+	if err := s.authorize(domain.RoleAdmin); err != nil {
+		return false, err.Error()
+	}
+	groups, ok := s.groupStore()
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+	if strings.TrimSpace(name) == "" {
+		return false, domain.ErrInvalidGroupName.Error()
+	}
+	return groups.CreateGroup(name)
+}
+
+func (s *PhonebookService) RenameGroup(name, newName string) (bool, string) {
+	if err := s.authorize(domain.RoleAdmin); err != nil {
+		return false, err.Error()
+	}
+	groups, ok := s.groupStore()
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+	if strings.TrimSpace(newName) == "" {
+		return false, domain.ErrInvalidGroupName.Error()
+	}
+	return groups.RenameGroup(name, newName)
+}
+
+func (s *PhonebookService) DeleteGroup(name string) (bool, string) {
+	if err := s.authorize(domain.RoleAdmin); err != nil {
+		return false, err.Error()
+	}
+	groups, ok := s.groupStore()
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+	return groups.DeleteGroup(name)
+}
+
+// ListGroups returns every group, or only the granted ones to actors without
+// a global role.
+func (s *PhonebookService) ListGroups() (bool, string, []string) {
+	groups, ok := s.groupStore()
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error(), nil
+	}
+
+	success, message, names := groups.ListGroups()
+	if !success || s.authorize(domain.RoleViewer) == nil {
+		return success, message, names
+	}
+	granted := make([]string, 0)
+	for _, name := range names {
+		if s.authorizeGroup(name, domain.RoleViewer) == nil {
+			granted = append(granted, name)
+		}
+	}
+	return true, "", granted
+}
+
+// AddToGroup adds a live contact to a group. Merged ids resolve to the survivor.
+func (s *PhonebookService) AddToGroup(group, id string) (bool, string) {
+	groups, ok := s.groupStore()
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+
+	success, message, id, _ := s.readLive(id)
+	if err := s.authorizeMembership(group, id); err != nil {
+		return false, err.Error()
+	}
+	if !success {
+		return false, message
+	}
+	return groups.AddMember(group, id)
+}
+
+func (s *PhonebookService) RemoveFromGroup(group, id string) (bool, string) {
+	groups, ok := s.groupStore()
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error()
+	}
+
+	success, message, id, _ := s.readLive(id)
+	if err := s.authorizeMembership(group, id); err != nil {
+		return false, err.Error()
+	}
+	if !success {
+		return false, message
+	}
+	return groups.RemoveMember(group, id)
+}
+
+// GetGroup returns a group with its live members.
+func (s *PhonebookService) GetGroup(name string) (bool, string, domain.Group) {
+	success, message, phonebook := s.ListGroupContacts(name)
+	if !success {
+		return false, message, domain.Group{}
+	}
+
+	group := domain.Group{Name: name, Members: make([]string, 0, len(phonebook.Contacts))}
+	for id := range phonebook.Contacts {
+		group.Members = append(group.Members, id)
+	}
+	sort.Strings(group.Members)
+	return true, "", group
+}
+
+// GroupsOfContact returns the groups a contact belongs to.
+func (s *PhonebookService) GroupsOfContact(id string) (bool, string, []string) {
+	groups, ok := s.groupStore()
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error(), nil
+	}
+
+	success, message, id, _ := s.readAuthorized(id, domain.RoleViewer)
+	if !success {
+		return false, message, nil
+	}
+	return groups.GroupsOf(id)
+}
+
+// ListGroupContacts returns the live contacts in a group.
+func (s *PhonebookService) ListGroupContacts(group string) (bool, string, *domain.Phonebook) {
+	groups, ok := s.groupStore()
+	if !ok {
+		return false, domain.ErrGroupsNotSupported.Error(), nil
+	}
+
+	success, message, ids := groups.Members(group)
+	if !success {
+		return false, message, nil
+	}
+	return s.readContacts(ids)
+}
+
+// SearchContacts returns the live contacts whose fields, custom ones
+// included, contain query, ignoring case. A non-empty group limits the
+// search to that group.
+func (s *PhonebookService) SearchContacts(query, group string) (bool, string, *domain.Phonebook) {
+	var success bool
+	var message string
+	var phonebook *domain.Phonebook
+	if group == "" {
+		success, message, phonebook = s.ListContacts("")
+	} else {
+		success, message, phonebook = s.ListGroupContacts(group)
+	}
+	if !success {
+		return false, message, nil
+	}
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	for id, contact := range phonebook.Contacts {
+		if !matchesQuery(contact, query) {
+			delete(phonebook.Contacts, id)
+		}
+	}
+	return true, "", phonebook
+}
+
+// FindByPhone returns the live contacts whose phone number matches phone,
+// ignoring formatting. Databases with an index over phone numbers answer
+// without reading every contact.
+func (s *PhonebookService) FindByPhone(phone string) (bool, string, *domain.Phonebook) {
+	digits := NormalizePhone(phone)
+	if index, ok := s.db.(ports.FieldIndex); ok && index.IndexedField("phone") {
+		success, message, ids := index.LookupField("phone", digits)
+		if !success {
+			return false, message, nil
+		}
+		return s.readContacts(ids)
+	}
+
+	success, message, phonebook := s.ListContacts("")
+	if !success {
+		return false, message, nil
+	}
+	for id, contact := range phonebook.Contacts {
+		if digits == "" || NormalizePhone(contact.Phone) != digits {
+			delete(phonebook.Contacts, id)
+		}
+	}
+	return true, "", phonebook
+}
+
+// moveMemberships copies the group memberships of from onto to and removes
+// them from from. It is a no-op when the database has no groups.
+func (s *PhonebookService) moveMemberships(from, to string) (bool, string) {
+	groups, ok := s.groupStore()
+	if !ok {
+		return true, ""
+	}
+
+	success, message, names := groups.GroupsOf(from)
+	if !success {
+		return false, message
+	}
+	for _, name := range names {
+		if success, message := groups.AddMember(name, to); !success {
+			return false, message
+		}
+		if success, message := groups.RemoveMember(name, from); !success {
+			return false, message
+		}
+	}
+	return true, ""
+}
+
+func matchesQuery(contact domain.Contact, query string) bool {
+	if query == "" {
+		return true
+	}
+	for _, field := range []string{contact.Name, contact.Phone, contact.Email, contact.Address} {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	for _, value := range contact.Custom {
+		if strings.Contains(strings.ToLower(value), query) {
+			return true
+		}
+	}
+	// Let "1234567" find "123-4567"
+	if digits := NormalizePhone(query); digits != "" && digits == query {
+		return strings.Contains(NormalizePhone(contact.Phone), digits)
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+package application
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// MockGroupDatabase adds an in-memory ports.GroupStore to a MockDatabase
+type MockGroupDatabase struct {
+	*MockDatabase
+	groups map[string]map[string]bool
+}
+
+func newMapGroupDatabase() *MockGroupDatabase {
+	return &MockGroupDatabase{MockDatabase: newMapDatabase(), groups: make(map[string]map[string]bool)}
+}
+
+func (m *MockGroupDatabase) CreateGroup(name string) (bool, string) {
+	if _, exists := m.groups[name]; exists {
+		return false, "Group already exists"
+	}
+	m.groups[name] = make(map[string]bool)
+	return true, ""
+}
+
+func (m *MockGroupDatabase) RenameGroup(name, newName string) (bool, string) {
+	members, exists := m.groups[name]
+	if !exists {
+		return false, "Group does not exist"
+	}
+	delete(m.groups, name)
+	m.groups[newName] = members
+	return true, ""
+}
+
+func (m *MockGroupDatabase) DeleteGroup(name string) (bool, string) {
+	if _, exists := m.groups[name]; !exists {
+		return false, "Group does not exist"
+	}
+	delete(m.groups, name)
+	return true, ""
+}
+
+func (m *MockGroupDatabase) ListGroups() (bool, string, []string) {
+	return true, "", sortedKeys(m.groups)
+}
+
+func (m *MockGroupDatabase) AddMember(group, location string) (bool, string) {
+	if _, exists := m.groups[group]; !exists {
+		return false, "Group does not exist"
+	}
+	m.groups[group][location] = true
+	return true, ""
+}
+
+func (m *MockGroupDatabase) RemoveMember(group, location string) (bool, string) {
+	if _, exists := m.groups[group]; !exists {
+		return false, "Group does not exist"
+	}
+	delete(m.groups[group], location)
+	return true, ""
+}
+
+func (m *MockGroupDatabase) Members(group string) (bool, string, []string) {
+	members, exists := m.groups[group]
+	if !exists {
+		return false, "Group does not exist", nil
+	}
+	return true, "", sortedKeys(members)
+}
+
+func (m *MockGroupDatabase) GroupsOf(location string) (bool, string, []string) {
+	names := make([]string, 0)
+	for name, members := range m.groups {
+		if members[location] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return true, "", names
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestPhonebookService_Groups(t *testing.T) {
+	s := NewPhonebookService(newMapGroupDatabase())
+	s.AddContact("contacts/john", domain.Contact{Name: "John Doe", Phone: "123-456-7890", Email: "john@example.com"})
+	s.AddContact("contacts/jane", domain.Contact{Name: "Jane Doe", Phone: "555-000-1111", Address: "1 Elm St"})
+	s.AddContact("contacts/bob", domain.Contact{Name: "Bob Smith", Phone: "555-000-2222"})
+
+	if success, msg := s.CreateGroup(" "); success || msg != domain.ErrInvalidGroupName.Error() {
+		t.Errorf("Expected invalid group name but got %q", msg)
+	}
+	s.CreateGroup("family")
+	s.AddToGroup("family", "contacts/john")
+	s.AddToGroup("family", "contacts/jane")
+	if success, _ := s.AddToGroup("family", "contacts/nobody"); success {
+		t.Error("Expected adding a missing contact to fail")
+	}
+
+	_, _, group := s.GetGroup("family")
+	if !reflect.DeepEqual(group.Members, []string{"contacts/jane", "contacts/john"}) {
+		t.Errorf("Unexpected members %v", group.Members)
+	}
+
+	// Trashed contacts are hidden from group listings
+	s.DeleteContact("contacts/jane")
+	_, _, phonebook := s.ListGroupContacts("family")
+	if len(phonebook.Contacts) != 1 {
+		t.Errorf("Expected only john in the group but got %v", phonebook.Contacts)
+	}
+	s.RestoreContact("contacts/jane")
+
+	s.RenameGroup("family", "relatives")
+	_, _, groups := s.GroupsOfContact("contacts/john")
+	if !reflect.DeepEqual(groups, []string{"relatives"}) {
+		t.Errorf("Unexpected groups %v", groups)
+	}
+
+	s.RemoveFromGroup("relatives", "contacts/john")
+	_, _, group = s.GetGroup("relatives")
+	if !reflect.DeepEqual(group.Members, []string{"contacts/jane"}) {
+		t.Errorf("Unexpected members %v", group.Members)
+	}
+}
+
+func TestPhonebookService_GroupsNotSupported(t *testing.T) {
+	s := NewPhonebookService(newMapDatabase())
+	if success, msg := s.CreateGroup("family"); success || msg != domain.ErrGroupsNotSupported.Error() {
+		t.Errorf("Expected %q but got %q", domain.ErrGroupsNotSupported.Error(), msg)
+	}
+}
+
+func TestPhonebookService_SearchContacts(t *testing.T) {
+	s := NewPhonebookService(newMapGroupDatabase())
+	s.AddContact("contacts/john", domain.Contact{Name: "John Doe", Phone: "123-456-7890", Email: "john@example.com"})
+	s.AddContact("contacts/jane", domain.Contact{Name: "Jane Doe", Phone: "555-000-1111", Address: "1 Elm St"})
+	s.AddContact("contacts/bob", domain.Contact{Name: "Bob Smith", Phone: "555-000-2222"})
+	s.CreateGroup("family")
+	s.AddToGroup("family", "contacts/john")
+
+	tests := []struct {
+		name  string
+		query string
+		group string
+		want  []string
+	}{
+		{name: "by name", query: "doe", want: []string{"contacts/jane", "contacts/john"}},
+		{name: "by address", query: "elm", want: []string{"contacts/jane"}},
+		{name: "by phone digits", query: "5550002222", want: []string{"contacts/bob"}},
+		{name: "within group", query: "doe", group: "family", want: []string{"contacts/john"}},
+		{name: "no match", query: "zzz", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			success, msg, phonebook := s.SearchContacts(tt.query, tt.group)
+			if !success {
+				t.Fatalf("Expected success but got error: %s", msg)
+			}
+			if got := sortedKeys(phonebook.Contacts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// indexedDatabase answers phone lookups from a map, recording the values asked for.
+type indexedDatabase struct {
+	*MockGroupDatabase
+	phones  map[string][]string
+	lookups []string
+}
+
+func (db *indexedDatabase) IndexedField(field string) bool {
+	return field == "phone"
+}
+
+func (db *indexedDatabase) LookupField(field, value string) (bool, string, []string) {
+	db.lookups = append(db.lookups, value)
+	return true, "", db.phones[value]
+}
+
+func TestPhonebookService_FindByPhone(t *testing.T) {
+	indexed := &indexedDatabase{MockGroupDatabase: newMapGroupDatabase()}
+	for name, db := range map[string]ports.Database{"scan": newMapGroupDatabase(), "index": indexed} {
+		t.Run(name, func(t *testing.T) {
+			s := NewPhonebookService(db)
+			s.AddContact("contacts/john", domain.Contact{Name: "John Doe", Phone: "123-456-7890"})
+			s.AddContact("contacts/jane", domain.Contact{Name: "Jane Doe", Phone: "(123) 456 7890"})
+			s.AddContact("contacts/bob", domain.Contact{Name: "Bob Smith", Phone: "555-000-2222"})
+			s.DeleteContact("contacts/bob")
+			indexed.phones = map[string][]string{
+				"1234567890": {"contacts/jane", "contacts/john"},
+				"5550002222": {"contacts/bob"},
+			}
+
+			tests := []struct {
+				phone string
+				want  []string
+			}{
+				{"123.456.7890", []string{"contacts/jane", "contacts/john"}},
+				{"5550002222", []string{}},
+				{"", []string{}},
+			}
+			for _, tt := range tests {
+				success, msg, phonebook := s.FindByPhone(tt.phone)
+				if !success {
+					t.Fatalf("Expected success but got error: %s", msg)
+				}
+				if got := sortedKeys(phonebook.Contacts); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("%q: expected %v but got %v", tt.phone, tt.want, got)
+				}
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(indexed.lookups, []string{"1234567890", "5550002222", ""}) {
+		t.Errorf("Expected the index to be used but got lookups %v", indexed.lookups)
+	}
+}
+
+func TestPhonebookService_ExportGroup(t *testing.T) {
+	s := NewPhonebookService(newMapGroupDatabase())
+	s.AddContact("contacts/john", domain.Contact{Name: "John Doe", Phone: "123-456-7890", Email: "john@example.com"})
+	s.AddContact("contacts/bob", domain.Contact{Name: "Bob Smith", Phone: "555-000-2222"})
+	s.CreateGroup("family")
+	s.AddToGroup("family", "contacts/john")
+
+	var buf bytes.Buffer
+	if success, msg := s.ExportGroup("family", &buf); !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+
+	want := "id,name,phone,email,address\ncontacts/john,John Doe,123-456-7890,john@example.com,\n"
+	if buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+}
@@ -0,0 +1,278 @@
+package application
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// importBatchSize is how many valid rows are written per batch.
+const importBatchSize = 500
+
+// ImportCSV reads contacts in the layout written by ExportContacts and stores
+// them in batches. Columns named after custom fields fill those fields.
+// Existing contacts are replaced when overwrite is set, keeping the custom
+// values a row leaves empty, and reported as errors otherwise. Invalid rows
+// are reported and skipped.
+func (s *PhonebookService) ImportCSV(r io.Reader, overwrite bool) (bool, string, []domain.ImportResult) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return false, err.Error(), nil
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"id", "name", "phone"} {
+		if _, ok := columns[required]; !ok {
+			return false, domain.ErrInvalidImportHeader.Error(), nil
+		}
+	}
+
+	importer := s.NewImporter(overwrite)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			importer.Fail(line, "", err.Error())
+			continue
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		contact := domain.Contact{
+			Name:    field("name"),
+			Phone:   field("phone"),
+			Email:   field("email"),
+			Address: field("address"),
+		}
+		for _, definition := range importer.schema {
+			if value := field(definition.Name); value != "" {
+				if contact.Custom == nil {
+					contact.Custom = make(map[string]string)
+				}
+				contact.Custom[definition.Name] = value
+			}
+		}
+		importer.Add(line, field("id"), contact)
+	}
+
+	return true, "", importer.Close()
+}
+
+// Importer validates contacts one at a time and writes them in batches,
+// keeping a result for each. Results are final once Close returns.
+type Importer struct {
+	s         *PhonebookService
+	overwrite bool
+	results   []domain.ImportResult
+	pending   []pendingContact
+	// quota is how many more contacts the tenant may hold, -1 for no limit.
+	quota    int
+	quotaErr error
+	// schema is read once, rather than for every contact.
+	schema    []domain.FieldDefinition
+	schemaErr error
+}
+
+// NewImporter starts an import. Existing contacts are replaced when overwrite
+// is set and reported as errors otherwise.
+func (s *PhonebookService) NewImporter(overwrite bool) *Importer {
+	quota, err := s.quotaLeft()
+	schema, schemaErr := s.fieldSchema()
+	return &Importer{
+		s:         s,
+		overwrite: overwrite,
+		results:   make([]domain.ImportResult, 0),
+		pending:   make([]pendingContact, 0, importBatchSize),
+		quota:     quota,
+		quotaErr:  err,
+		schema:    schema,
+		schemaErr: schemaErr,
+	}
+}
+
+// Add queues a contact, reporting it against line. Invalid contacts are
+// recorded as failures and skipped.
+func (imp *Importer) Add(line int, id string, contact domain.Contact) {
+	result := domain.ImportResult{Line: line, ID: id}
+	if err := imp.s.authorize(domain.RoleEditor); err != nil {
+		imp.Fail(line, id, err.Error())
+		return
+	}
+	if id == "" {
+		imp.Fail(line, id, domain.ErrMissingContactID.Error())
+		return
+	}
+	if isReserved(id) {
+		imp.Fail(line, id, domain.ErrReservedContactID.Error())
+		return
+	}
+	if err := imp.validate(contact); err != nil {
+		imp.Fail(line, id, err.Error())
+		return
+	}
+	// Whether an overwrite replaces a contact is only known once its batch
+	// is read, so its quota is taken then
+	if !imp.overwrite {
+		if err := imp.takeQuota(); err != nil {
+			imp.Fail(line, id, err.Error())
+			return
+		}
+	}
+
+	imp.results = append(imp.results, result)
+	imp.pending = append(imp.pending, pendingContact{id: id, contact: contact, result: len(imp.results) - 1})
+	if len(imp.pending) == importBatchSize {
+		imp.flush()
+	}
+}
+
+func (imp *Importer) validate(contact domain.Contact) error {
+	if err := validateCoreFields(contact); err != nil {
+		return err
+	}
+	if imp.schemaErr != nil {
+		return imp.schemaErr
+	}
+	return validateCustom(imp.schema, contact.Custom)
+}
+
+// takeQuota counts a new contact against the tenant's quota.
+func (imp *Importer) takeQuota() error {
+	if imp.quotaErr != nil || imp.quota < 0 {
+		return imp.quotaErr
+	}
+	if imp.quota == 0 {
+		return domain.ErrTenantQuotaExceeded
+	}
+	imp.quota--
+	return nil
+}
+
+// Fail records a line that could not be read.
+func (imp *Importer) Fail(line int, id, message string) {
+	imp.results = append(imp.results, domain.ImportResult{Line: line, ID: id, Message: message})
+}
+
+// Close writes the remaining contacts and returns the results in input order.
+func (imp *Importer) Close() []domain.ImportResult {
+	imp.flush()
+	return imp.results
+}
+
+// pendingContact is a valid contact waiting to be written.
+type pendingContact struct {
+	id      string
+	contact domain.Contact
+	// result is the index of the contact's result.
+	result int
+}
+
+func (imp *Importer) flush() {
+	if len(imp.pending) == 0 {
+		return
+	}
+	defer func() { imp.pending = imp.pending[:0] }()
+
+	if !imp.overwrite {
+		items := make([]ports.BatchItem, len(imp.pending))
+		for i, p := range imp.pending {
+			items[i] = ports.BatchItem{Location: p.id, Data: contactToData(p.contact)}
+		}
+		imp.record(imp.pending, imp.s.batchCreate(items))
+		return
+	}
+
+	// Replaced contacts keep their metadata and custom values, like
+	// UpdateContact, so the existing records are read first
+	ids := make([]string, len(imp.pending))
+	for i, p := range imp.pending {
+		ids[i] = p.id
+	}
+	success, message, existing := imp.s.batchRead(ids)
+	if !success {
+		for _, p := range imp.pending {
+			imp.results[p.result].Message = message
+		}
+		return
+	}
+
+	written := make([]pendingContact, 0, len(imp.pending))
+	items := make([]ports.BatchItem, 0, len(imp.pending))
+	created := make(map[string]bool)
+	for _, p := range imp.pending {
+		var data map[string]interface{}
+		if stored, ok := existing[p.id]; ok {
+			// Replacing a contact needs the role on that contact, like UpdateContact
+			if err := imp.s.authorizeContact(p.id, domain.RoleEditor); err != nil {
+				imp.results[p.result].Message = err.Error()
+				continue
+			}
+			var err error
+			if data, err = imp.replacement(p.contact, stored); err != nil {
+				imp.results[p.result].Message = err.Error()
+				continue
+			}
+		} else {
+			if !created[p.id] {
+				if err := imp.takeQuota(); err != nil {
+					imp.results[p.result].Message = err.Error()
+					continue
+				}
+				created[p.id] = true
+			}
+			data = contactToData(p.contact)
+		}
+		written = append(written, p)
+		items = append(items, ports.BatchItem{Location: p.id, Data: data})
+	}
+	imp.record(written, imp.s.batchUpsert(items))
+}
+
+// replacement builds the record replacing stored with contact. Trashed
+// contacts must be restored first, and merged ones are only kept as
+// redirects.
+func (imp *Importer) replacement(contact domain.Contact, stored map[string]interface{}) (map[string]interface{}, error) {
+	if isTrashedData(stored) {
+		return nil, domain.ErrContactInTrash
+	}
+	if isRedirectData(stored) {
+		return nil, domain.ErrContactMerged
+	}
+	current, version, _ := decodeContact(stored)
+	if version > currentVersion {
+		return nil, domain.ErrUnsupportedRecordVersion
+	}
+	contact.Custom = mergeCustom(current.Custom, contact.Custom)
+	if err := validateCustom(imp.schema, contact.Custom); err != nil {
+		return nil, err
+	}
+
+	data := contactToData(contact)
+	copyMetadata(data, stored)
+	return data, nil
+}
+
+// record stores the outcome of writing contacts.
+func (imp *Importer) record(contacts []pendingContact, written []ports.BatchResult) {
+	for i, result := range written {
+		imp.results[contacts[i].result].Success = result.Success
+		imp.results[contacts[i].result].Message = result.Message
+	}
+}
@@ -0,0 +1,225 @@
+package application
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+func TestPhonebookService_ImportCSV(t *testing.T) {
+	input := strings.Join([]string{
+		"ID,Name,Phone,Email",
+		"contacts/john,John Doe,123-456-7890,john@example.com",
+		"contacts/nameless,,555-000-1111,",
+		",Nobody,555-000-2222,",
+		"contacts/existing,Jane Doe,555-000-3333,jane@example.com",
+	}, "\n")
+
+	tests := []struct {
+		name        string
+		overwrite   bool
+		wantSuccess []bool
+		wantJane    string
+	}{
+		{name: "create only", overwrite: false, wantSuccess: []bool{true, false, false, false}, wantJane: "Old Jane"},
+		{name: "overwrite", overwrite: true, wantSuccess: []bool{true, false, false, true}, wantJane: "Jane Doe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewPhonebookService(newMapDatabase())
+			s.AddContact("contacts/existing", domain.Contact{Name: "Old Jane", Phone: "555-000-3333"})
+
+			success, msg, results := s.ImportCSV(strings.NewReader(input), tt.overwrite)
+			if !success {
+				t.Fatalf("Expected success but got error: %s", msg)
+			}
+			if len(results) != len(tt.wantSuccess) {
+				t.Fatalf("Expected %d results but got %+v", len(tt.wantSuccess), results)
+			}
+			for i, result := range results {
+				if result.Line != i+2 {
+					t.Errorf("Expected line %d but got %d", i+2, result.Line)
+				}
+				if result.Success != tt.wantSuccess[i] {
+					t.Errorf("Line %d: expected success=%v but got %+v", result.Line, tt.wantSuccess[i], result)
+				}
+			}
+			if results[1].Message != domain.ErrInvalidContactName.Error() {
+				t.Errorf("Expected validation error but got %q", results[1].Message)
+			}
+			if results[2].Message != domain.ErrMissingContactID.Error() {
+				t.Errorf("Expected missing id error but got %q", results[2].Message)
+			}
+
+			_, _, jane := s.GetContact("contacts/existing")
+			if jane.Name != tt.wantJane {
+				t.Errorf("Expected %q but got %q", tt.wantJane, jane.Name)
+			}
+		})
+	}
+}
+
+func TestPhonebookService_ImportCSVInvalidHeader(t *testing.T) {
+	s := NewPhonebookService(newMapDatabase())
+	success, msg, _ := s.ImportCSV(strings.NewReader("name,email\nJohn,john@example.com\n"), false)
+	if success || msg != domain.ErrInvalidImportHeader.Error() {
+		t.Errorf("Expected %q but got success=%v msg=%q", domain.ErrInvalidImportHeader.Error(), success, msg)
+	}
+}
+
+func TestPhonebookService_ImportCSVOverwriteKeepsMetadata(t *testing.T) {
+	db := newMapDatabase()
+	s := NewPhonebookService(db)
+	s.AddContact("contacts/jon", domain.Contact{Name: "Jon Doe", Phone: "123-456-7890", Email: "jon@example.com"})
+	s.AddContact("contacts/john", domain.Contact{Name: "John Doe", Phone: "123 456 7890", Email: "john@example.com"})
+	if success, msg, _ := s.MergeContacts([]string{"contacts/jon", "contacts/john"}, domain.MergePreferFirst); !success {
+		t.Fatalf("Failed to merge contacts: %s", msg)
+	}
+	s.AddContact("contacts/trashed", domain.Contact{Name: "Old", Phone: "555-000-1111"})
+	s.DeleteContact("contacts/trashed")
+
+	input := strings.Join([]string{
+		"id,name,phone",
+		"contacts/jon,Jonathan Doe,123-456-7890",
+		"contacts/john,John Doe,123-456-7890",
+		"contacts/trashed,New,555-000-1111",
+	}, "\n")
+	success, msg, results := s.ImportCSV(strings.NewReader(input), true)
+	if !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+
+	wantMessages := []string{"", domain.ErrContactMerged.Error(), domain.ErrContactInTrash.Error()}
+	for i, result := range results {
+		if result.Success != (wantMessages[i] == "") || result.Message != wantMessages[i] {
+			t.Errorf("Line %d: expected %q but got %+v", result.Line, wantMessages[i], result)
+		}
+	}
+
+	_, _, data := db.Read("contacts/jon")
+	if data["name"] != "Jonathan Doe" {
+		t.Errorf("Expected the contact to be replaced but got %v", data)
+	}
+	if history := interfaceSlice(data[mergeHistoryField]); len(history) != 1 {
+		t.Errorf("Expected the merge history to be kept but got %v", data)
+	}
+	if emails := stringSlice(data[otherEmailsField]); len(emails) != 1 {
+		t.Errorf("Expected the alternate email to be kept but got %v", data)
+	}
+	if _, _, data := db.Read("contacts/john"); !isRedirectData(data) {
+		t.Errorf("Expected the redirect to be kept but got %v", data)
+	}
+	if !s.isTrashed("contacts/trashed") {
+		t.Error("Expected the trashed contact to stay in the trash")
+	}
+}
+
+// batchingDatabase supports batches, recording every single read and batch
+// read.
+type batchingDatabase struct {
+	*MockDatabase
+	read       func(location string) (bool, string, map[string]interface{})
+	reads      []string
+	batchReads [][]string
+}
+
+func newBatchingDatabase() *batchingDatabase {
+	b := &batchingDatabase{MockDatabase: newMapDatabase()}
+	b.read = b.readFunc
+	b.readFunc = func(location string) (bool, string, map[string]interface{}) {
+		b.reads = append(b.reads, location)
+		return b.read(location)
+	}
+	return b
+}
+
+func (b *batchingDatabase) BatchRead(locations []string) (bool, string, map[string]map[string]interface{}) {
+	b.batchReads = append(b.batchReads, locations)
+	records := make(map[string]map[string]interface{})
+	for _, location := range locations {
+		if success, _, data := b.read(location); success {
+			records[location] = data
+		}
+	}
+	return true, "", records
+}
+
+func (b *batchingDatabase) BatchCreate(items []ports.BatchItem) []ports.BatchResult {
+	return b.each(items, b.Create)
+}
+
+func (b *batchingDatabase) BatchUpsert(items []ports.BatchItem) []ports.BatchResult {
+	return b.each(items, func(location string, data map[string]interface{}) (bool, string) {
+		if success, _, _ := b.read(location); success {
+			return b.Update(location, data)
+		}
+		return b.Create(location, data)
+	})
+}
+
+func (b *batchingDatabase) BatchDelete(locations []string) []ports.BatchResult {
+	results := make([]ports.BatchResult, len(locations))
+	for i, location := range locations {
+		success, message := b.Delete(location)
+		results[i] = ports.BatchResult{Location: location, Success: success, Message: message}
+	}
+	return results
+}
+
+func (b *batchingDatabase) each(items []ports.BatchItem, write func(string, map[string]interface{}) (bool, string)) []ports.BatchResult {
+	results := make([]ports.BatchResult, len(items))
+	for i, item := range items {
+		success, message := write(item.Location, item.Data)
+		results[i] = ports.BatchResult{Location: item.Location, Success: success, Message: message}
+	}
+	return results
+}
+
+func TestPhonebookService_ImportCSVOverwriteKeepsCustomFields(t *testing.T) {
+	db := newBatchingDatabase()
+	s := NewPhonebookService(db)
+	if success, msg := s.DefineField(domain.FieldDefinition{Name: "team", Type: domain.FieldText}); !success {
+		t.Fatalf("Failed to define field: %s", msg)
+	}
+	if success, msg := s.DefineField(domain.FieldDefinition{Name: "level", Type: domain.FieldNumber}); !success {
+		t.Fatalf("Failed to define field: %s", msg)
+	}
+	s.AddContact("contacts/john", domain.Contact{Name: "John", Phone: "123", Custom: map[string]string{"team": "ops", "level": "2"}})
+
+	input := strings.Join([]string{
+		"id,name,phone,level",
+		"contacts/john,John Doe,123,3",
+		"contacts/jane,Jane Doe,456,",
+	}, "\n")
+	db.reads = nil
+	success, msg, results := s.ImportCSV(strings.NewReader(input), true)
+	if !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+	for _, result := range results {
+		if !result.Success {
+			t.Errorf("Line %d: expected success but got %q", result.Line, result.Message)
+		}
+	}
+
+	// Existing contacts are read once per batch, not once per row
+	if !reflect.DeepEqual(db.batchReads, [][]string{{"contacts/john", "contacts/jane"}}) {
+		t.Errorf("Expected one batch read but got %v", db.batchReads)
+	}
+	for _, location := range db.reads {
+		if strings.HasPrefix(location, "contacts/") {
+			t.Errorf("Expected no single reads of contacts but got %v", db.reads)
+			break
+		}
+	}
+
+	_, _, john := s.GetContact("contacts/john")
+	want := domain.Contact{Name: "John Doe", Phone: "123", Custom: map[string]string{"team": "ops", "level": "3"}}
+	if !reflect.DeepEqual(john, want) {
+		t.Errorf("Expected %+v but got %+v", want, john)
+	}
+}
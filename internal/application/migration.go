@@ -0,0 +1,373 @@
+package application
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// DefaultMigrationChunkSize is how many records Migrate copies per batch.
+const DefaultMigrationChunkSize = 500
+
+// migrationPrefix holds one checkpoint per migration in the checkpoint store.
+const migrationPrefix = "migrations/"
+
+type migration struct {
+	chunkSize   int
+	checkpoints ports.Database
+	id          string
+	restart     bool
+	progress    func(domain.MigrationProgress)
+}
+
+type MigrationOption func(*migration)
+
+// WithChunkSize sets how many records are copied per batch.
+func WithChunkSize(n int) MigrationOption {
+	return func(m *migration) {
+		if n > 0 {
+			m.chunkSize = n
+		}
+	}
+}
+
+// WithCheckpoint saves the progress of migration id in store after every
+// chunk, and resumes from it when the migration is run again.
+func WithCheckpoint(store ports.Database, id string) MigrationOption {
+	return func(m *migration) {
+		m.checkpoints = store
+		m.id = id
+	}
+}
+
+// WithRestart ignores a saved checkpoint and copies everything again.
+func WithRestart() MigrationOption {
+	return func(m *migration) {
+		m.restart = true
+	}
+}
+
+// WithProgress calls fn after every chunk.
+func WithProgress(fn func(domain.MigrationProgress)) MigrationOption {
+	return func(m *migration) {
+		m.progress = fn
+	}
+}
+
+// Migrate copies every record, blob and group of the database to target,
+// the records in chunks, replacing what target already holds at the same
+// locations. Blobs target lacks are copied first, so no copied record refers
+// to a missing one. It stops between chunks when ctx is cancelled; with a
+// checkpoint, running it again resumes after the last chunk copied.
+//
+// Records written while the copy runs reach the target only when the
+// service writes through a mirrored database, and may still be overtaken
+// by an older copy. VerifyMigration finds those; a restarted copy fixes them.
+func (s *PhonebookService) Migrate(ctx context.Context, target ports.Database, opts ...MigrationOption) (bool, string, domain.MigrationProgress) {
+	if err := s.authorize(domain.RoleAdmin); err != nil {
+		return false, err.Error(), domain.MigrationProgress{}
+	}
+	m := &migration{chunkSize: DefaultMigrationChunkSize}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	progress := domain.MigrationProgress{ID: m.id, StartedAt: time.Now().UTC()}
+	if m.checkpoints != nil && !m.restart {
+		var saved domain.MigrationProgress
+		if success, message := readRecord(m.checkpoints, migrationPrefix+m.id, &saved); success {
+			progress = saved
+		} else if !isMissing(message) {
+			return false, message, domain.MigrationProgress{}
+		}
+	}
+
+	if err := copyBlobs(ctx, s.db, target); err != nil {
+		return false, err.Error(), progress
+	}
+
+	success, message, locations := s.db.List("")
+	if !success {
+		return false, message, domain.MigrationProgress{}
+	}
+	progress.Total, progress.Copied, progress.Done = len(locations), 0, false
+	var pending []string
+	for _, location := range locations {
+		if location > progress.Cursor {
+			pending = append(pending, location)
+		} else {
+			progress.Copied++
+		}
+	}
+
+	dest := *s
+	dest.db = target
+	for start := 0; start < len(pending); start += m.chunkSize {
+		if ctx.Err() != nil {
+			return false, domain.ErrMigrationInterrupted.Error(), progress
+		}
+
+		chunk := pending[start:min(start+m.chunkSize, len(pending))]
+		items := make([]ports.BatchItem, 0, len(chunk))
+		for _, location := range chunk {
+			success, message, data := s.db.Read(location)
+			if !success {
+				// Deleted since it was listed
+				if isMissing(message) {
+					continue
+				}
+				return false, fmt.Sprintf("Error reading %s: %s", location, message), progress
+			}
+			items = append(items, ports.BatchItem{Location: location, Data: data})
+		}
+		for _, result := range dest.batchUpsert(items) {
+			if !result.Success {
+				return false, fmt.Sprintf("Error copying %s: %s", result.Location, result.Message), progress
+			}
+		}
+
+		progress.Copied += len(chunk)
+		progress.Cursor = chunk[len(chunk)-1]
+		if err := m.save(&progress); err != nil {
+			return false, err.Error(), progress
+		}
+	}
+
+	if err := copyGroups(s.db, target); err != nil {
+		return false, err.Error(), progress
+	}
+	progress.Done = true
+	if err := m.save(&progress); err != nil {
+		return false, err.Error(), progress
+	}
+	return true, "", progress
+}
+
+func (m *migration) save(progress *domain.MigrationProgress) error {
+	progress.UpdatedAt = time.Now().UTC()
+	if m.checkpoints != nil {
+		location := migrationPrefix + m.id
+		if success, message, _ := upsert(m.checkpoints, location, encodeRecord(progress)); !success {
+			return fmt.Errorf("Error saving checkpoint: %s", message)
+		}
+	}
+	if m.progress != nil {
+		m.progress(*progress)
+	}
+	return nil
+}
+
+// copyGroups creates the source's groups in target and adds their members.
+// Groups and members only the target has are kept.
+func copyGroups(source, target ports.Database) error {
+	from, ok := source.(ports.GroupStore)
+	if !ok {
+		return nil
+	}
+	groups, err := listGroups(from)
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	to, ok := target.(ports.GroupStore)
+	if !ok {
+		return domain.ErrGroupsNotSupported
+	}
+	return restoreGroups(to, groups, domain.RestoreMerge, false)
+}
+
+// copyBlobs copies the blobs of source that target does not hold yet.
+func copyBlobs(ctx context.Context, source, target ports.Database) error {
+	from, ok := source.(ports.BlobStore)
+	if !ok {
+		return nil
+	}
+	missing, err := missingBlobs(source, target)
+	if err != nil || len(missing) == 0 {
+		return err
+	}
+	to := target.(ports.BlobStore)
+	for _, hash := range missing {
+		if ctx.Err() != nil {
+			return domain.ErrMigrationInterrupted
+		}
+		success, message, content := from.GetBlob(hash)
+		if !success {
+			return fmt.Errorf("Error reading blob %s: %s", hash, message)
+		}
+		if success, message := to.PutBlob(hash, content); !success {
+			return fmt.Errorf("Error copying blob %s: %s", hash, message)
+		}
+	}
+	return nil
+}
+
+// missingBlobs returns the hashes of the blobs of source that target lacks.
+// It fails when there are some and target cannot hold blobs.
+func missingBlobs(source, target ports.Database) ([]string, error) {
+	from, ok := source.(ports.BlobStore)
+	if !ok {
+		return nil, nil
+	}
+	success, message, hashes := from.ListBlobs()
+	if !success {
+		return nil, errors.New(message)
+	}
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	to, ok := target.(ports.BlobStore)
+	if !ok {
+		return nil, domain.ErrBlobsNotSupported
+	}
+	success, message, stored := to.ListBlobs()
+	if !success {
+		return nil, errors.New(message)
+	}
+	present := make(map[string]bool, len(stored))
+	for _, hash := range stored {
+		present[hash] = true
+	}
+	var missing []string
+	for _, hash := range hashes {
+		if !present[hash] {
+			missing = append(missing, hash)
+		}
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// VerifyMigration compares the database with target record by record and
+// group by group, by content hash, and checks target has every blob.
+func (s *PhonebookService) VerifyMigration(target ports.Database) (bool, string, domain.MigrationReport) {
+	if err := s.authorize(domain.RoleAdmin); err != nil {
+		return false, err.Error(), domain.MigrationReport{}
+	}
+
+	sourceHashes, sourceChecksum, err := hashRecords(s.db)
+	if err != nil {
+		return false, err.Error(), domain.MigrationReport{}
+	}
+	targetHashes, targetChecksum, err := hashRecords(target)
+	if err != nil {
+		return false, err.Error(), domain.MigrationReport{}
+	}
+	report := domain.MigrationReport{
+		SourceRecords:  len(sourceHashes),
+		TargetRecords:  len(targetHashes),
+		SourceChecksum: sourceChecksum,
+		TargetChecksum: targetChecksum,
+	}
+	for _, location := range sortedNames(sourceHashes) {
+		hash, ok := targetHashes[location]
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, location)
+		case hash != sourceHashes[location]:
+			report.Mismatched = append(report.Mismatched, location)
+		}
+	}
+	for _, location := range sortedNames(targetHashes) {
+		if _, ok := sourceHashes[location]; !ok {
+			report.Extra = append(report.Extra, location)
+		}
+	}
+
+	if report.MismatchedGroups, err = compareGroups(s.db, target); err != nil {
+		return false, err.Error(), domain.MigrationReport{}
+	}
+	if report.MissingBlobs, err = missingBlobs(s.db, target); err != nil {
+		return false, err.Error(), domain.MigrationReport{}
+	}
+	return true, "", report
+}
+
+// hashRecords returns the content hash of every record and a checksum over
+// all of them. Records hash as canonical JSON, so backends that store numbers
+// or key order differently still compare equal.
+func hashRecords(db ports.Database) (map[string]string, string, error) {
+	success, message, locations := db.List("")
+	if !success {
+		return nil, "", errors.New(message)
+	}
+
+	hashes := make(map[string]string, len(locations))
+	checksum := sha256.New()
+	for _, location := range locations {
+		success, message, data := db.Read(location)
+		if !success {
+			return nil, "", fmt.Errorf("Error reading %s: %s", location, message)
+		}
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return nil, "", fmt.Errorf("Error encoding %s: %v", location, err)
+		}
+		sum := sha256.Sum256(encoded)
+		hashes[location] = hex.EncodeToString(sum[:])
+		fmt.Fprintf(checksum, "%s\t%s\n", location, hashes[location])
+	}
+	return hashes, hex.EncodeToString(checksum.Sum(nil)), nil
+}
+
+// compareGroups returns the groups whose members differ between source and
+// target, including groups only one of them has.
+func compareGroups(source, target ports.Database) ([]string, error) {
+	read := func(db ports.Database) (map[string][]string, error) {
+		store, ok := db.(ports.GroupStore)
+		if !ok {
+			return nil, nil
+		}
+		groups, err := listGroups(store)
+		if err != nil {
+			return nil, err
+		}
+		members := make(map[string][]string, len(groups))
+		for _, group := range groups {
+			members[group.name] = append([]string{}, group.members...)
+		}
+		return members, nil
+	}
+
+	from, err := read(source)
+	if err != nil {
+		return nil, err
+	}
+	to, err := read(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatched []string
+	for _, name := range sortedNames(from) {
+		if members, ok := to[name]; !ok || !reflect.DeepEqual(members, from[name]) {
+			mismatched = append(mismatched, name)
+		}
+	}
+	for _, name := range sortedNames(to) {
+		if _, ok := from[name]; !ok {
+			mismatched = append(mismatched, name)
+		}
+	}
+	return mismatched, nil
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,127 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+func setupMigrationTest(t *testing.T, contacts int) (*PhonebookService, *MockGroupDatabase) {
+	source := newMapGroupDatabase()
+	s := NewPhonebookService(source)
+	for i := 0; i < contacts; i++ {
+		id := fmt.Sprintf("contact-%02d", i)
+		require.True(t, mustSucceed(s.AddContact(id, domain.Contact{Name: id, Phone: fmt.Sprintf("+2567000000%02d", i)})))
+	}
+	require.True(t, mustSucceed(s.CreateGroup("family")))
+	require.True(t, mustSucceed(s.AddToGroup("family", "contact-01")))
+	return s, newMapGroupDatabase()
+}
+
+func TestPhonebookService_Migrate(t *testing.T) {
+	s, target := setupMigrationTest(t, 7)
+
+	var reports []domain.MigrationProgress
+	success, message, progress := s.Migrate(context.Background(), target, WithChunkSize(3), WithProgress(func(p domain.MigrationProgress) {
+		reports = append(reports, p)
+	}))
+	require.True(t, success, message)
+	assert.True(t, progress.Done)
+	assert.Equal(t, 7, progress.Copied)
+	assert.Equal(t, []int{3, 6, 7, 7}, copiedCounts(reports))
+
+	success, message, report := s.VerifyMigration(target)
+	require.True(t, success, message)
+	assert.True(t, report.InSync(), "%+v", report)
+	assert.Equal(t, 7, report.TargetRecords)
+	_, _, members := target.Members("family")
+	assert.Equal(t, []string{"contact-01"}, members)
+}
+
+func TestPhonebookService_MigrateResumes(t *testing.T) {
+	s, target := setupMigrationTest(t, 5)
+	checkpoints := newMapDatabase()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	success, message, progress := s.Migrate(ctx, target, WithChunkSize(2), WithCheckpoint(checkpoints, "to-target"),
+		WithProgress(func(domain.MigrationProgress) { cancel() }))
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrMigrationInterrupted.Error(), message)
+	assert.Equal(t, 2, progress.Copied)
+
+	// Resuming skips the chunk already copied
+	target.Delete("contact-00")
+	var reports []domain.MigrationProgress
+	success, message, progress = s.Migrate(context.Background(), target, WithChunkSize(2), WithCheckpoint(checkpoints, "to-target"),
+		WithProgress(func(p domain.MigrationProgress) { reports = append(reports, p) }))
+	require.True(t, success, message)
+	assert.Equal(t, []int{4, 5, 5}, copiedCounts(reports))
+	assert.Equal(t, 5, progress.Copied)
+
+	_, _, report := s.VerifyMigration(target)
+	assert.Equal(t, []string{"contact-00"}, report.Missing)
+
+	success, message, _ = s.Migrate(context.Background(), target, WithCheckpoint(checkpoints, "to-target"), WithRestart())
+	require.True(t, success, message)
+	_, _, report = s.VerifyMigration(target)
+	assert.True(t, report.InSync(), "%+v", report)
+}
+
+func TestPhonebookService_VerifyMigration(t *testing.T) {
+	s, target := setupMigrationTest(t, 3)
+	success, message, _ := s.Migrate(context.Background(), target)
+	require.True(t, success, message)
+
+	target.Update("contact-00", map[string]interface{}{"name": "Changed", "phone": "+256700000000"})
+	target.Delete("contact-01")
+	target.RemoveMember("family", "contact-01")
+	target.Create("contact-99", map[string]interface{}{"name": "Extra", "phone": "+256700000099"})
+	target.CreateGroup("work")
+
+	success, message, report := s.VerifyMigration(target)
+	require.True(t, success, message)
+	assert.False(t, report.InSync())
+	assert.Equal(t, []string{"contact-00"}, report.Mismatched)
+	assert.Equal(t, []string{"contact-01"}, report.Missing)
+	assert.Equal(t, []string{"contact-99"}, report.Extra)
+	assert.Equal(t, []string{"family", "work"}, report.MismatchedGroups)
+	assert.NotEqual(t, report.SourceChecksum, report.TargetChecksum)
+}
+
+func copiedCounts(reports []domain.MigrationProgress) []int {
+	counts := make([]int, len(reports))
+	for i, report := range reports {
+		counts[i] = report.Copied
+	}
+	return counts
+}
+
+func TestPhonebookService_MigrateBlobs(t *testing.T) {
+	s := NewPhonebookService(newBlobDatabase())
+	require.True(t, mustSucceed(s.AddContact("alice", domain.Contact{Name: "Alice", Phone: "+256700000001"})))
+	success, message, attachment := s.AddAttachment("alice", "notes.txt", []byte("call after 5"))
+	require.True(t, success, message)
+
+	target := newBlobDatabase()
+	success, message, _ = s.Migrate(context.Background(), target)
+	require.True(t, success, message)
+	assert.Equal(t, []byte("call after 5"), target.blobs[attachment.Hash])
+
+	success, message, report := s.VerifyMigration(target)
+	require.True(t, success, message)
+	assert.True(t, report.InSync(), "%+v", report)
+
+	target.DeleteBlob(attachment.Hash)
+	_, _, report = s.VerifyMigration(target)
+	assert.False(t, report.InSync())
+	assert.Equal(t, []string{attachment.Hash}, report.MissingBlobs)
+
+	success, message, _ = s.Migrate(context.Background(), newMapDatabase())
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrBlobsNotSupported.Error(), message)
+}
@@ -0,0 +1,286 @@
+package application
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// PatchContact applies a merge patch or JSON Patch to a contact and returns
+// the result. Only the fields the patch changes are written, in place when
+// the database supports it, so concurrent changes to other fields are kept.
+// The read, the patch and the write run in one transaction, so a database
+// that rewrites whole records cannot clobber a concurrent write and JSON
+// Patch tests see the record that is written.
+func (s *PhonebookService) PatchContact(id string, patch domain.ContactPatch) (bool, string, domain.Contact) {
+	var contact domain.Contact
+	success, message := s.withTx(func(tx *PhonebookService) error {
+		var err error
+		contact, err = tx.patchContact(id, patch)
+		return err
+	})
+	if !success {
+		return false, message, domain.Contact{}
+	}
+	return true, "", contact
+}
+
+func (s *PhonebookService) patchContact(id string, patch domain.ContactPatch) (domain.Contact, error) {
+	success, message, id, data := s.readAuthorized(id, domain.RoleEditor)
+	if !success {
+		return domain.Contact{}, errors.New(message)
+	}
+
+	stored, version, _ := decodeContact(data)
+	if version > currentVersion {
+		return domain.Contact{}, domain.ErrUnsupportedRecordVersion
+	}
+	patched, err := applyPatch(patchDocument(stored), patch)
+	if err != nil {
+		return domain.Contact{}, err
+	}
+	contact, err := contactFromPatched(patched)
+	if err != nil {
+		return domain.Contact{}, err
+	}
+	if err := s.ValidateContact(contact); err != nil {
+		return domain.Contact{}, err
+	}
+
+	// The record is compared as stored, so fields an older version or
+	// another tool wrote are rewritten and cleared fields are removed
+	updated := contactToData(contact)
+	changes := make(map[string]interface{})
+	for field, value := range updated {
+		if field == versionField {
+			if version != currentVersion {
+				changes[field] = value
+			}
+		} else if data[field] != value {
+			changes[field] = value
+		}
+	}
+	for field := range data {
+		if _, ok := updated[field]; !ok && !strings.HasPrefix(field, "_") {
+			changes[field] = nil
+		}
+	}
+	if len(changes) == 0 {
+		return contact, nil
+	}
+
+	if patcher, ok := s.db.(ports.PatchDatabase); ok {
+		success, message = patcher.Patch(id, changes)
+	} else {
+		for field, value := range changes {
+			if value == nil {
+				delete(data, field)
+			} else {
+				data[field] = value
+			}
+		}
+		success, message = s.db.Update(id, data)
+	}
+	return contact, resultError(success, message)
+}
+
+// applyPatch returns a copy of doc with the patch applied.
+func applyPatch(doc map[string]interface{}, patch domain.ContactPatch) (map[string]interface{}, error) {
+	patched := make(map[string]interface{}, len(doc))
+	for field, value := range doc {
+		patched[field] = value
+	}
+
+	switch patch.Format {
+	case domain.MergePatch, "":
+		return applyMergePatch(patched, patch.Document)
+	case domain.JSONPatch:
+		return applyJSONPatch(patched, patch.Document)
+	}
+	return nil, domain.ErrInvalidPatchFormat
+}
+
+// applyMergePatch applies an RFC 7396 merge patch. Members are set or, when
+// null, removed; objects such as the custom fields are merged member by member.
+func applyMergePatch(doc map[string]interface{}, document []byte) (map[string]interface{}, error) {
+	var patch map[string]interface{}
+	if err := json.Unmarshal(document, &patch); err != nil || patch == nil {
+		return nil, domain.ErrInvalidPatch
+	}
+	return mergeObject(doc, patch), nil
+}
+
+func mergeObject(target, patch map[string]interface{}) map[string]interface{} {
+	for field, value := range patch {
+		switch value := value.(type) {
+		case nil:
+			delete(target, field)
+		case map[string]interface{}:
+			object, ok := target[field].(map[string]interface{})
+			if !ok {
+				object = make(map[string]interface{})
+			}
+			target[field] = mergeObject(object, value)
+		default:
+			target[field] = value
+		}
+	}
+	return target
+}
+
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// applyJSONPatch applies the operations of an RFC 6902 JSON Patch in order.
+// Either all of them apply or none do.
+func applyJSONPatch(doc map[string]interface{}, document []byte) (map[string]interface{}, error) {
+	var operations []patchOperation
+	if err := json.Unmarshal(document, &operations); err != nil {
+		return nil, domain.ErrInvalidPatch
+	}
+
+	for _, operation := range operations {
+		if operation.Path == nil {
+			return nil, domain.ErrInvalidPatch
+		}
+		var value interface{}
+		switch operation.Op {
+		case "add", "replace", "test":
+			if operation.Value == nil || json.Unmarshal(operation.Value, &value) != nil {
+				return nil, domain.ErrInvalidPatch
+			}
+		case "move", "copy":
+			if operation.From == nil {
+				return nil, domain.ErrInvalidPatch
+			}
+		case "remove":
+		default:
+			return nil, domain.ErrInvalidPatch
+		}
+
+		// The root is the whole contact, which add and replace swap out
+		if *operation.Path == "" {
+			object, ok := value.(map[string]interface{})
+			switch {
+			case operation.Op == "test" && reflect.DeepEqual(value, doc):
+				continue
+			case operation.Op == "test":
+				return nil, domain.ErrPatchTestFailed
+			case (operation.Op == "add" || operation.Op == "replace") && ok:
+				doc = object
+				continue
+			}
+			return nil, domain.ErrInvalidPatch
+		}
+
+		parent, field, ok := pointerParent(doc, *operation.Path)
+		if !ok {
+			return nil, domain.ErrInvalidPatch
+		}
+		_, exists := parent[field]
+		switch operation.Op {
+		case "add":
+			parent[field] = value
+		case "replace":
+			if !exists {
+				return nil, domain.ErrInvalidPatch
+			}
+			parent[field] = value
+		case "remove":
+			if !exists {
+				return nil, domain.ErrInvalidPatch
+			}
+			delete(parent, field)
+		case "test":
+			if !exists || !reflect.DeepEqual(parent[field], value) {
+				return nil, domain.ErrPatchTestFailed
+			}
+		case "move", "copy":
+			fromParent, from, ok := pointerParent(doc, *operation.From)
+			if !ok {
+				return nil, domain.ErrInvalidPatch
+			}
+			value, exists := fromParent[from]
+			if !exists {
+				return nil, domain.ErrInvalidPatch
+			}
+			if operation.Op == "move" {
+				delete(fromParent, from)
+			}
+			parent[field] = value
+		}
+	}
+	return doc, nil
+}
+
+// pointerParent returns the object holding the member a JSON Pointer names,
+// and the member's name. Every step before the last must be an object.
+func pointerParent(doc map[string]interface{}, pointer string) (map[string]interface{}, string, bool) {
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, "", false
+	}
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+	steps := strings.Split(pointer[1:], "/")
+	parent := doc
+	for _, step := range steps[:len(steps)-1] {
+		object, ok := parent[unescape.Replace(step)].(map[string]interface{})
+		if !ok {
+			return nil, "", false
+		}
+		parent = object
+	}
+	return parent, unescape.Replace(steps[len(steps)-1]), true
+}
+
+// patchDocument is the document patches apply to: the contact's fields and
+// an object of its custom fields, which is present even when empty.
+func patchDocument(contact domain.Contact) map[string]interface{} {
+	custom := make(map[string]interface{}, len(contact.Custom))
+	for name, value := range contact.Custom {
+		custom[name] = value
+	}
+	return map[string]interface{}{
+		"name":    contact.Name,
+		"phone":   contact.Phone,
+		"email":   contact.Email,
+		"address": contact.Address,
+		"custom":  custom,
+	}
+}
+
+// contactFromPatched reads a patched document back into a contact. Removed
+// fields are left empty.
+func contactFromPatched(doc map[string]interface{}) (domain.Contact, error) {
+	fields := map[string]interface{}{versionField: currentVersion}
+	for field, value := range doc {
+		if field == "custom" {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return domain.Contact{}, domain.ErrInvalidPatch
+			}
+			for name, value := range object {
+				text, ok := value.(string)
+				if !ok {
+					return domain.Contact{}, domain.ErrInvalidPatch
+				}
+				fields[customPrefix+name] = text
+			}
+			continue
+		}
+		text, ok := value.(string)
+		if !slices.Contains(contactFields, field) || !ok {
+			return domain.Contact{}, domain.ErrInvalidPatch
+		}
+		fields[field] = text
+	}
+	return contactFromData(fields), nil
+}
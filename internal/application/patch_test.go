@@ -0,0 +1,130 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+func TestPhonebookService_PatchContact(t *testing.T) {
+	john := domain.Contact{Name: "John", Phone: "+256700000001", Email: "john@example.com", Address: "Kampala"}
+
+	tests := []struct {
+		name            string
+		patch           domain.ContactPatch
+		expectedMessage string
+		expected        domain.Contact
+	}{
+		{"merge patch sets a field", domain.ContactPatch{Format: domain.MergePatch, Document: []byte(`{"email":"johnny@example.com"}`)}, "",
+			domain.Contact{Name: "John", Phone: "+256700000001", Email: "johnny@example.com", Address: "Kampala"}},
+		{"merge patch is the default", domain.ContactPatch{Document: []byte(`{"address":"Entebbe"}`)}, "",
+			domain.Contact{Name: "John", Phone: "+256700000001", Email: "john@example.com", Address: "Entebbe"}},
+		{"merge patch null clears a field", domain.ContactPatch{Format: domain.MergePatch, Document: []byte(`{"email":null}`)}, "",
+			domain.Contact{Name: "John", Phone: "+256700000001", Address: "Kampala"}},
+		{"merge patch cannot clear a required field", domain.ContactPatch{Format: domain.MergePatch, Document: []byte(`{"phone":null}`)},
+			domain.ErrInvalidContactNumber.Error(), domain.Contact{}},
+		{"merge patch of an unknown field", domain.ContactPatch{Format: domain.MergePatch, Document: []byte(`{"nickname":"Jo"}`)},
+			domain.ErrInvalidPatch.Error(), domain.Contact{}},
+		{"merge patch that is not an object", domain.ContactPatch{Format: domain.MergePatch, Document: []byte(`["name"]`)},
+			domain.ErrInvalidPatch.Error(), domain.Contact{}},
+		{"merge patch with a non-string value", domain.ContactPatch{Format: domain.MergePatch, Document: []byte(`{"name":42}`)},
+			domain.ErrInvalidPatch.Error(), domain.Contact{}},
+		{"json patch", domain.ContactPatch{Format: domain.JSONPatch, Document: []byte(`[
+			{"op":"test","path":"/name","value":"John"},
+			{"op":"replace","path":"/name","value":"Johnny"},
+			{"op":"copy","from":"/address","path":"/email"},
+			{"op":"remove","path":"/address"}]`)}, "",
+			domain.Contact{Name: "Johnny", Phone: "+256700000001", Email: "Kampala"}},
+		{"json patch move", domain.ContactPatch{Format: domain.JSONPatch, Document: []byte(`[{"op":"move","from":"/email","path":"/address"}]`)}, "",
+			domain.Contact{Name: "John", Phone: "+256700000001", Address: "john@example.com"}},
+		{"json patch failing test applies nothing", domain.ContactPatch{Format: domain.JSONPatch, Document: []byte(`[
+			{"op":"replace","path":"/name","value":"Johnny"},
+			{"op":"test","path":"/phone","value":"+256799999999"}]`)},
+			domain.ErrPatchTestFailed.Error(), domain.Contact{}},
+		{"json patch replacing the whole contact", domain.ContactPatch{Format: domain.JSONPatch, Document: []byte(`[
+			{"op":"replace","path":"","value":{"name":"Jane","phone":"+256700000002"}}]`)}, "",
+			domain.Contact{Name: "Jane", Phone: "+256700000002"}},
+		{"json patch with an unknown operation", domain.ContactPatch{Format: domain.JSONPatch, Document: []byte(`[{"op":"merge","path":"/name","value":"J"}]`)},
+			domain.ErrInvalidPatch.Error(), domain.Contact{}},
+		{"json patch with a nested path", domain.ContactPatch{Format: domain.JSONPatch, Document: []byte(`[{"op":"add","path":"/name/first","value":"J"}]`)},
+			domain.ErrInvalidPatch.Error(), domain.Contact{}},
+		{"json patch removing a missing field", domain.ContactPatch{Format: domain.JSONPatch, Document: []byte(`[{"op":"remove","path":"/nickname"}]`)},
+			domain.ErrInvalidPatch.Error(), domain.Contact{}},
+		{"unknown format", domain.ContactPatch{Format: "xml", Document: []byte(`{}`)},
+			domain.ErrInvalidPatchFormat.Error(), domain.Contact{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewPhonebookService(newMapDatabase())
+			require.True(t, mustSucceed(s.AddContact("john", john)))
+
+			success, message, contact := s.PatchContact("john", tt.patch)
+			assert.Equal(t, tt.expectedMessage == "", success)
+			assert.Equal(t, tt.expectedMessage, message)
+			assert.Equal(t, tt.expected, contact)
+
+			stored := john
+			if success {
+				stored = tt.expected
+			}
+			_, _, contact = s.GetContact("john")
+			assert.Equal(t, stored, contact)
+		})
+	}
+}
+
+// patchingDatabase records the fields written by Patch.
+type patchingDatabase struct {
+	*MockDatabase
+	patched []map[string]interface{}
+}
+
+func (p *patchingDatabase) Patch(location string, fields map[string]interface{}) (bool, string) {
+	p.patched = append(p.patched, fields)
+	success, message, data := p.Read(location)
+	if !success {
+		return false, message
+	}
+	for field, value := range fields {
+		data[field] = value
+	}
+	return p.Update(location, data)
+}
+
+func TestPhonebookService_PatchContactInPlace(t *testing.T) {
+	db := &patchingDatabase{MockDatabase: newMapDatabase()}
+	s := NewPhonebookService(db)
+	require.True(t, mustSucceed(s.AddContact("john", domain.Contact{Name: "John", Phone: "+256700000001"})))
+
+	success, message, _ := s.PatchContact("john", domain.ContactPatch{Document: []byte(`{"name":"John","email":"john@example.com"}`)})
+	require.True(t, success, message)
+	success, message, _ = s.PatchContact("john", domain.ContactPatch{Document: []byte(`{"name":"John"}`)})
+	require.True(t, success, message)
+	assert.Equal(t, []map[string]interface{}{{"email": "john@example.com"}}, db.patched, "only changed fields are written")
+
+	success, message, _ = s.PatchContact("nobody", domain.ContactPatch{Document: []byte(`{}`)})
+	assert.False(t, success)
+	assert.Equal(t, "Location does not exist", message)
+}
+
+func TestPhonebookService_PatchContactUsesTx(t *testing.T) {
+	db := &MockTxDatabase{MockGroupDatabase: newMapGroupDatabase()}
+	s := NewPhonebookService(db)
+	require.True(t, mustSucceed(s.AddContact("john", domain.Contact{Name: "John", Phone: "+256700000001"})))
+
+	// Without in-place patches the record is read and rewritten, so both
+	// happen in one transaction
+	success, message, _ := s.PatchContact("john", domain.ContactPatch{
+		Format:   domain.JSONPatch,
+		Document: []byte(`[{"op":"test","path":"/name","value":"John"},{"op":"add","path":"/email","value":"john@example.com"}]`),
+	})
+	require.True(t, success, message)
+	assert.Equal(t, 1, db.txCalls)
+
+	_, _, contact := s.GetContact("john")
+	assert.Equal(t, "john@example.com", contact.Email)
+}
@@ -1,83 +1,316 @@
 package application
 
 import (
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/Businge931/practice-interfaces/internal/domain"
 	"github.com/Businge931/practice-interfaces/internal/ports"
 )
 
 type PhonebookService struct {
-	db ports.Database
+	db             ports.Database
+	trashRetention time.Duration
+	now            func() time.Time
+	authorization  bool
+	actor          *domain.Actor
+	tenants        *TenantManager
+	maxContacts    int
+	maxBlobSize    int64
 }
 
-func NewPhonebookService(db ports.Database) *PhonebookService {
-	return &PhonebookService{db: db}
+func NewPhonebookService(db ports.Database, opts ...Option) *PhonebookService {
+	s := &PhonebookService{
+		db:             db,
+		trashRetention: DefaultTrashRetention,
+		now:            time.Now,
+		maxBlobSize:    DefaultMaxBlobSize,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *PhonebookService) AddContact(location string, contact domain.Contact) (bool, string) {
+	if err := s.authorize(domain.RoleEditor); err != nil {
+		return false, err.Error()
+	}
+	if isReserved(location) {
+		return false, domain.ErrReservedContactID.Error()
+	}
+
 	// Validate the contact
 	if err := s.ValidateContact(contact); err != nil {
 		return false, err.Error()
 	}
 
-	// Convert contact to a map for storage
-	contactData := map[string]interface{}{
-		"name":    contact.Name,
-		"phone":   contact.Phone,
-		"email":   contact.Email,
-		"address": contact.Address,
+	// Keep within the tenant's quota
+	if left, err := s.quotaLeft(); err != nil {
+		return false, err.Error()
+	} else if left == 0 {
+		return false, domain.ErrTenantQuotaExceeded.Error()
 	}
 
+	// Convert contact to a map for storage
+	contactData := contactToData(contact)
+
 	// Call the database's Create method
-	return s.db.Create(location, contactData)
+	success, message := s.db.Create(location, contactData)
+	if !success && s.isTrashed(location) {
+		return false, domain.ErrContactInTrash.Error()
+	}
+	return success, message
 }
 
-func (s *PhonebookService) GetContact(id string) (bool, string, domain.Contact) {
-	// Call the database's Read method
-	success, message, data := s.db.Read(id)
-	if !success {
-		return false, message, domain.Contact{}
+// GetContact reads a contact. Given fields, only those are read and the
+// others are left empty.
+func (s *PhonebookService) GetContact(id string, fields ...string) (bool, string, domain.Contact) {
+	if err := s.validateFields(fields); err != nil {
+		return false, err.Error(), domain.Contact{}
 	}
 
-	// Convert the map to a Contact struct
-	contact := domain.Contact{
-		Name:    data["name"].(string),
-		Phone:   data["phone"].(string),
-		Email:   data["email"].(string),
-		Address: data["address"].(string),
+	// Read the record, following merge redirects and hiding trashed contacts
+	success, message, _, data := s.readAuthorized(id, domain.RoleViewer, fields...)
+	if !success {
+		return false, message, domain.Contact{}
 	}
 
-	return true, "", contact
+	return true, "", contactFromData(data)
 }
 
+// UpdateContact replaces a contact. Custom fields the contact leaves out keep
+// their stored values, so clients that do not know them cannot drop them; an
+// empty value clears a field.
 func (s *PhonebookService) UpdateContact(id string, contact domain.Contact) (bool, string) {
 	// Validate the contact
-	if err := s.ValidateContact(contact); err != nil {
+	if err := validateCoreFields(contact); err != nil {
 		return false, err.Error()
 	}
 
-	// Convert contact to a map for storage
-	contactData := map[string]interface{}{
-		"name":    contact.Name,
-		"phone":   contact.Phone,
-		"email":   contact.Email,
-		"address": contact.Address,
+	// Contacts in the trash must be restored before they can be updated
+	success, message, id, data := s.readAuthorized(id, domain.RoleEditor)
+	if !success {
+		return false, message
 	}
 
+	stored, version, _ := decodeContact(data)
+	if version > currentVersion {
+		return false, domain.ErrUnsupportedRecordVersion.Error()
+	}
+	contact.Custom = mergeCustom(stored.Custom, contact.Custom)
+	if err := s.ValidateContact(contact); err != nil {
+		return false, err.Error()
+	}
+
+	// Convert contact to a map for storage, keeping the record's metadata
+	contactData := contactToData(contact)
+	copyMetadata(contactData, data)
+
 	// Call the database's Update method
 	return s.db.Update(id, contactData)
 }
 
+// DeleteContact moves the contact to the trash, recording the actor the
+// service is bound to, if any.
 func (s *PhonebookService) DeleteContact(id string) (bool, string) {
-	// Call the database's Delete method
-	return s.db.Delete(id)
+	return s.DeleteContactAs(id, s.actorName())
+}
+
+// DeleteContactAs moves the contact to the trash, recording the deletion time
+// and the actor responsible. Trashed contacts are purged after the retention period.
+func (s *PhonebookService) DeleteContactAs(id, actor string) (bool, string) {
+	success, message, id, data := s.readAuthorized(id, domain.RoleEditor)
+	if !success {
+		return false, message
+	}
+
+	// Mark the record as deleted
+	data[deletedAtField] = s.now().UTC().Format(time.RFC3339Nano)
+	data[deletedByField] = actor
+
+	return s.db.Update(id, data)
+}
+
+// MoveContact moves a contact to a new id, together with its metadata and
+// group memberships. The record is created and removed in one transaction.
+func (s *PhonebookService) MoveContact(from, to string) (bool, string) {
+	// Creating the new record needs the role globally, removing the old one
+	// needs it on that contact
+	if err := s.authorize(domain.RoleEditor); err != nil {
+		return false, err.Error()
+	}
+	if isReserved(to) {
+		return false, domain.ErrReservedContactID.Error()
+	}
+
+	success, message, from, _ := s.readAuthorized(from, domain.RoleEditor)
+	if !success {
+		return false, message
+	}
+
+	// Remember the memberships, since deleting the old record drops them
+	memberships := []string{}
+	groups, hasGroups := s.groupStore()
+	if hasGroups {
+		if success, message, memberships = groups.GroupsOf(from); !success {
+			return false, message
+		}
+	}
+
+	groupsMoved := false
+	success, message = s.withTx(func(tx *PhonebookService) error {
+		success, message, _, data := tx.readLive(from)
+		if !success {
+			return errors.New(message)
+		}
+		if err := resultError(tx.db.Create(to, data)); err != nil {
+			return err
+		}
+		if txGroups, ok := tx.groupStore(); ok {
+			for _, group := range memberships {
+				if err := resultError(txGroups.AddMember(group, to)); err != nil {
+					return err
+				}
+			}
+			groupsMoved = true
+		}
+		return resultError(tx.db.Delete(from))
+	})
+	if !success {
+		return false, message
+	}
+
+	// Transactions without group support leave memberships to be added after the commit
+	if hasGroups && !groupsMoved {
+		for _, group := range memberships {
+			if success, message := groups.AddMember(group, to); !success {
+				return false, message
+			}
+		}
+	}
+	return true, ""
 }
 
+// ValidateContact checks the built-in fields of a contact and its custom
+// fields against the phonebook's schema.
 func (s *PhonebookService) ValidateContact(contact domain.Contact) error {
-	if contact.Name == ""  {
+	if err := validateCoreFields(contact); err != nil {
+		return err
+	}
+	schema, err := s.fieldSchema()
+	if err != nil {
+		return err
+	}
+	return validateCustom(schema, contact.Custom)
+}
+
+func validateCoreFields(contact domain.Contact) error {
+	if contact.Name == "" {
 		return domain.ErrInvalidContactName
 	}
-	if contact.Phone==""{
+	if contact.Phone == "" {
 		return domain.ErrInvalidContactNumber
 	}
 	return nil
 }
+
+// ListContacts returns every live contact whose id starts with prefix. Given
+// fields, only those are read and the others are left empty.
+func (s *PhonebookService) ListContacts(prefix string, fields ...string) (bool, string, *domain.Phonebook) {
+	if err := s.validateFields(fields); err != nil {
+		return false, err.Error(), nil
+	}
+	if len(fields) > 0 {
+		return s.listProjected(prefix, fields)
+	}
+
+	success, message, ids := s.db.List(prefix)
+	if !success {
+		return false, message, nil
+	}
+	return s.readContacts(ids)
+}
+
+// readContacts reads the given records, skipping trashed contacts, merge
+// redirects and contacts the actor may not see.
+func (s *PhonebookService) readContacts(ids []string) (bool, string, *domain.Phonebook) {
+	phonebook := domain.NewPhonebook()
+	for _, id := range ids {
+		success, message, data := s.db.Read(id)
+		if !success {
+			return false, message, nil
+		}
+		if isReserved(id) || isTrashedData(data) || isRedirectData(data) || !s.canRead(id) {
+			continue
+		}
+		phonebook.Contacts[id] = contactFromData(data)
+	}
+
+	return true, "", phonebook
+}
+
+// maxRedirects bounds how many merge redirects a lookup follows.
+const maxRedirects = 8
+
+// readLive reads a live contact record, following merge redirects. It returns
+// the id the record was finally found under. Given fields, only those are
+// read.
+func (s *PhonebookService) readLive(id string, fields ...string) (bool, string, string, map[string]interface{}) {
+	if isReserved(id) {
+		return false, domain.ErrContactNotFound.Error(), id, nil
+	}
+	for i := 0; i <= maxRedirects; i++ {
+		success, message, data := s.readFields(id, fields)
+		if !success {
+			return false, message, id, nil
+		}
+		if isTrashedData(data) {
+			return false, domain.ErrContactNotFound.Error(), id, nil
+		}
+		target, ok := data[redirectField].(string)
+		if !ok {
+			return true, "", id, data
+		}
+		id = target
+	}
+	return false, domain.ErrContactNotFound.Error(), id, nil
+}
+
+// copyMetadata copies the service's bookkeeping fields from src into dst,
+// leaving dst's record version.
+func copyMetadata(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if strings.HasPrefix(k, "_") && k != versionField {
+			dst[k] = v
+		}
+	}
+}
+
+// SensitiveFields are the stored contact fields holding personal data, which
+// deployments encrypting data at rest should encrypt.
+var SensitiveFields = []string{"phone", "address"}
+
+func contactToData(contact domain.Contact) map[string]interface{} {
+	data := map[string]interface{}{
+		"name":       contact.Name,
+		"phone":      contact.Phone,
+		"email":      contact.Email,
+		"address":    contact.Address,
+		versionField: currentVersion,
+	}
+	for name, value := range contact.Custom {
+		if value != "" {
+			data[customPrefix+name] = value
+		}
+	}
+	return data
+}
+
+// contactFromData decodes a stored record, leaving out what cannot be read.
+// VerifyRecords reports the problems it skips over.
+func contactFromData(data map[string]interface{}) domain.Contact {
+	contact, _, _ := decodeContact(data)
+	return contact
+}
@@ -1,6 +1,10 @@
 package application
 
 import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/Businge931/practice-interfaces/internal/domain"
@@ -13,6 +17,7 @@ type MockDatabase struct {
 	readFunc   func(location string) (bool, string, map[string]interface{})
 	updateFunc func(location string, data map[string]interface{}) (bool, string)
 	deleteFunc func(location string) (bool, string)
+	listFunc   func(prefix string) (bool, string, []string)
 }
 
 func (m *MockDatabase) Create(location string, data map[string]interface{}) (bool, string) {
@@ -20,6 +25,9 @@ func (m *MockDatabase) Create(location string, data map[string]interface{}) (boo
 }
 
 func (m *MockDatabase) Read(location string) (bool, string, map[string]interface{}) {
+	if m.readFunc == nil {
+		return false, "Location does not exist", nil
+	}
 	return m.readFunc(location)
 }
 
@@ -31,6 +39,75 @@ func (m *MockDatabase) Delete(location string) (bool, string) {
 	return m.deleteFunc(location)
 }
 
+func (m *MockDatabase) List(prefix string) (bool, string, []string) {
+	return m.listFunc(prefix)
+}
+
+// newMapDatabase returns a MockDatabase backed by a plain map, for tests that
+// need several operations to see each other's writes.
+func newMapDatabase() *MockDatabase {
+	store := make(map[string]map[string]interface{})
+	var mu sync.Mutex
+	copyData := func(data map[string]interface{}) map[string]interface{} {
+		dataCopy := make(map[string]interface{})
+		for k, v := range data {
+			dataCopy[k] = v
+		}
+		return dataCopy
+	}
+
+	return &MockDatabase{
+		createFunc: func(location string, data map[string]interface{}) (bool, string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if _, exists := store[location]; exists {
+				return false, "Location already exists"
+			}
+			store[location] = copyData(data)
+			return true, ""
+		},
+		readFunc: func(location string) (bool, string, map[string]interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			data, exists := store[location]
+			if !exists {
+				return false, "Location does not exist", nil
+			}
+			return true, "", copyData(data)
+		},
+		updateFunc: func(location string, data map[string]interface{}) (bool, string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if _, exists := store[location]; !exists {
+				return false, "Location does not exist"
+			}
+			store[location] = copyData(data)
+			return true, ""
+		},
+		deleteFunc: func(location string) (bool, string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if _, exists := store[location]; !exists {
+				return false, "Location does not exist"
+			}
+			delete(store, location)
+			return true, ""
+		},
+		listFunc: func(prefix string) (bool, string, []string) {
+			mu.Lock()
+			defer mu.Unlock()
+			locations := make([]string, 0)
+			for location := range store {
+				if strings.HasPrefix(location, prefix) {
+					locations = append(locations, location)
+				}
+			}
+			sort.Strings(locations)
+			return true, "", locations
+		},
+	}
+}
+
 type phonebookTestCase struct {
 	name string
 	db   ports.Database
@@ -169,7 +246,7 @@ func TestPhonebookService_GetContact(t *testing.T) {
 				if !success {
 					t.Errorf("Expected success but got error: %s", msg)
 				}
-				if contact != tt.want {
+				if !reflect.DeepEqual(contact, tt.want) {
 					t.Errorf("Expected contact %+v but got %+v", tt.want, contact)
 				}
 			}
@@ -0,0 +1,98 @@
+package application
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// bookkeepingFields are read along with any projection, so trashed contacts
+// and merge redirects are still recognized.
+var bookkeepingFields = []string{deletedAtField, redirectField, versionField}
+
+// validateFields checks that fields name built-in fields or defined custom
+// fields, which are named by their stored key, custom_<name>.
+func (s *PhonebookService) validateFields(fields []string) error {
+	var schema []domain.FieldDefinition
+	for _, field := range fields {
+		if slices.Contains(contactFields, field) {
+			continue
+		}
+		name, ok := strings.CutPrefix(field, customPrefix)
+		if !ok {
+			return domain.ErrUnknownContactField
+		}
+		if schema == nil {
+			var err error
+			if schema, err = s.fieldSchema(); err != nil {
+				return err
+			}
+		}
+		if !slices.ContainsFunc(schema, func(d domain.FieldDefinition) bool { return d.Name == name }) {
+			return domain.ErrUnknownContactField
+		}
+	}
+	return nil
+}
+
+// readFields reads a record, or only the given fields and the bookkeeping
+// ones. The projection is left to the database when it supports it.
+func (s *PhonebookService) readFields(id string, fields []string) (bool, string, map[string]interface{}) {
+	if len(fields) == 0 {
+		return s.db.Read(id)
+	}
+	projection := append(append([]string{}, fields...), bookkeepingFields...)
+	if projector, ok := s.db.(ports.ProjectionDatabase); ok {
+		return projector.ReadFields(id, projection)
+	}
+
+	success, message, data := s.db.Read(id)
+	if !success {
+		return false, message, nil
+	}
+	projected := make(map[string]interface{}, len(projection))
+	for _, field := range projection {
+		if value, ok := data[field]; ok {
+			projected[field] = value
+		}
+	}
+	return true, "", projected
+}
+
+// listProjected returns the given fields of every live contact under prefix,
+// in one query when the database supports projections.
+func (s *PhonebookService) listProjected(prefix string, fields []string) (bool, string, *domain.Phonebook) {
+	var records map[string]map[string]interface{}
+	if projector, ok := s.db.(ports.ProjectionDatabase); ok {
+		var success bool
+		var message string
+		success, message, records = projector.ListFields(prefix, append(append([]string{}, fields...), bookkeepingFields...))
+		if !success {
+			return false, message, nil
+		}
+	} else {
+		success, message, ids := s.db.List(prefix)
+		if !success {
+			return false, message, nil
+		}
+		records = make(map[string]map[string]interface{}, len(ids))
+		for _, id := range ids {
+			success, message, data := s.readFields(id, fields)
+			if !success {
+				return false, message, nil
+			}
+			records[id] = data
+		}
+	}
+
+	phonebook := domain.NewPhonebook()
+	for id, data := range records {
+		if isReserved(id) || isTrashedData(data) || isRedirectData(data) || !s.canRead(id) {
+			continue
+		}
+		phonebook.Contacts[id] = contactFromData(data)
+	}
+	return true, "", phonebook
+}
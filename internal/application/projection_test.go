@@ -0,0 +1,144 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// projectingDatabase records the fields asked of its projections.
+type projectingDatabase struct {
+	*MockDatabase
+	projections [][]string
+}
+
+func (p *projectingDatabase) ReadFields(location string, fields []string) (bool, string, map[string]interface{}) {
+	p.projections = append(p.projections, fields)
+	success, message, data := p.Read(location)
+	if !success {
+		return false, message, nil
+	}
+	return true, "", projectData(data, fields)
+}
+
+func (p *projectingDatabase) ListFields(prefix string, fields []string) (bool, string, map[string]map[string]interface{}) {
+	p.projections = append(p.projections, fields)
+	records := make(map[string]map[string]interface{})
+	_, _, locations := p.List(prefix)
+	for _, location := range locations {
+		_, _, data := p.Read(location)
+		records[location] = projectData(data, fields)
+	}
+	return true, "", records
+}
+
+func projectData(data map[string]interface{}, fields []string) map[string]interface{} {
+	projected := make(map[string]interface{})
+	for _, field := range fields {
+		if value, ok := data[field]; ok {
+			projected[field] = value
+		}
+	}
+	return projected
+}
+
+func TestPhonebookService_Projection(t *testing.T) {
+	for name, newDatabase := range map[string]func() ports.Database{
+		"Projecting": func() ports.Database { return &projectingDatabase{MockDatabase: newMapDatabase()} },
+		"Fallback":   func() ports.Database { return newMapDatabase() },
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := NewPhonebookService(newDatabase())
+			require.True(t, mustSucceed(s.AddContact("contacts/john", domain.Contact{Name: "John", Phone: "+256700000001", Email: "john@example.com"})))
+			require.True(t, mustSucceed(s.AddContact("contacts/jane", domain.Contact{Name: "Jane", Phone: "+256700000002"})))
+			require.True(t, mustSucceed(s.AddContact("contacts/jim", domain.Contact{Name: "Jim", Phone: "+256700000003"})))
+			require.True(t, mustSucceed(s.DeleteContact("contacts/jim")))
+
+			tests := []struct {
+				name            string
+				id              string
+				fields          []string
+				expectedMessage string
+				expected        domain.Contact
+			}{
+				{"some fields", "contacts/john", []string{"name", "email"}, "", domain.Contact{Name: "John", Email: "john@example.com"}},
+				{"no fields reads everything", "contacts/jane", nil, "", domain.Contact{Name: "Jane", Phone: "+256700000002"}},
+				{"trashed contact", "contacts/jim", []string{"name"}, domain.ErrContactNotFound.Error(), domain.Contact{}},
+				{"unknown field", "contacts/john", []string{"nickname"}, domain.ErrUnknownContactField.Error(), domain.Contact{}},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					success, message, contact := s.GetContact(tt.id, tt.fields...)
+					assert.Equal(t, tt.expectedMessage == "", success)
+					assert.Equal(t, tt.expectedMessage, message)
+					assert.Equal(t, tt.expected, contact)
+				})
+			}
+
+			success, message, phonebook := s.ListContacts("contacts/", "phone")
+			require.True(t, success, message)
+			assert.Equal(t, map[string]domain.Contact{
+				"contacts/john": {Phone: "+256700000001"},
+				"contacts/jane": {Phone: "+256700000002"},
+			}, phonebook.Contacts)
+
+			success, message, _ = s.ListContacts("contacts/", "name", "nickname")
+			assert.False(t, success)
+			assert.Equal(t, domain.ErrUnknownContactField.Error(), message)
+		})
+	}
+}
+
+func TestPhonebookService_ProjectionIsPushedDown(t *testing.T) {
+	db := &projectingDatabase{MockDatabase: newMapDatabase()}
+	s := NewPhonebookService(db)
+	require.True(t, mustSucceed(s.AddContact("contacts/john", domain.Contact{Name: "John", Phone: "+256700000001"})))
+
+	_, _, contact := s.GetContact("contacts/john", "name")
+	assert.Equal(t, domain.Contact{Name: "John"}, contact)
+	_, _, phonebook := s.ListContacts("contacts/", "phone")
+	assert.Len(t, phonebook.Contacts, 1)
+
+	assert.Equal(t, [][]string{
+		{"name", deletedAtField, redirectField, versionField},
+		{"phone", deletedAtField, redirectField, versionField},
+	}, db.projections, "only the requested fields are read")
+}
+
+func TestPhonebookService_ProjectionCustomFields(t *testing.T) {
+	for name, newDatabase := range map[string]func() ports.Database{
+		"Projecting": func() ports.Database { return &projectingDatabase{MockDatabase: newMapDatabase()} },
+		"Fallback":   func() ports.Database { return newMapDatabase() },
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := NewPhonebookService(newDatabase())
+			require.True(t, mustSucceed(s.DefineField(domain.FieldDefinition{Name: "team", Type: domain.FieldText})))
+			require.True(t, mustSucceed(s.DefineField(domain.FieldDefinition{Name: "floor", Type: domain.FieldText})))
+			require.True(t, mustSucceed(s.AddContact("contacts/john", domain.Contact{Name: "John", Phone: "+256700000001",
+				Custom: map[string]string{"team": "ops", "floor": "3"}})))
+			require.True(t, mustSucceed(s.AddContact("contacts/jane", domain.Contact{Name: "Jane", Phone: "+256700000002"})))
+
+			// Custom fields are named by their stored key
+			success, message, contact := s.GetContact("contacts/john", "name", "custom_team")
+			require.True(t, success, message)
+			assert.Equal(t, domain.Contact{Name: "John", Custom: map[string]string{"team": "ops"}}, contact)
+
+			success, message, phonebook := s.ListContacts("contacts/", "custom_floor")
+			require.True(t, success, message)
+			assert.Equal(t, map[string]domain.Contact{
+				"contacts/john": {Custom: map[string]string{"floor": "3"}},
+				"contacts/jane": {},
+			}, phonebook.Contacts)
+
+			for _, field := range []string{"custom_badge", "team"} {
+				success, message, _ = s.GetContact("contacts/john", field)
+				assert.False(t, success, field)
+				assert.Equal(t, domain.ErrUnknownContactField.Error(), message, field)
+			}
+		})
+	}
+}
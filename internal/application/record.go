@@ -0,0 +1,244 @@
+package application
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+// versionField records the layout a contact record was written in. Records
+// without one predate versioning, or were written by other tools.
+const versionField = "_version"
+
+// contactFields are the built-in fields of a stored contact.
+var contactFields = []string{"name", "phone", "email", "address"}
+
+// recordUpgrades[v] turns a record of version v into one of version v+1.
+// Records are upgraded as they are read; the service writes the current
+// version.
+var recordUpgrades = []func(data map[string]interface{}){
+	upgradeUnversioned,
+}
+
+// currentVersion is the version of the records the service writes.
+var currentVersion = len(recordUpgrades)
+
+// upgradeUnversioned reads records written by tools that encode a contact
+// as it is exposed: with capitalised field names, or with the custom fields
+// in an object of their own.
+func upgradeUnversioned(data map[string]interface{}) {
+	for _, field := range contactFields {
+		if _, ok := data[field]; ok {
+			continue
+		}
+		for key, value := range data {
+			if strings.EqualFold(key, field) {
+				data[field] = value
+				delete(data, key)
+				break
+			}
+		}
+	}
+	if custom, ok := data["custom"].(map[string]interface{}); ok {
+		for name, value := range custom {
+			if _, exists := data[customPrefix+name]; !exists {
+				data[customPrefix+name] = value
+			}
+		}
+		delete(data, "custom")
+	}
+}
+
+// decodeContact reads a stored record into a contact, upgrading it first.
+// It never fails: missing fields are left empty, values of the wrong type
+// are converted where they can be, and every problem found is returned.
+func decodeContact(data map[string]interface{}) (domain.Contact, int, []domain.FieldError) {
+	version, errs := recordVersion(data)
+	if version > currentVersion {
+		errs = append(errs, domain.FieldError{
+			Field:   versionField,
+			Problem: fmt.Sprintf("version %d is newer than the supported version %d", version, currentVersion),
+		})
+	} else if version < currentVersion {
+		upgraded := make(map[string]interface{}, len(data))
+		for key, value := range data {
+			upgraded[key] = value
+		}
+		for v := version; v < currentVersion; v++ {
+			recordUpgrades[v](upgraded)
+		}
+		data = upgraded
+	}
+
+	text := func(field string) string {
+		value, problem := textValue(data[field])
+		if problem != "" {
+			errs = append(errs, domain.FieldError{Field: field, Problem: problem})
+		}
+		return value
+	}
+	contact := domain.Contact{
+		Name:    text("name"),
+		Phone:   text("phone"),
+		Email:   text("email"),
+		Address: text("address"),
+	}
+	for _, field := range []string{"name", "phone"} {
+		if data[field] == nil || data[field] == "" {
+			errs = append(errs, domain.FieldError{Field: field, Problem: "missing"})
+		}
+	}
+
+	keys := make([]string, 0)
+	for key := range data {
+		if strings.HasPrefix(key, customPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if value := text(key); value != "" {
+			if contact.Custom == nil {
+				contact.Custom = make(map[string]string)
+			}
+			contact.Custom[strings.TrimPrefix(key, customPrefix)] = value
+		}
+	}
+	return contact, version, errs
+}
+
+// recordVersion returns the version a record was written in. Backends hand
+// numbers back as different types, so any integral number is accepted.
+func recordVersion(data map[string]interface{}) (int, []domain.FieldError) {
+	var version float64
+	switch value := data[versionField].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return value, nil
+	case int32:
+		return int(value), nil
+	case int64:
+		return int(value), nil
+	case float64:
+		version = value
+	case string:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, []domain.FieldError{{Field: versionField, Problem: "not a version number"}}
+		}
+		return parsed, nil
+	default:
+		return 0, []domain.FieldError{{Field: versionField, Problem: "not a version number"}}
+	}
+	if version != math.Trunc(version) || version < 0 || version > math.MaxInt32 {
+		return 0, []domain.FieldError{{Field: versionField, Problem: "not a version number"}}
+	}
+	return int(version), nil
+}
+
+// textValue reads a stored field as text. Numbers and booleans are converted
+// but still reported; anything else is left empty.
+func textValue(value interface{}) (string, string) {
+	switch value := value.(type) {
+	case nil:
+		return "", ""
+	case string:
+		return value, ""
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), "expected text but got a number"
+	case int, int32, int64:
+		return fmt.Sprint(value), "expected text but got a number"
+	case bool:
+		return strconv.FormatBool(value), "expected text but got a boolean"
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Map, reflect.Struct:
+		return "", "expected text but got an object"
+	case reflect.Slice, reflect.Array:
+		return "", "expected text but got a list"
+	}
+	return "", fmt.Sprintf("expected text but got %T", value)
+}
+
+// VerifyRecords reads every record of the phonebook, including the trash,
+// and reports those that cannot be read or decoded cleanly, or whose custom
+// fields no longer match the schema.
+func (s *PhonebookService) VerifyRecords() (bool, string, domain.VerifyReport) {
+	if err := s.authorize(domain.RoleAdmin); err != nil {
+		return false, err.Error(), domain.VerifyReport{}
+	}
+	success, message, ids := s.db.List("")
+	if !success {
+		return false, message, domain.VerifyReport{}
+	}
+	sort.Strings(ids)
+
+	report := domain.VerifyReport{}
+	schema, err := s.fieldSchema()
+	if err != nil {
+		report.Problems = append(report.Problems, domain.RecordProblem{ID: schemaLocation, Errors: []domain.FieldError{{Problem: err.Error()}}})
+	}
+	for _, id := range ids {
+		if isReserved(id) {
+			continue
+		}
+		success, message, data := s.db.Read(id)
+		if !success {
+			if isMissing(message) {
+				continue
+			}
+			report.Records++
+			report.Problems = append(report.Problems, domain.RecordProblem{ID: id, Errors: []domain.FieldError{{Problem: message}}})
+			continue
+		}
+		report.Records++
+		if isRedirectData(data) {
+			continue
+		}
+
+		contact, version, errs := decodeContact(data)
+		if version < currentVersion {
+			report.Outdated++
+		}
+		if err == nil {
+			errs = append(errs, customFieldErrors(schema, contact.Custom)...)
+		}
+		if len(errs) > 0 {
+			report.Problems = append(report.Problems, domain.RecordProblem{ID: id, Version: version, Errors: errs})
+		}
+	}
+	return true, "", report
+}
+
+// customFieldErrors lists every custom value that does not match the schema.
+func customFieldErrors(schema []domain.FieldDefinition, custom map[string]string) []domain.FieldError {
+	var errs []domain.FieldError
+	defined := make(map[string]bool, len(schema))
+	for _, definition := range schema {
+		defined[definition.Name] = true
+		value := custom[definition.Name]
+		switch {
+		case value == "" && definition.Required:
+			errs = append(errs, domain.FieldError{Field: customPrefix + definition.Name, Problem: "missing"})
+		case value != "" && !validValue(definition, value):
+			errs = append(errs, domain.FieldError{Field: customPrefix + definition.Name, Problem: "does not match its definition"})
+		}
+	}
+	names := make([]string, 0, len(custom))
+	for name := range custom {
+		if !defined[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		errs = append(errs, domain.FieldError{Field: customPrefix + name, Problem: "not a defined custom field"})
+	}
+	return errs
+}
@@ -0,0 +1,150 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+func TestDecodeContact(t *testing.T) {
+	tests := []struct {
+		name            string
+		data            map[string]interface{}
+		expected        domain.Contact
+		expectedVersion int
+		expectedErrors  []domain.FieldError
+	}{
+		{"current record",
+			map[string]interface{}{"name": "John", "phone": "123", "email": "j@example.com", "custom_team": "ops", versionField: 1},
+			domain.Contact{Name: "John", Phone: "123", Email: "j@example.com", Custom: map[string]string{"team": "ops"}}, 1, nil},
+		{"version read back as a float",
+			map[string]interface{}{"name": "John", "phone": "123", versionField: 1.0},
+			domain.Contact{Name: "John", Phone: "123"}, 1, nil},
+		{"unversioned record",
+			map[string]interface{}{"name": "John", "phone": "123"},
+			domain.Contact{Name: "John", Phone: "123"}, 0, nil},
+		{"unversioned record with capitalised names and nested custom fields",
+			map[string]interface{}{"Name": "John", "PHONE": "123", "custom": map[string]interface{}{"team": "ops"}},
+			domain.Contact{Name: "John", Phone: "123", Custom: map[string]string{"team": "ops"}}, 0, nil},
+		{"extra fields are ignored",
+			map[string]interface{}{"name": "John", "phone": "123", "nickname": "Jo", "_deleted_at": "2024-01-01T00:00:00Z", versionField: 1},
+			domain.Contact{Name: "John", Phone: "123"}, 1, nil},
+		{"missing fields",
+			map[string]interface{}{"email": "j@example.com", versionField: 1},
+			domain.Contact{Email: "j@example.com"}, 1,
+			[]domain.FieldError{{Field: "name", Problem: "missing"}, {Field: "phone", Problem: "missing"}}},
+		{"mistyped fields",
+			map[string]interface{}{"name": "John", "phone": 256700000001.0, "email": true, "address": []interface{}{"a"}, "custom_level": int64(3), versionField: 1},
+			domain.Contact{Name: "John", Phone: "256700000001", Email: "true", Custom: map[string]string{"level": "3"}}, 1,
+			[]domain.FieldError{
+				{Field: "phone", Problem: "expected text but got a number"},
+				{Field: "email", Problem: "expected text but got a boolean"},
+				{Field: "address", Problem: "expected text but got a list"},
+				{Field: "custom_level", Problem: "expected text but got a number"},
+			}},
+		{"invalid version",
+			map[string]interface{}{"name": "John", "phone": "123", versionField: "one"},
+			domain.Contact{Name: "John", Phone: "123"}, 0,
+			[]domain.FieldError{{Field: versionField, Problem: "not a version number"}}},
+		{"newer version",
+			map[string]interface{}{"name": "John", "phone": "123", versionField: 7},
+			domain.Contact{Name: "John", Phone: "123"}, 7,
+			[]domain.FieldError{{Field: versionField, Problem: "version 7 is newer than the supported version 1"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contact, version, errs := decodeContact(tt.data)
+			assert.Equal(t, tt.expected, contact)
+			assert.Equal(t, tt.expectedVersion, version)
+			assert.Equal(t, tt.expectedErrors, errs)
+		})
+	}
+}
+
+func TestDecodeContact_LeavesRecordAlone(t *testing.T) {
+	data := map[string]interface{}{"Name": "John", "phone": "123"}
+	decodeContact(data)
+	assert.Equal(t, map[string]interface{}{"Name": "John", "phone": "123"}, data)
+}
+
+func TestPhonebookService_WritesUpgradeRecords(t *testing.T) {
+	db := newMapDatabase()
+	s := NewPhonebookService(db)
+	require.True(t, mustSucceed(db.Create("legacy", map[string]interface{}{"Name": "John", "phone": 123.0, "custom": map[string]interface{}{}})))
+	require.True(t, mustSucceed(db.Create("future", map[string]interface{}{"name": "Jane", "phone": "456", versionField: 2})))
+
+	_, _, contact := s.GetContact("legacy")
+	assert.Equal(t, domain.Contact{Name: "John", Phone: "123"}, contact)
+
+	require.True(t, mustSucceed(s.UpdateContact("legacy", domain.Contact{Name: "John", Phone: "123"})))
+	_, _, data := db.Read("legacy")
+	assert.Equal(t, map[string]interface{}{"name": "John", "phone": "123", "email": "", "address": "", versionField: currentVersion}, data)
+
+	success, message := s.UpdateContact("future", domain.Contact{Name: "Jane", Phone: "456"})
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrUnsupportedRecordVersion.Error(), message)
+	success, message, _ = s.PatchContact("future", domain.ContactPatch{Document: []byte(`{"email":"jane@example.com"}`)})
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrUnsupportedRecordVersion.Error(), message)
+}
+
+func TestPhonebookService_PatchRewritesStoredLayout(t *testing.T) {
+	db := &patchingDatabase{MockDatabase: newMapDatabase()}
+	s := NewPhonebookService(db)
+	require.True(t, mustSucceed(db.Create("john", map[string]interface{}{"Name": "John", "phone": 123.0})))
+
+	success, message, _ := s.PatchContact("john", domain.ContactPatch{Document: []byte(`{"email":"john@example.com"}`)})
+	require.True(t, success, message)
+	assert.Equal(t, []map[string]interface{}{{
+		"Name": nil, "name": "John", "phone": "123", "email": "john@example.com", "address": "", versionField: currentVersion,
+	}}, db.patched)
+}
+
+func TestPhonebookService_VerifyRecords(t *testing.T) {
+	db := newMapDatabase()
+	s := NewPhonebookService(db)
+	require.True(t, mustSucceed(s.DefineField(domain.FieldDefinition{Name: "team", Type: domain.FieldText, Enum: []string{"ops", "dev"}})))
+	require.True(t, mustSucceed(s.AddContact("contacts/john", domain.Contact{Name: "John", Phone: "123", Custom: map[string]string{"team": "ops"}})))
+	require.True(t, mustSucceed(s.AddContact("contacts/jane", domain.Contact{Name: "Jane", Phone: "456"})))
+	require.True(t, mustSucceed(s.DeleteContact("contacts/jane")))
+	require.True(t, mustSucceed(db.Create("contacts/legacy", map[string]interface{}{"Name": "Old", "phone": "789"})))
+	require.True(t, mustSucceed(db.Create("contacts/broken", map[string]interface{}{"phone": 42.0, "custom_team": "sales", versionField: 1})))
+
+	success, message, report := s.VerifyRecords()
+	require.True(t, success, message)
+	assert.Equal(t, domain.VerifyReport{
+		Records:  4,
+		Outdated: 1,
+		Problems: []domain.RecordProblem{{ID: "contacts/broken", Version: 1, Errors: []domain.FieldError{
+			{Field: "phone", Problem: "expected text but got a number"},
+			{Field: "name", Problem: "missing"},
+			{Field: "custom_team", Problem: "does not match its definition"},
+		}}},
+	}, report)
+}
+
+func TestPhonebookService_VerifyUnreadableRecords(t *testing.T) {
+	db := newMapDatabase()
+	read := db.readFunc
+	db.readFunc = func(location string) (bool, string, map[string]interface{}) {
+		if location == "contacts/secret" {
+			return false, "Error decrypting phone", nil
+		}
+		return read(location)
+	}
+	s := NewPhonebookService(db, WithAuthorization())
+	require.True(t, mustSucceed(db.Create("contacts/secret", map[string]interface{}{})))
+
+	success, message, _ := s.VerifyRecords()
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrUnauthenticated.Error(), message)
+
+	success, message, report := s.As(ContextWithActor(context.Background(), domain.Actor{Name: "root", Role: domain.RoleAdmin})).VerifyRecords()
+	require.True(t, success, message)
+	assert.Equal(t, []domain.RecordProblem{{ID: "contacts/secret", Errors: []domain.FieldError{{Problem: "Error decrypting phone"}}}}, report.Problems)
+}
@@ -0,0 +1,221 @@
+package application
+
+import (
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+const (
+	// schemaLocation holds the phonebook's custom field definitions. It lives
+	// with the contacts, so backups, migrations and tenants carry it along.
+	schemaLocation = "_schema/fields"
+
+	// reservedPrefix starts the ids of the service's own records, such as
+	// the schema. Contacts cannot be stored under it.
+	reservedPrefix = "_"
+	// customPrefix marks the stored values of custom fields. They are kept as
+	// top-level strings, like the built-in fields.
+	customPrefix = "custom_"
+)
+
+var fieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// DefineField adds a custom field to the phonebook, or replaces the
+// definition with the same name. Stored values are checked against the new
+// definition the next time their contact is written.
+func (s *PhonebookService) DefineField(definition domain.FieldDefinition) (bool, string) {
+	if err := s.authorize(domain.RoleAdmin); err != nil {
+		return false, err.Error()
+	}
+	if err := validateDefinition(definition); err != nil {
+		return false, err.Error()
+	}
+
+	schema, err := s.fieldSchema()
+	if err != nil {
+		return false, err.Error()
+	}
+	schema = slices.DeleteFunc(schema, func(d domain.FieldDefinition) bool { return d.Name == definition.Name })
+	schema = append(schema, definition)
+	sort.Slice(schema, func(i, j int) bool { return schema[i].Name < schema[j].Name })
+
+	success, message, _ := upsert(s.db, schemaLocation, encodeRecord(schema))
+	return success, message
+}
+
+// RemoveField removes a custom field from the phonebook and its values from
+// every contact, including those in the trash.
+func (s *PhonebookService) RemoveField(name string) (bool, string) {
+	if err := s.authorize(domain.RoleAdmin); err != nil {
+		return false, err.Error()
+	}
+	schema, err := s.fieldSchema()
+	if err != nil {
+		return false, err.Error()
+	}
+	i := slices.IndexFunc(schema, func(d domain.FieldDefinition) bool { return d.Name == name })
+	if i < 0 {
+		return false, domain.ErrFieldNotFound.Error()
+	}
+
+	// Drop the definition first, so values written meanwhile are rejected
+	schema = slices.Delete(schema, i, i+1)
+	if success, message := s.db.Update(schemaLocation, encodeRecord(schema)); !success {
+		return false, message
+	}
+
+	success, message, ids := s.db.List("")
+	if !success {
+		return false, message
+	}
+	key := customPrefix + name
+	for _, id := range ids {
+		success, message, data := s.db.Read(id)
+		if !success {
+			if isMissing(message) {
+				continue
+			}
+			return false, message
+		}
+		if _, ok := data[key]; !ok {
+			continue
+		}
+		delete(data, key)
+		if success, message := s.db.Update(id, data); !success {
+			return false, message
+		}
+	}
+	return true, ""
+}
+
+// FieldDefinitions returns the phonebook's custom fields, ordered by name.
+func (s *PhonebookService) FieldDefinitions() (bool, string, []domain.FieldDefinition) {
+	if err := s.authorize(domain.RoleViewer); err != nil {
+		return false, err.Error(), nil
+	}
+	schema, err := s.fieldSchema()
+	if err != nil {
+		return false, err.Error(), nil
+	}
+	return true, "", schema
+}
+
+// fieldSchema reads the custom field definitions. A phonebook without any
+// has no schema record.
+func (s *PhonebookService) fieldSchema() ([]domain.FieldDefinition, error) {
+	schema := []domain.FieldDefinition{}
+	success, message := readRecord(s.db, schemaLocation, &schema)
+	if !success && !isMissing(message) {
+		return nil, resultError(success, message)
+	}
+	return schema, nil
+}
+
+// mergeCustom returns the stored custom values overridden by custom, so the
+// fields custom leaves out keep their stored values.
+func mergeCustom(stored, custom map[string]string) map[string]string {
+	if len(custom) == 0 {
+		return stored
+	}
+	merged := make(map[string]string, len(stored)+len(custom))
+	for name, value := range stored {
+		merged[name] = value
+	}
+	for name, value := range custom {
+		merged[name] = value
+	}
+	return merged
+}
+
+func validateDefinition(definition domain.FieldDefinition) error {
+	if !fieldNamePattern.MatchString(definition.Name) {
+		return domain.ErrInvalidFieldName
+	}
+	if slices.Contains(contactFields, definition.Name) {
+		return domain.ErrInvalidFieldName
+	}
+	switch definition.Type {
+	case domain.FieldText, domain.FieldNumber, domain.FieldBoolean, domain.FieldDate:
+	default:
+		return domain.ErrInvalidFieldType
+	}
+	if _, err := regexp.Compile(anchored(definition.Pattern)); err != nil {
+		return domain.ErrInvalidFieldPattern
+	}
+	for _, value := range definition.Enum {
+		if value == "" || !validValue(domain.FieldDefinition{Type: definition.Type, Pattern: definition.Pattern}, value) {
+			return domain.ErrInvalidFieldEnum
+		}
+	}
+	return nil
+}
+
+// validateCustom checks the custom values of a contact against the schema.
+// Empty values count as unset.
+func validateCustom(schema []domain.FieldDefinition, custom map[string]string) error {
+	for name, value := range custom {
+		if value != "" && !slices.ContainsFunc(schema, func(d domain.FieldDefinition) bool { return d.Name == name }) {
+			return domain.ErrUnknownCustomField
+		}
+	}
+	for _, definition := range schema {
+		value := custom[definition.Name]
+		if value == "" {
+			if definition.Required {
+				return domain.ErrMissingCustomField
+			}
+			continue
+		}
+		if !validValue(definition, value) {
+			return domain.ErrInvalidCustomValue
+		}
+	}
+	return nil
+}
+
+// validValue reports whether value has the definition's type and is one of
+// its enum values and matches its pattern, when it has them.
+func validValue(definition domain.FieldDefinition, value string) bool {
+	switch definition.Type {
+	case domain.FieldNumber:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			return false
+		}
+	case domain.FieldBoolean:
+		if value != "true" && value != "false" {
+			return false
+		}
+	case domain.FieldDate:
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			return false
+		}
+	}
+	if len(definition.Enum) > 0 && !slices.Contains(definition.Enum, value) {
+		return false
+	}
+	if definition.Pattern != "" {
+		pattern, err := regexp.Compile(anchored(definition.Pattern))
+		if err != nil || !pattern.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// anchored makes a pattern match whole values only.
+func anchored(pattern string) string {
+	return "^(?:" + pattern + ")$"
+}
+
+// isReserved reports whether id is kept for the service's own records.
+func isReserved(id string) bool {
+	return strings.HasPrefix(id, reservedPrefix)
+}
@@ -0,0 +1,304 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+// setupSchemaTest returns a phonebook with a required number field, an
+// optional enum field and a date field.
+func setupSchemaTest(t *testing.T) *PhonebookService {
+	s := NewPhonebookService(newMapDatabase())
+	for _, definition := range []domain.FieldDefinition{
+		{Name: "employee_id", Type: domain.FieldNumber, Required: true},
+		{Name: "timezone", Type: domain.FieldText, Enum: []string{"UTC", "EAT"}},
+		{Name: "started", Type: domain.FieldDate},
+	} {
+		require.True(t, mustSucceed(s.DefineField(definition)))
+	}
+	return s
+}
+
+func TestPhonebookService_DefineField(t *testing.T) {
+	tests := []struct {
+		name            string
+		definition      domain.FieldDefinition
+		expectedMessage string
+	}{
+		{"text", domain.FieldDefinition{Name: "manager", Type: domain.FieldText}, ""},
+		{"boolean", domain.FieldDefinition{Name: "vip", Type: domain.FieldBoolean, Required: true}, ""},
+		{"enum of numbers", domain.FieldDefinition{Name: "level", Type: domain.FieldNumber, Enum: []string{"1", "2.5"}}, ""},
+		{"pattern", domain.FieldDefinition{Name: "badge", Type: domain.FieldText, Pattern: `[A-Z]{2}\d+`}, ""},
+		{"empty name", domain.FieldDefinition{Type: domain.FieldText}, domain.ErrInvalidFieldName.Error()},
+		{"uppercase name", domain.FieldDefinition{Name: "Manager", Type: domain.FieldText}, domain.ErrInvalidFieldName.Error()},
+		{"name with a dash", domain.FieldDefinition{Name: "account-manager", Type: domain.FieldText}, domain.ErrInvalidFieldName.Error()},
+		{"built-in name", domain.FieldDefinition{Name: "phone", Type: domain.FieldText}, domain.ErrInvalidFieldName.Error()},
+		{"unknown type", domain.FieldDefinition{Name: "manager", Type: "person"}, domain.ErrInvalidFieldType.Error()},
+		{"invalid pattern", domain.FieldDefinition{Name: "badge", Type: domain.FieldText, Pattern: "[A-Z"}, domain.ErrInvalidFieldPattern.Error()},
+		{"enum of the wrong type", domain.FieldDefinition{Name: "level", Type: domain.FieldNumber, Enum: []string{"one"}}, domain.ErrInvalidFieldEnum.Error()},
+		{"enum not matching the pattern", domain.FieldDefinition{Name: "badge", Type: domain.FieldText, Pattern: `\d+`, Enum: []string{"x1"}}, domain.ErrInvalidFieldEnum.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewPhonebookService(newMapDatabase())
+			success, message := s.DefineField(tt.definition)
+			assert.Equal(t, tt.expectedMessage == "", success)
+			assert.Equal(t, tt.expectedMessage, message)
+
+			_, _, definitions := s.FieldDefinitions()
+			if success {
+				assert.Equal(t, []domain.FieldDefinition{tt.definition}, definitions)
+			} else {
+				assert.Empty(t, definitions)
+			}
+		})
+	}
+}
+
+func TestPhonebookService_DefineFieldReplaces(t *testing.T) {
+	s := setupSchemaTest(t)
+	require.True(t, mustSucceed(s.DefineField(domain.FieldDefinition{Name: "timezone", Type: domain.FieldText})))
+
+	success, message, definitions := s.FieldDefinitions()
+	require.True(t, success, message)
+	names := []string{}
+	for _, definition := range definitions {
+		names = append(names, definition.Name)
+	}
+	assert.Equal(t, []string{"employee_id", "started", "timezone"}, names)
+	assert.Empty(t, definitions[2].Enum)
+}
+
+func TestPhonebookService_ValidateCustomFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		custom   map[string]string
+		expected error
+	}{
+		{"all fields", map[string]string{"employee_id": "42", "timezone": "EAT", "started": "2024-02-29"}, nil},
+		{"required field only", map[string]string{"employee_id": "-1.5e3"}, nil},
+		{"empty optional field", map[string]string{"employee_id": "42", "timezone": ""}, nil},
+		{"missing required field", map[string]string{"timezone": "UTC"}, domain.ErrMissingCustomField},
+		{"empty required field", map[string]string{"employee_id": ""}, domain.ErrMissingCustomField},
+		{"unknown field", map[string]string{"employee_id": "42", "nickname": "Jo"}, domain.ErrUnknownCustomField},
+		{"not a number", map[string]string{"employee_id": "forty-two"}, domain.ErrInvalidCustomValue},
+		{"not a finite number", map[string]string{"employee_id": "Inf"}, domain.ErrInvalidCustomValue},
+		{"not an enum value", map[string]string{"employee_id": "42", "timezone": "utc"}, domain.ErrInvalidCustomValue},
+		{"not a date", map[string]string{"employee_id": "42", "started": "2023-02-29"}, domain.ErrInvalidCustomValue},
+	}
+
+	s := setupSchemaTest(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.ValidateContact(domain.Contact{Name: "John", Phone: "123", Custom: tt.custom})
+			assert.Equal(t, tt.expected, err)
+		})
+	}
+
+	assert.Equal(t, domain.ErrInvalidContactName, s.ValidateContact(domain.Contact{Phone: "123"}), "built-in fields are checked first")
+}
+
+func TestPhonebookService_CustomFieldsRoundTrip(t *testing.T) {
+	s := setupSchemaTest(t)
+	john := domain.Contact{Name: "John", Phone: "123", Custom: map[string]string{"employee_id": "42", "timezone": "EAT"}}
+	require.True(t, mustSucceed(s.AddContact("contacts/john", john)))
+
+	_, _, contact := s.GetContact("contacts/john")
+	assert.Equal(t, john, contact)
+
+	success, message, phonebook := s.ListContacts("")
+	require.True(t, success, message)
+	assert.Equal(t, map[string]domain.Contact{"contacts/john": john}, phonebook.Contacts, "the schema is not listed as a contact")
+
+	success, _, _ = s.GetContact(schemaLocation)
+	assert.False(t, success)
+}
+
+func TestPhonebookService_ReservedIDs(t *testing.T) {
+	s := setupSchemaTest(t)
+	contact := domain.Contact{Name: "John", Phone: "123", Custom: map[string]string{"employee_id": "42"}}
+	require.True(t, mustSucceed(s.AddContact("contacts/john", contact)))
+
+	success, message := s.AddContact(schemaLocation, contact)
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrReservedContactID.Error(), message)
+
+	success, message = s.AddContact("_other", contact)
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrReservedContactID.Error(), message)
+
+	success, message = s.MoveContact("contacts/john", schemaLocation)
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrReservedContactID.Error(), message)
+
+	success, message = s.UpdateContact(schemaLocation, contact)
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrContactNotFound.Error(), message)
+
+	imp := s.NewImporter(true)
+	imp.Add(1, schemaLocation, contact)
+	results := imp.Close()
+	require.Len(t, results, 1)
+	assert.Equal(t, domain.ErrReservedContactID.Error(), results[0].Message)
+
+	// The schema is still readable
+	require.True(t, mustSucceed(s.AddContact("contacts/jane", contact)))
+	require.True(t, mustSucceed(s.DefineField(domain.FieldDefinition{Name: "team", Type: domain.FieldText})))
+}
+
+func TestPhonebookService_UpdateContactKeepsCustomFields(t *testing.T) {
+	s := setupSchemaTest(t)
+	require.True(t, mustSucceed(s.AddContact("contacts/john", domain.Contact{Name: "John", Phone: "123",
+		Custom: map[string]string{"employee_id": "42", "timezone": "EAT"}})))
+
+	// Fields left out keep their values, empty ones are cleared
+	success, message := s.UpdateContact("contacts/john", domain.Contact{Name: "Johnny", Phone: "123", Custom: map[string]string{"timezone": ""}})
+	require.True(t, success, message)
+	_, _, contact := s.GetContact("contacts/john")
+	assert.Equal(t, domain.Contact{Name: "Johnny", Phone: "123", Custom: map[string]string{"employee_id": "42"}}, contact)
+
+	success, message = s.UpdateContact("contacts/john", domain.Contact{Name: "Johnny", Phone: "123", Custom: map[string]string{"employee_id": ""}})
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrMissingCustomField.Error(), message)
+}
+
+func TestPhonebookService_RemoveField(t *testing.T) {
+	s := setupSchemaTest(t)
+	require.True(t, mustSucceed(s.AddContact("contacts/john", domain.Contact{Name: "John", Phone: "123",
+		Custom: map[string]string{"employee_id": "42", "timezone": "EAT"}})))
+	require.True(t, mustSucceed(s.DeleteContact("contacts/john")))
+
+	require.True(t, mustSucceed(s.RemoveField("timezone")))
+	success, message := s.RemoveField("timezone")
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrFieldNotFound.Error(), message)
+
+	require.True(t, mustSucceed(s.RestoreContact("contacts/john")))
+	_, _, contact := s.GetContact("contacts/john")
+	assert.Equal(t, map[string]string{"employee_id": "42"}, contact.Custom, "values are removed from trashed contacts too")
+}
+
+func TestPhonebookService_PatchCustomFields(t *testing.T) {
+	tests := []struct {
+		name            string
+		patch           domain.ContactPatch
+		expectedMessage string
+		expected        map[string]string
+	}{
+		{"merge patch sets a field", domain.ContactPatch{Document: []byte(`{"custom":{"started":"2024-01-02"}}`)}, "",
+			map[string]string{"employee_id": "42", "timezone": "EAT", "started": "2024-01-02"}},
+		{"merge patch null clears a field", domain.ContactPatch{Document: []byte(`{"custom":{"timezone":null}}`)}, "",
+			map[string]string{"employee_id": "42"}},
+		{"merge patch of an invalid value", domain.ContactPatch{Document: []byte(`{"custom":{"timezone":"PST"}}`)},
+			domain.ErrInvalidCustomValue.Error(), nil},
+		{"merge patch with a non-string value", domain.ContactPatch{Document: []byte(`{"custom":{"employee_id":43}}`)},
+			domain.ErrInvalidPatch.Error(), nil},
+		{"json patch", domain.ContactPatch{Format: domain.JSONPatch, Document: []byte(`[
+			{"op":"test","path":"/custom/employee_id","value":"42"},
+			{"op":"replace","path":"/custom/employee_id","value":"43"},
+			{"op":"add","path":"/custom/started","value":"2024-01-02"},
+			{"op":"remove","path":"/custom/timezone"}]`)}, "",
+			map[string]string{"employee_id": "43", "started": "2024-01-02"}},
+		{"json patch removing a required field", domain.ContactPatch{Format: domain.JSONPatch, Document: []byte(`[{"op":"remove","path":"/custom/employee_id"}]`)},
+			domain.ErrMissingCustomField.Error(), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &patchingDatabase{MockDatabase: newMapDatabase()}
+			s := NewPhonebookService(db)
+			require.True(t, mustSucceed(s.DefineField(domain.FieldDefinition{Name: "employee_id", Type: domain.FieldNumber, Required: true})))
+			require.True(t, mustSucceed(s.DefineField(domain.FieldDefinition{Name: "timezone", Type: domain.FieldText, Enum: []string{"UTC", "EAT"}})))
+			require.True(t, mustSucceed(s.DefineField(domain.FieldDefinition{Name: "started", Type: domain.FieldDate})))
+			require.True(t, mustSucceed(s.AddContact("john", domain.Contact{Name: "John", Phone: "123",
+				Custom: map[string]string{"employee_id": "42", "timezone": "EAT"}})))
+
+			success, message, contact := s.PatchContact("john", tt.patch)
+			assert.Equal(t, tt.expectedMessage == "", success)
+			assert.Equal(t, tt.expectedMessage, message)
+			assert.Equal(t, tt.expected, contact.Custom)
+			if success {
+				_, _, stored := s.GetContact("john")
+				assert.Equal(t, tt.expected, stored.Custom)
+			}
+		})
+	}
+}
+
+func TestPhonebookService_SearchCustomFields(t *testing.T) {
+	s := setupSchemaTest(t)
+	require.True(t, mustSucceed(s.AddContact("contacts/john", domain.Contact{Name: "John", Phone: "123", Custom: map[string]string{"employee_id": "4711"}})))
+	require.True(t, mustSucceed(s.AddContact("contacts/jane", domain.Contact{Name: "Jane", Phone: "456", Custom: map[string]string{"employee_id": "42", "timezone": "EAT"}})))
+
+	_, _, found := s.SearchContacts("eat", "")
+	assert.Equal(t, []string{"contacts/jane"}, sortedKeys(found.Contacts))
+	_, _, found = s.SearchContacts("4711", "")
+	assert.Equal(t, []string{"contacts/john"}, sortedKeys(found.Contacts))
+}
+
+func TestPhonebookService_CustomFieldsCSV(t *testing.T) {
+	s := setupSchemaTest(t)
+	require.True(t, mustSucceed(s.AddContact("contacts/john", domain.Contact{Name: "John", Phone: "123", Custom: map[string]string{"employee_id": "42", "timezone": "EAT"}})))
+
+	var exported bytes.Buffer
+	require.True(t, mustSucceed(s.ExportContacts("", &exported)))
+	assert.Equal(t, "id,name,phone,email,address,employee_id,started,timezone\ncontacts/john,John,123,,,42,,EAT\n", exported.String())
+
+	imported := setupSchemaTest(t)
+	input := exported.String() + "contacts/jane,Jane,456,,,,,\ncontacts/bob,Bob,789,,,x,,\n"
+	success, message, results := imported.ImportCSV(strings.NewReader(input), false)
+	require.True(t, success, message)
+	require.Len(t, results, 3)
+	assert.True(t, results[0].Success, results[0].Message)
+	assert.Equal(t, domain.ErrMissingCustomField.Error(), results[1].Message)
+	assert.Equal(t, domain.ErrInvalidCustomValue.Error(), results[2].Message)
+
+	_, _, john := imported.GetContact("contacts/john")
+	assert.Equal(t, map[string]string{"employee_id": "42", "timezone": "EAT"}, john.Custom)
+}
+
+func TestPhonebookService_CustomFieldsVCard(t *testing.T) {
+	s := setupSchemaTest(t)
+	john := domain.Contact{Name: "John Doe", Phone: "123", Custom: map[string]string{"employee_id": "42", "timezone": "EAT"}}
+	require.True(t, mustSucceed(s.AddContact("contacts/john", john)))
+
+	var exported bytes.Buffer
+	require.True(t, mustSucceed(s.ExportVCard("", &exported)))
+	assert.Contains(t, exported.String(), "X-PHONEBOOK-EMPLOYEE-ID:42")
+
+	imported := setupSchemaTest(t)
+	success, message, results := imported.ImportVCard(&exported, false)
+	require.True(t, success, message)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Success, results[0].Message)
+	_, _, contact := imported.GetContact("contacts/john")
+	assert.Equal(t, john, contact)
+}
+
+func TestPhonebookService_FieldsAuthorization(t *testing.T) {
+	s := NewPhonebookService(newMapDatabase(), WithAuthorization())
+	editor := s.As(ContextWithActor(context.Background(), domain.Actor{Name: "ed", Role: domain.RoleEditor}))
+	admin := s.As(ContextWithActor(context.Background(), domain.Actor{Name: "root", Role: domain.RoleAdmin}))
+	definition := domain.FieldDefinition{Name: "manager", Type: domain.FieldText}
+
+	success, message := editor.DefineField(definition)
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrPermissionDenied.Error(), message)
+	require.True(t, mustSucceed(admin.DefineField(definition)))
+
+	success, message, definitions := editor.FieldDefinitions()
+	require.True(t, success, message)
+	assert.Equal(t, []domain.FieldDefinition{definition}, definitions)
+
+	success, message = editor.RemoveField("manager")
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrPermissionDenied.Error(), message)
+}
@@ -0,0 +1,322 @@
+package application
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// tenantPrefix holds one record per tenant in the tenant registry.
+const tenantPrefix = "tenants/"
+
+// Tenant ids must be usable as a directory, a collection suffix and a
+// session setting in every backend.
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// TenantManager provisions tenants and opens their isolated databases. The
+// registry keeps the tenant records; the backend keeps their phonebooks.
+type TenantManager struct {
+	registry ports.Database
+	backend  ports.TenantDatabase
+	now      func() time.Time
+
+	mu   sync.Mutex
+	open map[string]openTenant
+}
+
+type openTenant struct {
+	tenant domain.Tenant
+	db     ports.Database
+}
+
+func NewTenantManager(registry ports.Database, backend ports.TenantDatabase) *TenantManager {
+	return &TenantManager{
+		registry: registry,
+		backend:  backend,
+		now:      time.Now,
+		open:     make(map[string]openTenant),
+	}
+}
+
+func (m *TenantManager) CreateTenant(tenant domain.Tenant) (bool, string, domain.Tenant) {
+	if !tenantIDPattern.MatchString(tenant.ID) {
+		return false, domain.ErrInvalidTenantID.Error(), domain.Tenant{}
+	}
+	if tenant.MaxContacts < 0 {
+		tenant.MaxContacts = 0
+	}
+
+	tenant.CreatedAt = m.now().UTC()
+	if success, message := m.registry.Create(tenantPrefix+tenant.ID, encodeRecord(tenant)); !success {
+		if strings.HasSuffix(message, "already exists") {
+			message = domain.ErrTenantExists.Error()
+		}
+		return false, message, domain.Tenant{}
+	}
+	return true, "", tenant
+}
+
+func (m *TenantManager) GetTenant(id string) (bool, string, domain.Tenant) {
+	if !tenantIDPattern.MatchString(id) {
+		return false, domain.ErrTenantNotFound.Error(), domain.Tenant{}
+	}
+	var tenant domain.Tenant
+	if success, message := readRecord(m.registry, tenantPrefix+id, &tenant); !success {
+		return false, notFound(message, domain.ErrTenantNotFound), domain.Tenant{}
+	}
+	return true, "", tenant
+}
+
+func (m *TenantManager) ListTenants() (bool, string, []domain.Tenant) {
+	success, message, ids := m.registry.List(tenantPrefix)
+	if !success {
+		return false, message, nil
+	}
+	sort.Strings(ids)
+
+	tenants := make([]domain.Tenant, 0, len(ids))
+	for _, id := range ids {
+		var tenant domain.Tenant
+		if success, message := readRecord(m.registry, id, &tenant); !success {
+			return false, message, nil
+		}
+		tenants = append(tenants, tenant)
+	}
+	return true, "", tenants
+}
+
+// SetQuota changes how many contacts a tenant may hold. Zero removes the limit.
+func (m *TenantManager) SetQuota(id string, maxContacts int) (bool, string) {
+	success, message, tenant := m.GetTenant(id)
+	if !success {
+		return false, message
+	}
+	tenant.MaxContacts = max(maxContacts, 0)
+	if success, message := m.registry.Update(tenantPrefix+id, encodeRecord(tenant)); !success {
+		return false, message
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if open, ok := m.open[id]; ok {
+		open.tenant = tenant
+		m.open[id] = open
+	}
+	return true, ""
+}
+
+// DeleteTenant removes a tenant together with its whole phonebook.
+func (m *TenantManager) DeleteTenant(id string) (bool, string) {
+	if success, message, _ := m.GetTenant(id); !success {
+		return false, message
+	}
+
+	m.mu.Lock()
+	open, ok := m.open[id]
+	delete(m.open, id)
+	m.mu.Unlock()
+	if ok {
+		closeDatabase(open.db)
+	}
+
+	if success, message := m.backend.DropTenant(id); !success {
+		return false, message
+	}
+	return m.registry.Delete(tenantPrefix + id)
+}
+
+// Open returns a provisioned tenant and its database, opening it on first use.
+func (m *TenantManager) Open(id string) (bool, string, domain.Tenant, ports.Database) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if open, ok := m.open[id]; ok {
+		return true, "", open.tenant, open.db
+	}
+
+	success, message, tenant := m.GetTenant(id)
+	if !success {
+		return false, message, domain.Tenant{}, nil
+	}
+	success, message, db := m.backend.Tenant(id)
+	if !success {
+		return false, message, domain.Tenant{}, nil
+	}
+	m.open[id] = openTenant{tenant: tenant, db: db}
+	return true, "", tenant, db
+}
+
+// Close closes the tenant databases opened so far.
+func (m *TenantManager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var first error
+	for id, open := range m.open {
+		if err := closeDatabase(open.db); err != nil && first == nil {
+			first = err
+		}
+		delete(m.open, id)
+	}
+	return first
+}
+
+func closeDatabase(db ports.Database) error {
+	if closer, ok := db.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// WithTenants gives every tenant a phonebook of its own. Calls then run
+// against the tenant chosen by As and fail when there is none.
+func WithTenants(tenants *TenantManager) Option {
+	return func(s *PhonebookService) {
+		s.tenants = tenants
+	}
+}
+
+type tenantKey struct{}
+
+// ContextWithTenant returns a context selecting a tenant.
+func ContextWithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// TenantFromContext returns the tenant selected by ctx, if any.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok && id != ""
+}
+
+// bindTenant points the service at the tenant selected by ctx, falling back
+// to the actor's own. Without one, every call fails.
+func (s *PhonebookService) bindTenant(ctx context.Context) {
+	id, ok := TenantFromContext(ctx)
+	if s.actor != nil && s.actor.Tenant != "" {
+		if ok && id != s.actor.Tenant {
+			s.db = failingDatabase{message: domain.ErrPermissionDenied.Error()}
+			return
+		}
+		id, ok = s.actor.Tenant, true
+	}
+	if !ok {
+		s.db = failingDatabase{message: domain.ErrTenantRequired.Error()}
+		return
+	}
+
+	success, message, tenant, db := s.tenants.Open(id)
+	if !success {
+		s.db = failingDatabase{message: message}
+		return
+	}
+	s.db = db
+	s.maxContacts = tenant.MaxContacts
+}
+
+// forEachTenant runs fn on a copy of the service bound to each tenant, or on
+// the service itself without tenants.
+func (s *PhonebookService) forEachTenant(fn func(id string, s *PhonebookService)) (bool, string) {
+	if s.tenants == nil {
+		fn("", s)
+		return true, ""
+	}
+
+	success, message, tenants := s.tenants.ListTenants()
+	if !success {
+		return false, message
+	}
+	for _, tenant := range tenants {
+		bound := *s
+		bound.bindTenant(ContextWithTenant(context.Background(), tenant.ID))
+		fn(tenant.ID, &bound)
+	}
+	return true, ""
+}
+
+// quotaLeft returns how many more contacts the tenant may hold, or -1 when
+// there is no limit.
+func (s *PhonebookService) quotaLeft() (int, error) {
+	if s.maxContacts <= 0 {
+		return -1, nil
+	}
+	success, message, ids := s.db.List("")
+	if !success {
+		return 0, resultError(success, message)
+	}
+	used := 0
+	for _, id := range ids {
+		if !isReserved(id) {
+			used++
+		}
+	}
+	return max(s.maxContacts-used, 0), nil
+}
+
+// failingDatabase stands in for the database of a call that has no tenant to
+// run against, failing every operation with the same message.
+type failingDatabase struct {
+	message string
+}
+
+func (f failingDatabase) Create(string, map[string]interface{}) (bool, string) {
+	return false, f.message
+}
+
+func (f failingDatabase) Read(string) (bool, string, map[string]interface{}) {
+	return false, f.message, nil
+}
+
+func (f failingDatabase) Update(string, map[string]interface{}) (bool, string) {
+	return false, f.message
+}
+
+func (f failingDatabase) Delete(string) (bool, string) {
+	return false, f.message
+}
+
+func (f failingDatabase) List(string) (bool, string, []string) {
+	return false, f.message, nil
+}
+
+func (f failingDatabase) CreateGroup(string) (bool, string) {
+	return false, f.message
+}
+
+func (f failingDatabase) RenameGroup(string, string) (bool, string) {
+	return false, f.message
+}
+
+func (f failingDatabase) DeleteGroup(string) (bool, string) {
+	return false, f.message
+}
+
+func (f failingDatabase) ListGroups() (bool, string, []string) {
+	return false, f.message, nil
+}
+
+func (f failingDatabase) AddMember(string, string) (bool, string) {
+	return false, f.message
+}
+
+func (f failingDatabase) RemoveMember(string, string) (bool, string) {
+	return false, f.message
+}
+
+func (f failingDatabase) Members(string) (bool, string, []string) {
+	return false, f.message, nil
+}
+
+func (f failingDatabase) GroupsOf(string) (bool, string, []string) {
+	return false, f.message, nil
+}
+
+func (f failingDatabase) Watch(context.Context, string, string) (bool, string, <-chan ports.ChangeEvent) {
+	return false, f.message, nil
+}
@@ -0,0 +1,160 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// mapTenantDatabase keeps every tenant in a database of its own.
+type mapTenantDatabase struct {
+	tenants map[string]*MockGroupDatabase
+}
+
+func (m *mapTenantDatabase) Tenant(id string) (bool, string, ports.Database) {
+	if m.tenants[id] == nil {
+		m.tenants[id] = newMapGroupDatabase()
+	}
+	return true, "", m.tenants[id]
+}
+
+func (m *mapTenantDatabase) DropTenant(id string) (bool, string) {
+	delete(m.tenants, id)
+	return true, ""
+}
+
+func setupTenantTest(t *testing.T) (*PhonebookService, *TenantManager, *mapTenantDatabase) {
+	backend := &mapTenantDatabase{tenants: make(map[string]*MockGroupDatabase)}
+	tenants := NewTenantManager(newMapDatabase(), backend)
+	for _, tenant := range []domain.Tenant{{ID: "acme"}, {ID: "globex", MaxContacts: 2}} {
+		success, message, _ := tenants.CreateTenant(tenant)
+		require.True(t, success, message)
+	}
+	return NewPhonebookService(newMapDatabase(), WithTenants(tenants)), tenants, backend
+}
+
+func tenantContext(id string) context.Context {
+	return ContextWithTenant(context.Background(), id)
+}
+
+func TestTenantManager_CreateTenant(t *testing.T) {
+	tests := []struct {
+		name        string
+		tenant      domain.Tenant
+		expectedErr error
+	}{
+		{"valid", domain.Tenant{ID: "initech", Name: "Initech"}, nil},
+		{"existing", domain.Tenant{ID: "acme"}, domain.ErrTenantExists},
+		{"uppercase", domain.Tenant{ID: "Acme"}, domain.ErrInvalidTenantID},
+		{"path", domain.Tenant{ID: "../acme"}, domain.ErrInvalidTenantID},
+		{"empty", domain.Tenant{}, domain.ErrInvalidTenantID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, tenants, _ := setupTenantTest(t)
+			success, message, _ := tenants.CreateTenant(tt.tenant)
+			if tt.expectedErr != nil {
+				assert.False(t, success)
+				assert.Equal(t, tt.expectedErr.Error(), message)
+				return
+			}
+			assert.True(t, success, message)
+			_, _, list := tenants.ListTenants()
+			assert.Len(t, list, 3)
+		})
+	}
+}
+
+func TestPhonebookService_TenantIsolation(t *testing.T) {
+	s, _, _ := setupTenantTest(t)
+
+	acme := s.As(tenantContext("acme"))
+	globex := s.As(tenantContext("globex"))
+	require.True(t, mustSucceed(acme.AddContact("contacts/john", trashTestContact)))
+
+	success, _, _ := globex.GetContact("contacts/john")
+	assert.False(t, success)
+	_, _, phonebook := acme.ListContacts("")
+	assert.Len(t, phonebook.Contacts, 1)
+	_, _, phonebook = globex.ListContacts("")
+	assert.Empty(t, phonebook.Contacts)
+
+	// Calls need a provisioned tenant
+	success, message := s.As(context.Background()).AddContact("contacts/jane", trashTestContact)
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrTenantRequired.Error(), message)
+	success, message = s.As(tenantContext("initech")).AddContact("contacts/jane", trashTestContact)
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrTenantNotFound.Error(), message)
+	success, message, _ = s.As(context.Background()).ListGroups()
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrTenantRequired.Error(), message)
+}
+
+func TestPhonebookService_TenantOfActor(t *testing.T) {
+	s, _, _ := setupTenantTest(t)
+	actor := domain.Actor{Name: "ann", Role: domain.RoleAdmin, Tenant: "acme"}
+	ctx := ContextWithActor(context.Background(), actor)
+
+	// The actor's tenant applies without selecting one
+	assert.True(t, mustSucceed(s.As(ctx).AddContact("contacts/john", trashTestContact)))
+	success, _, _ := s.As(tenantContext("acme")).GetContact("contacts/john")
+	assert.True(t, success)
+
+	success, message := s.As(ContextWithTenant(ctx, "globex")).AddContact("contacts/jane", trashTestContact)
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrPermissionDenied.Error(), message)
+}
+
+func TestPhonebookService_TenantQuota(t *testing.T) {
+	s, tenants, _ := setupTenantTest(t)
+	globex := s.As(tenantContext("globex"))
+
+	assert.True(t, mustSucceed(globex.AddContact("contacts/john", trashTestContact)))
+	assert.True(t, mustSucceed(globex.AddContact("contacts/jane", trashTestContact)))
+	success, message := globex.AddContact("contacts/bob", trashTestContact)
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrTenantQuotaExceeded.Error(), message)
+
+	// Trashed contacts still count
+	assert.True(t, mustSucceed(globex.DeleteContact("contacts/jane")))
+	success, _ = globex.AddContact("contacts/bob", trashTestContact)
+	assert.False(t, success)
+
+	// Overwriting takes no quota; new contacts do
+	imp := globex.NewImporter(true)
+	imp.Add(1, "contacts/john", trashTestContact)
+	imp.Add(2, "contacts/bob", trashTestContact)
+	results := imp.Close()
+	assert.True(t, results[0].Success, results[0].Message)
+	assert.Equal(t, domain.ErrTenantQuotaExceeded.Error(), results[1].Message)
+
+	require.True(t, mustSucceed(tenants.SetQuota("globex", 0)))
+	assert.True(t, mustSucceed(s.As(tenantContext("globex")).AddContact("contacts/bob", trashTestContact)))
+}
+
+func TestTenantManager_DeleteTenant(t *testing.T) {
+	s, tenants, backend := setupTenantTest(t)
+	require.True(t, mustSucceed(s.As(tenantContext("acme")).AddContact("contacts/john", trashTestContact)))
+
+	success, message := tenants.DeleteTenant("acme")
+	require.True(t, success, message)
+	assert.NotContains(t, backend.tenants, "acme")
+	success, message, _ = tenants.GetTenant("acme")
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrTenantNotFound.Error(), message)
+
+	success, message = tenants.DeleteTenant("acme")
+	assert.False(t, success)
+	assert.Equal(t, domain.ErrTenantNotFound.Error(), message)
+}
+
+func mustSucceed(success bool, _ string) bool {
+	return success
+}
@@ -0,0 +1,180 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+// DefaultTrashRetention is how long deleted contacts stay in the trash before
+// the janitor purges them.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// Soft-delete markers stored alongside the contact fields.
+const (
+	deletedAtField = "_deleted_at"
+	deletedByField = "_deleted_by"
+)
+
+type Option func(*PhonebookService)
+
+// WithTrashRetention sets how long deleted contacts are kept in the trash.
+func WithTrashRetention(retention time.Duration) Option {
+	return func(s *PhonebookService) {
+		s.trashRetention = retention
+	}
+}
+
+// WithClock replaces the clock used to timestamp deletions.
+func WithClock(now func() time.Time) Option {
+	return func(s *PhonebookService) {
+		s.now = now
+	}
+}
+
+// RestoreContact moves a contact out of the trash.
+func (s *PhonebookService) RestoreContact(id string) (bool, string) {
+	success, message, data := s.db.Read(id)
+	if err := s.authorizeContact(id, domain.RoleEditor); err != nil {
+		return false, err.Error()
+	}
+	if !success {
+		return false, message
+	}
+	if !isTrashedData(data) {
+		return false, domain.ErrContactNotInTrash.Error()
+	}
+
+	delete(data, deletedAtField)
+	delete(data, deletedByField)
+
+	return s.db.Update(id, data)
+}
+
+// ListTrash returns the trashed contacts whose id starts with prefix.
+func (s *PhonebookService) ListTrash(prefix string) (bool, string, []domain.TrashedContact) {
+	if err := s.authorize(domain.RoleEditor); err != nil {
+		return false, err.Error(), nil
+	}
+	success, message, ids := s.db.List(prefix)
+	if !success {
+		return false, message, nil
+	}
+
+	trashed := make([]domain.TrashedContact, 0)
+	for _, id := range ids {
+		success, message, data := s.db.Read(id)
+		if !success {
+			return false, message, nil
+		}
+		if !isTrashedData(data) {
+			continue
+		}
+		trashed = append(trashed, trashedFromData(id, data))
+	}
+
+	return true, "", trashed
+}
+
+// PurgeTrash permanently removes every trashed contact older than the retention
+// period and returns how many were removed.
+func (s *PhonebookService) PurgeTrash() (bool, string, int) {
+	if err := s.authorize(domain.RoleAdmin); err != nil {
+		return false, err.Error(), 0
+	}
+	success, message, trashed := s.ListTrash("")
+	if !success {
+		return false, message, 0
+	}
+
+	cutoff := s.now().Add(-s.trashRetention)
+	expired := make([]string, 0)
+	for _, contact := range trashed {
+		// Without a valid deletion time the contact's age is unknown, so it
+		// is kept rather than purged.
+		if contact.DeletedAt.IsZero() {
+			log.WithField("id", contact.ID).Printf("Skipping trashed contact with an invalid deletion time")
+			continue
+		}
+		if !contact.DeletedAt.After(cutoff) {
+			expired = append(expired, contact.ID)
+		}
+	}
+	if len(expired) == 0 {
+		return true, "", 0
+	}
+
+	purged := 0
+	message = ""
+	for _, result := range s.batchDelete(expired) {
+		if result.Success {
+			purged++
+		} else if message == "" {
+			message = result.Message
+		}
+	}
+
+	return message == "", message, purged
+}
+
+// StartTrashJanitor purges expired trash every interval until ctx is cancelled.
+func (s *PhonebookService) StartTrashJanitor(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.purgeAllTrash()
+			}
+		}
+	}()
+}
+
+// purgeAllTrash purges the trash of every tenant, logging the outcome.
+func (s *PhonebookService) purgeAllTrash() {
+	success, message := s.system().forEachTenant(func(tenant string, s *PhonebookService) {
+		success, message, purged := s.PurgeTrash()
+		if !success {
+			log.WithField("tenant", tenant).Printf("Error purging trash: %v", message)
+		} else if purged > 0 {
+			log.WithField("tenant", tenant).Printf("Purged %d contacts from the trash", purged)
+		}
+	})
+	if !success {
+		log.Printf("Error listing tenants: %v", message)
+	}
+}
+
+func (s *PhonebookService) isTrashed(id string) bool {
+	success, _, data := s.db.Read(id)
+	return success && isTrashedData(data)
+}
+
+func isTrashedData(data map[string]interface{}) bool {
+	_, ok := data[deletedAtField]
+	return ok
+}
+
+// trashedFromData leaves DeletedAt zero when the stored deletion time cannot
+// be parsed.
+func trashedFromData(id string, data map[string]interface{}) domain.TrashedContact {
+	deletedAt, _ := time.Parse(time.RFC3339Nano, stringField(data, deletedAtField))
+	return domain.TrashedContact{
+		ID:        id,
+		Contact:   contactFromData(data),
+		DeletedAt: deletedAt,
+		DeletedBy: stringField(data, deletedByField),
+	}
+}
+
+func stringField(data map[string]interface{}, key string) string {
+	value, _ := data[key].(string)
+	return value
+}
@@ -0,0 +1,163 @@
+package application
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+var trashTestContact = domain.Contact{
+	Name:    "John Doe",
+	Phone:   "123-456-7890",
+	Email:   "john@example.com",
+	Address: "123 Main St",
+}
+
+func TestPhonebookService_DeleteContact(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewPhonebookService(newMapDatabase(), WithClock(func() time.Time { return now }))
+
+	if success, msg := s.AddContact("contacts/john", trashTestContact); !success {
+		t.Fatalf("Failed to add contact: %s", msg)
+	}
+
+	if success, msg := s.DeleteContactAs("contacts/john", "alice"); !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+
+	// Deleted contacts are hidden from Get and List
+	success, msg, _ := s.GetContact("contacts/john")
+	if success {
+		t.Error("Expected trashed contact to be hidden")
+	}
+	if msg != domain.ErrContactNotFound.Error() {
+		t.Errorf("Expected error message %q but got %q", domain.ErrContactNotFound.Error(), msg)
+	}
+	if _, _, phonebook := s.ListContacts(""); len(phonebook.Contacts) != 0 {
+		t.Errorf("Expected no listed contacts but got %d", len(phonebook.Contacts))
+	}
+
+	// ...but show up in the trash with time and actor
+	_, _, trashed := s.ListTrash("")
+	if len(trashed) != 1 {
+		t.Fatalf("Expected 1 trashed contact but got %d", len(trashed))
+	}
+	if trashed[0].ID != "contacts/john" || trashed[0].DeletedBy != "alice" || !trashed[0].DeletedAt.Equal(now) {
+		t.Errorf("Unexpected trashed contact %+v", trashed[0])
+	}
+	if !reflect.DeepEqual(trashed[0].Contact, trashTestContact) {
+		t.Errorf("Expected contact %+v but got %+v", trashTestContact, trashed[0].Contact)
+	}
+
+	// Deleting twice, updating or re-adding a trashed contact fails
+	if success, _ := s.DeleteContact("contacts/john"); success {
+		t.Error("Expected second delete to fail")
+	}
+	if success, _ := s.UpdateContact("contacts/john", trashTestContact); success {
+		t.Error("Expected update of trashed contact to fail")
+	}
+	if success, msg := s.AddContact("contacts/john", trashTestContact); success || msg != domain.ErrContactInTrash.Error() {
+		t.Errorf("Expected %q but got success=%v msg=%q", domain.ErrContactInTrash.Error(), success, msg)
+	}
+}
+
+func TestPhonebookService_RestoreContact(t *testing.T) {
+	s := NewPhonebookService(newMapDatabase())
+	s.AddContact("contacts/john", trashTestContact)
+	s.DeleteContact("contacts/john")
+
+	if success, msg := s.RestoreContact("contacts/john"); !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+
+	success, msg, contact := s.GetContact("contacts/john")
+	if !success {
+		t.Fatalf("Expected restored contact but got error: %s", msg)
+	}
+	if !reflect.DeepEqual(contact, trashTestContact) {
+		t.Errorf("Expected contact %+v but got %+v", trashTestContact, contact)
+	}
+
+	if success, msg := s.RestoreContact("contacts/john"); success || msg != domain.ErrContactNotInTrash.Error() {
+		t.Errorf("Expected %q but got success=%v msg=%q", domain.ErrContactNotInTrash.Error(), success, msg)
+	}
+}
+
+func TestPhonebookService_PurgeTrash(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	db := newMapDatabase()
+	s := NewPhonebookService(db,
+		WithTrashRetention(24*time.Hour),
+		WithClock(func() time.Time { return now }),
+	)
+
+	s.AddContact("contacts/old", trashTestContact)
+	s.AddContact("contacts/new", trashTestContact)
+	s.AddContact("contacts/live", trashTestContact)
+	s.DeleteContact("contacts/old")
+	now = now.Add(12 * time.Hour)
+	s.DeleteContact("contacts/new")
+	now = now.Add(13 * time.Hour)
+
+	success, msg, purged := s.PurgeTrash()
+	if !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+	if purged != 1 {
+		t.Errorf("Expected 1 purged contact but got %d", purged)
+	}
+	if success, _, _ := db.Read("contacts/old"); success {
+		t.Error("Expected expired contact to be purged")
+	}
+	if success, _, _ := db.Read("contacts/new"); !success {
+		t.Error("Expected recent trashed contact to be kept")
+	}
+	if success, _, _ := s.GetContact("contacts/live"); !success {
+		t.Error("Expected live contact to be kept")
+	}
+}
+
+func TestPhonebookService_PurgeTrashKeepsInvalidDeletionTimes(t *testing.T) {
+	db := newMapDatabase()
+	s := NewPhonebookService(db, WithTrashRetention(0))
+
+	s.AddContact("contacts/broken", trashTestContact)
+	s.DeleteContact("contacts/broken")
+	_, _, data := db.Read("contacts/broken")
+	data[deletedAtField] = "yesterday"
+	db.Update("contacts/broken", data)
+
+	success, msg, purged := s.PurgeTrash()
+	if !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+	if purged != 0 {
+		t.Errorf("Expected no purged contacts but got %d", purged)
+	}
+	if success, _, _ := db.Read("contacts/broken"); !success {
+		t.Error("Expected contact with an invalid deletion time to be kept")
+	}
+}
+
+func TestPhonebookService_StartTrashJanitor(t *testing.T) {
+	db := newMapDatabase()
+	s := NewPhonebookService(db, WithTrashRetention(0))
+	s.AddContact("contacts/john", trashTestContact)
+	s.DeleteContact("contacts/john")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.StartTrashJanitor(ctx, 10*time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, _, trashed := s.ListTrash(""); len(trashed) == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("Expected janitor to purge the trash")
+}
@@ -0,0 +1,37 @@
+package application
+
+import (
+	"errors"
+
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// withTx runs fn against a copy of the service bound to a transaction, so all
+// of fn's writes succeed or fail together. Databases without transactions run
+// fn directly.
+func (s *PhonebookService) withTx(fn func(tx *PhonebookService) error) (bool, string) {
+	run := func(db ports.Database) error {
+		tx := *s
+		tx.db = db
+		return fn(&tx)
+	}
+
+	var err error
+	if transactor, ok := s.db.(ports.Transactor); ok {
+		err = transactor.WithTx(run)
+	} else {
+		err = run(s.db)
+	}
+	if err != nil {
+		return false, err.Error()
+	}
+	return true, ""
+}
+
+// resultError turns a (bool, string) result into an error for use inside withTx.
+func resultError(success bool, message string) error {
+	if success {
+		return nil
+	}
+	return errors.New(message)
+}
@@ -0,0 +1,65 @@
+package application
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// MockTxDatabase adds ports.Transactor to a MockGroupDatabase and counts the
+// transactions it runs. Writes are not rolled back.
+type MockTxDatabase struct {
+	*MockGroupDatabase
+	txCalls int
+}
+
+func (m *MockTxDatabase) WithTx(fn func(tx ports.Database) error) error {
+	m.txCalls++
+	return fn(m.MockGroupDatabase)
+}
+
+func TestPhonebookService_MoveContact(t *testing.T) {
+	db := &MockTxDatabase{MockGroupDatabase: newMapGroupDatabase()}
+	s := NewPhonebookService(db)
+	s.AddContact("contacts/john", trashTestContact)
+	s.CreateGroup("family")
+	s.AddToGroup("family", "contacts/john")
+
+	if success, msg := s.MoveContact("contacts/john", "people/john"); !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+	if db.txCalls != 1 {
+		t.Errorf("Expected the move to run in 1 transaction but got %d", db.txCalls)
+	}
+	if success, _, _ := s.GetContact("contacts/john"); success {
+		t.Error("Expected old id to be gone")
+	}
+	if _, _, contact := s.GetContact("people/john"); !reflect.DeepEqual(contact, trashTestContact) {
+		t.Errorf("Expected contact %+v but got %+v", trashTestContact, contact)
+	}
+	if _, _, groups := s.GroupsOfContact("people/john"); len(groups) != 1 || groups[0] != "family" {
+		t.Errorf("Expected membership to move but got %v", groups)
+	}
+
+	// Moving onto an existing contact fails
+	s.AddContact("contacts/jane", domain.Contact{Name: "Jane Doe", Phone: "555-000-1111"})
+	if success, msg := s.MoveContact("contacts/jane", "people/john"); success || msg != "Location already exists" {
+		t.Errorf("Expected move onto existing contact to fail but got success=%v msg=%q", success, msg)
+	}
+}
+
+func TestPhonebookService_MergeContactsUsesTx(t *testing.T) {
+	db := &MockTxDatabase{MockGroupDatabase: newMapGroupDatabase()}
+	s := NewPhonebookService(db)
+	s.AddContact("contacts/jon", domain.Contact{Name: "Jon Doe", Phone: "123-456-7890"})
+	s.AddContact("contacts/john", domain.Contact{Name: "John Doe", Phone: "123-456-7890"})
+
+	if success, msg, _ := s.MergeContacts([]string{"contacts/jon", "contacts/john"}, domain.MergePreferFirst); !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+	if db.txCalls != 1 {
+		t.Errorf("Expected the merge to run in 1 transaction but got %d", db.txCalls)
+	}
+}
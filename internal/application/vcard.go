@@ -0,0 +1,142 @@
+package application
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/emersion/go-vcard"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+// ExportVCard writes every live contact whose id starts with prefix as a
+// stream of vCards. The contact id is written as the UID.
+func (s *PhonebookService) ExportVCard(prefix string, w io.Writer) (bool, string) {
+	success, message, phonebook := s.ListContacts(prefix)
+	if !success {
+		return false, message
+	}
+
+	ids := make([]string, 0, len(phonebook.Contacts))
+	for id := range phonebook.Contacts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	encoder := vcard.NewEncoder(w)
+	for _, id := range ids {
+		if err := encoder.Encode(CardFromContact(id, phonebook.Contacts[id])); err != nil {
+			return false, err.Error()
+		}
+	}
+	return true, ""
+}
+
+// ImportVCard reads a stream of vCards, using each UID as the contact id.
+// Results are reported against the position of the card in the stream.
+func (s *PhonebookService) ImportVCard(r io.Reader, overwrite bool) (bool, string, []domain.ImportResult) {
+	decoder := vcard.NewDecoder(r)
+	importer := s.NewImporter(overwrite)
+	for line := 1; ; line++ {
+		card, err := decoder.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// The decoder cannot resynchronise after a malformed card
+			if line == 1 {
+				return false, err.Error(), nil
+			}
+			importer.Fail(line, "", err.Error())
+			break
+		}
+		importer.Add(line, strings.TrimSpace(card.Value(vcard.FieldUID)), ContactFromCard(card))
+	}
+	return true, "", importer.Close()
+}
+
+// customCardPrefix names the vCard extension properties holding custom
+// fields: employee_id is written as X-PHONEBOOK-EMPLOYEE-ID.
+const customCardPrefix = "X-PHONEBOOK-"
+
+// CardFromContact renders a contact as a vCard 3.0. The contact id is the UID.
+func CardFromContact(id string, contact domain.Contact) vcard.Card {
+	card := make(vcard.Card)
+	card.SetValue(vcard.FieldVersion, "3.0")
+	card.SetValue(vcard.FieldUID, id)
+	card.SetValue(vcard.FieldFormattedName, contact.Name)
+	card.SetName(nameFromFormatted(contact.Name))
+	card.SetValue(vcard.FieldTelephone, contact.Phone)
+	if contact.Email != "" {
+		card.SetValue(vcard.FieldEmail, contact.Email)
+	}
+	if contact.Address != "" {
+		card.SetAddress(&vcard.Address{StreetAddress: contact.Address})
+	}
+	for name, value := range contact.Custom {
+		if value != "" {
+			card.SetValue(customCardPrefix+strings.ToUpper(strings.ReplaceAll(name, "_", "-")), value)
+		}
+	}
+	return card
+}
+
+// ContactFromCard reads the preferred name, phone, email and address of a
+// vCard, and the custom fields written by CardFromContact.
+func ContactFromCard(card vcard.Card) domain.Contact {
+	contact := domain.Contact{
+		Name:  card.PreferredValue(vcard.FieldFormattedName),
+		Phone: card.PreferredValue(vcard.FieldTelephone),
+		Email: card.PreferredValue(vcard.FieldEmail),
+	}
+	if contact.Name == "" {
+		if name := card.Name(); name != nil {
+			contact.Name = joinNonEmpty(" ", name.GivenName, name.AdditionalName, name.FamilyName)
+		}
+	}
+	if address := card.Address(); address != nil {
+		contact.Address = joinNonEmpty(", ",
+			address.StreetAddress,
+			address.ExtendedAddress,
+			address.Locality,
+			address.Region,
+			address.PostalCode,
+			address.Country,
+		)
+	}
+	for property := range card {
+		name, ok := strings.CutPrefix(strings.ToUpper(property), customCardPrefix)
+		if value := card.PreferredValue(property); ok && value != "" {
+			if contact.Custom == nil {
+				contact.Custom = make(map[string]string)
+			}
+			contact.Custom[strings.ToLower(strings.ReplaceAll(name, "-", "_"))] = value
+		}
+	}
+	return contact
+}
+
+// nameFromFormatted splits "Given Family" into the structured N property,
+// which vCard 3.0 requires.
+func nameFromFormatted(formatted string) *vcard.Name {
+	parts := strings.Fields(formatted)
+	if len(parts) < 2 {
+		return &vcard.Name{GivenName: formatted}
+	}
+	return &vcard.Name{
+		GivenName:  strings.Join(parts[:len(parts)-1], " "),
+		FamilyName: parts[len(parts)-1],
+	}
+}
+
+func joinNonEmpty(sep string, values ...string) string {
+	parts := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,89 @@
+package application
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+)
+
+func TestPhonebookService_VCardRoundTrip(t *testing.T) {
+	s := NewPhonebookService(newMapDatabase())
+	s.AddContact("contacts/john", trashTestContact)
+	s.AddContact("contacts/jane", domain.Contact{Name: "Jane", Phone: "555-000-1111"})
+
+	var buf bytes.Buffer
+	if success, msg := s.ExportVCard("", &buf); !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+
+	imported := NewPhonebookService(newMapDatabase())
+	success, msg, results := imported.ImportVCard(&buf, false)
+	if !success || len(results) != 2 {
+		t.Fatalf("Expected 2 results but got %+v (%s)", results, msg)
+	}
+	for _, id := range []string{"contacts/jane", "contacts/john"} {
+		_, _, want := s.GetContact(id)
+		if _, _, got := imported.GetContact(id); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %+v but got %+v", id, want, got)
+		}
+	}
+}
+
+func TestPhonebookService_ImportVCard(t *testing.T) {
+	card := func(lines ...string) string {
+		return "BEGIN:VCARD\r\nVERSION:3.0\r\n" + strings.Join(lines, "\r\n") + "\r\nEND:VCARD\r\n"
+	}
+
+	tests := []struct {
+		name        string
+		input       string
+		expectedErr string
+		messages    []string
+	}{
+		{
+			name:     "structured name",
+			input:    card("UID:contacts/john", "N:Doe;John;;;", "TEL:123"),
+			messages: []string{""},
+		},
+		{
+			name:     "missing uid and phone",
+			input:    card("FN:Nobody", "TEL:1") + card("UID:contacts/jane", "FN:Jane"),
+			messages: []string{domain.ErrMissingContactID.Error(), domain.ErrInvalidContactNumber.Error()},
+		},
+		{
+			name:        "not a vcard",
+			input:       "FN:John Doe\r\n",
+			expectedErr: "vcard",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewPhonebookService(newMapDatabase())
+			success, msg, results := s.ImportVCard(strings.NewReader(tt.input), false)
+			if tt.expectedErr != "" {
+				if success || !strings.Contains(msg, tt.expectedErr) {
+					t.Errorf("Expected error containing %q but got success=%v msg=%q", tt.expectedErr, success, msg)
+				}
+				return
+			}
+			if len(results) != len(tt.messages) {
+				t.Fatalf("Expected %d results but got %+v", len(tt.messages), results)
+			}
+			for i, result := range results {
+				if result.Message != tt.messages[i] || result.Success != (tt.messages[i] == "") {
+					t.Errorf("Card %d: expected message %q but got %+v", i+1, tt.messages[i], result)
+				}
+			}
+		})
+	}
+
+	s := NewPhonebookService(newMapDatabase())
+	s.ImportVCard(strings.NewReader(card("UID:contacts/john", "N:Doe;John;;;", "TEL:123")), false)
+	if _, _, john := s.GetContact("contacts/john"); john.Name != "John Doe" {
+		t.Errorf("Expected the name to be built from N but got %q", john.Name)
+	}
+}
@@ -0,0 +1,69 @@
+package application
+
+import (
+	"context"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// WatchContacts streams contact changes under prefix, starting after cursor
+// (or now, if cursor is empty). Moving a contact to the trash or merging it
+// into another one is reported as a delete; restoring it as an update. Actors
+// only see changes to contacts they may read.
+func (s *PhonebookService) WatchContacts(ctx context.Context, prefix, cursor string) (bool, string, <-chan domain.ContactEvent) {
+	if err := s.authorize(domain.RoleViewer); err != nil && (err == domain.ErrUnauthenticated || len(s.actor.Groups) == 0) {
+		return false, err.Error(), nil
+	}
+
+	watcher, ok := s.db.(ports.Watcher)
+	if !ok {
+		return false, domain.ErrWatchNotSupported.Error(), nil
+	}
+
+	success, message, changes := watcher.Watch(ctx, prefix, cursor)
+	if !success {
+		return false, message, nil
+	}
+
+	out := make(chan domain.ContactEvent)
+	go func() {
+		defer close(out)
+		for change := range changes {
+			if isReserved(change.Location) {
+				continue
+			}
+			event := contactEventFromChange(change)
+			if !s.canRead(event.ID) {
+				continue
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return true, "", out
+}
+
+func contactEventFromChange(change ports.ChangeEvent) domain.ContactEvent {
+	event := domain.ContactEvent{
+		Cursor: change.Cursor,
+		ID:     change.Location,
+		Time:   change.Time,
+	}
+
+	switch {
+	case change.Op == ports.ChangeDelete || isTrashedData(change.Data) || isRedirectData(change.Data):
+		event.Type = domain.ContactDeleted
+	case change.Op == ports.ChangeCreate:
+		event.Type = domain.ContactCreated
+		event.Contact = contactFromData(change.Data)
+	default:
+		event.Type = domain.ContactUpdated
+		event.Contact = contactFromData(change.Data)
+	}
+	return event
+}
@@ -0,0 +1,81 @@
+package application
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// MockWatchDatabase adds ports.Watcher to a MockDatabase, replaying changes.
+type MockWatchDatabase struct {
+	*MockDatabase
+	changes []ports.ChangeEvent
+}
+
+func (m *MockWatchDatabase) Watch(ctx context.Context, prefix, fromCursor string) (bool, string, <-chan ports.ChangeEvent) {
+	events := make(chan ports.ChangeEvent, len(m.changes))
+	for _, change := range m.changes {
+		events <- change
+	}
+	close(events)
+	return true, "", events
+}
+
+func TestPhonebookService_WatchContacts(t *testing.T) {
+	contactData := contactToData(trashTestContact)
+	trashedData := contactToData(trashTestContact)
+	trashedData[deletedAtField] = time.Now().Format(time.RFC3339Nano)
+
+	db := &MockWatchDatabase{
+		MockDatabase: newMapDatabase(),
+		changes: []ports.ChangeEvent{
+			{Cursor: "1", Op: ports.ChangeCreate, Location: "contacts/john", Data: contactData},
+			{Cursor: "2", Op: ports.ChangeUpdate, Location: "contacts/john", Data: contactData},
+			{Cursor: "3", Op: ports.ChangeUpdate, Location: "contacts/john", Data: trashedData},
+			{Cursor: "4", Op: ports.ChangeUpdate, Location: "contacts/jon", Data: map[string]interface{}{redirectField: "contacts/john"}},
+			{Cursor: "5", Op: ports.ChangeDelete, Location: "contacts/john"},
+		},
+	}
+	s := NewPhonebookService(db)
+
+	success, msg, events := s.WatchContacts(context.Background(), "contacts/", "")
+	if !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+
+	expected := []domain.ContactEventType{
+		domain.ContactCreated,
+		domain.ContactUpdated,
+		domain.ContactDeleted,
+		domain.ContactDeleted,
+		domain.ContactDeleted,
+	}
+	i := 0
+	for event := range events {
+		if i >= len(expected) {
+			t.Fatalf("Unexpected extra event %+v", event)
+		}
+		if event.Type != expected[i] {
+			t.Errorf("Event %d: expected %s but got %s", i, expected[i], event.Type)
+		}
+		if event.Type != domain.ContactDeleted && !reflect.DeepEqual(event.Contact, trashTestContact) {
+			t.Errorf("Event %d: expected contact %+v but got %+v", i, trashTestContact, event.Contact)
+		}
+		i++
+	}
+	if i != len(expected) {
+		t.Errorf("Expected %d events but got %d", len(expected), i)
+	}
+}
+
+func TestPhonebookService_WatchContactsNotSupported(t *testing.T) {
+	s := NewPhonebookService(newMapDatabase())
+	success, msg, _ := s.WatchContacts(context.Background(), "", "")
+	if success || msg != domain.ErrWatchNotSupported.Error() {
+		t.Errorf("Expected %q but got success=%v msg=%q", domain.ErrWatchNotSupported.Error(), success, msg)
+	}
+}
@@ -0,0 +1,618 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// Retry defaults for webhook deliveries. The wait doubles after every failed
+// attempt, up to the maximum.
+const (
+	DefaultWebhookMaxAttempts = 8
+	DefaultWebhookBackoff     = 30 * time.Second
+	DefaultWebhookMaxBackoff  = time.Hour
+)
+
+// Defaults for re-subscribing when the watch behind Start ends. The wait
+// doubles after every failed attempt, up to the maximum.
+const (
+	DefaultWebhookWatchBackoff    = time.Second
+	DefaultWebhookWatchMaxBackoff = time.Minute
+)
+
+// Headers sent with every delivery. The signature is an HMAC-SHA256 of the
+// timestamp and body; see SignWebhookPayload.
+const (
+	WebhookEventHeader     = "X-Phonebook-Event"
+	WebhookDeliveryHeader  = "X-Phonebook-Delivery"
+	WebhookTimestampHeader = "X-Phonebook-Timestamp"
+	WebhookSignatureHeader = "X-Phonebook-Signature"
+)
+
+// Webhooks, deliveries and the watch cursor are kept in the dispatcher's store
+// as JSON, so they survive restarts on any adapter.
+const (
+	webhookPrefix         = "webhooks/"
+	deliveryPrefix        = "deliveries/"
+	webhookCursorLocation = "webhook-state/cursor"
+	recordField           = "record"
+)
+
+// WebhookDispatcher queues contact events for registered webhooks and delivers
+// them with retries.
+type WebhookDispatcher struct {
+	// phonebook is watched without authorization. Management calls are
+	// checked against caller, service bound by As.
+	phonebook   *PhonebookService
+	service     *PhonebookService
+	caller      *PhonebookService
+	store       ports.Database
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	now         func() time.Time
+
+	watchBackoff    time.Duration
+	watchMaxBackoff time.Duration
+
+	// mu keeps concurrent ProcessDue calls from delivering the same event
+	// twice. It is shared by the copies returned by As.
+	mu *sync.Mutex
+}
+
+type WebhookOption func(*WebhookDispatcher)
+
+// WithWebhookClient replaces the HTTP client used for deliveries.
+func WithWebhookClient(client *http.Client) WebhookOption {
+	return func(d *WebhookDispatcher) {
+		d.client = client
+	}
+}
+
+// WithWebhookRetries sets how many failed attempts dead-letter a delivery and
+// the backoff between attempts.
+func WithWebhookRetries(maxAttempts int, backoff, maxBackoff time.Duration) WebhookOption {
+	return func(d *WebhookDispatcher) {
+		d.maxAttempts = maxAttempts
+		d.backoff = backoff
+		d.maxBackoff = maxBackoff
+	}
+}
+
+// WithWebhookWatchRetry sets the backoff between attempts to re-subscribe
+// when the watch behind Start ends.
+func WithWebhookWatchRetry(backoff, maxBackoff time.Duration) WebhookOption {
+	return func(d *WebhookDispatcher) {
+		d.watchBackoff = backoff
+		d.watchMaxBackoff = maxBackoff
+	}
+}
+
+// WithWebhookClock replaces the clock used to schedule deliveries.
+func WithWebhookClock(now func() time.Time) WebhookOption {
+	return func(d *WebhookDispatcher) {
+		d.now = now
+	}
+}
+
+// NewWebhookDispatcher returns a dispatcher that keeps its webhooks, deliveries
+// and watch cursor in store. The store must be separate from the phonebook's
+// database: its records would otherwise be listed as contacts, and every
+// delivery written would be watched as a contact change.
+func NewWebhookDispatcher(phonebook *PhonebookService, store ports.Database, opts ...WebhookOption) (*WebhookDispatcher, error) {
+	if sameDatabase(phonebook.db, store) {
+		return nil, domain.ErrWebhookStoreShared
+	}
+
+	d := &WebhookDispatcher{
+		phonebook:   phonebook.system(),
+		service:     phonebook,
+		store:       store,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		maxAttempts: DefaultWebhookMaxAttempts,
+		backoff:     DefaultWebhookBackoff,
+		maxBackoff:  DefaultWebhookMaxBackoff,
+		now:         time.Now,
+		mu:          &sync.Mutex{},
+
+		watchBackoff:    DefaultWebhookWatchBackoff,
+		watchMaxBackoff: DefaultWebhookWatchMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d, nil
+}
+
+// As returns a copy of the dispatcher acting for the caller carried by ctx.
+// With authorization enabled on the phonebook, only admins may manage
+// webhooks and deliveries through it.
+func (d *WebhookDispatcher) As(ctx context.Context) *WebhookDispatcher {
+	bound := *d
+	bound.caller = d.service.As(ctx)
+	return &bound
+}
+
+func (d *WebhookDispatcher) authorize() error {
+	if d.caller == nil {
+		return nil
+	}
+	return d.caller.authorize(domain.RoleAdmin)
+}
+
+// sameDatabase reports whether a and b are the same database value.
+func sameDatabase(a, b ports.Database) bool {
+	ta := reflect.TypeOf(a)
+	return ta != nil && ta == reflect.TypeOf(b) && ta.Comparable() && a == b
+}
+
+// RegisterWebhook stores a new webhook and returns it with its id. A secret is
+// generated when none is given. This is the only call that returns the secret.
+func (d *WebhookDispatcher) RegisterWebhook(hook domain.Webhook) (bool, string, domain.Webhook) {
+	if err := d.authorize(); err != nil {
+		return false, err.Error(), domain.Webhook{}
+	}
+	target, err := url.Parse(hook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return false, domain.ErrInvalidWebhookURL.Error(), domain.Webhook{}
+	}
+	for _, event := range hook.Events {
+		switch event {
+		case domain.ContactCreated, domain.ContactUpdated, domain.ContactDeleted:
+		default:
+			return false, domain.ErrInvalidWebhookEvent.Error(), domain.Webhook{}
+		}
+	}
+
+	hook.ID = randomID()
+	if hook.Secret == "" {
+		hook.Secret = randomID() + randomID()
+	}
+	hook.CreatedAt = d.now().UTC()
+
+	if success, message := d.store.Create(webhookPrefix+hook.ID, encodeRecord(hook)); !success {
+		return false, message, domain.Webhook{}
+	}
+	return true, "", hook
+}
+
+// GetWebhook returns a webhook without its secret.
+func (d *WebhookDispatcher) GetWebhook(id string) (bool, string, domain.Webhook) {
+	if err := d.authorize(); err != nil {
+		return false, err.Error(), domain.Webhook{}
+	}
+	success, message, hook := d.webhook(id)
+	hook.Secret = ""
+	return success, message, hook
+}
+
+// DeleteWebhook removes a webhook. Its queued deliveries are dead-lettered
+// when they come up for delivery.
+func (d *WebhookDispatcher) DeleteWebhook(id string) (bool, string) {
+	if err := d.authorize(); err != nil {
+		return false, err.Error()
+	}
+	if success, message, _ := d.webhook(id); !success {
+		return false, message
+	}
+	return d.store.Delete(webhookPrefix + id)
+}
+
+// ListWebhooks returns every webhook without its secret.
+func (d *WebhookDispatcher) ListWebhooks() (bool, string, []domain.Webhook) {
+	if err := d.authorize(); err != nil {
+		return false, err.Error(), nil
+	}
+	success, message, hooks := d.webhooks()
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return success, message, hooks
+}
+
+func (d *WebhookDispatcher) webhook(id string) (bool, string, domain.Webhook) {
+	var hook domain.Webhook
+	if success, message := d.readRecord(webhookPrefix+id, &hook); !success {
+		return false, notFound(message, domain.ErrWebhookNotFound), domain.Webhook{}
+	}
+	return true, "", hook
+}
+
+func (d *WebhookDispatcher) webhooks() (bool, string, []domain.Webhook) {
+	success, message, ids := d.store.List(webhookPrefix)
+	if !success {
+		return false, message, nil
+	}
+
+	hooks := make([]domain.Webhook, 0, len(ids))
+	for _, id := range ids {
+		var hook domain.Webhook
+		if success, message := d.readRecord(id, &hook); !success {
+			return false, message, nil
+		}
+		hooks = append(hooks, hook)
+	}
+	return true, "", hooks
+}
+
+// Enqueue queues the event for every webhook whose filters match it and
+// returns how many deliveries were queued.
+func (d *WebhookDispatcher) Enqueue(event domain.ContactEvent) (bool, string, int) {
+	success, message, hooks := d.webhooks()
+	if !success {
+		return false, message, 0
+	}
+
+	queued := 0
+	for _, hook := range hooks {
+		if !d.matches(hook, event) {
+			continue
+		}
+
+		now := d.now().UTC()
+		delivery := domain.WebhookDelivery{
+			ID:          fmt.Sprintf("%019d-%s", now.UnixNano(), randomID()[:8]),
+			WebhookID:   hook.ID,
+			Event:       event,
+			Status:      domain.DeliveryPending,
+			NextAttempt: now,
+		}
+		if success, message := d.store.Create(deliveryPrefix+delivery.ID, encodeRecord(delivery)); !success {
+			return false, message, queued
+		}
+		queued++
+	}
+	return true, "", queued
+}
+
+// ProcessDue attempts every pending delivery whose next attempt is due and
+// returns how many were attempted.
+func (d *WebhookDispatcher) ProcessDue() (bool, string, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	success, message, deliveries := d.deliveries("", domain.DeliveryPending)
+	if !success {
+		return false, message, 0
+	}
+
+	now := d.now()
+	attempted := 0
+	for _, delivery := range deliveries {
+		if delivery.NextAttempt.After(now) {
+			continue
+		}
+		if success, message := d.attempt(delivery); !success {
+			return false, message, attempted
+		}
+		attempted++
+	}
+	return true, "", attempted
+}
+
+// ListDeliveries returns the delivery history, oldest first. An empty webhook
+// id or status matches every delivery.
+func (d *WebhookDispatcher) ListDeliveries(webhookID string, status domain.DeliveryStatus) (bool, string, []domain.WebhookDelivery) {
+	if err := d.authorize(); err != nil {
+		return false, err.Error(), nil
+	}
+	return d.deliveries(webhookID, status)
+}
+
+func (d *WebhookDispatcher) deliveries(webhookID string, status domain.DeliveryStatus) (bool, string, []domain.WebhookDelivery) {
+	success, message, ids := d.store.List(deliveryPrefix)
+	if !success {
+		return false, message, nil
+	}
+
+	deliveries := make([]domain.WebhookDelivery, 0)
+	for _, id := range ids {
+		var delivery domain.WebhookDelivery
+		if success, message := d.readRecord(id, &delivery); !success {
+			return false, message, nil
+		}
+		if webhookID != "" && delivery.WebhookID != webhookID {
+			continue
+		}
+		if status != "" && delivery.Status != status {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return true, "", deliveries
+}
+
+// RedeliverWebhook puts a dead-lettered delivery back in the queue with a
+// fresh set of attempts.
+func (d *WebhookDispatcher) RedeliverWebhook(deliveryID string) (bool, string) {
+	if err := d.authorize(); err != nil {
+		return false, err.Error()
+	}
+	var delivery domain.WebhookDelivery
+	if success, message := d.readRecord(deliveryPrefix+deliveryID, &delivery); !success {
+		return false, notFound(message, domain.ErrDeliveryNotFound)
+	}
+	if delivery.Status != domain.DeliveryDead {
+		return false, domain.ErrDeliveryNotDead.Error()
+	}
+
+	delivery.Status = domain.DeliveryPending
+	delivery.Failures = 0
+	delivery.NextAttempt = d.now().UTC()
+	return d.store.Update(deliveryPrefix+delivery.ID, encodeRecord(delivery))
+}
+
+// Start watches the phonebook, queueing every change, and delivers due events
+// every interval until ctx is cancelled. The watch resumes where the last run
+// stopped. When it ends, it is re-subscribed from the saved cursor with
+// backoff; if the cursor has expired, the changes since are lost, which is
+// logged as an error, and the watch restarts from the current position.
+func (d *WebhookDispatcher) Start(ctx context.Context, interval time.Duration) (bool, string) {
+	cursor := ""
+	if success, _, data := d.store.Read(webhookCursorLocation); success {
+		cursor = stringField(data, "cursor")
+	}
+
+	success, message, events := d.phonebook.WatchContacts(ctx, "", cursor)
+	if !success {
+		return false, message
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		// resubscribe fires while the watch is down
+		var resubscribe <-chan time.Time
+		wait := d.watchBackoff
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					if ctx.Err() != nil {
+						return
+					}
+					log.Warnf("Webhook watch stopped, re-subscribing in %v", wait)
+					events = nil
+					resubscribe = time.After(wait)
+					continue
+				}
+				if success, message, _ := d.Enqueue(event); !success {
+					log.Printf("Error queueing webhook event: %v", message)
+					continue
+				}
+				if success, message := d.saveCursor(event.Cursor); !success {
+					log.Printf("Error saving webhook cursor: %v", message)
+				}
+				cursor = event.Cursor
+			case <-resubscribe:
+				success, message, watched := d.phonebook.WatchContacts(ctx, "", cursor)
+				if !success && message == ports.CursorExpired && cursor != "" {
+					log.Errorf("Webhook watch cursor %s expired, changes since then were not queued; resuming from the current position", cursor)
+					cursor = ""
+					if success, message := d.saveCursor(cursor); !success {
+						log.Printf("Error saving webhook cursor: %v", message)
+					}
+					success, message, watched = d.phonebook.WatchContacts(ctx, "", cursor)
+				}
+				if !success {
+					wait = min(wait*2, d.watchMaxBackoff)
+					log.Printf("Error re-subscribing webhook watch, retrying in %v: %v", wait, message)
+					resubscribe = time.After(wait)
+					continue
+				}
+				events, resubscribe = watched, nil
+				wait = d.watchBackoff
+			case <-ticker.C:
+				if success, message, _ := d.ProcessDue(); !success {
+					log.Printf("Error delivering webhooks: %v", message)
+				}
+			}
+		}
+	}()
+
+	return true, ""
+}
+
+// SignWebhookPayload returns the signature header value for a delivery.
+// Receivers recompute it with the webhook secret to authenticate the request.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature reports whether signature matches the payload.
+func VerifyWebhookSignature(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhookPayload(secret, timestamp, body)), []byte(signature))
+}
+
+type webhookPayload struct {
+	DeliveryID string              `json:"delivery_id"`
+	WebhookID  string              `json:"webhook_id"`
+	Event      domain.ContactEvent `json:"event"`
+}
+
+// attempt makes one delivery attempt and records its outcome.
+func (d *WebhookDispatcher) attempt(delivery domain.WebhookDelivery) (bool, string) {
+	now := d.now().UTC()
+	attempt := domain.DeliveryAttempt{Time: now}
+
+	success, message, hook := d.webhook(delivery.WebhookID)
+	switch {
+	case !success && message == domain.ErrWebhookNotFound.Error():
+		// Nothing left to deliver to
+		attempt.Error = message
+		delivery.Failures = d.maxAttempts
+	case !success:
+		return false, message
+	default:
+		attempt.StatusCode, attempt.Error = d.post(hook, delivery, now)
+	}
+
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	if attempt.Error == "" {
+		delivery.Status = domain.DeliverySucceeded
+	} else {
+		delivery.Failures++
+		if delivery.Failures >= d.maxAttempts {
+			delivery.Status = domain.DeliveryDead
+		} else {
+			delivery.NextAttempt = now.Add(d.backoffFor(delivery.Failures))
+		}
+	}
+
+	return d.store.Update(deliveryPrefix+delivery.ID, encodeRecord(delivery))
+}
+
+func (d *WebhookDispatcher) post(hook domain.Webhook, delivery domain.WebhookDelivery, now time.Time) (int, string) {
+	body, err := json.Marshal(webhookPayload{
+		DeliveryID: delivery.ID,
+		WebhookID:  hook.ID,
+		Event:      delivery.Event,
+	})
+	if err != nil {
+		return 0, err.Error()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err.Error()
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, string(delivery.Event.Type))
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(hook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Sprintf("Unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, ""
+}
+
+func (d *WebhookDispatcher) backoffFor(failures int) time.Duration {
+	wait := d.backoff
+	for i := 1; i < failures && wait < d.maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.maxBackoff {
+		wait = d.maxBackoff
+	}
+	return wait
+}
+
+// matches reports whether the webhook's filters accept the event. Group
+// filters only match contacts whose membership can still be looked up.
+func (d *WebhookDispatcher) matches(hook domain.Webhook, event domain.ContactEvent) bool {
+	if len(hook.Events) > 0 {
+		found := false
+		for _, eventType := range hook.Events {
+			if eventType == event.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if hook.Group == "" {
+		return true
+	}
+	groups, ok := d.phonebook.groupStore()
+	if !ok {
+		return false
+	}
+	success, _, names := groups.GroupsOf(event.ID)
+	if !success {
+		return false
+	}
+	for _, name := range names {
+		if name == hook.Group {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *WebhookDispatcher) saveCursor(cursor string) (bool, string) {
+	data := map[string]interface{}{"cursor": cursor}
+	if success, _ := d.store.Update(webhookCursorLocation, data); success {
+		return true, ""
+	}
+	return d.store.Create(webhookCursorLocation, data)
+}
+
+func (d *WebhookDispatcher) readRecord(location string, v interface{}) (bool, string) {
+	return readRecord(d.store, location, v)
+}
+
+// readRecord decodes a record written by encodeRecord.
+func readRecord(store ports.Database, location string, v interface{}) (bool, string) {
+	success, message, data := store.Read(location)
+	if !success {
+		return false, message
+	}
+	if err := json.Unmarshal([]byte(stringField(data, recordField)), v); err != nil {
+		return false, fmt.Sprintf("Error decoding %s: %v", location, err)
+	}
+	return true, ""
+}
+
+func encodeRecord(v interface{}) map[string]interface{} {
+	record, _ := json.Marshal(v)
+	return map[string]interface{}{recordField: string(record)}
+}
+
+// notFound replaces the adapter's missing-record message with err.
+func notFound(message string, err error) string {
+	if isMissing(message) {
+		return err.Error()
+	}
+	return message
+}
+
+// isMissing reports whether message is an adapter's missing-record message.
+func isMissing(message string) bool {
+	return message == "Location does not exist" || message == "File does not exist"
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
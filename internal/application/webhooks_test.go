@@ -0,0 +1,428 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Businge931/practice-interfaces/internal/domain"
+	"github.com/Businge931/practice-interfaces/internal/ports"
+)
+
+// webhookReceiver records the requests sent to it and answers with status.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	w.WriteHeader(r.status)
+}
+
+func (r *webhookReceiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// setupWebhookTest returns a dispatcher for s that keeps its records in store.
+func setupWebhookTest(t *testing.T, s *PhonebookService, store ports.Database, opts ...WebhookOption) *WebhookDispatcher {
+	t.Helper()
+	d, err := NewWebhookDispatcher(s, store, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create the dispatcher: %v", err)
+	}
+	return d
+}
+
+func TestNewWebhookDispatcher_SharedStore(t *testing.T) {
+	db := newMapDatabase()
+	if _, err := NewWebhookDispatcher(NewPhonebookService(db), db); err != domain.ErrWebhookStoreShared {
+		t.Errorf("Expected %v but got %v", domain.ErrWebhookStoreShared, err)
+	}
+}
+
+func TestWebhookDispatcher_RegisterWebhook(t *testing.T) {
+	d := setupWebhookTest(t, NewPhonebookService(newMapDatabase()), newMapDatabase())
+
+	tests := []struct {
+		name        string
+		hook        domain.Webhook
+		expectedErr string
+	}{
+		{"valid", domain.Webhook{URL: "https://example.com/hook"}, ""},
+		{"filtered", domain.Webhook{URL: "http://example.com/hook", Events: []domain.ContactEventType{domain.ContactDeleted}}, ""},
+		{"relative url", domain.Webhook{URL: "/hook"}, domain.ErrInvalidWebhookURL.Error()},
+		{"bad scheme", domain.Webhook{URL: "ftp://example.com"}, domain.ErrInvalidWebhookURL.Error()},
+		{"bad event", domain.Webhook{URL: "https://example.com", Events: []domain.ContactEventType{"renamed"}}, domain.ErrInvalidWebhookEvent.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			success, msg, hook := d.RegisterWebhook(tt.hook)
+			if tt.expectedErr != "" {
+				if success || msg != tt.expectedErr {
+					t.Errorf("Expected error %q but got success=%v msg=%q", tt.expectedErr, success, msg)
+				}
+				return
+			}
+			if !success {
+				t.Fatalf("Expected success but got error: %s", msg)
+			}
+			if hook.ID == "" || hook.Secret == "" {
+				t.Errorf("Expected generated id and secret but got %+v", hook)
+			}
+			if _, _, stored := d.GetWebhook(hook.ID); stored.URL != tt.hook.URL || stored.Secret != "" {
+				t.Errorf("Expected stored webhook %+v without its secret but got %+v", hook, stored)
+			}
+		})
+	}
+
+	_, _, hooks := d.ListWebhooks()
+	if len(hooks) != 2 {
+		t.Fatalf("Expected 2 webhooks but got %d", len(hooks))
+	}
+	for _, hook := range hooks {
+		if hook.Secret != "" {
+			t.Errorf("Expected webhooks to be listed without their secret but got %+v", hook)
+		}
+	}
+	if success, msg := d.DeleteWebhook(hooks[0].ID); !success {
+		t.Errorf("Expected success but got error: %s", msg)
+	}
+	if success, msg := d.DeleteWebhook(hooks[0].ID); success || msg != domain.ErrWebhookNotFound.Error() {
+		t.Errorf("Expected %q but got success=%v msg=%q", domain.ErrWebhookNotFound.Error(), success, msg)
+	}
+}
+
+func TestWebhookDispatcher_Authorization(t *testing.T) {
+	s := NewPhonebookService(newMapDatabase(), WithAuthorization())
+	d := setupWebhookTest(t, s, newMapDatabase())
+
+	tests := []struct {
+		name        string
+		actor       *domain.Actor
+		expectedErr error
+	}{
+		{"anonymous", nil, domain.ErrUnauthenticated},
+		{"editor", &domain.Actor{Name: "ed", Role: domain.RoleEditor}, domain.ErrPermissionDenied},
+		{"admin", &domain.Actor{Name: "root", Role: domain.RoleAdmin}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.actor != nil {
+				ctx = ContextWithActor(ctx, *tt.actor)
+			}
+			bound := d.As(ctx)
+
+			success, msg, _ := bound.RegisterWebhook(domain.Webhook{URL: "https://example.com/hook"})
+			if tt.expectedErr != nil {
+				if success || msg != tt.expectedErr.Error() {
+					t.Errorf("Expected %q but got success=%v msg=%q", tt.expectedErr.Error(), success, msg)
+				}
+				if success, msg, _ := bound.ListWebhooks(); success || msg != tt.expectedErr.Error() {
+					t.Errorf("Expected %q listing but got success=%v msg=%q", tt.expectedErr.Error(), success, msg)
+				}
+				return
+			}
+			if !success {
+				t.Fatalf("Expected success but got error: %s", msg)
+			}
+		})
+	}
+}
+
+func TestWebhookDispatcher_Deliver(t *testing.T) {
+	receiver := &webhookReceiver{status: http.StatusOK}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	d := setupWebhookTest(t, NewPhonebookService(newMapDatabase()), newMapDatabase())
+	_, _, hook := d.RegisterWebhook(domain.Webhook{URL: server.URL, Secret: "s3cret"})
+
+	event := domain.ContactEvent{Cursor: "1", Type: domain.ContactCreated, ID: "contacts/john", Contact: trashTestContact}
+	if success, msg, queued := d.Enqueue(event); !success || queued != 1 {
+		t.Fatalf("Expected 1 queued delivery but got %d (%s)", queued, msg)
+	}
+	if success, msg, attempted := d.ProcessDue(); !success || attempted != 1 {
+		t.Fatalf("Expected 1 attempted delivery but got %d (%s)", attempted, msg)
+	}
+
+	if receiver.count() != 1 {
+		t.Fatalf("Expected 1 request but got %d", receiver.count())
+	}
+	req, body := receiver.requests[0], receiver.bodies[0]
+	if req.Header.Get(WebhookEventHeader) != string(domain.ContactCreated) {
+		t.Errorf("Expected event header %q but got %q", domain.ContactCreated, req.Header.Get(WebhookEventHeader))
+	}
+	if !VerifyWebhookSignature("s3cret", req.Header.Get(WebhookTimestampHeader), body, req.Header.Get(WebhookSignatureHeader)) {
+		t.Error("Expected a valid signature")
+	}
+	if VerifyWebhookSignature("wrong", req.Header.Get(WebhookTimestampHeader), body, req.Header.Get(WebhookSignatureHeader)) {
+		t.Error("Expected signature check with the wrong secret to fail")
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if payload.WebhookID != hook.ID || !reflect.DeepEqual(payload.Event.Contact, trashTestContact) {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+
+	_, _, deliveries := d.ListDeliveries(hook.ID, "")
+	if len(deliveries) != 1 || deliveries[0].Status != domain.DeliverySucceeded || len(deliveries[0].Attempts) != 1 {
+		t.Errorf("Expected 1 succeeded delivery but got %+v", deliveries)
+	}
+
+	// Delivered events are not sent again
+	d.ProcessDue()
+	if receiver.count() != 1 {
+		t.Errorf("Expected no redelivery but got %d requests", receiver.count())
+	}
+}
+
+func TestWebhookDispatcher_RetryAndDeadLetter(t *testing.T) {
+	receiver := &webhookReceiver{status: http.StatusInternalServerError}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	d := setupWebhookTest(t, NewPhonebookService(newMapDatabase()), newMapDatabase(),
+		WithWebhookRetries(3, time.Minute, 90*time.Second),
+		WithWebhookClock(func() time.Time { return now }),
+	)
+	d.RegisterWebhook(domain.Webhook{URL: server.URL})
+	d.Enqueue(domain.ContactEvent{Type: domain.ContactDeleted, ID: "contacts/john"})
+
+	d.ProcessDue()
+	_, _, deliveries := d.ListDeliveries("", domain.DeliveryPending)
+	if len(deliveries) != 1 || !deliveries[0].NextAttempt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("Expected a retry in 1m but got %+v", deliveries)
+	}
+	if attempt := deliveries[0].Attempts[0]; attempt.StatusCode != http.StatusInternalServerError || attempt.Error == "" {
+		t.Errorf("Expected the failure to be recorded but got %+v", attempt)
+	}
+
+	// Not due yet
+	now = now.Add(30 * time.Second)
+	if _, _, attempted := d.ProcessDue(); attempted != 0 {
+		t.Errorf("Expected no attempts before the backoff but got %d", attempted)
+	}
+
+	// The backoff doubles up to the maximum
+	now = now.Add(30 * time.Second)
+	d.ProcessDue()
+	_, _, deliveries = d.ListDeliveries("", domain.DeliveryPending)
+	if len(deliveries) != 1 || !deliveries[0].NextAttempt.Equal(now.Add(90*time.Second)) {
+		t.Fatalf("Expected a retry in 90s but got %+v", deliveries)
+	}
+
+	now = now.Add(90 * time.Second)
+	d.ProcessDue()
+	_, _, dead := d.ListDeliveries("", domain.DeliveryDead)
+	if len(dead) != 1 || len(dead[0].Attempts) != 3 {
+		t.Fatalf("Expected 1 dead delivery after 3 attempts but got %+v", dead)
+	}
+	if receiver.count() != 3 {
+		t.Errorf("Expected 3 requests but got %d", receiver.count())
+	}
+
+	// Dead letters can be queued again
+	receiver.mu.Lock()
+	receiver.status = http.StatusNoContent
+	receiver.mu.Unlock()
+	if success, msg := d.RedeliverWebhook(dead[0].ID); !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+	d.ProcessDue()
+	_, _, deliveries = d.ListDeliveries("", domain.DeliverySucceeded)
+	if len(deliveries) != 1 || len(deliveries[0].Attempts) != 4 {
+		t.Errorf("Expected redelivery to succeed but got %+v", deliveries)
+	}
+	if success, msg := d.RedeliverWebhook(dead[0].ID); success || msg != domain.ErrDeliveryNotDead.Error() {
+		t.Errorf("Expected %q but got success=%v msg=%q", domain.ErrDeliveryNotDead.Error(), success, msg)
+	}
+}
+
+func TestWebhookDispatcher_Filters(t *testing.T) {
+	db := newMapGroupDatabase()
+	s := NewPhonebookService(db)
+	s.AddContact("contacts/john", trashTestContact)
+	s.AddContact("contacts/jane", domain.Contact{Name: "Jane Doe", Phone: "555-000-1111"})
+	s.CreateGroup("family")
+	s.AddToGroup("family", "contacts/john")
+
+	d := setupWebhookTest(t, s, newMapDatabase())
+	_, _, deletes := d.RegisterWebhook(domain.Webhook{URL: "https://example.com/deletes", Events: []domain.ContactEventType{domain.ContactDeleted}})
+	_, _, family := d.RegisterWebhook(domain.Webhook{URL: "https://example.com/family", Group: "family"})
+
+	d.Enqueue(domain.ContactEvent{Type: domain.ContactUpdated, ID: "contacts/john"})
+	d.Enqueue(domain.ContactEvent{Type: domain.ContactDeleted, ID: "contacts/jane"})
+
+	tests := []struct {
+		hook     domain.Webhook
+		expected []string
+	}{
+		{deletes, []string{"contacts/jane"}},
+		{family, []string{"contacts/john"}},
+	}
+	for _, tt := range tests {
+		_, _, deliveries := d.ListDeliveries(tt.hook.ID, "")
+		ids := make([]string, 0)
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.Event.ID)
+		}
+		if len(ids) != len(tt.expected) || (len(ids) > 0 && ids[0] != tt.expected[0]) {
+			t.Errorf("%s: expected deliveries for %v but got %v", tt.hook.URL, tt.expected, ids)
+		}
+	}
+}
+
+func TestWebhookDispatcher_Start(t *testing.T) {
+	receiver := &webhookReceiver{status: http.StatusOK}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	db := &MockWatchDatabase{
+		MockDatabase: newMapDatabase(),
+		changes: []ports.ChangeEvent{
+			{Cursor: "1", Op: ports.ChangeCreate, Location: "contacts/john", Data: contactToData(trashTestContact)},
+			{Cursor: "2", Op: ports.ChangeDelete, Location: "contacts/john"},
+		},
+	}
+	store := newMapDatabase()
+	d := setupWebhookTest(t, NewPhonebookService(db), store)
+	d.RegisterWebhook(domain.Webhook{URL: server.URL})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if success, msg := d.Start(ctx, 10*time.Millisecond); !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && receiver.count() < 2 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if receiver.count() != 2 {
+		t.Fatalf("Expected 2 deliveries but got %d", receiver.count())
+	}
+	if _, _, data := store.Read(webhookCursorLocation); stringField(data, "cursor") != "2" {
+		t.Errorf("Expected the cursor to be saved but got %v", data)
+	}
+}
+
+// resumingWatchDatabase replays the changes after the cursor it is watched
+// from and then ends the stream. An empty cursor replays nothing, and cursors
+// in expired fail to resume.
+type resumingWatchDatabase struct {
+	*MockDatabase
+	mu      sync.Mutex
+	changes []ports.ChangeEvent
+	expired map[string]bool
+	cursors []string
+}
+
+func (m *resumingWatchDatabase) Watch(ctx context.Context, prefix, fromCursor string) (bool, string, <-chan ports.ChangeEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cursors = append(m.cursors, fromCursor)
+	if m.expired[fromCursor] {
+		return false, ports.CursorExpired, nil
+	}
+
+	events := make(chan ports.ChangeEvent, len(m.changes))
+	replay := false
+	for _, change := range m.changes {
+		if replay {
+			events <- change
+		}
+		replay = replay || change.Cursor == fromCursor
+	}
+	close(events)
+	return true, "", events
+}
+
+func (m *resumingWatchDatabase) add(change ports.ChangeEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.changes = append(m.changes, change)
+}
+
+func (m *resumingWatchDatabase) watchedFrom() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.cursors...)
+}
+
+func TestWebhookDispatcher_StartResubscribes(t *testing.T) {
+	receiver := &webhookReceiver{status: http.StatusOK}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	db := &resumingWatchDatabase{
+		MockDatabase: newMapDatabase(),
+		changes: []ports.ChangeEvent{
+			{Cursor: "1", Op: ports.ChangeCreate, Location: "contacts/john", Data: contactToData(trashTestContact)},
+			{Cursor: "2", Op: ports.ChangeUpdate, Location: "contacts/john", Data: contactToData(trashTestContact)},
+		},
+		expired: map[string]bool{},
+	}
+	store := newMapDatabase()
+	d := setupWebhookTest(t, NewPhonebookService(db), store, WithWebhookWatchRetry(10*time.Millisecond, 50*time.Millisecond))
+	d.RegisterWebhook(domain.Webhook{URL: server.URL})
+	d.saveCursor("1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if success, msg := d.Start(ctx, 10*time.Millisecond); !success {
+		t.Fatalf("Expected success but got error: %s", msg)
+	}
+
+	waitFor := func(condition func() bool) bool {
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) && !condition() {
+			time.Sleep(5 * time.Millisecond)
+		}
+		return condition()
+	}
+
+	// A change made while the watch is down arrives after re-subscribing
+	if !waitFor(func() bool { return receiver.count() >= 1 }) {
+		t.Fatalf("Expected the first change to be delivered")
+	}
+	db.add(ports.ChangeEvent{Cursor: "3", Op: ports.ChangeDelete, Location: "contacts/john"})
+	if !waitFor(func() bool { return receiver.count() >= 2 }) {
+		t.Fatalf("Expected the change made after the watch ended to be delivered")
+	}
+
+	// An expired cursor restarts the watch from the current position
+	db.mu.Lock()
+	db.expired["3"] = true
+	db.mu.Unlock()
+	if !waitFor(func() bool {
+		cursors := db.watchedFrom()
+		return cursors[len(cursors)-1] == ""
+	}) {
+		t.Fatalf("Expected the watch to restart from the current position, got cursors %v", db.watchedFrom())
+	}
+	if _, _, data := store.Read(webhookCursorLocation); stringField(data, "cursor") != "" {
+		t.Errorf("Expected the expired cursor to be reset but got %v", data)
+	}
+}
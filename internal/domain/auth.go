@@ -0,0 +1,58 @@
+package domain
+
+import "time"
+
+// Role is a level of access. Each role includes the ones below it.
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+var roleRanks = map[Role]int{RoleViewer: 1, RoleEditor: 2, RoleAdmin: 3}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	return roleRanks[r] > 0
+}
+
+// Includes reports whether r grants at least the access of other. The empty
+// role grants nothing.
+func (r Role) Includes(other Role) bool {
+	return roleRanks[r] > 0 && roleRanks[r] >= roleRanks[other]
+}
+
+// User is someone allowed to call the phonebook. Role applies to every
+// contact; Groups raises it for the members of individual groups. A user
+// without a Role only sees the groups they are granted, and a user with a
+// Tenant only that tenant's phonebook.
+type User struct {
+	Name      string          `json:"name"`
+	Role      Role            `json:"role,omitempty"`
+	Groups    map[string]Role `json:"groups,omitempty"`
+	Disabled  bool            `json:"disabled,omitempty"`
+	Tenant    string          `json:"tenant,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// APIKey authenticates a user. Only a hash of the secret is stored; the key
+// itself is shown once when it is created.
+type APIKey struct {
+	ID         string    `json:"id"`
+	User       string    `json:"user"`
+	Name       string    `json:"name,omitempty"`
+	Hash       string    `json:"hash"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at,omitempty"`
+}
+
+// Actor is the authenticated caller of a request.
+type Actor struct {
+	Name   string
+	Role   Role
+	Groups map[string]Role
+	// Tenant confines the actor to one tenant when set.
+	Tenant string
+}
@@ -0,0 +1,43 @@
+package domain
+
+import "time"
+
+// BackupManifest describes a backup archive. It is written at the end of the
+// archive, once the checksum of everything before it is known.
+type BackupManifest struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Records   int       `json:"records"`
+	Groups    int       `json:"groups"`
+	Blobs     int       `json:"blobs"`
+	// Checksum is the hex SHA-256 of the uncompressed archive up to the manifest.
+	Checksum string `json:"sha256"`
+}
+
+// RestoreMode decides what happens to data that is already stored.
+type RestoreMode string
+
+const (
+	// RestoreReplace makes the database match the backup exactly, deleting
+	// records and groups that are not in it.
+	RestoreReplace RestoreMode = "replace"
+	// RestoreMerge writes the backup over the stored data and keeps the
+	// records and group members that are not in it.
+	RestoreMerge RestoreMode = "merge"
+)
+
+// RestoreReport counts the changes a restore made, or would make when it is
+// a dry run.
+type RestoreReport struct {
+	Manifest BackupManifest `json:"manifest"`
+	DryRun   bool           `json:"dry_run"`
+	Created  int            `json:"created"`
+	Updated  int            `json:"updated"`
+	Deleted  int            `json:"deleted"`
+	Groups   int            `json:"groups"`
+	// Blobs counts the blobs stored that were missing, DeletedBlobs those
+	// removed because the backup does not have them.
+	Blobs        int `json:"blobs"`
+	DeletedBlobs int `json:"deleted_blobs"`
+}
@@ -0,0 +1,22 @@
+package domain
+
+// Blob describes binary content kept in the blob store, addressed by the
+// hex SHA-256 hash of its bytes.
+type Blob struct {
+	Hash        string `json:"hash"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// Photo is a contact's photo and a thumbnail of it. The thumbnail is the
+// photo itself when the photo is already small enough.
+type Photo struct {
+	Blob
+	Thumbnail Blob `json:"thumbnail"`
+}
+
+// Attachment is a document attached to a contact under a name.
+type Attachment struct {
+	Name string `json:"name"`
+	Blob
+}
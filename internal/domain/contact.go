@@ -5,4 +5,6 @@ type Contact struct {
 	Phone   string `json:"phone"`
 	Email   string `json:"email"`
 	Address string `json:"address"`
+	// Custom holds the values of the phonebook's custom fields by name.
+	Custom map[string]string `json:"custom,omitempty"`
 }
@@ -0,0 +1,18 @@
+package domain
+
+// DuplicateCandidate is a pair of contacts that probably describe the same person.
+type DuplicateCandidate struct {
+	IDs     [2]string `json:"ids"`
+	Score   float64   `json:"score"`
+	Reasons []string  `json:"reasons"`
+}
+
+// MergeStrategy decides which value wins when merged contacts disagree.
+type MergeStrategy string
+
+const (
+	// MergePreferFirst keeps the first contact's values and only fills its empty fields.
+	MergePreferFirst MergeStrategy = "prefer-first"
+	// MergePreferComplete keeps the longest value for each field.
+	MergePreferComplete MergeStrategy = "prefer-complete"
+)
@@ -7,4 +7,59 @@ var (
 	ErrInvalidContactNumber = errors.New("invalid contact: Phone is required")
 	ErrContactExists  = errors.New("contact already exists")
 	ErrContactNotFound = errors.New("contact not found")
+	ErrContactInTrash  = errors.New("contact is in the trash")
+	ErrContactNotInTrash = errors.New("contact is not in the trash")
+	ErrContactMerged = errors.New("contact was merged into another contact")
+	ErrMergeTooFewContacts = errors.New("merge needs at least two contacts")
+	ErrUnknownMergeStrategy = errors.New("unknown merge strategy")
+	ErrInvalidGroupName = errors.New("invalid group: Name is required")
+	ErrGroupsNotSupported = errors.New("database does not support groups")
+	ErrInvalidImportHeader = errors.New("invalid import: header must include id, name and phone")
+	ErrMissingContactID = errors.New("invalid contact: ID is required")
+	ErrReservedContactID = errors.New("invalid contact: IDs starting with _ are reserved")
+	ErrWatchNotSupported = errors.New("database does not support watching changes")
+	ErrInvalidWebhookURL = errors.New("invalid webhook: URL must be an absolute http or https URL")
+	ErrInvalidWebhookEvent = errors.New("invalid webhook: unknown event type")
+	ErrWebhookNotFound = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrDeliveryNotDead = errors.New("webhook delivery is not dead-lettered")
+	ErrWebhookStoreShared = errors.New("webhook store must be separate from the phonebook database")
+	ErrWebhooksNotEnabled = errors.New("webhooks are not enabled")
+	ErrUnauthenticated = errors.New("authentication required")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidRole = errors.New("invalid role: must be viewer, editor or admin")
+	ErrInvalidUserName = errors.New("invalid user: Name is required and cannot contain /")
+	ErrUserExists = errors.New("user already exists")
+	ErrUserNotFound = errors.New("user not found")
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrInvalidTenantID = errors.New("invalid tenant: ID must be lowercase letters, digits and dashes")
+	ErrTenantExists = errors.New("tenant already exists")
+	ErrTenantNotFound = errors.New("tenant not found")
+	ErrTenantRequired = errors.New("tenant required")
+	ErrTenantQuotaExceeded = errors.New("tenant contact quota exceeded")
+	ErrInvalidBackup = errors.New("invalid backup: not a phonebook backup archive")
+	ErrUnsupportedBackupVersion = errors.New("invalid backup: unsupported format version")
+	ErrBackupChecksum = errors.New("invalid backup: checksum mismatch, the archive is corrupt or truncated")
+	ErrInvalidRestoreMode = errors.New("invalid restore mode: must be replace or merge")
+	ErrMigrationInterrupted = errors.New("migration interrupted, run it again to resume")
+	ErrInvalidPatchFormat = errors.New("invalid patch: format must be merge-patch or json-patch")
+	ErrInvalidPatch = errors.New("invalid patch: malformed document or unknown contact field")
+	ErrPatchTestFailed = errors.New("patch test failed: contact does not match")
+	ErrUnknownContactField = errors.New("invalid fields: must be name, phone, email, address or custom_ followed by a defined custom field")
+	ErrBlobsNotSupported = errors.New("database does not support photos and attachments")
+	ErrBlobTooLarge = errors.New("file is too large")
+	ErrUnsupportedPhoto = errors.New("invalid photo: must be a JPEG, PNG or GIF image")
+	ErrInvalidAttachmentName = errors.New("invalid attachment: Name is required and cannot contain /")
+	ErrPhotoNotFound = errors.New("contact has no photo")
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrInvalidFieldName = errors.New("invalid field: Name must be lowercase letters, digits and underscores starting with a letter, and not a built-in field")
+	ErrInvalidFieldType = errors.New("invalid field: type must be text, number, boolean or date")
+	ErrInvalidFieldPattern = errors.New("invalid field: pattern is not a valid regular expression")
+	ErrInvalidFieldEnum = errors.New("invalid field: enum values must match the type and pattern")
+	ErrFieldNotFound = errors.New("custom field not found")
+	ErrUnknownCustomField = errors.New("invalid contact: unknown custom field")
+	ErrMissingCustomField = errors.New("invalid contact: a required custom field is missing")
+	ErrInvalidCustomValue = errors.New("invalid contact: custom field value does not match its definition")
+	ErrUnsupportedRecordVersion = errors.New("record was written by a newer version of the phonebook")
 )
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+type ContactEventType string
+
+const (
+	ContactCreated ContactEventType = "created"
+	ContactUpdated ContactEventType = "updated"
+	ContactDeleted ContactEventType = "deleted"
+)
+
+// ContactEvent is a change to a contact as seen by phonebook clients. Contact
+// is empty for deletes. Cursor resumes a watch after this event.
+type ContactEvent struct {
+	Cursor  string           `json:"cursor"`
+	Type    ContactEventType `json:"type"`
+	ID      string           `json:"id"`
+	Contact Contact          `json:"contact"`
+	Time    time.Time        `json:"time"`
+}
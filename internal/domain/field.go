@@ -0,0 +1,24 @@
+package domain
+
+// FieldType is the type of the values of a custom contact field.
+type FieldType string
+
+const (
+	FieldText    FieldType = "text"
+	FieldNumber  FieldType = "number"
+	FieldBoolean FieldType = "boolean"
+	// FieldDate values are calendar dates written as YYYY-MM-DD.
+	FieldDate FieldType = "date"
+)
+
+// FieldDefinition describes a custom contact field of a phonebook. Values are
+// kept as text and checked against the definition when contacts are written.
+type FieldDefinition struct {
+	Name     string    `json:"name"`
+	Type     FieldType `json:"type"`
+	Required bool      `json:"required,omitempty"`
+	// Enum lists the allowed values. Empty allows any value of the type.
+	Enum []string `json:"enum,omitempty"`
+	// Pattern is a regular expression the whole value must match.
+	Pattern string `json:"pattern,omitempty"`
+}
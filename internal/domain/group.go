@@ -0,0 +1,7 @@
+package domain
+
+// Group is a named set of contacts such as a team, a customer or a family.
+type Group struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
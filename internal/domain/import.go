@@ -0,0 +1,9 @@
+package domain
+
+// ImportResult reports what happened to one imported record.
+type ImportResult struct {
+	Line    int    `json:"line"`
+	ID      string `json:"id"`
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
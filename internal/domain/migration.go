@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// MigrationProgress is how far a copy between two databases has come. It is
+// saved after every chunk so an interrupted copy resumes where it stopped.
+type MigrationProgress struct {
+	ID     string `json:"id"`
+	Copied int    `json:"copied"`
+	Total  int    `json:"total"`
+	// Cursor is the last location copied; locations sort in copy order.
+	Cursor    string    `json:"cursor"`
+	Done      bool      `json:"done"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MigrationReport compares a source database with its copy.
+type MigrationReport struct {
+	SourceRecords  int    `json:"source_records"`
+	TargetRecords  int    `json:"target_records"`
+	SourceChecksum string `json:"source_checksum"`
+	TargetChecksum string `json:"target_checksum"`
+	// Missing are in the source only, Extra in the target only, and
+	// Mismatched in both with different contents.
+	Missing          []string `json:"missing,omitempty"`
+	Extra            []string `json:"extra,omitempty"`
+	Mismatched       []string `json:"mismatched,omitempty"`
+	MismatchedGroups []string `json:"mismatched_groups,omitempty"`
+	// MissingBlobs are the hashes of blobs the target lacks.
+	MissingBlobs []string `json:"missing_blobs,omitempty"`
+}
+
+// InSync reports whether the target holds exactly the source's data.
+func (r MigrationReport) InSync() bool {
+	return r.SourceChecksum == r.TargetChecksum && len(r.Missing) == 0 && len(r.Extra) == 0 &&
+		len(r.Mismatched) == 0 && len(r.MismatchedGroups) == 0 && len(r.MissingBlobs) == 0
+}
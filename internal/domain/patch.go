@@ -0,0 +1,19 @@
+package domain
+
+// PatchFormat names the format of a contact patch.
+type PatchFormat string
+
+const (
+	// MergePatch is an RFC 7396 JSON Merge Patch: an object whose members
+	// replace the contact's fields, with null clearing a field.
+	MergePatch PatchFormat = "merge-patch"
+	// JSONPatch is an RFC 6902 JSON Patch: an array of operations on the
+	// contact's fields.
+	JSONPatch PatchFormat = "json-patch"
+)
+
+// ContactPatch changes some fields of a contact and leaves the others.
+type ContactPatch struct {
+	Format   PatchFormat
+	Document []byte
+}
@@ -0,0 +1,31 @@
+package domain
+
+// FieldError is a problem with one field of a stored record. An empty Field
+// means the record as a whole could not be read.
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Problem string `json:"problem"`
+}
+
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return e.Problem
+	}
+	return e.Field + ": " + e.Problem
+}
+
+// RecordProblem lists what is wrong with a stored record.
+type RecordProblem struct {
+	ID      string       `json:"id"`
+	Version int          `json:"version"`
+	Errors  []FieldError `json:"errors"`
+}
+
+// VerifyReport is the result of checking every record of a phonebook.
+type VerifyReport struct {
+	Records int `json:"records"`
+	// Outdated records use an older record version. They are upgraded when
+	// read and rewritten in the current version on their next update.
+	Outdated int             `json:"outdated"`
+	Problems []RecordProblem `json:"problems,omitempty"`
+}
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// Tenant is a phonebook of its own, isolated from the others. MaxContacts
+// caps the contacts it holds, counting those in the trash; zero means no
+// limit.
+type Tenant struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name,omitempty"`
+	MaxContacts int       `json:"max_contacts,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// TrashedContact is a soft-deleted contact waiting to be restored or purged.
+type TrashedContact struct {
+	ID        string    `json:"id"`
+	Contact   Contact   `json:"contact"`
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy string    `json:"deleted_by"`
+}
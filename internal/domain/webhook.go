@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// Webhook is a registered endpoint that receives contact events. An empty
+// Events list subscribes to every event type; a non-empty Group limits
+// deliveries to members of that group.
+type Webhook struct {
+	ID        string             `json:"id"`
+	URL       string             `json:"url"`
+	Secret    string             `json:"secret"`
+	Events    []ContactEventType `json:"events,omitempty"`
+	Group     string             `json:"group,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryDead      DeliveryStatus = "dead"
+)
+
+// WebhookDelivery is one event queued for one webhook, with every attempt
+// made so far. Failures counts the failed attempts since it was last queued.
+type WebhookDelivery struct {
+	ID          string            `json:"id"`
+	WebhookID   string            `json:"webhook_id"`
+	Event       ContactEvent      `json:"event"`
+	Status      DeliveryStatus    `json:"status"`
+	NextAttempt time.Time         `json:"next_attempt"`
+	Failures    int               `json:"failures"`
+	Attempts    []DeliveryAttempt `json:"attempts,omitempty"`
+}
+
+type DeliveryAttempt struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
@@ -0,0 +1,26 @@
+package ports
+
+// BatchItem is one record in a batch write.
+type BatchItem struct {
+	Location string
+	Data     map[string]interface{}
+}
+
+// BatchResult reports the outcome of one item, in the same order as the input.
+type BatchResult struct {
+	Location string
+	Success  bool
+	Message  string
+}
+
+// BatchDatabase is implemented by databases that can apply many writes in one
+// round trip. A failed item does not stop the rest of the batch.
+type BatchDatabase interface {
+	// BatchRead reads the records at locations, keyed by location. Locations
+	// without a record are left out.
+	BatchRead(locations []string) (bool, string, map[string]map[string]interface{})
+	BatchCreate(items []BatchItem) []BatchResult
+	// BatchUpsert creates each record or replaces the existing one.
+	BatchUpsert(items []BatchItem) []BatchResult
+	BatchDelete(locations []string) []BatchResult
+}
@@ -0,0 +1,14 @@
+package ports
+
+// BlobStore is implemented by databases that can store binary content, such
+// as contact photos and attachments. Blobs are keyed by the hex SHA-256 hash
+// of their content, so storing content that is already there is a no-op and
+// identical files are kept once.
+type BlobStore interface {
+	// PutBlob stores content under hash, which must be its SHA-256 hash.
+	PutBlob(hash string, content []byte) (bool, string)
+	GetBlob(hash string) (bool, string, []byte)
+	DeleteBlob(hash string) (bool, string)
+	// ListBlobs returns the hashes of every stored blob, sorted.
+	ListBlobs() (bool, string, []string)
+}
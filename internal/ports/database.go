@@ -5,4 +5,6 @@ type Database interface {
 	Read(location string) (bool, string, map[string]interface{})
 	Update(location string, data map[string]interface{}) (bool, string)
 	Delete(location string) (bool, string)
+	// List returns every stored location that starts with prefix, sorted.
+	List(prefix string) (bool, string, []string)
 }
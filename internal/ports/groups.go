@@ -0,0 +1,16 @@
+package ports
+
+// GroupStore is implemented by databases that can store contact groups.
+// Membership is many-to-many between group names and contact locations.
+type GroupStore interface {
+	CreateGroup(name string) (bool, string)
+	RenameGroup(name, newName string) (bool, string)
+	DeleteGroup(name string) (bool, string)
+	ListGroups() (bool, string, []string)
+	AddMember(group, location string) (bool, string)
+	RemoveMember(group, location string) (bool, string)
+	// Members returns the locations in group, sorted.
+	Members(group string) (bool, string, []string)
+	// GroupsOf returns the groups location belongs to, sorted.
+	GroupsOf(location string) (bool, string, []string)
+}
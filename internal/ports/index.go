@@ -0,0 +1,11 @@
+package ports
+
+// FieldIndex is implemented by databases that can find records by the exact
+// value of a field without reading every record, for instance through blind
+// indexes over encrypted fields.
+type FieldIndex interface {
+	// IndexedField reports whether field can be looked up.
+	IndexedField(field string) bool
+	// LookupField returns the locations whose field holds value, sorted.
+	LookupField(field, value string) (bool, string, []string)
+}
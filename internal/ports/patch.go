@@ -0,0 +1,10 @@
+package ports
+
+// PatchDatabase is implemented by databases that can change some fields of a
+// record in place, so writers changing different fields do not overwrite
+// each other.
+type PatchDatabase interface {
+	// Patch sets the given top-level fields of an existing record and
+	// removes those whose value is nil. Other fields are left untouched.
+	Patch(location string, fields map[string]interface{}) (bool, string)
+}
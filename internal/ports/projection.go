@@ -0,0 +1,13 @@
+package ports
+
+// ProjectionDatabase is implemented by databases that can return only some
+// top-level fields of their records, so large fields are not transferred
+// when they are not needed.
+type ProjectionDatabase interface {
+	// ReadFields reads a record like Read, returning only those of fields
+	// the record has.
+	ReadFields(location string, fields []string) (bool, string, map[string]interface{})
+	// ListFields returns the given fields of every record whose location
+	// starts with prefix, keyed by location.
+	ListFields(prefix string, fields []string) (bool, string, map[string]map[string]interface{})
+}
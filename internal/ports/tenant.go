@@ -0,0 +1,11 @@
+package ports
+
+// TenantDatabase is implemented by databases that can keep the records of
+// several tenants apart. Each tenant sees an empty keyspace of its own.
+type TenantDatabase interface {
+	// Tenant returns the database of one tenant. It supports the same
+	// optional interfaces as the parent.
+	Tenant(id string) (bool, string, Database)
+	// DropTenant removes every record and group of a tenant.
+	DropTenant(id string) (bool, string)
+}
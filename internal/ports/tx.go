@@ -0,0 +1,8 @@
+package ports
+
+// Transactor is implemented by databases that can run several operations as
+// one unit of work. fn receives a Database scoped to the transaction; returning
+// an error rolls every write back, returning nil commits them together.
+type Transactor interface {
+	WithTx(fn func(tx Database) error) error
+}
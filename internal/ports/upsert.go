@@ -0,0 +1,17 @@
+package ports
+
+// UpsertDatabase is implemented by databases that decide whether a record
+// exists in the same step as writing it, so concurrent writers cannot both
+// create a record or bring back one that was just deleted.
+type UpsertDatabase interface {
+	// Upsert creates the record or replaces the existing one. The third
+	// result reports whether it was created.
+	Upsert(location string, data map[string]interface{}) (bool, string, bool)
+	// CreateIfAbsent creates the record unless it exists. The third result
+	// reports whether it was created; an existing record is left untouched
+	// and is not an error.
+	CreateIfAbsent(location string, data map[string]interface{}) (bool, string, bool)
+	// UpdateIfExists replaces the record if it exists. The third result
+	// reports whether it was replaced; a missing record is not an error.
+	UpdateIfExists(location string, data map[string]interface{}) (bool, string, bool)
+}
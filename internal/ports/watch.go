@@ -0,0 +1,37 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+type ChangeOp string
+
+const (
+	ChangeCreate ChangeOp = "create"
+	ChangeUpdate ChangeOp = "update"
+	ChangeDelete ChangeOp = "delete"
+)
+
+// ChangeEvent describes one write. Data is the record after the write and is
+// nil for deletes. Cursor identifies the event for resuming a watch.
+type ChangeEvent struct {
+	Cursor   string
+	Op       ChangeOp
+	Location string
+	Data     map[string]interface{}
+	Time     time.Time
+}
+
+// CursorExpired is the message Watch fails with when fromCursor points before
+// the oldest change the database still keeps.
+const CursorExpired = "Cursor expired"
+
+// Watcher is implemented by databases that can stream their changes.
+type Watcher interface {
+	// Watch streams changes to locations starting with prefix. An empty
+	// fromCursor starts at the current position; otherwise the stream resumes
+	// after the event with that cursor. The channel is closed when ctx is
+	// cancelled or the stream can no longer continue.
+	Watch(ctx context.Context, prefix, fromCursor string) (bool, string, <-chan ChangeEvent)
+}